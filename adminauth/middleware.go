@@ -0,0 +1,138 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package adminauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/urfave/negroni"
+
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/healthx"
+	"github.com/ory/x/stringslice"
+)
+
+// Principal is the authenticated caller of the admin API.
+type Principal struct {
+	// ID is the API key ID or the common name of the client certificate.
+	ID     string
+	Scopes []string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return stringslice.Has(p.Scopes, scope)
+}
+
+type principalContextKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated caller of the admin API, or nil if the request was not
+// authenticated.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return p
+}
+
+// Middleware authenticates requests to the admin API using the configured API keys and client certificates. If
+// neither is configured, all requests are passed through.
+func Middleware(reg InternalRegistry) negroni.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		ctx := r.Context()
+		if isUnauthenticatedPath(r.URL.Path) {
+			next(w, r)
+			return
+		}
+
+		keys, err := reg.Config().AdminAPIKeys(ctx)
+		if err != nil {
+			reg.Writer().WriteError(w, r, err)
+			return
+		}
+		mtls := reg.Config().AdminMTLSEnabled(ctx)
+		if len(keys) == 0 && !mtls {
+			next(w, r)
+			return
+		}
+
+		p, err := authenticate(r, reg.Config(), keys, mtls)
+		if err != nil {
+			if len(keys) > 0 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			reg.Writer().WriteError(w, r, err)
+			return
+		}
+
+		if scope, readOnly := requiredScope(r); !p.Allows(scope, readOnly) {
+			reg.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrForbidden.WithReasonf("The credentials are not granted the %q scope.", scope)))
+			return
+		}
+
+		next(w, r.WithContext(WithPrincipal(ctx, p)))
+	}
+}
+
+func authenticate(r *http.Request, c *config.DefaultProvider, keys []config.AdminAPIKey, mtls bool) (*Principal, error) {
+	if token, ok := bearerToken(r); ok && len(keys) > 0 {
+		presented := sha256.Sum256([]byte(token))
+		for _, key := range keys {
+			expected := sha256.Sum256([]byte(key.Key))
+			if subtle.ConstantTimeCompare(presented[:], expected[:]) == 1 {
+				return &Principal{ID: key.ID, Scopes: scopesOrAll(key.Scopes)}, nil
+			}
+		}
+		return nil, errorsx.WithStack(herodot.ErrUnauthorized.WithReason("The API key is invalid."))
+	}
+
+	if mtls && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		subjects, err := c.AdminMTLSSubjects(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		if len(subjects) == 0 {
+			return &Principal{ID: cn, Scopes: scopesOrAll(nil)}, nil
+		}
+		for _, s := range subjects {
+			if s.CommonName == cn {
+				return &Principal{ID: cn, Scopes: scopesOrAll(s.Scopes)}, nil
+			}
+		}
+		return nil, errorsx.WithStack(herodot.ErrForbidden.WithReasonf("The client certificate %q is not allowed to access the admin API.", cn))
+	}
+
+	return nil, errorsx.WithStack(herodot.ErrUnauthorized.WithReason("The admin API requires authentication."))
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func scopesOrAll(scopes []string) []string {
+	if len(scopes) == 0 {
+		return []string{ScopeRead, ScopeWrite}
+	}
+	return scopes
+}
+
+func isUnauthenticatedPath(path string) bool {
+	switch strings.TrimPrefix(path, "/admin") {
+	case healthx.AliveCheckPath, healthx.ReadyCheckPath:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package adminauth_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni"
+
+	"github.com/ory/hydra/v2/adminauth"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/healthx"
+)
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	newHandler := func(t *testing.T, conf *config.DefaultProvider) http.Handler {
+		reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+		n := negroni.New()
+		n.UseFunc(adminauth.Middleware(reg))
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p := adminauth.PrincipalFromContext(r.Context()); p != nil {
+				w.Header().Set("X-Principal", p.ID)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+		return n
+	}
+
+	do := func(h http.Handler, method, path string, modify func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if modify != nil {
+			modify(r)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	withKey := func(key string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+	}
+
+	withCertificate := func(cn string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}}
+		}
+	}
+
+	t.Run("case=passes requests through if not configured", func(t *testing.T) {
+		h := newHandler(t, internal.NewConfigurationWithDefaults())
+
+		res := do(h, http.MethodDelete, "/admin/clients/foo", nil)
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Empty(t, res.Header().Get("X-Principal"))
+	})
+
+	t.Run("case=api keys", func(t *testing.T) {
+		conf := internal.NewConfigurationWithDefaults()
+		conf.MustSet(ctx, config.KeyAdminAuthAPIKeys, []map[string]interface{}{
+			{"id": "full", "key": "full-access-key-full-access-key-00"},
+			{"id": "reader", "key": "read-only-key-read-only-key-000000", "scopes": []string{adminauth.ScopeRead}},
+		})
+		h := newHandler(t, conf)
+
+		res := do(h, http.MethodGet, "/admin/clients", nil)
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.Equal(t, "Bearer", res.Header().Get("WWW-Authenticate"))
+
+		res = do(h, http.MethodGet, "/admin/clients", withKey("not-a-valid-key"))
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+		res = do(h, http.MethodDelete, "/admin/clients/foo", withKey("full-access-key-full-access-key-00"))
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "full", res.Header().Get("X-Principal"))
+
+		res = do(h, http.MethodGet, "/admin/clients", withKey("read-only-key-read-only-key-000000"))
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "reader", res.Header().Get("X-Principal"))
+
+		res = do(h, http.MethodDelete, "/admin/clients/foo", withKey("read-only-key-read-only-key-000000"))
+		assert.Equal(t, http.StatusForbidden, res.Code)
+	})
+
+	t.Run("case=endpoint scopes", func(t *testing.T) {
+		conf := internal.NewConfigurationWithDefaults()
+		conf.MustSet(ctx, config.KeyAdminAuthAPIKeys, []map[string]interface{}{
+			{"id": "ci", "key": "client-manager-key-client-manager-00", "scopes": []string{adminauth.ScopeClientsRead, adminauth.ScopeClientsWrite}},
+			{"id": "resource-server", "key": "introspection-key-introspection-key", "scopes": []string{adminauth.ScopeTokensIntrospect}},
+			{"id": "auditor", "key": "auditor-key-auditor-key-auditor-key", "scopes": []string{adminauth.ScopeRead}},
+			{"id": "revoker", "key": "revocation-key-revocation-key-revoc", "scopes": []string{adminauth.ScopeTokensRevoke}},
+		})
+		h := newHandler(t, conf)
+
+		for _, tc := range []struct {
+			key, method, path string
+			expected          int
+		}{
+			{key: "client-manager-key-client-manager-00", method: http.MethodGet, path: "/admin/clients", expected: http.StatusNoContent},
+			{key: "client-manager-key-client-manager-00", method: http.MethodPost, path: "/admin/clients", expected: http.StatusNoContent},
+			{key: "client-manager-key-client-manager-00", method: http.MethodDelete, path: "/admin/clients/foo", expected: http.StatusNoContent},
+			{key: "client-manager-key-client-manager-00", method: http.MethodDelete, path: "/admin/keys/hydra.openid.id-token", expected: http.StatusForbidden},
+			{key: "client-manager-key-client-manager-00", method: http.MethodGet, path: "/admin/version/migrations", expected: http.StatusForbidden},
+			{key: "introspection-key-introspection-key", method: http.MethodPost, path: "/admin/oauth2/introspect", expected: http.StatusNoContent},
+			{key: "introspection-key-introspection-key", method: http.MethodDelete, path: "/admin/oauth2/tokens", expected: http.StatusForbidden},
+			{key: "introspection-key-introspection-key", method: http.MethodPost, path: "/admin/oauth2/tokens/jwt", expected: http.StatusNoContent},
+			{key: "revocation-key-revocation-key-revoc", method: http.MethodDelete, path: "/admin/oauth2/tokens", expected: http.StatusNoContent},
+			{key: "revocation-key-revocation-key-revoc", method: http.MethodPost, path: "/admin/oauth2/tokens/jwt", expected: http.StatusForbidden},
+			{key: "auditor-key-auditor-key-auditor-key", method: http.MethodGet, path: "/admin/audit/events", expected: http.StatusNoContent},
+			{key: "auditor-key-auditor-key-auditor-key", method: http.MethodGet, path: "/admin/keys/hydra.openid.id-token", expected: http.StatusNoContent},
+			{key: "auditor-key-auditor-key-auditor-key", method: http.MethodPost, path: "/admin/oauth2/introspect", expected: http.StatusNoContent},
+			{key: "auditor-key-auditor-key-auditor-key", method: http.MethodDelete, path: "/admin/oauth2/auth/sessions/login", expected: http.StatusForbidden},
+		} {
+			assert.Equal(t, tc.expected, do(h, tc.method, tc.path, withKey(tc.key)).Code, "%s %s", tc.method, tc.path)
+		}
+	})
+
+	t.Run("case=health checks do not require authentication", func(t *testing.T) {
+		conf := internal.NewConfigurationWithDefaults()
+		conf.MustSet(ctx, config.KeyAdminAuthAPIKeys, []map[string]interface{}{
+			{"id": "full", "key": "full-access-key-full-access-key-00"},
+		})
+		h := newHandler(t, conf)
+
+		for _, path := range []string{healthx.AliveCheckPath, healthx.ReadyCheckPath, "/admin" + healthx.AliveCheckPath, "/admin" + healthx.ReadyCheckPath} {
+			assert.Equal(t, http.StatusNoContent, do(h, http.MethodGet, path, nil).Code, path)
+		}
+	})
+
+	t.Run("case=client certificates", func(t *testing.T) {
+		conf := internal.NewConfigurationWithDefaults()
+		conf.MustSet(ctx, config.KeyAdminAuthMTLSEnabled, true)
+		conf.MustSet(ctx, config.KeyAdminAuthMTLSSubjects, []map[string]interface{}{
+			{"common_name": "operator"},
+			{"common_name": "dashboard", "scopes": []string{adminauth.ScopeRead}},
+		})
+		h := newHandler(t, conf)
+
+		assert.Equal(t, http.StatusUnauthorized, do(h, http.MethodGet, "/admin/clients", nil).Code)
+		assert.Equal(t, http.StatusForbidden, do(h, http.MethodGet, "/admin/clients", withCertificate("unknown")).Code)
+
+		res := do(h, http.MethodDelete, "/admin/clients/foo", withCertificate("operator"))
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "operator", res.Header().Get("X-Principal"))
+
+		assert.Equal(t, http.StatusNoContent, do(h, http.MethodGet, "/admin/clients", withCertificate("dashboard")).Code)
+		assert.Equal(t, http.StatusForbidden, do(h, http.MethodDelete, "/admin/clients/foo", withCertificate("dashboard")).Code)
+	})
+
+	t.Run("case=client certificates without subject restrictions", func(t *testing.T) {
+		conf := internal.NewConfigurationWithDefaults()
+		conf.MustSet(ctx, config.KeyAdminAuthMTLSEnabled, true)
+		h := newHandler(t, conf)
+
+		res := do(h, http.MethodDelete, "/admin/clients/foo", withCertificate("anyone"))
+		assert.Equal(t, http.StatusNoContent, res.Code)
+		assert.Equal(t, "anyone", res.Header().Get("X-Principal"))
+	})
+}
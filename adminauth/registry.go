@@ -0,0 +1,15 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package adminauth
+
+import (
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+)
+
+type InternalRegistry interface {
+	x.RegistryWriter
+	x.RegistryLogger
+	config.Provider
+}
@@ -0,0 +1,209 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package adminauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/ory/x/logrusx"
+)
+
+const (
+	// crlRefreshInterval is how often the certificate revocation lists are reloaded, so that updated lists are
+	// picked up without a restart.
+	crlRefreshInterval = time.Minute
+
+	// ocspTimeout bounds the time an OCSP responder may delay the TLS handshake.
+	ocspTimeout = 5 * time.Second
+
+	// ocspDefaultCacheTTL is how long OCSP responses without a next update time are cached.
+	ocspDefaultCacheTTL = time.Hour
+
+	// maxCachedOCSPResponses bounds the memory used to cache OCSP responses.
+	maxCachedOCSPResponses = 10000
+)
+
+// RevocationChecker checks whether the client certificates presented to the admin API are revoked, using
+// certificate revocation lists and OCSP.
+type RevocationChecker struct {
+	loadCRLs func() ([]*x509.RevocationList, error)
+	ocsp     bool
+	failOpen bool
+	client   *http.Client
+	l        *logrusx.Logger
+	now      func() time.Time
+
+	sync.Mutex
+	crls       []*x509.RevocationList
+	crlsLoaded time.Time
+	responses  map[string]*ocspCacheEntry
+}
+
+type ocspCacheEntry struct {
+	status  int
+	expires time.Time
+}
+
+// NewRevocationChecker returns a checker using the certificate revocation lists returned by loadCRLs and, if
+// enabled, the OCSP responders of the certificates. If failOpen is true, certificates are accepted if their OCSP
+// responder can not be reached.
+func NewRevocationChecker(loadCRLs func() ([]*x509.RevocationList, error), ocspEnabled, failOpen bool, client *http.Client, l *logrusx.Logger) *RevocationChecker {
+	return &RevocationChecker{
+		loadCRLs:  loadCRLs,
+		ocsp:      ocspEnabled,
+		failOpen:  failOpen,
+		client:    client,
+		l:         l,
+		now:       time.Now,
+		responses: make(map[string]*ocspCacheEntry),
+	}
+}
+
+// VerifyConnection is used as tls.Config.VerifyConnection. It rejects the handshake if a certificate of the
+// verified chain, except for the root certificate authority, is revoked.
+func (c *RevocationChecker) VerifyConnection(cs tls.ConnectionState) error {
+	if len(cs.VerifiedChains) == 0 {
+		return nil
+	}
+
+	chain := cs.VerifiedChains[0]
+	for i := 0; i < len(chain)-1; i++ {
+		if err := c.check(chain[i], chain[i+1]); err != nil {
+			c.l.WithError(err).WithField("subject", chain[i].Subject.String()).
+				Warn("Rejected a client certificate presented to the admin API.")
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RevocationChecker) check(cert, issuer *x509.Certificate) error {
+	crls, err := c.revocationLists()
+	if err != nil {
+		return err
+	}
+	for _, crl := range crls {
+		if crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return errors.Errorf("the certificate with serial number %s is revoked", cert.SerialNumber)
+			}
+		}
+	}
+
+	if !c.ocsp || len(cert.OCSPServer) == 0 {
+		return nil
+	}
+
+	status, err := c.ocspStatus(cert, issuer)
+	if err != nil {
+		if c.failOpen {
+			c.l.WithError(err).Warn("Unable to check the revocation status of a client certificate using OCSP, accepting it anyway.")
+			return nil
+		}
+		return err
+	}
+	switch status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return errors.Errorf("the certificate with serial number %s is revoked", cert.SerialNumber)
+	default:
+		return errors.Errorf("the OCSP responder does not know the certificate with serial number %s", cert.SerialNumber)
+	}
+}
+
+// revocationLists returns the certificate revocation lists, reloading them if they are older than the refresh
+// interval. If reloading fails, the previous lists are kept, unless no lists were loaded yet.
+func (c *RevocationChecker) revocationLists() ([]*x509.RevocationList, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.crlsLoaded.IsZero() || c.now().Sub(c.crlsLoaded) > crlRefreshInterval {
+		crls, err := c.loadCRLs()
+		if err != nil {
+			if c.crlsLoaded.IsZero() {
+				return nil, err
+			}
+			c.l.WithError(err).Error("Unable to reload the certificate revocation lists, keeping the previous ones.")
+		} else {
+			c.crls = crls
+		}
+		c.crlsLoaded = c.now()
+	}
+	return c.crls, nil
+}
+
+func (c *RevocationChecker) ocspStatus(cert, issuer *x509.Certificate) (int, error) {
+	key := string(issuer.RawSubjectPublicKeyInfo) + cert.SerialNumber.String()
+
+	c.Lock()
+	if e, ok := c.responses[key]; ok && c.now().Before(e.expires) {
+		c.Unlock()
+		return e.status, nil
+	}
+	c.Unlock()
+
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ocspTimeout)
+	defer cancel()
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, cert.OCSPServer[0], bytes.NewReader(req))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	hreq.Header.Set("Content-Type", "application/ocsp-request")
+
+	res, err := c.client.Do(hreq)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("the OCSP responder responded with status code %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	expires := resp.NextUpdate
+	if expires.IsZero() {
+		expires = c.now().Add(ocspDefaultCacheTTL)
+	}
+	c.Lock()
+	if len(c.responses) >= maxCachedOCSPResponses {
+		for k, e := range c.responses {
+			if !c.now().Before(e.expires) {
+				delete(c.responses, k)
+			}
+		}
+	}
+	if len(c.responses) < maxCachedOCSPResponses {
+		c.responses[key] = &ocspCacheEntry{status: resp.Status, expires: expires}
+	}
+	c.Unlock()
+
+	return resp.Status, nil
+}
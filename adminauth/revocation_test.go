@@ -0,0 +1,132 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package adminauth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/ory/hydra/v2/adminauth"
+	"github.com/ory/x/logrusx"
+)
+
+func newCertificate(t *testing.T, serial int64, parent *x509.Certificate, parentKey crypto.Signer, ocspServer string) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "hydra-operator"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	if ocspServer != "" {
+		tmpl.OCSPServer = []string{ocspServer}
+	}
+	if parent == nil {
+		tmpl.Subject.CommonName = "ca"
+		tmpl.IsCA = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func TestRevocationChecker(t *testing.T) {
+	l := logrusx.New("", "")
+	ca, caKey := newCertificate(t, 1, nil, nil, "")
+
+	crlDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Hour),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{{SerialNumber: big.NewInt(3), RevocationTime: time.Now()}},
+	}, ca, caKey)
+	require.NoError(t, err)
+	crl, err := x509.ParseRevocationList(crlDER)
+	require.NoError(t, err)
+	loadCRLs := func() ([]*x509.RevocationList, error) { return []*x509.RevocationList{crl}, nil }
+
+	requests := 0
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := ocsp.ParseRequest(body)
+		require.NoError(t, err)
+
+		status := ocsp.Good
+		if req.SerialNumber.Int64() == 5 {
+			status = ocsp.Revoked
+		}
+		res, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, caKey)
+		require.NoError(t, err)
+		_, _ = w.Write(res)
+	}))
+	t.Cleanup(responder.Close)
+
+	verify := func(c *adminauth.RevocationChecker, cert *x509.Certificate) error {
+		return c.VerifyConnection(tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert, ca}}})
+	}
+
+	t.Run("case=certificate revocation lists", func(t *testing.T) {
+		c := adminauth.NewRevocationChecker(loadCRLs, false, false, http.DefaultClient, l)
+
+		valid, _ := newCertificate(t, 2, ca, caKey, "")
+		assert.NoError(t, verify(c, valid))
+
+		revoked, _ := newCertificate(t, 3, ca, caKey, "")
+		assert.ErrorContains(t, verify(c, revoked), "revoked")
+
+		assert.NoError(t, c.VerifyConnection(tls.ConnectionState{}), "connections without client certificates are left to the authentication middleware")
+	})
+
+	t.Run("case=ocsp", func(t *testing.T) {
+		c := adminauth.NewRevocationChecker(loadCRLs, true, false, http.DefaultClient, l)
+
+		good, _ := newCertificate(t, 4, ca, caKey, responder.URL)
+		assert.NoError(t, verify(c, good))
+		assert.NoError(t, verify(c, good))
+		assert.Equal(t, 1, requests, "responses are cached")
+
+		revoked, _ := newCertificate(t, 5, ca, caKey, responder.URL)
+		assert.ErrorContains(t, verify(c, revoked), "revoked")
+	})
+
+	t.Run("case=unreachable ocsp responder", func(t *testing.T) {
+		unreachable, _ := newCertificate(t, 6, ca, caKey, "http://127.0.0.1:1/ocsp")
+
+		assert.Error(t, verify(adminauth.NewRevocationChecker(loadCRLs, true, false, http.DefaultClient, l), unreachable))
+		assert.NoError(t, verify(adminauth.NewRevocationChecker(loadCRLs, true, true, http.DefaultClient, l), unreachable))
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package adminauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ory/x/stringsx"
+)
+
+const (
+	// ScopeRead grants all read-only scopes.
+	ScopeRead = "admin:read"
+	// ScopeWrite grants all scopes which are not read-only.
+	ScopeWrite = "admin:write"
+
+	ScopeClientsRead      = "clients:read"
+	ScopeClientsWrite     = "clients:write"
+	ScopeKeysRead         = "keys:read"
+	ScopeKeysWrite        = "keys:write"
+	ScopeTrustRead        = "trust:read"
+	ScopeTrustWrite       = "trust:write"
+	ScopeFlowsRead        = "flows:read"
+	ScopeFlowsWrite       = "flows:write"
+	ScopeSessionsRead     = "sessions:read"
+	ScopeSessionsRevoke   = "sessions:revoke"
+	ScopeTokensIntrospect = "tokens:introspect"
+	ScopeTokensRevoke     = "tokens:revoke"
+	ScopeAuditRead        = "audit:read"
+	ScopeTenantsRead      = "tenants:read"
+	ScopeTenantsWrite     = "tenants:write"
+)
+
+type endpointScopes struct {
+	path  string
+	read  string
+	write string
+	// readOnly is set if the endpoint does not modify any resources, regardless of the request method.
+	readOnly bool
+}
+
+// endpoints maps the admin API endpoints (without the /admin prefix) to the scopes required for read-only and
+// modifying requests. Endpoints which are not listed, or which do not define the scope, require ScopeRead or
+// ScopeWrite.
+var endpoints = []endpointScopes{
+	{path: "/clients", read: ScopeClientsRead, write: ScopeClientsWrite},
+	{path: "/keys", read: ScopeKeysRead, write: ScopeKeysWrite},
+	{path: "/trust/grants", read: ScopeTrustRead, write: ScopeTrustWrite},
+	{path: "/oauth2/auth/requests", read: ScopeFlowsRead, write: ScopeFlowsWrite},
+	{path: "/oauth2/auth/sessions", read: ScopeSessionsRead, write: ScopeSessionsRevoke},
+	{path: "/oauth2/introspect", read: ScopeTokensIntrospect, readOnly: true},
+	{path: "/oauth2/tokens/jwt", read: ScopeTokensIntrospect, readOnly: true},
+	{path: "/oauth2/tokens", write: ScopeTokensRevoke},
+	{path: "/oauth2/fapi/report", read: ScopeClientsRead},
+	{path: "/audit", read: ScopeAuditRead},
+	{path: "/tenants", read: ScopeTenantsRead, write: ScopeTenantsWrite},
+	{path: "/backup/export", readOnly: true},
+}
+
+// requiredScope returns the scope required for the request, and whether the request is read-only.
+func requiredScope(r *http.Request) (scope string, readOnly bool) {
+	path := strings.TrimPrefix(r.URL.Path, "/admin")
+	for _, e := range endpoints {
+		if path != e.path && !strings.HasPrefix(path, e.path+"/") {
+			continue
+		}
+		if e.readOnly || isReadOnly(r) {
+			return stringsx.Coalesce(e.read, ScopeRead), true
+		}
+		return stringsx.Coalesce(e.write, ScopeWrite), false
+	}
+
+	if isReadOnly(r) {
+		return ScopeRead, true
+	}
+	return ScopeWrite, false
+}
+
+func isReadOnly(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// Allows returns true if the principal is granted the scope, either directly or through ScopeRead or ScopeWrite.
+func (p *Principal) Allows(scope string, readOnly bool) bool {
+	if p.HasScope(scope) {
+		return true
+	}
+	if readOnly {
+		return p.HasScope(ScopeRead)
+	}
+	return p.HasScope(ScopeWrite)
+}
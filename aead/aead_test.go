@@ -152,3 +152,34 @@ func TestAEAD(t *testing.T) {
 		})
 	}
 }
+
+func TestAESGCMReencrypt(t *testing.T) {
+	ctx := context.Background()
+	c := internal.NewConfigurationWithDefaults()
+	old := secret(t)
+	c.MustSet(ctx, config.KeyGetSystemSecret, []string{old})
+	a := aead.NewAESGCM(c)
+
+	plain := []byte(uuid.New())
+	ct, err := a.Encrypt(ctx, plain, []byte("aad"))
+	require.NoError(t, err)
+
+	got, changed, err := a.Reencrypt(ctx, ct, []byte("aad"))
+	require.NoError(t, err)
+	assert.False(t, changed, "ciphertexts of the current secret must not be re-encrypted")
+	assert.Equal(t, ct, got)
+
+	current := secret(t)
+	c.MustSet(ctx, config.KeyGetSystemSecret, []string{current, old})
+	got, changed, err = a.Reencrypt(ctx, ct, []byte("aad"))
+	require.NoError(t, err)
+	assert.True(t, changed, "ciphertexts of rotated secrets must be re-encrypted")
+
+	c.MustSet(ctx, config.KeyGetSystemSecret, []string{current})
+	res, err := a.Decrypt(ctx, got, []byte("aad"))
+	require.NoError(t, err)
+	assert.Equal(t, plain, res)
+
+	_, _, err = a.Reencrypt(ctx, ct, []byte("aad"))
+	require.Error(t, err, "ciphertexts of unknown secrets can not be re-encrypted")
+}
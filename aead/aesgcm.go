@@ -64,6 +64,32 @@ func (c *AESGCM) Decrypt(ctx context.Context, ciphertext string, aad []byte) (pl
 	return nil, err
 }
 
+// Reencrypt encrypts the ciphertext with the current key if it was encrypted with a rotated key, and returns whether
+// it did. Ciphertexts which were encrypted with the current key are returned as they are.
+func (c *AESGCM) Reencrypt(ctx context.Context, ciphertext string, aad []byte) (string, bool, error) {
+	msg, err := base64.URLEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", false, errorsx.WithStack(err)
+	}
+
+	key, err := encryptionKey(ctx, c.c, 32)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := c.decrypt(msg, key, aad); err == nil {
+		return ciphertext, false, nil
+	}
+
+	plaintext, err := c.Decrypt(ctx, ciphertext, aad)
+	if err != nil {
+		return "", false, err
+	}
+	if ciphertext, err = c.Encrypt(ctx, plaintext, aad); err != nil {
+		return "", false, err
+	}
+	return ciphertext, true, nil
+}
+
 func (c *AESGCM) decrypt(ciphertext []byte, key, additionalData []byte) ([]byte, error) {
 	if len(key) != 32 {
 		return nil, errors.Errorf("key must be exactly 32 long bytes, got %d bytes", len(key))
@@ -22,7 +22,17 @@ func encryptionKey(ctx context.Context, d Dependencies, keySize int) ([]byte, er
 	return key, nil
 }
 
+// globalSecretsProvider is implemented by dependencies which return the global and the rotated global secrets with
+// a single lookup.
+type globalSecretsProvider interface {
+	GetGlobalSecrets(ctx context.Context) ([][]byte, error)
+}
+
 func allKeys(ctx context.Context, d Dependencies) ([][]byte, error) {
+	if d, ok := d.(globalSecretsProvider); ok {
+		return d.GetGlobalSecrets(ctx)
+	}
+
 	global, err := d.GetGlobalSecret(ctx)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,105 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/httprouterx"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+const (
+	EventsPath = "/audit/events"
+)
+
+type Handler struct {
+	r InternalRegistry
+}
+
+func NewHandler(r InternalRegistry) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
+	admin.GET(EventsPath, h.listAuditEvents)
+}
+
+// Paginated Audit Event List Response
+//
+// swagger:response listAuditEvents
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listAuditEventsResponse struct {
+	keysetpagination.ResponseHeaders
+
+	// List of Audit Events
+	//
+	// in:body
+	Body []Event
+}
+
+// Paginated Audit Event List Parameters
+//
+// swagger:parameters listAuditEvents
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listAuditEventsParameters struct {
+	keysetpagination.RequestParameters
+
+	// The actor to filter by.
+	//
+	// in: query
+	Actor string `json:"actor"`
+
+	// The resource type to filter by, for example "oauth2_client".
+	//
+	// in: query
+	ResourceType string `json:"resource_type"`
+
+	// The resource ID to filter by.
+	//
+	// in: query
+	ResourceID string `json:"resource_id"`
+}
+
+// swagger:route GET /admin/audit/events audit listAuditEvents
+//
+// # List Audit Events
+//
+// This endpoint lists the mutations performed through the admin API, newest first. Audit events are only recorded
+// if the audit log is enabled.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: listAuditEvents
+//	  default: errorOAuth2
+func (h *Handler) listAuditEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	pageOpts, err := x.ParsePagination(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	events, nextPage, err := h.r.AuditManager().GetAuditEvents(r.Context(), Filter{
+		PageOpts:     pageOpts,
+		Actor:        r.URL.Query().Get("actor"),
+		ResourceType: r.URL.Query().Get("resource_type"),
+		ResourceID:   r.URL.Query().Get("resource_id"),
+	})
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	keysetpagination.Header(w, r.URL, nextPage)
+	h.r.Writer().Write(w, r, events)
+}
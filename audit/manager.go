@@ -0,0 +1,99 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlxx"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionImport = "import"
+	ActionRotate = "rotate"
+
+	ResourceOAuth2Client          = "oauth2_client"
+	ResourceJSONWebKeySet         = "json_web_key_set"
+	ResourceJSONWebKey            = "json_web_key"
+	ResourceTrustedJwtGrantIssuer = "trusted_jwt_grant_issuer"
+	ResourceOAuth2ConsentSessions = "oauth2_consent_sessions"
+	ResourceOAuth2LoginSessions   = "oauth2_login_sessions"
+	ResourceBundle                = "bundle"
+	ResourceTenant                = "tenant"
+)
+
+// Audit Event
+//
+// An audit event records a mutation performed through the admin API.
+//
+// swagger:model auditEvent
+type Event struct {
+	// The ID of the audit event.
+	ID uuid.UUID `json:"id" db:"id"`
+
+	NID uuid.UUID `json:"-" db:"nid"`
+
+	// The time at which the mutation was performed.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// The actor which performed the mutation, as identified by the configured actor header.
+	Actor string `json:"actor" db:"actor"`
+
+	// The IP address of the client which performed the mutation.
+	SourceIP string `json:"source_ip" db:"source_ip"`
+
+	// The ID of the HTTP request, taken from the X-Request-Id header.
+	RequestID string `json:"request_id" db:"request_id"`
+
+	// The action, one of "create", "update" or "delete".
+	Action string `json:"action" db:"action"`
+
+	// The type of the mutated resource, for example "oauth2_client".
+	ResourceType string `json:"resource_type" db:"resource_type"`
+
+	// The ID of the mutated resource.
+	ResourceID string `json:"resource_id" db:"resource_id"`
+
+	// The state of the resource before the mutation. Empty if the resource was created.
+	Before sqlxx.NullJSONRawMessage `json:"before" db:"before_state"`
+
+	// The state of the resource after the mutation. Empty if the resource was deleted.
+	After sqlxx.NullJSONRawMessage `json:"after" db:"after_state"`
+}
+
+func (Event) TableName() string {
+	return "hydra_audit_event"
+}
+
+// PageToken encodes the position of the event in a list ordered by created_at (newest first) and id.
+func (e Event) PageToken() keysetpagination.PageToken {
+	return keysetpagination.MapPageToken{
+		"id":         e.ID.String(),
+		"created_at": e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+type Filter struct {
+	// The pagination options, see x.ParsePagination.
+	PageOpts []keysetpagination.Option
+
+	Actor        string
+	ResourceType string
+	ResourceID   string
+}
+
+type Manager interface {
+	CreateAuditEvent(ctx context.Context, e *Event) error
+	GetAuditEvents(ctx context.Context, filters Filter) ([]Event, *keysetpagination.Paginator, error)
+
+	// FlushAuditEvents deletes the audit events which were created before notAfter.
+	FlushAuditEvents(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+}
@@ -0,0 +1,139 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/adminauth"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/sqlxx"
+)
+
+// Recorder records mutations performed through the admin API.
+type Recorder struct {
+	r InternalRegistry
+}
+
+func NewRecorder(r InternalRegistry) *Recorder {
+	return &Recorder{r: r}
+}
+
+// Record records a mutation of the given resource. Before and after are the states of the resource prior to and
+// following the mutation and must not contain any secrets. They may be nil if the resource did not exist before or
+// has been removed.
+//
+// The mutation has already been applied when Record is called, which is why errors are logged but not returned.
+func (rec *Recorder) Record(r *http.Request, action, resourceType, resourceID string, before, after interface{}) {
+	ctx := r.Context()
+	sl := rec.r.SecurityLog()
+	if !rec.r.Config().AuditEnabled(ctx) && sl == nil {
+		return
+	}
+
+	e, err := rec.newEvent(r, action, resourceType, resourceID, before, after)
+	if err != nil {
+		rec.r.Logger().WithRequest(r).WithError(err).Error("Unable to create the audit event.")
+		return
+	}
+
+	if sl != nil {
+		sl.logMutation(e)
+	}
+	if !rec.r.Config().AuditEnabled(ctx) {
+		return
+	}
+
+	if err := rec.r.AuditManager().CreateAuditEvent(ctx, e); err != nil {
+		rec.r.Logger().WithRequest(r).WithError(err).Error("Unable to store the audit event.")
+	}
+
+	if sink := rec.r.Config().AuditSinkConfig(ctx); sink != nil {
+		go func() {
+			if err := rec.send(context.WithoutCancel(ctx), sink, e); err != nil {
+				rec.r.Logger().WithError(err).WithField("audit_event_id", e.ID).Error("Unable to send the audit event to the audit sink.")
+			}
+		}()
+	}
+}
+
+func (rec *Recorder) newEvent(r *http.Request, action, resourceType, resourceID string, before, after interface{}) (*Event, error) {
+	e := &Event{
+		ID:           uuid.Must(uuid.NewV4()),
+		CreatedAt:    time.Now().UTC().Round(time.Second),
+		SourceIP:     x.ClientIP(r),
+		RequestID:    r.Header.Get("X-Request-Id"),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+	}
+
+	// The actor header is sent by the caller, so it must not override the authenticated principal.
+	if p := adminauth.PrincipalFromContext(r.Context()); p != nil {
+		e.Actor = p.ID
+	} else if header := rec.r.Config().AuditActorHeader(r.Context()); header != "" {
+		e.Actor = r.Header.Get(header)
+	}
+
+	var err error
+	if e.Before, err = state(before); err != nil {
+		return nil, err
+	}
+	if e.After, err = state(after); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func state(v interface{}) (sqlxx.NullJSONRawMessage, error) {
+	if v == nil {
+		return nil, nil
+	} else if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, nil
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+func (rec *Recorder) send(ctx context.Context, sink *config.HookConfig, e *Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := sink.Auth.Apply(req.Request); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	res, err := rec.r.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("the audit sink responded with status code %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+)
+
+type InternalRegistry interface {
+	x.RegistryWriter
+	x.RegistryLogger
+	x.HTTPClientProvider
+	config.Provider
+	Registry
+}
+
+type Registry interface {
+	AuditManager() Manager
+	AuditRecorder() *Recorder
+
+	// SecurityLog returns the security audit log, or nil if it is disabled.
+	SecurityLog() *SecurityLog
+}
@@ -0,0 +1,288 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/logrusx"
+)
+
+const (
+	SecurityLogSchemaVersion = "3"
+
+	SecurityCategoryAuthentication = "authentication"
+	SecurityCategoryAuthorization  = "authorization"
+	SecurityCategoryToken          = "token"
+	SecurityCategoryRevocation     = "revocation"
+	SecurityCategoryAdmin          = "admin"
+
+	SecurityOutcomeSuccess = "success"
+	SecurityOutcomeFailure = "failure"
+
+	// securityLogBatchSize is the maximum number of entries written to the sinks at once.
+	securityLogBatchSize = 1000
+)
+
+// SecurityLogEntry is an entry of the security audit log. The schema is stable: fields are never renamed or removed,
+// and new fields increase the schema version.
+type SecurityLogEntry struct {
+	SchemaVersion string    `json:"schema_version"`
+	ID            uuid.UUID `json:"id"`
+	Time          time.Time `json:"time"`
+
+	// Category is one of "authentication", "authorization", "token", "revocation" or "admin".
+	Category string `json:"category"`
+
+	// Action describes what happened, for example "login.accepted" or "oauth2_client.create".
+	Action string `json:"action"`
+
+	// Outcome is either "success" or "failure".
+	Outcome string `json:"outcome"`
+
+	// Error is the OAuth 2.0 error code of failures, for example "invalid_grant".
+	Error string `json:"error,omitempty"`
+
+	ClientID     string `json:"client_id,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	GrantType    string `json:"grant_type,omitempty"`
+	Actor        string `json:"actor,omitempty"`
+	SourceIP     string `json:"source_ip,omitempty"`
+	RequestID    string `json:"request_id,omitempty"`
+	ResourceType string `json:"resource_type,omitempty"`
+	ResourceID   string `json:"resource_id,omitempty"`
+
+	// AuthorizationRequest are the parameters of the authorization request of accepted consents. Added in schema
+	// version 2.
+	AuthorizationRequest map[string][]string `json:"authorization_request,omitempty"`
+
+	// GrantID identifies the grant of issued tokens, which all tokens issued by refreshing the grant share. Added in
+	// schema version 3.
+	GrantID string `json:"grant_id,omitempty"`
+
+	// GrantChain are the grant types with which the tokens of the grant were issued, in order. Added in schema
+	// version 3.
+	GrantChain []string `json:"grant_chain,omitempty"`
+}
+
+// SecurityLogSink writes batches of entries to a destination.
+type SecurityLogSink interface {
+	Write(ctx context.Context, entries []SecurityLogEntry) error
+	Close() error
+}
+
+type securityEventKind struct {
+	category, action, outcome string
+}
+
+// securityEvents maps the events which are relevant for the security audit log to their category and action. Client
+// mutations are recorded by the Recorder instead, and introspections are too frequent to be recorded.
+var securityEvents = map[string]securityEventKind{
+	string(events.LoginAccepted):              {SecurityCategoryAuthentication, "login.accepted", SecurityOutcomeSuccess},
+	string(events.LoginRejected):              {SecurityCategoryAuthentication, "login.rejected", SecurityOutcomeFailure},
+	string(events.LogoutAccepted):             {SecurityCategoryAuthentication, "logout.accepted", SecurityOutcomeSuccess},
+	string(events.LogoutRejected):             {SecurityCategoryAuthentication, "logout.rejected", SecurityOutcomeFailure},
+	string(events.ClientAuthenticationFailed): {SecurityCategoryAuthentication, "client_authentication.failed", SecurityOutcomeFailure},
+	string(events.ConsentAccepted):            {SecurityCategoryAuthorization, "consent.accepted", SecurityOutcomeSuccess},
+	string(events.ConsentRejected):            {SecurityCategoryAuthorization, "consent.rejected", SecurityOutcomeFailure},
+	string(events.AccessTokenIssued):          {SecurityCategoryToken, "access_token.issued", SecurityOutcomeSuccess},
+	string(events.RefreshTokenIssued):         {SecurityCategoryToken, "refresh_token.issued", SecurityOutcomeSuccess},
+	string(events.IdentityTokenIssued):        {SecurityCategoryToken, "id_token.issued", SecurityOutcomeSuccess},
+	string(events.TokenExchangeError):         {SecurityCategoryToken, "token.denied", SecurityOutcomeFailure},
+	string(events.RefreshTokenReused):         {SecurityCategoryToken, "refresh_token.reused", SecurityOutcomeFailure},
+	string(events.AuthorizationCodeReused):    {SecurityCategoryToken, "authorization_code.reused", SecurityOutcomeFailure},
+	string(events.AccessTokenRevoked):         {SecurityCategoryRevocation, "token.revoked", SecurityOutcomeSuccess},
+	string(events.ConsentRevoked):             {SecurityCategoryRevocation, "consent.revoked", SecurityOutcomeSuccess},
+}
+
+// SecurityLog is the security audit log. It receives the security relevant events and the admin API mutations,
+// redacts them, and writes them to the sinks in the background.
+type SecurityLog struct {
+	sinks         []SecurityLogSink
+	l             *logrusx.Logger
+	entries       chan SecurityLogEntry
+	flushInterval time.Duration
+	redact        map[string]bool
+	hash          bool
+}
+
+var _ events.Emitter = (*SecurityLog)(nil)
+
+// NewSecurityLog returns a security audit log which writes to the given sinks. Redact lists the JSON names of the
+// fields to redact, which are hashed instead of masked if hash is true. Run must be called to write the entries.
+func NewSecurityLog(sinks []SecurityLogSink, l *logrusx.Logger, bufferSize int, flushInterval time.Duration, redact []string, hash bool) *SecurityLog {
+	s := &SecurityLog{
+		sinks:         sinks,
+		l:             l,
+		entries:       make(chan SecurityLogEntry, bufferSize),
+		flushInterval: flushInterval,
+		redact:        make(map[string]bool, len(redact)),
+		hash:          hash,
+	}
+	for _, f := range redact {
+		s.redact[f] = true
+	}
+	return s
+}
+
+// Emit records the event if it is security relevant.
+func (s *SecurityLog) Emit(ctx context.Context, e events.Event) {
+	kind, ok := securityEvents[e.Type]
+	if !ok {
+		return
+	}
+
+	entry := SecurityLogEntry{
+		ID:         e.ID,
+		Time:       e.Time,
+		Category:   kind.category,
+		Action:     kind.action,
+		Outcome:    kind.outcome,
+		ClientID:   stringAttribute(e, events.AttributeKeyOAuth2ClientID),
+		Subject:    stringAttribute(e, events.AttributeKeyOAuth2Subject),
+		GrantType:  stringAttribute(e, events.AttributeKeyOAuth2GrantType),
+		Error:      stringAttribute(e, events.AttributeKeyOAuth2Error),
+		GrantID:    stringAttribute(e, events.AttributeKeyOAuth2GrantID),
+		GrantChain: stringsAttribute(e, events.AttributeKeyOAuth2GrantChain),
+	}
+	if params := stringAttribute(e, events.AttributeKeyOAuth2AuthorizationRequest); params != "" {
+		if err := json.Unmarshal([]byte(params), &entry.AuthorizationRequest); err != nil {
+			s.l.WithError(err).Warn("Unable to decode the authorization request of the security event.")
+		}
+	}
+	if entry.Error != "" {
+		entry.Outcome = SecurityOutcomeFailure
+	}
+	if r := events.RequestFromContext(ctx); r != nil {
+		entry.SourceIP = x.ClientIP(r)
+		entry.RequestID = r.Header.Get("X-Request-Id")
+	}
+	s.Log(entry)
+}
+
+// Log enqueues the entry. The entry is dropped if the buffer is full, so that the security audit log never blocks
+// the request which caused the entry.
+func (s *SecurityLog) Log(entry SecurityLogEntry) {
+	entry.SchemaVersion = SecurityLogSchemaVersion
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.Must(uuid.NewV4())
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	s.redactEntry(&entry)
+
+	select {
+	case s.entries <- entry:
+	default:
+		s.l.WithField("security_log_entry_id", entry.ID).WithField("action", entry.Action).
+			Warn("The security audit log buffer is full, dropping the entry.")
+	}
+}
+
+// logMutation records a mutation performed through the admin API.
+func (s *SecurityLog) logMutation(e *Event) {
+	s.Log(SecurityLogEntry{
+		ID:           e.ID,
+		Time:         e.CreatedAt,
+		Category:     SecurityCategoryAdmin,
+		Action:       e.ResourceType + "." + e.Action,
+		Outcome:      SecurityOutcomeSuccess,
+		Actor:        e.Actor,
+		SourceIP:     e.SourceIP,
+		RequestID:    e.RequestID,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+	})
+}
+
+func (s *SecurityLog) redactEntry(entry *SecurityLogEntry) {
+	if s.redact["authorization_request"] {
+		// The parameters are removed instead of hashed, because a hash of all parameters is of no use.
+		entry.AuthorizationRequest = nil
+	}
+	for field, value := range map[string]*string{
+		"subject":     &entry.Subject,
+		"client_id":   &entry.ClientID,
+		"actor":       &entry.Actor,
+		"source_ip":   &entry.SourceIP,
+		"request_id":  &entry.RequestID,
+		"resource_id": &entry.ResourceID,
+	} {
+		if !s.redact[field] || *value == "" {
+			continue
+		}
+		if s.hash {
+			sum := sha256.Sum256([]byte(*value))
+			*value = "sha256:" + hex.EncodeToString(sum[:])
+		} else {
+			*value = "[REDACTED]"
+		}
+	}
+}
+
+// Run writes the entries to the sinks until the context is canceled. Buffered entries are written and the sinks are
+// closed before Run returns.
+func (s *SecurityLog) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	writeCtx := context.WithoutCancel(ctx)
+	var batch []SecurityLogEntry
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, sink := range s.sinks {
+			if err := sink.Write(writeCtx, batch); err != nil {
+				s.l.WithError(err).WithField("entries", len(batch)).Error("Unable to write the security audit log.")
+			}
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case e := <-s.entries:
+			if batch = append(batch, e); len(batch) >= securityLogBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case e := <-s.entries:
+					batch = append(batch, e)
+				default:
+					flush()
+					for _, sink := range s.sinks {
+						if err := sink.Close(); err != nil {
+							s.l.WithError(err).Error("Unable to close the security audit log.")
+						}
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func stringAttribute(e events.Event, key string) string {
+	v, _ := e.Attributes[key].(string)
+	return v
+}
+
+func stringsAttribute(e events.Event, key string) []string {
+	v, _ := e.Attributes[key].([]string)
+	return v
+}
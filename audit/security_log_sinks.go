@@ -0,0 +1,124 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/driver/config"
+)
+
+// NewSecurityLogSinks returns the configured sinks of the security audit log.
+func NewSecurityLogSinks(sinks []config.SecurityLogSink, client *retryablehttp.Client) ([]SecurityLogSink, error) {
+	result := make([]SecurityLogSink, 0, len(sinks))
+	for _, c := range sinks {
+		var sink SecurityLogSink
+		var err error
+		switch c.Type {
+		case "file":
+			sink, err = newFileSink(c.Path)
+		case "syslog":
+			tag := c.Tag
+			if tag == "" {
+				tag = "hydra"
+			}
+			sink, err = newSyslogSink(c.Network, c.Address, tag)
+		case "http":
+			sink = &httpSink{client: client, url: c.URL, auth: c.Auth}
+		default:
+			err = errors.Errorf("unknown security audit log sink type %q", c.Type)
+		}
+		if err != nil {
+			for _, s := range result {
+				_ = s.Close()
+			}
+			return nil, err
+		}
+		result = append(result, sink)
+	}
+	return result, nil
+}
+
+// fileSink appends one JSON object per line to a file.
+type fileSink struct {
+	sync.Mutex
+	f *os.File
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	if path == "" {
+		return nil, errors.New("the file sink of the security audit log requires a path")
+	}
+	// #nosec G302 G304 -- the path is configured by the operator, and log shippers need to read the file.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &fileSink{f: f}, nil
+}
+
+func (s *fileSink) Write(_ context.Context, entries []SecurityLogEntry) error {
+	s.Lock()
+	defer s.Unlock()
+
+	w := bufio.NewWriter(s.f)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(w.Flush())
+}
+
+func (s *fileSink) Close() error {
+	return errors.WithStack(s.f.Close())
+}
+
+// httpSink posts batches of entries as a JSON array.
+type httpSink struct {
+	client *retryablehttp.Client
+	url    string
+	auth   *config.Auth
+}
+
+func (s *httpSink) Write(ctx context.Context, entries []SecurityLogEntry) error {
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := s.auth.Apply(req.Request); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("the security audit log endpoint responded with status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	return nil
+}
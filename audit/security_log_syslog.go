@@ -0,0 +1,50 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/syslog"
+
+	"github.com/pkg/errors"
+)
+
+// syslogSink sends every entry as a JSON object to a syslog server. Failures are logged with severity warning,
+// everything else with severity info.
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func newSyslogSink(network, address, tag string) (*syslogSink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_AUTHPRIV|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) Write(_ context.Context, entries []SecurityLogEntry) error {
+	for _, e := range entries {
+		msg, err := json.Marshal(e)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if e.Outcome == SecurityOutcomeFailure {
+			err = s.w.Warning(string(msg))
+		} else {
+			err = s.w.Info(string(msg))
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	return errors.WithStack(s.w.Close())
+}
@@ -0,0 +1,14 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows || plan9
+
+package audit
+
+import (
+	"github.com/pkg/errors"
+)
+
+func newSyslogSink(string, string, string) (SecurityLogSink, error) {
+	return nil, errors.New("the syslog sink of the security audit log is not supported on this platform")
+}
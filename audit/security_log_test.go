@@ -0,0 +1,258 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/logrusx"
+)
+
+func readSecurityLog(t *testing.T, path string) []audit.SecurityLogEntry {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []audit.SecurityLogEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var e audit.SecurityLogEntry
+		require.NoError(t, json.Unmarshal(s.Bytes(), &e))
+		entries = append(entries, e)
+	}
+	require.NoError(t, s.Err())
+	return entries
+}
+
+func TestSecurityLog(t *testing.T) {
+	l := logrusx.New("", "")
+
+	newLog := func(t *testing.T, redact []string, hash bool) (*audit.SecurityLog, string) {
+		path := filepath.Join(t.TempDir(), "security.log")
+		sinks, err := audit.NewSecurityLogSinks([]config.SecurityLogSink{{Type: "file", Path: path}}, nil)
+		require.NoError(t, err)
+		return audit.NewSecurityLog(sinks, l, 10, time.Hour, redact, hash), path
+	}
+
+	run := func(t *testing.T, sl *audit.SecurityLog, emit func(ctx context.Context)) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			sl.Run(ctx)
+			close(done)
+		}()
+
+		r := httptest.NewRequest(http.MethodPost, "/oauth2/token", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("X-Request-Id", "request-id")
+		events.Middleware(sl)(httptest.NewRecorder(), r, func(_ http.ResponseWriter, r *http.Request) {
+			emit(r.Context())
+		})
+
+		cancel()
+		<-done
+	}
+
+	t.Run("case=records security events", func(t *testing.T) {
+		sl, path := newLog(t, nil, false)
+		run(t, sl, func(ctx context.Context) {
+			events.Trace(ctx, events.AccessTokenIssued, events.WithClientID("client"), events.WithSubject("alice"), events.WithGrantType("authorization_code"))
+			events.Trace(ctx, events.TokenExchangeError, events.WithClientID("client"), events.WithError(fosite.ErrInvalidGrant))
+			events.Trace(ctx, events.AccessTokenInspected, events.WithClientID("client"))
+		})
+
+		entries := readSecurityLog(t, path)
+		require.Len(t, entries, 2)
+
+		assert.Equal(t, audit.SecurityLogSchemaVersion, entries[0].SchemaVersion)
+		assert.Equal(t, audit.SecurityCategoryToken, entries[0].Category)
+		assert.Equal(t, "access_token.issued", entries[0].Action)
+		assert.Equal(t, audit.SecurityOutcomeSuccess, entries[0].Outcome)
+		assert.Equal(t, "client", entries[0].ClientID)
+		assert.Equal(t, "alice", entries[0].Subject)
+		assert.Equal(t, "authorization_code", entries[0].GrantType)
+		assert.Equal(t, "192.0.2.1", entries[0].SourceIP)
+		assert.Equal(t, "request-id", entries[0].RequestID)
+
+		assert.Equal(t, "token.denied", entries[1].Action)
+		assert.Equal(t, audit.SecurityOutcomeFailure, entries[1].Outcome)
+		assert.Equal(t, "invalid_grant", entries[1].Error)
+	})
+
+	t.Run("case=records the authorization request of accepted consents", func(t *testing.T) {
+		params := map[string][]string{"scope": {"openid"}, "client_id": {"client"}}
+
+		sl, path := newLog(t, nil, false)
+		run(t, sl, func(ctx context.Context) {
+			events.Trace(ctx, events.ConsentAccepted, events.WithClientID("client"), events.WithAuthorizationRequest(params))
+		})
+		entries := readSecurityLog(t, path)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "consent.accepted", entries[0].Action)
+		assert.Equal(t, params, entries[0].AuthorizationRequest)
+
+		sl, path = newLog(t, []string{"authorization_request"}, true)
+		run(t, sl, func(ctx context.Context) {
+			events.Trace(ctx, events.ConsentAccepted, events.WithClientID("client"), events.WithAuthorizationRequest(params))
+		})
+		entries = readSecurityLog(t, path)
+		require.Len(t, entries, 1)
+		assert.Nil(t, entries[0].AuthorizationRequest)
+	})
+
+	t.Run("case=records the grant of issued tokens", func(t *testing.T) {
+		sl, path := newLog(t, nil, false)
+		run(t, sl, func(ctx context.Context) {
+			events.Trace(ctx, events.RefreshTokenIssued, events.WithGrant(&fosite.Request{
+				ID:      "grant",
+				Session: &oauth2.Session{GrantChain: []string{"authorization_code", "refresh_token"}},
+			}))
+		})
+		entries := readSecurityLog(t, path)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "grant", entries[0].GrantID)
+		assert.Equal(t, []string{"authorization_code", "refresh_token"}, entries[0].GrantChain)
+	})
+
+	t.Run("case=masks redacted fields", func(t *testing.T) {
+		sl, path := newLog(t, []string{"subject", "source_ip"}, false)
+		run(t, sl, func(ctx context.Context) {
+			events.Trace(ctx, events.LoginAccepted, events.WithClientID("client"), events.WithSubject("alice"))
+		})
+
+		entries := readSecurityLog(t, path)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "[REDACTED]", entries[0].Subject)
+		assert.Equal(t, "[REDACTED]", entries[0].SourceIP)
+		assert.Equal(t, "client", entries[0].ClientID)
+	})
+
+	t.Run("case=hashes redacted fields", func(t *testing.T) {
+		sl, path := newLog(t, []string{"subject"}, true)
+		run(t, sl, func(ctx context.Context) {
+			events.Trace(ctx, events.LoginAccepted, events.WithSubject("alice"))
+		})
+
+		sum := sha256.Sum256([]byte("alice"))
+		entries := readSecurityLog(t, path)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), entries[0].Subject)
+	})
+
+	t.Run("case=drops entries if the buffer is full", func(t *testing.T) {
+		sl, path := newLog(t, nil, false)
+		for i := 0; i < 20; i++ {
+			sl.Log(audit.SecurityLogEntry{Category: audit.SecurityCategoryAdmin, Action: "test"})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		sl.Run(ctx)
+		assert.Len(t, readSecurityLog(t, path), 10)
+	})
+
+	t.Run("case=posts entries to the http sink", func(t *testing.T) {
+		received := make(chan []audit.SecurityLogEntry, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			var entries []audit.SecurityLogEntry
+			require.NoError(t, json.Unmarshal(body, &entries))
+			received <- entries
+		}))
+		t.Cleanup(ts.Close)
+
+		sinks, err := audit.NewSecurityLogSinks([]config.SecurityLogSink{{
+			Type: "http",
+			URL:  ts.URL,
+			Auth: &config.Auth{Type: "api_key", Config: config.AuthConfig{In: "header", Name: "Authorization", Value: "Bearer secret"}},
+		}}, retryablehttp.NewClient())
+		require.NoError(t, err)
+
+		sl := audit.NewSecurityLog(sinks, l, 10, time.Hour, nil, false)
+		sl.Log(audit.SecurityLogEntry{Category: audit.SecurityCategoryRevocation, Action: "token.revoked"})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		sl.Run(ctx)
+
+		entries := <-received
+		require.Len(t, entries, 1)
+		assert.Equal(t, "token.revoked", entries[0].Action)
+	})
+
+	t.Run("case=rejects unknown sinks", func(t *testing.T) {
+		_, err := audit.NewSecurityLogSinks([]config.SecurityLogSink{{Type: "carrier-pigeon"}}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestSecurityLogRecordsAdminActions(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "security.log")
+
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyAuditSecurityLogEnabled, true)
+	conf.MustSet(ctx, config.KeyAuditSecurityLogSinks, []map[string]interface{}{{"type": "file", "path": path}})
+	conf.MustSet(ctx, config.KeyAuditSecurityLogFlushInterval, "10ms")
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	require.NotNil(t, reg.SecurityLog())
+
+	admin := x.NewRouterAdmin(conf.AdminURL)
+	reg.RegisterRoutes(ctx, admin, x.NewRouterPublic())
+	ts := httptest.NewServer(admin)
+	t.Cleanup(ts.Close)
+
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/admin"+client.ClientsHandlerPath+"/does-not-exist", nil)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	c := &client.Client{Name: "security-log"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+	req, err = http.NewRequest(http.MethodDelete, ts.URL+"/admin"+client.ClientsHandlerPath+"/"+c.GetID(), nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "request-id")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	var entries []audit.SecurityLogEntry
+	require.Eventually(t, func() bool {
+		entries = readSecurityLog(t, path)
+		return len(entries) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, audit.SecurityCategoryAdmin, entries[0].Category)
+	assert.Equal(t, "oauth2_client.delete", entries[0].Action)
+	assert.Equal(t, c.GetID(), entries[0].ResourceID)
+	assert.Equal(t, "request-id", entries[0].RequestID)
+}
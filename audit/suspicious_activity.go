@@ -0,0 +1,192 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/logrusx"
+)
+
+const (
+	DetectionRefreshTokenReuse                = "refresh_token_reuse"
+	DetectionAuthorizationCodeReplay          = "authorization_code_replay"
+	DetectionClientAuthenticationFailureBurst = "client_authentication_failure_burst"
+
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+
+	// maxTrackedFailureSources bounds the memory used to count failed client authentications, because attackers
+	// control the client IDs and, to some degree, the IP addresses.
+	maxTrackedFailureSources = 10000
+)
+
+// Detection is the payload sent to the suspicious activity webhook.
+type Detection struct {
+	ID   uuid.UUID `json:"id"`
+	Time time.Time `json:"time"`
+
+	// Type is one of "refresh_token_reuse", "authorization_code_replay" or "client_authentication_failure_burst".
+	Type string `json:"type"`
+
+	// Severity is either "high" or "medium".
+	Severity string `json:"severity"`
+
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	SourceIP  string `json:"source_ip,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// Count and WindowSeconds are the number of failed client authentications and the window they were counted in.
+	// They are only set for bursts of failed client authentications.
+	Count         int `json:"count,omitempty"`
+	WindowSeconds int `json:"window_seconds,omitempty"`
+}
+
+type failureWindow struct {
+	start time.Time
+	count int
+}
+
+// SuspiciousActivity detects suspicious activity in the events and sends every detection to a webhook. Refresh token
+// reuse and authorization code replay are reported immediately, failed client authentications once they exceed the
+// threshold within the window, per client and per IP address.
+type SuspiciousActivity struct {
+	client    *retryablehttp.Client
+	hook      *config.HookConfig
+	l         *logrusx.Logger
+	threshold int
+	window    time.Duration
+	now       func() time.Time
+
+	sync.Mutex
+	failures map[string]*failureWindow
+}
+
+var _ events.Emitter = (*SuspiciousActivity)(nil)
+
+func NewSuspiciousActivity(client *retryablehttp.Client, hook *config.HookConfig, l *logrusx.Logger, threshold int, window time.Duration) *SuspiciousActivity {
+	return &SuspiciousActivity{
+		client:    client,
+		hook:      hook,
+		l:         l,
+		threshold: threshold,
+		window:    window,
+		now:       time.Now,
+		failures:  make(map[string]*failureWindow),
+	}
+}
+
+// Emit inspects the event and reports it if it is suspicious.
+func (s *SuspiciousActivity) Emit(ctx context.Context, e events.Event) {
+	d := Detection{
+		ClientID: stringAttribute(e, events.AttributeKeyOAuth2ClientID),
+		Subject:  stringAttribute(e, events.AttributeKeyOAuth2Subject),
+	}
+	if r := events.RequestFromContext(ctx); r != nil {
+		d.SourceIP = x.ClientIP(r)
+		d.RequestID = r.Header.Get("X-Request-Id")
+	}
+
+	switch e.Type {
+	case string(events.RefreshTokenReused):
+		d.Type, d.Severity = DetectionRefreshTokenReuse, SeverityHigh
+	case string(events.AuthorizationCodeReused):
+		d.Type, d.Severity = DetectionAuthorizationCodeReplay, SeverityHigh
+	case string(events.ClientAuthenticationFailed):
+		count := s.countFailure(d.ClientID, d.SourceIP)
+		if count == 0 {
+			return
+		}
+		d.Type, d.Severity = DetectionClientAuthenticationFailureBurst, SeverityMedium
+		d.Count, d.WindowSeconds = count, int(s.window.Seconds())
+	default:
+		return
+	}
+
+	d.ID = uuid.Must(uuid.NewV4())
+	d.Time = s.now().UTC()
+	go func() {
+		if err := s.send(context.WithoutCancel(ctx), &d); err != nil {
+			s.l.WithError(err).WithField("detection_id", d.ID).WithField("detection_type", d.Type).
+				Error("Unable to send the detection to the suspicious activity webhook.")
+		}
+	}()
+}
+
+// countFailure counts a failed client authentication of the client from the IP address. It returns the number of
+// failures within the window when the threshold is reached for the client or the IP address, and zero otherwise, so
+// that every burst is reported once.
+func (s *SuspiciousActivity) countFailure(clientID, sourceIP string) int {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	if len(s.failures) >= maxTrackedFailureSources {
+		for k, w := range s.failures {
+			if now.Sub(w.start) > s.window {
+				delete(s.failures, k)
+			}
+		}
+	}
+
+	reported := 0
+	for _, key := range []string{"client:" + clientID, "ip:" + sourceIP} {
+		if key == "client:" || key == "ip:" {
+			continue
+		}
+
+		w, ok := s.failures[key]
+		if !ok || now.Sub(w.start) > s.window {
+			if !ok && len(s.failures) >= maxTrackedFailureSources {
+				continue
+			}
+			w = &failureWindow{start: now}
+			s.failures[key] = w
+		}
+		if w.count++; w.count == s.threshold {
+			reported = w.count
+		}
+	}
+	return reported
+}
+
+func (s *SuspiciousActivity) send(ctx context.Context, d *Detection) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, s.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := s.hook.Auth.Apply(req.Request); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("the suspicious activity webhook responded with status code %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/logrusx"
+)
+
+func newDetectionServer(t *testing.T) (*httptest.Server, chan audit.Detection) {
+	detections := make(chan audit.Detection, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var d audit.Detection
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&d))
+		detections <- d
+	}))
+	t.Cleanup(ts.Close)
+	return ts, detections
+}
+
+func expectDetection(t *testing.T, detections chan audit.Detection) audit.Detection {
+	select {
+	case d := <-detections:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a detection")
+		return audit.Detection{}
+	}
+}
+
+func expectNoDetection(t *testing.T, detections chan audit.Detection) {
+	select {
+	case d := <-detections:
+		t.Fatalf("unexpected detection: %+v", d)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSuspiciousActivity(t *testing.T) {
+	ts, detections := newDetectionServer(t)
+	hook := &config.HookConfig{
+		URL:  ts.URL,
+		Auth: &config.Auth{Type: "api_key", Config: config.AuthConfig{In: "header", Name: "Authorization", Value: "Bearer secret"}},
+	}
+	sa := audit.NewSuspiciousActivity(retryablehttp.NewClient(), hook, logrusx.New("", ""), 3, time.Hour)
+
+	emit := func(sourceIP string, event events.Event) {
+		r := httptest.NewRequest(http.MethodPost, "/oauth2/token", nil)
+		r.RemoteAddr = sourceIP
+		r.Header.Set("X-Request-Id", "request-id")
+		events.Middleware(sa)(httptest.NewRecorder(), r, func(_ http.ResponseWriter, r *http.Request) {
+			events.Trace(r.Context(), events.RefreshTokenIssued) // not suspicious
+			sa.Emit(r.Context(), event)
+		})
+	}
+	event := func(eventType string, clientID string) events.Event {
+		return events.Event{Type: eventType, Attributes: map[string]interface{}{
+			events.AttributeKeyOAuth2ClientID: clientID,
+			events.AttributeKeyOAuth2Subject:  "alice",
+		}}
+	}
+
+	t.Run("case=reports refresh token reuse", func(t *testing.T) {
+		emit("192.0.2.1", event(string(events.RefreshTokenReused), "client"))
+
+		d := expectDetection(t, detections)
+		assert.Equal(t, audit.DetectionRefreshTokenReuse, d.Type)
+		assert.Equal(t, audit.SeverityHigh, d.Severity)
+		assert.Equal(t, "client", d.ClientID)
+		assert.Equal(t, "alice", d.Subject)
+		assert.Equal(t, "192.0.2.1", d.SourceIP)
+		assert.Equal(t, "request-id", d.RequestID)
+		assert.NotEmpty(t, d.ID)
+	})
+
+	t.Run("case=ignores other events", func(t *testing.T) {
+		emit("192.0.2.1", event(string(events.AccessTokenIssued), "client"))
+		expectNoDetection(t, detections)
+	})
+
+	t.Run("case=reports bursts of failed client authentications of the same client once", func(t *testing.T) {
+		for i, ip := range []string{"192.0.2.10", "192.0.2.11", "192.0.2.12", "192.0.2.13"} {
+			emit(ip, event(string(events.ClientAuthenticationFailed), "burst-client"))
+			if i == 2 {
+				d := expectDetection(t, detections)
+				assert.Equal(t, audit.DetectionClientAuthenticationFailureBurst, d.Type)
+				assert.Equal(t, audit.SeverityMedium, d.Severity)
+				assert.Equal(t, "burst-client", d.ClientID)
+				assert.Equal(t, 3, d.Count)
+				assert.Equal(t, 3600, d.WindowSeconds)
+			}
+		}
+		expectNoDetection(t, detections)
+	})
+
+	t.Run("case=reports bursts of failed client authentications from the same IP address", func(t *testing.T) {
+		for _, id := range []string{"a", "b", "c"} {
+			emit("192.0.2.20", event(string(events.ClientAuthenticationFailed), id))
+		}
+		d := expectDetection(t, detections)
+		assert.Equal(t, audit.DetectionClientAuthenticationFailureBurst, d.Type)
+		assert.Equal(t, "192.0.2.20", d.SourceIP)
+	})
+}
+
+func TestSuspiciousActivityDetectsAuthorizationCodeReplay(t *testing.T) {
+	ctx := context.Background()
+	ts, detections := newDetectionServer(t)
+
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyAuditSuspiciousActivityWebhook, map[string]interface{}{
+		"url":  ts.URL,
+		"auth": map[string]interface{}{"type": "api_key", "config": map[string]interface{}{"in": "header", "name": "Authorization", "value": "Bearer secret"}},
+	})
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	require.NotNil(t, reg.EventEmitter())
+	ctx = events.WithEmitter(ctx, reg.EventEmitter())
+
+	cl := &client.Client{ID: "replay-client"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+	require.NoError(t, reg.OAuth2Storage().CreateAuthorizeCodeSession(ctx, "code", &fosite.Request{
+		ID:          "replay",
+		RequestedAt: time.Now().UTC().Round(time.Second),
+		Client:      cl,
+		Session:     oauth2.NewSession("alice"),
+	}))
+
+	_, err := reg.OAuth2Storage().GetAuthorizeCodeSession(ctx, "code", oauth2.NewSession(""))
+	require.NoError(t, err)
+	require.NoError(t, reg.OAuth2Storage().InvalidateAuthorizeCodeSession(ctx, "code"))
+	expectNoDetection(t, detections)
+
+	_, err = reg.OAuth2Storage().GetAuthorizeCodeSession(ctx, "code", oauth2.NewSession(""))
+	require.ErrorIs(t, err, fosite.ErrInvalidatedAuthorizeCode)
+
+	d := expectDetection(t, detections)
+	assert.Equal(t, audit.DetectionAuthorizationCodeReplay, d.Type)
+	assert.Equal(t, "replay-client", d.ClientID)
+	assert.Equal(t, "alice", d.Subject)
+}
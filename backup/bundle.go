@@ -0,0 +1,229 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+)
+
+// BundleVersion is the version of the bundle format written by this version of Ory Hydra. Version 2 derives the key
+// which encrypts the JSON Web Keys with scrypt instead of SHA-256.
+const BundleVersion = 2
+
+// MinEncryptionSecretLength is the minimum length of the secret used to encrypt the JSON Web Keys of a bundle.
+const MinEncryptionSecretLength = 16
+
+// Bundle
+//
+// A bundle contains the OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers of an Ory Hydra
+// instance. It can be used to restore or clone an instance without access to its database.
+//
+// swagger:model backupBundle
+type Bundle struct {
+	// The version of the bundle format.
+	Version int `json:"version"`
+
+	// The time at which the bundle was created.
+	CreatedAt time.Time `json:"created_at"`
+
+	// The OAuth 2.0 Clients.
+	Clients []Client `json:"clients"`
+
+	// The JSON Web Key Sets by set ID. Empty if the keys are encrypted.
+	JSONWebKeySets map[string]*jose.JSONWebKeySet `json:"json_web_key_sets,omitempty"`
+
+	// The JSON Web Key Sets encrypted with a secret chosen at export time.
+	EncryptedJSONWebKeySets string `json:"encrypted_json_web_key_sets,omitempty"`
+
+	// How the key which encrypts the JSON Web Key Sets is derived from the secret.
+	EncryptionKeyDerivation *KeyDerivation `json:"encryption_key_derivation,omitempty"`
+
+	// The trusted JWT grant issuers. Their public keys are part of the JSON Web Key Sets.
+	TrustedJwtGrantIssuers []trust.Grant `json:"trusted_jwt_grant_issuers"`
+}
+
+// Client is an OAuth 2.0 Client whose secret is exported in its hashed form.
+type Client struct {
+	client.Client
+
+	// The hashed client secret. Takes effect only if no client secret is set.
+	HashedSecret string `json:"hashed_client_secret,omitempty"`
+}
+
+func NewClient(c client.Client) Client {
+	hashed := c.Secret
+	c.Secret = ""
+	return Client{Client: c, HashedSecret: hashed}
+}
+
+// KeyDerivation holds the parameters of the scrypt key derivation function, which derives the key that encrypts the
+// JSON Web Key Sets of a bundle from the secret.
+type KeyDerivation struct {
+	// The key derivation function, always "scrypt".
+	Algorithm string `json:"algorithm"`
+
+	// The random salt, which is different for every bundle.
+	Salt []byte `json:"salt"`
+
+	// The CPU and memory cost parameter.
+	N int `json:"n"`
+
+	// The block size parameter.
+	R int `json:"r"`
+
+	// The parallelization parameter.
+	P int `json:"p"`
+}
+
+const (
+	keyDerivationScrypt = "scrypt"
+	scryptN             = 1 << 15
+	scryptR             = 8
+	scryptP             = 1
+	// scryptMaxN limits the cost of importing bundles, whose key derivation parameters are untrusted.
+	scryptMaxN = 1 << 20
+)
+
+func newKeyDerivation() (*KeyDerivation, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return &KeyDerivation{Algorithm: keyDerivationScrypt, Salt: salt, N: scryptN, R: scryptR, P: scryptP}, nil
+}
+
+func (k *KeyDerivation) deriveKey(secret string) ([]byte, error) {
+	if k.Algorithm != keyDerivationScrypt || len(k.Salt) < 16 || k.N > scryptMaxN || k.R < 1 || k.R > 32 || k.P < 1 || k.P > 16 {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The key derivation parameters of the bundle are not supported."))
+	}
+	key, err := scrypt.Key([]byte(secret), k.Salt, k.N, k.R, k.P, 32)
+	if err != nil {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The key derivation parameters of the bundle are not supported.").WithWrap(err))
+	}
+	return key, nil
+}
+
+// JSONWebKeySetIDs returns the IDs of the JSON Web Key Sets in a stable order.
+func (b *Bundle) JSONWebKeySetIDs() []string {
+	ids := make([]string, 0, len(b.JSONWebKeySets))
+	for id := range b.JSONWebKeySets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Validate returns an error if the bundle can not be imported by this version of Ory Hydra.
+func (b *Bundle) Validate() error {
+	if b.Version < 1 || b.Version > BundleVersion {
+		return errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Bundle version %d is not supported, the latest supported version is %d.", b.Version, BundleVersion))
+	}
+	if b.EncryptedJSONWebKeySets != "" {
+		return errorsx.WithStack(herodot.ErrBadRequest.WithReason("The JSON Web Keys of the bundle are encrypted, but no encryption secret was provided."))
+	}
+	for _, c := range b.Clients {
+		if c.ID == "" {
+			return errorsx.WithStack(herodot.ErrBadRequest.WithReason("The bundle contains an OAuth 2.0 Client without a client ID."))
+		}
+	}
+	for _, g := range b.TrustedJwtGrantIssuers {
+		if keys, ok := b.JSONWebKeySets[g.PublicKey.Set]; !ok || len(keys.Key(g.PublicKey.KeyID)) == 0 {
+			return errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("The bundle does not contain the public key of trusted JWT grant issuer %s.", g.ID))
+		}
+	}
+	return nil
+}
+
+// EncryptKeys encrypts the JSON Web Key Sets of the bundle with a key derived from the given secret and a random salt.
+func (b *Bundle) EncryptKeys(ctx context.Context, secret string) error {
+	kd, err := newKeyDerivation()
+	if err != nil {
+		return err
+	}
+	b.EncryptionKeyDerivation = kd
+
+	c, err := b.newCipher(secret)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(b.JSONWebKeySets)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	if b.EncryptedJSONWebKeySets, err = c.Encrypt(ctx, plaintext, b.additionalData()); err != nil {
+		return err
+	}
+	b.JSONWebKeySets = nil
+	return nil
+}
+
+// DecryptKeys decrypts the JSON Web Key Sets of the bundle with the given secret.
+func (b *Bundle) DecryptKeys(ctx context.Context, secret string) error {
+	c, err := b.newCipher(secret)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := c.Decrypt(ctx, b.EncryptedJSONWebKeySets, b.additionalData())
+	if err != nil {
+		return errorsx.WithStack(herodot.ErrBadRequest.WithReason("Unable to decrypt the JSON Web Keys of the bundle. Is the encryption secret correct?").WithWrap(err))
+	}
+
+	if err := json.Unmarshal(plaintext, &b.JSONWebKeySets); err != nil {
+		return errorsx.WithStack(err)
+	}
+	b.EncryptedJSONWebKeySets = ""
+	b.EncryptionKeyDerivation = nil
+	return nil
+}
+
+// additionalData binds the encrypted keys to the bundle version.
+func (b *Bundle) additionalData() []byte {
+	return []byte(fmt.Sprintf("hydra-bundle-v%d", b.Version))
+}
+
+type secretProvider []byte
+
+func (s secretProvider) GetGlobalSecret(context.Context) ([]byte, error) {
+	return s, nil
+}
+
+func (s secretProvider) GetRotatedGlobalSecrets(context.Context) ([][]byte, error) {
+	return nil, nil
+}
+
+func (b *Bundle) newCipher(secret string) (aead.Cipher, error) {
+	if len(secret) < MinEncryptionSecretLength {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("The encryption secret must be at least %d characters long.", MinEncryptionSecretLength))
+	}
+	if b.Version < 2 {
+		// Bundles of version 1 derived the key with a single SHA-256 hash. They can still be imported.
+		return aead.NewXChaCha20Poly1305(secretProvider(x.HashStringSecret(secret))), nil
+	}
+	if b.EncryptionKeyDerivation == nil {
+		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReason("The bundle does not contain the key derivation parameters."))
+	}
+	key, err := b.EncryptionKeyDerivation.deriveKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	return aead.NewXChaCha20Poly1305(secretProvider(key)), nil
+}
@@ -0,0 +1,239 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/httprouterx"
+)
+
+const (
+	ExportPath   = "/backup/export"
+	ImportPath   = "/backup/import"
+	SnapshotPath = "/backup/snapshot"
+)
+
+type Handler struct {
+	r InternalRegistry
+}
+
+func NewHandler(r InternalRegistry) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
+	admin.POST(ExportPath, h.exportBundle)
+	admin.POST(ImportPath, h.importBundle)
+	admin.GET(SnapshotPath, h.snapshotDatabase)
+}
+
+// Export Bundle Request Body
+//
+// swagger:model exportBundleBody
+type ExportBundleBody struct {
+	// If set, the JSON Web Keys of the bundle are encrypted with this secret. It must be at least 16 characters long
+	// and is required to import the bundle.
+	EncryptionSecret string `json:"encryption_secret"`
+}
+
+// Export Bundle Request
+//
+// swagger:parameters exportBundle
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type exportBundle struct {
+	// in: body
+	Body ExportBundleBody
+}
+
+// swagger:route POST /admin/backup/export backup exportBundle
+//
+// # Export a Bundle
+//
+// This endpoint exports all OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers as a versioned
+// bundle. Client secrets are exported in their hashed form. The bundle contains private keys in plain text unless
+// an encryption secret is provided, so handle it with care.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: backupBundle
+//	  default: errorOAuth2
+func (h *Handler) exportBundle(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body ExportBundleBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	b, err := h.r.BackupManager().ExportBundle(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if body.EncryptionSecret != "" {
+		if err := b.EncryptKeys(r.Context(), body.EncryptionSecret); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	h.r.Writer().Write(w, r, b)
+}
+
+// Import Bundle Request Body
+//
+// swagger:model importBundleBody
+type ImportBundleBody struct {
+	// The bundle to import.
+	//
+	// required: true
+	Bundle *Bundle `json:"bundle"`
+
+	// The secret the JSON Web Keys of the bundle were encrypted with, if any.
+	EncryptionSecret string `json:"encryption_secret"`
+}
+
+// Import Bundle Request
+//
+// swagger:parameters importBundle
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type importBundle struct {
+	// in: body
+	Body ImportBundleBody
+}
+
+type importSummary struct {
+	Version                int `json:"version"`
+	Clients                int `json:"clients"`
+	JSONWebKeySets         int `json:"json_web_key_sets"`
+	TrustedJwtGrantIssuers int `json:"trusted_jwt_grant_issuers"`
+}
+
+// swagger:route POST /admin/backup/import backup importBundle
+//
+// # Import a Bundle
+//
+// This endpoint imports a bundle created by the export endpoint in a single transaction. OAuth 2.0 Clients, JSON
+// Web Keys and trusted JWT grant issuers which already exist are replaced, all other resources are left untouched.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) importBundle(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body ImportBundleBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+	if body.Bundle == nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReason("Field 'bundle' must be set.")))
+		return
+	}
+
+	b := body.Bundle
+	if b.EncryptedJSONWebKeySets != "" && body.EncryptionSecret != "" {
+		if err := b.DecryptKeys(r.Context(), body.EncryptionSecret); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	if err := b.Validate(); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.BackupManager().ImportBundle(r.Context(), b); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.AuditRecorder().Record(r, audit.ActionImport, audit.ResourceBundle, "", nil, &importSummary{
+		Version:                b.Version,
+		Clients:                len(b.Clients),
+		JSONWebKeySets:         len(b.JSONWebKeySets),
+		TrustedJwtGrantIssuers: len(b.TrustedJwtGrantIssuers),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:route GET /admin/backup/snapshot backup snapshotDatabase
+//
+// # Snapshot the SQLite Database
+//
+// This endpoint returns a consistent copy of the SQLite database, which can be restored by replacing the database
+// file while Ory Hydra is stopped. The database remains available while the snapshot is taken. The snapshot contains
+// all data including private keys, so handle it with care. This endpoint is only available for SQLite databases.
+//
+//	Produces:
+//	- application/vnd.sqlite3
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) snapshotDatabase(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// The snapshot contains the data of all tenants.
+	if _, ok := tenant.FromContext(r.Context()); ok {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrForbidden.WithReason("Database snapshots are not available for tenants.")))
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "hydra-snapshot-*")
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "hydra.sqlite")
+	if err := h.r.BackupManager().SnapshotDatabase(r.Context(), path); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", `attachment; filename="hydra.sqlite"`)
+	if _, err := io.Copy(w, f); err != nil {
+		h.r.Logger().WithRequest(r).WithError(err).Error("Unable to send the database snapshot.")
+	}
+}
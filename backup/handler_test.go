@@ -0,0 +1,179 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/backup"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+)
+
+func TestBackupHandler(t *testing.T) {
+	ctx := context.Background()
+
+	newServer := func(t *testing.T) (*httptest.Server, driver.Registry) {
+		conf := internal.NewConfigurationWithDefaults()
+		reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+		admin := x.NewRouterAdmin(conf.AdminURL)
+		reg.RegisterRoutes(ctx, admin, x.NewRouterPublic())
+		ts := httptest.NewServer(admin)
+		t.Cleanup(ts.Close)
+		return ts, reg
+	}
+
+	post := func(t *testing.T, url string, body interface{}) *http.Response {
+		var b bytes.Buffer
+		require.NoError(t, json.NewEncoder(&b).Encode(body))
+		res, err := http.Post(url, "application/json", &b)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		return res
+	}
+
+	source, sourceReg := newServer(t)
+
+	require.NoError(t, sourceReg.ClientManager().CreateClient(ctx, &client.Client{ID: "exported-client", Secret: "exported-client-secret", Name: "exported"}))
+	keys, err := sourceReg.KeyManager().GenerateAndPersistKeySet(ctx, "exported-set", "exported-key", "RS256", "sig")
+	require.NoError(t, err)
+
+	issuerKeys, err := jwk.GenerateJWK(ctx, jose.RS256, "issuer-key", "sig")
+	require.NoError(t, err)
+	grant := trust.Grant{
+		ID:        "2d5d4b1c-2e1e-4a44-8e2c-a1c1d0c7b5d3",
+		Issuer:    "https://issuer.example.com",
+		Subject:   "subject",
+		Scope:     []string{"openid"},
+		PublicKey: trust.PublicKey{Set: "https://issuer.example.com", KeyID: "issuer-key"},
+		CreatedAt: time.Now().UTC().Round(time.Second),
+		ExpiresAt: time.Now().UTC().Add(time.Hour).Round(time.Second),
+	}
+	require.NoError(t, sourceReg.GrantManager().CreateGrant(ctx, grant, issuerKeys.Keys[0].Public()))
+
+	export := func(t *testing.T, secret string) *backup.Bundle {
+		res := post(t, source.URL+"/admin"+backup.ExportPath, &backup.ExportBundleBody{EncryptionSecret: secret})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var b backup.Bundle
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&b))
+		return &b
+	}
+
+	t.Run("case=exports all resources", func(t *testing.T) {
+		b := export(t, "")
+		assert.Equal(t, backup.BundleVersion, b.Version)
+		require.Len(t, b.Clients, 1)
+		assert.Equal(t, "exported-client", b.Clients[0].ID)
+		assert.Empty(t, b.Clients[0].Secret)
+		assert.NotEmpty(t, b.Clients[0].HashedSecret)
+		assert.NotEqual(t, "exported-client-secret", b.Clients[0].HashedSecret)
+		require.Contains(t, b.JSONWebKeySets, "exported-set")
+		assert.Equal(t, keys.Keys[0].KeyID, b.JSONWebKeySets["exported-set"].Keys[0].KeyID)
+		assert.False(t, b.JSONWebKeySets["exported-set"].Keys[0].IsPublic())
+		require.Len(t, b.TrustedJwtGrantIssuers, 1)
+		assert.Equal(t, grant.ID, b.TrustedJwtGrantIssuers[0].ID)
+	})
+
+	t.Run("case=imports an encrypted bundle", func(t *testing.T) {
+		b := export(t, "a-sufficiently-long-secret")
+		assert.Empty(t, b.JSONWebKeySets)
+		assert.NotEmpty(t, b.EncryptedJSONWebKeySets)
+
+		target, targetReg := newServer(t)
+
+		res := post(t, target.URL+"/admin"+backup.ImportPath, &backup.ImportBundleBody{Bundle: b})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		res = post(t, target.URL+"/admin"+backup.ImportPath, &backup.ImportBundleBody{Bundle: b, EncryptionSecret: "not-the-right-secret"})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		res = post(t, target.URL+"/admin"+backup.ImportPath, &backup.ImportBundleBody{Bundle: b, EncryptionSecret: "a-sufficiently-long-secret"})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		c, err := targetReg.ClientManager().AuthenticateClient(ctx, "exported-client", []byte("exported-client-secret"))
+		require.NoError(t, err)
+		assert.Equal(t, "exported", c.Name)
+
+		imported, err := targetReg.KeyManager().GetKeySet(ctx, "exported-set")
+		require.NoError(t, err)
+		assert.Equal(t, keys.Keys[0].KeyID, imported.Keys[0].KeyID)
+
+		g, err := targetReg.GrantManager().GetConcreteGrant(ctx, grant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, grant.Issuer, g.Issuer)
+
+		// Importing the same bundle again replaces the existing resources.
+		res = post(t, target.URL+"/admin"+backup.ImportPath, &backup.ImportBundleBody{Bundle: b, EncryptionSecret: "a-sufficiently-long-secret"})
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		_, err = targetReg.GrantManager().GetConcreteGrant(ctx, grant.ID)
+		require.NoError(t, err)
+		n, err := targetReg.ClientManager().CountClients(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("case=derives a different key for every bundle", func(t *testing.T) {
+		first, second := export(t, "a-sufficiently-long-secret"), export(t, "a-sufficiently-long-secret")
+		require.NotNil(t, first.EncryptionKeyDerivation)
+		require.NotNil(t, second.EncryptionKeyDerivation)
+		assert.Equal(t, "scrypt", first.EncryptionKeyDerivation.Algorithm)
+		assert.NotEqual(t, first.EncryptionKeyDerivation.Salt, second.EncryptionKeyDerivation.Salt)
+
+		// The keys of one bundle can not be decrypted with the salt of the other.
+		first.EncryptionKeyDerivation = second.EncryptionKeyDerivation
+		target, _ := newServer(t)
+		res := post(t, target.URL+"/admin"+backup.ImportPath, &backup.ImportBundleBody{Bundle: first, EncryptionSecret: "a-sufficiently-long-secret"})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("case=rejects unsupported bundle versions", func(t *testing.T) {
+		b := export(t, "")
+		b.Version = backup.BundleVersion + 1
+
+		res := post(t, source.URL+"/admin"+backup.ImportPath, &backup.ImportBundleBody{Bundle: b})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("case=snapshots the SQLite database", func(t *testing.T) {
+		res, err := http.Get(source.URL + "/admin" + backup.SnapshotPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/vnd.sqlite3", res.Header.Get("Content-Type"))
+
+		snapshot, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(snapshot, []byte("SQLite format 3\x00")))
+
+		path := filepath.Join(t.TempDir(), "snapshot.sqlite")
+		require.NoError(t, os.WriteFile(path, snapshot, 0o600))
+		db, err := sql.Open("sqlite3", path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM hydra_client WHERE id = ?", "exported-client").Scan(&n))
+		assert.Equal(t, 1, n)
+	})
+}
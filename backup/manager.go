@@ -0,0 +1,22 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"context"
+)
+
+type Manager interface {
+	// ExportBundle returns all OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers stored in the
+	// database. JSON Web Keys stored in a Hardware Security Module are not exported.
+	ExportBundle(ctx context.Context) (*Bundle, error)
+
+	// ImportBundle imports the bundle in a single transaction. Resources which already exist are replaced, all
+	// other resources are left untouched. The JSON Web Keys of the bundle must not be encrypted.
+	ImportBundle(ctx context.Context, b *Bundle) error
+
+	// SnapshotDatabase writes a consistent copy of the database to the file at path, which must not exist yet. It is
+	// only supported for SQLite.
+	SnapshotDatabase(ctx context.Context, path string) error
+}
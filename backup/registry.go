@@ -0,0 +1,20 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package backup
+
+import (
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/x"
+)
+
+type InternalRegistry interface {
+	x.RegistryWriter
+	x.RegistryLogger
+	audit.Registry
+	Registry
+}
+
+type Registry interface {
+	BackupManager() Manager
+}
@@ -4,6 +4,7 @@
 package client
 
 import (
+	"context"
 	"database/sql"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlxx"
 )
 
@@ -127,7 +129,8 @@ type Client struct {
 	// One or more URLs (scheme://host[:port]) which are allowed to make CORS requests
 	// to the /oauth/token endpoint. If this array is empty, the sever's CORS origin configuration (`CORS_ALLOWED_ORIGINS`)
 	// will be used instead. If this array is set, the allowed origins are appended to the server's CORS origin configuration.
-	// Be aware that environment variable `CORS_ENABLED` MUST be set to `true` for this to work.
+	// Be aware that environment variable `CORS_ENABLED` MUST be set to `true` for this to work, unless
+	// `oauth2.client_cors.enabled` is set, which allows only the origins of the client.
 	AllowedCORSOrigins sqlxx.StringSliceJSONFormat `json:"allowed_cors_origins" db:"allowed_cors_origins"`
 
 	// OAuth 2.0 Client Terms of Service URI
@@ -229,6 +232,14 @@ type Client struct {
 	// from this Client MUST be rejected, if not signed with this algorithm.
 	RequestObjectSigningAlgorithm string `json:"request_object_signing_alg,omitempty" db:"request_object_signing_alg" faker:"len=10"`
 
+	// OpenID Connect Request Object Signing Keys
+	//
+	// JSON Web Key Set of the public keys with which the Client signs Request Objects. If set, Request Objects are
+	// verified with these keys only, and the keys of jwks or jwks_uri only authenticate the Client. Every key must
+	// have a unique key ID by which it is selected, so that keys are rotated by registering the new key before
+	// removing the old one.
+	RequestObjectSigningJSONWebKeys *x.JoseJSONWebKeySet `json:"request_object_signing_jwks,omitempty" db:"request_object_signing_jwks" faker:"-"`
+
 	// OpenID Connect Request Userinfo Signed Response Algorithm
 	//
 	// JWS alg algorithm [JWA] REQUIRED for signing UserInfo Responses. If this is specified, the response will be JWT
@@ -279,6 +290,26 @@ type Client struct {
 	// If omitted, the default value is false.
 	BackChannelLogoutSessionRequired bool `json:"backchannel_logout_session_required,omitempty" db:"backchannel_logout_session_required"`
 
+	// OpenID Connect Back-Channel Logout Token Encryption Algorithm
+	//
+	// JWE alg algorithm required for encrypting the Logout Token sent to the backchannel_logout_uri. The Logout Token
+	// is signed and then encrypted to a key of the client's jwks or jwks_uri, resulting in a Nested JWT. If omitted,
+	// the Logout Token is not encrypted.
+	BackChannelLogoutTokenEncryptedResponseAlg string `json:"backchannel_logout_token_encrypted_response_alg,omitempty" db:"backchannel_logout_token_encrypted_response_alg"`
+
+	// OpenID Connect Back-Channel Logout Token Encryption Encoding
+	//
+	// JWE enc algorithm required for encrypting the Logout Token sent to the backchannel_logout_uri. If
+	// backchannel_logout_token_encrypted_response_alg is set, the default is A128CBC-HS256.
+	BackChannelLogoutTokenEncryptedResponseEnc string `json:"backchannel_logout_token_encrypted_response_enc,omitempty" db:"backchannel_logout_token_encrypted_response_enc"`
+
+	// Revoke Tokens on Logout
+	//
+	// Boolean value specifying whether the access and refresh tokens issued to this client in a login session are
+	// revoked when that session is logged out, either by the relying party or by the admin API. If omitted, the
+	// default value is false.
+	RevokeTokensOnLogout bool `json:"revoke_tokens_on_logout,omitempty" db:"revoke_tokens_on_logout"`
+
 	// OAuth 2.0 Client Metadata
 	//
 	// Use this field to story arbitrary data about the OAuth 2.0 Client. Can not be modified using OpenID Connect Dynamic Client Registration protocol.
@@ -375,12 +406,50 @@ type Lifespans struct {
 	//
 	// The lifespan of a refresh token issued by the OAuth2 2.0 Refresh Token Grant for this OAuth 2.0 Client.
 	RefreshTokenGrantRefreshTokenLifespan x.NullDuration `json:"refresh_token_grant_refresh_token_lifespan,omitempty" db:"refresh_token_grant_refresh_token_lifespan"`
+
+	// OAuth2 Authorization Code Lifespan
+	//
+	// The lifespan of an authorization code issued for this OAuth 2.0 Client.
+	AuthorizationCodeLifespan x.NullDuration `json:"authorization_code_lifespan,omitempty" db:"authorization_code_lifespan"`
+
+	// Login Challenge Lifespan
+	//
+	// The lifespan of a login challenge issued for this OAuth 2.0 Client.
+	LoginChallengeLifespan x.NullDuration `json:"login_challenge_lifespan,omitempty" db:"login_challenge_lifespan"`
+
+	// Consent Challenge Lifespan
+	//
+	// The lifespan of a consent challenge issued for this OAuth 2.0 Client.
+	ConsentChallengeLifespan x.NullDuration `json:"consent_challenge_lifespan,omitempty" db:"consent_challenge_lifespan"`
+
+	// Logout Challenge Lifespan
+	//
+	// The lifespan of a logout challenge issued for this OAuth 2.0 Client.
+	LogoutChallengeLifespan x.NullDuration `json:"logout_challenge_lifespan,omitempty" db:"logout_challenge_lifespan"`
+
+	// OAuth2 Client Credentials Grant Access Token Cache Lifespan
+	//
+	// If set, an access token issued by the OAuth 2.0 Client Credentials Grant for this OAuth 2.0 Client is returned
+	// again for this long to token requests with the same scope and audience, instead of issuing a new one.
+	ClientCredentialsGrantAccessTokenCacheLifespan x.NullDuration `json:"client_credentials_grant_access_token_cache_lifespan,omitempty" db:"client_credentials_grant_access_token_cache_lifespan"`
 }
 
 func (Client) TableName() string {
 	return "hydra_client"
 }
 
+func (c Client) PageToken() keysetpagination.PageToken {
+	return keysetpagination.MapPageToken{"id": c.ID}
+}
+
+// AfterFind unsets the request object signing keys of clients which have none, which are stored as null.
+func (c *Client) AfterFind(_ *pop.Connection) error {
+	if c.RequestObjectSigningJSONWebKeys != nil && c.RequestObjectSigningJSONWebKeys.JSONWebKeySet == nil {
+		c.RequestObjectSigningJSONWebKeys = nil
+	}
+	return nil
+}
+
 func (c *Client) BeforeSave(_ *pop.Connection) error {
 	if c.JSONWebKeys == nil {
 		c.JSONWebKeys = new(x.JoseJSONWebKeySet)
@@ -483,6 +552,30 @@ func (c *Client) GetJSONWebKeys() *jose.JSONWebKeySet {
 	return c.JSONWebKeys.JSONWebKeySet
 }
 
+type requestObjectSigningKeysContextKey struct{}
+
+// WithRequestObjectSigningKeys returns a context in which clients are resolved for the verification of request objects,
+// see ForContext.
+func WithRequestObjectSigningKeys(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestObjectSigningKeysContextKey{}, true)
+}
+
+// ForContext returns the client as it is used in the context. In contexts returned by WithRequestObjectSigningKeys, it
+// returns a copy of clients with request object signing keys whose JSON Web Keys are these keys.
+func (c *Client) ForContext(ctx context.Context) *Client {
+	if ok, _ := ctx.Value(requestObjectSigningKeysContextKey{}).(bool); !ok {
+		return c
+	}
+	if c.RequestObjectSigningJSONWebKeys == nil || c.RequestObjectSigningJSONWebKeys.JSONWebKeySet == nil {
+		return c
+	}
+
+	cc := *c
+	cc.JSONWebKeys = c.RequestObjectSigningJSONWebKeys
+	cc.JSONWebKeysURI = ""
+	return &cc
+}
+
 func (c *Client) GetTokenEndpointAuthSigningAlgorithm() string {
 	if c.TokenEndpointAuthSigningAlgorithm == "" {
 		return "RS256"
@@ -510,7 +603,9 @@ var _ fosite.ClientWithCustomTokenLifespans = &Client{}
 func (c *Client) GetEffectiveLifespan(gt fosite.GrantType, tt fosite.TokenType, fallback time.Duration) time.Duration {
 	var cl *time.Duration
 	if gt == fosite.GrantTypeAuthorizationCode {
-		if tt == fosite.AccessToken && c.AuthorizationCodeGrantAccessTokenLifespan.Valid {
+		if tt == fosite.AuthorizeCode && c.AuthorizationCodeLifespan.Valid {
+			cl = &c.AuthorizationCodeLifespan.Duration
+		} else if tt == fosite.AccessToken && c.AuthorizationCodeGrantAccessTokenLifespan.Valid {
 			cl = &c.AuthorizationCodeGrantAccessTokenLifespan.Duration
 		} else if tt == fosite.IDToken && c.AuthorizationCodeGrantIDTokenLifespan.Valid {
 			cl = &c.AuthorizationCodeGrantIDTokenLifespan.Duration
@@ -553,6 +648,42 @@ func (c *Client) GetEffectiveLifespan(gt fosite.GrantType, tt fosite.TokenType,
 	return *cl
 }
 
+// GetLoginChallengeLifespan returns the lifespan of login challenges issued for the client, or the fallback if the
+// client does not override it.
+func (c *Client) GetLoginChallengeLifespan(fallback time.Duration) time.Duration {
+	if c == nil || !c.LoginChallengeLifespan.Valid {
+		return fallback
+	}
+	return c.LoginChallengeLifespan.Duration
+}
+
+// GetConsentChallengeLifespan returns the lifespan of consent challenges issued for the client, or the fallback if
+// the client does not override it.
+func (c *Client) GetConsentChallengeLifespan(fallback time.Duration) time.Duration {
+	if c == nil || !c.ConsentChallengeLifespan.Valid {
+		return fallback
+	}
+	return c.ConsentChallengeLifespan.Duration
+}
+
+// GetLogoutChallengeLifespan returns the lifespan of logout challenges issued for the client, or the fallback if the
+// client does not override it.
+func (c *Client) GetLogoutChallengeLifespan(fallback time.Duration) time.Duration {
+	if c == nil || !c.LogoutChallengeLifespan.Valid {
+		return fallback
+	}
+	return c.LogoutChallengeLifespan.Duration
+}
+
+// GetClientCredentialsTokenCacheLifespan returns how long access tokens issued by the client credentials grant are
+// returned again to the client, or 0 if they are not cached.
+func (c *Client) GetClientCredentialsTokenCacheLifespan() time.Duration {
+	if c == nil || !c.ClientCredentialsGrantAccessTokenCacheLifespan.Valid {
+		return 0
+	}
+	return c.ClientCredentialsGrantAccessTokenCacheLifespan.Duration
+}
+
 func (c *Client) GetAccessTokenStrategy() config.AccessTokenStrategyType {
 	// We ignore the error here, because the empty string will default to
 	// the global access token strategy.
@@ -17,12 +17,13 @@ import (
 
 	"github.com/ory/fosite"
 	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/audit"
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/errorsx"
 	"github.com/ory/x/httprouterx"
 	"github.com/ory/x/jsonx"
 	"github.com/ory/x/openapix"
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/urlx"
 	"github.com/ory/x/uuidx"
 )
@@ -96,6 +97,7 @@ func (h *Handler) createOAuth2Client(w http.ResponseWriter, r *http.Request, _ h
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionCreate, audit.ResourceOAuth2Client, c.GetID(), nil, withoutSecret(c))
 	h.r.Writer().WriteCreated(w, r, "/admin"+ClientsHandlerPath+"/"+c.GetID(), &c)
 }
 
@@ -145,6 +147,7 @@ func (h *Handler) createOidcDynamicClient(w http.ResponseWriter, r *http.Request
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
+	h.limitDynamicRegistrationBody(w, r)
 	c, err := h.CreateClient(r, h.r.ClientValidator().ValidateDynamicRegistration, true)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
@@ -157,6 +160,9 @@ func (h *Handler) createOidcDynamicClient(w http.ResponseWriter, r *http.Request
 func (h *Handler) CreateClient(r *http.Request, validator func(context.Context, *Client) error, isDynamic bool) (*Client, error) {
 	var c Client
 	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		if err := bodyTooLargeError(err); err != nil {
+			return nil, err
+		}
 		return nil, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err))
 	}
 
@@ -229,6 +235,8 @@ type setOAuth2Client struct {
 // Replaces an existing OAuth 2.0 Client with the payload you send. If you pass `client_secret` the secret is used,
 // otherwise the existing secret is used.
 //
+// If you pass the ETag of the client in the If-Match header, the client is only replaced if it was not modified since.
+//
 // If set, the secret is echoed in the response. It is not possible to retrieve it later on.
 //
 // OAuth 2.0 Clients are used to perform OAuth 2.0 and OpenID Connect flows. Usually, OAuth 2.0 clients are
@@ -255,14 +263,62 @@ func (h *Handler) setOAuth2Client(w http.ResponseWriter, r *http.Request, ps htt
 	}
 
 	c.ID = ps.ByName("id")
+	if err := h.checkIfMatch(r, c.ID); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	before := h.auditState(r.Context(), c.ID)
 	if err := h.updateClient(r.Context(), &c, h.r.ClientValidator().Validate); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionUpdate, audit.ResourceOAuth2Client, c.ID, before, withoutSecret(&c))
 	h.r.Writer().Write(w, r, &c)
 }
 
+// checkIfMatch returns an error if the request has an If-Match header which does not match the entity tag of the
+// client. The client is only loaded if the request has an If-Match header.
+func (h *Handler) checkIfMatch(r *http.Request, id string) error {
+	if !x.HasIfMatch(r) {
+		return nil
+	}
+
+	c, err := h.r.ClientManager().GetConcreteClient(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	etag, err := x.ETag(c)
+	if err != nil {
+		return err
+	}
+	return x.CheckIfMatch(r, etag)
+}
+
+// auditState returns the current state of the client for the audit log, or nil if the audit log is disabled.
+func (h *Handler) auditState(ctx context.Context, id string) *Client {
+	if !h.r.Config().AuditEnabled(ctx) {
+		return nil
+	}
+
+	c, err := h.r.ClientManager().GetConcreteClient(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return withoutSecret(c)
+}
+
+func withoutSecret(c *Client) *Client {
+	if c == nil {
+		return nil
+	}
+
+	cc := *c
+	cc.Secret = ""
+	return &cc
+}
+
 func (h *Handler) updateClient(ctx context.Context, c *Client, validator func(context.Context, *Client) error) error {
 	var secret string
 	if len(c.Secret) > 0 {
@@ -348,7 +404,12 @@ func (h *Handler) setOidcDynamicClient(w http.ResponseWriter, r *http.Request, p
 	}
 
 	var c Client
+	h.limitDynamicRegistrationBody(w, r)
 	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		if err := bodyTooLargeError(err); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body. Is it valid JSON?").WithDebug(err.Error())))
 		return
 	}
@@ -403,6 +464,8 @@ type patchOAuth2Client struct {
 // the secret will be updated and returned via the API. This is the
 // only time you will be able to retrieve the client secret, so write it down and keep it safe.
 //
+// If you pass the ETag of the client in the If-Match header, the client is only patched if it was not modified since.
+//
 // OAuth 2.0 clients are used to perform OAuth 2.0 and OpenID Connect flows. Usually, OAuth 2.0 clients are
 // generated for applications which want to consume your OAuth 2.0 or OpenID Connect capabilities.
 //
@@ -432,7 +495,16 @@ func (h *Handler) patchOAuth2Client(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 
+	if etag, err := x.ETag(c); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	} else if err := x.CheckIfMatch(r, etag); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	oldSecret := c.Secret
+	before := withoutSecret(c)
 
 	if err := jsonx.ApplyJSONPatch(patchJSON, c, "/id"); err != nil {
 		h.r.Writer().WriteError(w, r, err)
@@ -452,6 +524,7 @@ func (h *Handler) patchOAuth2Client(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionUpdate, audit.ResourceOAuth2Client, c.ID, before, withoutSecret(c))
 	h.r.Writer().Write(w, r, c)
 }
 
@@ -461,7 +534,7 @@ func (h *Handler) patchOAuth2Client(w http.ResponseWriter, r *http.Request, ps h
 //
 //lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
 type listOAuth2ClientsResponse struct {
-	tokenpagination.ResponseHeaders
+	keysetpagination.ResponseHeaders
 
 	// List of OAuth 2.0 Clients
 	//
@@ -475,7 +548,7 @@ type listOAuth2ClientsResponse struct {
 //
 //lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
 type listOAuth2ClientsParameters struct {
-	tokenpagination.RequestParameters
+	keysetpagination.RequestParameters
 
 	// The name of the clients to filter by.
 	//
@@ -493,7 +566,7 @@ type listOAuth2ClientsParameters struct {
 // # List OAuth 2.0 Clients
 //
 // This endpoint lists all clients in the database, and never returns client secrets.
-// As a default it lists the first 100 clients.
+// As a default it lists the first 250 clients. Use the page_token from the Link header to fetch the next page.
 //
 //	Consumes:
 //	- application/json
@@ -507,15 +580,19 @@ type listOAuth2ClientsParameters struct {
 //	  200: listOAuth2Clients
 //	  default: errorOAuth2Default
 func (h *Handler) listOAuth2Clients(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	page, itemsPerPage := x.ParsePagination(r)
+	pageOpts, err := x.ParsePagination(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	filters := Filter{
-		Limit:  itemsPerPage,
-		Offset: page * itemsPerPage,
-		Name:   r.URL.Query().Get("client_name"),
-		Owner:  r.URL.Query().Get("owner"),
+		PageOpts: pageOpts,
+		Name:     r.URL.Query().Get("client_name"),
+		Owner:    r.URL.Query().Get("owner"),
 	}
 
-	c, err := h.r.ClientManager().GetClients(r.Context(), filters)
+	c, nextPage, err := h.r.ClientManager().GetClients(r.Context(), filters)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
@@ -535,7 +612,7 @@ func (h *Handler) listOAuth2Clients(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 
-	x.PaginationHeader(w, r.URL, int64(total), page, itemsPerPage)
+	x.PaginationHeader(w, r.URL, int64(total), nextPage)
 	h.r.Writer().Write(w, r, c)
 }
 
@@ -558,6 +635,9 @@ type adminGetOAuth2Client struct {
 //
 // Get an OAuth 2.0 client by its ID. This endpoint never returns the client secret.
 //
+// The response contains an ETag header. Pass it in the If-Match header when updating or deleting the client to
+// reject the request if the client was modified in the meantime.
+//
 // OAuth 2.0 clients are used to perform OAuth 2.0 and OpenID Connect flows. Usually, OAuth 2.0 clients are
 // generated for applications which want to consume your OAuth 2.0 or OpenID Connect capabilities.
 //
@@ -580,6 +660,14 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		return
 	}
 
+	// The entity tag covers the hashed secret, so that rotating the secret changes it as well.
+	etag, err := x.ETag(c)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+
 	c.Secret = ""
 	h.r.Writer().Write(w, r, c)
 }
@@ -658,13 +746,20 @@ type deleteOAuth2Client struct {
 	// in: path
 	// required: true
 	ID string `json:"id"`
+
+	// If set to `true`, the client is not deleted. Instead, the response lists how many clients, consent sessions
+	// and tokens would be deleted.
+	//
+	// in: query
+	DryRun bool `json:"dry_run"`
 }
 
 // swagger:route DELETE /admin/clients/{id} oAuth2 deleteOAuth2Client
 //
 // # Delete OAuth 2.0 Client
 //
-// Delete an existing OAuth 2.0 Client by its ID.
+// Delete an existing OAuth 2.0 Client by its ID. If you pass the ETag of the client in the If-Match header, the
+// client is only deleted if it was not modified since.
 //
 // OAuth 2.0 clients are used to perform OAuth 2.0 and OpenID Connect flows. Usually, OAuth 2.0 clients are
 // generated for applications which want to consume your OAuth 2.0 or OpenID Connect capabilities.
@@ -680,15 +775,33 @@ type deleteOAuth2Client struct {
 //	Schemes: http, https
 //
 //	Responses:
+//	  200: dryRunResult
 //	  204: emptyResponse
 //	  default: genericError
 func (h *Handler) deleteOAuth2Client(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var id = ps.ByName("id")
+	if err := h.checkIfMatch(r, id); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if x.IsDryRun(r) {
+		affected, err := h.r.ClientManager().DeleteClientDryRun(r.Context(), id)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		h.r.Writer().Write(w, r, &x.DryRunResult{Affected: affected})
+		return
+	}
+
+	before := h.auditState(r.Context(), id)
 	if err := h.r.ClientManager().DeleteClient(r.Context(), id); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceOAuth2Client, id, before, nil)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -712,7 +825,8 @@ type setOAuth2ClientLifespans struct {
 //
 // # Set OAuth2 Client Token Lifespans
 //
-// Set lifespans of different token types issued for this OAuth 2.0 client. Does not modify other fields.
+// Set lifespans of different token types issued for this OAuth 2.0 client. Does not modify other fields. If you
+// pass the ETag of the client in the If-Match header, the lifespans are only set if it was not modified since.
 //
 //	Consumes:
 //	- application/json
@@ -730,12 +844,21 @@ func (h *Handler) setOAuth2ClientLifespans(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if etag, err := x.ETag(c); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	} else if err := x.CheckIfMatch(r, etag); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	var ls Lifespans
 	if err := json.NewDecoder(r.Body).Decode(&ls); err != nil {
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
 		return
 	}
 
+	before := withoutSecret(c)
 	c.Lifespans = ls
 	c.Secret = ""
 
@@ -744,6 +867,7 @@ func (h *Handler) setOAuth2ClientLifespans(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionUpdate, audit.ResourceOAuth2Client, c.ID, before, withoutSecret(c))
 	h.r.Writer().Write(w, r, c)
 }
 
@@ -833,6 +957,21 @@ func (h *Handler) ValidDynamicAuth(r *http.Request, ps httprouter.Params) (fosit
 	return c, nil
 }
 
+// limitDynamicRegistrationBody limits the body of dynamic client registration requests to the configured size.
+func (h *Handler) limitDynamicRegistrationBody(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.r.Config().OAuth2MaxDynamicClientRegistrationSize(r.Context())))
+}
+
+// bodyTooLargeError returns a request too large error if reading the body failed because it exceeds its limit, and nil
+// otherwise.
+func bodyTooLargeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return nil
+	}
+	return errorsx.WithStack(x.ErrRequestTooLarge.WithHintf("The request body exceeds the maximum size of %d bytes.", maxBytesErr.Limit))
+}
+
 func (h *Handler) requireDynamicAuth(r *http.Request) *herodot.DefaultError {
 	if !h.r.Config().PublicAllowDynamicRegistration(r.Context()) {
 		return herodot.ErrNotFound.WithReason("Dynamic registration is not enabled.")
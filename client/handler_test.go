@@ -11,6 +11,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/ory/x/httprouterx"
@@ -397,6 +398,15 @@ func TestHandler(t *testing.T) {
 			}
 		})
 
+		t.Run("case=dynamic client registration rejects bodies exceeding the limit", func(t *testing.T) {
+			body, res := makeJSON(t, ts, "POST", client.DynClientsHandlerPath, &client.Client{
+				Name:         strings.Repeat("a", 70000),
+				RedirectURIs: []string{"http://localhost:3000/cb"},
+			})
+			assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode, body)
+			assert.Equal(t, "request_too_large", gjson.Get(body, "error").String(), body)
+		})
+
 		t.Run("case=fetching non-existing client", func(t *testing.T) {
 			for _, path := range []string{
 				client.DynClientsHandlerPath + "/foo",
@@ -567,6 +577,49 @@ func TestHandler(t *testing.T) {
 			snapshotx.SnapshotTExcept(t, newResponseSnapshot(body, res), []string{"body.client_id", "body.created_at", "body.updated_at"})
 		})
 
+		t.Run("case=rejects concurrent updates with If-Match", func(t *testing.T) {
+			expected := createClient(t, &client.Client{
+				Secret:                  "averylongsecret",
+				RedirectURIs:            []string{"http://localhost:3000/cb"},
+				TokenEndpointAuthMethod: "client_secret_basic",
+			}, ts, client.ClientsHandlerPath)
+			path := ts.URL + client.ClientsHandlerPath + "/" + getClientID(expected)
+
+			do := func(t *testing.T, method, ifMatch string, body string) *http.Response {
+				r, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+				require.NoError(t, err)
+				r.Header.Set("If-Match", ifMatch)
+				res, err := ts.Client().Do(r)
+				require.NoError(t, err)
+				defer res.Body.Close()
+				return res
+			}
+
+			_, res := fetch(t, path)
+			etag := res.Header.Get("ETag")
+			require.NotEmpty(t, etag)
+			_, res = fetch(t, path)
+			assert.Equal(t, etag, res.Header.Get("ETag"))
+
+			payload, _ := sjson.Set(expected, "client_name", "first")
+			payload, _ = sjson.Delete(payload, "client_secret")
+			assert.Equal(t, http.StatusOK, do(t, "PUT", etag, payload).StatusCode)
+
+			_, res = fetch(t, path)
+			assert.NotEqual(t, etag, res.Header.Get("ETag"))
+
+			payload, _ = sjson.Set(payload, "client_name", "second")
+			assert.Equal(t, http.StatusPreconditionFailed, do(t, "PUT", etag, payload).StatusCode)
+			assert.Equal(t, http.StatusPreconditionFailed, do(t, "PATCH", etag, `[{"op":"replace","path":"/client_name","value":"second"}]`).StatusCode)
+			assert.Equal(t, http.StatusPreconditionFailed, do(t, "DELETE", etag, "").StatusCode)
+
+			body, _ := fetch(t, path)
+			assert.Equal(t, "first", gjson.Get(body, "client_name").String())
+
+			_, res = fetch(t, path)
+			assert.Equal(t, http.StatusNoContent, do(t, "DELETE", res.Header.Get("ETag"), "").StatusCode)
+		})
+
 		t.Run("case=delete existing client", func(t *testing.T) {
 			t.Run("endpoint=admin", func(t *testing.T) {
 				expected := createClient(t, &client.Client{
@@ -579,6 +632,25 @@ func TestHandler(t *testing.T) {
 				assert.Equal(t, http.StatusNoContent, res.StatusCode)
 			})
 
+			t.Run("endpoint=admin with dry run", func(t *testing.T) {
+				expected := createClient(t, &client.Client{
+					RedirectURIs:            []string{"http://localhost:3000/cb"},
+					TokenEndpointAuthMethod: "client_secret_basic",
+				}, ts, client.ClientsHandlerPath)
+				expectedID := getClientID(expected)
+
+				body, res := makeJSON(t, ts, "DELETE", client.ClientsHandlerPath+"/"+expectedID+"?dry_run=true", nil)
+				require.Equal(t, http.StatusOK, res.StatusCode, body)
+				assert.EqualValues(t, 1, gjson.Get(body, "affected.oauth2_clients").Int(), body)
+				assert.True(t, gjson.Get(body, "affected.access_tokens").Exists(), body)
+
+				_, res = fetch(t, ts.URL+client.ClientsHandlerPath+"/"+expectedID)
+				assert.Equal(t, http.StatusOK, res.StatusCode, "the client must not be deleted")
+
+				_, res = makeJSON(t, ts, "DELETE", client.ClientsHandlerPath+"/unknown-client?dry_run=true", nil)
+				assert.Equal(t, http.StatusNotFound, res.StatusCode)
+			})
+
 			t.Run("endpoint=selfservice", func(t *testing.T) {
 				expected := createClient(t, &client.Client{
 					Secret:                  "averylongsecret",
@@ -7,17 +7,13 @@ import (
 	"context"
 
 	"github.com/ory/fosite"
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 // swagger:ignore
 type Filter struct {
-	// The maximum amount of clients to returned, upper bound is 500 clients.
-	// in: query
-	Limit int `json:"limit"`
-
-	// The offset from where to start looking.
-	// in: query
-	Offset int `json:"offset"`
+	// The pagination options, see x.ParsePagination.
+	PageOpts []keysetpagination.Option `json:"-"`
 
 	// The name of the clients to filter by.
 	// in: query
@@ -43,7 +39,10 @@ type Storage interface {
 
 	DeleteClient(ctx context.Context, id string) error
 
-	GetClients(ctx context.Context, filters Filter) ([]Client, error)
+	// DeleteClientDryRun returns the number of resources by type which DeleteClient would delete.
+	DeleteClientDryRun(ctx context.Context, id string) (map[string]int, error)
+
+	GetClients(ctx context.Context, filters Filter) ([]Client, *keysetpagination.Paginator, error)
 
 	CountClients(ctx context.Context) (int, error)
 
@@ -22,6 +22,7 @@ import (
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/assertx"
 	"github.com/ory/x/contextx"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -275,8 +276,9 @@ func TestHelperCreateGetUpdateDeleteClient(k string, connection *pop.Connection,
 
 		compare(t, t1c1, d, k)
 
-		ds, err := t1.GetClients(ctx, Filter{Limit: 100, Offset: 0})
+		ds, nextPage, err := t1.GetClients(ctx, Filter{})
 		assert.NoError(t, err)
+		assert.True(t, nextPage.IsLast())
 		assert.Len(t, ds, 2)
 		assert.NotEqual(t, ds[0].GetID(), ds[1].GetID())
 		assert.NotEqual(t, ds[0].GetID(), ds[1].GetID())
@@ -284,27 +286,31 @@ func TestHelperCreateGetUpdateDeleteClient(k string, connection *pop.Connection,
 		assert.Equal(t, ds[0].SecretExpiresAt, 0)
 		assert.Equal(t, ds[1].SecretExpiresAt, 1)
 
-		ds, err = t1.GetClients(ctx, Filter{Limit: 1, Offset: 0})
+		ds, nextPage, err = t1.GetClients(ctx, Filter{PageOpts: []keysetpagination.Option{keysetpagination.WithSize(1)}})
 		assert.NoError(t, err)
-		assert.Len(t, ds, 1)
+		require.Len(t, ds, 1)
+		assert.False(t, nextPage.IsLast())
+		first := ds[0].GetID()
 
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 100})
+		ds, nextPage, err = t1.GetClients(ctx, Filter{PageOpts: nextPage.ToOptions()})
 		assert.NoError(t, err)
-		assert.Empty(t, ds)
+		require.Len(t, ds, 1)
+		assert.True(t, nextPage.IsLast())
+		assert.NotEqual(t, first, ds[0].GetID())
 
 		// get by name
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 0, Name: "name"})
+		ds, _, err = t1.GetClients(ctx, Filter{Name: "name"})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 1)
 		assert.Equal(t, ds[0].Name, "name")
 
 		// get by name not exist
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 0, Name: "bad name"})
+		ds, _, err = t1.GetClients(ctx, Filter{Name: "bad name"})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 0)
 
 		// get by owner
-		ds, err = t1.GetClients(ctx, Filter{Limit: 100, Offset: 0, Owner: "aeneas"})
+		ds, _, err = t1.GetClients(ctx, Filter{Owner: "aeneas"})
 		assert.NoError(t, err)
 		assert.Len(t, ds, 1)
 		assert.Equal(t, ds[0].Owner, "aeneas")
@@ -8,12 +8,14 @@ import (
 
 	"github.com/ory/fosite"
 	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/hydra/v2/audit"
 	"github.com/ory/hydra/v2/jwk"
 	"github.com/ory/hydra/v2/x"
 )
 
 type InternalRegistry interface {
 	x.RegistryWriter
+	audit.Registry
 	Registry
 }
 
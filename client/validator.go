@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/go-jose/go-jose/v3"
 	"github.com/hashicorp/go-retryablehttp"
 
 	"github.com/ory/herodot"
@@ -32,6 +33,28 @@ var (
 		"ES384",
 		"ES512",
 	}
+
+	// SupportedLogoutTokenEncryptionAlgs are the JWE alg algorithms with which back-channel logout tokens can be
+	// encrypted.
+	SupportedLogoutTokenEncryptionAlgs = []string{
+		string(jose.RSA_OAEP),
+		string(jose.RSA_OAEP_256),
+		string(jose.ECDH_ES),
+		string(jose.ECDH_ES_A128KW),
+		string(jose.ECDH_ES_A192KW),
+		string(jose.ECDH_ES_A256KW),
+	}
+
+	// SupportedLogoutTokenEncryptionEncs are the JWE enc algorithms with which back-channel logout tokens can be
+	// encrypted.
+	SupportedLogoutTokenEncryptionEncs = []string{
+		string(jose.A128CBC_HS256),
+		string(jose.A192CBC_HS384),
+		string(jose.A256CBC_HS512),
+		string(jose.A128GCM),
+		string(jose.A192GCM),
+		string(jose.A256GCM),
+	}
 )
 
 type validatorRegistry interface {
@@ -73,6 +96,10 @@ func (v *Validator) Validate(ctx context.Context, c *Client) error {
 		}
 	}
 
+	if err := validateRequestObjectSigningKeys(c); err != nil {
+		return err
+	}
+
 	if v.r.Config().ClientHTTPNoPrivateIPRanges() {
 		values := map[string]string{
 			"jwks_uri":               c.JSONWebKeysURI,
@@ -166,9 +193,12 @@ func (v *Validator) Validate(ctx context.Context, c *Client) error {
 			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Unable to parse post_logout_redirect_uri: %s", l))
 		}
 
+		// With subdomain wildcards, a pattern such as https://*.example.org/logout must cover the host of a redirect URI.
+		wildcard := v.r.Config().PostLogoutRedirectSubdomainWildcards(ctx) && strings.HasPrefix(u.Hostname(), "*.")
+
 		var found bool
 		for _, r := range redirs {
-			if r.Hostname() == u.Hostname() &&
+			if (r.Hostname() == u.Hostname() || wildcard && x.MatchSubdomainWildcard(u.Hostname(), r.Hostname())) &&
 				r.Port() == u.Port() &&
 				r.Scheme == u.Scheme {
 				found = true
@@ -182,6 +212,23 @@ func (v *Validator) Validate(ctx context.Context, c *Client) error {
 		}
 	}
 
+	if c.BackChannelLogoutTokenEncryptedResponseAlg != "" {
+		if !stringslice.Has(SupportedLogoutTokenEncryptionAlgs, c.BackChannelLogoutTokenEncryptedResponseAlg) {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field backchannel_logout_token_encrypted_response_alg must be one of %s.", strings.Join(SupportedLogoutTokenEncryptionAlgs, ", ")))
+		}
+		if len(c.JSONWebKeysURI) == 0 && c.JSONWebKeys == nil {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("When backchannel_logout_token_encrypted_response_alg is set, either jwks or jwks_uri must be set."))
+		}
+		if c.BackChannelLogoutTokenEncryptedResponseEnc == "" {
+			c.BackChannelLogoutTokenEncryptedResponseEnc = string(jose.A128CBC_HS256)
+		}
+	} else if c.BackChannelLogoutTokenEncryptedResponseEnc != "" {
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Field backchannel_logout_token_encrypted_response_enc requires backchannel_logout_token_encrypted_response_alg to be set."))
+	}
+	if c.BackChannelLogoutTokenEncryptedResponseEnc != "" && !stringslice.Has(SupportedLogoutTokenEncryptionEncs, c.BackChannelLogoutTokenEncryptedResponseEnc) {
+		return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Field backchannel_logout_token_encrypted_response_enc must be one of %s.", strings.Join(SupportedLogoutTokenEncryptionEncs, ", ")))
+	}
+
 	if c.AccessTokenStrategy != "" {
 		s, err := config.ToAccessTokenStrategyType(c.AccessTokenStrategy)
 		if err != nil {
@@ -257,3 +304,37 @@ func isSupportedAuthTokenSigningAlg(alg string) bool {
 	}
 	return false
 }
+
+// validateRequestObjectSigningKeys validates that the request object signing keys are public signing keys which are
+// selected by a unique key ID that is not used by the keys in jwks.
+func validateRequestObjectSigningKeys(c *Client) error {
+	if c.RequestObjectSigningJSONWebKeys == nil || c.RequestObjectSigningJSONWebKeys.JSONWebKeySet == nil {
+		return nil
+	}
+
+	kids := map[string]bool{}
+	for _, k := range c.RequestObjectSigningJSONWebKeys.Keys {
+		if !k.Valid() || !k.IsPublic() {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Field request_object_signing_jwks must only contain valid public keys."))
+		}
+		if k.Use != "sig" {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Key %q of request_object_signing_jwks must have the use 'sig'.", k.KeyID))
+		}
+		if k.KeyID == "" {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHint("Every key of request_object_signing_jwks must have a key ID."))
+		}
+		if kids[k.KeyID] {
+			return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Key ID %q is used by more than one key of request_object_signing_jwks.", k.KeyID))
+		}
+		kids[k.KeyID] = true
+	}
+
+	if c.JSONWebKeys != nil && c.JSONWebKeys.JSONWebKeySet != nil {
+		for _, k := range c.JSONWebKeys.Keys {
+			if kids[k.KeyID] {
+				return errorsx.WithStack(ErrInvalidClientMetadata.WithHintf("Key ID %q is used by both jwks and request_object_signing_jwks, but request object signing keys must be separate.", k.KeyID))
+			}
+		}
+	}
+	return nil
+}
@@ -5,6 +5,9 @@ package client_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -129,6 +132,52 @@ func TestValidate(t *testing.T) {
 				assert.Equal(t, []string{"https://foo/"}, []string(c.PostLogoutRedirectURIs))
 			},
 		},
+		{
+			in:        &Client{ID: "foo", PostLogoutRedirectURIs: []string{"https://*.foo.org/"}, RedirectURIs: []string{"https://app.foo.org/"}},
+			assertErr: assert.Error,
+		},
+		{
+			v: func(t *testing.T) *Validator {
+				c.MustSet(ctx, config.KeyPostLogoutRedirectSubdomainWildcards, true)
+				t.Cleanup(func() { c.MustSet(ctx, config.KeyPostLogoutRedirectSubdomainWildcards, false) })
+				return NewValidator(reg)
+			},
+			in: &Client{ID: "foo", PostLogoutRedirectURIs: []string{"https://*.foo.org/"}, RedirectURIs: []string{"https://app.foo.org/"}},
+			check: func(t *testing.T, c *Client) {
+				assert.Equal(t, []string{"https://*.foo.org/"}, []string(c.PostLogoutRedirectURIs))
+			},
+		},
+		{
+			v: func(t *testing.T) *Validator {
+				c.MustSet(ctx, config.KeyPostLogoutRedirectSubdomainWildcards, true)
+				t.Cleanup(func() { c.MustSet(ctx, config.KeyPostLogoutRedirectSubdomainWildcards, false) })
+				return NewValidator(reg)
+			},
+			in:        &Client{ID: "foo", PostLogoutRedirectURIs: []string{"https://*.foo.org/"}, RedirectURIs: []string{"https://foo.org/"}},
+			assertErr: assert.Error,
+		},
+		{
+			in: &Client{ID: "foo", BackChannelLogoutTokenEncryptedResponseAlg: "RSA-OAEP-256", JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: &goodJWKS}},
+			check: func(t *testing.T, c *Client) {
+				assert.Equal(t, "A128CBC-HS256", c.BackChannelLogoutTokenEncryptedResponseEnc)
+			},
+		},
+		{
+			in:        &Client{ID: "foo", BackChannelLogoutTokenEncryptedResponseAlg: "RSA-OAEP-256"},
+			assertErr: assert.Error,
+		},
+		{
+			in:        &Client{ID: "foo", BackChannelLogoutTokenEncryptedResponseAlg: "RSA1_5", JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: &goodJWKS}},
+			assertErr: assert.Error,
+		},
+		{
+			in:        &Client{ID: "foo", BackChannelLogoutTokenEncryptedResponseAlg: "ECDH-ES", BackChannelLogoutTokenEncryptedResponseEnc: "foo", JSONWebKeysURI: "https://foo.org/jwks.json"},
+			assertErr: assert.Error,
+		},
+		{
+			in:        &Client{ID: "foo", BackChannelLogoutTokenEncryptedResponseEnc: "A256GCM"},
+			assertErr: assert.Error,
+		},
 		{
 			in: &Client{ID: "foo"},
 			check: func(t *testing.T, c *Client) {
@@ -266,6 +315,39 @@ const validJWKS = `
 }
 `
 
+func TestValidateRequestObjectSigningKeys(t *testing.T) {
+	ctx := context.Background()
+	c := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, c, &contextx.Static{C: c.Source(ctx)})
+	v := NewValidator(reg)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := func(keys ...jose.JSONWebKey) *x.JoseJSONWebKeySet {
+		return &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: keys}}
+	}
+	public := func(kid, use string) jose.JSONWebKey {
+		return jose.JSONWebKey{Key: &key.PublicKey, KeyID: kid, Use: use, Algorithm: "ES256"}
+	}
+
+	for k, tc := range []struct {
+		in        *Client
+		assertErr assert.ErrorAssertionFunc
+	}{
+		{in: &Client{RequestObjectSigningJSONWebKeys: keys(public("ro-1", "sig"), public("ro-2", "sig"))}, assertErr: assert.NoError},
+		{in: &Client{RequestObjectSigningJSONWebKeys: keys(public("ro-1", "sig")), JSONWebKeys: keys(public("auth", "sig"))}, assertErr: assert.NoError},
+		{in: &Client{RequestObjectSigningJSONWebKeys: keys(public("", "sig"))}, assertErr: assert.Error},
+		{in: &Client{RequestObjectSigningJSONWebKeys: keys(public("ro-1", "enc"))}, assertErr: assert.Error},
+		{in: &Client{RequestObjectSigningJSONWebKeys: keys(public("ro-1", "sig"), public("ro-1", "sig"))}, assertErr: assert.Error},
+		{in: &Client{RequestObjectSigningJSONWebKeys: keys(public("ro-1", "sig")), JSONWebKeys: keys(public("ro-1", "sig"))}, assertErr: assert.Error},
+		{in: &Client{RequestObjectSigningJSONWebKeys: keys(jose.JSONWebKey{Key: key, KeyID: "ro-1", Use: "sig"})}, assertErr: assert.Error},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			tc.assertErr(t, v.Validate(ctx, tc.in))
+		})
+	}
+}
+
 func TestValidateIPRanges(t *testing.T) {
 	ctx := context.Background()
 	c := internal.NewConfigurationWithDefaults()
@@ -12,11 +12,13 @@ import (
 type Handler struct {
 	Migration *MigrateHandler
 	Janitor   *JanitorHandler
+	Doctor    *DoctorHandler
 }
 
 func NewHandler(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier, cOpts []configx.OptionModifier) *Handler {
 	return &Handler{
 		Migration: newMigrateHandler(slOpts, dOpts, cOpts),
 		Janitor:   NewJanitorHandler(slOpts, dOpts, cOpts),
+		Doctor:    newDoctorHandler(slOpts, dOpts, cOpts),
 	}
 }
@@ -0,0 +1,234 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/hsm"
+	"github.com/ory/hydra/v2/metadata"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/servicelocatorx"
+)
+
+const (
+	DoctorStatusOK      = "ok"
+	DoctorStatusWarning = "warning"
+	DoctorStatusError   = "error"
+)
+
+type (
+	DoctorHandler struct {
+		slOpts []servicelocatorx.Option
+		dOpts  []driver.OptionsModifier
+		cOpts  []configx.OptionModifier
+	}
+
+	// DoctorFinding is the result of a single diagnostic check.
+	DoctorFinding struct {
+		Check   string `json:"check"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+
+	DoctorFindings []DoctorFinding
+)
+
+var _ cmdx.Table = (DoctorFindings)(nil)
+
+func (DoctorFindings) Header() []string {
+	return []string{"CHECK", "STATUS", "MESSAGE"}
+}
+
+func (f DoctorFindings) Table() [][]string {
+	rows := make([][]string, len(f))
+	for i, finding := range f {
+		rows[i] = []string{finding.Check, finding.Status, finding.Message}
+	}
+	return rows
+}
+
+func (f DoctorFindings) Interface() interface{} {
+	return f
+}
+
+func (f DoctorFindings) Len() int {
+	return len(f)
+}
+
+// HasErrors returns true if any of the findings has an error status.
+func (f DoctorFindings) HasErrors() bool {
+	for _, finding := range f {
+		if finding.Status == DoctorStatusError {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *DoctorFindings) add(check, status, format string, args ...interface{}) {
+	*f = append(*f, DoctorFinding{Check: check, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+func newDoctorHandler(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier, cOpts []configx.OptionModifier) *DoctorHandler {
+	return &DoctorHandler{
+		slOpts: slOpts,
+		dOpts:  dOpts,
+		cOpts:  cOpts,
+	}
+}
+
+func (h *DoctorHandler) Doctor(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	l := logrusx.New("Ory Hydra", config.Version)
+
+	findings := new(DoctorFindings)
+	c := h.checkConfig(ctx, cmd, l, findings)
+	if c != nil {
+		checkIssuer(ctx, c, findings)
+		checkTLS(ctx, c, l, findings)
+		checkSecrets(ctx, c, findings)
+		checkHSM(c, findings)
+		h.checkDatabase(ctx, c, findings)
+	}
+
+	cmdx.PrintTable(cmd, *findings)
+	if findings.HasErrors() {
+		return cmdx.FailSilently(cmd)
+	}
+	return nil
+}
+
+func (h *DoctorHandler) checkConfig(ctx context.Context, cmd *cobra.Command, l *logrusx.Logger, findings *DoctorFindings) *config.DefaultProvider {
+	opts := append(append([]configx.OptionModifier{}, h.cOpts...), configx.WithFlags(cmd.Flags()))
+
+	c, err := config.New(ctx, l, opts...)
+	if err == nil {
+		findings.add("config", DoctorStatusOK, "The configuration is valid.")
+		return c
+	}
+	findings.add("config", DoctorStatusError, "The configuration does not match the configuration schema: %s", err)
+
+	// Continue with the remaining checks on a best-effort basis.
+	c, err = config.New(ctx, l, append(opts, configx.SkipValidation())...)
+	if err != nil {
+		findings.add("config", DoctorStatusError, "The configuration could not be loaded: %s", err)
+		return nil
+	}
+	return c
+}
+
+func checkIssuer(ctx context.Context, c *config.DefaultProvider, findings *DoctorFindings) {
+	issuer := c.IssuerURL(ctx)
+	switch {
+	case c.Source(ctx).String(config.KeyIssuerURL) == "" && !c.IsDevelopmentMode(ctx):
+		findings.add("issuer", DoctorStatusError, "Configuration key %s must be set unless development mode is enabled.", config.KeyIssuerURL)
+	case issuer.Scheme != "https" && !c.IsDevelopmentMode(ctx):
+		findings.add("issuer", DoctorStatusError, "The issuer URL %s must use the https scheme unless development mode is enabled.", issuer)
+	case issuer.Scheme != "https":
+		findings.add("issuer", DoctorStatusWarning, "The issuer URL %s does not use the https scheme. This is only acceptable in development mode.", issuer)
+	default:
+		findings.add("issuer", DoctorStatusOK, "The issuer URL is %s.", issuer)
+	}
+}
+
+func checkTLS(ctx context.Context, c *config.DefaultProvider, l *logrusx.Logger, findings *DoctorFindings) {
+	for _, iface := range []config.ServeInterface{config.PublicInterface, config.AdminInterface} {
+		check := iface.Key("tls")
+		tc := c.TLS(ctx, iface)
+		if !tc.Enabled() {
+			if iface == config.PublicInterface && !c.IsDevelopmentMode(ctx) {
+				findings.add(check, DoctorStatusWarning, "TLS is disabled. Make sure that a reverse proxy terminates TLS in front of this interface.")
+			} else {
+				findings.add(check, DoctorStatusOK, "TLS is disabled.")
+			}
+			continue
+		}
+
+		stop := make(chan struct{})
+		if _, err := tc.GetCertificateFunc(stop, l); err != nil {
+			findings.add(check, DoctorStatusError, "The TLS certificate could not be loaded: %s", err)
+		} else {
+			findings.add(check, DoctorStatusOK, "The TLS certificate was loaded successfully.")
+		}
+		close(stop)
+	}
+}
+
+func checkSecrets(ctx context.Context, c *config.DefaultProvider, findings *DoctorFindings) {
+	if _, err := c.GetGlobalSecret(ctx); err != nil {
+		findings.add("secrets", DoctorStatusError, "Configuration key %s is invalid: %s", config.KeyGetSystemSecret, err)
+		return
+	}
+	findings.add("secrets", DoctorStatusOK, "The system secret is configured.")
+}
+
+func (h *DoctorHandler) checkDatabase(ctx context.Context, c *config.DefaultProvider, findings *DoctorFindings) {
+	switch dsn := c.Source(ctx).String(config.KeyDSN); dsn {
+	case "":
+		findings.add("database", DoctorStatusError, "Configuration key %s must be set.", config.KeyDSN)
+		return
+	case config.DSNMemory:
+		findings.add("database", DoctorStatusWarning, "The in-memory database is used. All data is lost when Ory Hydra stops.")
+		return
+	}
+
+	// The Hardware Security Module has already been probed, and initializing it again within the registry would
+	// terminate the process on failure.
+	if err := c.Set(ctx, config.HSMEnabled, false); err != nil {
+		findings.add("database", DoctorStatusError, "Unable to prepare the database check: %s", err)
+		return
+	}
+
+	d, err := driver.New(ctx, servicelocatorx.NewOptions(h.slOpts...), append([]driver.OptionsModifier{
+		driver.WithConfig(c),
+		driver.DisableValidation(),
+		driver.DisablePreloading(),
+		driver.SkipNetworkInit(),
+	}, h.dOpts...))
+	if err != nil {
+		findings.add("database", DoctorStatusError, "Unable to connect to the database: %s", err)
+		return
+	}
+
+	if err := d.Persister().Ping(); err != nil {
+		findings.add("database", DoctorStatusError, "Unable to reach the database: %s", err)
+		return
+	}
+	findings.add("database", DoctorStatusOK, "The database is reachable.")
+
+	statuses, err := d.Persister().MigrationStatus(ctx)
+	if err != nil {
+		findings.add("migrations", DoctorStatusError, "Unable to determine the migration status: %s", err)
+		return
+	}
+
+	switch status := metadata.NewMigrationStatus(statuses); {
+	case !status.Compatible:
+		findings.add("migrations", DoctorStatusError, "%d migrations are pending. Run \"hydra migrate sql\" to apply them.", status.Pending)
+	case !status.UpToDate:
+		findings.add("migrations", DoctorStatusWarning, "%d contract migrations are pending. Run \"hydra migrate sql\" once all instances have been upgraded.", status.Pending)
+	default:
+		findings.add("migrations", DoctorStatusOK, "All %d migrations have been applied.", status.Applied)
+	}
+}
+
+func checkHSM(c *config.DefaultProvider, findings *DoctorFindings) {
+	if !c.HSMEnabled() {
+		return
+	}
+
+	if err := hsm.Probe(c); err != nil {
+		findings.add("hsm", DoctorStatusError, "Unable to connect to the Hardware Security Module: %s", err)
+		return
+	}
+	findings.add("hsm", DoctorStatusOK, "The Hardware Security Module is reachable.")
+}
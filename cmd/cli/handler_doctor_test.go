@@ -0,0 +1,64 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cli_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/v2/cmd"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/dbal"
+)
+
+func TestDoctorHandler(t *testing.T) {
+	findings := func(t *testing.T, out string) map[string]string {
+		res := map[string]string{}
+		for _, f := range gjson.Parse(out).Array() {
+			res[f.Get("check").String()] = f.Get("status").String()
+		}
+		return res
+	}
+
+	t.Run("case=all checks pass", func(t *testing.T) {
+		dsn := dbal.NewSQLiteTestDatabase(t)
+		_ = internal.NewRegistrySQLFromURL(t, dsn, true, &contextx.Default{})
+
+		t.Setenv("DSN", dsn)
+		t.Setenv("SECRETS_SYSTEM", "a-very-secure-system-secret")
+		t.Setenv("URLS_SELF_ISSUER", "https://hydra.example.com/")
+		t.Setenv("SERVE_TLS_ALLOW_TERMINATION_FROM", "127.0.0.1/32")
+
+		stdOut, stdErr, err := cmdx.Exec(t, cmd.NewRootCmd(nil, nil, nil), nil, "doctor", "--format", "json")
+		require.NoError(t, err, "%s\n%s", stdOut, stdErr)
+
+		actual := findings(t, stdOut)
+		assert.Equal(t, "ok", actual["config"], stdOut)
+		assert.Equal(t, "ok", actual["issuer"], stdOut)
+		assert.Equal(t, "ok", actual["secrets"], stdOut)
+		assert.Equal(t, "ok", actual["database"], stdOut)
+		assert.Equal(t, "ok", actual["migrations"], stdOut)
+		assert.Equal(t, "warning", actual["serve.public.tls"], stdOut)
+	})
+
+	t.Run("case=reports problems", func(t *testing.T) {
+		t.Setenv("DSN", "memory")
+		t.Setenv("SECRETS_SYSTEM", "short")
+		t.Setenv("URLS_SELF_ISSUER", "http://hydra.example.com/")
+
+		stdOut, stdErr, err := cmdx.Exec(t, cmd.NewRootCmd(nil, nil, nil), nil, "doctor", "--format", "json")
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail, "%s\n%s", stdOut, stdErr)
+
+		actual := findings(t, stdOut)
+		assert.Equal(t, "error", actual["issuer"], stdOut)
+		assert.Equal(t, "error", actual["secrets"], stdOut)
+		assert.Equal(t, "warning", actual["database"], stdOut)
+	})
+
+}
@@ -6,9 +6,10 @@ package cli
 import (
 	"context"
 	"fmt"
-	"io"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/ory/x/servicelocatorx"
 
 	"github.com/ory/hydra/v2/persistence"
@@ -21,6 +22,8 @@ import (
 
 	"github.com/ory/hydra/v2/driver"
 	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/cmdx"
 	"github.com/ory/x/configx"
 	"github.com/ory/x/errorsx"
 )
@@ -37,6 +40,7 @@ const (
 	OnlyGrants             = "grants"
 	ReadFromEnv            = "read-from-env"
 	Config                 = "config"
+	MetricsTextfile        = "metrics-textfile"
 )
 
 type JanitorHandler struct {
@@ -160,45 +164,62 @@ func purge(cmd *cobra.Command, args []string, sl *servicelocatorx.Options, dOpts
 		routineFlags = append(routineFlags, OnlyGrants)
 	}
 
-	return cleanupRun(cmd.Context(), notAfter, limit, batchSize, addRoutine(cmd.OutOrStdout(), p, routineFlags...)...)
+	summary := x.NewJanitorSummary()
+	runErr := cleanupRun(cmd.Context(), summary, notAfter, limit, batchSize, addRoutine(p, routineFlags...)...)
+	summary.Finish()
+
+	if path := flagx.MustGetString(cmd, MetricsTextfile); path != "" {
+		if err := writeJanitorMetrics(path, summary); err != nil {
+			return err
+		}
+	}
+	cmdx.PrintJSONAble(cmd, summary)
+
+	if runErr != nil {
+		return runErr
+	}
+	return errors.Wrap(p.Checkpoint(cmd.Context()), "Could not checkpoint the database")
 }
 
-func addRoutine(out io.Writer, p persistence.Persister, names ...string) []cleanupRoutine {
+// writeJanitorMetrics writes the metrics of the janitor run to a file in the Prometheus text format, which can be
+// exported with the textfile collector of the node exporter.
+func writeJanitorMetrics(path string, summary *x.JanitorSummary) error {
+	reg := prometheus.NewRegistry()
+	x.NewJanitorMetrics(reg).Record(summary)
+	return errors.Wrap(prometheus.WriteToTextfile(path, reg), "Could not write the janitor metrics")
+}
+
+func addRoutine(p persistence.Persister, names ...string) []cleanupRoutine {
 	var routines []cleanupRoutine
 	for _, n := range names {
 		switch n {
 		case OnlyTokens:
-			routines = append(routines, cleanup(out, p.FlushInactiveAccessTokens, "access tokens"))
-			routines = append(routines, cleanup(out, p.FlushInactiveRefreshTokens, "refresh tokens"))
+			routines = append(routines, cleanupRoutine{name: "access tokens", run: p.FlushInactiveAccessTokens})
+			routines = append(routines, cleanupRoutine{name: "refresh tokens", run: p.FlushInactiveRefreshTokens})
 		case OnlyRequests:
-			routines = append(routines, cleanup(out, p.FlushInactiveLoginConsentRequests, "login-consent requests"))
+			routines = append(routines, cleanupRoutine{name: "login-consent requests", run: p.FlushInactiveLoginConsentRequests})
 		case OnlyGrants:
-			routines = append(routines, cleanup(out, p.FlushInactiveGrants, "grants"))
+			routines = append(routines, cleanupRoutine{name: "grants", run: p.FlushInactiveGrants})
 		}
 	}
 	return routines
 }
 
-type cleanupRoutine func(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
-
-func cleanup(out io.Writer, cr cleanupRoutine, routineName string) cleanupRoutine {
-	return func(ctx context.Context, notAfter time.Time, limit int, batchSize int) error {
-		if err := cr(ctx, notAfter, limit, batchSize); err != nil {
-			return errors.Wrap(errorsx.WithStack(err), fmt.Sprintf("Could not cleanup inactive %s", routineName))
-		}
-		fmt.Fprintf(out, "Successfully completed Janitor run on %s\n", routineName)
-		return nil
-	}
+type cleanupRoutine struct {
+	name string
+	run  func(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
 }
 
-func cleanupRun(ctx context.Context, notAfter time.Time, limit int, batchSize int, routines ...cleanupRoutine) error {
+func cleanupRun(ctx context.Context, summary *x.JanitorSummary, notAfter time.Time, limit int, batchSize int, routines ...cleanupRoutine) error {
 	if len(routines) == 0 {
 		return errors.New("clean up run received 0 routines")
 	}
 
 	for _, r := range routines {
-		if err := r(ctx, notAfter, limit, batchSize); err != nil {
-			return err
+		if err := summary.Run(ctx, r.name, limit, func(ctx context.Context) error {
+			return r.run(ctx, notAfter, limit, batchSize)
+		}); err != nil {
+			return errors.Wrap(errorsx.WithStack(err), fmt.Sprintf("Could not cleanup inactive %s", r.name))
 		}
 	}
 	return nil
@@ -6,6 +6,8 @@ package cli_test
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -13,7 +15,9 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
 
 	"github.com/ory/hydra/v2/cmd/cli"
 	"github.com/ory/hydra/v2/internal/testhelpers"
@@ -297,3 +301,37 @@ func TestJanitorHandler_PurgeGrantNotAfter(t *testing.T) {
 		})
 	}
 }
+
+func TestJanitorHandler_Summary(t *testing.T) {
+	ctx := context.Background()
+	jt := testhelpers.NewConsentJanitorTestHelper(t.Name())
+	reg, err := jt.GetRegistry(ctx, "summary")
+	require.NoError(t, err)
+
+	t.Run("step=setup-access", jt.AccessTokenNotAfterSetup(ctx, reg.ClientManager(), reg.OAuth2Storage()))
+
+	metrics := filepath.Join(t.TempDir(), "janitor.prom")
+	out := cmdx.ExecNoErr(t, newJanitorCmd(),
+		"janitor",
+		fmt.Sprintf("--%s=%s", cli.AccessLifespan, jt.GetAccessTokenLifespan(ctx).String()),
+		fmt.Sprintf("--%s=%s", cli.Limit, "1"),
+		fmt.Sprintf("--%s=%s", cli.BatchSize, "1"),
+		fmt.Sprintf("--%s", cli.OnlyTokens),
+		fmt.Sprintf("--%s=%s", cli.MetricsTextfile, metrics),
+		"--format=json",
+		jt.GetDSN(),
+	)
+
+	summary := gjson.Parse(out)
+	assert.Equal(t, []string{"access tokens", "refresh tokens"}, []string{summary.Get("routines.0.name").String(), summary.Get("routines.1.name").String()})
+	assert.EqualValues(t, 1, summary.Get("routines.0.deleted_rows.hydra_oauth2_access").Int(), "%s", out)
+	assert.True(t, summary.Get("routines.0.limit_reached").Bool(), "%s", out)
+	assert.False(t, summary.Get("routines.1.limit_reached").Bool(), "%s", out)
+	assert.EqualValues(t, 0, summary.Get("errors").Int(), "%s", out)
+
+	written, err := os.ReadFile(metrics)
+	require.NoError(t, err)
+	assert.Contains(t, string(written), `hydra_janitor_deleted_rows_total{routine="access tokens",table="hydra_oauth2_access"} 1`)
+	assert.Contains(t, string(written), `hydra_janitor_routine_limit_reached{routine="access tokens"} 1`)
+	assert.Contains(t, string(written), `hydra_janitor_routine_runs_total{routine="refresh tokens",status="success"} 1`)
+}
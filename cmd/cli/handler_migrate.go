@@ -357,6 +357,26 @@ func (h *MigrateHandler) MigrateSQL(cmd *cobra.Command, args []string) (err erro
 		}
 	}
 
+	if flagx.MustGetBool(cmd, "expand-only") {
+		n, err := p.MigrateUpExpand(context.Background())
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not apply migrations:\n%+v\n", errorsx.WithStack(err))
+			return cmdx.FailSilently(cmd)
+		}
+
+		status, err := p.MigrationStatus(context.Background())
+		if err != nil {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not get the migration status:\n%+v\n", errorsx.WithStack(err))
+			return cmdx.FailSilently(cmd)
+		}
+
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Successfully applied %d backwards-compatible migrations!\n", n)
+		if status.HasPending() {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Some migrations are not backwards-compatible and are still pending. Run this command without --expand-only once all instances have been upgraded.")
+		}
+		return nil
+	}
+
 	// apply migrations
 	if err := p.MigrateUp(context.Background()); err != nil {
 		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not apply migrations:\n%+v\n", errorsx.WithStack(err))
@@ -367,6 +387,111 @@ func (h *MigrateHandler) MigrateSQL(cmd *cobra.Command, args []string) (err erro
 	return nil
 }
 
+func (h *MigrateHandler) MigrateTokenPartitions(cmd *cobra.Command, args []string) error {
+	p, err := h.makePersister(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	partitioner, ok := p.(interface {
+		PartitionTokenTables(ctx context.Context) error
+	})
+	if !ok || p.Connection(cmd.Context()) == nil {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "The token tables can only be partitioned in a SQL database.")
+		return cmdx.FailSilently(cmd)
+	}
+
+	if !flagx.MustGetBool(cmd, "yes") {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "To skip the next question use flag --yes (at your own risk).")
+		if !cmdx.AskForConfirmation("Do you wish to partition the access and refresh token tables?", nil, nil) {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Partitioning aborted.")
+			return nil
+		}
+	}
+
+	if err := partitioner.PartitionTokenTables(cmd.Context()); err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not partition the token tables:\n%+v\n", errorsx.WithStack(err))
+		return cmdx.FailSilently(cmd)
+	}
+
+	_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Successfully partitioned the token tables!")
+	return nil
+}
+
+func (h *MigrateHandler) MigrateConsentSessions(cmd *cobra.Command, args []string) error {
+	if !flagx.MustGetBool(cmd, "read-from-env") {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Encrypting consent sessions requires the system secret, please use flag --read-from-env.")
+		return cmdx.FailSilently(cmd)
+	}
+
+	p, err := h.makePersister(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	encrypter, ok := p.(interface {
+		EncryptConsentPayloads(ctx context.Context, batchSize int) (int, error)
+	})
+	if !ok || p.Connection(cmd.Context()) == nil {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Consent sessions can only be encrypted in a SQL database.")
+		return cmdx.FailSilently(cmd)
+	}
+
+	if !flagx.MustGetBool(cmd, "yes") {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "To skip the next question use flag --yes (at your own risk).")
+		if !cmdx.AskForConfirmation("Do you wish to encrypt the existing consent sessions?", nil, nil) {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Encryption aborted.")
+			return nil
+		}
+	}
+
+	n, err := encrypter.EncryptConsentPayloads(cmd.Context(), flagx.MustGetInt(cmd, BatchSize))
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not encrypt the consent sessions after encrypting %d of them:\n%+v\n", n, errorsx.WithStack(err))
+		return cmdx.FailSilently(cmd)
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Successfully encrypted %d consent sessions!\n", n)
+	return nil
+}
+
+func (h *MigrateHandler) MigrateTokenSessions(cmd *cobra.Command, args []string) error {
+	if !flagx.MustGetBool(cmd, "read-from-env") {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Encrypting token sessions requires the system secret, please use flag --read-from-env.")
+		return cmdx.FailSilently(cmd)
+	}
+
+	p, err := h.makePersister(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	encrypter, ok := p.(interface {
+		EncryptTokenSessions(ctx context.Context, batchSize int) (int, error)
+	})
+	if !ok || p.Connection(cmd.Context()) == nil {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Token sessions can only be encrypted in a SQL database.")
+		return cmdx.FailSilently(cmd)
+	}
+
+	if !flagx.MustGetBool(cmd, "yes") {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "To skip the next question use flag --yes (at your own risk).")
+		if !cmdx.AskForConfirmation("Do you wish to encrypt the existing token sessions?", nil, nil) {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "Encryption aborted.")
+			return nil
+		}
+	}
+
+	n, err := encrypter.EncryptTokenSessions(cmd.Context(), flagx.MustGetInt(cmd, BatchSize))
+	if err != nil {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not encrypt the token sessions after encrypting %d of them:\n%+v\n", n, errorsx.WithStack(err))
+		return cmdx.FailSilently(cmd)
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Successfully encrypted %d token sessions!\n", n)
+	return nil
+}
+
 func (h *MigrateHandler) MigrateStatus(cmd *cobra.Command, args []string) error {
 	p, err := h.makePersister(cmd, args)
 	if err != nil {
@@ -0,0 +1,131 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/hydra/v2/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+)
+
+const (
+	flagApplyFile   = "file"
+	flagApplyPrune  = "prune"
+	flagApplyDryRun = "dry-run"
+)
+
+func NewApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply -f <state.yaml> [-f <state-2.yaml> ...]",
+		Args:  cobra.NoArgs,
+		Short: "Reconcile OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers with a declarative state",
+		Long: `This command reads the desired state from one or more YAML or JSON files, compares it with the resources
+of Ory Hydra, displays the difference and applies it.
+
+The format for the state file is:
+
+	clients:
+	  - client_id: my-app
+	    client_secret: ...
+	    # ... all other fields of the OAuth 2.0 Client model are allowed here
+	json_web_key_sets:
+	  # Generated if the set does not exist:
+	  - set: my-set
+	    alg: RS256
+	    use: sig
+	  # Replaced if the keys differ:
+	  - set: my-other-set
+	    keys:
+	      - kid: ...
+	        # ... all other fields of the JSON Web Key model
+	trusted_jwt_grant_issuers:
+	  - issuer: https://jwt-idp.example.com
+	    subject: alice@example.com
+	    scope: [openid]
+	    expires_at: "2030-01-01T00:00:00Z"
+	    jwk:
+	      kid: ...
+	      # ... all other fields of the JSON Web Key model
+
+OAuth 2.0 Clients are identified by their client ID and replaced if any of the fields set in the state differ.
+Fields which are not set in the state are reset to their defaults when the client is replaced. Client secrets can
+not be compared, so changing only the secret does not replace a client.
+
+Trusted JWT grant issuers are identified by their issuer, subject and key ID and replaced if their scope or
+expiry differ.
+
+Use ` + "`--prune`" + ` to delete OAuth 2.0 Clients and trusted JWT grant issuers which are not part of the state.
+JSON Web Key Sets are never deleted.`,
+		Example: `{{ .CommandPath }} -f state.yaml --dry-run
+
+	{{ .CommandPath }} -f clients.yaml -f keys.yaml --prune
+
+	cat state.yaml | {{ .CommandPath }} -f -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := flagx.MustGetStringSlice(cmd, flagApplyFile)
+			if len(files) == 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Flag --%s is required.\n", flagApplyFile)
+				return cmdx.FailSilently(cmd)
+			}
+
+			state, err := readApplyState(cmd, files)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Invalid state: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			m, _, err := cliclient.NewClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			planner := &applyPlanner{cmd: cmd, m: m, prune: flagx.MustGetBool(cmd, flagApplyPrune)}
+			changes, err := planner.plan(state)
+			if err != nil {
+				return cmdx.PrintOpenAPIError(cmd, err)
+			}
+
+			cmdx.PrintTable(cmd, &outputApplyChangeSet{changes: changes})
+			if len(changes) == 0 {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "No changes.")
+				return nil
+			} else if flagx.MustGetBool(cmd, flagApplyDryRun) {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Dry run, %d change(s) were not applied.\n", len(changes))
+				return nil
+			}
+
+			failed := make(map[string]error)
+			for _, c := range changes {
+				if err := c.apply(cmd.Context()); err != nil {
+					var apiErr *hydra.GenericOpenAPIError
+					if errors.As(err, &apiErr) {
+						err = errors.Errorf("%s: %s", apiErr.Error(), apiErr.Body())
+					}
+					failed[fmt.Sprintf("%s %s %s", c.Action, c.Resource, c.ID)] = err
+				}
+			}
+
+			if len(failed) != 0 {
+				cmdx.PrintErrors(cmd, failed)
+				return cmdx.FailSilently(cmd)
+			}
+
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Applied %d change(s).\n", len(changes))
+			return nil
+		},
+	}
+
+	cmdx.RegisterHTTPClientFlags(cmd.PersistentFlags())
+	cmdx.RegisterFormatFlags(cmd.PersistentFlags())
+	cmd.Flags().StringSliceP(flagApplyFile, "f", nil, "Read the desired state from this YAML or JSON file. Use \"-\" to read from STDIN.")
+	cmd.Flags().Bool(flagApplyPrune, false, "Delete OAuth 2.0 Clients and trusted JWT grant issuers which are not part of the desired state.")
+	cmd.Flags().Bool(flagApplyDryRun, false, "Only display the changes without applying them.")
+	return cmd
+}
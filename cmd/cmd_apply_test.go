@@ -0,0 +1,150 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/cmd"
+	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/x/cmdx"
+)
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewApplyCmd()
+	reg := setup(t, c)
+	endpoint := c.Flag(cmdx.FlagEndpoint).Value.String()
+
+	// Slice flags accumulate values, so every run uses a new command.
+	apply := func(t *testing.T, args ...string) (string, string, error) {
+		c := cmd.NewApplyCmd()
+		require.NoError(t, c.PersistentFlags().Set(cmdx.FlagEndpoint, endpoint))
+		require.NoError(t, c.PersistentFlags().Set(cmdx.FlagFormat, string(cmdx.FormatJSON)))
+		return cmdx.Exec(t, c, nil, args...)
+	}
+	applyNoErr := func(t *testing.T, args ...string) string {
+		stdout, stderr, err := apply(t, args...)
+		require.NoError(t, err, "stderr: %s", stderr)
+		return stdout
+	}
+
+	issuerKey, err := jwk.GenerateJWK(ctx, jose.RS256, "issuer-key", "sig")
+	require.NoError(t, err)
+	publicKey, err := json.Marshal(issuerKey.Keys[0].Public())
+	require.NoError(t, err)
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Round(time.Second).Format(time.RFC3339)
+	writeState := func(t *testing.T, clientName string) string {
+		state := `
+clients:
+  - client_id: applied-client
+    client_name: ` + clientName + `
+    client_secret: applied-client-secret
+    grant_types: [client_credentials]
+    redirect_uris: [https://example.com/callback]
+json_web_key_sets:
+  - set: applied-set
+    alg: ES256
+    kid: applied-key
+trusted_jwt_grant_issuers:
+  - issuer: https://issuer.example.com
+    subject: alice
+    scope: [openid]
+    expires_at: "` + expiresAt + `"
+    jwk: ` + string(publicKey) + `
+`
+		file := filepath.Join(t.TempDir(), "state.yaml")
+		require.NoError(t, os.WriteFile(file, []byte(state), 0600))
+		return file
+	}
+
+	changes := func(stdout string) map[string]string {
+		actions := map[string]string{}
+		for _, c := range gjson.Parse(stdout).Array() {
+			actions[c.Get("resource").String()+" "+c.Get("id").String()] = c.Get("action").String()
+		}
+		return actions
+	}
+
+	file := writeState(t, "first")
+
+	t.Run("case=creates missing resources", func(t *testing.T) {
+		stdout := applyNoErr(t, "-f", file)
+		assert.Equal(t, map[string]string{
+			"oauth2-client applied-client": "create",
+			"jwk-set applied-set":          "create",
+			"trusted-jwt-grant-issuer https://issuer.example.com alice issuer-key": "create",
+		}, changes(stdout))
+
+		cl, err := reg.ClientManager().AuthenticateClient(ctx, "applied-client", []byte("applied-client-secret"))
+		require.NoError(t, err)
+		assert.Equal(t, "first", cl.Name)
+
+		keys, err := reg.KeyManager().GetKeySet(ctx, "applied-set")
+		require.NoError(t, err)
+		assert.Equal(t, "applied-key", keys.Keys[0].KeyID)
+
+		grants, _, err := reg.GrantManager().GetGrants(ctx, "https://issuer.example.com")
+		require.NoError(t, err)
+		assert.Len(t, grants, 1)
+	})
+
+	t.Run("case=does nothing if the state is unchanged", func(t *testing.T) {
+		assert.Empty(t, changes(applyNoErr(t, "-f", file)))
+	})
+
+	t.Run("case=dry run does not apply changes", func(t *testing.T) {
+		stdout := applyNoErr(t, "-f", writeState(t, "second"), "--dry-run")
+		assert.Equal(t, "client_name", gjson.Get(stdout, "0.fields.0").String())
+
+		cl, err := reg.ClientManager().GetConcreteClient(ctx, "applied-client")
+		require.NoError(t, err)
+		assert.Equal(t, "first", cl.Name)
+	})
+
+	t.Run("case=updates and prunes resources", func(t *testing.T) {
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, &client.Client{ID: "unmanaged-client"}))
+
+		stdout := applyNoErr(t, "-f", writeState(t, "second"))
+		assert.Equal(t, map[string]string{"oauth2-client applied-client": "update"}, changes(stdout))
+
+		cl, err := reg.ClientManager().GetConcreteClient(ctx, "applied-client")
+		require.NoError(t, err)
+		assert.Equal(t, "second", cl.Name)
+		_, err = reg.ClientManager().GetConcreteClient(ctx, "unmanaged-client")
+		require.NoError(t, err)
+
+		stdout = applyNoErr(t, "-f", file, "--prune")
+		assert.Equal(t, map[string]string{
+			"oauth2-client applied-client":   "update",
+			"oauth2-client unmanaged-client": "delete",
+		}, changes(stdout))
+
+		_, err = reg.ClientManager().GetConcreteClient(ctx, "unmanaged-client")
+		assert.Error(t, err)
+		_, err = reg.ClientManager().AuthenticateClient(ctx, "applied-client", []byte("applied-client-secret"))
+		require.NoError(t, err)
+	})
+
+	t.Run("case=rejects invalid state", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "state.yaml")
+		require.NoError(t, os.WriteFile(file, []byte("clients:\n  - client_name: without-id\n"), 0600))
+
+		_, stderr, err := apply(t, "-f", file)
+		require.Error(t, err)
+		assert.Contains(t, stderr, "client_id")
+	})
+}
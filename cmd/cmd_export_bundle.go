@@ -0,0 +1,88 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/v2/backup"
+	"github.com/ory/hydra/v2/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/urlx"
+)
+
+const flagEncryptionSecret = "encryption-secret"
+
+func NewExportBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Args:  cobra.NoArgs,
+		Short: "Export all OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers as a bundle",
+		Long: `This command exports all OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers as a versioned
+bundle which can be imported using "hydra import bundle", for example to restore a backup or to clone an environment.
+
+Client secrets are exported in their hashed form. The bundle contains private keys in plain text unless
+` + "`--encryption-secret`" + ` is set, so handle it with care.`,
+		Example: `{{ .CommandPath }} --encryption-secret "$BUNDLE_SECRET" > bundle.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var b backup.Bundle
+			if err := doAdminRequest(cmd, backup.ExportPath, &backup.ExportBundleBody{
+				EncryptionSecret: flagx.MustGetString(cmd, flagEncryptionSecret),
+			}, &b); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Unable to export the bundle: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			e := json.NewEncoder(cmd.OutOrStdout())
+			e.SetIndent("", "  ")
+			return e.Encode(&b)
+		},
+	}
+
+	cmd.Flags().String(flagEncryptionSecret, "", "Encrypt the JSON Web Keys of the bundle with this secret. Must be at least 16 characters long.")
+	return cmd
+}
+
+// doAdminRequest sends the body as JSON to the admin API endpoint and decodes the response into out, if set.
+func doAdminRequest(cmd *cobra.Command, path string, body, out interface{}) error {
+	m, target, err := cliclient.NewClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodPost, urlx.AppendPaths(target, "/admin", path).String(), &buf)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := m.GetConfig().HTTPClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		raw, _ := io.ReadAll(res.Body)
+		return errors.Errorf("expected status code 2xx but got %d: %s", res.StatusCode, raw)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return errors.WithStack(json.NewDecoder(res.Body).Decode(out))
+}
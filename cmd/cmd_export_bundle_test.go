@@ -0,0 +1,61 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/cmd"
+	"github.com/ory/x/cmdx"
+)
+
+func TestExportImportBundle(t *testing.T) {
+	ctx := context.Background()
+
+	export := cmd.NewExportBundleCmd()
+	source := setup(t, export)
+	require.NoError(t, source.ClientManager().CreateClient(ctx, &client.Client{ID: "bundled-client", Secret: "bundled-client-secret"}))
+	_, err := source.KeyManager().GenerateAndPersistKeySet(ctx, "bundled-set", "bundled-key", "ES256", "sig")
+	require.NoError(t, err)
+
+	stdout := cmdx.ExecNoErr(t, export, "--encryption-secret", "a-sufficiently-long-secret")
+	bundle := gjson.Parse(stdout)
+	assert.EqualValues(t, 2, bundle.Get("version").Int())
+	assert.Equal(t, "scrypt", bundle.Get("encryption_key_derivation.algorithm").String())
+	assert.Equal(t, "bundled-client", bundle.Get("clients.0.client_id").String())
+	assert.NotEmpty(t, bundle.Get("encrypted_json_web_key_sets").String())
+	assert.False(t, bundle.Get("json_web_key_sets").Exists())
+
+	file := filepath.Join(t.TempDir(), "bundle.json")
+	require.NoError(t, os.WriteFile(file, []byte(stdout), 0600))
+
+	t.Run("case=imports the bundle", func(t *testing.T) {
+		imp := cmd.NewImportBundleCmd()
+		target := setup(t, imp)
+
+		cmdx.ExecNoErr(t, imp, file, "--encryption-secret", "a-sufficiently-long-secret")
+
+		_, err := target.ClientManager().AuthenticateClient(ctx, "bundled-client", []byte("bundled-client-secret"))
+		require.NoError(t, err)
+		keys, err := target.KeyManager().GetKeySet(ctx, "bundled-set")
+		require.NoError(t, err)
+		assert.Equal(t, "bundled-key", keys.Keys[0].KeyID)
+	})
+
+	t.Run("case=fails without the encryption secret", func(t *testing.T) {
+		imp := cmd.NewImportBundleCmd()
+		setup(t, imp)
+
+		stderr := cmdx.ExecExpectedErr(t, imp, file)
+		assert.Contains(t, stderr, "encrypted")
+	})
+}
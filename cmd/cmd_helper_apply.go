@@ -0,0 +1,477 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	hydra "github.com/ory/hydra-client-go/v2"
+	"github.com/ory/hydra/v2/cmd/cliclient"
+	"github.com/ory/x/stringslice"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	applyActionCreate = "create"
+	applyActionUpdate = "update"
+	applyActionDelete = "delete"
+
+	applyResourceClient = "oauth2-client"
+	applyResourceKeySet = "jwk-set"
+	applyResourceGrant  = "trusted-jwt-grant-issuer"
+
+	applyPageSize = 500
+)
+
+type (
+	// applyState is the desired state read by "hydra apply".
+	applyState struct {
+		Clients                []hydra.OAuth2Client              `json:"clients"`
+		JSONWebKeySets         []applyJSONWebKeySet              `json:"json_web_key_sets"`
+		TrustedJwtGrantIssuers []hydra.TrustOAuth2JwtGrantIssuer `json:"trusted_jwt_grant_issuers"`
+	}
+
+	// applyJSONWebKeySet either lists the keys of the set or describes how to generate the set if it does not exist.
+	applyJSONWebKeySet struct {
+		Set  string             `json:"set"`
+		Alg  string             `json:"alg"`
+		Use  string             `json:"use"`
+		Kid  string             `json:"kid"`
+		Keys []hydra.JsonWebKey `json:"keys"`
+	}
+
+	applyChange struct {
+		Action   string   `json:"action"`
+		Resource string   `json:"resource"`
+		ID       string   `json:"id"`
+		Fields   []string `json:"fields,omitempty"`
+
+		apply func(ctx context.Context) error
+	}
+
+	applyPlanner struct {
+		cmd   *cobra.Command
+		m     *hydra.APIClient
+		prune bool
+	}
+)
+
+// readApplyState reads and merges the desired state from the given YAML or JSON files. "-" reads from STDIN.
+func readApplyState(cmd *cobra.Command, files []string) (*applyState, error) {
+	var state applyState
+	for _, file := range files {
+		var (
+			raw []byte
+			err error
+		)
+		if file == "-" {
+			raw, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			raw, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read %s", file)
+		}
+
+		j, err := yaml.YAMLToJSON(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse %s", file)
+		}
+
+		var current applyState
+		dec := json.NewDecoder(bytes.NewReader(j))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&current); err != nil && !errors.Is(err, io.EOF) {
+			return nil, errors.Wrapf(err, "could not decode %s", file)
+		}
+
+		state.Clients = append(state.Clients, current.Clients...)
+		state.JSONWebKeySets = append(state.JSONWebKeySets, current.JSONWebKeySets...)
+		state.TrustedJwtGrantIssuers = append(state.TrustedJwtGrantIssuers, current.TrustedJwtGrantIssuers...)
+	}
+
+	return &state, state.validate()
+}
+
+func (s *applyState) validate() error {
+	clients := map[string]bool{}
+	for _, c := range s.Clients {
+		id := c.GetClientId()
+		if id == "" {
+			return errors.New("every OAuth 2.0 Client must have a client_id")
+		} else if clients[id] {
+			return errors.Errorf("OAuth 2.0 Client %s is defined more than once", id)
+		}
+		clients[id] = true
+	}
+
+	sets := map[string]bool{}
+	for _, s := range s.JSONWebKeySets {
+		if s.Set == "" {
+			return errors.New("every JSON Web Key Set must have a set ID")
+		} else if sets[s.Set] {
+			return errors.Errorf("JSON Web Key Set %s is defined more than once", s.Set)
+		} else if len(s.Keys) == 0 && s.Alg == "" {
+			return errors.Errorf("JSON Web Key Set %s must either define keys or the alg used to generate them", s.Set)
+		}
+		for _, k := range s.Keys {
+			if k.Kid == "" {
+				return errors.Errorf("every key of JSON Web Key Set %s must have a kid", s.Set)
+			}
+		}
+		sets[s.Set] = true
+	}
+
+	grants := map[string]bool{}
+	for _, g := range s.TrustedJwtGrantIssuers {
+		if g.Issuer == "" || g.Jwk.Kid == "" {
+			return errors.New("every trusted JWT grant issuer must have an issuer and a jwk with a kid")
+		} else if g.GetSubject() == "" && !g.GetAllowAnySubject() {
+			return errors.Errorf("trusted JWT grant issuer %s must either have a subject or allow any subject", g.Issuer)
+		}
+		id := grantIdentity(g.Issuer, g.GetSubject(), g.GetAllowAnySubject(), g.Jwk.Kid)
+		if grants[id] {
+			return errors.Errorf("trusted JWT grant issuer %s is defined more than once", id)
+		}
+		grants[id] = true
+	}
+
+	return nil
+}
+
+// plan computes the changes needed to reconcile the remote state with the desired state.
+func (p *applyPlanner) plan(state *applyState) ([]applyChange, error) {
+	var changes []applyChange
+	for _, f := range []func(*applyState) ([]applyChange, error){p.planKeySets, p.planClients, p.planGrants} {
+		c, err := f(state)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+
+	// Deletions run last so that nothing is removed if creating or updating a resource fails.
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Action != applyActionDelete && changes[j].Action == applyActionDelete
+	})
+	return changes, nil
+}
+
+func (p *applyPlanner) planClients(state *applyState) ([]applyChange, error) {
+	remote := map[string]hydra.OAuth2Client{}
+	pageToken := ""
+	for {
+		list, res, err := p.m.OAuth2Api.ListOAuth2Clients(p.cmd.Context()).PageSize(applyPageSize).PageToken(pageToken).Execute()
+		if err != nil {
+			return nil, err
+		}
+		_ = res.Body.Close()
+
+		for _, c := range list {
+			remote[c.GetClientId()] = c
+		}
+		if pageToken = getPageToken(res); pageToken == "" {
+			break
+		}
+	}
+
+	var changes []applyChange
+	for _, c := range state.Clients {
+		c := c
+		id := c.GetClientId()
+		actual, ok := remote[id]
+		delete(remote, id)
+
+		if !ok {
+			changes = append(changes, applyChange{Action: applyActionCreate, Resource: applyResourceClient, ID: id, apply: func(ctx context.Context) error {
+				created, _, err := p.m.OAuth2Api.CreateOAuth2Client(ctx).OAuth2Client(c).Execute() //nolint:bodyclose
+				if err != nil {
+					return err
+				}
+				if c.ClientSecret == nil && created.ClientSecret != nil {
+					_, _ = fmt.Fprintf(p.cmd.ErrOrStderr(), "Generated client secret for OAuth 2.0 Client %s: %s\n", id, created.GetClientSecret())
+				}
+				return nil
+			}})
+			continue
+		}
+
+		fields, err := changedFields(c, actual, "client_secret")
+		if err != nil {
+			return nil, err
+		} else if len(fields) == 0 {
+			continue
+		}
+
+		changes = append(changes, applyChange{Action: applyActionUpdate, Resource: applyResourceClient, ID: id, Fields: fields, apply: func(ctx context.Context) error {
+			_, _, err := p.m.OAuth2Api.SetOAuth2Client(ctx, id).OAuth2Client(c).Execute() //nolint:bodyclose
+			return err
+		}})
+	}
+
+	if p.prune {
+		for id := range remote {
+			id := id
+			changes = append(changes, applyChange{Action: applyActionDelete, Resource: applyResourceClient, ID: id, apply: func(ctx context.Context) error {
+				_, err := p.m.OAuth2Api.DeleteOAuth2Client(ctx, id).Execute() //nolint:bodyclose
+				return err
+			}})
+		}
+	}
+
+	sortChanges(changes)
+	return changes, nil
+}
+
+// planKeySets never prunes JSON Web Key Sets, because the admin API can not list them and Ory Hydra manages some
+// of them itself.
+func (p *applyPlanner) planKeySets(state *applyState) ([]applyChange, error) {
+	var changes []applyChange
+	for _, s := range state.JSONWebKeySets {
+		actual, res, err := p.m.JwkApi.GetJsonWebKeySet(p.cmd.Context(), s.Set).Execute() //nolint:bodyclose
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			changes = append(changes, applyChange{Action: applyActionCreate, Resource: applyResourceKeySet, ID: s.Set, apply: p.applyKeySet(s)})
+			continue
+		} else if err != nil {
+			return nil, err
+		} else if len(s.Keys) == 0 {
+			continue
+		}
+
+		remote := map[string]hydra.JsonWebKey{}
+		for _, k := range actual.Keys {
+			remote[k.Kid] = k
+		}
+
+		var fields []string
+		for _, k := range s.Keys {
+			if a, ok := remote[k.Kid]; !ok {
+				fields = append(fields, "+"+k.Kid)
+			} else if diff, err := changedFields(k, a); err != nil {
+				return nil, err
+			} else if len(diff) > 0 {
+				fields = append(fields, "~"+k.Kid)
+			}
+			delete(remote, k.Kid)
+		}
+		for kid := range remote {
+			fields = append(fields, "-"+kid)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		sort.Strings(fields)
+		changes = append(changes, applyChange{Action: applyActionUpdate, Resource: applyResourceKeySet, ID: s.Set, Fields: fields, apply: p.applyKeySet(s)})
+	}
+	return changes, nil
+}
+
+// applyKeySet generates the set or replaces it with the listed keys.
+func (p *applyPlanner) applyKeySet(s applyJSONWebKeySet) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if len(s.Keys) > 0 {
+			_, _, err := p.m.JwkApi.SetJsonWebKeySet(ctx, s.Set).JsonWebKeySet(hydra.JsonWebKeySet{Keys: s.Keys}).Execute() //nolint:bodyclose
+			return err
+		}
+
+		use := s.Use
+		if use == "" {
+			use = "sig"
+		}
+		_, _, err := p.m.JwkApi.CreateJsonWebKeySet(ctx, s.Set).CreateJsonWebKeySet(hydra.CreateJsonWebKeySet{Alg: s.Alg, Use: use, Kid: s.Kid}).Execute() //nolint:bodyclose
+		return err
+	}
+}
+
+func (p *applyPlanner) planGrants(state *applyState) ([]applyChange, error) {
+	list, err := listAllTrustedJwtGrantIssuers(p.cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := map[string]hydra.TrustedOAuth2JwtGrantIssuer{}
+	for _, g := range list {
+		key := g.GetPublicKey()
+		remote[grantIdentity(g.GetIssuer(), g.GetSubject(), g.GetAllowAnySubject(), key.GetKid())] = g
+	}
+
+	var changes []applyChange
+	for _, g := range state.TrustedJwtGrantIssuers {
+		g := g
+		id := grantIdentity(g.Issuer, g.GetSubject(), g.GetAllowAnySubject(), g.Jwk.Kid)
+		actual, ok := remote[id]
+		delete(remote, id)
+
+		create := func(ctx context.Context) error {
+			_, _, err := p.m.OAuth2Api.TrustOAuth2JwtGrantIssuer(ctx).TrustOAuth2JwtGrantIssuer(g).Execute() //nolint:bodyclose
+			return err
+		}
+
+		if !ok {
+			changes = append(changes, applyChange{Action: applyActionCreate, Resource: applyResourceGrant, ID: id, apply: create})
+			continue
+		}
+
+		var fields []string
+		if !sameStrings(g.Scope, actual.Scope) {
+			fields = append(fields, "scope")
+		}
+		if !g.ExpiresAt.Truncate(time.Second).Equal(actual.GetExpiresAt().Truncate(time.Second)) {
+			fields = append(fields, "expires_at")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Trusted JWT grant issuers can not be updated, so they are replaced.
+		grantID := actual.GetId()
+		changes = append(changes, applyChange{Action: applyActionUpdate, Resource: applyResourceGrant, ID: id, Fields: fields, apply: func(ctx context.Context) error {
+			if _, err := p.m.OAuth2Api.DeleteTrustedOAuth2JwtGrantIssuer(ctx, grantID).Execute(); err != nil { //nolint:bodyclose
+				return err
+			}
+			return create(ctx)
+		}})
+	}
+
+	if p.prune {
+		for id, g := range remote {
+			grantID := g.GetId()
+			changes = append(changes, applyChange{Action: applyActionDelete, Resource: applyResourceGrant, ID: id, apply: func(ctx context.Context) error {
+				_, err := p.m.OAuth2Api.DeleteTrustedOAuth2JwtGrantIssuer(ctx, grantID).Execute() //nolint:bodyclose
+				return err
+			}})
+		}
+	}
+
+	sortChanges(changes)
+	return changes, nil
+}
+
+// listAllTrustedJwtGrantIssuers follows the pagination links of the admin API, which the SDK does not support for
+// this endpoint.
+func listAllTrustedJwtGrantIssuers(cmd *cobra.Command) ([]hydra.TrustedOAuth2JwtGrantIssuer, error) {
+	m, target, err := cliclient.NewClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []hydra.TrustedOAuth2JwtGrantIssuer
+	u := urlx.AppendPaths(target, "/admin/trust/grants/jwt-bearer/issuers")
+	query := url.Values{"page_size": {fmt.Sprintf("%d", applyPageSize)}}
+	for {
+		u.RawQuery = query.Encode()
+		req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		res, err := m.GetConfig().HTTPClient.Do(req)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var page []hydra.TrustedOAuth2JwtGrantIssuer
+		if res.StatusCode != http.StatusOK {
+			raw, _ := io.ReadAll(res.Body)
+			_ = res.Body.Close()
+			return nil, errors.Errorf("expected status code 200 but got %d: %s", res.StatusCode, raw)
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		all = append(all, page...)
+		pageToken := getPageToken(res)
+		if pageToken == "" {
+			return all, nil
+		}
+		query.Set("page_token", pageToken)
+	}
+}
+
+func grantIdentity(issuer, subject string, anySubject bool, kid string) string {
+	if anySubject {
+		subject = "*"
+	}
+	return strings.Join([]string{issuer, subject, kid}, " ")
+}
+
+// changedFields returns the top-level JSON fields set in desired which differ in actual. Empty values are considered
+// equal to absent ones.
+func changedFields(desired, actual interface{}, ignore ...string) ([]string, error) {
+	d, err := toJSONMap(desired)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toJSONMap(actual)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for k, v := range d {
+		if stringslice.Has(ignore, k) || (isEmptyJSON(v) && isEmptyJSON(a[k])) {
+			continue
+		}
+		if !reflect.DeepEqual(v, a[k]) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var m map[string]interface{}
+	return m, errors.WithStack(json.Unmarshal(raw, &m))
+}
+
+func isEmptyJSON(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func sameStrings(a, b []string) bool {
+	a, b = append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
+
+func sortChanges(changes []applyChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+}
@@ -73,7 +73,7 @@ func registerClientFlags(flags *pflag.FlagSet) {
 	flags.String(flagClientTOSURI, "", "A URL string that points to a human-readable terms of service document for the client that describes a contractual relationship between the end-user and the client that the end-user accepts when authorizing the client.")
 	flags.String(flagClientClientURI, "", "A URL string of a web page providing information about the client")
 	flags.String(flagClientLogoURI, "", "A URL string that references a logo for the client")
-	flags.StringSlice(flagClientAllowedCORSOrigin, []string{}, "The list of URLs allowed to make CORS requests. Requires CORS_ENABLED.")
+	flags.StringSlice(flagClientAllowedCORSOrigin, []string{}, "The list of URLs allowed to make CORS requests. Requires CORS_ENABLED or oauth2.client_cors.enabled.")
 	flags.String(flagClientSubjectType, "public", "A identifier algorithm. Valid values are `public` and `pairwise`.")
 	flags.String(flagClientSecret, "", "Provide the client's secret.")
 	flags.String(flagClientName, "", "The client's name.")
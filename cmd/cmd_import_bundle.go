@@ -0,0 +1,66 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/v2/backup"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+)
+
+func NewImportBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle [file.json]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Import a bundle created by \"hydra export bundle\" from a file or STDIN",
+		Long: `This command imports a bundle created by "hydra export bundle" in a single transaction.
+
+OAuth 2.0 Clients, JSON Web Keys and trusted JWT grant issuers which already exist are replaced, all other
+resources are left untouched.`,
+		Example: `{{ .CommandPath }} bundle.json --encryption-secret "$BUNDLE_SECRET"
+
+Alternatively:
+
+	cat bundle.json | {{ .CommandPath }} --encryption-secret "$BUNDLE_SECRET"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if len(args) == 1 {
+				f, err := os.Open(args[0])
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not open file %s: %s\n", args[0], err)
+					return cmdx.FailSilently(cmd)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			var b backup.Bundle
+			if err := json.NewDecoder(in).Decode(&b); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not decode the bundle: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			if err := doAdminRequest(cmd, backup.ImportPath, &backup.ImportBundleBody{
+				Bundle:           &b,
+				EncryptionSecret: flagx.MustGetString(cmd, flagEncryptionSecret),
+			}, nil); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Unable to import the bundle: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Imported %d OAuth 2.0 Clients and %d trusted JWT grant issuers.\n", len(b.Clients), len(b.TrustedJwtGrantIssuers))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagEncryptionSecret, "", "The secret the JSON Web Keys of the bundle were encrypted with.")
+	return cmd
+}
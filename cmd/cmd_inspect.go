@@ -0,0 +1,20 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/x/cmdx"
+)
+
+func NewInspectCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "inspect",
+		Short: "Inspect resources",
+	}
+	cmdx.RegisterHTTPClientFlags(cmd.PersistentFlags())
+	cmdx.RegisterFormatFlags(cmd.PersistentFlags())
+	return cmd
+}
@@ -0,0 +1,182 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/v2/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	flagInspectJWKSURL    = "jwks-url"
+	flagInspectIssuer     = "issuer"
+	flagInspectSkipVerify = "skip-verify"
+)
+
+func NewInspectTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token the-token",
+		Args:  cobra.ExactArgs(1),
+		Short: "Inspect an OAuth 2.0 Access, Refresh or OpenID Connect ID Token",
+		Long: `Inspect an OAuth 2.0 Access, Refresh or OpenID Connect ID Token.
+
+JSON Web Tokens are decoded locally and their signature is verified against the JSON Web Key Set of the issuer. The
+issuer is never taken from the token itself: either provide the JSON Web Key Set URL, or the expected issuer whose
+"/.well-known/jwks.json" endpoint is used. If the issuer is provided, the token's "iss" claim must match it.
+
+Opaque tokens are introspected using the admin API.`,
+		Example: `{{ .CommandPath }} eyJhbGciOiJSUzI1NiIsImtpZCI6I...
+{{ .CommandPath }} --issuer https://hydra.example.com/ eyJhbGciOiJSUzI1NiIsImtpZCI6I...
+{{ .CommandPath }} --jwks-url https://hydra.example.com/.well-known/jwks.json eyJhbGciOiJSUzI1NiIsImtpZCI6I...
+{{ .CommandPath }} --endpoint http://localhost:4445 ory_at_AYjcyMzY3ZDhiNmJkNTY...`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token := args[0]
+			if strings.Count(token, ".") != 2 {
+				return inspectOpaqueToken(cmd, token)
+			}
+
+			result, err := inspectJWT(cmd, token)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Unable to inspect the token: %s\n", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			cmdx.PrintRow(cmd, result)
+			return nil
+		},
+	}
+	cmd.Flags().String(flagInspectJWKSURL, "", "The URL of the JSON Web Key Set used to verify the signature of JSON Web Tokens. Defaults to the JSON Web Key Set of the issuer.")
+	cmd.Flags().String(flagInspectIssuer, "", "The expected issuer of JSON Web Tokens. Tokens of other issuers are rejected.")
+	cmd.Flags().Bool(flagInspectSkipVerify, false, "Decode JSON Web Tokens without verifying their signature.")
+	return cmd
+}
+
+func inspectOpaqueToken(cmd *cobra.Command, token string) error {
+	client, _, err := cliclient.NewClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	result, _, err := client.OAuth2Api.IntrospectOAuth2Token(cmd.Context()).Token(token).Execute() //nolint:bodyclose
+	if err != nil {
+		return cmdx.PrintOpenAPIError(cmd, err)
+	}
+
+	cmdx.PrintRow(cmd, &outputInspectedToken{
+		Format:        "opaque",
+		Active:        result.Active,
+		Introspection: result,
+	})
+	return nil
+}
+
+func inspectJWT(cmd *cobra.Command, token string) (*outputInspectedToken, error) {
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "the token is not a valid JSON Web Token")
+	}
+
+	result := &outputInspectedToken{Format: "jwt", JOSEHeader: map[string]interface{}{}}
+	if err := json.Unmarshal(jws.UnsafePayloadWithoutVerification(), &result.Claims); err != nil {
+		return nil, errors.Wrap(err, "the token payload is not a valid JSON object")
+	}
+
+	if len(jws.Signatures) > 0 {
+		header := jws.Signatures[0].Header
+		result.JOSEHeader["alg"] = header.Algorithm
+		if header.KeyID != "" {
+			result.JOSEHeader["kid"] = header.KeyID
+		}
+		for k, v := range header.ExtraHeaders {
+			result.JOSEHeader[string(k)] = v
+		}
+	}
+
+	result.Active = true
+	if exp, ok := result.Claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(time.Now()) {
+		result.Active = false
+	}
+	if nbf, ok := result.Claims["nbf"].(float64); ok && time.Unix(int64(nbf), 0).After(time.Now()) {
+		result.Active = false
+	}
+
+	if flagx.MustGetBool(cmd, flagInspectSkipVerify) {
+		return result, nil
+	}
+
+	jwksURL := flagx.MustGetString(cmd, flagInspectJWKSURL)
+	expectedIssuer := flagx.MustGetString(cmd, flagInspectIssuer)
+	if expectedIssuer != "" {
+		// The issuer claim is not verified yet, so it must never be used to discover the keys.
+		if iss, _ := result.Claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(expectedIssuer, "/") {
+			return nil, errors.Errorf("the token was issued by %q, not by %q", iss, expectedIssuer)
+		}
+		if jwksURL == "" {
+			issuer, err := urlx.Parse(expectedIssuer)
+			if err != nil {
+				return nil, errors.Wrapf(err, "the value of --%s is not a valid URL", flagInspectIssuer)
+			}
+			jwksURL = urlx.AppendPaths(issuer, "/.well-known/jwks.json").String()
+		}
+	}
+	if jwksURL == "" {
+		return nil, errors.Errorf("please provide the expected issuer using --%s or the JSON Web Key Set URL using --%s, or use --%s", flagInspectIssuer, flagInspectJWKSURL, flagInspectSkipVerify)
+	}
+
+	keys, err := fetchJSONWebKeySet(cmd, jwksURL)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := false
+	for _, key := range keys.Keys {
+		if kid, ok := result.JOSEHeader["kid"].(string); ok && kid != key.KeyID {
+			continue
+		}
+		if _, err := jws.Verify(key.Public()); err == nil {
+			verified = true
+			break
+		}
+	}
+
+	result.SignatureVerified = &verified
+	result.Active = result.Active && verified
+	return result, nil
+}
+
+func fetchJSONWebKeySet(cmd *cobra.Command, location string) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(cmd.Context(), "GET", location, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	hc := &http.Client{Timeout: 10 * time.Second}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to fetch the JSON Web Key Set from %s", location)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to fetch the JSON Web Key Set from %s: expected status code 200 but got %d", location, res.StatusCode)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&keys); err != nil {
+		return nil, errors.Wrapf(err, "unable to decode the JSON Web Key Set from %s", location)
+	}
+	return &keys, nil
+}
@@ -0,0 +1,89 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/ory/hydra/v2/cmd"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/x/cmdx"
+)
+
+func TestInspectToken(t *testing.T) {
+	ctx := context.Background()
+	c := cmd.NewInspectTokenCmd()
+	public, admin, reg := setupRoutes(t, c)
+	require.NoError(t, c.Flags().Set(cmdx.FlagEndpoint, admin.URL))
+
+	expected := createClientCredentialsClient(t, reg)
+	cc := clientcredentials.Config{
+		ClientID:     expected.GetID(),
+		ClientSecret: expected.Secret,
+		TokenURL:     public.URL + "/oauth2/token",
+		Scopes:       []string{},
+	}
+
+	t.Run("case=introspects opaque token", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+		token, err := cc.Token(ctx)
+		require.NoError(t, err)
+
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, token.AccessToken))
+		assert.Equal(t, "opaque", actual.Get("format").String(), actual.Raw)
+		assert.True(t, actual.Get("active").Bool(), actual.Raw)
+		assert.Equal(t, expected.GetID(), actual.Get("introspection.client_id").String(), actual.Raw)
+	})
+
+	t.Run("case=decodes and verifies JWT", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "jwt")
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque") })
+		token, err := cc.Token(ctx)
+		require.NoError(t, err)
+
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, "--jwks-url", public.URL+"/.well-known/jwks.json", token.AccessToken))
+		assert.Equal(t, "jwt", actual.Get("format").String(), actual.Raw)
+		assert.True(t, actual.Get("active").Bool(), actual.Raw)
+		assert.True(t, actual.Get("signature_verified").Bool(), actual.Raw)
+		assert.Equal(t, expected.GetID(), actual.Get("claims.client_id").String(), actual.Raw)
+		assert.NotEmpty(t, actual.Get("header.kid").String(), actual.Raw)
+
+		actual = gjson.Parse(cmdx.ExecNoErr(t, c, "--skip-verify", token.AccessToken))
+		assert.True(t, actual.Get("active").Bool(), actual.Raw)
+		assert.False(t, actual.Get("signature_verified").Exists(), actual.Raw)
+	})
+
+	t.Run("case=verifies JWT against the expected issuer", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "jwt")
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque") })
+		token, err := cc.Token(ctx)
+		require.NoError(t, err)
+
+		actual := gjson.Parse(cmdx.ExecNoErr(t, c, "--jwks-url=", "--skip-verify=false", "--issuer", reg.Config().IssuerURL(ctx).String(), token.AccessToken))
+		assert.True(t, actual.Get("active").Bool(), actual.Raw)
+		assert.True(t, actual.Get("signature_verified").Bool(), actual.Raw)
+
+		stdOut, stdErr, err := cmdx.Exec(t, c, nil, "--jwks-url", public.URL+"/.well-known/jwks.json", "--skip-verify=false", "--issuer", "https://evil.example/", token.AccessToken)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Empty(t, stdOut)
+		assert.Contains(t, stdErr, "https://evil.example/")
+
+		stdOut, stdErr, err = cmdx.Exec(t, c, nil, "--jwks-url=", "--skip-verify=false", "--issuer=", token.AccessToken)
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Empty(t, stdOut)
+		assert.Contains(t, stdErr, "--issuer")
+	})
+
+	t.Run("case=rejects invalid JWT", func(t *testing.T) {
+		stdOut, _, err := cmdx.Exec(t, c, nil, "--skip-verify", "not.a.jwt")
+		require.ErrorIs(t, err, cmdx.ErrNoPrintButFail)
+		assert.Empty(t, stdOut)
+	})
+}
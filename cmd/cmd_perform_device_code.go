@@ -0,0 +1,93 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+
+	"github.com/ory/hydra/v2/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/stringsx"
+	"github.com/ory/x/urlx"
+)
+
+func NewPerformDeviceCodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "device-code",
+		Args:    cobra.NoArgs,
+		Example: `{{ .CommandPath }} --client-id ... --scope openid,offline`,
+		Short:   "Perform the OAuth2 Device Authorization Grant",
+		Long: `Performs the OAuth 2.0 Device Authorization Grant (RFC 8628).
+
+This command requests a device and user code, prints the verification URL and user code which must be entered on
+another device, and polls the token endpoint until the authorization request was approved, denied, or has expired.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hc, target, err := cliclient.NewClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			ctx := context.WithValue(cmd.Context(), oauth2.HTTPClient, hc)
+
+			clientID := flagx.MustGetString(cmd, "client-id")
+			if clientID == "" {
+				fmt.Print(cmd.UsageString())
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Please provide a Client ID using flag --client-id, or environment variable OAUTH2_CLIENT_ID.")
+				return cmdx.FailSilently(cmd)
+			}
+
+			conf := oauth2.Config{
+				ClientID:     clientID,
+				ClientSecret: flagx.MustGetString(cmd, "client-secret"),
+				Endpoint: oauth2.Endpoint{
+					DeviceAuthURL: stringsx.Coalesce(flagx.MustGetString(cmd, "device-auth-url"), urlx.AppendPaths(target, "/oauth2/device/auth").String()),
+					TokenURL:      stringsx.Coalesce(flagx.MustGetString(cmd, "token-url"), urlx.AppendPaths(target, "/oauth2/token").String()),
+				},
+				Scopes: flagx.MustGetStringSlice(cmd, "scope"),
+			}
+
+			var opts []oauth2.AuthCodeOption
+			if audience := flagx.MustGetStringSlice(cmd, "audience"); len(audience) > 0 {
+				opts = append(opts, oauth2.SetAuthURLParam("audience", strings.Join(audience, " ")))
+			}
+
+			da, err := conf.DeviceAuth(ctx, opts...)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not request a device code because: %s", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "To authorize this device, open the following URL in a browser and enter the code %s:\n\n\t%s\n\n", da.UserCode, da.VerificationURI)
+			if da.VerificationURIComplete != "" {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Alternatively, open the following URL which already contains the code:\n\n\t%s\n\n", da.VerificationURIComplete)
+			}
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "Waiting for the authorization request to be approved...")
+
+			t, err := conf.DeviceAccessToken(ctx, da)
+			if err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Could not retrieve access token because: %s", err)
+				return cmdx.FailSilently(cmd)
+			}
+
+			cmdx.PrintRow(cmd, (*outputOAuth2Token)(t))
+			return nil
+		},
+	}
+
+	cmd.Flags().String("client-id", os.Getenv("OAUTH2_CLIENT_ID"), "Use the provided OAuth 2.0 Client ID, defaults to environment variable OAUTH2_CLIENT_ID.")
+	cmd.Flags().String("client-secret", os.Getenv("OAUTH2_CLIENT_SECRET"), "Use the provided OAuth 2.0 Client Secret, defaults to environment variable OAUTH2_CLIENT_SECRET. Not required for public clients.")
+	cmd.Flags().StringSlice("scope", []string{"offline", "openid"}, "OAuth2 scope to request.")
+	cmd.Flags().StringSlice("audience", []string{}, "Request a specific OAuth 2.0 Access Token Audience.")
+	cmd.Flags().String("device-auth-url", "", "Usually it is enough to specify the `endpoint` flag, but if you want to force the device authorization url, use this flag.")
+	cmd.Flags().String("token-url", "", "Usually it is enough to specify the `endpoint` flag, but if you want to force the token url, use this flag.")
+
+	return cmd
+}
@@ -0,0 +1,71 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/v2/cmd"
+	"github.com/ory/x/cmdx"
+)
+
+func TestPerformDeviceCodeGrant(t *testing.T) {
+	var polls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/device/auth", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "my-client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "openid offline", r.PostForm.Get("scope"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "USER-CODE",
+			"verification_uri": "https://hydra.example.com/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:device_code", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "device-code", r.PostForm.Get("device_code"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&polls, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "authorization_pending"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := cmd.NewPerformDeviceCodeCmd()
+	cmdx.RegisterHTTPClientFlags(c.Flags())
+	cmdx.RegisterFormatFlags(c.Flags())
+	require.NoError(t, c.Flags().Set(cmdx.FlagFormat, string(cmdx.FormatJSON)))
+	require.NoError(t, c.Flags().Set(cmdx.FlagEndpoint, server.URL))
+
+	stdOut, stdErr, err := cmdx.Exec(t, c, nil, "--client-id", "my-client", "--scope", "openid,offline")
+	require.NoError(t, err, "%s\n%s", stdOut, stdErr)
+
+	assert.Contains(t, stdErr, "USER-CODE")
+	assert.Contains(t, stdErr, "https://hydra.example.com/device")
+	assert.Equal(t, "access-token", gjson.Get(stdOut, "access_token").String(), stdOut)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&polls))
+}
@@ -0,0 +1,40 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/hydra/v2/cmd/cli"
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/servicelocatorx"
+)
+
+func NewDoctorCmd(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier, cOpts []configx.OptionModifier) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Diagnose configuration and connectivity problems",
+		Example: `hydra doctor -c /path/to/config.yml`,
+		Long: `This command runs a series of diagnostic checks and prints actionable findings. It
+
+- validates the configuration against the configuration schema,
+- verifies the issuer URL and TLS settings,
+- checks that the system secret is set,
+- probes the Hardware Security Module, if enabled,
+- checks that the database is reachable and all migrations have been applied.
+
+The configuration is read the same way as for "hydra serve", from configuration files and environment variables.
+The command exits with a non-zero exit code if any check fails.`,
+		Args: cobra.NoArgs,
+		RunE: cli.NewHandler(slOpts, dOpts, cOpts).Doctor.Doctor,
+	}
+
+	configx.RegisterFlags(cmd.PersistentFlags())
+	cmdx.RegisterFormatFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().Bool("dev", false, "Run the checks as if development mode was enabled.")
+
+	return cmd
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/ory/x/servicelocatorx"
 
 	"github.com/ory/hydra/v2/cmd/cli"
+	"github.com/ory/x/cmdx"
 	"github.com/ory/x/configx"
 )
 
@@ -61,6 +62,14 @@ Janitor can be used in several ways.
    or any combination of them
 
 		hydra janitor --tokens --requests --grants {database-url}
+
+6. Reporting the run
+
+   The rows deleted per table, the duration and the errors of every cleanup are printed when
+   the run completes. Use --format json to print them as JSON, and --metrics-textfile to write
+   them as Prometheus metrics, e.g. to alert when a cleanup reaches --limit on every run.
+
+		hydra janitor --tokens --format json --metrics-textfile /var/lib/node_exporter/hydra_janitor.prom {database-url}
 `,
 		RunE: cli.NewHandler(slOpts, dOpts, cOpts).Janitor.RunE,
 		Args: cli.NewHandler(slOpts, dOpts, cOpts).Janitor.Args,
@@ -75,6 +84,8 @@ Janitor can be used in several ways.
 	cmd.Flags().Bool(cli.OnlyTokens, false, "This will only run the cleanup on tokens and will skip requests and trust relationships cleanup.")
 	cmd.Flags().Bool(cli.OnlyGrants, false, "This will only run the cleanup on trust relationships and will skip requests and token cleanup.")
 	cmd.Flags().BoolP(cli.ReadFromEnv, "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	cmd.Flags().String(cli.MetricsTextfile, "", "If set, writes the metrics of the run in the Prometheus text format to this file, e.g. for the textfile collector of the node exporter.")
+	cmdx.RegisterJSONFormatFlags(cmd.Flags())
 	configx.RegisterFlags(cmd.PersistentFlags())
 	return cmd
 
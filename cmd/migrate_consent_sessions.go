@@ -0,0 +1,41 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/x/configx"
+	"github.com/ory/x/servicelocatorx"
+
+	"github.com/ory/hydra/v2/cmd/cli"
+	"github.com/ory/hydra/v2/driver"
+)
+
+func NewMigrateConsentSessionsCmd(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier, cOpts []configx.OptionModifier) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "consent-sessions",
+		Short: "Encrypt the data of existing consent sessions",
+		Long: `This command encrypts the ID token claims, access token claims and context of consent sessions which were stored
+before oauth2.session.encrypt_at_rest was enabled, or before Ory Hydra encrypted consent sessions at all. New consent
+sessions are encrypted when they are stored.
+
+The consent sessions are encrypted with the system secret in batches, so this command can run while Ory Hydra is
+serving requests, and can be interrupted and run again. Consent sessions which are already encrypted are skipped.
+
+This command reads the system secret from the configuration, so it requires flag --read-from-env.
+
+### WARNING ###
+
+Before running this command on an existing database, create a back up! Older versions of Ory Hydra can not read
+encrypted consent sessions.`,
+		RunE: cli.NewHandler(slOpts, dOpts, cOpts).Migration.MigrateConsentSessions,
+	}
+
+	cmd.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	cmd.Flags().BoolP("yes", "y", false, "If set all confirmation requests are accepted without user interaction.")
+	cmd.Flags().Int(cli.BatchSize, 100, "Define how many consent sessions are encrypted with each iteration.")
+
+	return cmd
+}
@@ -27,6 +27,16 @@ You can read in the database URL using the -e flag, for example:
 	export DSN=...
 	hydra migrate sql -e
 
+To upgrade without downtime, run the migrations in two phases. First, apply only backwards-compatible (expand)
+migrations while the previous version of Hydra is still serving traffic:
+
+	hydra migrate sql -e --expand-only
+
+Then roll out the new version and, once no instance of the previous version is running anymore, apply the remaining
+(contract) migrations:
+
+	hydra migrate sql -e
+
 ### WARNING ###
 
 Before running this command on an existing database, create a back up!`,
@@ -35,6 +45,7 @@ Before running this command on an existing database, create a back up!`,
 
 	cmd.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
 	cmd.Flags().BoolP("yes", "y", false, "If set all confirmation requests are accepted without user interaction.")
+	cmd.Flags().Bool("expand-only", false, "If set, only backwards-compatible migrations are applied so that the previous version of Hydra can keep running against the database.")
 
 	return cmd
 }
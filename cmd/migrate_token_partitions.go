@@ -0,0 +1,43 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/x/configx"
+	"github.com/ory/x/servicelocatorx"
+
+	"github.com/ory/hydra/v2/cmd/cli"
+	"github.com/ory/hydra/v2/driver"
+)
+
+func NewMigrateTokenPartitionsCmd(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier, cOpts []configx.OptionModifier) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token-partitions <database-url>",
+		Short: "Partition the access and refresh token tables by time",
+		Long: `This command converts the access and refresh token tables into tables which are partitioned by the time the
+tokens were requested. Only PostgreSQL 11 and later is supported.
+
+The janitor then drops partitions which only contain expired tokens instead of deleting the tokens row by row, and
+creates new partitions ahead of time. Configure the time range of each partition with db.token_partitions.interval
+and make sure the janitor runs at least once within db.token_partitions.premake intervals. Partitions contain the
+tokens of all networks and are dropped based on the token lifespans of the janitor's configuration.
+
+The existing tokens are moved into a single partition without copying them, which is dropped once all of them
+expired. Looking up a token checks the primary key of every partition, so keep the number of partitions small.
+
+Run the SQL migrations before this command. The conversion locks the token tables until it completes.
+
+### WARNING ###
+
+Before running this command on an existing database, create a back up!`,
+		RunE: cli.NewHandler(slOpts, dOpts, cOpts).Migration.MigrateTokenPartitions,
+	}
+
+	cmd.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	cmd.Flags().BoolP("yes", "y", false, "If set all confirmation requests are accepted without user interaction.")
+
+	return cmd
+}
@@ -0,0 +1,43 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/x/configx"
+	"github.com/ory/x/servicelocatorx"
+
+	"github.com/ory/hydra/v2/cmd/cli"
+	"github.com/ory/hydra/v2/driver"
+)
+
+func NewMigrateTokenSessionsCmd(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier, cOpts []configx.OptionModifier) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token-sessions",
+		Short: "Encrypt the sessions of existing OAuth 2.0 tokens with the current system secret",
+		Long: `This command encrypts the sessions of OAuth 2.0 tokens, which hold the claims and extra data of the tokens, if
+they were stored before oauth2.session.encrypt_at_rest was enabled. It also encrypts sessions which were encrypted with
+a rotated system secret with the current system secret again, so that the rotated secret can be removed afterwards.
+New sessions are encrypted when they are stored.
+
+The sessions are encrypted in batches, so this command can run while Ory Hydra is serving requests, and can be
+interrupted and run again. Sessions which are already encrypted with the current system secret are skipped. Sessions
+stored in Redis are not encrypted again, because they expire shortly.
+
+This command reads the system secrets from the configuration, so it requires flag --read-from-env.
+
+### WARNING ###
+
+Before running this command on an existing database, create a back up! Sessions can no longer be read by Ory Hydra
+instances which are not configured with the current system secret.`,
+		RunE: cli.NewHandler(slOpts, dOpts, cOpts).Migration.MigrateTokenSessions,
+	}
+
+	cmd.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	cmd.Flags().BoolP("yes", "y", false, "If set all confirmation requests are accepted without user interaction.")
+	cmd.Flags().Int(cli.BatchSize, 100, "Define how many token sessions are encrypted with each iteration.")
+
+	return cmd
+}
@@ -0,0 +1,60 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+)
+
+type (
+	outputApplyChange    applyChange
+	outputApplyChangeSet struct {
+		changes []applyChange
+	}
+)
+
+func (outputApplyChange) Header() []string {
+	return []string{"ACTION", "RESOURCE", "ID", "CHANGED FIELDS"}
+}
+
+func (c outputApplyChange) Columns() []string {
+	return []string{
+		c.Action,
+		c.Resource,
+		c.ID,
+		strings.Join(c.Fields, ", "),
+	}
+}
+
+func (c outputApplyChange) Interface() interface{} {
+	return applyChange(c)
+}
+
+func (outputApplyChangeSet) Header() []string {
+	return outputApplyChange{}.Header()
+}
+
+func (c outputApplyChangeSet) Table() [][]string {
+	rows := make([][]string, len(c.changes))
+	for i, change := range c.changes {
+		rows[i] = outputApplyChange(change).Columns()
+	}
+	return rows
+}
+
+func (c outputApplyChangeSet) Interface() interface{} {
+	return c.changes
+}
+
+func (c outputApplyChangeSet) Len() int {
+	return len(c.changes)
+}
+
+func (c outputApplyChangeSet) IDs() []string {
+	ids := make([]string, len(c.changes))
+	for i, change := range c.changes {
+		ids[i] = change.ID
+	}
+	return ids
+}
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ory/x/pointerx"
@@ -54,3 +55,67 @@ func (i outputOAuth2TokenIntrospection) Columns() []string {
 func (i outputOAuth2TokenIntrospection) Interface() interface{} {
 	return i
 }
+
+type outputInspectedToken struct {
+	// Format is either "jwt" or "opaque".
+	Format string `json:"format"`
+	Active bool   `json:"active"`
+
+	// SignatureVerified is only set for JSON Web Tokens whose signature was checked.
+	SignatureVerified *bool                  `json:"signature_verified,omitempty"`
+	JOSEHeader        map[string]interface{} `json:"header,omitempty"`
+	Claims            map[string]interface{} `json:"claims,omitempty"`
+
+	Introspection *hydra.IntrospectedOAuth2Token `json:"introspection,omitempty"`
+}
+
+func (outputInspectedToken) Header() []string {
+	return []string{"FORMAT", "ACTIVE", "SIGNATURE", "SUBJECT", "CLIENT ID", "SCOPE", "EXPIRY"}
+}
+
+func (i outputInspectedToken) Columns() []string {
+	signature := "unverified"
+	if i.SignatureVerified != nil && *i.SignatureVerified {
+		signature = "valid"
+	} else if i.SignatureVerified != nil {
+		signature = "invalid"
+	} else if i.Format == "opaque" {
+		signature = "n/a"
+	}
+
+	if i.Introspection != nil {
+		introspection := outputOAuth2TokenIntrospection(*i.Introspection).Columns()
+		return []string{i.Format, introspection[0], signature, introspection[1], introspection[2], introspection[3], introspection[4]}
+	}
+
+	claim := func(name string) string {
+		switch v := i.Claims[name].(type) {
+		case nil:
+			return ""
+		case []interface{}:
+			s := make([]string, len(v))
+			for k := range v {
+				s[k] = fmt.Sprintf("%v", v[k])
+			}
+			return strings.Join(s, " ")
+		default:
+			return fmt.Sprintf("%v", v)
+		}
+	}
+
+	scope := claim("scp")
+	if scope == "" {
+		scope = claim("scope")
+	}
+
+	var expiry string
+	if exp, ok := i.Claims["exp"].(float64); ok {
+		expiry = time.Unix(int64(exp), 0).String()
+	}
+
+	return []string{i.Format, fmt.Sprintf("%v", i.Active), signature, claim("sub"), claim("client_id"), scope, expiry}
+}
+
+func (i outputInspectedToken) Interface() interface{} {
+	return i
+}
@@ -56,12 +56,17 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 	importCmd.AddCommand(
 		NewImportClientCmd(),
 		NewKeysImportCmd(),
+		NewImportBundleCmd(),
 	)
 
+	exportCmd := NewExportCmd()
+	exportCmd.AddCommand(NewExportBundleCmd())
+
 	performCmd := NewPerformCmd()
 	performCmd.AddCommand(
 		NewPerformClientCredentialsCmd(),
 		NewPerformAuthorizationCodeCmd(),
+		NewPerformDeviceCodeCmd(),
 	)
 
 	revokeCmd := NewRevokeCmd()
@@ -70,10 +75,16 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 	introspectCmd := NewIntrospectCmd()
 	introspectCmd.AddCommand(NewIntrospectTokenCmd())
 
+	inspectCmd := NewInspectCmd()
+	inspectCmd.AddCommand(NewInspectTokenCmd())
+
 	migrateCmd := NewMigrateCmd()
 	migrateCmd.AddCommand(NewMigrateGenCmd())
 	migrateCmd.AddCommand(NewMigrateSqlCmd(slOpts, dOpts, cOpts))
 	migrateCmd.AddCommand(NewMigrateStatusCmd(slOpts, dOpts, cOpts))
+	migrateCmd.AddCommand(NewMigrateTokenPartitionsCmd(slOpts, dOpts, cOpts))
+	migrateCmd.AddCommand(NewMigrateConsentSessionsCmd(slOpts, dOpts, cOpts))
+	migrateCmd.AddCommand(NewMigrateTokenSessionsCmd(slOpts, dOpts, cOpts))
 
 	serveCmd := NewServeCmd()
 	serveCmd.AddCommand(NewServeAdminCmd(slOpts, dOpts, cOpts))
@@ -87,12 +98,16 @@ func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Op
 		listCmd,
 		updateCmd,
 		importCmd,
+		exportCmd,
+		NewApplyCmd(),
 		performCmd,
 		introspectCmd,
+		inspectCmd,
 		revokeCmd,
 		migrateCmd,
 		serveCmd,
 		NewJanitorCmd(slOpts, dOpts, cOpts),
+		NewDoctorCmd(slOpts, dOpts, cOpts),
 		NewVersionCmd(),
 	)
 }
@@ -15,7 +15,7 @@ import (
 
 // allCmd represents the all command
 func NewServeAllCmd(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier, cOpts []configx.OptionModifier) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "all",
 		Short: "Serves both public and administrative HTTP/2 APIs",
 		Long: `Starts a process which listens on two ports for public and administrative HTTP/2 API requests.
@@ -33,4 +33,7 @@ the controls section.
 ` + serveControls,
 		RunE: server.RunServeAll(slOpts, dOpts, cOpts),
 	}
+
+	cmd.Flags().String(server.FlagFixtures, "", `Load OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers from a YAML or JSON file in the format of "hydra export bundle" on startup. Requires --dev and the DSN set to "memory".`)
+	return cmd
 }
@@ -6,7 +6,10 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -26,10 +29,14 @@ import (
 	"github.com/ory/x/reqlog"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/rs/cors"
 	"github.com/spf13/cobra"
 	"github.com/urfave/negroni"
 	"go.uber.org/automaxprocs/maxprocs"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/ory/graceful"
 	"github.com/ory/x/healthx"
@@ -37,19 +44,24 @@ import (
 	"github.com/ory/x/networkx"
 	"github.com/ory/x/otelx"
 
+	"github.com/ory/hydra/v2/adminauth"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/consent"
 	"github.com/ory/hydra/v2/driver"
 	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/hydra/v2/jwk"
 	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/tenant"
 	"github.com/ory/hydra/v2/x"
+	"github.com/ory/hydra/v2/x/events"
 	prometheus "github.com/ory/x/prometheusx"
 )
 
 var _ = &consent.Handler{}
 
 func EnhanceMiddleware(ctx context.Context, sl *servicelocatorx.Options, d driver.Registry, n *negroni.Negroni, address string, router *httprouter.Router, iface config.ServeInterface) http.Handler {
+	n.UseFunc(x.ClientIPMiddleware(d.Config().TrustedProxies(iface)))
+
 	if !networkx.AddressIsUnixSocket(address) {
 		n.UseFunc(x.RejectInsecureRequests(d, d.Config().TLS(ctx, iface)))
 	}
@@ -66,6 +78,16 @@ func EnhanceMiddleware(ctx context.Context, sl *servicelocatorx.Options, d drive
 		cors.New(cfg).ServeHTTP(w, r, next)
 	})
 
+	n.UseFunc(tenant.Middleware(d))
+
+	if iface == config.AdminInterface {
+		n.UseFunc(adminauth.Middleware(d))
+	}
+
+	if e := d.EventEmitter(); e != nil {
+		n.UseFunc(events.Middleware(e))
+	}
+
 	n.UseHandler(router)
 
 	return n
@@ -91,6 +113,9 @@ func RunServeAdmin(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifi
 		admin, _, adminmw, _ := setup(ctx, d, cmd)
 		d.PrometheusManager().RegisterRouter(admin.Router)
 
+		stopMetrics := startOTLPMetrics(ctx, d)
+		defer stopMetrics()
+
 		var wg sync.WaitGroup
 		wg.Add(1)
 
@@ -105,7 +130,11 @@ func RunServeAdmin(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifi
 			d.Config().SocketPermission(config.AdminInterface),
 		)
 
+		go runJanitor(ctx, d)
+		go runTrustGrantExpiryWarnings(ctx, d)
+
 		wg.Wait()
+		shutdownRegistry(d)
 		return nil
 	}
 }
@@ -124,6 +153,9 @@ func RunServePublic(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModif
 		_, public, _, publicmw := setup(ctx, d, cmd)
 		d.PrometheusManager().RegisterRouter(public.Router)
 
+		stopMetrics := startOTLPMetrics(ctx, d)
+		defer stopMetrics()
+
 		var wg sync.WaitGroup
 		wg.Add(1)
 
@@ -139,6 +171,7 @@ func RunServePublic(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModif
 		)
 
 		wg.Wait()
+		shutdownRegistry(d)
 		return nil
 	}
 }
@@ -153,11 +186,20 @@ func RunServeAll(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier
 			return err
 		}
 
+		if fixtures, _ := cmd.Flags().GetString(FlagFixtures); fixtures != "" {
+			if err := SeedFixtures(ctx, d, fixtures); err != nil {
+				return err
+			}
+		}
+
 		admin, public, adminmw, publicmw := setup(ctx, d, cmd)
 
 		d.PrometheusManager().RegisterRouter(admin.Router)
 		d.PrometheusManager().RegisterRouter(public.Router)
 
+		stopMetrics := startOTLPMetrics(ctx, d)
+		defer stopMetrics()
+
 		var wg sync.WaitGroup
 		wg.Add(2)
 
@@ -183,11 +225,25 @@ func RunServeAll(slOpts []servicelocatorx.Option, dOpts []driver.OptionsModifier
 			d.Config().SocketPermission(config.AdminInterface),
 		)
 
+		go runJanitor(ctx, d)
+		go runTrustGrantExpiryWarnings(ctx, d)
+
 		wg.Wait()
+		shutdownRegistry(d)
 		return nil
 	}
 }
 
+// shutdownRegistry flushes the buffered events and closes the connections of the registry once the servers are shut
+// down.
+func shutdownRegistry(d driver.Registry) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Config().ShutdownGracePeriod())
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		d.Logger().WithError(err).Error("Unable to shut down cleanly.")
+	}
+}
+
 func setup(ctx context.Context, d driver.Registry, cmd *cobra.Command) (admin *httprouterx.RouterAdmin, public *httprouterx.RouterPublic, adminmw, publicmw *negroni.Negroni) {
 	fmt.Println(banner(config.Version))
 
@@ -328,9 +384,66 @@ func serve(
 
 	var tlsConfig *tls.Config
 	stopReload := make(chan struct{})
+	acmeManager := NewACMEManager(d, iface)
 	if tc := d.Config().TLS(ctx, iface); tc.Enabled() {
-		// #nosec G402 - This is a false positive because we use graceful.WithDefaults which sets the correct TLS settings.
-		tlsConfig = &tls.Config{GetCertificate: GetOrCreateTLSCertificate(ctx, d, iface, stopReload)}
+		if acmeManager != nil {
+			tlsConfig = acmeManager.TLSConfig()
+		} else {
+			// #nosec G402 - This is a false positive because we use graceful.WithDefaults which sets the correct TLS settings.
+			tlsConfig = &tls.Config{GetCertificate: GetOrCreateTLSCertificate(ctx, d, iface, stopReload)}
+		}
+	} else if acmeManager != nil {
+		d.Logger().Fatal("Provisioning TLS certificates using ACME requires TLS to be enabled for the public API.")
+	}
+
+	if iface == config.AdminInterface && d.Config().AdminMTLSEnabled(ctx) {
+		if tlsConfig == nil {
+			d.Logger().Fatal("Mutual TLS authentication for the admin API requires TLS to be enabled for the admin API.")
+		}
+		pool, err := d.Config().AdminMTLSClientCAs(ctx)
+		if err != nil {
+			d.Logger().WithError(err).Fatal("Unable to load the certificate authorities for mutual TLS authentication.")
+		}
+		// Client certificates are optional at the TLS layer so that API keys can still be used, unless they are
+		// required.
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if d.Config().AdminMTLSRequired(ctx) {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		tlsConfig.ClientCAs = pool
+
+		if _, err := d.Config().AdminMTLSCRLs(ctx); err != nil {
+			d.Logger().WithError(err).Fatal("Unable to load the certificate revocation lists for mutual TLS authentication.")
+		}
+		tlsConfig.VerifyConnection = adminauth.NewRevocationChecker(
+			func() ([]*x509.RevocationList, error) { return d.Config().AdminMTLSCRLs(ctx) },
+			d.Config().AdminMTLSOCSPEnabled(ctx),
+			d.Config().AdminMTLSOCSPFailOpen(ctx),
+			d.HTTPClient(ctx).StandardClient(),
+			d.Logger(),
+		).VerifyConnection
+	}
+
+	if d.Config().H2CEnabled(iface) {
+		if tlsConfig != nil {
+			d.Logger().Warnf("HTTP/2 without TLS (h2c) is enabled for %s but has no effect because TLS is enabled.", iface)
+		} else {
+			handler = h2c.NewHandler(handler, &http2.Server{})
+		}
+	}
+
+	var srv3 *http3.Server
+	if d.Config().HTTP3Enabled(iface) {
+		if tlsConfig == nil || networkx.AddressIsUnixSocket(address) {
+			d.Logger().Fatalf("HTTP/3 for %s requires TLS to be enabled and a TCP address to listen on.", iface)
+		}
+		// 0-RTT is left disabled because early data, for example a token request, can be replayed.
+		srv3 = &http3.Server{Addr: address, Handler: handler, QuicConfig: &quic.Config{}}
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = srv3.SetQuicHeaders(w.Header())
+			next.ServeHTTP(w, r)
+		})
 	}
 
 	var srv = graceful.WithDefaults(&http.Server{
@@ -340,6 +453,26 @@ func serve(
 	})
 
 	if err := graceful.Graceful(func() error {
+		if challenge := serveACMEHTTPChallenge(d, acmeManager); challenge != nil {
+			defer challenge.Close()
+		}
+
+		if srv3 != nil {
+			srv3.TLSConfig = srv.TLSConfig
+			conn, err := net.ListenPacket("udp", address)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			d.Logger().Infof("Setting up http/3 server on %s", address)
+			go func() {
+				if err := srv3.Serve(conn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					d.Logger().WithError(err).Fatal("Could not run http/3 server")
+				}
+			}()
+		}
+
 		d.Logger().Infof("Setting up http server on %s", address)
 		listener, err := networkx.MakeListener(address, permission)
 		if err != nil {
@@ -350,6 +483,10 @@ func serve(
 			return srv.Serve(listener)
 		}
 
+		if tp := d.Config().TrustedProxies(iface); tp.Enabled() && tp.ProxyProtocol() {
+			listener = x.NewProxyProtocolListener(listener, tp, srv.ReadHeaderTimeout)
+		}
+
 		if tlsConfig != nil {
 			return srv.ServeTLS(listener, "", "")
 		}
@@ -359,9 +496,30 @@ func serve(
 		}
 
 		return srv.Serve(listener)
-	}, func(ctx context.Context) error {
+	}, func(context.Context) error {
+		// Failing the readiness check first lets load balancers stop routing requests to this instance while it
+		// still serves them.
+		d.BeginShutdown()
+		if delay := d.Config().ShutdownDrainDelay(); delay > 0 {
+			d.Logger().Infof("Draining http server on %s for %s before shutting it down", address, delay)
+			time.Sleep(delay)
+		}
 		close(stopReload)
-		return srv.Shutdown(ctx)
+
+		// The grace period replaces the fixed timeout of graceful.
+		ctx, cancel := context.WithTimeout(context.Background(), d.Config().ShutdownGracePeriod())
+		defer cancel()
+		if srv3 != nil {
+			// quic-go does not implement a graceful shutdown of HTTP/3 connections yet.
+			defer srv3.Close()
+		}
+		if err := srv.Shutdown(ctx); errors.Is(err, context.DeadlineExceeded) {
+			d.Logger().Warnf("In-flight requests to the http server on %s did not complete within the grace period, closing their connections", address)
+			return srv.Close()
+		} else if err != nil {
+			return err
+		}
+		return nil
 	}); err != nil {
 		d.Logger().WithError(err).Fatal("Could not gracefully run server")
 	}
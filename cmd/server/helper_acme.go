@@ -0,0 +1,60 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/driver/config"
+)
+
+// NewACMEManager returns the manager which provisions and renews the TLS certificates of the interface using ACME,
+// or nil if ACME is not enabled for the interface. Only the public interface supports ACME.
+func NewACMEManager(d driver.Registry, iface config.ServeInterface) *autocert.Manager {
+	c := d.Config().PublicACME()
+	if iface != config.PublicInterface || !c.Enabled {
+		return nil
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(c.Hosts...),
+		Email:      c.Email,
+		Client:     &acme.Client{DirectoryURL: c.DirectoryURL},
+	}
+	if c.CacheDir != "" {
+		m.Cache = autocert.DirCache(c.CacheDir)
+	} else {
+		d.Logger().Warn("No cache directory is configured for ACME, certificates are requested again on every start which may exhaust the rate limits of the certificate authority.")
+	}
+	return m
+}
+
+// serveACMEHTTPChallenge serves the ACME HTTP-01 challenge if it is enabled, and redirects all other requests to
+// HTTPS. It returns the server, or nil if the challenge is not enabled.
+func serveACMEHTTPChallenge(d driver.Registry, m *autocert.Manager) *http.Server {
+	c := d.Config().PublicACME()
+	if m == nil || !c.HTTPChallenge {
+		return nil
+	}
+
+	srv := &http.Server{
+		Addr:              c.HTTPListenOn,
+		Handler:           m.HTTPHandler(nil),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		d.Logger().Infof("Setting up ACME HTTP-01 challenge server on %s", c.HTTPListenOn)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			d.Logger().WithError(err).Fatal("Could not run the ACME HTTP-01 challenge server")
+		}
+	}()
+	return srv
+}
@@ -0,0 +1,69 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/ory/x/configx"
+	"github.com/ory/x/logrusx"
+
+	"github.com/ory/hydra/v2/cmd/server"
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/driver/config"
+)
+
+func TestNewACMEManager(t *testing.T) {
+	ctx := context.Background()
+	newRegistry := func(t *testing.T, values map[string]interface{}) driver.Registry {
+		values["dsn"] = config.DSNMemory
+		values[config.KeyIssuerURL] = "https://auth.example.com/"
+		d, err := driver.NewRegistryWithoutInit(config.MustNew(ctx, logrusx.New("", ""), configx.WithValues(values)), logrusx.New("", ""))
+		require.NoError(t, err)
+		return d
+	}
+
+	t.Run("case=disabled", func(t *testing.T) {
+		d := newRegistry(t, map[string]interface{}{})
+		assert.Nil(t, server.NewACMEManager(d, config.PublicInterface))
+	})
+
+	t.Run("case=only the public interface", func(t *testing.T) {
+		d := newRegistry(t, map[string]interface{}{config.KeyPublicACMEEnabled: true})
+		assert.Nil(t, server.NewACMEManager(d, config.AdminInterface))
+	})
+
+	t.Run("case=issuer host name", func(t *testing.T) {
+		d := newRegistry(t, map[string]interface{}{config.KeyPublicACMEEnabled: true})
+		m := server.NewACMEManager(d, config.PublicInterface)
+		require.NotNil(t, m)
+		assert.NoError(t, m.HostPolicy(ctx, "auth.example.com"))
+		assert.Error(t, m.HostPolicy(ctx, "attacker.example.com"))
+		assert.Equal(t, config.DefaultACMEDirectoryURL, m.Client.DirectoryURL)
+		assert.Nil(t, m.Cache)
+	})
+
+	t.Run("case=configured hosts and cache", func(t *testing.T) {
+		dir := t.TempDir()
+		d := newRegistry(t, map[string]interface{}{
+			config.KeyPublicACMEEnabled:      true,
+			config.KeyPublicACMEHosts:        []string{"login.example.com"},
+			config.KeyPublicACMECacheDir:     dir,
+			config.KeyPublicACMEDirectoryURL: "https://acme.example.com/directory",
+			config.KeyPublicACMEEmail:        "ops@example.com",
+		})
+		m := server.NewACMEManager(d, config.PublicInterface)
+		require.NotNil(t, m)
+		assert.NoError(t, m.HostPolicy(ctx, "login.example.com"))
+		assert.Error(t, m.HostPolicy(ctx, "auth.example.com"))
+		assert.Equal(t, autocert.DirCache(dir), m.Cache)
+		assert.Equal(t, "https://acme.example.com/directory", m.Client.DirectoryURL)
+		assert.Equal(t, "ops@example.com", m.Email)
+	})
+}
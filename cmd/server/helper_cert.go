@@ -43,13 +43,14 @@ var lock sync.Mutex
 
 // GetOrCreateTLSCertificate returns a function for use with
 // "net/tls".Config.GetCertificate. If the certificate and key are read from
-// disk, they will be automatically reloaded until stopReload is close()'d.
+// disk, they will be automatically reloaded until stopReload is close()'d. The same applies to changes of the
+// configured certificate.
 func GetOrCreateTLSCertificate(ctx context.Context, d driver.Registry, iface config.ServeInterface, stopReload <-chan struct{}) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
 	lock.Lock()
 	defer lock.Unlock()
 
 	// check if certificates are configured
-	certFunc, err := d.Config().TLS(ctx, iface).GetCertificateFunc(stopReload, d.Logger())
+	certFunc, err := d.Config().TLSCertificateFunc(ctx, iface, stopReload, d.Logger())
 	if err == nil {
 		return certFunc
 	} else if !errors.Is(err, tlsx.ErrNoCertificatesConfigured) {
@@ -151,3 +151,56 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 	require.NoError(t, json.NewEncoder(&b).Encode(keys))
 	require.NoError(t, json.NewDecoder(&b).Decode(&actual))
 }
+
+func TestGetOrCreateTLSCertificateConfigChange(t *testing.T) {
+	toBase64 := func(t *testing.T, path string) string {
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(raw)
+	}
+
+	certPath, keyPath, cert, _ := testhelpers.GenerateTLSCertificateFilesForTests(t)
+	newCertPath, newKeyPath, newCert, _ := testhelpers.GenerateTLSCertificateFilesForTests(t)
+
+	logger := logrusx.New("", "")
+	logger.Logger.ExitFunc = func(code int) { t.Fatalf("Logger called os.Exit(%v)", code) }
+	hook := test.NewLocal(logger.Logger)
+	ctx := context.Background()
+	cfg := config.MustNew(
+		ctx,
+		logger,
+		configx.WithValues(map[string]interface{}{
+			"dsn":                   config.DSNMemory,
+			"serve.tls.enabled":     true,
+			"serve.tls.cert.base64": toBase64(t, certPath),
+			"serve.tls.key.base64":  toBase64(t, keyPath),
+		}),
+	)
+	d, err := driver.NewRegistryWithoutInit(cfg, logger)
+	require.NoError(t, err)
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	getCert := server.GetOrCreateTLSCertificate(ctx, d, config.AdminInterface, stop)
+	require.NotNil(t, getCert)
+
+	leaf := func(t *testing.T) *x509.Certificate {
+		tlsCert, err := getCert(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+		require.NoError(t, err)
+		return leaf
+	}
+	require.True(t, leaf(t).Equal(cert))
+
+	// switch to another certificate
+	cfg.MustSet(ctx, config.KeyTLSCertString, toBase64(t, newCertPath))
+	cfg.MustSet(ctx, config.KeyTLSKeyString, toBase64(t, newKeyPath))
+	require.True(t, leaf(t).Equal(newCert))
+	require.Contains(t, hook.LastEntry().Message, "Reloaded the TLS certificates")
+
+	// invalid configuration keeps the previous certificate
+	cfg.MustSet(ctx, config.KeyTLSCertString, "bm90LWEtY2VydGlmaWNhdGU=")
+	require.True(t, leaf(t).Equal(newCert))
+	require.Contains(t, hook.LastEntry().Message, "Failed to load the changed TLS certificate configuration")
+}
@@ -0,0 +1,60 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/backup"
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/x/dbal"
+)
+
+// FlagFixtures is the flag of "hydra serve all" which sets the fixtures loaded on startup.
+const FlagFixtures = "fixtures"
+
+// SeedFixtures imports the OAuth 2.0 Clients, JSON Web Key Sets and trusted JWT grant issuers of the fixtures file
+// at path. The file uses the format of "hydra export bundle" and may be written in YAML or JSON. The version may
+// be omitted, and client secrets may be given in plain text. Fixtures are only loaded in development mode with
+// the in-memory database, so that they never overwrite persistent data.
+func SeedFixtures(ctx context.Context, d driver.Registry, path string) error {
+	if !d.Config().IsDevelopmentMode(ctx) || !dbal.IsMemorySQLite(d.Config().DSN()) {
+		return errors.New(`fixtures can only be loaded with the --dev flag and the DSN set to "memory"`)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "could not read the fixtures %s", path)
+	}
+	j, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse the fixtures %s", path)
+	}
+
+	b := backup.Bundle{Version: backup.BundleVersion}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&b); err != nil {
+		return errors.Wrapf(err, "could not decode the fixtures %s", path)
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	if err := d.BackupManager().ImportBundle(ctx, &b); err != nil {
+		return err
+	}
+
+	d.Logger().
+		WithField("clients", len(b.Clients)).
+		WithField("json_web_key_sets", len(b.JSONWebKeySets)).
+		WithField("trusted_jwt_grant_issuers", len(b.TrustedJwtGrantIssuers)).
+		Infof("Loaded the fixtures %s.", path)
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/contextx"
+
+	"github.com/ory/hydra/v2/cmd/server"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/jwk"
+)
+
+func TestSeedFixtures(t *testing.T) {
+	ctx := context.Background()
+
+	keys, err := jwk.GenerateJWK(ctx, "RS256", "issuer-key", "sig")
+	require.NoError(t, err)
+	publicKeys, err := json.Marshal(keys.Keys[0].Public())
+	require.NoError(t, err)
+
+	fixtures := filepath.Join(t.TempDir(), "fixtures.yaml")
+	require.NoError(t, os.WriteFile(fixtures, []byte(`clients:
+  - client_id: local-app
+    client_secret: local-secret
+    redirect_uris:
+      - http://127.0.0.1:5555/callback
+json_web_key_sets:
+  https://issuer.example.com:
+    keys:
+      - `+string(publicKeys)+`
+trusted_jwt_grant_issuers:
+  - id: local-issuer
+    issuer: https://issuer.example.com
+    allow_any_subject: true
+    scope: [openid]
+    public_key:
+      set: https://issuer.example.com
+      kid: issuer-key
+    expires_at: "2100-01-01T00:00:00Z"
+`), 0600))
+
+	t.Run("case=loads the fixtures", func(t *testing.T) {
+		reg := internal.NewRegistryMemory(t, internal.NewConfigurationWithDefaults(), &contextx.Default{})
+
+		require.NoError(t, server.SeedFixtures(ctx, reg, fixtures))
+		require.NoError(t, server.SeedFixtures(ctx, reg, fixtures), "loading the fixtures again replaces the resources")
+
+		c, err := reg.ClientManager().AuthenticateClient(ctx, "local-app", []byte("local-secret"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http://127.0.0.1:5555/callback"}, c.GetRedirectURIs())
+
+		key, err := reg.KeyManager().GetKey(ctx, "https://issuer.example.com", "issuer-key")
+		require.NoError(t, err)
+		assert.Len(t, key.Keys, 1)
+
+		g, err := reg.GrantManager().GetConcreteGrant(ctx, "local-issuer")
+		require.NoError(t, err)
+		assert.True(t, g.AllowAnySubject)
+	})
+
+	t.Run("case=requires development mode", func(t *testing.T) {
+		reg := internal.NewRegistryMemory(t, internal.NewConfigurationWithDefaults(), &contextx.Default{})
+		reg.Config().MustSet(ctx, config.KeyDevelopmentMode, false)
+		assert.ErrorContains(t, server.SeedFixtures(ctx, reg, fixtures), "--dev")
+	})
+
+	t.Run("case=rejects unknown fields", func(t *testing.T) {
+		reg := internal.NewRegistryMemory(t, internal.NewConfigurationWithDefaults(), &contextx.Default{})
+
+		invalid := filepath.Join(t.TempDir(), "fixtures.yaml")
+		require.NoError(t, os.WriteFile(invalid, []byte("client:\n  - client_id: typo\n"), 0600))
+		assert.ErrorContains(t, server.SeedFixtures(ctx, reg, invalid), "unknown field")
+	})
+}
@@ -0,0 +1,136 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+const janitorLockName = "hydra.janitor"
+
+// runJanitor periodically removes stale database rows if the built-in janitor is enabled. When running multiple
+// replicas, only the replica holding the janitor lock performs the cleanup.
+func runJanitor(ctx context.Context, d driver.Registry) {
+	if !d.Config().JanitorEnabled(ctx) {
+		return
+	}
+
+	interval := d.Config().JanitorInterval(ctx)
+	d.Logger().WithField("interval", interval).Info("Built-in janitor is enabled.")
+
+	metrics := x.NewJanitorMetrics(prometheus.DefaultRegisterer)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			janitorRun(ctx, d, metrics)
+		}
+	}
+}
+
+func janitorRun(ctx context.Context, d driver.Registry, metrics *x.JanitorMetrics) {
+	unlock, acquired, err := d.Persister().TryLock(ctx, janitorLockName)
+	if err != nil {
+		d.Logger().WithError(err).Error("Unable to acquire janitor lock.")
+		return
+	} else if !acquired {
+		d.Logger().Debug("Skipping janitor run because another instance holds the janitor lock.")
+		return
+	}
+	defer unlock()
+
+	summary := x.NewJanitorSummary()
+	janitorRunNetwork(ctx, d, summary)
+	if d.Config().TenancyEnabled(ctx) {
+		janitorRunTenants(ctx, d, summary)
+	}
+	summary.Finish()
+	metrics.Record(summary)
+	d.Logger().
+		WithField("duration_seconds", summary.Duration).
+		WithField("routines", summary.Routines).
+		WithField("errors", summary.Errors).
+		Info("Completed janitor run.")
+
+	if err := d.Persister().Checkpoint(ctx); err != nil {
+		d.Logger().WithError(err).Error("Could not checkpoint the database after the janitor run.")
+	}
+}
+
+// janitorRunTenants removes the stale rows of every tenant.
+func janitorRunTenants(ctx context.Context, d driver.Registry, summary *x.JanitorSummary) {
+	pageOpts := []keysetpagination.Option{}
+	for {
+		tenants, nextPage, err := d.TenantManager().GetTenants(ctx, pageOpts...)
+		if err != nil {
+			d.Logger().WithError(err).Error("Unable to list tenants for janitor run.")
+			return
+		}
+		for i := range tenants {
+			janitorRunNetwork(tenant.NewContext(ctx, &tenants[i]), d, summary)
+		}
+		if nextPage.IsLast() {
+			return
+		}
+		pageOpts = nextPage.ToOptions()
+	}
+}
+
+// janitorRunNetwork removes the stale rows of the network of the context and adds the routines to the summary.
+func janitorRunNetwork(ctx context.Context, d driver.Registry, summary *x.JanitorSummary) {
+	now := time.Now()
+	limit, batchSize := d.Config().JanitorLimit(ctx), d.Config().JanitorBatchSize(ctx)
+	if batchSize > limit {
+		batchSize = limit
+	}
+
+	p := d.Persister()
+	for _, routine := range []struct {
+		name      string
+		run       func(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+		retention time.Duration
+		// keepForever skips the routine if no retention is configured.
+		keepForever bool
+	}{
+		{name: "access tokens", run: p.FlushInactiveAccessTokens, retention: d.Config().JanitorRetentionTokens(ctx)},
+		{name: "refresh tokens", run: p.FlushInactiveRefreshTokens, retention: d.Config().JanitorRetentionTokens(ctx)},
+		{name: "login-consent requests", run: p.FlushInactiveLoginConsentRequests, retention: d.Config().JanitorRetentionLoginConsentRequests(ctx)},
+		{name: "grants", run: p.FlushInactiveGrants, retention: d.Config().JanitorKeepIfYounger(ctx)},
+		{name: "GNAP grant requests", run: p.FlushInactiveGNAPGrants, retention: d.Config().JanitorKeepIfYounger(ctx)},
+		{name: "consent sessions", run: p.FlushConsentSessions, retention: d.Config().JanitorRetentionConsentSessions(ctx), keepForever: true},
+		{name: "audit events", run: p.FlushAuditEvents, retention: d.Config().JanitorRetentionAuditEvents(ctx), keepForever: true},
+	} {
+		if routine.keepForever && routine.retention == 0 {
+			continue
+		}
+		if err := summary.Run(ctx, routine.name, limit, func(ctx context.Context) error {
+			return routine.run(ctx, now.Add(-routine.retention), limit, batchSize)
+		}); err != nil {
+			d.Logger().WithError(err).Errorf("Could not cleanup inactive %s.", routine.name)
+			continue
+		}
+		d.Logger().Infof("Successfully completed janitor run on %s.", routine.name)
+	}
+
+	if d.Config().JWKSPruningKeepNewest(ctx) > 0 {
+		if err := summary.Run(ctx, "key sets", 0, p.PruneKeySets); err != nil {
+			d.Logger().WithError(err).Error("Could not prune key sets.")
+			return
+		}
+		d.Logger().Info("Successfully completed janitor run on key sets.")
+	}
+}
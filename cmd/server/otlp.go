@@ -0,0 +1,37 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/hydra/v2/driver"
+)
+
+// startOTLPMetrics starts exporting the Prometheus metrics to the configured OpenTelemetry collector if enabled. The
+// returned function exports the metrics one last time and stops the export.
+func startOTLPMetrics(ctx context.Context, d driver.Registry) (stop func()) {
+	oc := d.Config().OTLP()
+	if !oc.MetricsEnabled {
+		return func() {}
+	}
+
+	mp, err := driver.NewOTLPMeterProvider(ctx, d.Config(), prometheus.DefaultGatherer)
+	if err != nil {
+		d.Logger().WithError(err).Error("Unable to export metrics with OTLP.")
+		return func() {}
+	}
+	d.Logger().WithField("interval", oc.MetricsInterval).Infof("OTLP metrics configured! Sending metrics to %s", oc.Endpoint)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if err := mp.Shutdown(ctx); err != nil {
+			d.Logger().WithError(err).Error("Unable to export the last metrics with OTLP.")
+		}
+	}
+}
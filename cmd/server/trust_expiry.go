@@ -0,0 +1,57 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/x/events"
+)
+
+const trustGrantExpiryLockName = "hydra.trust_grant_expiry"
+
+// runTrustGrantExpiryWarnings periodically warns about trust relationships of JWT issuers which are about to expire, if
+// enabled. When running multiple replicas, only the replica holding the advisory lock performs the check, so that
+// every warning is emitted once.
+func runTrustGrantExpiryWarnings(ctx context.Context, d driver.Registry) {
+	if d.Config().GrantJWTExpiryWarningBefore(ctx) <= 0 {
+		return
+	}
+
+	interval := d.Config().GrantJWTExpiryWarningInterval(ctx)
+	metrics := trust.NewExpiryMetrics(prometheus.DefaultRegisterer)
+	ctx = events.WithEmitter(ctx, d.EventEmitter())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			trustGrantExpiryRun(ctx, d, metrics, now)
+		}
+	}
+}
+
+func trustGrantExpiryRun(ctx context.Context, d driver.Registry, metrics *trust.ExpiryMetrics, now time.Time) {
+	unlock, acquired, err := d.Persister().TryLock(ctx, trustGrantExpiryLockName)
+	if err != nil {
+		d.Logger().WithError(err).Error("Unable to acquire the lock for checking the expiry of trust relationships.")
+		return
+	} else if !acquired {
+		return
+	}
+	defer unlock()
+
+	if err := trust.WarnExpiringGrants(ctx, d, metrics, now); err != nil {
+		d.Logger().WithError(err).Error("Unable to check the expiry of trust relationships.")
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/oauth2/flowctx"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
+)
+
+// FlowRegistry is what is needed to accept or reject login and consent requests, by the admin API as well as by the
+// login and consent pages which Ory Hydra serves itself.
+type FlowRegistry interface {
+	Registry
+	x.RegistryLogger
+	x.ClockProvider
+	FlowCipher() *aead.XChaCha20Poly1305
+}
+
+// FlowHandler accepts and rejects login and consent requests like the admin API does. Embedding applications use it
+// to implement the login and consent UI in-process instead of calling the admin API over HTTP. The redirects returned
+// include the login or consent verifier, so the user agent only has to follow them.
+type FlowHandler interface {
+	GetLoginRequest(ctx context.Context, challenge string) (*flow.LoginRequest, error)
+	AcceptLoginRequest(r *http.Request, challenge string, p *flow.HandledLoginRequest) (*flow.OAuth2RedirectTo, error)
+	RejectLoginRequest(r *http.Request, challenge string, p *flow.RequestDeniedError) (*flow.OAuth2RedirectTo, error)
+	GetConsentRequest(ctx context.Context, challenge string) (*flow.OAuth2ConsentRequest, error)
+	AcceptConsentRequest(r *http.Request, challenge string, p *flow.AcceptOAuth2ConsentRequest) (*flow.OAuth2RedirectTo, error)
+	RejectConsentRequest(r *http.Request, challenge string, p *flow.RequestDeniedError) (*flow.OAuth2RedirectTo, error)
+}
+
+var _ FlowHandler = new(flowHandler)
+
+type flowHandler struct {
+	r FlowRegistry
+}
+
+func NewFlowHandler(r FlowRegistry) FlowHandler {
+	return &flowHandler{r: r}
+}
+
+// GetLoginRequest returns the login request of the challenge. Its client is sanitized.
+func (h *flowHandler) GetLoginRequest(ctx context.Context, challenge string) (*flow.LoginRequest, error) {
+	request, err := h.r.ConsentManager().GetLoginRequest(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	request.Client = sanitizeClient(request.Client)
+	return request, nil
+}
+
+func (h *flowHandler) AcceptLoginRequest(r *http.Request, challenge string, p *flow.HandledLoginRequest) (*flow.OAuth2RedirectTo, error) {
+	return AcceptLoginRequest(r, h.r, challenge, p)
+}
+
+func (h *flowHandler) RejectLoginRequest(r *http.Request, challenge string, p *flow.RequestDeniedError) (*flow.OAuth2RedirectTo, error) {
+	return RejectLoginRequest(r, h.r, challenge, p)
+}
+
+// GetConsentRequest returns the consent request of the challenge. Its client is sanitized.
+func (h *flowHandler) GetConsentRequest(ctx context.Context, challenge string) (*flow.OAuth2ConsentRequest, error) {
+	request, err := h.r.ConsentManager().GetConsentRequest(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	request.Client = sanitizeClient(request.Client)
+	return request, nil
+}
+
+func (h *flowHandler) AcceptConsentRequest(r *http.Request, challenge string, p *flow.AcceptOAuth2ConsentRequest) (*flow.OAuth2RedirectTo, error) {
+	return AcceptConsentRequest(r, h.r, challenge, p)
+}
+
+func (h *flowHandler) RejectConsentRequest(r *http.Request, challenge string, p *flow.RequestDeniedError) (*flow.OAuth2RedirectTo, error) {
+	return RejectConsentRequest(r, h.r, challenge, p)
+}
+
+// AcceptLoginRequest marks the login request of the challenge as authenticated by the subject of the handled login
+// request, and returns where to redirect the user agent to.
+func AcceptLoginRequest(r *http.Request, reg FlowRegistry, challenge string, handledLoginRequest *flow.HandledLoginRequest) (*flow.OAuth2RedirectTo, error) {
+	ctx := r.Context()
+
+	if handledLoginRequest.Subject == "" {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Field 'subject' must not be empty."))
+	}
+
+	handledLoginRequest.ID = challenge
+	loginRequest, err := reg.ConsentManager().GetLoginRequest(ctx, challenge)
+	if err != nil {
+		return nil, err
+	} else if loginRequest.Subject != "" && handledLoginRequest.Subject != loginRequest.Subject {
+		// The subject that was confirmed by the login screen does not match what we
+		// remembered in the session cookie. We handle this gracefully by redirecting the
+		// original authorization request URL, but attaching "prompt=login" to the query.
+		// This forces the user to log in again.
+		requestURL, err := url.Parse(loginRequest.RequestURL)
+		if err != nil {
+			return nil, err
+		}
+		return &flow.OAuth2RedirectTo{
+			RedirectTo: urlx.SetQuery(requestURL, withCorrelationID(url.Values{"prompt": {"login"}}, loginRequest.CorrelationID)).String(),
+		}, nil
+	}
+
+	if loginRequest.Skip {
+		handledLoginRequest.Remember = true // If skip is true remember is also true to allow consecutive calls as the same user!
+		handledLoginRequest.AuthenticatedAt = loginRequest.AuthenticatedAt
+	} else {
+		handledLoginRequest.AuthenticatedAt = sqlxx.NullTime(reg.Clock().Now().UTC().
+			// Rounding is important to avoid SQL time synchronization issues in e.g. MySQL!
+			Truncate(time.Second))
+		loginRequest.AuthenticatedAt = handledLoginRequest.AuthenticatedAt
+	}
+	handledLoginRequest.RequestedAt = loginRequest.RequestedAt
+
+	f, err := flowctx.Decode[flow.Flow](ctx, reg.FlowCipher(), challenge, flowctx.AsLoginChallenge)
+	if err != nil {
+		return nil, err
+	}
+	request, err := reg.ConsentManager().HandleLoginRequest(ctx, f, challenge, handledLoginRequest)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	ru, err := url.Parse(request.RequestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := f.ToLoginVerifier(ctx, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Trace(ctx, events.LoginAccepted, events.WithClientID(request.Client.GetID()), events.WithSubject(request.Subject))
+	reg.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).Info("The login request was accepted.")
+
+	return &flow.OAuth2RedirectTo{
+		RedirectTo: urlx.SetQuery(ru, withCorrelationID(url.Values{"login_verifier": {verifier}}, f.CorrelationID)).String(),
+	}, nil
+}
+
+// RejectLoginRequest marks the login request of the challenge as denied, and returns where to redirect the user agent
+// to.
+func RejectLoginRequest(r *http.Request, reg FlowRegistry, challenge string, p *flow.RequestDeniedError) (*flow.OAuth2RedirectTo, error) {
+	ctx := r.Context()
+
+	p.Valid = true
+	p.SetDefaults(flow.LoginRequestDeniedErrorName)
+	ar, err := reg.ConsentManager().GetLoginRequest(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := flowctx.Decode[flow.Flow](ctx, reg.FlowCipher(), challenge, flowctx.AsLoginChallenge)
+	if err != nil {
+		return nil, err
+	}
+	request, err := reg.ConsentManager().HandleLoginRequest(ctx, f, challenge, &flow.HandledLoginRequest{
+		Error:       p,
+		ID:          challenge,
+		RequestedAt: ar.RequestedAt,
+	})
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	verifier, err := f.ToLoginVerifier(ctx, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	ru, err := url.Parse(request.RequestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Trace(ctx, events.LoginRejected, events.WithClientID(request.Client.GetID()), events.WithSubject(request.Subject))
+	reg.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).Info("The login request was rejected.")
+
+	return &flow.OAuth2RedirectTo{
+		RedirectTo: urlx.SetQuery(ru, withCorrelationID(url.Values{"login_verifier": {verifier}}, f.CorrelationID)).String(),
+	}, nil
+}
+
+// AcceptConsentRequest marks the consent request of the challenge as granted, and returns where to redirect the user
+// agent to.
+func AcceptConsentRequest(r *http.Request, reg FlowRegistry, challenge string, p *flow.AcceptOAuth2ConsentRequest) (*flow.OAuth2RedirectTo, error) {
+	ctx := r.Context()
+
+	cr, err := reg.ConsentManager().GetConsentRequest(ctx, challenge)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	p.ID = challenge
+	p.RequestedAt = cr.RequestedAt
+	p.HandledAt = sqlxx.NullTime(reg.Clock().Now().UTC())
+
+	f, err := flowctx.Decode[flow.Flow](ctx, reg.FlowCipher(), challenge, flowctx.AsConsentChallenge)
+	if err != nil {
+		return nil, err
+	}
+	hr, err := reg.ConsentManager().HandleConsentRequest(ctx, f, p)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	} else if hr.Skip {
+		p.Remember = false
+	}
+
+	ru, err := url.Parse(hr.RequestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := f.ToConsentVerifier(ctx, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Trace(ctx, events.ConsentAccepted, events.WithClientID(cr.Client.GetID()), events.WithSubject(cr.Subject), events.WithAuthorizationRequest(f.RequestParameters))
+	reg.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).Info("The consent request was accepted.")
+
+	return &flow.OAuth2RedirectTo{
+		RedirectTo: urlx.SetQuery(ru, withCorrelationID(url.Values{"consent_verifier": {verifier}}, f.CorrelationID)).String(),
+	}, nil
+}
+
+// RejectConsentRequest marks the consent request of the challenge as denied, and returns where to redirect the user
+// agent to.
+func RejectConsentRequest(r *http.Request, reg FlowRegistry, challenge string, p *flow.RequestDeniedError) (*flow.OAuth2RedirectTo, error) {
+	ctx := r.Context()
+
+	p.Valid = true
+	p.SetDefaults(flow.ConsentRequestDeniedErrorName)
+	hr, err := reg.ConsentManager().GetConsentRequest(ctx, challenge)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	f, err := flowctx.Decode[flow.Flow](ctx, reg.FlowCipher(), challenge, flowctx.AsConsentChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := reg.ConsentManager().HandleConsentRequest(ctx, f, &flow.AcceptOAuth2ConsentRequest{
+		Error:       p,
+		ID:          challenge,
+		RequestedAt: hr.RequestedAt,
+		HandledAt:   sqlxx.NullTime(reg.Clock().Now().UTC()),
+	})
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	ru, err := url.Parse(request.RequestURL)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := f.ToConsentVerifier(ctx, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Trace(ctx, events.ConsentRejected, events.WithClientID(request.Client.GetID()), events.WithSubject(request.Subject))
+	reg.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).Info("The consent request was rejected.")
+
+	return &flow.OAuth2RedirectTo{
+		RedirectTo: urlx.SetQuery(ru, withCorrelationID(url.Values{"consent_verifier": {verifier}}, f.CorrelationID)).String(),
+	}, nil
+}
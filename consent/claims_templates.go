@@ -0,0 +1,113 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/flow"
+)
+
+// Claims which are never composed from templates, because Ory Hydra sets them itself.
+var (
+	idTokenRegisteredClaims = map[string]bool{
+		"iss": true, "sub": true, "aud": true, "exp": true, "iat": true, "nbf": true, "jti": true, "auth_time": true,
+		"rat": true, "nonce": true, "acr": true, "amr": true, "azp": true, "at_hash": true, "c_hash": true, "sid": true,
+	}
+	logoutTokenRegisteredClaims = map[string]bool{
+		"iss": true, "aud": true, "iat": true, "exp": true, "jti": true, "events": true, "sid": true, "nonce": true,
+	}
+)
+
+// claimsTemplateFuncs are the functions which claims templates may use in addition to the builtin ones.
+var claimsTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// claimsTemplateData is the data which claims templates are rendered with.
+type claimsTemplateData struct {
+	Subject   string
+	SessionID string
+	Client    claimsTemplateClient
+	Consent   *claimsTemplateConsent
+}
+
+type claimsTemplateClient struct {
+	ID       string
+	Name     string
+	Audience []string
+	Metadata map[string]interface{}
+}
+
+type claimsTemplateConsent struct {
+	GrantedScope    []string
+	GrantedAudience []string
+	ACR             string
+	AMR             []string
+	Context         map[string]interface{}
+	IDToken         map[string]interface{}
+	AccessToken     map[string]interface{}
+}
+
+func newClaimsTemplateData(subject, sid string, c *client.Client) *claimsTemplateData {
+	data := &claimsTemplateData{
+		Subject:   subject,
+		SessionID: sid,
+		Client: claimsTemplateClient{
+			ID:       c.GetID(),
+			Name:     c.Name,
+			Audience: c.Audience,
+		},
+	}
+	if len(c.Metadata) > 0 {
+		_ = json.Unmarshal(c.Metadata, &data.Client.Metadata)
+	}
+	return data
+}
+
+func newConsentClaimsTemplateData(session *flow.AcceptOAuth2ConsentRequest) *claimsTemplateData {
+	data := newClaimsTemplateData(session.ConsentRequest.Subject, session.ConsentRequest.LoginSessionID.String(), session.ConsentRequest.Client)
+	data.Consent = &claimsTemplateConsent{
+		GrantedScope:    session.GrantedScope,
+		GrantedAudience: session.GrantedAudience,
+		ACR:             session.ConsentRequest.ACR,
+		AMR:             session.ConsentRequest.AMR,
+		IDToken:         session.Session.IDToken,
+		AccessToken:     session.Session.AccessToken,
+	}
+	if len(session.ConsentRequest.Context) > 0 {
+		_ = json.Unmarshal(session.ConsentRequest.Context, &data.Consent.Context)
+	}
+	return data
+}
+
+// applyClaimsTemplates renders the claims templates of the given configuration key into claims. Claims which are
+// already set or registered are left untouched. A claim is omitted if its template can not be rendered, for example
+// because it refers to missing data, or if it renders an empty string.
+func (s *DefaultStrategy) applyClaimsTemplates(ctx context.Context, key string, registered map[string]bool, data *claimsTemplateData, claims map[string]interface{}) {
+	for claim, text := range s.c.OIDCClaimsTemplates(ctx, key) {
+		if _, ok := claims[claim]; ok || registered[claim] {
+			continue
+		}
+
+		t, err := template.New(claim).Funcs(claimsTemplateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			s.r.Logger().WithError(err).WithField("claim", claim).Error("Unable to parse claims template, ignoring it.")
+			continue
+		}
+
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			s.r.Logger().WithError(err).WithField("claim", claim).Debug("Unable to render claims template, omitting the claim.")
+			continue
+		}
+		if value := b.String(); value != "" {
+			claims[claim] = value
+		}
+	}
+}
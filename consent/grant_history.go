@@ -0,0 +1,59 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+const (
+	GrantHistoryTypeScope    = "scope"
+	GrantHistoryTypeAudience = "audience"
+)
+
+// GrantHistoryEntry records that a subject has granted a scope or an audience to an OAuth 2.0 client. Entries are
+// kept when the consent session is revoked or the client is deleted.
+//
+// swagger:model oAuth2GrantHistoryEntry
+type GrantHistoryEntry struct {
+	ID  uuid.UUID `json:"-" db:"id"`
+	NID uuid.UUID `json:"-" db:"nid"`
+
+	// Subject is the subject who granted the scope or audience.
+	Subject string `json:"subject" db:"subject"`
+
+	// ClientID is the OAuth 2.0 client the scope or audience was granted to.
+	ClientID string `json:"client_id" db:"client_id"`
+
+	// Type is either "scope" or "audience".
+	Type string `json:"type" db:"type"`
+
+	// Value is the granted scope or audience.
+	Value string `json:"value" db:"value"`
+
+	// GrantCount is the number of consent sessions which granted the scope or audience.
+	GrantCount int `json:"grant_count" db:"grant_count"`
+
+	// FirstGrantedAt is the time the scope or audience was granted for the first time.
+	FirstGrantedAt time.Time `json:"first_granted_at" db:"first_granted_at"`
+
+	// LastGrantedAt is the time the scope or audience was granted most recently.
+	LastGrantedAt time.Time `json:"last_granted_at" db:"last_granted_at"`
+}
+
+func (GrantHistoryEntry) TableName() string {
+	return "hydra_oauth2_grant_history"
+}
+
+// PageToken encodes the position of the entry in a list ordered by last_granted_at (newest first) and id.
+func (e GrantHistoryEntry) PageToken() keysetpagination.PageToken {
+	return keysetpagination.MapPageToken{
+		"id":              e.ID.String(),
+		"last_granted_at": e.LastGrantedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
@@ -7,12 +7,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/ory/hydra/v2/flow"
-	"github.com/ory/hydra/v2/oauth2/flowctx"
 	"github.com/ory/hydra/v2/x/events"
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/ory/x/httprouterx"
 
@@ -20,10 +18,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/audit"
 	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/errorsx"
-	"github.com/ory/x/sqlxx"
 	"github.com/ory/x/stringsx"
 	"github.com/ory/x/urlx"
 )
@@ -54,6 +52,9 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
 	admin.GET(LoginPath, h.getOAuth2LoginRequest)
 	admin.PUT(LoginPath+"/accept", h.acceptOAuth2LoginRequest)
 	admin.PUT(LoginPath+"/reject", h.rejectOAuth2LoginRequest)
+	admin.GET(SIOPPath, h.getOAuth2LoginSIOPRequest)
+	admin.PUT(SIOPPath+"/accept", h.acceptOAuth2LoginSIOPResponse)
+	admin.PUT(LoginAssertionPath+"/accept", h.acceptOAuth2LoginAssertion)
 
 	admin.GET(ConsentPath, h.getOAuth2ConsentRequest)
 	admin.PUT(ConsentPath+"/accept", h.acceptOAuth2ConsentRequest)
@@ -62,6 +63,7 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
 	admin.DELETE(SessionsPath+"/login", h.revokeOAuth2LoginSessions)
 	admin.GET(SessionsPath+"/consent", h.listOAuth2ConsentSessions)
 	admin.DELETE(SessionsPath+"/consent", h.revokeOAuth2ConsentSessions)
+	admin.GET(SessionsPath+"/consent/history", h.listOAuth2GrantHistory)
 
 	admin.GET(LogoutPath, h.getOAuth2LogoutRequest)
 	admin.PUT(LogoutPath+"/accept", h.acceptOAuth2LogoutRequest)
@@ -95,6 +97,12 @@ type revokeOAuth2ConsentSessions struct {
 	//
 	// in: query
 	All bool `json:"all"`
+
+	// If set to `true`, the consent sessions are not revoked. Instead, the response lists how many consent sessions
+	// and tokens would be revoked.
+	//
+	// in: query
+	DryRun bool `json:"dry_run"`
 }
 
 // swagger:route DELETE /admin/oauth2/auth/sessions/consent oAuth2 revokeOAuth2ConsentSessions
@@ -113,6 +121,7 @@ type revokeOAuth2ConsentSessions struct {
 //	Schemes: http, https
 //
 //	Responses:
+//	  200: dryRunResult
 //	  204: emptyResponse
 //	  default: errorOAuth2
 func (h *Handler) revokeOAuth2ConsentSessions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -124,6 +133,20 @@ func (h *Handler) revokeOAuth2ConsentSessions(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if x.IsDryRun(r) {
+		if len(client) == 0 && !allClients {
+			h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter both 'client' and 'all' is not defined but one of them should have been.`)))
+			return
+		}
+		affected, err := h.r.ConsentManager().RevokeSubjectConsentSessionDryRun(r.Context(), subject, client)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		h.r.Writer().Write(w, r, &x.DryRunResult{Affected: affected})
+		return
+	}
+
 	switch {
 	case len(client) > 0:
 		if err := h.r.ConsentManager().RevokeSubjectClientConsentSession(r.Context(), subject, client); err != nil && !errors.Is(err, x.ErrNotFound) {
@@ -131,12 +154,14 @@ func (h *Handler) revokeOAuth2ConsentSessions(w http.ResponseWriter, r *http.Req
 			return
 		}
 		events.Trace(r.Context(), events.ConsentRevoked, events.WithSubject(subject), events.WithClientID(client))
+		h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceOAuth2ConsentSessions, subject+"/"+client, nil, nil)
 	case allClients:
 		if err := h.r.ConsentManager().RevokeSubjectConsentSession(r.Context(), subject); err != nil && !errors.Is(err, x.ErrNotFound) {
 			h.r.Writer().WriteError(w, r, err)
 			return
 		}
 		events.Trace(r.Context(), events.ConsentRevoked, events.WithSubject(subject))
+		h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceOAuth2ConsentSessions, subject, nil, nil)
 	default:
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter both 'client' and 'all' is not defined but one of them should have been.`)))
 		return
@@ -151,7 +176,7 @@ func (h *Handler) revokeOAuth2ConsentSessions(w http.ResponseWriter, r *http.Req
 //
 //lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
 type listOAuth2ConsentSessions struct {
-	tokenpagination.RequestParameters
+	keysetpagination.RequestParameters
 
 	// The subject to list the consent sessions for.
 	//
@@ -193,14 +218,18 @@ func (h *Handler) listOAuth2ConsentSessions(w http.ResponseWriter, r *http.Reque
 	}
 	loginSessionId := r.URL.Query().Get("login_session_id")
 
-	page, itemsPerPage := x.ParsePagination(r)
+	pageOpts, err := x.ParsePagination(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
 
 	var s []flow.AcceptOAuth2ConsentRequest
-	var err error
+	var nextPage *keysetpagination.Paginator
 	if len(loginSessionId) == 0 {
-		s, err = h.r.ConsentManager().FindSubjectsGrantedConsentRequests(r.Context(), subject, itemsPerPage, itemsPerPage*page)
+		s, nextPage, err = h.r.ConsentManager().FindSubjectsGrantedConsentRequests(r.Context(), subject, pageOpts...)
 	} else {
-		s, err = h.r.ConsentManager().FindSubjectsSessionGrantedConsentRequests(r.Context(), subject, loginSessionId, itemsPerPage, itemsPerPage*page)
+		s, nextPage, err = h.r.ConsentManager().FindSubjectsSessionGrantedConsentRequests(r.Context(), subject, loginSessionId, pageOpts...)
 	}
 	if errors.Is(err, ErrNoPreviousConsentFound) {
 		h.r.Writer().Write(w, r, []flow.OAuth2ConsentSession{})
@@ -226,10 +255,81 @@ func (h *Handler) listOAuth2ConsentSessions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	x.PaginationHeader(w, r.URL, int64(n), itemsPerPage, itemsPerPage*page)
+	x.PaginationHeader(w, r.URL, int64(n), nextPage)
 	h.r.Writer().Write(w, r, a)
 }
 
+// List OAuth 2.0 Grant History Parameters
+//
+// swagger:parameters listOAuth2GrantHistory
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listOAuth2GrantHistory struct {
+	keysetpagination.RequestParameters
+
+	// The subject to list the grant history for.
+	//
+	// in: query
+	// required: true
+	Subject string `json:"subject"`
+
+	// If set, only the scopes and audiences granted to this OAuth 2.0 Client are listed.
+	//
+	// in: query
+	// required: false
+	Client string `json:"client"`
+}
+
+// List of OAuth 2.0 Grant History Entries
+//
+// swagger:model oAuth2GrantHistory
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type oAuth2GrantHistory []GrantHistoryEntry
+
+// swagger:route GET /admin/oauth2/auth/sessions/consent/history oAuth2 listOAuth2GrantHistory
+//
+// # List the Scopes and Audiences a Subject has Ever Granted
+//
+// This endpoint lists every scope and audience the subject has granted to an OAuth 2.0 Client, together with the
+// number of grants and the times of the first and the most recent grant. Unlike the consent sessions, the history
+// is kept when consent sessions are revoked or clients are deleted, which makes it suitable for privacy dashboards
+// and data access reports. Entries are ordered by the time of the most recent grant, newest first.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2GrantHistory
+//	  default: errorOAuth2
+func (h *Handler) listOAuth2GrantHistory(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	subject := r.URL.Query().Get("subject")
+	if subject == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'subject' is not defined but should have been.`)))
+		return
+	}
+
+	pageOpts, err := x.ParsePagination(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	entries, nextPage, err := h.r.ConsentManager().ListGrantHistory(r.Context(), subject, r.URL.Query().Get("client"), pageOpts...)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	keysetpagination.Header(w, r.URL, nextPage)
+	h.r.Writer().Write(w, r, entries)
+}
+
 // Revoke OAuth 2.0 Consent Login Sessions Parameters
 //
 // swagger:parameters revokeOAuth2LoginSessions
@@ -249,6 +349,12 @@ type revokeOAuth2LoginSessions struct {
 	//
 	// in: query
 	SessionID string `json:"sid"`
+
+	// If set to `true`, the login sessions are not revoked. Instead, the response lists how many login sessions and
+	// tokens would be revoked.
+	//
+	// in: query
+	DryRun bool `json:"dry_run"`
 }
 
 // swagger:route DELETE /admin/oauth2/auth/sessions/login oAuth2 revokeOAuth2LoginSessions
@@ -256,7 +362,8 @@ type revokeOAuth2LoginSessions struct {
 // # Revokes OAuth 2.0 Login Sessions by either a Subject or a SessionID
 //
 // This endpoint invalidates authentication sessions. After revoking the authentication session(s), the subject
-// has to re-authenticate at the Ory OAuth2 Provider. This endpoint does not invalidate any tokens.
+// has to re-authenticate at the Ory OAuth2 Provider. This endpoint does not invalidate any tokens, except those
+// issued in the revoked sessions to OAuth 2.0 Clients with `revoke_tokens_on_logout`.
 //
 // If you send the subject in a query param, all authentication sessions that belong to that subject are revoked.
 // No OpenID Connect Front- or Back-channel logout is performed in this case.
@@ -273,6 +380,7 @@ type revokeOAuth2LoginSessions struct {
 //	Schemes: http, https
 //
 //	Responses:
+//	  200: dryRunResult
 //	  204: emptyResponse
 //	  default: errorOAuth2
 func (h *Handler) revokeOAuth2LoginSessions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -284,21 +392,40 @@ func (h *Handler) revokeOAuth2LoginSessions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if x.IsDryRun(r) {
+		affected, err := h.r.ConsentManager().RevokeSubjectLoginSessionDryRun(r.Context(), subject, sid)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		h.r.Writer().Write(w, r, &x.DryRunResult{Affected: affected})
+		return
+	}
+
 	if sid != "" {
 		if err := h.r.ConsentStrategy().HandleHeadlessLogout(r.Context(), w, r, sid); err != nil {
 			h.r.Writer().WriteError(w, r, err)
 			return
 		}
 
+		h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceOAuth2LoginSessions, sid, nil, nil)
+
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	if err := h.r.ConsentManager().RevokeLoginSessionTokens(r.Context(), subject, ""); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	if err := h.r.ConsentManager().RevokeSubjectLoginSession(r.Context(), subject); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceOAuth2LoginSessions, subject, nil, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -412,8 +539,6 @@ type acceptOAuth2LoginRequest struct {
 //	  200: oAuth2RedirectTo
 //	  default: errorOAuth2
 func (h *Handler) acceptOAuth2LoginRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	ctx := r.Context()
-
 	challenge := stringsx.Coalesce(
 		r.URL.Query().Get("login_challenge"),
 		r.URL.Query().Get("challenge"),
@@ -431,71 +556,18 @@ func (h *Handler) acceptOAuth2LoginRequest(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if handledLoginRequest.Subject == "" {
-		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Field 'subject' must not be empty.")))
-		return
-	}
-
-	handledLoginRequest.ID = challenge
-	loginRequest, err := h.r.ConsentManager().GetLoginRequest(ctx, challenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	} else if loginRequest.Subject != "" && handledLoginRequest.Subject != loginRequest.Subject {
-		// The subject that was confirmed by the login screen does not match what we
-		// remembered in the session cookie. We handle this gracefully by redirecting the
-		// original authorization request URL, but attaching "prompt=login" to the query.
-		// This forces the user to log in again.
-		requestURL, err := url.Parse(loginRequest.RequestURL)
-		if err != nil {
-			h.r.Writer().WriteError(w, r, err)
-			return
-		}
-		h.r.Writer().Write(w, r, &flow.OAuth2RedirectTo{
-			RedirectTo: urlx.SetQuery(requestURL, url.Values{"prompt": {"login"}}).String(),
-		})
-		return
-	}
-
-	if loginRequest.Skip {
-		handledLoginRequest.Remember = true // If skip is true remember is also true to allow consecutive calls as the same user!
-		handledLoginRequest.AuthenticatedAt = loginRequest.AuthenticatedAt
-	} else {
-		handledLoginRequest.AuthenticatedAt = sqlxx.NullTime(time.Now().UTC().
-			// Rounding is important to avoid SQL time synchronization issues in e.g. MySQL!
-			Truncate(time.Second))
-		loginRequest.AuthenticatedAt = handledLoginRequest.AuthenticatedAt
-	}
-	handledLoginRequest.RequestedAt = loginRequest.RequestedAt
-
-	f, err := flowctx.Decode[flow.Flow](ctx, h.r.FlowCipher(), challenge, flowctx.AsLoginChallenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-	request, err := h.r.ConsentManager().HandleLoginRequest(ctx, f, challenge, &handledLoginRequest)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
-		return
-	}
-
-	ru, err := url.Parse(request.RequestURL)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
+	h.acceptLoginRequest(w, r, challenge, &handledLoginRequest)
+}
 
-	verifier, err := f.ToLoginVerifier(ctx, h.r)
+// acceptLoginRequest marks the login request of the challenge as authenticated by the subject of the handled login
+// request, and writes where to redirect the user agent to.
+func (h *Handler) acceptLoginRequest(w http.ResponseWriter, r *http.Request, challenge string, handledLoginRequest *flow.HandledLoginRequest) {
+	redirectTo, err := AcceptLoginRequest(r, h.r, challenge, handledLoginRequest)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
-
-	events.Trace(ctx, events.LoginAccepted, events.WithClientID(request.Client.GetID()), events.WithSubject(request.Subject))
-
-	h.r.Writer().Write(w, r, &flow.OAuth2RedirectTo{
-		RedirectTo: urlx.SetQuery(ru, url.Values{"login_verifier": {verifier}}).String(),
-	})
+	h.r.Writer().Write(w, r, redirectTo)
 }
 
 // Reject OAuth 2.0 Login Request
@@ -541,8 +613,6 @@ type rejectOAuth2LoginRequest struct {
 //	  200: oAuth2RedirectTo
 //	  default: errorOAuth2
 func (h *Handler) rejectOAuth2LoginRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	ctx := r.Context()
-
 	challenge := stringsx.Coalesce(
 		r.URL.Query().Get("login_challenge"),
 		r.URL.Query().Get("challenge"),
@@ -560,46 +630,12 @@ func (h *Handler) rejectOAuth2LoginRequest(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	p.Valid = true
-	p.SetDefaults(flow.LoginRequestDeniedErrorName)
-	ar, err := h.r.ConsentManager().GetLoginRequest(ctx, challenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-
-	f, err := flowctx.Decode[flow.Flow](ctx, h.r.FlowCipher(), challenge, flowctx.AsLoginChallenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-	request, err := h.r.ConsentManager().HandleLoginRequest(ctx, f, challenge, &flow.HandledLoginRequest{
-		Error:       &p,
-		ID:          challenge,
-		RequestedAt: ar.RequestedAt,
-	})
-	if err != nil {
-		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
-		return
-	}
-
-	verifier, err := f.ToLoginVerifier(ctx, h.r)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-
-	ru, err := url.Parse(request.RequestURL)
+	redirectTo, err := RejectLoginRequest(r, h.r, challenge, &p)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
-
-	events.Trace(ctx, events.LoginRejected, events.WithClientID(request.Client.GetID()), events.WithSubject(request.Subject))
-
-	h.r.Writer().Write(w, r, &flow.OAuth2RedirectTo{
-		RedirectTo: urlx.SetQuery(ru, url.Values{"login_verifier": {verifier}}).String(),
-	})
+	h.r.Writer().Write(w, r, redirectTo)
 }
 
 // Get OAuth 2.0 Consent Request
@@ -725,8 +761,6 @@ type acceptOAuth2ConsentRequest struct {
 //	  200: oAuth2RedirectTo
 //	  default: errorOAuth2
 func (h *Handler) acceptOAuth2ConsentRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	ctx := r.Context()
-
 	challenge := stringsx.Coalesce(
 		r.URL.Query().Get("consent_challenge"),
 		r.URL.Query().Get("challenge"),
@@ -744,46 +778,12 @@ func (h *Handler) acceptOAuth2ConsentRequest(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	cr, err := h.r.ConsentManager().GetConsentRequest(ctx, challenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
-		return
-	}
-
-	p.ID = challenge
-	p.RequestedAt = cr.RequestedAt
-	p.HandledAt = sqlxx.NullTime(time.Now().UTC())
-
-	f, err := flowctx.Decode[flow.Flow](ctx, h.r.FlowCipher(), challenge, flowctx.AsConsentChallenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-	hr, err := h.r.ConsentManager().HandleConsentRequest(ctx, f, &p)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
-		return
-	} else if hr.Skip {
-		p.Remember = false
-	}
-
-	ru, err := url.Parse(hr.RequestURL)
+	redirectTo, err := AcceptConsentRequest(r, h.r, challenge, &p)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
-
-	verifier, err := f.ToConsentVerifier(ctx, h.r)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-
-	events.Trace(ctx, events.ConsentAccepted, events.WithClientID(cr.Client.GetID()), events.WithSubject(cr.Subject))
-
-	h.r.Writer().Write(w, r, &flow.OAuth2RedirectTo{
-		RedirectTo: urlx.SetQuery(ru, url.Values{"consent_verifier": {verifier}}).String(),
-	})
+	h.r.Writer().Write(w, r, redirectTo)
 }
 
 // Reject OAuth 2.0 Consent Request
@@ -834,8 +834,6 @@ type adminRejectOAuth2ConsentRequest struct {
 //	  200: oAuth2RedirectTo
 //	  default: errorOAuth2
 func (h *Handler) rejectOAuth2ConsentRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	ctx := r.Context()
-
 	challenge := stringsx.Coalesce(
 		r.URL.Query().Get("consent_challenge"),
 		r.URL.Query().Get("challenge"),
@@ -853,48 +851,12 @@ func (h *Handler) rejectOAuth2ConsentRequest(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	p.Valid = true
-	p.SetDefaults(flow.ConsentRequestDeniedErrorName)
-	hr, err := h.r.ConsentManager().GetConsentRequest(ctx, challenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
-		return
-	}
-
-	f, err := flowctx.Decode[flow.Flow](ctx, h.r.FlowCipher(), challenge, flowctx.AsConsentChallenge)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-
-	request, err := h.r.ConsentManager().HandleConsentRequest(ctx, f, &flow.AcceptOAuth2ConsentRequest{
-		Error:       &p,
-		ID:          challenge,
-		RequestedAt: hr.RequestedAt,
-		HandledAt:   sqlxx.NullTime(time.Now().UTC()),
-	})
-	if err != nil {
-		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
-		return
-	}
-
-	ru, err := url.Parse(request.RequestURL)
+	redirectTo, err := RejectConsentRequest(r, h.r, challenge, &p)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
-
-	verifier, err := f.ToConsentVerifier(ctx, h.r)
-	if err != nil {
-		h.r.Writer().WriteError(w, r, err)
-		return
-	}
-
-	events.Trace(ctx, events.ConsentRejected, events.WithClientID(request.Client.GetID()), events.WithSubject(request.Subject))
-
-	h.r.Writer().Write(w, r, &flow.OAuth2RedirectTo{
-		RedirectTo: urlx.SetQuery(ru, url.Values{"consent_verifier": {verifier}}).String(),
-	})
+	h.r.Writer().Write(w, r, redirectTo)
 }
 
 // Accept OAuth 2.0 Logout Request
@@ -938,6 +900,8 @@ func (h *Handler) acceptOAuth2LogoutRequest(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	events.Trace(r.Context(), events.LogoutAccepted, events.WithClientID(c.ClientID.String), events.WithSubject(c.Subject))
+
 	h.r.Writer().Write(w, r, &flow.OAuth2RedirectTo{
 		RedirectTo: urlx.SetQuery(urlx.AppendPaths(h.c.PublicURL(r.Context()), "/oauth2/sessions/logout"), url.Values{"logout_verifier": {c.Verifier}}).String(),
 	})
@@ -982,6 +946,8 @@ func (h *Handler) rejectOAuth2LogoutRequest(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	events.Trace(r.Context(), events.LogoutRejected)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -4,9 +4,13 @@
 package consent
 
 import (
+	"net/url"
+
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/stringslice"
 )
 
 func sanitizeClientFromRequest(ar fosite.AuthorizeRequester) *client.Client {
@@ -38,3 +42,34 @@ func matchScopes(scopeStrategy fosite.ScopeStrategy, previousConsent []flow.Acce
 
 	return nil
 }
+
+// withCorrelationID adds the correlation ID of the flow to the query of a redirect, so that the next hop can log it.
+func withCorrelationID(query url.Values, correlationID string) url.Values {
+	if correlationID != "" {
+		query.Set(x.CorrelationIDParameter, correlationID)
+	}
+	return query
+}
+
+// sanitizedRequestParameters are the authorization request parameters which are not persisted with the flow, because
+// they carry client credentials or tokens. The claims of the id_token_hint are part of the OpenID Connect context, and
+// the parameters of request objects are part of the request form.
+var sanitizedRequestParameters = []string{
+	"client_secret",
+	"client_assertion",
+	"id_token_hint",
+	"request",
+	"code_verifier",
+}
+
+// sanitizeRequestParameters returns a copy of the authorization request form without client credentials and tokens.
+func sanitizeRequestParameters(form url.Values) flow.AuthorizationRequestParameters {
+	params := make(flow.AuthorizationRequestParameters, len(form))
+	for k, v := range form {
+		if stringslice.Has(sanitizedRequestParameters, k) {
+			continue
+		}
+		params[k] = append([]string{}, v...)
+	}
+	return params
+}
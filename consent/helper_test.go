@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -37,6 +38,28 @@ func TestSanitizeClient(t *testing.T) {
 	assert.NotEmpty(t, c.Secret)
 }
 
+func TestSanitizeRequestParameters(t *testing.T) {
+	form := url.Values{
+		"client_id":        {"client"},
+		"scope":            {"openid offline"},
+		"claims":           {`{"id_token":{"email":null}}`},
+		"client_secret":    {"secret"},
+		"client_assertion": {"eyJhbGciOi..."},
+		"id_token_hint":    {"eyJhbGciOi..."},
+		"request":          {"eyJhbGciOi..."},
+	}
+
+	got := sanitizeRequestParameters(form)
+	assert.Equal(t, flow.AuthorizationRequestParameters{
+		"client_id": {"client"},
+		"scope":     {"openid offline"},
+		"claims":    {`{"id_token":{"email":null}}`},
+	}, got)
+
+	got["scope"][0] = "changed"
+	assert.Equal(t, "openid offline", form.Get("scope"), "the form must not be modified")
+}
+
 func TestMatchScopes(t *testing.T) {
 	for k, tc := range []struct {
 		granted         []flow.AcceptOAuth2ConsentRequest
@@ -0,0 +1,246 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringsx"
+)
+
+// LoginAssertionPath is the path of the endpoint which authenticates the subject of a login request by an assertion of
+// a trusted issuer.
+const LoginAssertionPath = LoginPath + "/assertion"
+
+// Login Assertion
+//
+// An assertion of a trusted issuer which authenticates the subject of a login request.
+//
+// swagger:model acceptOAuth2LoginAssertion
+type LoginAssertion struct {
+	// The assertion, a JWT signed by a trusted issuer whose subject is the authenticated end-user. The issuer, subject
+	// and key must be allowed by a trust relationship.
+	//
+	// required: true
+	Assertion string `json:"assertion"`
+
+	// Remember, if set to true, tells Ory to remember this user by telling the user agent (browser) to store
+	// a cookie with authentication data.
+	Remember bool `json:"remember"`
+
+	// RememberFor sets how long the authentication should be remembered for in seconds. If set to `0`, the
+	// authorization will be remembered for the duration of the browser session (using a session cookie).
+	RememberFor int `json:"remember_for"`
+}
+
+// Accept OAuth 2.0 Login Request by an Assertion
+//
+// swagger:parameters acceptOAuth2LoginAssertion
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type acceptOAuth2LoginAssertion struct {
+	// OAuth 2.0 Login Request Challenge
+	//
+	// in: query
+	// required: true
+	Challenge string `json:"login_challenge"`
+
+	// in: body
+	Body LoginAssertion
+}
+
+// swagger:route PUT /admin/oauth2/auth/requests/login/assertion/accept oAuth2 acceptOAuth2LoginAssertion
+//
+// # Accept an OAuth 2.0 Login Request by an Assertion
+//
+// This endpoint accepts the login request with the subject of an assertion of a trusted upstream identity provider,
+// so that a login provider which brokers single sign-on does not need to authenticate the subject again.
+//
+// The assertion is validated like the assertion of the JWT Bearer grant (RFC7523): it must be signed by a key of a
+// trust relationship of its issuer and subject, its audience must be the issuer URL of Ory, and it must not have been
+// used before. Assertions for the token endpoint are refused, so that assertions of the JWT Bearer grant can not be
+// used to log in. The "acr" and "amr" claims of the assertion are taken over, and its issuer and claims are stored in
+// the context of the login request.
+//
+// Login assertions must be enabled with `oauth2.grant.jwt.login_assertions`.
+//
+// The response contains a redirect URL which the login provider should redirect the user-agent to.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2RedirectTo
+//	  default: errorOAuth2
+func (h *Handler) acceptOAuth2LoginAssertion(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	if !h.c.GrantJWTLoginAssertions(ctx) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Login assertions are disabled.")))
+		return
+	}
+
+	challenge := stringsx.Coalesce(
+		r.URL.Query().Get("login_challenge"),
+		r.URL.Query().Get("challenge"),
+	)
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'challenge' is not defined but should have been.`)))
+		return
+	}
+
+	var body LoginAssertion
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&body); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err)))
+		return
+	}
+
+	claims, err := h.verifyLoginAssertion(ctx, body.Assertion)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	loginContext, err := json.Marshal(map[string]interface{}{"assertion": map[string]interface{}{
+		"issuer": claims["iss"],
+		"claims": claims,
+	}})
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+		return
+	}
+
+	acr, _ := claims["acr"].(string)
+	var amr []string
+	if values, ok := claims["amr"].([]interface{}); ok {
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				amr = append(amr, s)
+			}
+		}
+	}
+
+	subject, _ := claims["sub"].(string)
+	h.acceptLoginRequest(w, r, challenge, &flow.HandledLoginRequest{
+		Subject:     subject,
+		ACR:         acr,
+		AMR:         amr,
+		Context:     loginContext,
+		Remember:    body.Remember,
+		RememberFor: body.RememberFor,
+	})
+}
+
+// verifyLoginAssertion verifies a login assertion by the trust relationships of its issuer and subject, marks it as
+// used, and returns its claims.
+func (h *Handler) verifyLoginAssertion(ctx context.Context, assertion string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(assertion, func(t *jwt.Token) (interface{}, error) {
+		issuer, _ := t.Claims["iss"].(string)
+		subject, _ := t.Claims["sub"].(string)
+		kid, _ := t.Header["kid"].(string)
+		return h.loginAssertionKey(ctx, issuer, subject, kid)
+	})
+	if token != nil {
+		err = x.RevalidateTimeClaimsWithLeeway(err, token.Claims, h.r.Clock().Now(), h.c.ClockSkew(ctx))
+	}
+	if err != nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHint("The login assertion could not be verified.").WithDebug(err.Error()))
+	}
+	claims := token.Claims
+
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`The login assertion does not contain the "sub" claim.`))
+	}
+	if !claims.VerifyAudience(h.c.IssuerURL(ctx).String(), true) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The login assertion was not issued for this authorization server."))
+	}
+
+	expiresAt, ok := numericDateClaim(claims, "exp")
+	if !ok {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`The login assertion does not contain the "exp" claim.`))
+	}
+	issuedAt, ok := numericDateClaim(claims, "iat")
+	if !ok && !h.c.GetGrantTypeJWTBearerIssuedDateOptional(ctx) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`The login assertion does not contain the "iat" claim.`))
+	}
+	if !ok {
+		issuedAt = h.r.Clock().Now()
+	}
+	if expiresAt.Sub(issuedAt) > h.c.GetJWTMaxDuration(ctx) {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The login assertion is valid for longer than %s.", h.c.GetJWTMaxDuration(ctx)))
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		if h.c.GetGrantTypeJWTBearerIDOptional(ctx) {
+			return claims, nil
+		}
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`The login assertion does not contain the "jti" claim.`))
+	}
+	if used, err := h.r.OAuth2Storage().IsJWTUsed(ctx, jti); err != nil {
+		return nil, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	} else if used {
+		return nil, errorsx.WithStack(fosite.ErrJTIKnown.WithHint("The login assertion has already been used."))
+	}
+	if err := h.r.OAuth2Storage().MarkJWTUsedForTime(ctx, jti, expiresAt); err != nil {
+		return nil, errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	return claims, nil
+}
+
+// loginAssertionKey returns the key of a trust relationship of the issuer and subject by its key ID. The key ID may
+// be omitted if the trust relationships have a single key.
+func (h *Handler) loginAssertionKey(ctx context.Context, issuer, subject, kid string) (*jose.JSONWebKey, error) {
+	if kid != "" {
+		key, err := h.r.OAuth2Storage().GetPublicKey(ctx, issuer, subject, kid)
+		if err != nil {
+			return nil, errors.Errorf("the issuer %q is not trusted to assert the subject %q with the key ID %q", issuer, subject, kid)
+		}
+		return key, nil
+	}
+
+	keys, err := h.r.OAuth2Storage().GetPublicKeys(ctx, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys.Keys) != 1 {
+		return nil, errors.Errorf("the issuer %q is not trusted to assert the subject %q, or the assertion has no key ID", issuer, subject)
+	}
+	return &keys.Keys[0], nil
+}
+
+// numericDateClaim returns the time of a NumericDate claim.
+func numericDateClaim(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return time.Unix(n, 0), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,174 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/v2/client"
+	. "github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+)
+
+func TestLoginAssertion(t *testing.T) {
+	ctx := context.Background()
+	issuer := "https://idp.example.com"
+
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyOAuth2GrantJWTLoginAssertions, true)
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	h := NewHandler(reg, conf)
+	r := x.NewRouterAdmin(conf.AdminURL)
+	h.SetRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	key := &jose.JSONWebKey{Key: ecKey, KeyID: "idp-key", Algorithm: string(jose.ES256), Use: "sig"}
+	require.NoError(t, reg.GrantManager().CreateGrant(ctx, trust.Grant{
+		ID:              uuid.Must(uuid.NewV4()).String(),
+		Issuer:          issuer,
+		AllowAnySubject: true,
+		Scope:           []string{},
+		PublicKey:       trust.PublicKey{Set: issuer, KeyID: key.KeyID},
+		CreatedAt:       time.Now().UTC().Round(time.Second),
+		ExpiresAt:       time.Now().UTC().Round(time.Second).Add(time.Hour),
+	}, key.Public()))
+
+	sign := func(t *testing.T, key *jose.JSONWebKey, claims map[string]interface{}) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", key.KeyID))
+		require.NoError(t, err)
+		token, err := josejwt.Signed(signer).Claims(claims).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	claims := func(subject string) map[string]interface{} {
+		return map[string]interface{}{
+			"iss": issuer,
+			"sub": subject,
+			"aud": conf.IssuerURL(ctx).String(),
+			"jti": uuid.Must(uuid.NewV4()).String(),
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"acr": "urn:example:loa:2",
+			"amr": []string{"pwd", "otp"},
+		}
+	}
+	newChallenge := func(t *testing.T) string {
+		cl := &client.Client{ID: uuid.Must(uuid.NewV4()).String()}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+		f, err := reg.ConsentManager().CreateLoginRequest(ctx, &flow.LoginRequest{
+			Client:      cl,
+			ID:          uuid.Must(uuid.NewV4()).String(),
+			RequestURL:  "http://192.0.2.1",
+			RequestedAt: time.Now(),
+		})
+		require.NoError(t, err)
+		challenge, err := f.ToLoginChallenge(ctx, reg)
+		require.NoError(t, err)
+		return challenge
+	}
+	accept := func(t *testing.T, challenge string, assertion string) (int, map[string]interface{}) {
+		body, err := json.Marshal(&LoginAssertion{Assertion: assertion})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPut, ts.URL+"/admin"+LoginAssertionPath+"/accept?login_challenge="+challenge, bytes.NewReader(body))
+		require.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		return res.StatusCode, result
+	}
+
+	t.Run("case=accepts the login request", func(t *testing.T) {
+		status, result := accept(t, newChallenge(t), sign(t, key, claims("alice")))
+		require.Equal(t, http.StatusOK, status, "%+v", result)
+		redirectTo, err := url.Parse(result["redirect_to"].(string))
+		require.NoError(t, err)
+
+		handled, err := reg.ConsentManager().VerifyAndInvalidateLoginRequest(ctx, redirectTo.Query().Get("login_verifier"))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", handled.Subject)
+		assert.Equal(t, "urn:example:loa:2", handled.ACR)
+		assert.EqualValues(t, []string{"pwd", "otp"}, handled.AMR)
+		assert.Equal(t, issuer, gjson.GetBytes(handled.Context, "assertion.issuer").String())
+		assert.Equal(t, "alice", gjson.GetBytes(handled.Context, "assertion.claims.sub").String())
+	})
+
+	t.Run("case=rejects a replayed assertion", func(t *testing.T) {
+		assertion := sign(t, key, claims("alice"))
+		status, result := accept(t, newChallenge(t), assertion)
+		require.Equal(t, http.StatusOK, status, "%+v", result)
+
+		status, result = accept(t, newChallenge(t), assertion)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "already been used")
+	})
+
+	t.Run("case=rejects an assertion of an untrusted key", func(t *testing.T) {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		status, result := accept(t, newChallenge(t), sign(t, &jose.JSONWebKey{Key: other, KeyID: key.KeyID}, claims("alice")))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "could not be verified")
+	})
+
+	t.Run("case=rejects an assertion for another audience", func(t *testing.T) {
+		c := claims("alice")
+		c["aud"] = "https://other.example.com"
+		status, result := accept(t, newChallenge(t), sign(t, key, c))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "not issued for this authorization server")
+	})
+
+	t.Run("case=rejects an assertion for the token endpoint", func(t *testing.T) {
+		c := claims("alice")
+		c["aud"] = conf.OAuth2TokenURL(ctx).String()
+		status, result := accept(t, newChallenge(t), sign(t, key, c))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "not issued for this authorization server")
+	})
+
+	t.Run("case=rejects an expired assertion", func(t *testing.T) {
+		c := claims("alice")
+		c["iat"] = time.Now().Add(-time.Hour).Unix()
+		c["exp"] = time.Now().Add(-time.Minute).Unix()
+		status, _ := accept(t, newChallenge(t), sign(t, key, c))
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("case=rejects assertions if disabled", func(t *testing.T) {
+		conf.MustSet(ctx, config.KeyOAuth2GrantJWTLoginAssertions, false)
+		t.Cleanup(func() { conf.MustSet(ctx, config.KeyOAuth2GrantJWTLoginAssertions, true) })
+
+		status, result := accept(t, newChallenge(t), sign(t, key, claims("alice")))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "disabled")
+	})
+}
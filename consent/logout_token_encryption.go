@@ -0,0 +1,95 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/client"
+)
+
+// encryptLogoutToken encrypts the signed logout token to a key of the client, resulting in a Nested JWT. The key is
+// taken from the jwks of the client or fetched from its jwks_uri.
+func (s *DefaultStrategy) encryptLogoutToken(ctx context.Context, c *client.Client, token string) (string, error) {
+	alg := c.BackChannelLogoutTokenEncryptedResponseAlg
+	enc := c.BackChannelLogoutTokenEncryptedResponseEnc
+	if enc == "" {
+		enc = string(jose.A128CBC_HS256)
+	}
+
+	key, err := s.logoutTokenEncryptionKey(ctx, c, alg)
+	if err != nil {
+		return "", err
+	}
+
+	encrypter, err := jose.NewEncrypter(
+		jose.ContentEncryption(enc),
+		jose.Recipient{Algorithm: jose.KeyAlgorithm(alg), Key: key.Key, KeyID: key.KeyID},
+		(&jose.EncrypterOptions{}).WithContentType("JWT"),
+	)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	encrypted, err := encrypter.Encrypt([]byte(token))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return encrypted.CompactSerialize()
+}
+
+// logoutTokenEncryptionKey returns the first key of the client which may be used for encryption with the algorithm.
+// Keys from the jwks_uri are fetched again if none matches, as the client may have rotated its keys.
+func (s *DefaultStrategy) logoutTokenEncryptionKey(ctx context.Context, c *client.Client, alg string) (*jose.JSONWebKey, error) {
+	if keys := c.GetJSONWebKeys(); keys != nil && len(keys.Keys) > 0 {
+		if key := findEncryptionKey(keys, alg); key != nil {
+			return key, nil
+		}
+		return nil, errors.Errorf("the client has no key for encryption with %s", alg)
+	}
+
+	if c.GetJSONWebKeysURI() == "" {
+		return nil, errors.New("the client has no JSON Web Keys registered")
+	}
+
+	for _, ignoreCache := range []bool{false, true} {
+		keys, err := s.r.GetJWKSFetcherStrategy().Resolve(ctx, c.GetJSONWebKeysURI(), ignoreCache)
+		if err != nil {
+			return nil, err
+		}
+		if key := findEncryptionKey(keys, alg); key != nil {
+			return key, nil
+		}
+	}
+	return nil, errors.Errorf("the client has no key for encryption with %s", alg)
+}
+
+// findEncryptionKey returns the public part of the first key which is not restricted to another use or algorithm and
+// whose type matches the algorithm.
+func findEncryptionKey(keys *jose.JSONWebKeySet, alg string) *jose.JSONWebKey {
+	for _, k := range keys.Keys {
+		if (k.Use != "" && k.Use != "enc") || (k.Algorithm != "" && k.Algorithm != alg) {
+			continue
+		}
+
+		public := k.Public()
+		switch public.Key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(alg, "RSA-") {
+				return &public
+			}
+		case *ecdsa.PublicKey:
+			if strings.HasPrefix(alg, "ECDH-ES") {
+				return &public
+			}
+		}
+	}
+	return nil
+}
@@ -5,11 +5,13 @@ package consent
 
 import (
 	"context"
+	"time"
 
 	"github.com/gofrs/uuid"
 
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 type ForcedObfuscatedLoginSession struct {
@@ -30,18 +32,38 @@ type (
 		HandleConsentRequest(ctx context.Context, f *flow.Flow, r *flow.AcceptOAuth2ConsentRequest) (*flow.OAuth2ConsentRequest, error)
 		RevokeSubjectConsentSession(ctx context.Context, user string) error
 		RevokeSubjectClientConsentSession(ctx context.Context, user, client string) error
+		// RevokeSubjectConsentSessionDryRun returns the number of resources by type which
+		// RevokeSubjectClientConsentSession would revoke, or RevokeSubjectConsentSession if client is empty.
+		RevokeSubjectConsentSessionDryRun(ctx context.Context, user, client string) (map[string]int, error)
 
 		VerifyAndInvalidateConsentRequest(ctx context.Context, verifier string) (*flow.AcceptOAuth2ConsentRequest, error)
 		FindGrantedAndRememberedConsentRequests(ctx context.Context, client, user string) ([]flow.AcceptOAuth2ConsentRequest, error)
-		FindSubjectsGrantedConsentRequests(ctx context.Context, user string, limit, offset int) ([]flow.AcceptOAuth2ConsentRequest, error)
-		FindSubjectsSessionGrantedConsentRequests(ctx context.Context, user, sid string, limit, offset int) ([]flow.AcceptOAuth2ConsentRequest, error)
+		FindSubjectsGrantedConsentRequests(ctx context.Context, user string, pageOpts ...keysetpagination.Option) ([]flow.AcceptOAuth2ConsentRequest, *keysetpagination.Paginator, error)
+		FindSubjectsSessionGrantedConsentRequests(ctx context.Context, user, sid string, pageOpts ...keysetpagination.Option) ([]flow.AcceptOAuth2ConsentRequest, *keysetpagination.Paginator, error)
 		CountSubjectsGrantedConsentRequests(ctx context.Context, user string) (int, error)
 
+		// FlushConsentSessions deletes granted consent sessions which were requested before notAfter, including the
+		// tokens issued from them.
+		FlushConsentSessions(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+
+		// RecordGrantHistory records that the subject granted the scopes and audiences to the client at the given time.
+		RecordGrantHistory(ctx context.Context, subject, client string, scopes, audiences []string, at time.Time) error
+		// ListGrantHistory lists the scopes and audiences the subject has ever granted, most recently granted first. If
+		// client is not empty, only the grants to that client are listed.
+		ListGrantHistory(ctx context.Context, subject, client string, pageOpts ...keysetpagination.Option) ([]GrantHistoryEntry, *keysetpagination.Paginator, error)
+
 		// Cookie management
 		GetRememberedLoginSession(ctx context.Context, loginSessionFromCookie *flow.LoginSession, id string) (*flow.LoginSession, error)
 		CreateLoginSession(ctx context.Context, session *flow.LoginSession) error
 		DeleteLoginSession(ctx context.Context, id string) (deletedSession *flow.LoginSession, err error)
 		RevokeSubjectLoginSession(ctx context.Context, user string) error
+		// RevokeLoginSessionTokens revokes the access and refresh tokens which were issued to the subject in the login
+		// session to clients which revoke their tokens on logout. If sid is empty, the tokens of all login sessions of
+		// the subject are revoked.
+		RevokeLoginSessionTokens(ctx context.Context, subject, sid string) error
+		// RevokeSubjectLoginSessionDryRun returns the number of resources by type which revoking the login sessions
+		// of the subject, or the login session sid if it is not empty, would revoke.
+		RevokeSubjectLoginSessionDryRun(ctx context.Context, subject, sid string) (map[string]int, error)
 		ConfirmLoginSession(ctx context.Context, loginSession *flow.LoginSession) error
 
 		CreateLoginRequest(ctx context.Context, req *flow.LoginRequest) (*flow.Flow, error)
@@ -14,6 +14,7 @@ import (
 	"github.com/ory/hydra/v2/flow"
 	"github.com/ory/x/assertx"
 	"github.com/ory/x/contextx"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	gofrsuuid "github.com/gofrs/uuid"
 	"github.com/google/uuid"
@@ -796,7 +797,7 @@ func ManagerTests(deps Deps, m Manager, clientManager client.Manager, fositeMana
 				},
 			} {
 				t.Run(fmt.Sprintf("case=%d/subject=%s/session=%s", i, tc.subject, tc.sid), func(t *testing.T) {
-					consents, err := m.FindSubjectsSessionGrantedConsentRequests(ctx, tc.subject, tc.sid, 100, 0)
+					consents, _, err := m.FindSubjectsSessionGrantedConsentRequests(ctx, tc.subject, tc.sid)
 					assert.Equal(t, len(tc.challenges), len(consents))
 
 					if len(tc.challenges) == 0 {
@@ -838,7 +839,7 @@ func ManagerTests(deps Deps, m Manager, clientManager client.Manager, fositeMana
 				},
 			} {
 				t.Run(fmt.Sprintf("case=%d/subject=%s", i, tc.subject), func(t *testing.T) {
-					consents, err := m.FindSubjectsGrantedConsentRequests(ctx, tc.subject, 100, 0)
+					consents, _, err := m.FindSubjectsGrantedConsentRequests(ctx, tc.subject)
 					assert.Equal(t, len(tc.challenges), len(consents))
 
 					if len(tc.challenges) == 0 {
@@ -1096,6 +1097,45 @@ func ManagerTests(deps Deps, m Manager, clientManager client.Manager, fositeMana
 			require.NoError(t, err)
 			assert.EqualValues(t, expected.ID, result.ID)
 		})
+
+		t.Run("case=grant-history", func(t *testing.T) {
+			subject := uuid.New().String()
+			first := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+			last := first.Add(time.Minute)
+
+			require.NoError(t, m.RecordGrantHistory(ctx, subject, "client-a", []string{"openid", "offline"}, []string{"https://api.example.org"}, first))
+			require.NoError(t, m.RecordGrantHistory(ctx, subject, "client-a", []string{"openid", "openid"}, nil, last))
+			require.NoError(t, m.RecordGrantHistory(ctx, subject, "client-b", []string{"email"}, nil, first))
+
+			entries, _, err := m.ListGrantHistory(ctx, subject, "client-a")
+			require.NoError(t, err)
+			require.Len(t, entries, 3)
+			assert.Equal(t, GrantHistoryTypeScope, entries[0].Type)
+			assert.Equal(t, "openid", entries[0].Value)
+			assert.Equal(t, 2, entries[0].GrantCount)
+			assert.Equal(t, first.Unix(), entries[0].FirstGrantedAt.Unix())
+			assert.Equal(t, last.Unix(), entries[0].LastGrantedAt.Unix())
+			for _, e := range entries[1:] {
+				assert.Equal(t, 1, e.GrantCount)
+				assert.Equal(t, "client-a", e.ClientID)
+			}
+
+			entries, nextPage, err := m.ListGrantHistory(ctx, subject, "", keysetpagination.WithSize(2))
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+			assert.Equal(t, "openid", entries[0].Value)
+			require.False(t, nextPage.IsLast())
+
+			rest, nextPage, err := m.ListGrantHistory(ctx, subject, "", nextPage.ToOptions()...)
+			require.NoError(t, err)
+			require.Len(t, rest, 2)
+			assert.True(t, nextPage.IsLast())
+			assert.NotContains(t, rest, entries[1])
+
+			entries, _, err = m.ListGrantHistory(ctx, uuid.New().String(), "")
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
 	}
 }
 
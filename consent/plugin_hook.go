@@ -0,0 +1,95 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/extension"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/x/errorsx"
+)
+
+// handleLoginRequestWithPlugins lets the login providers handle the login request of the challenge. It returns where
+// to redirect the user agent to, or nil if no login provider made a decision.
+func (s *DefaultStrategy) handleLoginRequestWithPlugins(r *http.Request, challenge string) (*flow.OAuth2RedirectTo, error) {
+	providers := extension.Hooks[extension.LoginProvider](s.r.Plugins())
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	flows := NewFlowHandler(s.r)
+	request, err := flows.GetLoginRequest(r.Context(), challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range providers {
+		decision, err := p.HandleLoginRequest(r.Context(), r, request)
+		if err != nil {
+			return nil, pluginError(p, err)
+		}
+
+		switch {
+		case decision == nil:
+			continue
+		case decision.Accept != nil:
+			return flows.AcceptLoginRequest(r, challenge, decision.Accept)
+		case decision.Reject != nil:
+			return flows.RejectLoginRequest(r, challenge, decision.Reject)
+		default:
+			return nil, pluginError(p, errors.New("the login decision neither accepts nor rejects the login request"))
+		}
+	}
+	return nil, nil
+}
+
+// handleConsentRequestWithPlugins lets the consent providers handle the consent request of the challenge. It returns
+// where to redirect the user agent to, or nil if no consent provider made a decision.
+func (s *DefaultStrategy) handleConsentRequestWithPlugins(r *http.Request, challenge string) (*flow.OAuth2RedirectTo, error) {
+	providers := extension.Hooks[extension.ConsentProvider](s.r.Plugins())
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	flows := NewFlowHandler(s.r)
+	request, err := flows.GetConsentRequest(r.Context(), challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range providers {
+		decision, err := p.HandleConsentRequest(r.Context(), r, request)
+		if err != nil {
+			return nil, pluginError(p, err)
+		}
+
+		switch {
+		case decision == nil:
+			continue
+		case decision.Accept != nil:
+			return flows.AcceptConsentRequest(r, challenge, decision.Accept)
+		case decision.Reject != nil:
+			return flows.RejectConsentRequest(r, challenge, decision.Reject)
+		default:
+			return nil, pluginError(p, errors.New("the consent decision neither accepts nor rejects the consent request"))
+		}
+	}
+	return nil, nil
+}
+
+// pluginError returns OAuth 2.0 errors of plugins as they are and all other errors as server errors.
+func pluginError(p extension.Plugin, err error) error {
+	var rfcErr *fosite.RFC6749Error
+	if errors.As(err, &rfcErr) {
+		return err
+	}
+	return errorsx.WithStack(fosite.ErrServerError.
+		WithWrap(err).
+		WithDescription("An error occurred while executing a plugin.").
+		WithDebugf("Plugin %s failed: %s", p.Name(), err))
+}
@@ -0,0 +1,108 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/extension"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/x/contextx"
+)
+
+type flowProviderPlugin struct {
+	rejectLogin   bool
+	rejectConsent bool
+	skipLogin     bool
+}
+
+func (p *flowProviderPlugin) Name() string { return "flow-provider" }
+
+func (p *flowProviderPlugin) HandleLoginRequest(_ context.Context, _ *http.Request, req *flow.LoginRequest) (*extension.LoginDecision, error) {
+	switch {
+	case p.skipLogin:
+		return nil, nil
+	case p.rejectLogin:
+		return &extension.LoginDecision{Reject: &flow.RequestDeniedError{Name: "access_denied", Description: "The subject is locked."}}, nil
+	}
+	return &extension.LoginDecision{Accept: &flow.HandledLoginRequest{Subject: "alice"}}, nil
+}
+
+func (p *flowProviderPlugin) HandleConsentRequest(_ context.Context, _ *http.Request, req *flow.OAuth2ConsentRequest) (*extension.ConsentDecision, error) {
+	if p.rejectConsent {
+		return &extension.ConsentDecision{Reject: &flow.RequestDeniedError{Name: "access_denied", Description: "The subject declined."}}, nil
+	}
+	return &extension.ConsentDecision{Accept: &flow.AcceptOAuth2ConsentRequest{GrantedScope: req.RequestedScope}}, nil
+}
+
+func TestFlowProviderPlugins(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	plugin := new(flowProviderPlugin)
+	reg.WithPlugins(extension.Plugins{plugin})
+	testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	c := createClient(t, reg, &client.Client{RedirectURIs: []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)}})
+
+	t.Run("case=login and consent are handled in-process", func(t *testing.T) {
+		testhelpers.NewLoginConsentUI(t, reg.Config(), testhelpers.HTTPServerNoExpectedCallHandler(t), testhelpers.HTTPServerNoExpectedCallHandler(t))
+		*plugin = flowProviderPlugin{}
+
+		_, res := makeOAuth2Request(t, reg, nil, c, url.Values{"scope": {"openid"}})
+		assert.EqualValues(t, http.StatusNotImplemented, res.StatusCode)
+		assert.NotEmpty(t, res.Request.URL.Query().Get("code"), "%v", res.Request.URL.Query())
+	})
+
+	t.Run("case=login is rejected in-process", func(t *testing.T) {
+		testhelpers.NewLoginConsentUI(t, reg.Config(), testhelpers.HTTPServerNoExpectedCallHandler(t), testhelpers.HTTPServerNoExpectedCallHandler(t))
+		*plugin = flowProviderPlugin{rejectLogin: true}
+
+		_, res := makeOAuth2Request(t, reg, nil, c, url.Values{"scope": {"openid"}})
+		assert.EqualValues(t, http.StatusNotImplemented, res.StatusCode)
+		assert.Empty(t, res.Request.URL.Query().Get("code"))
+		assert.Equal(t, "The subject is locked.", res.Request.URL.Query().Get("error_description"))
+	})
+
+	t.Run("case=consent is rejected in-process", func(t *testing.T) {
+		testhelpers.NewLoginConsentUI(t, reg.Config(), testhelpers.HTTPServerNoExpectedCallHandler(t), testhelpers.HTTPServerNoExpectedCallHandler(t))
+		*plugin = flowProviderPlugin{rejectConsent: true}
+
+		_, res := makeOAuth2Request(t, reg, nil, c, url.Values{"scope": {"openid"}})
+		assert.EqualValues(t, http.StatusNotImplemented, res.StatusCode)
+		assert.Empty(t, res.Request.URL.Query().Get("code"))
+		assert.Equal(t, "The subject declined.", res.Request.URL.Query().Get("error_description"))
+	})
+
+	t.Run("case=login requests without a decision are sent to the login UI", func(t *testing.T) {
+		var challenge string
+		testhelpers.NewLoginConsentUI(t, reg.Config(), func(w http.ResponseWriter, r *http.Request) {
+			challenge = r.URL.Query().Get("login_challenge")
+
+			flows := consent.NewFlowHandler(reg)
+			req, err := flows.GetLoginRequest(r.Context(), challenge)
+			require.NoError(t, err)
+			assert.Empty(t, req.Client.Secret)
+
+			redirectTo, err := flows.AcceptLoginRequest(r, challenge, &flow.HandledLoginRequest{Subject: "bob"})
+			require.NoError(t, err)
+			http.Redirect(w, r, redirectTo.RedirectTo, http.StatusFound)
+		}, testhelpers.HTTPServerNoExpectedCallHandler(t))
+		*plugin = flowProviderPlugin{skipLogin: true}
+
+		_, res := makeOAuth2Request(t, reg, nil, c, url.Values{"scope": {"openid"}})
+		assert.EqualValues(t, http.StatusNotImplemented, res.StatusCode)
+		assert.NotEmpty(t, challenge)
+		assert.NotEmpty(t, res.Request.URL.Query().Get("code"), "%v", res.Request.URL.Query())
+	})
+}
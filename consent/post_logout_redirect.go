@@ -0,0 +1,72 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+)
+
+// matchPostLogoutRedirectURI returns the requested post_logout_redirect_uri if the client, which was identified by
+// the id_token_hint, may be redirected to it. The URI matches if it
+//
+//   - equals one of the post-logout redirect URIs of the client or of the global allow-list,
+//   - matches one of them whose host starts with a "*." label, if subdomain wildcards are enabled, or
+//   - has the same origin as one of the redirect or post-logout redirect URIs of the client, if same-origin
+//     redirects are enabled.
+func (s *DefaultStrategy) matchPostLogoutRedirectURI(ctx context.Context, cl *client.Client, requested string) (*url.URL, error) {
+	notAllowed := errorsx.WithStack(fosite.ErrInvalidRequest.
+		WithHint("Logout failed because query parameter post_logout_redirect_uri is not a whitelisted as a post_logout_redirect_uri for the client."),
+	)
+
+	u, err := url.Parse(requested)
+	if err != nil {
+		return nil, notAllowed
+	}
+
+	allowed := append(append([]string{}, cl.PostLogoutRedirectURIs...), s.c.PostLogoutRedirectURIsAllowList(ctx)...)
+	for _, a := range allowed {
+		if a == requested {
+			return u, nil
+		}
+	}
+
+	// Relaxed matching only applies to absolute URIs without user info and fragment.
+	if !u.IsAbs() || u.Host == "" || u.User != nil || u.Fragment != "" {
+		return nil, notAllowed
+	}
+
+	if s.c.PostLogoutRedirectSubdomainWildcards(ctx) {
+		for _, a := range allowed {
+			p, err := url.Parse(a)
+			if err != nil {
+				continue
+			}
+			if p.Scheme == u.Scheme && p.Port() == u.Port() && p.Path == u.Path && p.RawQuery == u.RawQuery &&
+				x.MatchSubdomainWildcard(p.Hostname(), u.Hostname()) {
+				return u, nil
+			}
+		}
+	}
+
+	if s.c.PostLogoutRedirectSameOrigin(ctx) {
+		for _, o := range append(append([]string{}, cl.RedirectURIs...), cl.PostLogoutRedirectURIs...) {
+			p, err := url.Parse(o)
+			if err != nil {
+				continue
+			}
+			if p.Scheme == u.Scheme && p.Port() == u.Port() && strings.EqualFold(p.Hostname(), u.Hostname()) {
+				return u, nil
+			}
+		}
+	}
+
+	return nil, notAllowed
+}
@@ -0,0 +1,125 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/jsonschema/v3"
+)
+
+// presentationDefinition is the subset of a DIF Presentation Exchange presentation definition which is evaluated
+// against the credential of a SIOPv2 response: every input descriptor must be satisfied by the credential.
+type presentationDefinition struct {
+	ID               string              `json:"id"`
+	InputDescriptors []presentationInput `json:"input_descriptors"`
+}
+
+type presentationInput struct {
+	ID          string `json:"id"`
+	Constraints struct {
+		Fields []presentationField `json:"fields"`
+	} `json:"constraints"`
+}
+
+type presentationField struct {
+	Path     []string        `json:"path"`
+	Filter   json.RawMessage `json:"filter,omitempty"`
+	Optional bool            `json:"optional,omitempty"`
+}
+
+// evaluate returns an error if the claims of the credential do not satisfy the presentation definition.
+func (d *presentationDefinition) evaluate(ctx context.Context, claims map[string]interface{}) error {
+	for _, input := range d.InputDescriptors {
+		for k, field := range input.Constraints.Fields {
+			if field.Optional {
+				continue
+			}
+			if err := field.evaluate(ctx, claims); err != nil {
+				return errors.Wrapf(err, "input descriptor %q is not satisfied by field %d", input.ID, k)
+			}
+		}
+	}
+	return nil
+}
+
+// evaluate returns an error unless one of the paths of the field selects a claim which matches its filter.
+func (f *presentationField) evaluate(ctx context.Context, claims map[string]interface{}) error {
+	var filter *jsonschema.Schema
+	if len(f.Filter) > 0 {
+		var err error
+		if filter, err = jsonschema.CompileString(ctx, "presentation_definition_filter.json", string(f.Filter)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for _, path := range f.Path {
+		value, ok := selectJSONPath(claims, path)
+		if !ok {
+			continue
+		}
+		if filter == nil || filter.ValidateInterface(value) == nil {
+			return nil
+		}
+	}
+	return errors.Errorf("none of the paths %v selects a matching claim", f.Path)
+}
+
+// selectJSONPath selects a value by a JSONPath expression which only consists of member and index selectors, for
+// example $.address.country or $['credentialSubject']['given_name'].
+func selectJSONPath(doc interface{}, path string) (interface{}, bool) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, false
+	}
+	rest := path[1:]
+	for rest != "" {
+		var selector string
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			selector, rest = rest[:end], rest[end:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, false
+			}
+			selector, rest = rest[2:end], rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, false
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, false
+			}
+			items, ok := doc.([]interface{})
+			if !ok || index < 0 || index >= len(items) {
+				return nil, false
+			}
+			doc, rest = items[index], rest[end+1:]
+			continue
+		default:
+			return nil, false
+		}
+
+		members, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if doc, ok = members[selector]; !ok {
+			return nil, false
+		}
+	}
+	return doc, true
+}
@@ -6,10 +6,14 @@ package consent
 import (
 	"context"
 
+	"github.com/ory/fosite"
 	"github.com/ory/fosite/handler/openid"
 	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/audit"
 	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/extension"
 	"github.com/ory/hydra/v2/internal/kratos"
+	"github.com/ory/hydra/v2/jwk"
 	"github.com/ory/hydra/v2/x"
 )
 
@@ -18,17 +22,22 @@ type InternalRegistry interface {
 	x.RegistryCookieStore
 	x.RegistryLogger
 	x.HTTPClientProvider
+	x.ClockProvider
 	kratos.Provider
 	Registry
+	audit.Registry
 	client.Registry
+	extension.Registry
 
 	FlowCipher() *aead.XChaCha20Poly1305
 	OAuth2Storage() x.FositeStorer
 	OpenIDConnectRequestValidator() *openid.OpenIDConnectRequestValidator
+	GetJWKSFetcherStrategy() fosite.JWKSFetcherStrategy
 }
 
 type Registry interface {
 	ConsentManager() Manager
 	ConsentStrategy() Strategy
 	SubjectIdentifierAlgorithm(ctx context.Context) map[string]SubjectIdentifierAlgorithm
+	LogoutTokenJWTStrategy() jwk.JWTSigner
 }
@@ -0,0 +1,61 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/mapx"
+)
+
+// sessionFingerprint returns the fingerprint of the request attributes configured in
+// `oauth2.session_fingerprint.attributes`, or an empty string if none are configured.
+func (s *DefaultStrategy) sessionFingerprint(ctx context.Context, r *http.Request) string {
+	attributes := s.c.SessionFingerprintAttributes(ctx)
+	if len(attributes) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, attribute := range attributes {
+		var value string
+		switch {
+		case attribute == "client_ip":
+			value = x.ClientIP(r)
+		case attribute == "user_agent":
+			value = r.UserAgent()
+		case strings.HasPrefix(attribute, "header:"):
+			value = r.Header.Get(strings.TrimPrefix(attribute, "header:"))
+		}
+		_, _ = fmt.Fprintf(h, "%q=%q\n", attribute, value)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// matchesSessionFingerprint reports whether the request matches the fingerprint which was captured in the
+// authentication session cookie at login. Requests always match if no fingerprint attributes are configured.
+func (s *DefaultStrategy) matchesSessionFingerprint(ctx context.Context, r *http.Request) bool {
+	expected := s.sessionFingerprint(ctx, r)
+	if expected == "" {
+		return true
+	}
+
+	store, err := s.r.CookieStore(ctx)
+	if err != nil {
+		return false
+	}
+	cookie, err := store.Get(r, s.c.SessionCookieName(ctx))
+	if err != nil {
+		return false
+	}
+	fingerprint := mapx.GetStringDefault(cookie.Values, CookieAuthenticationFingerprintName, "")
+	return subtle.ConstantTimeCompare([]byte(fingerprint), []byte(expected)) == 1
+}
@@ -0,0 +1,432 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/josex"
+	"github.com/ory/x/stringsx"
+)
+
+// SIOPPath is the path of the endpoints which authenticate the subject of a login request by the response of a
+// Self-Issued OpenID Provider v2 (SIOPv2), such as a wallet.
+const SIOPPath = LoginPath + "/siop"
+
+// SIOPv2 Authorization Request
+//
+// The parameters of the SIOPv2 and OpenID for Verifiable Presentations authorization request which the login provider
+// sends to the wallet of the subject.
+//
+// swagger:model oAuth2LoginSIOPRequest
+type SIOPAuthorizationRequest struct {
+	// The client ID of Ory towards the wallet, which is the audience of the ID token and the key binding JWT.
+	ClientID string `json:"client_id"`
+
+	// The response type, which is "vp_token id_token" if a credential must be presented, and "id_token" otherwise.
+	ResponseType string `json:"response_type"`
+
+	// The requested scope, which is always "openid".
+	Scope string `json:"scope"`
+
+	// The nonce which binds the response of the wallet to the login request.
+	Nonce string `json:"nonce"`
+
+	// The presentation definition which the presented credential must satisfy.
+	PresentationDefinition json.RawMessage `json:"presentation_definition,omitempty"`
+}
+
+// SIOPv2 Authorization Response
+//
+// The response of the wallet to a SIOPv2 authorization request.
+//
+// swagger:model acceptOAuth2LoginSIOPResponse
+type SIOPAuthorizationResponse struct {
+	// The self-issued ID token of the wallet.
+	//
+	// required: true
+	IDToken string `json:"id_token"`
+
+	// The presented credential, an SD-JWT verifiable credential with a key binding JWT.
+	VPToken string `json:"vp_token,omitempty"`
+
+	// Remember, if set to true, tells Ory to remember this user by telling the user agent (browser) to store
+	// a cookie with authentication data.
+	Remember bool `json:"remember"`
+
+	// RememberFor sets how long the authentication should be remembered for in seconds. If set to `0`, the
+	// authorization will be remembered for the duration of the browser session (using a session cookie).
+	RememberFor int `json:"remember_for"`
+}
+
+// Get OAuth 2.0 Login SIOPv2 Request
+//
+// swagger:parameters getOAuth2LoginSIOPRequest
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getOAuth2LoginSIOPRequest struct {
+	// OAuth 2.0 Login Request Challenge
+	//
+	// in: query
+	// required: true
+	Challenge string `json:"login_challenge"`
+}
+
+// swagger:route GET /admin/oauth2/auth/requests/login/siop oAuth2 getOAuth2LoginSIOPRequest
+//
+// # Get the SIOPv2 Authorization Request of an OAuth 2.0 Login Request
+//
+// Returns the parameters of the Self-Issued OpenID Provider v2 authorization request which the login provider sends
+// to the wallet of the subject, to authenticate the subject by the wallet instead of by a login screen.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2LoginSIOPRequest
+//	  410: oAuth2RedirectTo
+//	  default: errorOAuth2
+func (h *Handler) getOAuth2LoginSIOPRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	challenge := stringsx.Coalesce(
+		r.URL.Query().Get("login_challenge"),
+		r.URL.Query().Get("challenge"),
+	)
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'challenge' is not defined but should have been.`)))
+		return
+	}
+
+	request, err := h.r.ConsentManager().GetLoginRequest(ctx, challenge)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	if request.WasHandled {
+		h.r.Writer().WriteCode(w, r, http.StatusGone, &flow.OAuth2RedirectTo{
+			RedirectTo: request.RequestURL,
+		})
+		return
+	}
+
+	definition := h.c.OIDCSIOPPresentationDefinition(ctx)
+	responseType := "id_token"
+	if definition != nil {
+		responseType = "vp_token id_token"
+	}
+
+	h.r.Writer().Write(w, r, &SIOPAuthorizationRequest{
+		ClientID:               h.c.OIDCSIOPClientID(ctx),
+		ResponseType:           responseType,
+		Scope:                  "openid",
+		Nonce:                  siopNonce(challenge),
+		PresentationDefinition: definition,
+	})
+}
+
+// Accept OAuth 2.0 Login Request by a SIOPv2 Response
+//
+// swagger:parameters acceptOAuth2LoginSIOPResponse
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type acceptOAuth2LoginSIOPResponse struct {
+	// OAuth 2.0 Login Request Challenge
+	//
+	// in: query
+	// required: true
+	Challenge string `json:"login_challenge"`
+
+	// in: body
+	Body SIOPAuthorizationResponse
+}
+
+// swagger:route PUT /admin/oauth2/auth/requests/login/siop/accept oAuth2 acceptOAuth2LoginSIOPResponse
+//
+// # Accept an OAuth 2.0 Login Request by a SIOPv2 Response
+//
+// This endpoint verifies the response of the wallet to the SIOPv2 authorization request of the login request and, if
+// it is valid, accepts the login request with the subject of the self-issued ID token.
+//
+// The ID token must be signed by the key of its subject, and the subject must be the JWK thumbprint of that key or
+// a did:jwk. If a presentation definition is configured, the wallet must present an SD-JWT verifiable credential
+// of a trusted issuer which is bound to the same key and satisfies the presentation definition. The disclosed claims
+// of the credential are stored in the context of the login request.
+//
+// The response contains a redirect URL which the login provider should redirect the user-agent to.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: oAuth2RedirectTo
+//	  default: errorOAuth2
+func (h *Handler) acceptOAuth2LoginSIOPResponse(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	challenge := stringsx.Coalesce(
+		r.URL.Query().Get("login_challenge"),
+		r.URL.Query().Get("challenge"),
+	)
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Query parameter 'challenge' is not defined but should have been.`)))
+		return
+	}
+
+	var response SIOPAuthorizationResponse
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&response); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err)))
+		return
+	}
+
+	nonce := siopNonce(challenge)
+	holder, subject, err := h.verifySIOPIDToken(ctx, response.IDToken, nonce)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	siopContext := map[string]interface{}{"sub_jwk": holder}
+	if definition := h.c.OIDCSIOPPresentationDefinition(ctx); definition != nil || response.VPToken != "" {
+		issuer, claims, err := h.verifySIOPVPToken(ctx, response.VPToken, holder, nonce)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		if definition != nil {
+			var pd presentationDefinition
+			if err := json.Unmarshal(definition, &pd); err != nil {
+				h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+				return
+			}
+			if err := pd.evaluate(ctx, claims); err != nil {
+				h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHint("The presented credential does not satisfy the presentation definition.").WithDebug(err.Error())))
+				return
+			}
+		}
+
+		siopContext["credential"] = map[string]interface{}{"issuer": issuer, "claims": claims}
+	}
+
+	loginContext, err := json.Marshal(map[string]interface{}{"siop": siopContext})
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+		return
+	}
+
+	h.acceptLoginRequest(w, r, challenge, &flow.HandledLoginRequest{
+		Subject:     subject,
+		AMR:         []string{"swk"},
+		Context:     loginContext,
+		Remember:    response.Remember,
+		RememberFor: response.RememberFor,
+	})
+}
+
+// siopNonce derives the nonce of the SIOPv2 authorization request from the login challenge, so that the response of
+// the wallet can only be used to accept this login request.
+func siopNonce(challenge string) string {
+	digest := sha256.Sum256([]byte("siop:" + challenge))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// verifySIOPIDToken verifies the self-issued ID token of a SIOPv2 response, and returns the key and the subject of
+// the wallet.
+func (h *Handler) verifySIOPIDToken(ctx context.Context, idToken, nonce string) (*jose.JSONWebKey, string, error) {
+	var holder *jose.JSONWebKey
+	token, err := jwt.Parse(idToken, func(t *jwt.Token) (interface{}, error) {
+		var err error
+		holder, err = siopSubjectJWK(t.Claims)
+		return holder, err
+	})
+	if token != nil {
+		err = x.RevalidateTimeClaimsWithLeeway(err, token.Claims, h.r.Clock().Now(), h.c.ClockSkew(ctx))
+	}
+	if err != nil {
+		return nil, "", errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHint("The ID token of the SIOPv2 response could not be verified.").WithDebug(err.Error()))
+	}
+
+	subject, _ := token.Claims["sub"].(string)
+	if !token.Claims.VerifyIssuer(subject, true) {
+		return nil, "", errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The ID token of the SIOPv2 response is not self-issued."))
+	}
+	if !token.Claims.VerifyAudience(h.c.OIDCSIOPClientID(ctx), true) {
+		return nil, "", errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The ID token of the SIOPv2 response was not issued for this client."))
+	}
+	if _, ok := token.Claims["exp"]; !ok {
+		return nil, "", errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`The ID token of the SIOPv2 response does not contain the "exp" claim.`))
+	}
+	if token.Claims["nonce"] != nonce {
+		return nil, "", errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The nonce of the ID token does not match the nonce of the SIOPv2 authorization request."))
+	}
+
+	return holder, subject, nil
+}
+
+// siopSubjectJWK returns the key of the subject of a self-issued ID token, which is either encoded in the subject as
+// a did:jwk, or is the sub_jwk claim whose JWK thumbprint is the subject.
+func siopSubjectJWK(claims jwt.MapClaims) (*jose.JSONWebKey, error) {
+	subject, _ := claims["sub"].(string)
+	if encoded, ok := strings.CutPrefix(subject, "did:jwk:"); ok {
+		raw, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return josex.LoadJSONWebKey(raw, true)
+	}
+
+	raw, err := json.Marshal(claims["sub_jwk"])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	key, err := josex.LoadJSONWebKey(raw, true)
+	if err != nil {
+		return nil, err
+	}
+	if thumbprint, err := jwkThumbprint(key); err != nil {
+		return nil, err
+	} else if thumbprint != subject {
+		return nil, errors.New("the subject is not the JWK thumbprint of the sub_jwk claim")
+	}
+	return key, nil
+}
+
+// verifySIOPVPToken verifies that the vp_token of a SIOPv2 response is an SD-JWT verifiable credential of a trusted
+// issuer which is bound to the key of the wallet by its key binding JWT, and returns the issuer and the claims of the
+// credential.
+func (h *Handler) verifySIOPVPToken(ctx context.Context, vpToken string, holder *jose.JSONWebKey, nonce string) (string, map[string]interface{}, error) {
+	end := strings.LastIndex(vpToken, "~")
+	if end < 0 {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The SIOPv2 response does not present an SD-JWT verifiable credential with a key binding JWT."))
+	}
+	sdJWT, kbJWT := vpToken[:end+1], vpToken[end+1:]
+	issuerJWT, _, _ := strings.Cut(sdJWT, "~")
+
+	var issuer string
+	token, err := jwt.Parse(issuerJWT, func(t *jwt.Token) (interface{}, error) {
+		issuer, _ = t.Claims["iss"].(string)
+		kid, _ := t.Header["kid"].(string)
+		return h.siopIssuerKey(ctx, issuer, kid)
+	})
+	if token != nil {
+		err = x.RevalidateTimeClaimsWithLeeway(err, token.Claims, h.r.Clock().Now(), h.c.ClockSkew(ctx))
+	}
+	if err != nil {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHint("The credential of the SIOPv2 response could not be verified.").WithDebug(err.Error()))
+	}
+
+	cnf, _ := token.Claims["cnf"].(map[string]interface{})
+	raw, err := json.Marshal(cnf["jwk"])
+	if err != nil {
+		return "", nil, errorsx.WithStack(err)
+	}
+	if key, err := josex.LoadJSONWebKey(raw, true); err != nil || !sameJWK(key, holder) {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The credential of the SIOPv2 response is not bound to the key of the ID token."))
+	}
+
+	kb, err := jwt.Parse(kbJWT, func(*jwt.Token) (interface{}, error) {
+		return holder, nil
+	})
+	if kb != nil {
+		err = x.RevalidateTimeClaimsWithLeeway(err, kb.Claims, h.r.Clock().Now(), h.c.ClockSkew(ctx))
+	}
+	if err != nil {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHint("The key binding JWT of the credential could not be verified.").WithDebug(err.Error()))
+	}
+	sdHash := sha256.Sum256([]byte(sdJWT))
+	if kb.Header["typ"] != "kb+jwt" ||
+		!kb.Claims.VerifyAudience(h.c.OIDCSIOPClientID(ctx), true) ||
+		kb.Claims["nonce"] != nonce ||
+		kb.Claims["sd_hash"] != base64.RawURLEncoding.EncodeToString(sdHash[:]) {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The key binding JWT of the credential does not bind it to the SIOPv2 authorization request."))
+	}
+
+	disclosed, err := x.DisclosedClaims(token.Claims, sdJWT)
+	if err != nil {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHint("The disclosures of the credential are invalid.").WithDebug(err.Error()))
+	}
+	claims := make(map[string]interface{}, len(token.Claims)+len(disclosed))
+	for name, value := range token.Claims {
+		switch name {
+		case "_sd", "_sd_alg", "cnf":
+		default:
+			claims[name] = value
+		}
+	}
+	for name, value := range disclosed {
+		claims[name] = value
+	}
+
+	return issuer, claims, nil
+}
+
+// siopIssuerKey returns the key of a trusted credential issuer by its key ID.
+func (h *Handler) siopIssuerKey(ctx context.Context, issuer, kid string) (*jose.JSONWebKey, error) {
+	for _, trusted := range h.c.OIDCSIOPTrustedIssuers(ctx) {
+		if trusted.Issuer != issuer {
+			continue
+		}
+
+		// The keys are fetched again if the key ID is unknown, as the issuer may have rotated its keys.
+		for _, ignoreCache := range []bool{false, true} {
+			keys, err := h.r.GetJWKSFetcherStrategy().Resolve(ctx, trusted.JWKSURI, ignoreCache)
+			if err != nil {
+				return nil, err
+			}
+			if kid == "" && len(keys.Keys) == 1 {
+				return &keys.Keys[0], nil
+			}
+			if found := keys.Key(kid); kid != "" && len(found) > 0 {
+				return &found[0], nil
+			}
+		}
+		return nil, errors.Errorf("the issuer %q has no key with the key ID %q", issuer, kid)
+	}
+	return nil, errors.Errorf("the issuer %q is not trusted", issuer)
+}
+
+func jwkThumbprint(key *jose.JSONWebKey) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+func sameJWK(a, b *jose.JSONWebKey) bool {
+	ta, err := jwkThumbprint(a)
+	if err != nil {
+		return false
+	}
+	tb, err := jwkThumbprint(b)
+	return err == nil && ta == tb
+}
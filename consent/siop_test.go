@@ -0,0 +1,228 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/v2/client"
+	. "github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+)
+
+func TestSIOP(t *testing.T) {
+	ctx := context.Background()
+	clientID := "https://verifier.example.com"
+
+	newKey := func(t *testing.T, kid string) *jose.JSONWebKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return &jose.JSONWebKey{Key: key, KeyID: kid, Algorithm: string(jose.ES256), Use: "sig"}
+	}
+	sign := func(t *testing.T, key *jose.JSONWebKey, typ string, claims map[string]interface{}) string {
+		opts := (&jose.SignerOptions{}).WithType(jose.ContentType(typ))
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, opts)
+		require.NoError(t, err)
+		token, err := josejwt.Signed(signer).Claims(claims).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	thumbprint := func(t *testing.T, key *jose.JSONWebKey) string {
+		raw, err := key.Thumbprint(crypto.SHA256)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	issuerKey := newKey(t, "issuer-key")
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{issuerKey.Public()}}))
+	}))
+	t.Cleanup(jwks.Close)
+
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyOIDCSIOPClientID, clientID)
+	conf.MustSet(ctx, config.KeyOIDCSIOPTrustedIssuers, []map[string]interface{}{
+		{"issuer": "https://issuer.example.com", "jwks_uri": jwks.URL},
+	})
+	conf.MustSet(ctx, config.KeyOIDCSIOPPresentationDefinition, map[string]interface{}{
+		"id": "residency",
+		"input_descriptors": []map[string]interface{}{{
+			"id": "country",
+			"constraints": map[string]interface{}{
+				"fields": []map[string]interface{}{
+					{"path": []string{"$.address.country"}, "filter": map[string]interface{}{"const": "EE"}},
+					{"path": []string{"$.nickname"}, "optional": true},
+				},
+			},
+		}},
+	})
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	h := NewHandler(reg, conf)
+	r := x.NewRouterAdmin(conf.AdminURL)
+	h.SetRoutes(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	newChallenge := func(t *testing.T) string {
+		cl := &client.Client{ID: uuid.Must(uuid.NewV4()).String()}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+		f, err := reg.ConsentManager().CreateLoginRequest(ctx, &flow.LoginRequest{
+			Client:      cl,
+			ID:          uuid.Must(uuid.NewV4()).String(),
+			RequestURL:  "http://192.0.2.1",
+			RequestedAt: time.Now(),
+		})
+		require.NoError(t, err)
+		challenge, err := f.ToLoginChallenge(ctx, reg)
+		require.NoError(t, err)
+		return challenge
+	}
+	getRequest := func(t *testing.T, challenge string) *SIOPAuthorizationRequest {
+		res, err := http.Get(ts.URL + "/admin" + SIOPPath + "?login_challenge=" + challenge)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var request SIOPAuthorizationRequest
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&request))
+		return &request
+	}
+	accept := func(t *testing.T, challenge string, response *SIOPAuthorizationResponse) (int, map[string]interface{}) {
+		body, err := json.Marshal(response)
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPut, ts.URL+"/admin"+SIOPPath+"/accept?login_challenge="+challenge, bytes.NewReader(body))
+		require.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		return res.StatusCode, result
+	}
+
+	type wallet struct {
+		key        *jose.JSONWebKey
+		credential string
+	}
+	newWallet := func(t *testing.T, country string) *wallet {
+		w := &wallet{key: newKey(t, "")}
+		concealed, disclosures, err := x.ConcealClaims(map[string]interface{}{
+			"iss":        "https://issuer.example.com",
+			"iat":        time.Now().Unix(),
+			"exp":        time.Now().Add(time.Hour).Unix(),
+			"vct":        "Residency",
+			"cnf":        map[string]interface{}{"jwk": w.key.Public()},
+			"given_name": "Mari",
+			"address":    map[string]interface{}{"country": country},
+		}, "iss", "iat", "exp", "vct", "cnf")
+		require.NoError(t, err)
+		w.credential = x.SDJWT(sign(t, issuerKey, "vc+sd-jwt", concealed), disclosures)
+		return w
+	}
+	respond := func(t *testing.T, w *wallet, request *SIOPAuthorizationRequest) *SIOPAuthorizationResponse {
+		public := w.key.Public()
+		subject := thumbprint(t, &public)
+		sdHash := sha256.Sum256([]byte(w.credential))
+		return &SIOPAuthorizationResponse{
+			IDToken: sign(t, w.key, "JWT", map[string]interface{}{
+				"iss":     subject,
+				"sub":     subject,
+				"sub_jwk": public,
+				"aud":     request.ClientID,
+				"nonce":   request.Nonce,
+				"iat":     time.Now().Unix(),
+				"exp":     time.Now().Add(time.Minute).Unix(),
+			}),
+			VPToken: w.credential + sign(t, w.key, "kb+jwt", map[string]interface{}{
+				"aud":     request.ClientID,
+				"nonce":   request.Nonce,
+				"iat":     time.Now().Unix(),
+				"sd_hash": base64.RawURLEncoding.EncodeToString(sdHash[:]),
+			}),
+		}
+	}
+
+	t.Run("case=returns the authorization request", func(t *testing.T) {
+		request := getRequest(t, newChallenge(t))
+		assert.Equal(t, clientID, request.ClientID)
+		assert.Equal(t, "vp_token id_token", request.ResponseType)
+		assert.Equal(t, "openid", request.Scope)
+		assert.NotEmpty(t, request.Nonce)
+		assert.Contains(t, string(request.PresentationDefinition), "residency")
+	})
+
+	t.Run("case=accepts the login request", func(t *testing.T) {
+		challenge := newChallenge(t)
+		w := newWallet(t, "EE")
+		status, result := accept(t, challenge, respond(t, w, getRequest(t, challenge)))
+		require.Equal(t, http.StatusOK, status, "%+v", result)
+		redirectTo, err := url.Parse(result["redirect_to"].(string))
+		require.NoError(t, err)
+
+		handled, err := reg.ConsentManager().VerifyAndInvalidateLoginRequest(ctx, redirectTo.Query().Get("login_verifier"))
+		require.NoError(t, err)
+		public := w.key.Public()
+		assert.Equal(t, thumbprint(t, &public), handled.Subject)
+		assert.EqualValues(t, []string{"swk"}, handled.AMR)
+		assert.Equal(t, "EE", gjson.GetBytes(handled.Context, "siop.credential.claims.address.country").String())
+		assert.Equal(t, "Mari", gjson.GetBytes(handled.Context, "siop.credential.claims.given_name").String())
+		assert.Equal(t, "https://issuer.example.com", gjson.GetBytes(handled.Context, "siop.credential.issuer").String())
+	})
+
+	t.Run("case=rejects a response to another login request", func(t *testing.T) {
+		w := newWallet(t, "EE")
+		status, result := accept(t, newChallenge(t), respond(t, w, getRequest(t, newChallenge(t))))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "nonce")
+	})
+
+	t.Run("case=rejects a credential which does not satisfy the presentation definition", func(t *testing.T) {
+		challenge := newChallenge(t)
+		status, result := accept(t, challenge, respond(t, newWallet(t, "FI"), getRequest(t, challenge)))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "presentation definition")
+	})
+
+	t.Run("case=rejects a credential of another wallet", func(t *testing.T) {
+		challenge := newChallenge(t)
+		request := getRequest(t, challenge)
+		response := respond(t, newWallet(t, "EE"), request)
+		response.VPToken = respond(t, newWallet(t, "EE"), request).VPToken
+		status, result := accept(t, challenge, response)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, result["error_description"], "not bound")
+	})
+
+	t.Run("case=rejects a response without a credential", func(t *testing.T) {
+		challenge := newChallenge(t)
+		response := respond(t, newWallet(t, "EE"), getRequest(t, challenge))
+		response.VPToken = ""
+		status, _ := accept(t, challenge, response)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
@@ -6,6 +6,7 @@ package consent
 import (
 	"context"
 	"net/http"
+	"net/url"
 
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/v2/flow"
@@ -24,3 +25,10 @@ type Strategy interface {
 	HandleHeadlessLogout(ctx context.Context, w http.ResponseWriter, r *http.Request, sid string) error
 	ObfuscateSubjectIdentifier(ctx context.Context, cl fosite.Client, subject, forcedIdentifier string) (string, error)
 }
+
+// InteractionRequester is an authorization request which is not started at the OAuth 2.0 authorization endpoint, such
+// as a GNAP grant request. The user agent returns to the interaction URL after login and consent.
+type InteractionRequester interface {
+	fosite.AuthorizeRequester
+	GetInteractionURL() *url.URL
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/ory/hydra/v2/flow"
@@ -28,6 +29,7 @@ import (
 	"github.com/ory/fosite/token/jwt"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/extension"
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/errorsx"
 	"github.com/ory/x/mapx"
@@ -40,7 +42,8 @@ import (
 )
 
 const (
-	CookieAuthenticationSIDName = "sid"
+	CookieAuthenticationSIDName         = "sid"
+	CookieAuthenticationFingerprintName = "fingerprint"
 )
 
 type DefaultStrategy struct {
@@ -136,6 +139,12 @@ func (s *DefaultStrategy) requestAuthentication(ctx context.Context, w http.Resp
 		return err
 	}
 
+	if !s.matchesSessionFingerprint(ctx, r) {
+		s.r.Logger().WithRequest(r).
+			Info("The login session is not remembered because the user agent does not match the fingerprint captured at login.")
+		return s.forwardAuthenticationRequest(ctx, w, r, ar, "", time.Time{}, nil)
+	}
+
 	maxAge := int64(-1)
 	if ma := ar.GetRequestForm().Get("max_age"); len(ma) > 0 {
 		var err error
@@ -145,7 +154,7 @@ func (s *DefaultStrategy) requestAuthentication(ctx context.Context, w http.Resp
 		}
 	}
 
-	if maxAge > -1 && time.Time(session.AuthenticatedAt).UTC().Add(time.Second*time.Duration(maxAge)).Before(time.Now().UTC()) {
+	if maxAge > -1 && time.Time(session.AuthenticatedAt).UTC().Add(time.Second*time.Duration(maxAge)).Before(s.r.Clock().Now().UTC()) {
 		if stringslice.Has(prompt, "none") {
 			return errorsx.WithStack(fosite.ErrLoginRequired.WithHint("Request failed because prompt is set to 'none' and authentication time reached 'max_age'."))
 		}
@@ -216,6 +225,9 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 
 	// Generate the request URL
 	iu := s.c.OAuth2AuthURL(ctx)
+	if ir, ok := ar.(InteractionRequester); ok {
+		iu = ir.GetInteractionURL()
+	}
 	iu.RawQuery = r.URL.RawQuery
 
 	var idTokenHintClaims jwt.MapClaims
@@ -245,8 +257,9 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 		Subject:           subject,
 		Client:            cl,
 		RequestURL:        iu.String(),
+		CorrelationID:     x.CorrelationIDFromContext(ctx),
 		AuthenticatedAt:   sqlxx.NullTime(authenticatedAt),
-		RequestedAt:       time.Now().Truncate(time.Second).UTC(),
+		RequestedAt:       s.r.Clock().Now().Truncate(time.Second).UTC(),
 		SessionID:         sqlxx.NullString(sessionID),
 		OpenIDConnectContext: &flow.OAuth2ConsentRequestOpenIDConnectContext{
 			IDTokenHintClaims: idTokenHintClaims,
@@ -263,6 +276,7 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 	if err != nil {
 		return errorsx.WithStack(err)
 	}
+	f.RequestParameters = sanitizeRequestParameters(ar.GetRequestForm())
 
 	store, err := s.r.CookieStore(ctx)
 	if err != nil {
@@ -270,7 +284,7 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 	}
 
 	clientSpecificCookieNameLoginCSRF := fmt.Sprintf("%s_%s", s.r.Config().CookieNameLoginCSRF(ctx), cl.CookieSuffix())
-	if err := createCsrfSession(w, r, s.r.Config(), store, clientSpecificCookieNameLoginCSRF, csrf, s.c.ConsentRequestMaxAge(ctx)); err != nil {
+	if err := createCsrfSession(w, r, s.r.Config(), store, clientSpecificCookieNameLoginCSRF, csrf, cl.GetLoginChallengeLifespan(s.c.LoginChallengeLifespan(ctx))); err != nil {
 		return errorsx.WithStack(err)
 	}
 
@@ -279,6 +293,15 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 		return err
 	}
 
+	if redirectTo, err := s.handleLoginRequestWithPlugins(r, encodedFlow); err != nil {
+		return err
+	} else if redirectTo != nil {
+		s.r.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).
+			Info("The login request was handled by a plugin, redirecting the user agent back.")
+		http.Redirect(w, r, redirectTo.RedirectTo, http.StatusFound)
+		return errorsx.WithStack(ErrAbortOAuth2Request)
+	}
+
 	var baseURL *url.URL
 	if stringslice.Has(prompt, "registration") {
 		baseURL = s.c.RegistrationURL(ctx)
@@ -286,7 +309,9 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(ctx context.Context, w ht
 		baseURL = s.c.LoginURL(ctx)
 	}
 
-	http.Redirect(w, r, urlx.SetQuery(baseURL, url.Values{"login_challenge": {encodedFlow}}).String(), http.StatusFound)
+	s.r.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).
+		Info("Redirecting the user agent to the login endpoint.")
+	http.Redirect(w, r, urlx.SetQuery(baseURL, withCorrelationID(url.Values{"login_challenge": {encodedFlow}}, f.CorrelationID)).String(), http.StatusFound)
 
 	// generate the verifier
 	return errorsx.WithStack(ErrAbortOAuth2Request)
@@ -347,6 +372,8 @@ func (s *DefaultStrategy) verifyAuthentication(
 	if err != nil {
 		return nil, errorsx.WithStack(fosite.ErrAccessDenied.WithHint("The login verifier is invalid."))
 	}
+	s.r.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).
+		Info("The user agent returned from the login endpoint.")
 
 	session, err := s.r.ConsentManager().VerifyAndInvalidateLoginRequest(ctx, verifier)
 	if errors.Is(err, sqlcon.ErrNoRows) {
@@ -360,7 +387,7 @@ func (s *DefaultStrategy) verifyAuthentication(
 		return nil, errorsx.WithStack(session.Error.ToRFCError())
 	}
 
-	if session.RequestedAt.Add(s.c.ConsentRequestMaxAge(ctx)).Before(time.Now()) {
+	if session.RequestedAt.Add(session.LoginRequest.Client.GetLoginChallengeLifespan(s.c.LoginChallengeLifespan(ctx))).Before(s.r.Clock().Now()) {
 		return nil, errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The login request has expired. Please try again."))
 	}
 
@@ -480,6 +507,7 @@ func (s *DefaultStrategy) verifyAuthentication(
 	// Not a skipped login and the user asked to remember its session, store a cookie
 	cookie, _ := store.Get(r, s.c.SessionCookieName(ctx))
 	cookie.Values[CookieAuthenticationSIDName] = sessionID
+	cookie.Values[CookieAuthenticationFingerprintName] = s.sessionFingerprint(ctx, r)
 	if session.RememberFor >= 0 {
 		cookie.Options.MaxAge = session.RememberFor
 	}
@@ -629,13 +657,24 @@ func (s *DefaultStrategy) forwardConsentRequest(
 	}
 
 	clientSpecificCookieNameConsentCSRF := fmt.Sprintf("%s_%s", s.r.Config().CookieNameConsentCSRF(ctx), cl.CookieSuffix())
-	if err := createCsrfSession(w, r, s.r.Config(), store, clientSpecificCookieNameConsentCSRF, csrf, s.c.ConsentRequestMaxAge(ctx)); err != nil {
+	if err := createCsrfSession(w, r, s.r.Config(), store, clientSpecificCookieNameConsentCSRF, csrf, cl.GetConsentChallengeLifespan(s.c.ConsentChallengeLifespan(ctx))); err != nil {
 		return errorsx.WithStack(err)
 	}
 
+	if redirectTo, err := s.handleConsentRequestWithPlugins(r, consentChallenge); err != nil {
+		return err
+	} else if redirectTo != nil {
+		s.r.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).
+			Info("The consent request was handled by a plugin, redirecting the user agent back.")
+		http.Redirect(w, r, redirectTo.RedirectTo, http.StatusFound)
+		return errorsx.WithStack(ErrAbortOAuth2Request)
+	}
+
+	s.r.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).
+		Info("Redirecting the user agent to the consent endpoint.")
 	http.Redirect(
 		w, r,
-		urlx.SetQuery(s.c.ConsentURL(ctx), url.Values{"consent_challenge": {consentChallenge}}).String(),
+		urlx.SetQuery(s.c.ConsentURL(ctx), withCorrelationID(url.Values{"consent_challenge": {consentChallenge}}, f.CorrelationID)).String(),
 		http.StatusFound,
 	)
 
@@ -652,6 +691,8 @@ func (s *DefaultStrategy) verifyConsent(ctx context.Context, _ http.ResponseWrit
 	if err != nil {
 		return nil, nil, errorsx.WithStack(fosite.ErrAccessDenied.WithHint("The consent verifier has already been used, has not been granted, or is invalid."))
 	}
+	s.r.Logger().WithRequest(r).WithField("correlation_id", f.CorrelationID).
+		Info("The user agent returned from the consent endpoint.")
 	if f.Client.GetID() != r.URL.Query().Get("client_id") {
 		return nil, nil, errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The flow client id does not match the authorize request client id."))
 	}
@@ -665,7 +706,7 @@ func (s *DefaultStrategy) verifyConsent(ctx context.Context, _ http.ResponseWrit
 		return nil, nil, err
 	}
 
-	if session.RequestedAt.Add(s.c.ConsentRequestMaxAge(ctx)).Before(time.Now()) {
+	if session.RequestedAt.Add(f.Client.GetConsentChallengeLifespan(s.c.ConsentChallengeLifespan(ctx))).Before(s.r.Clock().Now()) {
 		return nil, nil, errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The consent request has expired, please try again."))
 	}
 
@@ -699,6 +740,11 @@ func (s *DefaultStrategy) verifyConsent(ctx context.Context, _ http.ResponseWrit
 	if session.Session.IDToken == nil {
 		session.Session.IDToken = map[string]interface{}{}
 	}
+	s.applyClaimsTemplates(ctx, config.KeyOIDCIDTokenClaimsTemplates, idTokenRegisteredClaims, newConsentClaimsTemplateData(session), session.Session.IDToken)
+
+	if err := s.r.ConsentManager().RecordGrantHistory(ctx, session.ConsentRequest.Subject, session.ConsentRequest.Client.GetID(), session.GrantedScope, session.GrantedAudience, s.r.Clock().Now()); err != nil {
+		return nil, nil, err
+	}
 
 	session.AuthenticatedAt = session.ConsentRequest.AuthenticatedAt
 	return session, f, nil
@@ -733,7 +779,7 @@ func (s *DefaultStrategy) executeBackChannelLogout(r *http.Request, subject, sid
 		return err
 	}
 
-	openIDKeyID, err := s.r.OpenIDJWTStrategy().GetPublicKeyID(ctx)
+	logoutKeyID, err := s.r.LogoutTokenJWTStrategy().GetPublicKeyID(ctx)
 	if err != nil {
 		return err
 	}
@@ -744,6 +790,10 @@ func (s *DefaultStrategy) executeBackChannelLogout(r *http.Request, subject, sid
 		clientID string
 	}
 
+	logoutTokenHeaders := &jwt.Headers{
+		Extra: map[string]interface{}{"kid": logoutKeyID},
+	}
+
 	var tasks []task
 	for _, c := range clients {
 		// Getting the forced obfuscated login session is tricky because the user id could be obfuscated with a new
@@ -753,20 +803,31 @@ func (s *DefaultStrategy) executeBackChannelLogout(r *http.Request, subject, sid
 		// s.r.ConsentManager().GetForcedObfuscatedLoginSession(context.Background(), subject, <missing>)
 		// sub := s.obfuscateSubjectIdentifier(c, subject, )
 
-		t, _, err := s.r.OpenIDJWTStrategy().Generate(ctx, jwt.MapClaims{
+		claims := jwt.MapClaims{
 			"iss":    s.c.IssuerURL(ctx).String(),
 			"aud":    []string{c.ID},
-			"iat":    time.Now().UTC().Unix(),
+			"iat":    s.r.Clock().Now().UTC().Unix(),
 			"jti":    uuid.New(),
 			"events": map[string]struct{}{"http://schemas.openid.net/event/backchannel-logout": {}},
 			"sid":    sid,
-		}, &jwt.Headers{
-			Extra: map[string]interface{}{"kid": openIDKeyID},
-		})
+		}
+		s.applyClaimsTemplates(ctx, config.KeyOIDCLogoutTokenClaimsTemplates, logoutTokenRegisteredClaims, newClaimsTemplateData(subject, sid, &c), claims)
+
+		t, _, err := s.r.LogoutTokenJWTStrategy().Generate(ctx, claims, logoutTokenHeaders)
 		if err != nil {
 			return err
 		}
 
+		if c.BackChannelLogoutTokenEncryptedResponseAlg != "" {
+			if t, err = s.encryptLogoutToken(ctx, &c, t); err != nil {
+				// The client must not receive a plaintext logout token if it requires encryption.
+				s.r.Logger().WithRequest(r).WithError(err).
+					WithField("client_id", c.GetID()).
+					Error("Unable to encrypt the OpenID Connect Back-Channel Logout Token")
+				continue
+			}
+		}
+
 		tasks = append(tasks, task{url: c.BackChannelLogoutURI, clientID: c.GetID(), token: t})
 	}
 
@@ -861,6 +922,7 @@ func (s *DefaultStrategy) issueLogoutVerifier(ctx context.Context, w http.Respon
 			SessionID:   session.ID,
 			Verifier:    uuid.New(),
 			RPInitiated: false,
+			RequestedAt: sqlxx.NullTime(s.r.Clock().Now().Truncate(time.Second).UTC()),
 
 			// PostLogoutRedirectURI is set to the value from config.Provider().LogoutRedirectURL()
 			PostLogoutRedirectURI: redir,
@@ -891,8 +953,8 @@ func (s *DefaultStrategy) issueLogoutVerifier(ctx context.Context, w http.Respon
 		)
 	}
 
-	now := time.Now().UTC().Unix()
-	if !claims.VerifyIssuedAt(now, true) {
+	now := s.r.Clock().Now().UTC().Unix()
+	if !claims.VerifyIssuedAt(now+int64(s.c.ClockSkew(ctx).Seconds()), true) {
 		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.
 			WithHintf(
 				`Logout failed because iat claim value '%.0f' from query parameter id_token_hint is before now ('%d').`,
@@ -937,22 +999,9 @@ func (s *DefaultStrategy) issueLogoutVerifier(ctx context.Context, w http.Respon
 	}
 
 	if len(requestedRedir) > 0 {
-		var f *url.URL
-		for _, w := range cl.PostLogoutRedirectURIs {
-			if w == requestedRedir {
-				u, err := url.Parse(w)
-				if err != nil {
-					return nil, errorsx.WithStack(fosite.ErrServerError.WithHintf("Unable to parse post_logout_redirect_uri '%s'.", w).WithDebug(err.Error()))
-				}
-
-				f = u
-			}
-		}
-
-		if f == nil {
-			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.
-				WithHint("Logout failed because query parameter post_logout_redirect_uri is not a whitelisted as a post_logout_redirect_uri for the client."),
-			)
+		f, err := s.matchPostLogoutRedirectURI(ctx, cl, requestedRedir)
+		if err != nil {
+			return nil, err
 		}
 
 		params := url.Values{}
@@ -984,6 +1033,7 @@ func (s *DefaultStrategy) issueLogoutVerifier(ctx context.Context, w http.Respon
 		Verifier:    uuid.New(),
 		Client:      cl,
 		RPInitiated: true,
+		RequestedAt: sqlxx.NullTime(s.r.Clock().Now().Truncate(time.Second).UTC()),
 
 		// PostLogoutRedirectURI is set to the value from config.Provider().LogoutRedirectURL()
 		PostLogoutRedirectURI: redir,
@@ -1001,6 +1051,11 @@ func (s *DefaultStrategy) performBackChannelLogoutAndDeleteSession(r *http.Reque
 		return err
 	}
 
+	// The tokens are found by the login session of their flow, which is unset when the session is deleted.
+	if err := s.r.ConsentManager().RevokeLoginSessionTokens(ctx, subject, sid); err != nil {
+		return err
+	}
+
 	// We delete the session after back channel log out has worked as the session is otherwise removed
 	// from the store which will break the query for finding all the channels.
 	//
@@ -1127,6 +1182,7 @@ func (s *DefaultStrategy) HandleOAuth2AuthorizationRequest(
 ) (_ *flow.AcceptOAuth2ConsentRequest, _ *flow.Flow, err error) {
 	ctx, span := trace.SpanFromContext(ctx).TracerProvider().Tracer("").Start(ctx, "DefaultStrategy.HandleOAuth2AuthorizationRequest")
 	defer otelx.End(span, &err)
+	span.SetAttributes(attribute.String("hydra.correlation_id", x.CorrelationIDFromContext(ctx)))
 
 	loginVerifier := strings.TrimSpace(req.GetRequestForm().Get("login_verifier"))
 	consentVerifier := strings.TrimSpace(req.GetRequestForm().Get("consent_verifier"))
@@ -1152,7 +1208,17 @@ func (s *DefaultStrategy) HandleOAuth2AuthorizationRequest(
 }
 
 func (s *DefaultStrategy) ObfuscateSubjectIdentifier(ctx context.Context, cl fosite.Client, subject, forcedIdentifier string) (string, error) {
-	if c, ok := cl.(*client.Client); ok && c.SubjectType == "pairwise" {
+	if c, ok := cl.(*client.Client); ok {
+		for _, m := range extension.Hooks[extension.SubjectMapper](s.r.Plugins()) {
+			mapped, err := m.MapSubject(ctx, c, subject)
+			if err != nil {
+				return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebugf("Plugin %s failed to map the subject: %s", m.Name(), err))
+			}
+			subject = mapped
+		}
+	}
+
+	if c, ok := cl.(*client.Client); ok && c.SubjectType != "" && c.SubjectType != "public" {
 		algorithm, ok := s.r.SubjectIdentifierAlgorithm(ctx)[c.SubjectType]
 		if !ok {
 			return "", errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf(`Subject Identifier Algorithm '%s' was requested by OAuth 2.0 Client '%s' but is not configured.`, c.SubjectType, c.GetID()))
@@ -1162,6 +1228,9 @@ func (s *DefaultStrategy) ObfuscateSubjectIdentifier(ctx context.Context, cl fos
 			return forcedIdentifier, nil
 		}
 
+		if plugin, ok := algorithm.(*SubjectIdentifierAlgorithmPlugin); ok {
+			return plugin.ObfuscateWithContext(ctx, subject, c)
+		}
 		return algorithm.Obfuscate(subject, c)
 	} else if !ok {
 		return "", errors.New("Unable to type assert OAuth 2.0 Client to *client.Client")
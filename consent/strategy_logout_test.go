@@ -6,6 +6,8 @@ package consent_test
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -16,12 +18,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-jose/go-jose/v3"
+
 	"github.com/ory/hydra/v2/internal/kratos"
 	"github.com/ory/x/pointerx"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
+	"golang.org/x/oauth2"
 
 	jwtgo "github.com/ory/fosite/token/jwt"
 
@@ -30,6 +35,7 @@ import (
 	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/hydra/v2/internal"
 	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/contextx"
 	"github.com/ory/x/ioutilx"
 )
@@ -299,6 +305,63 @@ func TestLogoutFlows(t *testing.T) {
 		assert.Equal(t, 200, res.StatusCode)
 	})
 
+	t.Run("case=should expire the logout challenge after its lifespan", func(t *testing.T) {
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyLogoutChallengeLifespan, time.Hour) })
+
+		setupLogoutHandler := func(t *testing.T, wg *sync.WaitGroup, cb func(t *testing.T, code int, body gjson.Result) bool) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer wg.Done()
+
+				res, err := adminTS.Client().Get(adminTS.URL + "/admin/oauth2/auth/requests/logout?" + url.Values{"logout_challenge": {r.URL.Query().Get("logout_challenge")}}.Encode())
+				require.NoError(t, err)
+				defer res.Body.Close()
+				if !cb(t, res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))) {
+					w.WriteHeader(http.StatusNotImplemented)
+					return
+				}
+
+				v, _, err := adminApi.OAuth2Api.AcceptOAuth2LogoutRequest(ctx).LogoutChallenge(r.URL.Query().Get("logout_challenge")).Execute()
+				require.NoError(t, err)
+				http.Redirect(w, r, v.RedirectTo, http.StatusFound)
+			}))
+			t.Cleanup(server.Close)
+			reg.Config().MustSet(ctx, config.KeyLogoutURL, server.URL)
+		}
+
+		t.Run("case=surfaces the expiry", func(t *testing.T) {
+			reg.Config().MustSet(ctx, config.KeyLogoutChallengeLifespan, "15m")
+			acceptLoginAs(t, subject)
+			browser := createBrowserWithSession(t, createSampleClient(t))
+
+			wg := newWg(1)
+			setupLogoutHandler(t, wg, func(t *testing.T, code int, body gjson.Result) bool {
+				require.Equal(t, http.StatusOK, code, "%s", body)
+				assert.WithinDuration(t, time.Now().Add(15*time.Minute), body.Get("expires_at").Time(), 5*time.Second, "%s", body)
+				return true
+			})
+
+			logoutAndExpectPostLogoutPage(t, browser, http.MethodGet, url.Values{}, defaultRedirectedMessage)
+			wg.Wait()
+		})
+
+		t.Run("case=rejects an expired logout challenge", func(t *testing.T) {
+			reg.Config().MustSet(ctx, config.KeyLogoutChallengeLifespan, "1ns")
+			acceptLoginAs(t, subject)
+			browser := createBrowserWithSession(t, createSampleClient(t))
+
+			wg := newWg(1)
+			setupLogoutHandler(t, wg, func(t *testing.T, code int, body gjson.Result) bool {
+				assert.Equal(t, http.StatusUnauthorized, code, "%s", body)
+				assert.Contains(t, body.Get("error_description").String(), "The logout request has expired", "%s", body)
+				return false
+			})
+
+			_, res := makeLogoutRequest(t, browser, http.MethodGet, url.Values{})
+			assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+			wg.Wait()
+		})
+	})
+
 	t.Run("case=should handle an invalid logout challenge", func(t *testing.T) {
 		_, res, err := adminApi.OAuth2Api.GetOAuth2LogoutRequest(ctx).LogoutChallenge("some-invalid-challenge").Execute()
 		assert.Error(t, err)
@@ -504,6 +567,68 @@ func TestLogoutFlows(t *testing.T) {
 		}, defaultRedirectedMessage+"1234logged-out/custom")
 	})
 
+	t.Run("case=should apply the post logout redirect uri policy", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyPostLogoutRedirectSubdomainWildcards, true)
+		reg.Config().MustSet(ctx, config.KeyPostLogoutRedirectURIsAllowList, []string{"https://www.example.com/bye"})
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyPostLogoutRedirectSubdomainWildcards, false)
+			reg.Config().MustSet(ctx, config.KeyPostLogoutRedirectSameOrigin, false)
+			reg.Config().MustSet(ctx, config.KeyPostLogoutRedirectURIsAllowList, nil)
+		})
+
+		c := createClient(t, reg, &client.Client{
+			RedirectURIs:           []string{"https://app.example.org/callback"},
+			PostLogoutRedirectURIs: []string{"https://*.example.org/logout"},
+		})
+		noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+		for _, tc := range []struct {
+			d          string
+			uri        string
+			wildcards  bool
+			sameOrigin bool
+			allowed    bool
+		}{
+			{d: "subdomain wildcard", uri: "https://eu.example.org/logout", wildcards: true, allowed: true},
+			{d: "subdomain wildcard with nested subdomain", uri: "https://a.eu.example.org/logout", wildcards: true, allowed: true},
+			{d: "subdomain wildcard does not match the domain itself", uri: "https://example.org/logout", wildcards: true},
+			{d: "subdomain wildcard requires the same path", uri: "https://eu.example.org/other", wildcards: true},
+			{d: "subdomain wildcard requires the same scheme", uri: "http://eu.example.org/logout", wildcards: true},
+			{d: "subdomain wildcard when disabled", uri: "https://eu.example.org/logout"},
+			{d: "global allow-list", uri: "https://www.example.com/bye", allowed: true},
+			{d: "same origin when disabled", uri: "https://app.example.org/anywhere"},
+			{d: "same origin", uri: "https://app.example.org/anywhere", sameOrigin: true, allowed: true},
+			{d: "same origin requires the same host", uri: "https://evil.example.com/anywhere", sameOrigin: true},
+			{d: "same origin rejects fragments", uri: "https://app.example.org/anywhere#fragment", sameOrigin: true},
+		} {
+			t.Run("case="+tc.d, func(t *testing.T) {
+				reg.Config().MustSet(ctx, config.KeyPostLogoutRedirectSubdomainWildcards, tc.wildcards)
+				reg.Config().MustSet(ctx, config.KeyPostLogoutRedirectSameOrigin, tc.sameOrigin)
+
+				res, err := noRedirects.Get(publicTS.URL + "/oauth2/sessions/logout?" + url.Values{
+					"post_logout_redirect_uri": {tc.uri},
+					"id_token_hint": {genIDToken(t, reg, jwtgo.MapClaims{
+						"aud": c.GetID(),
+						"iss": reg.Config().IssuerURL(ctx).String(),
+						"sub": subject,
+						"sid": "i-do-not-exist",
+						"exp": time.Now().Add(time.Hour).Unix(),
+						"iat": time.Now().Add(-time.Hour).Unix(),
+					})},
+				}.Encode())
+				require.NoError(t, err)
+				defer res.Body.Close()
+
+				require.Equal(t, http.StatusFound, res.StatusCode)
+				if tc.allowed {
+					assert.Equal(t, tc.uri, res.Header.Get("Location"))
+				} else {
+					assert.Contains(t, res.Header.Get("Location"), "error=invalid_request")
+				}
+			})
+		}
+	})
+
 	t.Run("case=should not append a state param if no state was passed to logout server", func(t *testing.T) {
 		c := createSampleClient(t)
 		sid := make(chan string)
@@ -599,6 +724,115 @@ func TestLogoutFlows(t *testing.T) {
 		assert.Equal(t, fakeKratos.LastDisabledSession, kratos.FakeSessionID)
 	})
 
+	t.Run("case=should compose logout token claims from the claims templates", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOIDCLogoutTokenClaimsTemplates, map[string]string{
+			"client_name": "{{ .Client.Name }}",
+			"session":     "{{ .SessionID }}",
+			"sid":         "overridden",
+			"scopes":      "{{ .Consent.GrantedScope }}",
+		})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOIDCLogoutTokenClaimsTemplates, nil) })
+
+		fakeKratos.Reset()
+		numSidConsumers := 2
+		sid := make(chan string, numSidConsumers)
+		acceptLoginAsAndWatchSidForConsumers(t, subject, sid, true, numSidConsumers)
+
+		backChannelWG := newWg(1)
+		c := createClientWithBackchannelLogout(t, backChannelWG, func(t *testing.T, logoutToken gjson.Result) {
+			expected := <-sid
+			assert.EqualValues(t, expected, logoutToken.Get("sid").String(), logoutToken.Raw)
+			assert.EqualValues(t, expected, logoutToken.Get("session").String(), logoutToken.Raw)
+			assert.False(t, logoutToken.Get("client_name").Exists(), "the client has no name: %s", logoutToken.Raw)
+			assert.False(t, logoutToken.Get("scopes").Exists(), "logout tokens have no consent: %s", logoutToken.Raw)
+		})
+
+		logoutViaHeadlessAndExpectNoContent(t, createBrowserWithSession(t, c), url.Values{"sid": {<-sid}})
+		backChannelWG.Wait()
+	})
+
+	t.Run("case=should encrypt the logout token to the key of the client", func(t *testing.T) {
+		fakeKratos.Reset()
+		numSidConsumers := 2
+		sid := make(chan string, numSidConsumers)
+		acceptLoginAsAndWatchSidForConsumers(t, subject, sid, true, numSidConsumers)
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		backChannelWG := newWg(1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer backChannelWG.Done()
+
+			encrypted, err := jose.ParseEncrypted(r.PostFormValue("logout_token"))
+			require.NoError(t, err)
+			assert.EqualValues(t, "RSA-OAEP-256", encrypted.Header.Algorithm)
+			assert.EqualValues(t, "enc-key", encrypted.Header.KeyID)
+			assert.EqualValues(t, "JWT", encrypted.Header.ExtraHeaders[jose.HeaderContentType])
+
+			lt, err := encrypted.Decrypt(key)
+			require.NoError(t, err)
+			token, err := reg.OpenIDJWTStrategy().Decode(r.Context(), string(lt))
+			require.NoError(t, err)
+			assert.EqualValues(t, <-sid, token.Claims["sid"])
+		}))
+		t.Cleanup(server.Close)
+
+		c := createClient(t, reg, &client.Client{
+			BackChannelLogoutURI:                       server.URL,
+			BackChannelLogoutTokenEncryptedResponseAlg: "RSA-OAEP-256",
+			JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: &key.PublicKey, KeyID: "sig-key", Use: "sig"},
+				{Key: &key.PublicKey, KeyID: "enc-key", Use: "enc"},
+			}}},
+			RedirectURIs: []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+		})
+
+		logoutViaHeadlessAndExpectNoContent(t, createBrowserWithSession(t, c), url.Values{"sid": {<-sid}})
+		backChannelWG.Wait()
+	})
+
+	t.Run("case=should revoke the tokens of clients which revoke tokens on logout", func(t *testing.T) {
+		fakeKratos.Reset()
+		sid := make(chan string, 2)
+		acceptLoginAsAndWatchSidForConsumers(t, subject, sid, true, 1)
+
+		browser := testhelpers.NewEmptyJarClient(t)
+		issueToken := func(t *testing.T, c *client.Client) string {
+			_, res := makeOAuth2Request(t, reg, browser, c, url.Values{"redirect_uri": {c.RedirectURIs[0]}})
+			code := res.Request.URL.Query().Get("code")
+			require.NotEmpty(t, code, "%s", res.Request.URL)
+			token, err := (&oauth2.Config{
+				ClientID:     c.GetID(),
+				ClientSecret: c.Secret,
+				Endpoint:     oauth2.Endpoint{TokenURL: publicTS.URL + "/oauth2/token", AuthStyle: oauth2.AuthStyleInHeader},
+				RedirectURL:  c.RedirectURIs[0],
+			}).Exchange(ctx, code)
+			require.NoError(t, err)
+			return token.AccessToken
+		}
+		isActive := func(t *testing.T, token string) bool {
+			res, _, err := adminApi.OAuth2Api.IntrospectOAuth2Token(ctx).Token(token).Execute()
+			require.NoError(t, err)
+			return res.Active
+		}
+
+		revoking := createClient(t, reg, &client.Client{
+			RedirectURIs:         []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+			RevokeTokensOnLogout: true,
+		})
+		revokingToken := issueToken(t, revoking)
+		otherToken := issueToken(t, createSampleClient(t))
+		require.True(t, isActive(t, revokingToken))
+
+		loginSessionID := <-sid
+		require.Equal(t, loginSessionID, <-sid)
+		logoutViaHeadlessAndExpectNoContent(t, browser, url.Values{"sid": {loginSessionID}})
+
+		assert.False(t, isActive(t, revokingToken))
+		assert.True(t, isActive(t, otherToken))
+	})
+
 	t.Run("case=should logout in headless flow with non-existing sid", func(t *testing.T) {
 		fakeKratos.Reset()
 		logoutViaHeadlessAndExpectNoContent(t, browserWithoutSession, url.Values{"sid": {"non-existing-sid"}})
@@ -31,6 +31,7 @@ import (
 	"github.com/ory/x/contextx"
 
 	"github.com/ory/fosite"
+	"github.com/ory/x/ioutilx"
 	"github.com/ory/x/urlx"
 	"github.com/ory/x/uuidx"
 
@@ -38,6 +39,7 @@ import (
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/x"
 )
 
 func TestStrategyLoginConsentNext(t *testing.T) {
@@ -153,6 +155,53 @@ func TestStrategyLoginConsentNext(t *testing.T) {
 		assert.NotEmpty(t, res.Request.URL.Query().Get("consent_challenge"), "%s", res.Request.URL)
 	})
 
+	t.Run("case=should propagate the correlation ID to the login and consent endpoints", func(t *testing.T) {
+		var loginCorrelationID string
+		login := acceptLoginHandler(t, "aeneas-rekkas", nil)
+		testhelpers.NewLoginConsentUI(t, reg.Config(), func(w http.ResponseWriter, r *http.Request) {
+			loginCorrelationID = r.URL.Query().Get("correlation_id")
+			login(w, r)
+		}, testhelpers.HTTPServerNotImplementedHandler)
+		c := createClientWithRedir(t, testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNoExpectedCallHandler(t)))
+
+		_, res := makeOAuth2Request(t, reg, nil, c, url.Values{"correlation_id": {"correlation-id"}})
+		assert.EqualValues(t, http.StatusNotImplemented, res.StatusCode)
+		assert.Equal(t, "correlation-id", loginCorrelationID)
+		assert.Equal(t, "correlation-id", res.Request.URL.Query().Get("correlation_id"), "%s", res.Request.URL)
+	})
+
+	t.Run("case=should expose the sanitized authorization request parameters on the consent request", func(t *testing.T) {
+		var params gjson.Result
+		consent := acceptConsentHandler(t, nil)
+		testhelpers.NewLoginConsentUI(t, reg.Config(),
+			acceptLoginHandler(t, "aeneas-rekkas", nil),
+			func(w http.ResponseWriter, r *http.Request) {
+				res, err := adminTS.Client().Get(adminTS.URL + "/admin/oauth2/auth/requests/consent?" + url.Values{"consent_challenge": {r.URL.Query().Get("consent_challenge")}}.Encode())
+				require.NoError(t, err)
+				defer res.Body.Close()
+				body := ioutilx.MustReadAll(res.Body)
+				require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+				params = gjson.GetBytes(body, "request_parameters")
+				consent(w, r)
+			})
+		c := createDefaultClient(t)
+
+		makeRequestAndExpectCode(t, nil, c, url.Values{"scope": {"openid"}, "ui_locales": {"de"}, "client_secret": {"secret"}})
+		assert.Equal(t, "openid", params.Get("scope.0").String(), params.Raw)
+		assert.Equal(t, "de", params.Get("ui_locales.0").String(), params.Raw)
+		assert.Equal(t, c.GetID(), params.Get("client_id.0").String(), params.Raw)
+		assert.False(t, params.Get("client_secret").Exists(), params.Raw)
+	})
+
+	t.Run("case=should add the correlation ID to the error page", func(t *testing.T) {
+		testhelpers.NewLoginConsentUI(t, reg.Config(), testhelpers.HTTPServerNoExpectedCallHandler(t), testhelpers.HTTPServerNoExpectedCallHandler(t))
+		c := createDefaultClient(t)
+
+		_, res := makeOAuth2Request(t, reg, nil, c, url.Values{"correlation_id": {"correlation-id"}, "redirect_uri": {"https://not-registered/"}})
+		assert.Equal(t, "invalid_request", res.Request.URL.Query().Get("error"), "%s", res.Request.URL)
+		assert.Equal(t, "correlation-id", res.Request.URL.Query().Get("correlation_id"), "%s", res.Request.URL)
+	})
+
 	t.Run("case=should fail because the request was redirected but the login endpoint rejected the request", func(t *testing.T) {
 		testhelpers.NewLoginConsentUI(t, reg.Config(), func(w http.ResponseWriter, r *http.Request) {
 			vr, _, err := adminClient.OAuth2Api.RejectOAuth2LoginRequest(context.Background()).
@@ -279,6 +328,151 @@ func TestStrategyLoginConsentNext(t *testing.T) {
 		makeRequestAndExpectCode(t, nil, c, url.Values{})
 	})
 
+	t.Run("case=should record the grant history which outlives the consent session", func(t *testing.T) {
+		c := createDefaultClient(t)
+		subject := uuid.New()
+		testhelpers.NewLoginConsentUI(t, reg.Config(),
+			acceptLoginHandler(t, subject, nil),
+			acceptConsentHandler(t, &hydra.AcceptOAuth2ConsentRequest{
+				GrantScope:               []string{"openid"},
+				GrantAccessTokenAudience: []string{"https://api.example.org"},
+			}))
+
+		makeRequestAndExpectCode(t, nil, c, url.Values{"scope": {"openid"}})
+		makeRequestAndExpectCode(t, nil, c, url.Values{"scope": {"openid"}})
+
+		_, err := adminClient.OAuth2Api.RevokeOAuth2ConsentSessions(ctx).Subject(subject).All(true).Execute()
+		require.NoError(t, err)
+
+		res, err := adminTS.Client().Get(adminTS.URL + "/admin/oauth2/auth/sessions/consent/history?" + url.Values{"subject": {subject}, "client": {c.GetID()}}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body := ioutilx.MustReadAll(res.Body)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+
+		entries := gjson.ParseBytes(body).Array()
+		require.Len(t, entries, 2, "%s", body)
+		for _, e := range entries {
+			assert.Equal(t, subject, e.Get("subject").String())
+			assert.Equal(t, c.GetID(), e.Get("client_id").String())
+			assert.EqualValues(t, 2, e.Get("grant_count").Int())
+		}
+		assert.ElementsMatch(t, []string{"scope=openid", "audience=https://api.example.org"}, []string{
+			entries[0].Get("type").String() + "=" + entries[0].Get("value").String(),
+			entries[1].Get("type").String() + "=" + entries[1].Get("value").String(),
+		})
+	})
+
+	t.Run("case=should compose id token claims from the claims templates", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOIDCIDTokenClaimsTemplates, map[string]string{
+			"department": "{{ .Client.Metadata.department }}",
+			"tenant":     "{{ .Consent.Context.tenant }}",
+			"scopes":     `{{ join .Consent.GrantedScope " " }}`,
+			"bar":        "overridden",
+			"sub":        "overridden",
+			"missing":    "{{ .Client.Metadata.missing }}",
+		})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOIDCIDTokenClaimsTemplates, nil) })
+
+		c := createClient(t, reg, &client.Client{
+			RedirectURIs: []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+			Metadata:     []byte(`{"department":"engineering"}`),
+		})
+		testhelpers.NewLoginConsentUI(t, reg.Config(),
+			acceptLoginHandler(t, "aeneas-rekkas", &hydra.AcceptOAuth2LoginRequest{
+				Context: map[string]interface{}{"tenant": "acme"},
+			}),
+			acceptConsentHandler(t, &hydra.AcceptOAuth2ConsentRequest{
+				GrantScope: []string{"openid", "offline"},
+				Session: &hydra.AcceptOAuth2ConsentRequestSession{
+					IdToken: map[string]interface{}{"bar": "baz"},
+				},
+			}))
+
+		code := makeRequestAndExpectCode(t, nil, c, url.Values{"redirect_uri": {c.RedirectURIs[0]}, "scope": {"openid offline"}})
+		token, err := oauth2Config(t, c).Exchange(ctx, code)
+		require.NoError(t, err)
+
+		idClaims := testhelpers.DecodeIDToken(t, token)
+		assert.Equal(t, "engineering", idClaims.Get("department").String(), "%s", idClaims.Raw)
+		assert.Equal(t, "acme", idClaims.Get("tenant").String(), "%s", idClaims.Raw)
+		assert.Equal(t, "openid offline", idClaims.Get("scopes").String(), "%s", idClaims.Raw)
+		assert.Equal(t, "baz", idClaims.Get("bar").String(), "the consent app takes precedence: %s", idClaims.Raw)
+		assert.Equal(t, "aeneas-rekkas", idClaims.Get("sub").String(), "registered claims take precedence: %s", idClaims.Raw)
+		assert.False(t, idClaims.Get("missing").Exists(), "%s", idClaims.Raw)
+	})
+
+	t.Run("case=should expire the challenges and authorization codes after their lifespans", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyLoginChallengeLifespan, "10m")
+		reg.Config().MustSet(ctx, config.KeyConsentChallengeLifespan, "20m")
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyLoginChallengeLifespan, time.Hour)
+			reg.Config().MustSet(ctx, config.KeyConsentChallengeLifespan, time.Hour)
+		})
+
+		getRequest := func(t *testing.T, kind, challenge string) (int, gjson.Result) {
+			res, err := adminTS.Client().Get(adminTS.URL + "/admin/oauth2/auth/requests/" + kind + "?" + url.Values{kind + "_challenge": {challenge}}.Encode())
+			require.NoError(t, err)
+			defer res.Body.Close()
+			return res.StatusCode, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+		}
+
+		t.Run("case=surfaces the expiry of the challenges", func(t *testing.T) {
+			c := createClient(t, reg, &client.Client{
+				RedirectURIs: []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+				Lifespans:    client.Lifespans{ConsentChallengeLifespan: x.NullDuration{Duration: 5 * time.Minute, Valid: true}},
+			})
+
+			login := acceptLoginHandler(t, "aeneas-rekkas", nil)
+			consent := acceptConsentHandler(t, nil)
+			testhelpers.NewLoginConsentUI(t, reg.Config(), func(w http.ResponseWriter, r *http.Request) {
+				code, body := getRequest(t, "login", r.URL.Query().Get("login_challenge"))
+				require.Equal(t, http.StatusOK, code, "%s", body)
+				assert.WithinDuration(t, time.Now().Add(10*time.Minute), body.Get("expires_at").Time(), 5*time.Second, "%s", body)
+				login(w, r)
+			}, func(w http.ResponseWriter, r *http.Request) {
+				code, body := getRequest(t, "consent", r.URL.Query().Get("consent_challenge"))
+				require.Equal(t, http.StatusOK, code, "%s", body)
+				assert.WithinDuration(t, time.Now().Add(5*time.Minute), body.Get("expires_at").Time(), 5*time.Second, "%s", body)
+				consent(w, r)
+			})
+
+			makeRequestAndExpectCode(t, nil, c, url.Values{})
+		})
+
+		t.Run("case=rejects expired login challenges", func(t *testing.T) {
+			c := createClient(t, reg, &client.Client{
+				RedirectURIs: []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNoExpectedCallHandler(t))},
+				Lifespans:    client.Lifespans{LoginChallengeLifespan: x.NullDuration{Duration: time.Nanosecond, Valid: true}},
+			})
+
+			testhelpers.NewLoginConsentUI(t, reg.Config(), func(w http.ResponseWriter, r *http.Request) {
+				code, body := getRequest(t, "login", r.URL.Query().Get("login_challenge"))
+				assert.Equal(t, http.StatusUnauthorized, code, "%s", body)
+				assert.Contains(t, body.Get("error_description").String(), "The login request has expired", "%s", body)
+				w.WriteHeader(http.StatusNotImplemented)
+			}, testhelpers.HTTPServerNoExpectedCallHandler(t))
+
+			_, res := makeOAuth2Request(t, reg, nil, c, url.Values{})
+			assert.EqualValues(t, http.StatusNotImplemented, res.StatusCode)
+		})
+
+		t.Run("case=rejects expired authorization codes", func(t *testing.T) {
+			c := createClient(t, reg, &client.Client{
+				RedirectURIs: []string{testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler)},
+				Lifespans:    client.Lifespans{AuthorizationCodeLifespan: x.NullDuration{Duration: time.Nanosecond, Valid: true}},
+			})
+			testhelpers.NewLoginConsentUI(t, reg.Config(),
+				acceptLoginHandler(t, "aeneas-rekkas", nil),
+				acceptConsentHandler(t, nil))
+
+			code := makeRequestAndExpectCode(t, nil, c, url.Values{})
+			_, err := oauth2Config(t, c).Exchange(context.Background(), code)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "expired")
+		})
+	})
+
 	t.Run("case=should pass if both login and consent are granted and check remember flows as well as various payloads", func(t *testing.T) {
 		// Covers old test cases:
 		// - This should pass because login and consent have been granted, this time we remember the decision
@@ -797,6 +991,26 @@ func TestStrategyLoginConsentNext(t *testing.T) {
 			"Prompt 'none' was requested, but no existing login session was found")
 	})
 
+	t.Run("case=should require authentication if the user agent does not match the session fingerprint", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeySessionFingerprintAttributes, []string{"user_agent", "header:X-Device-Id"})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeySessionFingerprintAttributes, nil) })
+
+		c := createDefaultClient(t)
+		testhelpers.NewLoginConsentUI(t, reg.Config(), acceptLoginHandler(t, "aeneas-rekkas", &hydra.AcceptOAuth2LoginRequest{Remember: pointerx.Bool(true)}),
+			acceptConsentHandler(t, &hydra.AcceptOAuth2ConsentRequest{Remember: pointerx.Bool(true)}))
+
+		hc := testhelpers.NewEmptyJarClient(t)
+		device := &headerTransport{header: http.Header{"X-Device-Id": {"device-a"}}, next: hc.Transport}
+		hc.Transport = device
+		makeRequestAndExpectCode(t, hc, c, url.Values{})
+		makeRequestAndExpectCode(t, hc, c, url.Values{"prompt": {"none"}})
+
+		// The cookies are imported into another device.
+		device.header.Set("X-Device-Id", "device-b")
+		makeRequestAndExpectError(t, hc, c, url.Values{"prompt": {"none"}},
+			"Prompt 'none' was requested, but no existing login session was found")
+	})
+
 	t.Run("case=should fail because prompt is none and consent is missing a permission which requires re-authorization of the app", func(t *testing.T) {
 		c := createDefaultClient(t)
 		testhelpers.NewLoginConsentUI(t, reg.Config(), acceptLoginHandler(t, "aeneas-rekkas", &hydra.AcceptOAuth2LoginRequest{Remember: pointerx.Bool(true)}),
@@ -1120,3 +1334,16 @@ func (d *dropCSRFCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
 func (d *dropCSRFCookieJar) Cookies(u *url.URL) []*http.Cookie {
 	return d.jar.Cookies(u)
 }
+
+// headerTransport sets the header on all requests, like a user agent which always sends the same headers.
+type headerTransport struct {
+	header http.Header
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	for k := range t.header {
+		r.Header.Set(k, t.header.Get(k))
+	}
+	return t.next.RoundTrip(r)
+}
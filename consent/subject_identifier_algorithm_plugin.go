@@ -0,0 +1,36 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/extension"
+	"github.com/ory/x/errorsx"
+)
+
+// SubjectIdentifierAlgorithmPlugin derives subject identifiers of a subject type which is implemented by a plugin.
+type SubjectIdentifierAlgorithmPlugin struct {
+	SubjectType string
+	Plugin      extension.SubjectIdentifierAlgorithm
+}
+
+func NewSubjectIdentifierAlgorithmPlugin(subjectType string, plugin extension.SubjectIdentifierAlgorithm) *SubjectIdentifierAlgorithmPlugin {
+	return &SubjectIdentifierAlgorithmPlugin{SubjectType: subjectType, Plugin: plugin}
+}
+
+func (g *SubjectIdentifierAlgorithmPlugin) Obfuscate(subject string, client *client.Client) (string, error) {
+	return g.ObfuscateWithContext(context.Background(), subject, client)
+}
+
+// ObfuscateWithContext derives the subject identifier and passes the context of the request to the plugin.
+func (g *SubjectIdentifierAlgorithmPlugin) ObfuscateWithContext(ctx context.Context, subject string, client *client.Client) (string, error) {
+	obfuscated, err := g.Plugin.ObfuscateSubject(ctx, g.SubjectType, client, subject)
+	if err != nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebugf("Plugin %s failed to derive the %s subject identifier: %s", g.Plugin.Name(), g.SubjectType, err))
+	}
+	return obfuscated, nil
+}
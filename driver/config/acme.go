@@ -0,0 +1,58 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"github.com/ory/x/contextx"
+)
+
+const (
+	KeyPublicACMEEnabled       = "serve.public.tls.acme.enabled"
+	KeyPublicACMEDirectoryURL  = "serve.public.tls.acme.directory_url"
+	KeyPublicACMEEmail         = "serve.public.tls.acme.email"
+	KeyPublicACMEHosts         = "serve.public.tls.acme.hosts"
+	KeyPublicACMECacheDir      = "serve.public.tls.acme.cache_dir"
+	KeyPublicACMEHTTPChallenge = "serve.public.tls.acme.http_challenge.enabled"
+	KeyPublicACMEHTTPListenOn  = "serve.public.tls.acme.http_challenge.listen_on"
+
+	// DefaultACMEDirectoryURL is the production directory of Let's Encrypt.
+	DefaultACMEDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+)
+
+// ACME configures the provisioning of the TLS certificates of the public API using ACME, for example with Let's
+// Encrypt.
+type ACME struct {
+	Enabled      bool
+	DirectoryURL string
+	Email        string
+
+	// Hosts are the host names certificates are requested for. They default to the host name of the issuer.
+	Hosts []string
+
+	// CacheDir is the directory the account key and the certificates are stored in. If empty, they are kept in
+	// memory and requested again on every start.
+	CacheDir string
+
+	// HTTPChallenge enables the HTTP-01 challenge in addition to the TLS-ALPN-01 challenge, served on
+	// HTTPListenOn, which also redirects all other requests to HTTPS.
+	HTTPChallenge bool
+	HTTPListenOn  string
+}
+
+func (p *DefaultProvider) PublicACME() *ACME {
+	ctx := contextx.RootContext
+	c := &ACME{
+		Enabled:       p.getProvider(ctx).Bool(KeyPublicACMEEnabled),
+		DirectoryURL:  p.getProvider(ctx).StringF(KeyPublicACMEDirectoryURL, DefaultACMEDirectoryURL),
+		Email:         p.getProvider(ctx).String(KeyPublicACMEEmail),
+		Hosts:         p.getProvider(ctx).Strings(KeyPublicACMEHosts),
+		CacheDir:      p.getProvider(ctx).String(KeyPublicACMECacheDir),
+		HTTPChallenge: p.getProvider(ctx).Bool(KeyPublicACMEHTTPChallenge),
+		HTTPListenOn:  p.getProvider(ctx).StringF(KeyPublicACMEHTTPListenOn, ":80"),
+	}
+	if len(c.Hosts) == 0 {
+		c.Hosts = []string{p.IssuerURL(ctx).Hostname()}
+	}
+	return c
+}
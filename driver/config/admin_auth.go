@@ -0,0 +1,138 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	KeyAdminAuthAPIKeys            = "serve.admin.auth.api_keys"
+	KeyAdminAuthMTLSEnabled        = "serve.admin.auth.mtls.enabled"
+	KeyAdminAuthMTLSClientCAPath   = "serve.admin.auth.mtls.client_ca.path"
+	KeyAdminAuthMTLSClientCAString = "serve.admin.auth.mtls.client_ca.base64"
+	KeyAdminAuthMTLSSubjects       = "serve.admin.auth.mtls.subjects"
+	KeyAdminAuthMTLSRequired       = "serve.admin.auth.mtls.required"
+	KeyAdminAuthMTLSCRLPath        = "serve.admin.auth.mtls.crl.path"
+	KeyAdminAuthMTLSCRLString      = "serve.admin.auth.mtls.crl.base64"
+	KeyAdminAuthMTLSOCSPEnabled    = "serve.admin.auth.mtls.ocsp.enabled"
+	KeyAdminAuthMTLSOCSPFailOpen   = "serve.admin.auth.mtls.ocsp.fail_open"
+)
+
+type (
+	// AdminAPIKey is a static API key granting access to the admin API.
+	AdminAPIKey struct {
+		ID     string   `json:"id" koanf:"id"`
+		Key    string   `json:"key" koanf:"key"`
+		Scopes []string `json:"scopes" koanf:"scopes"`
+	}
+
+	// AdminMTLSSubject grants scopes to the admin API to the client certificate with the given common name.
+	AdminMTLSSubject struct {
+		CommonName string   `json:"common_name" koanf:"common_name"`
+		Scopes     []string `json:"scopes" koanf:"scopes"`
+	}
+)
+
+func (p *DefaultProvider) AdminAPIKeys(ctx context.Context) ([]AdminAPIKey, error) {
+	var keys []AdminAPIKey
+	if err := p.getProvider(ctx).Unmarshal(KeyAdminAuthAPIKeys, &keys); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return keys, nil
+}
+
+func (p *DefaultProvider) AdminMTLSEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyAdminAuthMTLSEnabled)
+}
+
+// AdminMTLSRequired returns true if the TLS handshake of the admin API fails without a valid client certificate. API
+// keys can then only be used together with a client certificate.
+func (p *DefaultProvider) AdminMTLSRequired(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyAdminAuthMTLSRequired)
+}
+
+func (p *DefaultProvider) AdminMTLSOCSPEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyAdminAuthMTLSOCSPEnabled)
+}
+
+// AdminMTLSOCSPFailOpen returns true if client certificates are accepted when their OCSP responder can not be
+// reached.
+func (p *DefaultProvider) AdminMTLSOCSPFailOpen(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyAdminAuthMTLSOCSPFailOpen)
+}
+
+// AdminMTLSCRLs returns the certificate revocation lists used to check client certificates presented to the admin
+// API. It returns no lists if none are configured.
+func (p *DefaultProvider) AdminMTLSCRLs(ctx context.Context) ([]*x509.RevocationList, error) {
+	var raw []byte
+	if path := p.getProvider(ctx).String(KeyAdminAuthMTLSCRLPath); path != "" {
+		var err error
+		if raw, err = os.ReadFile(path); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else if encoded := p.getProvider(ctx).String(KeyAdminAuthMTLSCRLString); encoded != "" {
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		return nil, nil
+	}
+
+	var crls []*x509.RevocationList
+	for block, rest := pem.Decode(raw); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "X509 CRL" {
+			continue
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		crls = append(crls, crl)
+	}
+	if len(crls) == 0 {
+		return nil, errors.New("the certificate revocation lists do not contain any PEM encoded CRL")
+	}
+	return crls, nil
+}
+
+func (p *DefaultProvider) AdminMTLSSubjects(ctx context.Context) ([]AdminMTLSSubject, error) {
+	var subjects []AdminMTLSSubject
+	if err := p.getProvider(ctx).Unmarshal(KeyAdminAuthMTLSSubjects, &subjects); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return subjects, nil
+}
+
+// AdminMTLSClientCAs returns the certificate authorities used to verify client certificates presented to the admin
+// API.
+func (p *DefaultProvider) AdminMTLSClientCAs(ctx context.Context) (*x509.CertPool, error) {
+	var pem []byte
+	if path := p.getProvider(ctx).String(KeyAdminAuthMTLSClientCAPath); path != "" {
+		var err error
+		if pem, err = os.ReadFile(path); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else if encoded := p.getProvider(ctx).String(KeyAdminAuthMTLSClientCAString); encoded != "" {
+		var err error
+		if pem, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		return nil, errors.Errorf("configuration key %s or %s must be set", KeyAdminAuthMTLSClientCAPath, KeyAdminAuthMTLSClientCAString)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("the client certificate authorities do not contain any PEM encoded certificate")
+	}
+	return pool, nil
+}
@@ -0,0 +1,74 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/contextx"
+)
+
+const (
+	KeyOTLPEndpoint                  = "otlp.endpoint"
+	KeyOTLPInsecure                  = "otlp.insecure"
+	KeyOTLPHeaders                   = "otlp.headers"
+	KeyOTLPResourceAttributes        = "otlp.resource_attributes"
+	KeyOTLPTracesEnabled             = "otlp.traces.enabled"
+	KeyOTLPTracesSamplingRatio       = "otlp.traces.sampling.ratio"
+	KeyOTLPTracesSamplingParentBased = "otlp.traces.sampling.parent_based"
+	KeyOTLPMetricsEnabled            = "otlp.metrics.enabled"
+	KeyOTLPMetricsInterval           = "otlp.metrics.interval"
+)
+
+type (
+	// OTLP configures the export of traces and metrics to an OpenTelemetry collector.
+	OTLP struct {
+		// Endpoint is the host and port of the OTLP/HTTP receiver, for example "otel-collector:4318".
+		Endpoint string
+		Insecure bool
+		Headers  map[string]string
+
+		// ResourceAttributes are added to the resource of all traces and metrics, in addition to the service name,
+		// version and deployment environment.
+		ResourceAttributes []OTLPResourceAttribute
+
+		TracesEnabled             bool
+		TracesSamplingRatio       float64
+		TracesSamplingParentBased bool
+
+		MetricsEnabled  bool
+		MetricsInterval time.Duration
+	}
+
+	OTLPResourceAttribute struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+)
+
+func (p *DefaultProvider) OTLP() *OTLP {
+	pp := p.getProvider(contextx.RootContext)
+	c := &OTLP{
+		Endpoint:                  pp.String(KeyOTLPEndpoint),
+		Insecure:                  pp.Bool(KeyOTLPInsecure),
+		Headers:                   map[string]string{},
+		TracesEnabled:             pp.Bool(KeyOTLPTracesEnabled),
+		TracesSamplingRatio:       pp.Float64F(KeyOTLPTracesSamplingRatio, 1),
+		TracesSamplingParentBased: pp.BoolF(KeyOTLPTracesSamplingParentBased, true),
+		MetricsEnabled:            pp.Bool(KeyOTLPMetricsEnabled),
+		MetricsInterval:           pp.DurationF(KeyOTLPMetricsInterval, time.Minute),
+	}
+
+	if err := pp.Unmarshal(KeyOTLPHeaders, &c.Headers); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOTLPHeaders)
+	}
+	if err := pp.Unmarshal(KeyOTLPResourceAttributes, &c.ResourceAttributes); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOTLPResourceAttributes)
+	}
+	return c
+}
@@ -5,6 +5,7 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -25,6 +26,7 @@ import (
 	"github.com/ory/x/configx"
 
 	"github.com/ory/x/logrusx"
+	"github.com/ory/x/pointerx"
 
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/contextx"
@@ -40,6 +42,12 @@ const (
 	HSMSlotNumber                                = "hsm.slot"
 	HSMKeySetPrefix                              = "hsm.key_set_prefix"
 	HSMTokenLabel                                = "hsm.token_label" // #nosec G101
+	HSMRotationGracePeriod                       = "hsm.rotation.grace_period"
+	HSMKeySets                                   = "hsm.key_sets"
+	HSMKeySetCacheTTL                            = "hsm.key_set_cache_ttl"
+	HSMImportKeys                                = "hsm.import_keys"
+	HSMPublishCertificates                       = "hsm.publish_certificates"
+	HSMPartitions                                = "hsm.partitions"
 	KeyWellKnownKeys                             = "webfinger.jwks.broadcast_keys"
 	KeyOAuth2ClientRegistrationURL               = "webfinger.oidc_discovery.client_registration_url"
 	KeyOAuth2TokenURL                            = "webfinger.oidc_discovery.token_url" // #nosec G101
@@ -49,6 +57,8 @@ const (
 	KeyOIDCDiscoverySupportedClaims              = "webfinger.oidc_discovery.supported_claims"
 	KeyOIDCDiscoverySupportedScope               = "webfinger.oidc_discovery.supported_scope"
 	KeyOIDCDiscoveryUserinfoEndpoint             = "webfinger.oidc_discovery.userinfo_url"
+	KeyOIDCDiscoveryExtraFields                  = "webfinger.oidc_discovery.extra_fields"
+	KeyOIDCDiscoveryCacheTTL                     = "webfinger.oidc_discovery.cache_ttl"
 	KeySubjectTypesSupported                     = "oidc.subject_identifiers.supported_types"
 	KeyDefaultClientScope                        = "oidc.dynamic_client_registration.default_scope"
 	KeyDSN                                       = "dsn"
@@ -67,11 +77,15 @@ const (
 	KeyCookieSessionName                         = "serve.cookies.names.session"
 	KeyCookieSessionPath                         = "serve.cookies.paths.session"
 	KeyConsentRequestMaxAge                      = "ttl.login_consent_request"
+	KeyLoginChallengeLifespan                    = "ttl.login_challenge"
+	KeyConsentChallengeLifespan                  = "ttl.consent_challenge"
+	KeyLogoutChallengeLifespan                   = "ttl.logout_challenge"
 	KeyAccessTokenLifespan                       = "ttl.access_token"  // #nosec G101
 	KeyRefreshTokenLifespan                      = "ttl.refresh_token" // #nosec G101
 	KeyVerifiableCredentialsNonceLifespan        = "ttl.vc_nonce"      // #nosec G101
 	KeyIDTokenLifespan                           = "ttl.id_token"      // #nosec G101
 	KeyAuthCodeLifespan                          = "ttl.auth_code"
+	KeyDerivedAccessTokenLifespan                = "ttl.derived_access_token" // #nosec G101
 	KeyScopeStrategy                             = "strategies.scope"
 	KeyGetCookieSecrets                          = "secrets.cookie"
 	KeyGetSystemSecret                           = "secrets.system"
@@ -89,14 +103,58 @@ const (
 	KeyIdentityProviderHeaders                   = "urls.identity_provider.headers"
 	KeyAccessTokenStrategy                       = "strategies.access_token"
 	KeyJWTScopeClaimStrategy                     = "strategies.jwt.scope_claim"
+	KeyJWTClaimsProfile                          = "strategies.jwt.claims_profile"
+	KeyJWTHashSubject                            = "strategies.jwt.hash_subject"
 	KeyDBIgnoreUnknownTableColumns               = "db.ignore_unknown_table_columns"
+	KeyDBReadReplicas                            = "db.read_replicas"
+	KeyDBPool                                    = "db.pool"
+	KeyDBReadReplicaPool                         = "db.read_replica_pool"
+	KeyDBRedisURL                                = "db.redis.url"
+	KeyDBRedisEphemeralSessions                  = "db.redis.ephemeral_sessions"
+	KeyDBRedisAccessTokenCache                   = "db.redis.access_token_cache"
+	KeyDBRedisRememberedConsentCache             = "db.redis.remembered_consent_cache"
+	KeyDBRedisReplayProtection                   = "db.redis.replay_protection"
+	KeyDBClientCacheEnabled                      = "db.client_cache.enabled"
+	KeyDBClientCacheMaxClients                   = "db.client_cache.max_clients"
+	KeyDBClientCacheTTL                          = "db.client_cache.ttl"
+	KeyDBTokenPartitionsInterval                 = "db.token_partitions.interval"
+	KeyDBTokenPartitionsPremake                  = "db.token_partitions.premake"
+	KeyDBFollowerReadsEnabled                    = "db.follower_reads.enabled"
+	KeyDBFollowerReadsMaxStaleness               = "db.follower_reads.max_staleness"
+	KeyDBStatementCommentsEnabled                = "db.statement_comments.enabled"
+	KeyDBStatementCommentsTraceparent            = "db.statement_comments.traceparent"
+	KeyDBSlowQueryThreshold                      = "db.slow_query_log.threshold"
+	KeyDBSQLiteWAL                               = "db.sqlite.wal"
+	KeyDBSQLiteBusyTimeout                       = "db.sqlite.busy_timeout"
+	KeyDBBatchTokenWritesEnabled                 = "db.batch_token_writes.enabled"
+	KeySuffixDBPoolMaxOpenConns                  = "max_open_conns"
+	KeySuffixDBPoolMaxIdleConns                  = "max_idle_conns"
+	KeySuffixDBPoolMaxConnLifetime               = "max_conn_lifetime"
+	KeySuffixDBPoolMaxConnIdleTime               = "max_conn_idle_time"
 	KeySubjectIdentifierAlgorithmSalt            = "oidc.subject_identifiers.pairwise.salt"
+	KeyOIDCNonceReplayProtection                 = "oidc.nonce_replay_protection"
+	KeyOIDCSDJWTIDTokenClients                   = "oidc.sd_jwt.id_token_clients"
+	KeyOIDCSIOPClientID                          = "oidc.siop.client_id"
+	KeyOIDCSIOPPresentationDefinition            = "oidc.siop.presentation_definition"
+	KeyOIDCSIOPTrustedIssuers                    = "oidc.siop.trusted_issuers"
+	KeyOIDCIDTokenClaimsTemplates                = "oidc.claims_templates.id_token"
+	KeyOIDCLogoutTokenClaimsTemplates            = "oidc.claims_templates.logout_token"
+	KeyI18nDefaultLocale                         = "i18n.default_locale"
+	KeyPostLogoutRedirectURIsAllowList           = "oidc.rp_initiated_logout.allowed_post_logout_redirect_uris"
+	KeyPostLogoutRedirectSubdomainWildcards      = "oidc.rp_initiated_logout.subdomain_wildcards"
+	KeyPostLogoutRedirectSameOrigin              = "oidc.rp_initiated_logout.same_origin_redirects"
+	KeyI18nMessages                              = "i18n.messages"
+	KeyUMAPolicyHook                             = "uma.policy_hook"
+	KeyUMAPermissionTicketLifespan               = "uma.permission_ticket_lifespan"
+	KeyGNAPEnabled                               = "gnap.enabled"
+	KeyGNAPGrantRequestLifespan                  = "gnap.grant_request_lifespan"
 	KeyPublicAllowDynamicRegistration            = "oidc.dynamic_client_registration.enabled"
 	KeyPKCEEnforced                              = "oauth2.pkce.enforced"
 	KeyPKCEEnforcedForPublicClients              = "oauth2.pkce.enforced_for_public_clients"
 	KeyLogLevel                                  = "log.level"
 	KeyCGroupsV1AutoMaxProcsEnabled              = "cgroups.v1.auto_max_procs_enabled"
 	KeyGrantAllClientCredentialsScopesPerDefault = "oauth2.client_credentials.default_grant_allowed_scope" // #nosec G101
+	KeyClientCredentialsTokenCacheMaxEntries     = "oauth2.client_credentials.token_cache.max_entries"
 	KeyExposeOAuth2Debug                         = "oauth2.expose_internal_errors"
 	KeyExcludeNotBeforeClaim                     = "oauth2.exclude_not_before_claim"
 	KeyAllowedTopLevelClaims                     = "oauth2.allowed_top_level_claims"
@@ -104,13 +162,135 @@ const (
 	KeyOAuth2GrantJWTIDOptional                  = "oauth2.grant.jwt.jti_optional"
 	KeyOAuth2GrantJWTIssuedDateOptional          = "oauth2.grant.jwt.iat_optional"
 	KeyOAuth2GrantJWTMaxDuration                 = "oauth2.grant.jwt.max_ttl"
+	KeyOAuth2GrantJWTLoginAssertions             = "oauth2.grant.jwt.login_assertions"
+	KeyOAuth2GrantJWTExpiryWarningBefore         = "oauth2.grant.jwt.expiry_warning.before"
+	KeyOAuth2GrantJWTExpiryWarningInterval       = "oauth2.grant.jwt.expiry_warning.interval"
 	KeyRefreshTokenHook                          = "oauth2.refresh_token_hook" // #nosec G101
 	KeyTokenHook                                 = "oauth2.token_hook"         // #nosec G101
+	KeyAuthorizationRequestHook                  = "oauth2.authorization_request_hook"
+	KeyAuthorizationRequestHookType              = "oauth2.authorization_request_hook.type"
+	KeyOAuth2MetricsClientIDsEnabled             = "oauth2.metrics.client_ids.enabled"
+	KeyOAuth2MetricsClientIDsAllowed             = "oauth2.metrics.client_ids.allowed"
+	KeyOAuth2MetricsClientIDsMax                 = "oauth2.metrics.client_ids.max"
+	KeyOAuth2FAPIEnabled                         = "oauth2.fapi.enabled"
+	KeyOAuth2IntrospectionMetadata               = "oauth2.introspection.metadata"
+	KeyOAuth2ClientAttestationHook               = "oauth2.client_attestation.hook"
+	KeyOAuth2ClientAttestationClients            = "oauth2.client_attestation.clients"
+	KeyOAuth2TokenQuotas                         = "oauth2.token_quotas"
+	KeyOAuth2ErrorResponses                      = "oauth2.error_responses"
+	KeyOAuth2FallbackUIEnabled                   = "oauth2.fallback_ui.enabled"
+	KeyOAuth2ClientCORSEnabled                   = "oauth2.client_cors.enabled"
+	KeySessionFingerprintAttributes              = "oauth2.session_fingerprint.attributes"
+	KeyOAuth2RevocationNotifications             = "oauth2.revocation_notifications"
+	KeyOAuth2FallbackUIIdentityHook              = "oauth2.fallback_ui.identity_hook"
+	KeyClockSkew                                 = "oauth2.clock_skew"
+	KeyOAuth2MaxAuthorizationURLLength           = "oauth2.request_limits.authorization_url_length"
+	KeyOAuth2MaxRequestObjectSize                = "oauth2.request_limits.request_object_size"
+	KeyOAuth2MaxDynamicClientRegistrationSize    = "oauth2.request_limits.dynamic_client_registration_body_size"
+	KeyJWTHeadersX5T                             = "oauth2.jwt_headers.x5t"
+	KeyJWTHeadersExtra                           = "oauth2.jwt_headers.extra"
+	KeyJWTHeadersAccessTokenType                 = "oauth2.jwt_headers.typ.access_token" // #nosec G101
+	KeyJWTHeadersIDTokenType                     = "oauth2.jwt_headers.typ.id_token"
+	KeyJWTHeadersLogoutTokenType                 = "oauth2.jwt_headers.typ.logout_token"
 	KeyDevelopmentMode                           = "dev"
+	KeyJanitorEnabled                            = "janitor.enabled"
+	KeyJanitorInterval                           = "janitor.interval"
+	KeyJanitorKeepIfYounger                      = "janitor.keep_if_younger"
+	KeyJanitorLimit                              = "janitor.limit"
+	KeyJanitorBatchSize                          = "janitor.batch_size"
+	KeyJanitorRetentionLoginConsentRequests      = "janitor.retention.login_consent_requests"
+	KeyJanitorRetentionTokens                    = "janitor.retention.tokens"
+	KeyJanitorRetentionConsentSessions           = "janitor.retention.consent_sessions"
+	KeyJanitorRetentionAuditEvents               = "janitor.retention.audit_events"
+	KeyJWKSPruningKeepNewest                     = "jwks.pruning.keep_newest"
+	KeyJWKSPruningKeepIfYounger                  = "jwks.pruning.keep_if_younger"
+	KeyJWKSGenerationRSAMinBits                  = "jwks.generation.rsa.min_bits"
+	KeyJWKSGenerationRSAMaxBits                  = "jwks.generation.rsa.max_bits"
+	KeyJWKSGenerationCurves                      = "jwks.generation.curves"
+	KeyJWKSIDTokenSigningKeySet                  = "jwks.signing_key_sets.id_token"
+	KeyJWKSAccessTokenSigningKeySet              = "jwks.signing_key_sets.access_token" // #nosec G101
+	KeyJWKSLogoutTokenSigningKeySet              = "jwks.signing_key_sets.logout_token"
+	KeyAuditEnabled                              = "audit.enabled"
+	KeyAuditActorHeader                          = "audit.actor_header"
+	KeyAuditSink                                 = "audit.sink"
+	KeyAuditSecurityLogEnabled                   = "audit.security_log.enabled"
+	KeyAuditSecurityLogSinks                     = "audit.security_log.sinks"
+	KeyAuditSecurityLogBufferSize                = "audit.security_log.buffer_size"
+	KeyAuditSecurityLogFlushInterval             = "audit.security_log.flush_interval"
+	KeyAuditSecurityLogRedactFields              = "audit.security_log.redact.fields"
+	KeyAuditSecurityLogRedactMode                = "audit.security_log.redact.mode"
+	KeyAuditSuspiciousActivityWebhook            = "audit.suspicious_activity.webhook"
+	KeyAuditSuspiciousActivityAuthFailures       = "audit.suspicious_activity.client_authentication_failures.threshold"
+	KeyAuditSuspiciousActivityAuthFailureWindow  = "audit.suspicious_activity.client_authentication_failures.window"
+	KeyEventStreamType                           = "events.stream.type"
+	KeyEventStreamWebhook                        = "events.stream.webhook"
+	KeyEventStreamKafkaBrokers                   = "events.stream.kafka.brokers"
+	KeyEventStreamKafkaTopic                     = "events.stream.kafka.topic"
+	KeyEventStreamNATSURL                        = "events.stream.nats.url"
+	KeyEventStreamNATSSubject                    = "events.stream.nats.subject"
+	KeyEventStreamBatchSize                      = "events.stream.batch_size"
+	KeyEventStreamFlushInterval                  = "events.stream.flush_interval"
+	KeyEventStreamBufferSize                     = "events.stream.buffer_size"
+	KeyEventWebhooks                             = "events.webhooks"
+	KeyAdminSwaggerUIEnabled                     = "serve.admin.swagger_ui.enabled"
+	KeyStatsCacheTTL                             = "stats.cache_ttl"
+	KeyPluginPaths                               = "plugins.paths"
+	KeyPluginWASMModules                         = "plugins.wasm"
 )
 
 const DSNMemory = "memory"
 
+// immutableServeKeys are the keys below "serve" which are only read when the server starts. configx compares
+// immutable keys with the flattened keys of the configuration, so they are listed individually. All other keys,
+// for example CORS and TLS certificates, are reloaded when the configuration file changes.
+// immutableDBPoolKeys are the connection pool settings, which are applied when connecting to the database.
+var immutableDBPoolKeys = []string{
+	KeyDBPool + "." + KeySuffixDBPoolMaxOpenConns,
+	KeyDBPool + "." + KeySuffixDBPoolMaxIdleConns,
+	KeyDBPool + "." + KeySuffixDBPoolMaxConnLifetime,
+	KeyDBPool + "." + KeySuffixDBPoolMaxConnIdleTime,
+	KeyDBReadReplicaPool + "." + KeySuffixDBPoolMaxOpenConns,
+	KeyDBReadReplicaPool + "." + KeySuffixDBPoolMaxIdleConns,
+	KeyDBReadReplicaPool + "." + KeySuffixDBPoolMaxConnLifetime,
+	KeyDBReadReplicaPool + "." + KeySuffixDBPoolMaxConnIdleTime,
+}
+
+var immutableServeKeys = []string{
+	PublicInterface.Key(KeySuffixListenOnHost),
+	PublicInterface.Key(KeySuffixListenOnPort),
+	PublicInterface.Key(KeySuffixSocketOwner),
+	PublicInterface.Key(KeySuffixSocketGroup),
+	PublicInterface.Key(KeySuffixSocketMode),
+	PublicInterface.Key(KeySuffixTLSEnabled),
+	PublicInterface.Key(KeySuffixTLSAllowTerminationFrom),
+	PublicInterface.Key(KeySuffixH2CEnabled),
+	PublicInterface.Key(KeySuffixHTTP3Enabled),
+	AdminInterface.Key(KeySuffixListenOnHost),
+	AdminInterface.Key(KeySuffixListenOnPort),
+	AdminInterface.Key(KeySuffixSocketOwner),
+	AdminInterface.Key(KeySuffixSocketGroup),
+	AdminInterface.Key(KeySuffixSocketMode),
+	AdminInterface.Key(KeySuffixTLSEnabled),
+	AdminInterface.Key(KeySuffixTLSAllowTerminationFrom),
+	AdminInterface.Key(KeySuffixH2CEnabled),
+	AdminInterface.Key(KeySuffixHTTP3Enabled),
+	KeyPublicACMEEnabled,
+	KeyPublicACMEDirectoryURL,
+	KeyPublicACMEEmail,
+	KeyPublicACMEHosts,
+	KeyPublicACMECacheDir,
+	KeyPublicACMEHTTPChallenge,
+	KeyPublicACMEHTTPListenOn,
+	KeyTLSEnabled,
+	KeyTLSAllowTerminationFrom,
+	KeyAdminAuthMTLSEnabled,
+	KeyAdminAuthMTLSClientCAPath,
+	KeyAdminAuthMTLSClientCAString,
+	KeyAdminAuthMTLSRequired,
+	KeyAdminAuthMTLSOCSPEnabled,
+	KeyAdminAuthMTLSOCSPFailOpen,
+}
+
 var (
 	_ hasherx.PBKDF2Configurator = (*DefaultProvider)(nil)
 	_ hasherx.BCryptConfigurator = (*DefaultProvider)(nil)
@@ -165,7 +345,9 @@ func New(ctx context.Context, l *logrusx.Logger, opts ...configx.OptionModifier)
 		[]configx.OptionModifier{
 			configx.WithStderrValidationReporter(),
 			configx.OmitKeysFromTracing("dsn", "secrets.system", "secrets.cookie"),
-			configx.WithImmutables("log", "serve", "dsn", "profiling"),
+			configx.WithImmutables("log", "dsn", KeyDBReadReplicas, KeyDBRedisURL, KeyDBRedisEphemeralSessions, KeyDBRedisAccessTokenCache, KeyDBRedisRememberedConsentCache, KeyDBRedisReplayProtection, KeyDBClientCacheEnabled, KeyDBClientCacheMaxClients, KeyDBClientCacheTTL, "profiling"),
+			configx.WithImmutables(immutableDBPoolKeys...),
+			configx.WithImmutables(immutableServeKeys...),
 			configx.WithLogrusWatcher(l),
 		}, opts...,
 	)
@@ -201,7 +383,7 @@ func (p *DefaultProvider) IsDevelopmentMode(ctx context.Context) bool {
 }
 
 func (p *DefaultProvider) WellKnownKeys(ctx context.Context, include ...string) []string {
-	include = append(include, x.OAuth2JWTKeyName, x.OpenIDConnectKeyName)
+	include = append(include, p.AccessTokenSigningKeySet(ctx), p.IDTokenSigningKeySet(ctx), p.LogoutTokenSigningKeySet(ctx))
 	return stringslice.Unique(append(p.getProvider(ctx).Strings(KeyWellKnownKeys), include...))
 }
 
@@ -222,10 +404,11 @@ func (p *DefaultProvider) MirrorTopLevelClaims(ctx context.Context) bool {
 }
 
 func (p *DefaultProvider) SubjectTypesSupported(ctx context.Context, additionalSources ...AccessTokenStrategySource) []string {
+	// Subject types other than "public" and "pairwise" are implemented by plugins.
 	types := stringslice.Filter(
 		p.getProvider(ctx).StringsF(KeySubjectTypesSupported, []string{"public"}),
 		func(s string) bool {
-			return !(s == "public" || s == "pairwise")
+			return s == ""
 		},
 	)
 
@@ -323,6 +506,23 @@ func (p *DefaultProvider) ConsentRequestMaxAge(ctx context.Context) time.Duratio
 	return p.getProvider(ctx).DurationF(KeyConsentRequestMaxAge, time.Minute*30)
 }
 
+// LoginChallengeLifespan returns how long a login challenge may be used, starting when the flow was requested. It
+// defaults to ttl.login_consent_request.
+func (p *DefaultProvider) LoginChallengeLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyLoginChallengeLifespan, p.ConsentRequestMaxAge(ctx))
+}
+
+// ConsentChallengeLifespan returns how long a consent challenge may be used, starting when the flow was requested. It
+// defaults to ttl.login_consent_request.
+func (p *DefaultProvider) ConsentChallengeLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyConsentChallengeLifespan, p.ConsentRequestMaxAge(ctx))
+}
+
+// LogoutChallengeLifespan returns how long a logout challenge may be used. It defaults to ttl.login_consent_request.
+func (p *DefaultProvider) LogoutChallengeLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyLogoutChallengeLifespan, p.ConsentRequestMaxAge(ctx))
+}
+
 func (p *DefaultProvider) Tracing() *otelx.Config {
 	return p.getProvider(contextx.RootContext).TracingConfig("Ory Hydra")
 }
@@ -345,6 +545,9 @@ func (p *DefaultProvider) GetCookieSecrets(ctx context.Context) ([][]byte, error
 }
 
 func (p *DefaultProvider) LogoutRedirectURL(ctx context.Context) *url.URL {
+	if u, ok := p.issuerURL(ctx, func(u IssuerURLsCustomization) string { return u.PostLogoutRedirect }); ok {
+		return u
+	}
 	return urlRoot(
 		p.getProvider(ctx).RequestURIF(
 			KeyLogoutRedirectURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/logout/callback"),
@@ -373,26 +576,44 @@ func (p *DefaultProvider) fallbackURL(ctx context.Context, path string, host str
 }
 
 func (p *DefaultProvider) LoginURL(ctx context.Context) *url.URL {
+	if u, ok := p.issuerURL(ctx, func(u IssuerURLsCustomization) string { return u.Login }); ok {
+		return u
+	}
 	return urlRoot(p.getProvider(ctx).URIF(KeyLoginURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/login")))
 }
 
 func (p *DefaultProvider) RegistrationURL(ctx context.Context) *url.URL {
+	if u, ok := p.issuerURL(ctx, func(u IssuerURLsCustomization) string { return u.Registration }); ok {
+		return u
+	}
 	return urlRoot(p.getProvider(ctx).URIF(KeyRegistrationURL, p.LoginURL(ctx)))
 }
 
 func (p *DefaultProvider) LogoutURL(ctx context.Context) *url.URL {
+	if u, ok := p.issuerURL(ctx, func(u IssuerURLsCustomization) string { return u.Logout }); ok {
+		return u
+	}
 	return urlRoot(p.getProvider(ctx).RequestURIF(KeyLogoutURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/logout")))
 }
 
 func (p *DefaultProvider) ConsentURL(ctx context.Context) *url.URL {
+	if u, ok := p.issuerURL(ctx, func(u IssuerURLsCustomization) string { return u.Consent }); ok {
+		return u
+	}
 	return urlRoot(p.getProvider(ctx).URIF(KeyConsentURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/consent")))
 }
 
 func (p *DefaultProvider) ErrorURL(ctx context.Context) *url.URL {
+	if u, ok := p.issuerURL(ctx, func(u IssuerURLsCustomization) string { return u.Error }); ok {
+		return u
+	}
 	return urlRoot(p.getProvider(ctx).RequestURIF(KeyErrorURL, p.publicFallbackURL(ctx, "oauth2/fallbacks/error")))
 }
 
 func (p *DefaultProvider) PublicURL(ctx context.Context) *url.URL {
+	if u, ok := selfURLsFromContext(ctx); ok {
+		return urlx.Copy(u.public)
+	}
 	return urlRoot(p.getProvider(ctx).RequestURIF(KeyPublicURL, p.IssuerURL(ctx)))
 }
 
@@ -405,6 +626,9 @@ func (p *DefaultProvider) AdminURL(ctx context.Context) *url.URL {
 }
 
 func (p *DefaultProvider) IssuerURL(ctx context.Context) *url.URL {
+	if u, ok := selfURLsFromContext(ctx); ok {
+		return urlx.Copy(u.issuer)
+	}
 	return p.getProvider(ctx).RequestURIF(
 		KeyIssuerURL, p.fallbackURL(ctx, "/", p.host(PublicInterface), p.port(PublicInterface)),
 	)
@@ -493,8 +717,124 @@ type (
 		URL  string `json:"url"`
 		Auth *Auth  `json:"auth"`
 	}
+	// DBPool configures the connection pool of a database. Zero values are not set and leave the value of the DSN
+	// query parameters or the default in place.
+	DBPool struct {
+		MaxOpenConns    int
+		MaxIdleConns    int
+		MaxConnLifetime time.Duration
+		MaxConnIdleTime time.Duration
+	}
+	// SecurityLogSink is a destination of the security audit log.
+	SecurityLogSink struct {
+		// Type is one of "file", "syslog" or "http".
+		Type string `json:"type" koanf:"type"`
+
+		// Path is the file which the entries of the "file" sink are appended to.
+		Path string `json:"path" koanf:"path"`
+
+		// Network and Address are the syslog server of the "syslog" sink. The local syslog server is used if they are
+		// empty.
+		Network string `json:"network" koanf:"network"`
+		Address string `json:"address" koanf:"address"`
+		Tag     string `json:"tag" koanf:"tag"`
+
+		// URL and Auth configure the endpoint which the entries of the "http" sink are posted to.
+		URL  string `json:"url" koanf:"url"`
+		Auth *Auth  `json:"auth" koanf:"auth"`
+	}
+	// EventWebhook receives events as CloudEvents.
+	EventWebhook struct {
+		URL        string   `json:"url" koanf:"url"`
+		Auth       *Auth    `json:"auth" koanf:"auth"`
+		Secret     string   `json:"secret" koanf:"secret"`
+		Events     []string `json:"events" koanf:"events"`
+		MaxRetries *int     `json:"max_retries" koanf:"max_retries"`
+	}
+	// RevocationNotification notifies a resource server when tokens issued for its audience are revoked.
+	RevocationNotification struct {
+		URL        string   `json:"url" koanf:"url"`
+		Audience   []string `json:"audience" koanf:"audience"`
+		Auth       *Auth    `json:"auth" koanf:"auth"`
+		Secret     string   `json:"secret" koanf:"secret"`
+		MaxRetries *int     `json:"max_retries" koanf:"max_retries"`
+	}
+	// WASMModule is a WebAssembly module which is executed at the extension points it exports.
+	WASMModule struct {
+		Path string `json:"path" koanf:"path"`
+
+		// MaxMemoryPages limits the linear memory of the module in pages of 64 KiB.
+		MaxMemoryPages uint32 `json:"max_memory_pages" koanf:"max_memory_pages"`
+
+		// Timeout limits the duration of a single call of the module.
+		Timeout time.Duration `json:"timeout" koanf:"timeout"`
+	}
+	// SIOPTrustedIssuer is an issuer of verifiable credentials which are accepted in SIOPv2 responses.
+	SIOPTrustedIssuer struct {
+		Issuer  string `json:"issuer" koanf:"issuer"`
+		JWKSURI string `json:"jwks_uri" koanf:"jwks_uri"`
+	}
+	// ClientAttestationRequirement requires a client to present attestation evidence at the token endpoint.
+	ClientAttestationRequirement struct {
+		ClientID string   `json:"client_id" koanf:"client_id"`
+		Types    []string `json:"types" koanf:"types"`
+	}
+	// TokenQuota limits the number of token requests of a client per hour and per day.
+	TokenQuota struct {
+		ClientID string           `json:"client_id" koanf:"client_id"`
+		PerHour  TokenQuotaLimits `json:"per_hour" koanf:"per_hour"`
+		PerDay   TokenQuotaLimits `json:"per_day" koanf:"per_day"`
+	}
+	// TokenQuotaLimits are the soft and hard limits of a token quota. A limit of zero is not enforced.
+	TokenQuotaLimits struct {
+		Soft int `json:"soft" koanf:"soft"`
+		Hard int `json:"hard" koanf:"hard"`
+	}
+	// ErrorResponse customizes the responses of an OAuth 2.0 error for a client or for all clients.
+	ErrorResponse struct {
+		ClientID string              `json:"client_id" koanf:"client_id"`
+		Error    string              `json:"error" koanf:"error"`
+		Response ErrorResponseFields `json:"response" koanf:"response"`
+	}
+	// ErrorResponseFields are the fields which replace those of a customized error response. Empty fields are not
+	// replaced.
+	ErrorResponseFields struct {
+		Error            string `json:"error" koanf:"error"`
+		ErrorDescription string `json:"error_description" koanf:"error_description"`
+		ErrorURI         string `json:"error_uri" koanf:"error_uri"`
+	}
+	// ErrorResponses are the customized OAuth 2.0 error responses.
+	ErrorResponses []ErrorResponse
+	// HSMPartition stores key sets on a different token of the Hardware Security Module than the one configured in
+	// `hsm.slot` or `hsm.token_label`.
+	HSMPartition struct {
+		KeySets    []string `json:"key_sets" koanf:"key_sets"`
+		Slot       *int     `json:"slot" koanf:"slot"`
+		TokenLabel string   `json:"token_label" koanf:"token_label"`
+		Pin        string   `json:"pin" koanf:"pin"`
+	}
 )
 
+// Apply adds the credentials to the request.
+func (a *Auth) Apply(req *http.Request) error {
+	if a == nil {
+		return nil
+	}
+
+	switch a.Type {
+	case "api_key":
+		switch a.Config.In {
+		case "header":
+			req.Header.Set(a.Config.Name, a.Config.Value)
+		case "cookie":
+			req.AddCookie(&http.Cookie{Name: a.Config.Name, Value: a.Config.Value})
+		}
+	default:
+		return errors.Errorf("unsupported auth type %q", a.Type)
+	}
+	return nil
+}
+
 func (p *DefaultProvider) getHookConfig(ctx context.Context, key string) *HookConfig {
 	if hookURL := p.getProvider(ctx).RequestURIF(key, nil); hookURL != nil {
 		return &HookConfig{
@@ -532,14 +872,264 @@ func (p *DefaultProvider) TokenRefreshHookConfig(ctx context.Context) *HookConfi
 	return p.getHookConfig(ctx, KeyRefreshTokenHook)
 }
 
+func (p *DefaultProvider) AuthorizationRequestHookConfig(ctx context.Context) *HookConfig {
+	return p.getHookConfig(ctx, KeyAuthorizationRequestHook)
+}
+
+// AuthorizationRequestHookType returns "webhook" if the authorization request hook is a webhook, or "opa" if it
+// is the data API of an Open Policy Agent.
+func (p *DefaultProvider) AuthorizationRequestHookType(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyAuthorizationRequestHookType, "webhook")
+}
+
 func (p *DefaultProvider) DbIgnoreUnknownTableColumns() bool {
 	return p.p.Bool(KeyDBIgnoreUnknownTableColumns)
 }
 
+func (p *DefaultProvider) DBReadReplicaDSNs() []string {
+	return p.p.Strings(KeyDBReadReplicas)
+}
+
+// DBPool returns the connection pool settings of the database configured in dsn.
+func (p *DefaultProvider) DBPool() DBPool {
+	return p.dbPool(KeyDBPool)
+}
+
+// DBReadReplicaPool returns the connection pool settings of every read replica.
+func (p *DefaultProvider) DBReadReplicaPool() DBPool {
+	return p.dbPool(KeyDBReadReplicaPool)
+}
+
+// DBRedisURL returns the URL of the Redis server which stores short-lived OAuth 2.0 sessions and caches access
+// tokens. Redis is not used if the URL is empty.
+func (p *DefaultProvider) DBRedisURL() string {
+	return p.p.String(KeyDBRedisURL)
+}
+
+// DBRedisEphemeralSessions returns whether authorization codes, PKCE and OpenID Connect sessions are stored in
+// Redis instead of the database.
+func (p *DefaultProvider) DBRedisEphemeralSessions() bool {
+	return p.p.BoolF(KeyDBRedisEphemeralSessions, true)
+}
+
+// DBRedisAccessTokenCache returns whether access token lookups are cached in Redis.
+func (p *DefaultProvider) DBRedisAccessTokenCache() bool {
+	return p.p.BoolF(KeyDBRedisAccessTokenCache, true)
+}
+
+// DBRedisRememberedConsentCache returns whether remembered consent lookups are cached in Redis.
+func (p *DefaultProvider) DBRedisRememberedConsentCache() bool {
+	return p.p.BoolF(KeyDBRedisRememberedConsentCache, true)
+}
+
+// DBRedisReplayProtection returns whether used client assertion and JWT bearer grant jti values and OpenID Connect
+// nonces are stored in Redis instead of the database.
+func (p *DefaultProvider) DBRedisReplayProtection() bool {
+	return p.p.BoolF(KeyDBRedisReplayProtection, true)
+}
+
+// DBClientCacheEnabled returns whether OAuth 2.0 Clients are cached in memory.
+func (p *DefaultProvider) DBClientCacheEnabled() bool {
+	return p.p.Bool(KeyDBClientCacheEnabled)
+}
+
+// DBClientCacheMaxClients returns the maximum number of OAuth 2.0 Clients cached in memory.
+func (p *DefaultProvider) DBClientCacheMaxClients() int {
+	return p.p.IntF(KeyDBClientCacheMaxClients, 10000)
+}
+
+// DBClientCacheTTL returns how long OAuth 2.0 Clients are cached in memory.
+func (p *DefaultProvider) DBClientCacheTTL() time.Duration {
+	return p.p.DurationF(KeyDBClientCacheTTL, 30*time.Second)
+}
+
+// DBTokenPartitionsInterval returns the time range covered by each partition of the access and refresh token
+// tables.
+func (p *DefaultProvider) DBTokenPartitionsInterval() time.Duration {
+	return p.p.DurationF(KeyDBTokenPartitionsInterval, 24*time.Hour)
+}
+
+// DBTokenPartitionsPremake returns the number of partitions of the access and refresh token tables which are created
+// ahead of time.
+func (p *DefaultProvider) DBTokenPartitionsPremake() int {
+	return p.p.IntF(KeyDBTokenPartitionsPremake, 3)
+}
+
+// DBFollowerReadsEnabled returns whether access token lookups use follower reads on CockroachDB.
+func (p *DefaultProvider) DBFollowerReadsEnabled() bool {
+	return p.p.Bool(KeyDBFollowerReadsEnabled)
+}
+
+// DBFollowerReadsMaxStaleness returns the maximum staleness of bounded staleness reads. Follower reads use the
+// follower read timestamp of CockroachDB if it is zero.
+func (p *DefaultProvider) DBFollowerReadsMaxStaleness() time.Duration {
+	return p.p.Duration(KeyDBFollowerReadsMaxStaleness)
+}
+
+// DBStatementCommentsEnabled returns whether SQL statements are annotated with the function which issued them.
+func (p *DefaultProvider) DBStatementCommentsEnabled() bool {
+	return p.p.Bool(KeyDBStatementCommentsEnabled)
+}
+
+// DBStatementCommentsTraceparent returns whether SQL statements are additionally annotated with the W3C trace
+// context of the request.
+func (p *DefaultProvider) DBStatementCommentsTraceparent() bool {
+	return p.p.Bool(KeyDBStatementCommentsTraceparent)
+}
+
+// DBSlowQueryThreshold returns the duration after which SQL statements are logged as slow. Slow statements are not
+// logged if it is zero.
+func (p *DefaultProvider) DBSlowQueryThreshold() time.Duration {
+	return p.p.Duration(KeyDBSlowQueryThreshold)
+}
+
+// DBSQLiteWAL returns whether SQLite databases use write-ahead logging.
+func (p *DefaultProvider) DBSQLiteWAL() bool {
+	return p.p.Bool(KeyDBSQLiteWAL)
+}
+
+// DBSQLiteBusyTimeout returns how long SQLite waits for locks held by other connections before failing with
+// "database is locked".
+func (p *DefaultProvider) DBSQLiteBusyTimeout() time.Duration {
+	return p.p.DurationF(KeyDBSQLiteBusyTimeout, 5*time.Second)
+}
+
+// DBBatchTokenWritesEnabled returns whether the tokens issued in a transaction are written together when the
+// transaction is committed.
+func (p *DefaultProvider) DBBatchTokenWritesEnabled() bool {
+	return p.p.Bool(KeyDBBatchTokenWritesEnabled)
+}
+
+func (p *DefaultProvider) dbPool(key string) DBPool {
+	return DBPool{
+		MaxOpenConns:    p.p.Int(key + "." + KeySuffixDBPoolMaxOpenConns),
+		MaxIdleConns:    p.p.Int(key + "." + KeySuffixDBPoolMaxIdleConns),
+		MaxConnLifetime: p.p.Duration(key + "." + KeySuffixDBPoolMaxConnLifetime),
+		MaxConnIdleTime: p.p.Duration(key + "." + KeySuffixDBPoolMaxConnIdleTime),
+	}
+}
+
 func (p *DefaultProvider) SubjectIdentifierAlgorithmSalt(ctx context.Context) string {
 	return p.getProvider(ctx).String(KeySubjectIdentifierAlgorithmSalt)
 }
 
+// OIDCSDJWTIDTokenClients returns the IDs of the clients which receive ID tokens as SD-JWT.
+func (p *DefaultProvider) OIDCSDJWTIDTokenClients(ctx context.Context) []string {
+	return p.getProvider(ctx).Strings(KeyOIDCSDJWTIDTokenClients)
+}
+
+// OIDCSIOPClientID returns the client ID of Ory Hydra towards wallets which respond to SIOPv2 authorization
+// requests. It defaults to the issuer URL.
+func (p *DefaultProvider) OIDCSIOPClientID(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyOIDCSIOPClientID, p.IssuerURL(ctx).String())
+}
+
+// OIDCSIOPPresentationDefinition returns the presentation definition which the verifiable presentations of SIOPv2
+// responses must satisfy, or nil if SIOPv2 responses do not need to present credentials.
+func (p *DefaultProvider) OIDCSIOPPresentationDefinition(ctx context.Context) json.RawMessage {
+	definition := map[string]interface{}{}
+	if err := p.getProvider(ctx).Unmarshal(KeyOIDCSIOPPresentationDefinition, &definition); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOIDCSIOPPresentationDefinition)
+		return nil
+	}
+	if len(definition) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(definition)
+	if err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be encoded.", KeyOIDCSIOPPresentationDefinition)
+		return nil
+	}
+	return raw
+}
+
+// OIDCSIOPTrustedIssuers returns the issuers of verifiable credentials which are accepted in SIOPv2 responses.
+func (p *DefaultProvider) OIDCSIOPTrustedIssuers(ctx context.Context) []SIOPTrustedIssuer {
+	var issuers []SIOPTrustedIssuer
+	if err := p.getProvider(ctx).Unmarshal(KeyOIDCSIOPTrustedIssuers, &issuers); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOIDCSIOPTrustedIssuers)
+		return nil
+	}
+	return issuers
+}
+
+// OIDCClaimsTemplates returns the templates of the claims of the tokens of the given configuration key, keyed by
+// the name of the claim.
+func (p *DefaultProvider) OIDCClaimsTemplates(ctx context.Context, key string) map[string]string {
+	templates := map[string]string{}
+	if err := p.getProvider(ctx).Unmarshal(key, &templates); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", key)
+		return nil
+	}
+	return templates
+}
+
+// PostLogoutRedirectURIsAllowList returns the post-logout redirect URIs which all clients may use in addition to
+// their own.
+func (p *DefaultProvider) PostLogoutRedirectURIsAllowList(ctx context.Context) []string {
+	return p.getProvider(ctx).Strings(KeyPostLogoutRedirectURIsAllowList)
+}
+
+// PostLogoutRedirectSubdomainWildcards returns whether the host of post-logout redirect URIs may start with a "*."
+// label which matches any subdomain.
+func (p *DefaultProvider) PostLogoutRedirectSubdomainWildcards(ctx context.Context) bool {
+	return p.getProvider(ctx).BoolF(KeyPostLogoutRedirectSubdomainWildcards, false)
+}
+
+// PostLogoutRedirectSameOrigin returns whether clients identified by the id_token_hint may be redirected to any
+// URI on the origin of one of their redirect or post-logout redirect URIs.
+func (p *DefaultProvider) PostLogoutRedirectSameOrigin(ctx context.Context) bool {
+	return p.getProvider(ctx).BoolF(KeyPostLogoutRedirectSameOrigin, false)
+}
+
+// I18nDefaultLocale returns the locale of the messages shown to users which prefer none of the configured locales.
+func (p *DefaultProvider) I18nDefaultLocale(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyI18nDefaultLocale, "en")
+}
+
+// I18nMessages returns the translated messages keyed by locale and message ID.
+func (p *DefaultProvider) I18nMessages(ctx context.Context) map[string]map[string]string {
+	messages := map[string]map[string]string{}
+	if err := p.getProvider(ctx).Unmarshal(KeyI18nMessages, &messages); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyI18nMessages)
+		return nil
+	}
+	return messages
+}
+
+// UMAPolicyHookConfig returns the hook which decides which of the permissions requested with an UMA 2.0 permission
+// ticket are granted to the requesting party, or nil if only resource owners are granted access to their resources.
+func (p *DefaultProvider) UMAPolicyHookConfig(ctx context.Context) *HookConfig {
+	return p.getHookConfig(ctx, KeyUMAPolicyHook)
+}
+
+// UMAPermissionTicketLifespan returns how long UMA 2.0 permission tickets can be exchanged for requesting party
+// tokens.
+func (p *DefaultProvider) UMAPermissionTicketLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyUMAPermissionTicketLifespan, 5*time.Minute)
+}
+
+// GNAPEnabled returns whether the experimental GNAP grant endpoints are served.
+func (p *DefaultProvider) GNAPEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyGNAPEnabled)
+}
+
+// GNAPGrantRequestLifespan returns how long a GNAP grant request can be continued.
+func (p *DefaultProvider) GNAPGrantRequestLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyGNAPGrantRequestLifespan, 30*time.Minute)
+}
+
+// OIDCNonceReplayProtection returns whether the nonce of an OpenID Connect authentication request may only be used
+// once by a client while the ID tokens issued for it are valid.
+func (p *DefaultProvider) OIDCNonceReplayProtection(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyOIDCNonceReplayProtection)
+}
+
 func (p *DefaultProvider) OIDCDiscoverySupportedClaims(ctx context.Context) []string {
 	return stringslice.Unique(
 		append(
@@ -550,12 +1140,11 @@ func (p *DefaultProvider) OIDCDiscoverySupportedClaims(ctx context.Context) []st
 }
 
 func (p *DefaultProvider) OIDCDiscoverySupportedScope(ctx context.Context) []string {
-	return stringslice.Unique(
-		append(
-			[]string{"offline_access", "offline", "openid"},
-			p.getProvider(ctx).Strings(KeyOIDCDiscoverySupportedScope)...,
-		),
-	)
+	scope := p.getProvider(ctx).Strings(KeyOIDCDiscoverySupportedScope)
+	if c := p.issuerCustomization(ctx); c != nil && len(c.OIDCDiscovery.SupportedScope) > 0 {
+		scope = c.OIDCDiscovery.SupportedScope
+	}
+	return stringslice.Unique(append([]string{"offline_access", "offline", "openid"}, scope...))
 }
 
 func (p *DefaultProvider) OIDCDiscoveryUserinfoEndpoint(ctx context.Context) *url.URL {
@@ -564,6 +1153,31 @@ func (p *DefaultProvider) OIDCDiscoveryUserinfoEndpoint(ctx context.Context) *ur
 	)
 }
 
+// OIDCDiscoveryExtraFields returns the fields which are added to the OpenID Connect Discovery document, replacing
+// the advertised values of fields with the same name. The extra fields of the issuer's customization take precedence
+// over the globally configured ones.
+func (p *DefaultProvider) OIDCDiscoveryExtraFields(ctx context.Context) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if err := p.getProvider(ctx).Unmarshal(KeyOIDCDiscoveryExtraFields, &fields); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOIDCDiscoveryExtraFields)
+	}
+	if c := p.issuerCustomization(ctx); c != nil {
+		for k, v := range c.OIDCDiscovery.ExtraFields {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+func (p *DefaultProvider) OIDCDiscoveryCacheTTL(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyOIDCDiscoveryCacheTTL, time.Minute)
+}
+
+func (p *DefaultProvider) StatsCacheTTL(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyStatsCacheTTL, time.Minute)
+}
+
 func (p *DefaultProvider) GetSendDebugMessagesToClients(ctx context.Context) bool {
 	return p.getProvider(ctx).Bool(KeyExposeOAuth2Debug)
 }
@@ -576,6 +1190,65 @@ func (p *DefaultProvider) GetEnforcePKCEForPublicClients(ctx context.Context) bo
 	return p.getProvider(ctx).Bool(KeyPKCEEnforcedForPublicClients)
 }
 
+// PluginPaths returns the paths of the Go plugins loaded on startup.
+func (p *DefaultProvider) PluginPaths() []string {
+	return p.getProvider(contextx.RootContext).Strings(KeyPluginPaths)
+}
+
+// PluginWASMModules returns the WebAssembly modules loaded on startup.
+func (p *DefaultProvider) PluginWASMModules() ([]WASMModule, error) {
+	var modules []WASMModule
+	if err := p.getProvider(contextx.RootContext).Unmarshal(KeyPluginWASMModules, &modules); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range modules {
+		if modules[i].MaxMemoryPages == 0 {
+			modules[i].MaxMemoryPages = 256
+		}
+		if modules[i].Timeout == 0 {
+			modules[i].Timeout = 100 * time.Millisecond
+		}
+	}
+	return modules, nil
+}
+
+// ClockSkew returns the tolerated clock skew when validating the exp, iat and nbf claims of JWTs issued by others.
+func (p *DefaultProvider) ClockSkew(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyClockSkew, 0)
+}
+
+// JWTHeadersX5T returns whether the x5t and x5t#S256 header parameters are added to JWTs signed with keys which
+// have a certificate.
+func (p *DefaultProvider) JWTHeadersX5T(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyJWTHeadersX5T)
+}
+
+// JWTHeadersExtra returns the header parameters which are added to all issued JWTs.
+func (p *DefaultProvider) JWTHeadersExtra(ctx context.Context) map[string]interface{} {
+	extra := map[string]interface{}{}
+	if err := p.getProvider(ctx).Unmarshal(KeyJWTHeadersExtra, &extra); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyJWTHeadersExtra)
+	}
+	return extra
+}
+
+// JWTHeadersType returns the typ header parameter of JWTs of the configured key, or an empty string if it is not
+// configured.
+func (p *DefaultProvider) JWTHeadersType(ctx context.Context, key string) string {
+	return p.getProvider(ctx).String(key)
+}
+
+// JWTClaimsProfile returns the profile of the claims of JWT access tokens, either "default" or "minimal".
+func (p *DefaultProvider) JWTClaimsProfile(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyJWTClaimsProfile, "default")
+}
+
+// JWTHashSubject returns whether the subject of JWT access tokens is replaced by its salted hash.
+func (p *DefaultProvider) JWTHashSubject(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyJWTHashSubject)
+}
+
 func (p *DefaultProvider) CGroupsV1AutoMaxProcsEnabled() bool {
 	return p.getProvider(contextx.RootContext).Bool(KeyCGroupsV1AutoMaxProcsEnabled)
 }
@@ -613,6 +1286,51 @@ func (p *DefaultProvider) HSMKeySetPrefix() string {
 	return p.getProvider(contextx.RootContext).String(HSMKeySetPrefix)
 }
 
+// HSMKeySets returns the key sets which are stored on the Hardware Security Module. If empty, every key set is
+// generated on the Hardware Security Module.
+func (p *DefaultProvider) HSMKeySets(ctx context.Context) []string {
+	return p.getProvider(ctx).Strings(HSMKeySets)
+}
+
+// HSMRotationGracePeriod returns how long the previous keys of a key set rotated on the Hardware Security Module stay
+// published after they were superseded.
+func (p *DefaultProvider) HSMRotationGracePeriod(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(HSMRotationGracePeriod, 24*time.Hour)
+}
+
+// HSMKeySetCacheTTL returns how long key sets read from the Hardware Security Module are cached in memory. Key sets
+// are not cached if zero.
+func (p *DefaultProvider) HSMKeySetCacheTTL(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(HSMKeySetCacheTTL, time.Minute)
+}
+
+// HSMImportKeys returns whether key pairs which were provisioned on the Hardware Security Module out-of-band can be
+// added to key sets.
+func (p *DefaultProvider) HSMImportKeys(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(HSMImportKeys)
+}
+
+// HSMPartitions returns the partitions of the Hardware Security Module which key sets are stored on instead of the
+// token configured in `hsm.slot` or `hsm.token_label`. Partitions without a PIN use `hsm.pin`.
+func (p *DefaultProvider) HSMPartitions() ([]HSMPartition, error) {
+	var partitions []HSMPartition
+	if err := p.getProvider(contextx.RootContext).Unmarshal(HSMPartitions, &partitions); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range partitions {
+		if partitions[i].Pin == "" {
+			partitions[i].Pin = p.HSMPin()
+		}
+	}
+	return partitions, nil
+}
+
+// HSMPublishCertificates returns whether the certificate chains stored on the Hardware Security Module alongside key
+// pairs are published with their keys.
+func (p *DefaultProvider) HSMPublishCertificates(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(HSMPublishCertificates)
+}
+
 func (p *DefaultProvider) GetGrantTypeJWTBearerIssuedDateOptional(ctx context.Context) bool {
 	return p.getProvider(ctx).Bool(KeyOAuth2GrantJWTIssuedDateOptional)
 }
@@ -621,6 +1339,184 @@ func (p *DefaultProvider) GetJWTMaxDuration(ctx context.Context) time.Duration {
 	return p.getProvider(ctx).DurationF(KeyOAuth2GrantJWTMaxDuration, time.Hour*24*30)
 }
 
+// GrantJWTLoginAssertions returns whether login requests may be accepted by assertions of trusted issuers.
+func (p *DefaultProvider) GrantJWTLoginAssertions(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyOAuth2GrantJWTLoginAssertions)
+}
+
+// GrantJWTExpiryWarningBefore returns how long before their expiry trust relationships of JWT issuers are reported as
+// expiring. Zero disables the warnings.
+func (p *DefaultProvider) GrantJWTExpiryWarningBefore(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).Duration(KeyOAuth2GrantJWTExpiryWarningBefore)
+}
+
+// GrantJWTExpiryWarningInterval returns how often trust relationships of JWT issuers are checked for their expiry.
+func (p *DefaultProvider) GrantJWTExpiryWarningInterval(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyOAuth2GrantJWTExpiryWarningInterval, time.Hour)
+}
+
+// OAuth2MetricsClientIDsEnabled returns whether the OAuth 2.0 request metrics are labeled with the client ID.
+func (p *DefaultProvider) OAuth2MetricsClientIDsEnabled() bool {
+	return p.getProvider(contextx.RootContext).Bool(KeyOAuth2MetricsClientIDsEnabled)
+}
+
+// OAuth2MetricsClientIDsAllowed returns the client IDs which the OAuth 2.0 request metrics are labeled with. All
+// client IDs are allowed if it is empty.
+func (p *DefaultProvider) OAuth2MetricsClientIDsAllowed() []string {
+	return p.getProvider(contextx.RootContext).Strings(KeyOAuth2MetricsClientIDsAllowed)
+}
+
+// OAuth2MetricsClientIDsMax returns how many distinct client IDs the OAuth 2.0 request metrics are labeled with.
+func (p *DefaultProvider) OAuth2MetricsClientIDsMax() int {
+	return p.getProvider(contextx.RootContext).IntF(KeyOAuth2MetricsClientIDsMax, 100)
+}
+
+// OAuth2MaxAuthorizationURLLength returns how long the query of an authorization request, or its body if it is
+// posted, can be in bytes.
+func (p *DefaultProvider) OAuth2MaxAuthorizationURLLength(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyOAuth2MaxAuthorizationURLLength, 32768)
+}
+
+// OAuth2MaxRequestObjectSize returns how large a request object passed by value or by reference can be in bytes.
+func (p *DefaultProvider) OAuth2MaxRequestObjectSize(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyOAuth2MaxRequestObjectSize, 16384)
+}
+
+// OAuth2MaxDynamicClientRegistrationSize returns how large the body of a dynamic client registration request can be in
+// bytes.
+func (p *DefaultProvider) OAuth2MaxDynamicClientRegistrationSize(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyOAuth2MaxDynamicClientRegistrationSize, 65536)
+}
+
+// ClientCredentialsTokenCacheMaxEntries returns how many access tokens issued by the client credentials grant are
+// cached for the clients which opted in.
+func (p *DefaultProvider) ClientCredentialsTokenCacheMaxEntries() int {
+	return p.getProvider(contextx.RootContext).IntF(KeyClientCredentialsTokenCacheMaxEntries, 10000)
+}
+
+// OAuth2FAPIEnabled returns whether the FAPI 1.0 Advanced requirements are enforced for all clients.
+func (p *DefaultProvider) OAuth2FAPIEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyOAuth2FAPIEnabled)
+}
+
+// OAuth2ClientAttestationHookConfig returns the hook which validates client attestation evidence, or nil if none is
+// configured.
+func (p *DefaultProvider) OAuth2ClientAttestationHookConfig(ctx context.Context) *HookConfig {
+	return p.getHookConfig(ctx, KeyOAuth2ClientAttestationHook)
+}
+
+// OAuth2ClientAttestationRequirement returns the attestation requirement of the client, or nil if the client does
+// not need to present attestation evidence.
+func (p *DefaultProvider) OAuth2ClientAttestationRequirement(ctx context.Context, clientID string) *ClientAttestationRequirement {
+	var requirements []ClientAttestationRequirement
+	if err := p.getProvider(ctx).Unmarshal(KeyOAuth2ClientAttestationClients, &requirements); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOAuth2ClientAttestationClients)
+		return nil
+	}
+	for k := range requirements {
+		if requirements[k].ClientID == clientID {
+			return &requirements[k]
+		}
+	}
+	return nil
+}
+
+// OAuth2TokenQuota returns the token quota of the client. Clients which are not configured explicitly get the quota
+// with client ID "*", if any.
+func (p *DefaultProvider) OAuth2TokenQuota(ctx context.Context, clientID string) *TokenQuota {
+	var quotas []TokenQuota
+	if err := p.getProvider(ctx).Unmarshal(KeyOAuth2TokenQuotas, &quotas); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOAuth2TokenQuotas)
+		return nil
+	}
+
+	var fallback *TokenQuota
+	for k := range quotas {
+		switch quotas[k].ClientID {
+		case clientID:
+			return &quotas[k]
+		case "*":
+			fallback = &quotas[k]
+		}
+	}
+	return fallback
+}
+
+// OAuth2ErrorResponses returns the customized OAuth 2.0 error responses.
+func (p *DefaultProvider) OAuth2ErrorResponses(ctx context.Context) ErrorResponses {
+	var responses ErrorResponses
+	if err := p.getProvider(ctx).Unmarshal(KeyOAuth2ErrorResponses, &responses); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyOAuth2ErrorResponses)
+		return nil
+	}
+	return responses
+}
+
+// Find returns the customization of the error for the client. Errors which are not customized for the client
+// explicitly use the customization with client ID "*", if any.
+func (r ErrorResponses) Find(clientID, code string) *ErrorResponse {
+	var fallback *ErrorResponse
+	for k := range r {
+		if r[k].Error != code {
+			continue
+		}
+		switch r[k].ClientID {
+		case clientID:
+			return &r[k]
+		case "*":
+			fallback = &r[k]
+		}
+	}
+	return fallback
+}
+
+// OAuth2FallbackUIEnabled returns whether Ory Hydra serves a minimal login and consent UI at the default login and
+// consent URLs.
+func (p *DefaultProvider) OAuth2FallbackUIEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyOAuth2FallbackUIEnabled)
+}
+
+// OAuth2FallbackUIIdentityHookConfig returns the hook which checks the credentials entered at the login page of the
+// fallback UI, or nil if none is configured.
+func (p *DefaultProvider) OAuth2FallbackUIIdentityHookConfig(ctx context.Context) *HookConfig {
+	return p.getHookConfig(ctx, KeyOAuth2FallbackUIIdentityHook)
+}
+
+// OAuth2ClientCORSEnabled returns whether the allowed CORS origins of a client may make cross-origin requests to the
+// token, revocation and userinfo endpoints even if CORS is disabled for the public endpoints.
+func (p *DefaultProvider) OAuth2ClientCORSEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyOAuth2ClientCORSEnabled)
+}
+
+// SessionFingerprintAttributes returns the request attributes which the user agent must present unchanged for its
+// login session to be remembered.
+func (p *DefaultProvider) SessionFingerprintAttributes(ctx context.Context) []string {
+	return p.getProvider(ctx).Strings(KeySessionFingerprintAttributes)
+}
+
+// OAuth2RevocationNotifications returns the resource servers which are notified when tokens issued for their audience
+// are revoked.
+func (p *DefaultProvider) OAuth2RevocationNotifications(ctx context.Context) ([]RevocationNotification, error) {
+	var hooks []RevocationNotification
+	if err := p.getProvider(ctx).Unmarshal(KeyOAuth2RevocationNotifications, &hooks); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range hooks {
+		if hooks[i].MaxRetries == nil {
+			hooks[i].MaxRetries = pointerx.Int(5)
+		}
+	}
+	return hooks, nil
+}
+
+// OAuth2IntrospectionMetadata returns the client and grant metadata which is added to introspection responses.
+func (p *DefaultProvider) OAuth2IntrospectionMetadata(ctx context.Context) []string {
+	return p.getProvider(ctx).Strings(KeyOAuth2IntrospectionMetadata)
+}
+
 func (p *DefaultProvider) CookieDomain(ctx context.Context) string {
 	return p.getProvider(ctx).String(KeyCookieDomain)
 }
@@ -649,3 +1545,195 @@ func (p *DefaultProvider) cookieSuffix(ctx context.Context, key string) string {
 
 	return p.getProvider(ctx).String(key) + suffix
 }
+
+func (p *DefaultProvider) JanitorEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyJanitorEnabled)
+}
+
+func (p *DefaultProvider) JanitorInterval(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyJanitorInterval, time.Hour)
+}
+
+func (p *DefaultProvider) JanitorKeepIfYounger(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyJanitorKeepIfYounger, 0)
+}
+
+func (p *DefaultProvider) JanitorLimit(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyJanitorLimit, 10000)
+}
+
+func (p *DefaultProvider) JanitorBatchSize(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyJanitorBatchSize, 100)
+}
+
+// JanitorRetentionLoginConsentRequests returns how long the janitor keeps unhandled, rejected and timed out login and
+// consent requests. Defaults to janitor.keep_if_younger.
+func (p *DefaultProvider) JanitorRetentionLoginConsentRequests(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyJanitorRetentionLoginConsentRequests, p.JanitorKeepIfYounger(ctx))
+}
+
+// JanitorRetentionTokens returns how long the janitor keeps expired access and refresh tokens. Defaults to
+// janitor.keep_if_younger.
+func (p *DefaultProvider) JanitorRetentionTokens(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyJanitorRetentionTokens, p.JanitorKeepIfYounger(ctx))
+}
+
+// JanitorRetentionConsentSessions returns how long the janitor keeps granted consent sessions. Consent sessions are
+// kept forever if it is zero.
+func (p *DefaultProvider) JanitorRetentionConsentSessions(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).Duration(KeyJanitorRetentionConsentSessions)
+}
+
+// JanitorRetentionAuditEvents returns how long the janitor keeps audit events. Audit events are kept forever if it is
+// zero.
+func (p *DefaultProvider) JanitorRetentionAuditEvents(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).Duration(KeyJanitorRetentionAuditEvents)
+}
+
+// JWKSPruningKeepNewest returns how many of the newest keys of each key set are never pruned. Key sets are not pruned
+// if it is zero.
+func (p *DefaultProvider) JWKSPruningKeepNewest(ctx context.Context) int {
+	return p.getProvider(ctx).Int(KeyJWKSPruningKeepNewest)
+}
+
+// JWKSPruningKeepIfYounger returns how long keys are kept even if they are not among the newest keys of their set.
+func (p *DefaultProvider) JWKSPruningKeepIfYounger(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).Duration(KeyJWKSPruningKeepIfYounger)
+}
+
+// JWKSGenerationRSABits returns the minimum and maximum modulus size of RSA keys generated by the API.
+func (p *DefaultProvider) JWKSGenerationRSABits(ctx context.Context) (min, max int) {
+	return p.getProvider(ctx).IntF(KeyJWKSGenerationRSAMinBits, 2048), p.getProvider(ctx).IntF(KeyJWKSGenerationRSAMaxBits, 8192)
+}
+
+// JWKSGenerationCurves returns the curves of EC and OKP keys generated by the API.
+func (p *DefaultProvider) JWKSGenerationCurves(ctx context.Context) []string {
+	return p.getProvider(ctx).StringsF(KeyJWKSGenerationCurves, []string{"P-256", "P-384", "P-521", "Ed25519"})
+}
+
+// IDTokenSigningKeySet returns the JSON Web Key Set which signs ID tokens and other OpenID Connect responses.
+func (p *DefaultProvider) IDTokenSigningKeySet(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyJWKSIDTokenSigningKeySet, x.OpenIDConnectKeyName)
+}
+
+// AccessTokenSigningKeySet returns the JSON Web Key Set which signs JWT access tokens.
+func (p *DefaultProvider) AccessTokenSigningKeySet(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyJWKSAccessTokenSigningKeySet, x.OAuth2JWTKeyName)
+}
+
+// LogoutTokenSigningKeySet returns the JSON Web Key Set which signs back-channel logout tokens. It defaults to the
+// key set of ID tokens.
+func (p *DefaultProvider) LogoutTokenSigningKeySet(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyJWKSLogoutTokenSigningKeySet, p.IDTokenSigningKeySet(ctx))
+}
+
+func (p *DefaultProvider) AuditEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyAuditEnabled)
+}
+
+func (p *DefaultProvider) AuditActorHeader(ctx context.Context) string {
+	return p.getProvider(ctx).String(KeyAuditActorHeader)
+}
+
+func (p *DefaultProvider) AuditSinkConfig(ctx context.Context) *HookConfig {
+	return p.getHookConfig(ctx, KeyAuditSink)
+}
+
+func (p *DefaultProvider) AuditSecurityLogEnabled() bool {
+	return p.getProvider(contextx.RootContext).Bool(KeyAuditSecurityLogEnabled)
+}
+
+func (p *DefaultProvider) AuditSecurityLogSinks() ([]SecurityLogSink, error) {
+	var sinks []SecurityLogSink
+	if err := p.getProvider(contextx.RootContext).Unmarshal(KeyAuditSecurityLogSinks, &sinks); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sinks, nil
+}
+
+func (p *DefaultProvider) AuditSecurityLogBufferSize() int {
+	return p.getProvider(contextx.RootContext).IntF(KeyAuditSecurityLogBufferSize, 10000)
+}
+
+func (p *DefaultProvider) AuditSecurityLogFlushInterval() time.Duration {
+	return p.getProvider(contextx.RootContext).DurationF(KeyAuditSecurityLogFlushInterval, time.Second)
+}
+
+// AuditSecurityLogRedactFields returns the fields of security audit log entries which are redacted.
+func (p *DefaultProvider) AuditSecurityLogRedactFields() []string {
+	return p.getProvider(contextx.RootContext).Strings(KeyAuditSecurityLogRedactFields)
+}
+
+// AuditSecurityLogRedactMode returns how fields are redacted, either "mask" or "hash".
+func (p *DefaultProvider) AuditSecurityLogRedactMode() string {
+	return p.getProvider(contextx.RootContext).StringF(KeyAuditSecurityLogRedactMode, "mask")
+}
+
+// AuditSuspiciousActivityWebhook returns the webhook which detections of suspicious activity are sent to, or nil if
+// suspicious activity is not detected.
+func (p *DefaultProvider) AuditSuspiciousActivityWebhook() *HookConfig {
+	return p.getHookConfig(contextx.RootContext, KeyAuditSuspiciousActivityWebhook)
+}
+
+// AuditSuspiciousActivityAuthFailures returns the number of failed client authentications of the same client or from
+// the same IP address within the window which are reported as suspicious.
+func (p *DefaultProvider) AuditSuspiciousActivityAuthFailures() int {
+	return p.getProvider(contextx.RootContext).IntF(KeyAuditSuspiciousActivityAuthFailures, 10)
+}
+
+func (p *DefaultProvider) AuditSuspiciousActivityAuthFailureWindow() time.Duration {
+	return p.getProvider(contextx.RootContext).DurationF(KeyAuditSuspiciousActivityAuthFailureWindow, time.Minute)
+}
+
+func (p *DefaultProvider) EventStreamType(ctx context.Context) string {
+	return p.getProvider(ctx).String(KeyEventStreamType)
+}
+
+func (p *DefaultProvider) EventStreamWebhookConfig(ctx context.Context) *HookConfig {
+	return p.getHookConfig(ctx, KeyEventStreamWebhook)
+}
+
+func (p *DefaultProvider) EventStreamKafkaBrokers(ctx context.Context) []string {
+	return p.getProvider(ctx).Strings(KeyEventStreamKafkaBrokers)
+}
+
+func (p *DefaultProvider) EventStreamKafkaTopic(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyEventStreamKafkaTopic, "hydra.events")
+}
+
+func (p *DefaultProvider) EventStreamNATSURL(ctx context.Context) string {
+	return p.getProvider(ctx).String(KeyEventStreamNATSURL)
+}
+
+func (p *DefaultProvider) EventStreamNATSSubject(ctx context.Context) string {
+	return p.getProvider(ctx).StringF(KeyEventStreamNATSSubject, "hydra.events")
+}
+
+func (p *DefaultProvider) EventStreamBatchSize(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyEventStreamBatchSize, 100)
+}
+
+func (p *DefaultProvider) EventStreamFlushInterval(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyEventStreamFlushInterval, time.Second)
+}
+
+func (p *DefaultProvider) EventStreamBufferSize(ctx context.Context) int {
+	return p.getProvider(ctx).IntF(KeyEventStreamBufferSize, 10000)
+}
+
+func (p *DefaultProvider) EventWebhooks(ctx context.Context) ([]EventWebhook, error) {
+	var hooks []EventWebhook
+	if err := p.getProvider(ctx).Unmarshal(KeyEventWebhooks, &hooks); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := range hooks {
+		if hooks[i].MaxRetries == nil {
+			hooks[i].MaxRetries = pointerx.Int(5)
+		}
+	}
+	return hooks, nil
+}
+
+func (p *DefaultProvider) AdminSwaggerUIEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyAdminSwaggerUIEnabled)
+}
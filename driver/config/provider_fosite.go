@@ -18,8 +18,10 @@ import (
 var _ fosite.GlobalSecretProvider = (*DefaultProvider)(nil)
 
 func (p *DefaultProvider) GetGlobalSecret(ctx context.Context) ([]byte, error) {
-	secrets := p.getProvider(ctx).Strings(KeyGetSystemSecret)
+	return p.globalSecret(p.getProvider(ctx).Strings(KeyGetSystemSecret))
+}
 
+func (p *DefaultProvider) globalSecret(secrets []string) ([]byte, error) {
 	if len(secrets) == 0 {
 		p.l.Error("The system secret is not configured. Please provide one in the configuration file or environment variables.")
 		return nil, errors.New("global secret is not configured")
@@ -37,10 +39,12 @@ func (p *DefaultProvider) GetGlobalSecret(ctx context.Context) ([]byte, error) {
 var _ fosite.RotatedGlobalSecretsProvider = (*DefaultProvider)(nil)
 
 func (p *DefaultProvider) GetRotatedGlobalSecrets(ctx context.Context) ([][]byte, error) {
-	secrets := p.getProvider(ctx).Strings(KeyGetSystemSecret)
+	return rotatedGlobalSecrets(p.getProvider(ctx).Strings(KeyGetSystemSecret)), nil
+}
 
+func rotatedGlobalSecrets(secrets []string) [][]byte {
 	if len(secrets) < 2 {
-		return nil, nil
+		return nil
 	}
 
 	var rotated [][]byte
@@ -48,7 +52,19 @@ func (p *DefaultProvider) GetRotatedGlobalSecrets(ctx context.Context) ([][]byte
 		rotated = append(rotated, x.HashStringSecret(secret))
 	}
 
-	return rotated, nil
+	return rotated
+}
+
+// GetGlobalSecrets returns the global secret followed by the rotated global secrets. Unlike calling GetGlobalSecret
+// and GetRotatedGlobalSecrets, it looks up the configuration only once, which matters because it is called for every
+// value that is encrypted or decrypted.
+func (p *DefaultProvider) GetGlobalSecrets(ctx context.Context) ([][]byte, error) {
+	secrets := p.getProvider(ctx).Strings(KeyGetSystemSecret)
+	global, err := p.globalSecret(secrets)
+	if err != nil {
+		return nil, err
+	}
+	return append([][]byte{global}, rotatedGlobalSecrets(secrets)...), nil
 }
 
 var _ fosite.BCryptCostProvider = (*DefaultProvider)(nil)
@@ -83,10 +99,27 @@ func (p *DefaultProvider) GetIDTokenLifespan(ctx context.Context) time.Duration
 
 var _ fosite.AuthorizeCodeLifespanProvider = (*DefaultProvider)(nil)
 
+type authorizeCodeLifespanContextKey struct{}
+
+// WithAuthorizeCodeLifespan returns a context in which GetAuthorizeCodeLifespan returns the given lifespan instead of
+// the configured one. It is used to issue authorization codes with the lifespan of the OAuth 2.0 Client.
+func WithAuthorizeCodeLifespan(ctx context.Context, lifespan time.Duration) context.Context {
+	return context.WithValue(ctx, authorizeCodeLifespanContextKey{}, lifespan)
+}
+
 func (p *DefaultProvider) GetAuthorizeCodeLifespan(ctx context.Context) time.Duration {
+	if lifespan, ok := ctx.Value(authorizeCodeLifespanContextKey{}).(time.Duration); ok {
+		return lifespan
+	}
 	return p.getProvider(ctx).DurationF(KeyAuthCodeLifespan, time.Minute*10)
 }
 
+// GetDerivedAccessTokenLifespan returns the maximum lifespan of JSON Web Tokens which are derived from opaque access
+// tokens.
+func (p *DefaultProvider) GetDerivedAccessTokenLifespan(ctx context.Context) time.Duration {
+	return p.getProvider(ctx).DurationF(KeyDerivedAccessTokenLifespan, time.Minute*5)
+}
+
 var _ fosite.ScopeStrategyProvider = (*DefaultProvider)(nil)
 
 func (p *DefaultProvider) GetScopeStrategy(ctx context.Context) fosite.ScopeStrategy {
@@ -99,6 +132,9 @@ func (p *DefaultProvider) GetScopeStrategy(ctx context.Context) fosite.ScopeStra
 var _ fosite.JWTScopeFieldProvider = (*DefaultProvider)(nil)
 
 func (p *DefaultProvider) GetJWTScopeField(ctx context.Context) jwt.JWTScopeFieldEnum {
+	if p.JWTClaimsProfile(ctx) == "minimal" {
+		return jwt.JWTScopeFieldString
+	}
 	switch strings.ToLower(p.getProvider(ctx).String(KeyJWTScopeClaimStrategy)) {
 	case "string":
 		return jwt.JWTScopeFieldString
@@ -101,6 +101,34 @@ func TestCORSOptions(t *testing.T) {
 	}, conf)
 }
 
+func TestReloadableServeKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	file := t.TempDir() + "/hydra.yaml"
+	write := func(t *testing.T, corsEnabled bool, port int) {
+		require.NoError(t, os.WriteFile(file, []byte(fmt.Sprintf("dsn: memory\nserve:\n  public:\n    port: %d\n    cors:\n      enabled: %t\n", port, corsEnabled)), 0600))
+	}
+	write(t, false, 4444)
+
+	p := MustNew(ctx, logrusx.New("", ""), configx.WithConfigFiles(file), configx.WithContext(ctx))
+	_, enabled := p.CORS(ctx, PublicInterface)
+	require.False(t, enabled)
+
+	write(t, true, 4444)
+	assert.Eventually(t, func() bool {
+		_, enabled := p.CORS(ctx, PublicInterface)
+		return enabled
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The listener can not change at runtime, so the whole change is rejected.
+	write(t, false, 5555)
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, ":4444", p.ListenOn(PublicInterface))
+	_, enabled = p.CORS(ctx, PublicInterface)
+	assert.True(t, enabled)
+}
+
 func TestProviderAdminDisableHealthAccessLog(t *testing.T) {
 	ctx := context.Background()
 	l := logrusx.New("", "")
@@ -400,6 +428,53 @@ func TestLoginConsentURL(t *testing.T) {
 	assert.Equal(t, "http://localhost:3000/#/oauth/consent", p2.ConsentURL(ctx).String())
 }
 
+func TestIssuerCustomization(t *testing.T) {
+	ctx := context.Background()
+	p := MustNew(context.Background(), logrusx.New("", ""))
+	p.MustSet(ctx, KeyLoginURL, "https://login.example.com/login")
+	p.MustSet(ctx, KeyConsentURL, "https://login.example.com/consent")
+	p.MustSet(ctx, KeyOIDCDiscoverySupportedScope, []string{"email"})
+	p.MustSet(ctx, KeyOIDCDiscoveryExtraFields, map[string]interface{}{"service_documentation": "https://example.com/docs", "op_tos_uri": "https://example.com/tos"})
+	p.MustSet(ctx, KeyTenancyIssuers, []map[string]interface{}{{
+		"host": "acme.example.com",
+		"oidc_discovery": map[string]interface{}{
+			"supported_scope": []string{"acme.read"},
+			"extra_fields":    map[string]interface{}{"op_tos_uri": "https://acme.example.com/tos"},
+		},
+		"urls": map[string]interface{}{
+			"login": "https://acme.example.com/login",
+		},
+	}})
+
+	acme := WithSelfURLs(ctx, urlx.ParseOrPanic("https://acme.example.com/"), urlx.ParseOrPanic("https://hydra.example.com/tenants/acme/"))
+	globex := WithSelfURLs(ctx, urlx.ParseOrPanic("https://globex.example.com/"), urlx.ParseOrPanic("https://hydra.example.com/tenants/globex/"))
+
+	t.Run("case=is ignored if tenancy is disabled", func(t *testing.T) {
+		assert.Equal(t, "https://login.example.com/login", p.LoginURL(acme).String())
+		assert.ElementsMatch(t, []string{"offline_access", "offline", "openid", "email"}, p.OIDCDiscoverySupportedScope(acme))
+	})
+
+	p.MustSet(ctx, KeyTenancyEnabled, true)
+
+	t.Run("case=customizes the issuer with the configured host", func(t *testing.T) {
+		assert.Equal(t, "https://acme.example.com/login", p.LoginURL(acme).String())
+		assert.Equal(t, "https://acme.example.com/login", p.RegistrationURL(acme).String())
+		assert.Equal(t, "https://login.example.com/consent", p.ConsentURL(acme).String())
+		assert.ElementsMatch(t, []string{"offline_access", "offline", "openid", "acme.read"}, p.OIDCDiscoverySupportedScope(acme))
+		assert.Equal(t, map[string]interface{}{
+			"service_documentation": "https://example.com/docs",
+			"op_tos_uri":            "https://acme.example.com/tos",
+		}, p.OIDCDiscoveryExtraFields(acme))
+	})
+
+	t.Run("case=does not customize other issuers", func(t *testing.T) {
+		assert.Equal(t, "https://login.example.com/login", p.LoginURL(globex).String())
+		assert.ElementsMatch(t, []string{"offline_access", "offline", "openid", "email"}, p.OIDCDiscoverySupportedScope(globex))
+		assert.Equal(t, "https://example.com/tos", p.OIDCDiscoveryExtraFields(globex)["op_tos_uri"])
+		assert.Equal(t, "https://login.example.com/login", p.LoginURL(ctx).String())
+	})
+}
+
 func TestInfinitRefreshTokenTTL(t *testing.T) {
 	ctx := context.Background()
 	l := logrusx.New("", "")
@@ -432,8 +507,9 @@ func TestHookConfigs(t *testing.T) {
 	c := MustNew(context.Background(), l, configx.SkipValidation())
 
 	for key, getFunc := range map[string]func(context.Context) *HookConfig{
-		KeyRefreshTokenHook: c.TokenRefreshHookConfig,
-		KeyTokenHook:        c.TokenHookConfig,
+		KeyRefreshTokenHook:         c.TokenRefreshHookConfig,
+		KeyTokenHook:                c.TokenHookConfig,
+		KeyAuthorizationRequestHook: c.AuthorizationRequestHookConfig,
 	} {
 		assert.Nil(t, getFunc(ctx))
 		c.MustSet(ctx, key, "")
@@ -465,6 +541,30 @@ func TestHookConfigs(t *testing.T) {
 	}
 }
 
+func TestEventWebhooks(t *testing.T) {
+	ctx := context.Background()
+	l := logrusx.New("", "")
+	l.Logrus().SetOutput(io.Discard)
+	c := MustNew(context.Background(), l, configx.SkipValidation())
+
+	hooks, err := c.EventWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, hooks)
+
+	c.MustSet(ctx, KeyEventWebhooks, []map[string]interface{}{
+		{"url": "http://localhost:8080/events", "secret": "secret", "events": []string{"OAuth2LoginAccepted"}},
+		{"url": "http://localhost:8080/other-events", "max_retries": 0},
+	})
+	hooks, err = c.EventWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, hooks, 2)
+	assert.Equal(t, "http://localhost:8080/events", hooks[0].URL)
+	assert.Equal(t, "secret", hooks[0].Secret)
+	assert.Equal(t, []string{"OAuth2LoginAccepted"}, hooks[0].Events)
+	assert.Equal(t, 5, *hooks[0].MaxRetries)
+	assert.Equal(t, 0, *hooks[1].MaxRetries)
+}
+
 func TestJWTBearer(t *testing.T) {
 	l := logrusx.New("", "")
 	l.Logrus().SetOutput(io.Discard)
@@ -508,4 +608,8 @@ func TestJWTScopeClaimStrategy(t *testing.T) {
 	assert.Equal(t, jwt.JWTScopeFieldString, p.GetJWTScopeField(ctx))
 	p.MustSet(ctx, KeyJWTScopeClaimStrategy, "both")
 	assert.Equal(t, jwt.JWTScopeFieldBoth, p.GetJWTScopeField(ctx))
+
+	assert.Equal(t, "default", p.JWTClaimsProfile(ctx))
+	p.MustSet(ctx, KeyJWTClaimsProfile, "minimal")
+	assert.Equal(t, jwt.JWTScopeFieldString, p.GetJWTScopeField(ctx))
 }
@@ -0,0 +1,59 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	KeyPublicSecurityHeadersEnabled        = "serve.public.security_headers.enabled"
+	KeyPublicSecurityHeadersCSP            = "serve.public.security_headers.content_security_policy"
+	KeyPublicSecurityHeadersReferrerPolicy = "serve.public.security_headers.referrer_policy"
+	KeyPublicSecurityHeadersFrameOptions   = "serve.public.security_headers.frame_options"
+	KeyPublicSecurityHeadersHSTSEnabled    = "serve.public.security_headers.hsts.enabled"
+	KeyPublicSecurityHeadersHSTSMaxAge     = "serve.public.security_headers.hsts.max_age"
+	KeyPublicSecurityHeadersHSTSSubdomains = "serve.public.security_headers.hsts.include_subdomains"
+	KeyPublicSecurityHeadersHSTSPreload    = "serve.public.security_headers.hsts.preload"
+
+	// CSPNoncePlaceholder is replaced with a random nonce in the Content-Security-Policy header.
+	CSPNoncePlaceholder = "{nonce}"
+
+	// DefaultContentSecurityPolicy allows the scripts and styles of the pages, and frames for OpenID Connect
+	// Front-Channel Logout.
+	DefaultContentSecurityPolicy = "default-src 'none'; script-src 'nonce-" + CSPNoncePlaceholder + "'; style-src 'nonce-" + CSPNoncePlaceholder + "'; frame-src https: http:; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+)
+
+// SecurityHeaders configures the security headers of the pages served by Ory Hydra, such as the fallback, error
+// and logout pages.
+type SecurityHeaders struct {
+	Enabled bool
+
+	// ContentSecurityPolicy is the Content-Security-Policy header. Every occurrence of CSPNoncePlaceholder is
+	// replaced with a random nonce per response, which is also set on the scripts and styles of the page.
+	ContentSecurityPolicy string
+
+	ReferrerPolicy string
+	FrameOptions   string
+
+	// HSTSEnabled enables the Strict-Transport-Security header, which is only sent over HTTPS.
+	HSTSEnabled           bool
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+}
+
+func (p *DefaultProvider) PublicSecurityHeaders(ctx context.Context) *SecurityHeaders {
+	return &SecurityHeaders{
+		Enabled:               p.getProvider(ctx).BoolF(KeyPublicSecurityHeadersEnabled, true),
+		ContentSecurityPolicy: p.getProvider(ctx).StringF(KeyPublicSecurityHeadersCSP, DefaultContentSecurityPolicy),
+		ReferrerPolicy:        p.getProvider(ctx).StringF(KeyPublicSecurityHeadersReferrerPolicy, "no-referrer"),
+		FrameOptions:          p.getProvider(ctx).StringF(KeyPublicSecurityHeadersFrameOptions, "DENY"),
+		HSTSEnabled:           p.getProvider(ctx).BoolF(KeyPublicSecurityHeadersHSTSEnabled, true),
+		HSTSMaxAge:            p.getProvider(ctx).DurationF(KeyPublicSecurityHeadersHSTSMaxAge, 365*24*time.Hour),
+		HSTSIncludeSubdomains: p.getProvider(ctx).Bool(KeyPublicSecurityHeadersHSTSSubdomains),
+		HSTSPreload:           p.getProvider(ctx).Bool(KeyPublicSecurityHeadersHSTSPreload),
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ory/x/contextx"
 
@@ -23,6 +24,11 @@ const (
 	KeySuffixSocketGroup            = "socket.group"
 	KeySuffixSocketMode             = "socket.mode"
 	KeySuffixDisableHealthAccessLog = "request_log.disable_for_health"
+	KeySuffixH2CEnabled             = "h2c.enabled"
+	KeySuffixHTTP3Enabled           = "http3.enabled"
+
+	KeyShutdownDrainDelay  = "serve.shutdown.drain_delay"
+	KeyShutdownGracePeriod = "serve.shutdown.grace_period"
 )
 
 var (
@@ -47,7 +53,7 @@ func (iface *servePrefix) Key(suffix string) string {
 	if suffix == KeyRoot {
 		return iface.prefix
 	}
-	return fmt.Sprintf("%s.%s", iface.prefix, suffix)
+	return iface.prefix + "." + suffix
 }
 
 func (iface *servePrefix) String() string {
@@ -70,6 +76,29 @@ func (p *DefaultProvider) SocketPermission(iface ServeInterface) *configx.UnixPe
 	}
 }
 
+// H2CEnabled returns true if the interface serves HTTP/2 without TLS (h2c) in addition to HTTP/1.1.
+func (p *DefaultProvider) H2CEnabled(iface ServeInterface) bool {
+	return p.getProvider(contextx.RootContext).Bool(iface.Key(KeySuffixH2CEnabled))
+}
+
+// HTTP3Enabled returns true if the interface serves HTTP/3 over QUIC on the UDP port with the same number as its
+// TCP port.
+func (p *DefaultProvider) HTTP3Enabled(iface ServeInterface) bool {
+	return p.getProvider(contextx.RootContext).Bool(iface.Key(KeySuffixHTTP3Enabled))
+}
+
+// ShutdownDrainDelay is how long the servers keep accepting requests after the readiness check started failing,
+// so that load balancers stop routing requests to this instance before it shuts down.
+func (p *DefaultProvider) ShutdownDrainDelay() time.Duration {
+	return p.getProvider(contextx.RootContext).DurationF(KeyShutdownDrainDelay, 0)
+}
+
+// ShutdownGracePeriod is how long in-flight requests may take to complete, and buffered events and audit log
+// entries to be flushed, when shutting down.
+func (p *DefaultProvider) ShutdownGracePeriod() time.Duration {
+	return p.getProvider(contextx.RootContext).DurationF(KeyShutdownGracePeriod, 5*time.Second)
+}
+
 func (p *DefaultProvider) CORS(ctx context.Context, iface ServeInterface) (cors.Options, bool) {
 	return p.getProvider(ctx).CORS(iface.Key(KeyRoot), cors.Options{
 		AllowedMethods: []string{
@@ -0,0 +1,100 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	KeyTenancyEnabled = "tenancy.enabled"
+	KeyTenancyIssuers = "tenancy.issuers"
+)
+
+type (
+	// IssuerCustomization overrides the OpenID Connect Discovery document and the user interface URLs for all
+	// requests served under an issuer with the given host.
+	IssuerCustomization struct {
+		Host          string                           `json:"host" koanf:"host"`
+		OIDCDiscovery IssuerOIDCDiscoveryCustomization `json:"oidc_discovery" koanf:"oidc_discovery"`
+		URLs          IssuerURLsCustomization          `json:"urls" koanf:"urls"`
+	}
+	IssuerOIDCDiscoveryCustomization struct {
+		SupportedScope []string               `json:"supported_scope" koanf:"supported_scope"`
+		ExtraFields    map[string]interface{} `json:"extra_fields" koanf:"extra_fields"`
+	}
+	IssuerURLsCustomization struct {
+		Login              string `json:"login" koanf:"login"`
+		Registration       string `json:"registration" koanf:"registration"`
+		Consent            string `json:"consent" koanf:"consent"`
+		Logout             string `json:"logout" koanf:"logout"`
+		Error              string `json:"error" koanf:"error"`
+		PostLogoutRedirect string `json:"post_logout_redirect" koanf:"post_logout_redirect"`
+	}
+)
+
+type selfURLsContextKey struct{}
+
+// selfURLs are the issuer and public URLs of a tenant.
+type selfURLs struct {
+	issuer, public *url.URL
+}
+
+func (p *DefaultProvider) TenancyEnabled(ctx context.Context) bool {
+	return p.getProvider(ctx).Bool(KeyTenancyEnabled)
+}
+
+// WithSelfURLs returns a context in which IssuerURL and PublicURL return the given URLs instead of the configured
+// ones. It is used to serve each tenant under its own issuer.
+func WithSelfURLs(ctx context.Context, issuer, public *url.URL) context.Context {
+	return context.WithValue(ctx, selfURLsContextKey{}, &selfURLs{issuer: issuer, public: public})
+}
+
+func selfURLsFromContext(ctx context.Context) (*selfURLs, bool) {
+	u, ok := ctx.Value(selfURLsContextKey{}).(*selfURLs)
+	return u, ok
+}
+
+// issuerCustomization returns the customization of the issuer of the context, if tenancy is enabled and the host of
+// the issuer URL is configured in tenancy.issuers.
+func (p *DefaultProvider) issuerCustomization(ctx context.Context) *IssuerCustomization {
+	if !p.TenancyEnabled(ctx) {
+		return nil
+	}
+
+	var issuers []IssuerCustomization
+	if err := p.getProvider(ctx).Unmarshal(KeyTenancyIssuers, &issuers); err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyTenancyIssuers)
+		return nil
+	}
+
+	host := p.IssuerURL(ctx).Host
+	for k := range issuers {
+		if strings.EqualFold(issuers[k].Host, host) {
+			return &issuers[k]
+		}
+	}
+	return nil
+}
+
+// issuerURL returns the user interface URL selected by get from the customization of the issuer of the context.
+func (p *DefaultProvider) issuerURL(ctx context.Context, get func(IssuerURLsCustomization) string) (*url.URL, bool) {
+	c := p.issuerCustomization(ctx)
+	if c == nil || get(c.URLs) == "" {
+		return nil, false
+	}
+
+	u, err := url.Parse(get(c.URLs))
+	if err != nil {
+		p.l.WithError(errors.WithStack(err)).
+			Errorf("Configuration value from key %s could not be decoded.", KeyTenancyIssuers)
+		return nil, false
+	}
+	return urlRoot(u), true
+}
@@ -6,6 +6,7 @@ package config
 import (
 	"context"
 	"crypto/tls"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -96,3 +97,55 @@ func (c *tlsConfig) GetCertificateFunc(stopReload <-chan struct{}, log *logrusx.
 	}
 	return nil, tlsx.ErrNoCertificatesConfigured
 }
+
+func (c *tlsConfig) sameCertificate(o *tlsConfig) bool {
+	return c.certPath == o.certPath && c.keyPath == o.keyPath && c.certString == o.certString && c.keyString == o.keyString
+}
+
+// TLSCertificateFunc is like TLS(ctx, iface).GetCertificateFunc, but additionally picks up changes of the configured
+// certificate, for example new paths or base64 encoded certificates, until stopReload is close()'d.
+func (p *DefaultProvider) TLSCertificateFunc(ctx context.Context, iface ServeInterface, stopReload <-chan struct{}, l *logrusx.Logger) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	var (
+		mu      sync.Mutex
+		stopped bool
+		current = p.TLS(ctx, iface).(*tlsConfig)
+		stop    = make(chan struct{})
+	)
+
+	get, err := current.GetCertificateFunc(stop, l)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-stopReload
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		close(stop)
+	}()
+
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		next := p.TLS(ctx, iface).(*tlsConfig)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped || next.sameCertificate(current) {
+			return get(hello)
+		}
+
+		// The configuration is only evaluated once per change, even if it is invalid.
+		current = next
+		nextStop := make(chan struct{})
+		nextGet, err := next.GetCertificateFunc(nextStop, l)
+		if err != nil {
+			l.WithError(err).Error("Failed to load the changed TLS certificate configuration. Using the previously loaded certificates.")
+			return get(hello)
+		}
+
+		l.Infof("Reloaded the TLS certificates of %s after a configuration change.", iface)
+		close(stop)
+		stop, get = nextStop, nextGet
+		return get(hello)
+	}, nil
+}
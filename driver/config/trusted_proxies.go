@@ -0,0 +1,77 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"net"
+
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+)
+
+const (
+	KeySuffixTrustedProxiesCIDRs         = "trusted_proxies.cidrs"
+	KeySuffixTrustedProxiesHeaders       = "trusted_proxies.headers"
+	KeySuffixTrustedProxiesProxyProtocol = "trusted_proxies.proxy_protocol"
+)
+
+// TrustedProxiesConfig configures which peers are trusted to report the IP address of the client, and how.
+type TrustedProxiesConfig interface {
+	// Enabled returns true if at least one trusted proxy is configured.
+	Enabled() bool
+
+	// IsTrusted returns true if the IP address belongs to a trusted proxy.
+	IsTrusted(ip net.IP) bool
+
+	// Headers returns the headers which carry the client IP address, in the order they are checked.
+	Headers() []string
+
+	// ProxyProtocol returns true if trusted proxies may send a PROXY protocol header.
+	ProxyProtocol() bool
+}
+
+var _ TrustedProxiesConfig = (*trustedProxiesConfig)(nil)
+
+type trustedProxiesConfig struct {
+	cidrs         []*net.IPNet
+	headers       []string
+	proxyProtocol bool
+}
+
+func (c *trustedProxiesConfig) Enabled() bool {
+	return len(c.cidrs) > 0
+}
+
+func (c *trustedProxiesConfig) IsTrusted(ip net.IP) bool {
+	for _, cidr := range c.cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *trustedProxiesConfig) Headers() []string {
+	return c.headers
+}
+
+func (c *trustedProxiesConfig) ProxyProtocol() bool {
+	return c.proxyProtocol
+}
+
+func (p *DefaultProvider) TrustedProxies(iface ServeInterface) TrustedProxiesConfig {
+	c := &trustedProxiesConfig{
+		headers:       p.getProvider(contextx.RootContext).StringsF(iface.Key(KeySuffixTrustedProxiesHeaders), []string{x.ClientIPHeaderXForwardedFor}),
+		proxyProtocol: p.getProvider(contextx.RootContext).Bool(iface.Key(KeySuffixTrustedProxiesProxyProtocol)),
+	}
+	for _, raw := range p.getProvider(contextx.RootContext).Strings(iface.Key(KeySuffixTrustedProxiesCIDRs)) {
+		_, cidr, err := net.ParseCIDR(raw)
+		if err != nil {
+			p.l.WithError(err).Errorf("Ignoring the invalid trusted proxy CIDR range %q.", raw)
+			continue
+		}
+		c.cidrs = append(c.cidrs, cidr)
+	}
+	return c
+}
@@ -8,7 +8,10 @@ import (
 	"io/fs"
 
 	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/extension"
+	"github.com/ory/hydra/v2/extension/wasm"
 	"github.com/ory/hydra/v2/fositex"
+	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/configx"
 	"github.com/ory/x/logrusx"
 	"github.com/ory/x/otelx"
@@ -28,6 +31,8 @@ type (
 		extraMigrations  []fs.FS
 		goMigrations     []popx.Migration
 		fositexFactories []fositex.Factory
+		plugins          extension.Plugins
+		clock            x.Clock
 	}
 	OptionsModifier func(*options)
 
@@ -102,6 +107,22 @@ func WithExtraFositeFactories(f ...fositex.Factory) OptionsModifier {
 	}
 }
 
+// WithPlugins registers plugins which are compiled into the binary. They are called before the Go plugins
+// configured in plugins.paths and the WebAssembly modules configured in plugins.wasm.
+func WithPlugins(p ...extension.Plugin) OptionsModifier {
+	return func(o *options) {
+		o.plugins = append(o.plugins, p...)
+	}
+}
+
+// WithClock sets the clock used to issue and validate tokens instead of the clock of the operating system, for
+// example to control the time in tests.
+func WithClock(c x.Clock) OptionsModifier {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
 func New(ctx context.Context, sl *servicelocatorx.Options, opts []OptionsModifier) (Registry, error) {
 	o := newOptions()
 	for _, f := range opts {
@@ -142,6 +163,27 @@ func New(ctx context.Context, sl *servicelocatorx.Options, opts []OptionsModifie
 
 	r.WithExtraFositeFactories(o.fositexFactories)
 
+	if o.clock != nil {
+		r.WithClock(o.clock)
+	}
+
+	plugins, err := extension.Load(c.PluginPaths())
+	if err != nil {
+		l.WithError(err).Error("Unable to load plugins.")
+		return nil, err
+	}
+	modules, err := c.PluginWASMModules()
+	if err != nil {
+		l.WithError(err).Error("Unable to read the WebAssembly modules configuration.")
+		return nil, err
+	}
+	wasmPlugins, err := wasm.Load(ctx, modules)
+	if err != nil {
+		l.WithError(err).Error("Unable to load WebAssembly modules.")
+		return nil, err
+	}
+	r.WithPlugins(append(append(o.plugins, plugins...), wasmPlugins...))
+
 	if err = r.Init(ctx, o.skipNetworkInit, false, ctxter, o.extraMigrations, o.goMigrations); err != nil {
 		l.WithError(err).Error("Unable to initialize service registry.")
 		return nil, err
@@ -0,0 +1,98 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	jaegerPropagator "go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+)
+
+// OTLPResource returns the resource which describes this Hydra instance in exported traces and metrics.
+func OTLPResource(c *config.DefaultProvider) *resource.Resource {
+	tc := c.Tracing()
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(tc.ServiceName),
+		semconv.ServiceVersion(config.Version),
+	}
+	if tc.DeploymentEnvironment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(tc.DeploymentEnvironment))
+	}
+	for _, a := range c.OTLP().ResourceAttributes {
+		attrs = append(attrs, attribute.String(a.Key, a.Value))
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
+
+// newOTLPTracer returns a tracer which exports spans to the configured OpenTelemetry collector. It also sets the
+// global tracer provider and propagators, like the tracers of otelx.
+func newOTLPTracer(ctx context.Context, c *config.DefaultProvider, name string) (trace.Tracer, error) {
+	oc := c.OTLP()
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(oc.Endpoint), otlptracehttp.WithHeaders(oc.Headers)}
+	if oc.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exp, err := otlptrace.New(ctx, otlptracehttp.NewClient(opts...))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sampler := sdktrace.TraceIDRatioBased(oc.TracesSamplingRatio)
+	if oc.TracesSamplingParentBased {
+		sampler = sdktrace.ParentBased(sampler)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(OTLPResource(c)),
+		sdktrace.WithSampler(sampler),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		jaegerPropagator.Jaeger{},
+		b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader|b3.B3SingleHeader)),
+		propagation.Baggage{},
+	))
+	return tp.Tracer(name), nil
+}
+
+// NewOTLPMeterProvider returns a meter provider which periodically exports the metrics of the Prometheus gatherer to
+// the configured OpenTelemetry collector. It must be shut down to stop the export.
+func NewOTLPMeterProvider(ctx context.Context, c *config.DefaultProvider, gatherer prometheus.Gatherer) (*sdkmetric.MeterProvider, error) {
+	oc := c.OTLP()
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(oc.Endpoint), otlpmetrichttp.WithHeaders(oc.Headers)}
+	if oc.Insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	}
+	exp, err := otlpmetrichttp.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(OTLPResource(c)),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp,
+			sdkmetric.WithInterval(oc.MetricsInterval),
+			sdkmetric.WithProducer(x.NewPrometheusProducer(gatherer)),
+		)),
+	), nil
+}
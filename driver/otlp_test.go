@@ -0,0 +1,65 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/logrusx"
+)
+
+func TestOTLP(t *testing.T) {
+	ctx := context.Background()
+	l := logrusx.New("", "")
+	c := config.MustNew(ctx, l, configx.WithConfigFiles("../internal/.hydra.yaml"))
+
+	var exports atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/metrics" && r.Header.Get("Authorization") == "Bearer secret" {
+			exports.Add(1)
+		}
+	}))
+	t.Cleanup(collector.Close)
+	u, err := url.Parse(collector.URL)
+	require.NoError(t, err)
+
+	c.MustSet(ctx, config.KeyOTLPEndpoint, u.Host)
+	c.MustSet(ctx, config.KeyOTLPInsecure, true)
+	c.MustSet(ctx, config.KeyOTLPHeaders, map[string]interface{}{"Authorization": "Bearer secret"})
+	c.MustSet(ctx, config.KeyOTLPResourceAttributes, []interface{}{map[string]interface{}{"key": "service.namespace", "value": "auth"}})
+
+	t.Run("case=resource", func(t *testing.T) {
+		res := OTLPResource(c)
+		v, ok := res.Set().Value("service.namespace")
+		require.True(t, ok)
+		assert.Equal(t, "auth", v.AsString())
+		v, ok = res.Set().Value(attribute.Key("service.version"))
+		require.True(t, ok)
+		assert.Equal(t, config.Version, v.AsString())
+	})
+
+	t.Run("case=pushes metrics", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total"})
+		reg.MustRegister(counter)
+		counter.Inc()
+
+		mp, err := NewOTLPMeterProvider(ctx, c, reg)
+		require.NoError(t, err)
+		require.NoError(t, mp.Shutdown(ctx))
+		assert.EqualValues(t, 1, exports.Load())
+	})
+}
@@ -10,16 +10,25 @@ import (
 
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/ory/hydra/v2/extension"
 	"github.com/ory/hydra/v2/fositex"
+	"github.com/ory/hydra/v2/gnap"
 	"github.com/ory/hydra/v2/internal/kratos"
+	"github.com/ory/hydra/v2/metadata"
 	"github.com/ory/x/httprouterx"
 	"github.com/ory/x/popx"
 
 	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/backup"
 	"github.com/ory/hydra/v2/hsm"
 	"github.com/ory/x/contextx"
 
 	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/stats"
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/hydra/v2/uma"
+	"github.com/ory/hydra/v2/x/events"
 
 	"github.com/pkg/errors"
 
@@ -63,6 +72,12 @@ type Registry interface {
 	WithExtraFositeFactories(f []fositex.Factory) Registry
 	ExtraFositeFactories() []fositex.Factory
 
+	WithPlugins(p extension.Plugins) Registry
+	extension.Registry
+
+	WithClock(c x.Clock) Registry
+	x.ClockProvider
+
 	contextx.Provider
 	config.Provider
 	persistence.Provider
@@ -74,6 +89,12 @@ type Registry interface {
 	jwk.Registry
 	trust.Registry
 	oauth2.Registry
+	audit.Registry
+	backup.Registry
+	stats.Registry
+	tenant.Registry
+	uma.Registry
+	gnap.Registry
 	PrometheusManager() *prometheus.MetricsManager
 	x.TracingProvider
 	FlowCipher() *aead.XChaCha20Poly1305
@@ -86,12 +107,21 @@ type Registry interface {
 	ConsentHandler() *consent.Handler
 	OAuth2Handler() *oauth2.Handler
 	HealthHandler() *healthx.Handler
+	MigrationHandler() *metadata.Handler
+	AuditHandler() *audit.Handler
+	BackupHandler() *backup.Handler
+	StatsHandler() *stats.Handler
 	OAuth2AwareMiddleware() func(h http.Handler) http.Handler
+	EventEmitter() events.Emitter
+	BeginShutdown()
+	Shutdown(ctx context.Context) error
 
 	OAuth2HMACStrategy() *foauth2.HMACSHAStrategy
+	OAuth2CoreStrategy() foauth2.CoreStrategy
 	WithOAuth2Provider(f fosite.OAuth2Provider)
 	WithConsentStrategy(c consent.Strategy)
 	WithHsmContext(h hsm.Context)
+	WithHSMKeySetContexts(contexts map[string]hsm.Context)
 }
 
 func NewRegistryFromDSN(ctx context.Context, c *config.DefaultProvider, l *logrusx.Logger, skipNetworkInit bool, migrate bool, ctxer contextx.Contextualizer) (Registry, error) {
@@ -134,6 +164,7 @@ func CallRegistry(ctx context.Context, r Registry) {
 	r.AudienceStrategy()
 	r.AccessTokenJWTStrategy()
 	r.OpenIDJWTStrategy()
+	r.LogoutTokenJWTStrategy()
 	r.OpenIDConnectRequestValidator()
 	r.PrometheusManager()
 	r.Tracer(ctx)
@@ -6,8 +6,12 @@ package driver
 import (
 	"context"
 	"crypto/sha256"
+	stderrors "errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/sessions"
@@ -21,19 +25,29 @@ import (
 	"github.com/ory/fosite/compose"
 	foauth2 "github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/backup"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/consent"
 	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/extension"
 	"github.com/ory/hydra/v2/fositex"
+	"github.com/ory/hydra/v2/gnap"
 	"github.com/ory/hydra/v2/hsm"
 	"github.com/ory/hydra/v2/internal/kratos"
 	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/hydra/v2/metadata"
 	"github.com/ory/hydra/v2/oauth2"
 	"github.com/ory/hydra/v2/oauth2/trust"
 	"github.com/ory/hydra/v2/persistence"
+	"github.com/ory/hydra/v2/stats"
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/hydra/v2/uma"
 	"github.com/ory/hydra/v2/x"
+	"github.com/ory/hydra/v2/x/events"
 	"github.com/ory/hydra/v2/x/oauth2cors"
 	"github.com/ory/x/contextx"
 	"github.com/ory/x/healthx"
@@ -43,6 +57,7 @@ import (
 	"github.com/ory/x/otelx"
 	"github.com/ory/x/popx"
 	prometheus "github.com/ory/x/prometheusx"
+	"github.com/ory/x/stringslice"
 )
 
 var (
@@ -61,12 +76,22 @@ type RegistryBase struct {
 	cv              *client.Validator
 	ctxer           contextx.Contextualizer
 	hh              *healthx.Handler
+	mh              *metadata.Handler
+	ah              *audit.Handler
+	ar              *audit.Recorder
+	bh              *backup.Handler
+	sh              *stats.Handler
+	th              *tenant.Handler
+	umah            *uma.Handler
+	gh              *gnap.Handler
 	migrationStatus *popx.MigrationStatuses
 	kc              *aead.AESGCM
 	flowc           *aead.XChaCha20Poly1305
 	cos             consent.Strategy
 	writer          herodot.Writer
 	hsm             hsm.Context
+	hsmKeySets      map[string]hsm.Context
+	hsmKeyManager   *hsm.KeyManager
 	forv            *openid.OpenIDConnectRequestValidator
 	fop             fosite.OAuth2Provider
 	coh             *consent.Handler
@@ -86,11 +111,24 @@ type RegistryBase struct {
 	oc              fosite.Configurator
 	oidcs           jwk.JWTSigner
 	ats             jwk.JWTSigner
+	lts             jwk.JWTSigner
 	hmacs           *foauth2.HMACSHAStrategy
+	cs              foauth2.CoreStrategy
 	fc              *fositex.Config
 	publicCORS      *cors.Cors
 	kratos          kratos.Client
 	fositeFactories []fositex.Factory
+	plugins         extension.Plugins
+	clock           x.Clock
+	eventsOnce      sync.Once
+	events          events.Emitter
+	securityLogOnce sync.Once
+	securityLog     *audit.SecurityLog
+	shuttingDown    atomic.Bool
+	workersMu       sync.Mutex
+	workersCtx      context.Context
+	stopWorkers     context.CancelFunc
+	workers         sync.WaitGroup
 }
 
 func (m *RegistryBase) GetJWKSFetcherStrategy() fosite.JWKSFetcherStrategy {
@@ -103,7 +141,7 @@ func (m *RegistryBase) GetJWKSFetcherStrategy() fosite.JWKSFetcherStrategy {
 }
 
 func (m *RegistryBase) WithContextualizer(ctxer contextx.Contextualizer) Registry {
-	m.ctxer = ctxer
+	m.ctxer = &tenant.Contextualizer{Contextualizer: ctxer}
 	return m.r
 }
 
@@ -151,6 +189,11 @@ func (m *RegistryBase) addPublicCORSOnHandler(ctx context.Context) func(http.Han
 func (m *RegistryBase) RegisterRoutes(ctx context.Context, admin *httprouterx.RouterAdmin, public *httprouterx.RouterPublic) {
 	m.HealthHandler().SetHealthRoutes(admin.Router, true)
 	m.HealthHandler().SetVersionRoutes(admin.Router)
+	m.MigrationHandler().SetRoutes(admin)
+	m.AuditHandler().SetRoutes(admin)
+	m.BackupHandler().SetRoutes(admin)
+	m.StatsHandler().SetRoutes(admin)
+	m.TenantHandler().SetRoutes(admin)
 
 	m.HealthHandler().SetHealthRoutes(public.Router, false, healthx.WithMiddleware(m.addPublicCORSOnHandler(ctx)))
 
@@ -161,6 +204,8 @@ func (m *RegistryBase) RegisterRoutes(ctx context.Context, admin *httprouterx.Ro
 	m.ClientHandler().SetRoutes(admin, public)
 	m.OAuth2Handler().SetRoutes(admin, public, m.OAuth2AwareMiddleware())
 	m.JWTGrantHandler().SetRoutes(admin)
+	m.UMAHandler().SetRoutes(public)
+	m.GNAPHandler().SetRoutes(public)
 }
 
 func (m *RegistryBase) BuildVersion() string {
@@ -252,6 +297,228 @@ func (m *RegistryBase) KeyHandler() *jwk.Handler {
 	return m.kh
 }
 
+func (m *RegistryBase) MigrationHandler() *metadata.Handler {
+	if m.mh == nil {
+		m.mh = metadata.NewHandler(m.r)
+	}
+	return m.mh
+}
+
+func (m *RegistryBase) AuditHandler() *audit.Handler {
+	if m.ah == nil {
+		m.ah = audit.NewHandler(m.r)
+	}
+	return m.ah
+}
+
+func (m *RegistryBase) AuditRecorder() *audit.Recorder {
+	if m.ar == nil {
+		m.ar = audit.NewRecorder(m.r)
+	}
+	return m.ar
+}
+
+// SecurityLog returns the security audit log or nil if it is disabled. The log is started on the first call.
+func (m *RegistryBase) SecurityLog() *audit.SecurityLog {
+	m.securityLogOnce.Do(func() {
+		c := m.Config()
+		if !c.AuditSecurityLogEnabled() {
+			return
+		}
+
+		cfgs, err := c.AuditSecurityLogSinks()
+		if err != nil {
+			m.Logger().WithError(err).Error("Unable to configure the security audit log, security events will not be recorded.")
+			return
+		}
+		sinks, err := audit.NewSecurityLogSinks(cfgs, m.HTTPClient(context.Background()))
+		if err != nil {
+			m.Logger().WithError(err).Error("Unable to configure the security audit log, security events will not be recorded.")
+			return
+		}
+
+		m.securityLog = audit.NewSecurityLog(sinks, m.Logger(),
+			c.AuditSecurityLogBufferSize(),
+			c.AuditSecurityLogFlushInterval(),
+			c.AuditSecurityLogRedactFields(),
+			c.AuditSecurityLogRedactMode() == "hash")
+		m.goWorker(m.securityLog.Run)
+	})
+	return m.securityLog
+}
+
+// goWorker runs a background worker, such as the event stream, until the registry is shut down. The worker must
+// flush its buffers before returning once its context is canceled.
+func (m *RegistryBase) goWorker(run func(ctx context.Context)) {
+	m.workersMu.Lock()
+	defer m.workersMu.Unlock()
+
+	if m.workersCtx == nil {
+		m.workersCtx, m.stopWorkers = context.WithCancel(context.Background())
+	}
+	m.workers.Add(1)
+	go func() {
+		defer m.workers.Done()
+		run(m.workersCtx)
+	}()
+}
+
+// BeginShutdown makes the readiness check fail, so that load balancers stop routing requests to this instance.
+func (m *RegistryBase) BeginShutdown() {
+	m.shuttingDown.Store(true)
+}
+
+// Shutdown stops the background workers, waiting until buffered events and security audit log entries are
+// flushed or ctx is done, and then closes the database connections and the Hardware Security Module session.
+func (m *RegistryBase) Shutdown(ctx context.Context) error {
+	m.BeginShutdown()
+
+	m.workersMu.Lock()
+	if m.stopWorkers != nil {
+		m.stopWorkers()
+	}
+	m.workersMu.Unlock()
+
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		m.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, errors.New("timed out flushing buffered events and security audit log entries"))
+	}
+
+	if c, ok := m.persister.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	if c, ok := m.hsm.(io.Closer); ok {
+		errs = append(errs, errors.WithStack(c.Close()))
+	}
+	return stderrors.Join(errs...)
+}
+
+// EventEmitter returns the emitter of the configured event stream, webhooks, security audit log and suspicious
+// activity detection or nil if none is configured. The stream is started on the first call.
+func (m *RegistryBase) EventEmitter() events.Emitter {
+	m.eventsOnce.Do(func() {
+		ctx := context.Background()
+		var emitters events.Emitters
+
+		if publisher, err := m.newEventPublisher(ctx); err != nil {
+			m.Logger().WithError(err).Error("Unable to configure the event stream, events will not be published.")
+		} else if publisher != nil {
+			stream := events.NewStream(publisher, m.Logger(),
+				m.Config().EventStreamBufferSize(ctx),
+				m.Config().EventStreamBatchSize(ctx),
+				m.Config().EventStreamFlushInterval(ctx))
+			m.goWorker(stream.Run)
+			emitters = append(emitters, stream)
+		}
+
+		if hooks, err := m.Config().EventWebhooks(ctx); err != nil {
+			m.Logger().WithError(err).Error("Unable to configure the event webhooks, events will not be sent.")
+		} else if len(hooks) > 0 {
+			webhooks := make([]events.CloudEventsWebhook, len(hooks))
+			for i, hook := range hooks {
+				client := m.HTTPClient(ctx)
+				client.RetryMax = *hook.MaxRetries
+				webhooks[i] = events.CloudEventsWebhook{
+					Client: client,
+					URL:    hook.URL,
+					Auth:   hook.Auth.Apply,
+					Secret: hook.Secret,
+					Types:  hook.Events,
+				}
+			}
+			emitters = append(emitters, events.NewCloudEventsEmitter(m.Logger(), m.Config().IssuerURL(ctx).String(), webhooks))
+		}
+
+		if sl := m.SecurityLog(); sl != nil {
+			emitters = append(emitters, sl)
+		}
+
+		if hook := m.Config().AuditSuspiciousActivityWebhook(); hook != nil {
+			emitters = append(emitters, audit.NewSuspiciousActivity(m.HTTPClient(ctx), hook, m.Logger(),
+				m.Config().AuditSuspiciousActivityAuthFailures(),
+				m.Config().AuditSuspiciousActivityAuthFailureWindow()))
+		}
+
+		switch len(emitters) {
+		case 0:
+		case 1:
+			m.events = emitters[0]
+		default:
+			m.events = emitters
+		}
+	})
+	return m.events
+}
+
+func (m *RegistryBase) newEventPublisher(ctx context.Context) (events.Publisher, error) {
+	switch t := m.Config().EventStreamType(ctx); t {
+	case "":
+		return nil, nil
+	case "webhook":
+		hook := m.Config().EventStreamWebhookConfig(ctx)
+		if hook == nil {
+			return nil, errors.Errorf("the event stream type is webhook but %s is not set", config.KeyEventStreamWebhook)
+		}
+		return events.NewWebhookPublisher(m.HTTPClient(ctx), hook.URL, hook.Auth.Apply), nil
+	case "kafka":
+		brokers := m.Config().EventStreamKafkaBrokers(ctx)
+		if len(brokers) == 0 {
+			return nil, errors.Errorf("the event stream type is kafka but %s is not set", config.KeyEventStreamKafkaBrokers)
+		}
+		return events.NewKafkaPublisher(brokers, m.Config().EventStreamKafkaTopic(ctx)), nil
+	case "nats":
+		url := m.Config().EventStreamNATSURL(ctx)
+		if url == "" {
+			return nil, errors.Errorf("the event stream type is nats but %s is not set", config.KeyEventStreamNATSURL)
+		}
+		return events.NewNATSPublisher(url, m.Config().EventStreamNATSSubject(ctx))
+	default:
+		return nil, errors.Errorf("unknown event stream type %q", t)
+	}
+}
+
+func (m *RegistryBase) BackupHandler() *backup.Handler {
+	if m.bh == nil {
+		m.bh = backup.NewHandler(m.r)
+	}
+	return m.bh
+}
+
+func (m *RegistryBase) StatsHandler() *stats.Handler {
+	if m.sh == nil {
+		m.sh = stats.NewHandler(m.r)
+	}
+	return m.sh
+}
+
+func (m *RegistryBase) TenantHandler() *tenant.Handler {
+	if m.th == nil {
+		m.th = tenant.NewHandler(m.r)
+	}
+	return m.th
+}
+
+func (m *RegistryBase) UMAHandler() *uma.Handler {
+	if m.umah == nil {
+		m.umah = uma.NewHandler(m.r)
+	}
+	return m.umah
+}
+
+func (m *RegistryBase) GNAPHandler() *gnap.Handler {
+	if m.gh == nil {
+		m.gh = gnap.NewHandler(m.r)
+	}
+	return m.gh
+}
+
 func (m *RegistryBase) JWTGrantHandler() *trust.Handler {
 	if m.jwtGrantH == nil {
 		m.jwtGrantH = trust.NewHandler(m.r)
@@ -268,7 +535,13 @@ func (m *RegistryBase) GrantValidator() *trust.GrantValidator {
 
 func (m *RegistryBase) HealthHandler() *healthx.Handler {
 	if m.hh == nil {
-		m.hh = healthx.NewHandler(m.Writer(), m.buildVersion, healthx.ReadyCheckers{
+		checkers := healthx.ReadyCheckers{
+			"shutdown": func(_ *http.Request) error {
+				if m.shuttingDown.Load() {
+					return errors.New("the instance is shutting down")
+				}
+				return nil
+			},
 			"database": func(_ *http.Request) error {
 				return m.r.Ping()
 			},
@@ -291,7 +564,20 @@ func (m *RegistryBase) HealthHandler() *healthx.Handler {
 				m.migrationStatus = &status
 				return nil
 			},
-		})
+		}
+		if m.Config().HSMEnabled() {
+			checkers["hsm"] = func(r *http.Request) error {
+				if m.hsmKeyManager == nil {
+					return errors.New("the Hardware Security Module is not initialized yet")
+				}
+				if err := m.hsmKeyManager.HealthCheck(r.Context()); err != nil {
+					m.Logger().WithError(err).Warn("Instance is not ready because the Hardware Security Module is unreachable.")
+					return err
+				}
+				return nil
+			}
+		}
+		m.hh = healthx.NewHandler(m.Writer(), m.buildVersion, checkers)
 	}
 
 	return m.hh
@@ -387,7 +673,7 @@ func (m *RegistryBase) OpenIDJWTStrategy() jwk.JWTSigner {
 		return m.oidcs
 	}
 
-	m.oidcs = jwk.NewDefaultJWTSigner(m.Config(), m.r, x.OpenIDConnectKeyName)
+	m.oidcs = jwk.NewConfiguredJWTSigner(m.Config(), m.r, m.Config().IDTokenSigningKeySet, config.KeyJWTHeadersIDTokenType)
 	return m.oidcs
 }
 
@@ -396,10 +682,19 @@ func (m *RegistryBase) AccessTokenJWTStrategy() jwk.JWTSigner {
 		return m.ats
 	}
 
-	m.ats = jwk.NewDefaultJWTSigner(m.Config(), m.r, x.OAuth2JWTKeyName)
+	m.ats = jwk.NewConfiguredJWTSigner(m.Config(), m.r, m.Config().AccessTokenSigningKeySet, config.KeyJWTHeadersAccessTokenType)
 	return m.ats
 }
 
+func (m *RegistryBase) LogoutTokenJWTStrategy() jwk.JWTSigner {
+	if m.lts != nil {
+		return m.lts
+	}
+
+	m.lts = jwk.NewConfiguredJWTSigner(m.Config(), m.r, m.Config().LogoutTokenSigningKeySet, config.KeyJWTHeadersLogoutTokenType)
+	return m.lts
+}
+
 func (m *RegistryBase) OAuth2HMACStrategy() *foauth2.HMACSHAStrategy {
 	if m.hmacs != nil {
 		return m.hmacs
@@ -419,7 +714,7 @@ func (m *RegistryBase) OAuth2Config() *fositex.Config {
 }
 
 func (m *RegistryBase) ExtraFositeFactories() []fositex.Factory {
-	return m.fositeFactories
+	return append([]fositex.Factory{fositex.Factory(uma.NewGrantHandlerFactory(m.r))}, m.fositeFactories...)
 }
 
 func (m *RegistryBase) WithExtraFositeFactories(f []fositex.Factory) Registry {
@@ -428,31 +723,47 @@ func (m *RegistryBase) WithExtraFositeFactories(f []fositex.Factory) Registry {
 	return m.r
 }
 
+func (m *RegistryBase) Plugins() extension.Plugins {
+	return m.plugins
+}
+
+func (m *RegistryBase) WithPlugins(p extension.Plugins) Registry {
+	m.plugins = p
+	m.sia = nil
+
+	return m.r
+}
+
+func (m *RegistryBase) Clock() x.Clock {
+	if m.clock == nil {
+		return x.SystemClock
+	}
+	return m.clock
+}
+
+// WithClock sets the clock used to issue and validate tokens. Because Fosite validates JWTs with a process-wide
+// clock, it is also set as the clock of Fosite.
+func (m *RegistryBase) WithClock(c x.Clock) Registry {
+	m.clock = c
+	jwt.TimeFunc = c.Now
+
+	return m.r
+}
+
 func (m *RegistryBase) OAuth2ProviderConfig() fosite.Configurator {
 	if m.oc != nil {
 		return m.oc
 	}
 
 	conf := m.OAuth2Config()
-	hmacAtStrategy := m.OAuth2HMACStrategy()
 	oidcSigner := m.OpenIDJWTStrategy()
-	atSigner := m.AccessTokenJWTStrategy()
-	jwtAtStrategy := &foauth2.DefaultJWTStrategy{
-		Signer:          atSigner,
-		HMACSHAStrategy: hmacAtStrategy,
-		Config:          conf,
-	}
 
 	conf.LoadDefaultHandlers(&compose.CommonStrategy{
-		CoreStrategy: fositex.NewTokenStrategy(m.Config(), hmacAtStrategy, &foauth2.DefaultJWTStrategy{
-			Signer:          jwtAtStrategy,
-			HMACSHAStrategy: hmacAtStrategy,
-			Config:          conf,
-		}),
-		OpenIDConnectTokenStrategy: &openid.DefaultStrategy{
+		CoreStrategy: m.OAuth2CoreStrategy(),
+		OpenIDConnectTokenStrategy: fositex.NewIDTokenStrategy(m.Config(), &openid.DefaultStrategy{
 			Config: conf,
 			Signer: oidcSigner,
-		},
+		}),
 		Signer: oidcSigner,
 	})
 
@@ -460,6 +771,29 @@ func (m *RegistryBase) OAuth2ProviderConfig() fosite.Configurator {
 	return m.oc
 }
 
+// OAuth2CoreStrategy returns the strategy which generates and validates access tokens, refresh tokens and
+// authorization codes either as opaque tokens or as JWTs.
+func (m *RegistryBase) OAuth2CoreStrategy() foauth2.CoreStrategy {
+	if m.cs != nil {
+		return m.cs
+	}
+
+	conf := m.OAuth2Config()
+	hmacAtStrategy := m.OAuth2HMACStrategy()
+	jwtAtStrategy := &foauth2.DefaultJWTStrategy{
+		Signer:          m.AccessTokenJWTStrategy(),
+		HMACSHAStrategy: hmacAtStrategy,
+		Config:          conf,
+	}
+
+	m.cs = fositex.NewTokenStrategy(m.Config(), hmacAtStrategy, &foauth2.DefaultJWTStrategy{
+		Signer:          jwtAtStrategy,
+		HMACSHAStrategy: hmacAtStrategy,
+		Config:          conf,
+	})
+	return m.cs
+}
+
 func (m *RegistryBase) OpenIDConnectRequestValidator() *openid.OpenIDConnectRequestValidator {
 	if m.forv == nil {
 		m.forv = openid.NewOpenIDConnectRequestValidator(&openid.DefaultStrategy{
@@ -497,15 +831,36 @@ func (m *RegistryBase) SubjectIdentifierAlgorithm(ctx context.Context) map[strin
 				m.sia["public"] = consent.NewSubjectIdentifierAlgorithmPublic()
 			case "pairwise":
 				m.sia["pairwise"] = consent.NewSubjectIdentifierAlgorithmPairwise([]byte(m.Config().SubjectIdentifierAlgorithmSalt(ctx)))
+			default:
+				for _, p := range extension.Hooks[extension.SubjectIdentifierAlgorithm](m.Plugins()) {
+					if stringslice.Has(p.SubjectTypes(), t) {
+						m.sia[t] = consent.NewSubjectIdentifierAlgorithmPlugin(t, p)
+						break
+					}
+				}
+				if _, ok := m.sia[t]; !ok {
+					m.Logger().Errorf("Subject type %s is listed in oidc.subject_identifiers.supported_types but no plugin implements it. Clients with this subject type can not be issued tokens.", t)
+				}
 			}
 		}
 	}
 	return m.sia
 }
 
-func (m *RegistryBase) Tracer(_ context.Context) *otelx.Tracer {
+func (m *RegistryBase) Tracer(ctx context.Context) *otelx.Tracer {
 	if m.trc == nil {
-		t, err := otelx.New("Ory Hydra", m.l, m.conf.Tracing())
+		var t *otelx.Tracer
+		var err error
+		if m.conf.OTLP().TracesEnabled {
+			var ot trace.Tracer
+			// The tracer outlives the request which happens to initialize it.
+			if ot, err = newOTLPTracer(context.WithoutCancel(ctx), m.conf, "Ory Hydra"); err == nil {
+				t = new(otelx.Tracer).WithOTLP(ot)
+				m.Logger().Infof("OTLP tracer configured! Sending spans to %s", m.conf.OTLP().Endpoint)
+			}
+		} else {
+			t, err = otelx.New("Ory Hydra", m.l, m.conf.Tracing())
+		}
 		if err != nil {
 			m.Logger().WithError(err).Error("Unable to initialize Tracer.")
 		} else {
@@ -555,6 +910,7 @@ func (m *RegistryBase) AccessRequestHooks() []oauth2.AccessRequestHook {
 		m.arhs = []oauth2.AccessRequestHook{
 			oauth2.RefreshTokenHook(m),
 			oauth2.TokenHook(m),
+			oauth2.PluginHook(m),
 		}
 	}
 	return m.arhs
@@ -571,6 +927,19 @@ func (m *RegistryBase) HSMContext() hsm.Context {
 	return m.hsm
 }
 
+func (m *RegistryBase) WithHSMKeySetContexts(contexts map[string]hsm.Context) {
+	m.hsmKeySets = contexts
+}
+
+// HSMKeySetContexts returns the contexts of the partitions of the Hardware Security Module by the key sets which are
+// stored on them.
+func (m *RegistryBase) HSMKeySetContexts() map[string]hsm.Context {
+	if m.hsmKeySets == nil {
+		m.hsmKeySets = hsm.NewKeySetContexts(m.Config(), m.l)
+	}
+	return m.hsmKeySets
+}
+
 func (m *RegistrySQL) ClientAuthenticator() x.ClientAuthenticator {
 	return m.OAuth2Provider().(*fosite.Fosite)
 }
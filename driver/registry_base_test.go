@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ory/x/randx"
 
@@ -122,3 +123,40 @@ func TestRegistryBase_HTTPClient(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestRegistryBase_Shutdown(t *testing.T) {
+	r := new(RegistryBase)
+	r.WithConfig(config.MustNew(context.Background(), logrusx.New("", ""), configx.SkipValidation()))
+
+	flushed := make(chan struct{})
+	r.goWorker(func(ctx context.Context) {
+		<-ctx.Done()
+		close(flushed)
+	})
+
+	ready := r.HealthHandler().ReadyChecks["shutdown"]
+	require.NoError(t, ready(nil))
+
+	r.BeginShutdown()
+	assert.Error(t, ready(nil), "the readiness check fails as soon as the shutdown begins")
+
+	require.NoError(t, r.Shutdown(context.Background()))
+	select {
+	case <-flushed:
+	default:
+		t.Fatal("the background workers are stopped before Shutdown returns")
+	}
+
+	t.Run("case=workers do not stop in time", func(t *testing.T) {
+		r := new(RegistryBase)
+		r.WithConfig(config.MustNew(context.Background(), logrusx.New("", ""), configx.SkipValidation()))
+
+		stop := make(chan struct{})
+		t.Cleanup(func() { close(stop) })
+		r.goWorker(func(context.Context) { <-stop })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorContains(t, r.Shutdown(ctx), "timed out")
+	})
+}
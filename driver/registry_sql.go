@@ -5,20 +5,34 @@ package driver
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gobuffalo/pop/v6"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/luna-duclos/instrumentedsql"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/backup"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/gnap"
 	"github.com/ory/hydra/v2/hsm"
 	"github.com/ory/hydra/v2/jwk"
 	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/persistence"
 	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/hydra/v2/stats"
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/hydra/v2/uma"
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/contextx"
 	"github.com/ory/x/dbal"
@@ -75,39 +89,14 @@ func (m *RegistrySQL) Init(
 ) error {
 	if m.persister == nil {
 		m.WithContextualizer(ctxer)
-		var opts []instrumentedsql.Opt
-		if m.Tracer(ctx).IsLoaded() {
-			opts = []instrumentedsql.Opt{
-				instrumentedsql.WithTracer(otelsql.NewTracer()),
-				instrumentedsql.WithOmitArgs(), // don't risk leaking PII or secrets
-				instrumentedsql.WithOpsExcluded(instrumentedsql.OpSQLRowsNext),
-			}
-		}
 
-		// new db connection
-		pool, idlePool, connMaxLifetime, connMaxIdleTime, cleanedDSN := sqlcon.ParseConnectionOptions(
-			m.l, m.Config().DSN(),
-		)
-		c, err := pop.NewConnection(
-			&pop.ConnectionDetails{
-				URL:                       sqlcon.FinalizeDSN(m.l, cleanedDSN),
-				IdlePool:                  idlePool,
-				ConnMaxLifetime:           connMaxLifetime,
-				ConnMaxIdleTime:           connMaxIdleTime,
-				Pool:                      pool,
-				UseInstrumentedDriver:     m.Tracer(ctx).IsLoaded(),
-				InstrumentedDriverOptions: opts,
-				Unsafe:                    m.Config().DbIgnoreUnknownTableColumns(),
-			},
-		)
-		if err != nil {
-			return errorsx.WithStack(err)
-		}
-		if err := resilience.Retry(m.l, 5*time.Second, 5*time.Minute, c.Open); err != nil {
-			return errorsx.WithStack(err)
+		var p persistence.Persister
+		var err error
+		if f, ok := persistence.FactoryFor(m.Config().DSN()); ok {
+			p, err = f(ctx, m.Config(), m)
+		} else {
+			p, err = m.newSQLPersister(ctx, extraMigrations, goMigrations)
 		}
-
-		p, err := sql.NewPersister(ctx, c, m, m.Config(), extraMigrations, goMigrations)
 		if err != nil {
 			return err
 		}
@@ -117,8 +106,9 @@ func (m *RegistrySQL) Init(
 		}
 
 		if m.Config().HSMEnabled() {
-			hardwareKeyManager := hsm.NewKeyManager(m.HSMContext(), m.Config())
-			m.defaultKeyManager = jwk.NewManagerStrategy(hardwareKeyManager, m.persister)
+			m.hsmKeyManager = hsm.NewKeyManager(m.HSMContext(), m.Config()).WithKeySetContexts(m.HSMKeySetContexts())
+			m.defaultKeyManager = jwk.NewManagerStrategy(m.hsmKeyManager, m.persister).WithHardwareKeySets(m.Config().HSMKeySets).
+				WithHardwareKeyImport(m.Config().HSMImportKeys)
 		} else {
 			m.defaultKeyManager = m.persister
 		}
@@ -154,8 +144,9 @@ func (m *RegistrySQL) Init(
 		}
 
 		if m.Config().HSMEnabled() {
-			hardwareKeyManager := hsm.NewKeyManager(m.HSMContext(), m.Config())
-			m.defaultKeyManager = jwk.NewManagerStrategy(hardwareKeyManager, m.persister)
+			m.hsmKeyManager = hsm.NewKeyManager(m.HSMContext(), m.Config()).WithKeySetContexts(m.HSMKeySetContexts())
+			m.defaultKeyManager = jwk.NewManagerStrategy(m.hsmKeyManager, m.persister).WithHardwareKeySets(m.Config().HSMKeySets).
+				WithHardwareKeyImport(m.Config().HSMImportKeys)
 		} else {
 			m.defaultKeyManager = m.persister
 		}
@@ -165,7 +156,174 @@ func (m *RegistrySQL) Init(
 	return nil
 }
 
+// newSQLPersister connects to the SQL database configured in dsn and its read replicas.
+func (m *RegistrySQL) newSQLPersister(ctx context.Context, extraMigrations []fs.FS, goMigrations []popx.Migration) (*sql.Persister, error) {
+	c, err := m.newConnection(ctx, m.Config().DSN(), m.Config().DBPool())
+	if err != nil {
+		return nil, err
+	}
+	if err := resilience.Retry(m.l, 5*time.Second, 5*time.Minute, c.Open); err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	m.registerPoolCollector(c, "primary")
+
+	var replicas []*pop.Connection
+	for i, dsn := range m.Config().DBReadReplicaDSNs() {
+		rc, err := m.newConnection(ctx, dsn, m.Config().DBReadReplicaPool())
+		if err != nil {
+			return nil, err
+		}
+		// Opening does not connect to the replica. Reads fall back to the primary database while a replica is
+		// unavailable.
+		if err := rc.Open(); err != nil {
+			return nil, errorsx.WithStack(err)
+		}
+		m.registerPoolCollector(rc, fmt.Sprintf("replica-%d", i+1))
+		replicas = append(replicas, rc)
+	}
+
+	p, err := sql.NewPersister(ctx, c, m, m.Config(), extraMigrations, goMigrations)
+	if err != nil {
+		return nil, err
+	}
+	p = p.WithReadReplicas(replicas...)
+
+	if u := m.Config().DBRedisURL(); u != "" {
+		opts, err := redis.ParseURL(u)
+		if err != nil {
+			return nil, errorsx.WithStack(err)
+		}
+		p = p.WithRedis(redis.NewClient(opts), m.Config().DBRedisEphemeralSessions(), m.Config().DBRedisAccessTokenCache(), m.Config().DBRedisRememberedConsentCache(), m.Config().DBRedisReplayProtection())
+	}
+
+	if m.Config().DBClientCacheEnabled() {
+		if p, err = p.WithClientCache(m.Config().DBClientCacheMaxClients(), m.Config().DBClientCacheTTL()); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (m *RegistrySQL) newConnection(ctx context.Context, dsn string, pool config.DBPool) (*pop.Connection, error) {
+	var opts []instrumentedsql.Opt
+	if m.Tracer(ctx).IsLoaded() {
+		opts = []instrumentedsql.Opt{
+			instrumentedsql.WithTracer(otelsql.NewTracer()),
+			instrumentedsql.WithOmitArgs(), // don't risk leaking PII or secrets
+			instrumentedsql.WithOpsExcluded(instrumentedsql.OpSQLRowsNext),
+		}
+	}
+
+	maxConns, maxIdleConns, connMaxLifetime, connMaxIdleTime, cleanedDSN := sqlcon.ParseConnectionOptions(m.l, dsn)
+	if pool.MaxOpenConns > 0 {
+		maxConns = pool.MaxOpenConns
+	}
+	if pool.MaxIdleConns > 0 {
+		maxIdleConns = pool.MaxIdleConns
+	}
+	if pool.MaxConnLifetime > 0 {
+		connMaxLifetime = pool.MaxConnLifetime
+	}
+	if pool.MaxConnIdleTime > 0 {
+		connMaxIdleTime = pool.MaxConnIdleTime
+	}
+
+	c, err := pop.NewConnection(
+		&pop.ConnectionDetails{
+			URL:                       sqlcon.FinalizeDSN(m.l, cleanedDSN),
+			IdlePool:                  maxIdleConns,
+			ConnMaxLifetime:           connMaxLifetime,
+			ConnMaxIdleTime:           connMaxIdleTime,
+			Pool:                      maxConns,
+			UseInstrumentedDriver:     m.Tracer(ctx).IsLoaded(),
+			InstrumentedDriverOptions: opts,
+			Unsafe:                    m.Config().DbIgnoreUnknownTableColumns(),
+		},
+	)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	if c.Dialect.Name() == "sqlite3" {
+		m.configureSQLite(c.Dialect.Details(), dsn)
+	}
+
+	if m.Config().DBStatementCommentsEnabled() || m.Config().DBSlowQueryThreshold() > 0 {
+		stmtOpts := sql.StatementOptions{
+			Comments:      m.Config().DBStatementCommentsEnabled(),
+			Traceparent:   m.Config().DBStatementCommentsTraceparent(),
+			SlowThreshold: m.Config().DBSlowQueryThreshold(),
+			Logger:        m.Logger(),
+		}
+		if m.Tracer(ctx).IsLoaded() {
+			stmtOpts.Tracer = otelsql.NewTracer()
+		}
+
+		// The statement driver instruments the statements itself, and replaces the instrumentation of pop.
+		name, err := sql.RegisterStatementDriver(c.Dialect.DefaultDriver(), stmtOpts)
+		if err != nil {
+			return nil, err
+		}
+		details := c.Dialect.Details()
+		details.Driver = name
+		details.UseInstrumentedDriver = false
+		details.InstrumentedDriverOptions = nil
+	}
+
+	return c, nil
+}
+
+// configureSQLite applies the SQLite settings to the connection details. Options set in the query of the DSN take
+// precedence.
+func (m *RegistrySQL) configureSQLite(details *pop.ConnectionDetails, dsn string) {
+	var query url.Values
+	if _, raw, ok := strings.Cut(dsn, "?"); ok {
+		query, _ = url.ParseQuery(raw)
+	}
+	if details.Options == nil {
+		details.Options = map[string]string{}
+	}
+	set := func(k, v string) {
+		if !query.Has(k) {
+			details.Options[k] = v
+		}
+	}
+
+	set("_busy_timeout", strconv.FormatInt(m.Config().DBSQLiteBusyTimeout().Milliseconds(), 10))
+
+	// In-memory databases do not have a journal file.
+	if m.Config().DBSQLiteWAL() && !dbal.IsMemorySQLite(dsn) {
+		set("_journal_mode", "WAL")
+		set("_synchronous", "NORMAL")
+		// Transactions which start reading and then write can not wait for other writers and fail immediately,
+		// which is why all transactions acquire the write lock when they begin.
+		set("_txlock", "immediate")
+	}
+}
+
+// registerPoolCollector exports the connection pool statistics of the connection. The collector of the first
+// registry wins if several registries are created in the same process, which only happens in tests.
+func (m *RegistrySQL) registerPoolCollector(c *pop.Connection, dbName string) {
+	collector, err := sql.NewPoolCollector(c, dbName)
+	if err != nil {
+		m.Logger().WithError(err).Warn("Unable to export the connection pool metrics.")
+		return
+	}
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if err := prometheus.Register(collector); err != nil && !errors.As(err, &alreadyRegistered) {
+		m.Logger().WithError(err).Warn("Unable to export the connection pool metrics.")
+	}
+}
+
+// alwaysCanHandle returns whether the data source name is handled regardless of build tags. This includes the
+// persisters registered with persistence.Register.
 func (m *RegistrySQL) alwaysCanHandle(dsn string) bool {
+	if _, ok := persistence.FactoryFor(dsn); ok {
+		return true
+	}
+
 	scheme := strings.Split(dsn, "://")[0]
 	s := dbal.Canonicalize(scheme)
 	return s == dbal.DriverMySQL || s == dbal.DriverPostgreSQL || s == dbal.DriverCockroachDB
@@ -198,3 +356,27 @@ func (m *RegistrySQL) SoftwareKeyManager() jwk.Manager {
 func (m *RegistrySQL) GrantManager() trust.GrantManager {
 	return m.Persister()
 }
+
+func (m *RegistrySQL) AuditManager() audit.Manager {
+	return m.Persister()
+}
+
+func (m *RegistrySQL) BackupManager() backup.Manager {
+	return m.Persister()
+}
+
+func (m *RegistrySQL) StatsManager() stats.Manager {
+	return m.Persister()
+}
+
+func (m *RegistrySQL) TenantManager() tenant.Manager {
+	return m.Persister()
+}
+
+func (m *RegistrySQL) UMAManager() uma.Manager {
+	return m.Persister()
+}
+
+func (m *RegistrySQL) GNAPManager() gnap.Manager {
+	return m.Persister()
+}
@@ -7,14 +7,17 @@ import (
 	"context"
 	"math/rand"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/persistence"
 	"github.com/ory/hydra/v2/persistence/sql"
 	"github.com/ory/x/configx"
 	"github.com/ory/x/contextx"
@@ -90,3 +93,59 @@ func failedPing(err error) func(r *RegistrySQL) error {
 		return errorsx.WithStack(err)
 	}
 }
+
+func TestConnectionPool(t *testing.T) {
+	ctx := context.Background()
+	l := logrusx.New("", "")
+	c := config.MustNew(ctx, l, configx.SkipValidation())
+	c.MustSet(ctx, config.KeyDSN, "memory")
+	c.MustSet(ctx, config.KeyDBPool+"."+config.KeySuffixDBPoolMaxOpenConns, 3)
+	reg, err := NewRegistryWithoutInit(c, l)
+	require.NoError(t, err)
+
+	conn, err := reg.(*RegistrySQL).newConnection(ctx, c.DSN()+"&max_conns=5&max_idle_conns=2", c.DBPool())
+	require.NoError(t, err)
+	require.NoError(t, conn.Open())
+	t.Cleanup(func() { _ = conn.Close() })
+
+	collector, err := sql.NewPoolCollector(conn, "primary")
+	require.NoError(t, err)
+	assert.Equal(t, 9, testutil.CollectAndCount(collector))
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP go_sql_max_open_connections Maximum number of open connections to the database.
+# TYPE go_sql_max_open_connections gauge
+go_sql_max_open_connections{db_name="primary"} 3
+`), "go_sql_max_open_connections"), "the configuration takes precedence over the DSN")
+}
+
+func TestRegisteredPersister(t *testing.T) {
+	ctx := context.Background()
+	l := logrusx.New("", "")
+
+	backendConfig := config.MustNew(ctx, l, configx.SkipValidation())
+	backendConfig.MustSet(ctx, config.KeyDSN, "memory")
+	backend, err := NewRegistryWithoutInit(backendConfig, l)
+	require.NoError(t, err)
+	require.NoError(t, backend.Init(ctx, false, true, &contextx.Default{}, nil, nil))
+
+	var deps persistence.Dependencies
+	persistence.Register("registered-persister", func(_ context.Context, c *config.DefaultProvider, d persistence.Dependencies) (persistence.Persister, error) {
+		assert.Equal(t, "registered-persister://test", c.DSN())
+		deps = d
+		return backend.Persister(), nil
+	})
+	assert.Panics(t, func() {
+		persistence.Register("registered-persister", nil)
+	})
+
+	c := config.MustNew(ctx, l, configx.SkipValidation())
+	c.MustSet(ctx, config.KeyDSN, "registered-persister://test")
+	reg, err := NewRegistryWithoutInit(c, l)
+	require.NoError(t, err)
+	require.NoError(t, reg.Init(ctx, false, false, &contextx.Default{}, nil, nil))
+	assert.Equal(t, reg, deps)
+
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, &client.Client{ID: "registered-persister-client"}))
+	_, err = backend.ClientManager().GetConcreteClient(ctx, "registered-persister-client")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,117 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+// Package extension defines the hooks which plugins use to customize tokens and flows without forking Ory Hydra.
+//
+// Plugins are either compiled in using driver.WithPlugins, or, if Ory Hydra is built with the "plugins" build tag,
+// loaded from the Go plugins configured in plugins.paths. A Go plugin exports its Plugin as the variable "Plugin".
+// WebAssembly modules configured in plugins.wasm are loaded as plugins by package wasm.
+package extension
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/flow"
+)
+
+// Plugin extends Ory Hydra. It implements one or more of the hook interfaces of this package, which are called in
+// the order the plugins are registered.
+type Plugin interface {
+	// Name identifies the plugin in logs and errors.
+	Name() string
+}
+
+// AuthorizeRequestValidator validates authorization requests before the user is sent to the login provider.
+// Returning an error rejects the request. Errors other than OAuth 2.0 errors are returned as server errors.
+type AuthorizeRequestValidator interface {
+	Plugin
+	ValidateAuthorizeRequest(ctx context.Context, ar fosite.AuthorizeRequester) error
+}
+
+// GrantAuthorizer decides whether a token may be issued at the token endpoint. It is called for all grant types
+// before the claims are mutated. Returning an error denies the token request. Errors other than OAuth 2.0 errors
+// such as fosite.ErrAccessDenied are returned as server errors.
+type GrantAuthorizer interface {
+	Plugin
+	AuthorizeGrant(ctx context.Context, requester fosite.AccessRequester) error
+}
+
+// ClaimsMutator mutates the extra claims of the access and ID tokens issued at the token endpoint. The maps may be
+// modified in place and are never nil.
+type ClaimsMutator interface {
+	Plugin
+	MutateClaims(ctx context.Context, requester fosite.AccessRequester, accessToken, idToken map[string]interface{}) error
+}
+
+// ClientResolver resolves OAuth 2.0 Clients which are not stored in the database during OAuth 2.0 and OpenID
+// Connect flows, for example from an external registry. It returns nil if it does not know the client. Resolved
+// clients are not stored, so their secret must be hashed with the configured hasher.
+type ClientResolver interface {
+	Plugin
+	ResolveClient(ctx context.Context, id string) (*client.Client, error)
+}
+
+// SubjectMapper maps the subject accepted by the login provider to the subject of the tokens, before a pairwise
+// subject identifier is derived from it.
+type SubjectMapper interface {
+	Plugin
+	MapSubject(ctx context.Context, c *client.Client, subject string) (string, error)
+}
+
+// SubjectIdentifierAlgorithm derives the subject identifiers of clients whose subject_type is one of its subject
+// types, in addition to the built-in "public" and "pairwise" types. This allows to keep the subject identifiers of an
+// identity provider which is migrated to Ory Hydra, for example HMACs against a key from the client metadata. The
+// subject types must also be listed in oidc.subject_identifiers.supported_types.
+type SubjectIdentifierAlgorithm interface {
+	Plugin
+	SubjectTypes() []string
+	ObfuscateSubject(ctx context.Context, subjectType string, c *client.Client, subject string) (string, error)
+}
+
+// LoginProvider handles login requests in-process instead of redirecting the user agent to the login UI, for example
+// when Ory Hydra is embedded as a library. It returns nil to leave the login request to the next login provider or
+// the login UI. Ory Hydra applies the decision like the admin API and redirects the user agent back to the
+// authorization endpoint with the login verifier. The client of the login request is sanitized.
+type LoginProvider interface {
+	Plugin
+	HandleLoginRequest(ctx context.Context, r *http.Request, req *flow.LoginRequest) (*LoginDecision, error)
+}
+
+// LoginDecision accepts the login request if Accept is set, and rejects it otherwise.
+type LoginDecision struct {
+	Accept *flow.HandledLoginRequest
+	Reject *flow.RequestDeniedError
+}
+
+// ConsentProvider handles consent requests in-process instead of redirecting the user agent to the consent UI, like
+// LoginProvider handles login requests.
+type ConsentProvider interface {
+	Plugin
+	HandleConsentRequest(ctx context.Context, r *http.Request, req *flow.OAuth2ConsentRequest) (*ConsentDecision, error)
+}
+
+// ConsentDecision accepts the consent request if Accept is set, and rejects it otherwise.
+type ConsentDecision struct {
+	Accept *flow.AcceptOAuth2ConsentRequest
+	Reject *flow.RequestDeniedError
+}
+
+type Plugins []Plugin
+
+type Registry interface {
+	Plugins() Plugins
+}
+
+// Hooks returns the plugins which implement the hook interface T.
+func Hooks[T Plugin](ps Plugins) []T {
+	var hooks []T
+	for _, p := range ps {
+		if h, ok := p.(T); ok {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
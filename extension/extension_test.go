@@ -0,0 +1,115 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package extension_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/extension"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/sqlcon"
+)
+
+type testPlugin struct {
+	clients map[string]*client.Client
+}
+
+func (p *testPlugin) Name() string { return "test" }
+
+func (p *testPlugin) AuthorizeGrant(_ context.Context, r fosite.AccessRequester) error {
+	if r.GetClient().GetID() == "blocked" {
+		return fosite.ErrAccessDenied.WithHint("The client is blocked.")
+	}
+	return nil
+}
+
+func (p *testPlugin) MutateClaims(_ context.Context, _ fosite.AccessRequester, accessToken, idToken map[string]interface{}) error {
+	accessToken["tenant"] = "acme"
+	idToken["tenant"] = "acme"
+	return nil
+}
+
+func (p *testPlugin) ResolveClient(_ context.Context, id string) (*client.Client, error) {
+	return p.clients[id], nil
+}
+
+func (p *testPlugin) MapSubject(_ context.Context, _ *client.Client, subject string) (string, error) {
+	return "mapped:" + subject, nil
+}
+
+func (p *testPlugin) SubjectTypes() []string { return []string{"hmac"} }
+
+func (p *testPlugin) ObfuscateSubject(_ context.Context, subjectType string, c *client.Client, subject string) (string, error) {
+	return subjectType + ":" + c.GetID() + ":" + subject, nil
+}
+
+type namedPlugin string
+
+func (p namedPlugin) Name() string { return string(p) }
+
+func TestHooks(t *testing.T) {
+	ps := extension.Plugins{namedPlugin("a"), &testPlugin{}, namedPlugin("b")}
+	assert.Len(t, extension.Hooks[extension.ClaimsMutator](ps), 1)
+	assert.Len(t, extension.Hooks[extension.Plugin](ps), 3)
+	assert.Empty(t, extension.Hooks[extension.SubjectMapper](extension.Plugins{namedPlugin("a")}))
+}
+
+func TestPluginHooks(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeySubjectTypesSupported, []string{"public", "hmac", "unimplemented"})
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	reg.WithPlugins(extension.Plugins{&testPlugin{clients: map[string]*client.Client{
+		"external": {ID: "external", SubjectType: "public"},
+	}}})
+
+	t.Run("hook=client resolution", func(t *testing.T) {
+		c, err := reg.OAuth2Storage().GetClient(ctx, "external")
+		require.NoError(t, err)
+		assert.Equal(t, "external", c.GetID())
+
+		_, err = reg.OAuth2Storage().GetClient(ctx, "unknown")
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+
+	t.Run("hook=subject mapping", func(t *testing.T) {
+		subject, err := reg.ConsentStrategy().ObfuscateSubjectIdentifier(ctx, &client.Client{ID: "external", SubjectType: "public"}, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, "mapped:alice", subject)
+	})
+
+	t.Run("hook=subject identifier algorithm", func(t *testing.T) {
+		subject, err := reg.ConsentStrategy().ObfuscateSubjectIdentifier(ctx, &client.Client{ID: "external", SubjectType: "hmac"}, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, "hmac:external:mapped:alice", subject)
+
+		subject, err = reg.ConsentStrategy().ObfuscateSubjectIdentifier(ctx, &client.Client{ID: "external", SubjectType: "hmac"}, "alice", "forced")
+		require.NoError(t, err)
+		assert.Equal(t, "forced", subject)
+
+		_, err = reg.ConsentStrategy().ObfuscateSubjectIdentifier(ctx, &client.Client{ID: "external", SubjectType: "unimplemented"}, "alice", "")
+		assert.ErrorIs(t, err, fosite.ErrInvalidRequest)
+	})
+
+	t.Run("hook=grant authorization and claims", func(t *testing.T) {
+		hook := oauth2.PluginHook(reg)
+
+		session := oauth2.NewSession("alice")
+		require.NoError(t, hook(ctx, &fosite.AccessRequest{Request: fosite.Request{Client: &client.Client{ID: "external"}, Session: session}}))
+		assert.Equal(t, "acme", session.Extra["tenant"])
+		assert.Equal(t, "acme", session.IDTokenClaims().Extra["tenant"])
+
+		err := hook(ctx, &fosite.AccessRequest{Request: fosite.Request{Client: &client.Client{ID: "blocked"}, Session: oauth2.NewSession("alice")}})
+		assert.ErrorIs(t, err, fosite.ErrAccessDenied)
+	})
+}
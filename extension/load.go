@@ -0,0 +1,34 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build plugins
+// +build plugins
+
+package extension
+
+import (
+	"plugin"
+
+	"github.com/pkg/errors"
+)
+
+// Load opens the Go plugins at paths. Each plugin must export a variable "Plugin" of type Plugin.
+func Load(paths []string) (Plugins, error) {
+	ps := make(Plugins, 0, len(paths))
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to open the plugin %s", path)
+		}
+		sym, err := p.Lookup("Plugin")
+		if err != nil {
+			return nil, errors.Wrapf(err, "the plugin %s does not export the variable Plugin", path)
+		}
+		v, ok := sym.(*Plugin)
+		if !ok || *v == nil {
+			return nil, errors.Errorf("the variable Plugin of the plugin %s must be a non-nil extension.Plugin but is %T", path, sym)
+		}
+		ps = append(ps, *v)
+	}
+	return ps, nil
+}
@@ -0,0 +1,20 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !plugins
+// +build !plugins
+
+package extension
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Load returns an error if any plugins are configured, because Ory Hydra was built without the "plugins" build
+// tag.
+func Load(paths []string) (Plugins, error) {
+	if len(paths) > 0 {
+		return nil, errors.New(`loading plugins requires Ory Hydra to be built with the "plugins" build tag`)
+	}
+	return nil, nil
+}
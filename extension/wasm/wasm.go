@@ -0,0 +1,256 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wasm loads WebAssembly modules as plugins, which lets operators customize Ory Hydra without recompiling
+// it.
+//
+// A module exports its linear memory as "memory" and a function "alloc(size i32) i32" which returns a buffer of
+// size bytes. The extension points are exported functions with the signature "(ptr i32, len i32) i64" which take
+// a JSON document at ptr and return the position of the resulting JSON document as ptr<<32|len, or 0 if there is
+// nothing to change:
+//
+//   - validate_authorize_request takes an AuthorizeRequest and returns an Error to reject the request.
+//   - shape_claims takes a ClaimsRequest and returns Claims which replace the claims of the tokens.
+//
+// Every call runs in a fresh instance of the module, with limited memory and time, and without access to the file
+// system, the network or the environment. Modules compiled for WASI may use it with these restrictions.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/extension"
+)
+
+const (
+	FunctionAlloc                    = "alloc"
+	FunctionValidateAuthorizeRequest = "validate_authorize_request"
+	FunctionShapeClaims              = "shape_claims"
+)
+
+type (
+	// AuthorizeRequest is the input of validate_authorize_request.
+	AuthorizeRequest struct {
+		ClientID          string     `json:"client_id"`
+		RedirectURI       string     `json:"redirect_uri"`
+		ResponseTypes     []string   `json:"response_types"`
+		RequestedScope    []string   `json:"requested_scope"`
+		RequestedAudience []string   `json:"requested_audience"`
+		State             string     `json:"state"`
+		Form              url.Values `json:"form"`
+	}
+
+	// Error is the output of validate_authorize_request. The request is rejected if Error is set.
+	Error struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	// ClaimsRequest is the input of shape_claims.
+	ClaimsRequest struct {
+		ClientID        string   `json:"client_id"`
+		GrantTypes      []string `json:"grant_types"`
+		GrantedScope    []string `json:"granted_scope"`
+		GrantedAudience []string `json:"granted_audience"`
+		Subject         string   `json:"subject"`
+		Claims
+	}
+
+	// Claims are the extra claims of the tokens. A nil map leaves the claims of the token unchanged.
+	Claims struct {
+		AccessToken map[string]interface{} `json:"access_token"`
+		IDToken     map[string]interface{} `json:"id_token"`
+	}
+
+	// Module is a WebAssembly module loaded as a plugin.
+	Module struct {
+		name     string
+		timeout  time.Duration
+		runtime  wazero.Runtime
+		compiled wazero.CompiledModule
+	}
+)
+
+var (
+	_ extension.AuthorizeRequestValidator = (*Module)(nil)
+	_ extension.ClaimsMutator             = (*Module)(nil)
+)
+
+// Load compiles the configured WebAssembly modules.
+func Load(ctx context.Context, modules []config.WASMModule) (extension.Plugins, error) {
+	var plugins extension.Plugins
+	for _, c := range modules {
+		m, err := New(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		plugins = append(plugins, m)
+	}
+	return plugins, nil
+}
+
+// New compiles the WebAssembly module configured by c.
+func New(ctx context.Context, c config.WASMModule) (*Module, error) {
+	binary, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read the WebAssembly module %s", c.Path)
+	}
+	return Compile(ctx, filepath.Base(c.Path), binary, c.MaxMemoryPages, c.Timeout)
+}
+
+// Compile compiles the WebAssembly module binary.
+func Compile(ctx context.Context, name string, binary []byte, maxMemoryPages uint32, timeout time.Duration) (*Module, error) {
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(maxMemoryPages).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		_ = r.Close(ctx)
+		return nil, errors.WithStack(err)
+	}
+
+	compiled, err := r.CompileModule(ctx, binary)
+	if err != nil {
+		_ = r.Close(ctx)
+		return nil, errors.Wrapf(err, "could not compile the WebAssembly module %s", name)
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		_ = r.Close(ctx)
+		return nil, errors.Errorf("the WebAssembly module %s does not export its memory", name)
+	}
+	if _, ok := compiled.ExportedFunctions()[FunctionAlloc]; !ok {
+		_ = r.Close(ctx)
+		return nil, errors.Errorf("the WebAssembly module %s does not export the function %s", name, FunctionAlloc)
+	}
+
+	return &Module{name: name, timeout: timeout, runtime: r, compiled: compiled}, nil
+}
+
+func (m *Module) Name() string {
+	return m.name
+}
+
+// Close releases the runtime of the module.
+func (m *Module) Close(ctx context.Context) error {
+	return m.runtime.Close(ctx)
+}
+
+func (m *Module) ValidateAuthorizeRequest(ctx context.Context, ar fosite.AuthorizeRequester) error {
+	in := &AuthorizeRequest{
+		ClientID:          ar.GetClient().GetID(),
+		ResponseTypes:     ar.GetResponseTypes(),
+		RequestedScope:    ar.GetRequestedScopes(),
+		RequestedAudience: ar.GetRequestedAudience(),
+		State:             ar.GetState(),
+		Form:              ar.GetRequestForm(),
+	}
+	if u := ar.GetRedirectURI(); u != nil {
+		in.RedirectURI = u.String()
+	}
+
+	var out Error
+	if err := m.call(ctx, FunctionValidateAuthorizeRequest, in, &out); err != nil {
+		return err
+	}
+	if out.Error == "" {
+		return nil
+	}
+	return errors.WithStack(&fosite.RFC6749Error{
+		ErrorField:       out.Error,
+		DescriptionField: out.ErrorDescription,
+		CodeField:        http.StatusBadRequest,
+	})
+}
+
+func (m *Module) MutateClaims(ctx context.Context, requester fosite.AccessRequester, accessToken, idToken map[string]interface{}) error {
+	in := &ClaimsRequest{
+		ClientID:        requester.GetClient().GetID(),
+		GrantTypes:      requester.GetGrantTypes(),
+		GrantedScope:    requester.GetGrantedScopes(),
+		GrantedAudience: requester.GetGrantedAudience(),
+		Claims:          Claims{AccessToken: accessToken, IDToken: idToken},
+	}
+	if s := requester.GetSession(); s != nil {
+		in.Subject = s.GetSubject()
+	}
+
+	var out Claims
+	if err := m.call(ctx, FunctionShapeClaims, in, &out); err != nil {
+		return err
+	}
+	replace(accessToken, out.AccessToken)
+	replace(idToken, out.IDToken)
+	return nil
+}
+
+func replace(claims, with map[string]interface{}) {
+	if with == nil {
+		return
+	}
+	for k := range claims {
+		delete(claims, k)
+	}
+	for k, v := range with {
+		claims[k] = v
+	}
+}
+
+// call passes in to the exported function fn of a fresh instance of the module and decodes its result into out.
+// It does nothing if the module does not export fn.
+func (m *Module) call(ctx context.Context, fn string, in, out interface{}) error {
+	if _, ok := m.compiled.ExportedFunctions()[fn]; !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	mod, err := m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return errors.Wrapf(err, "could not instantiate the WebAssembly module %s", m.name)
+	}
+	defer mod.Close(ctx)
+
+	input, err := json.Marshal(in)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res, err := mod.ExportedFunction(FunctionAlloc).Call(ctx, uint64(len(input)))
+	if err != nil {
+		return errors.Wrapf(err, "could not call %s of the WebAssembly module %s", FunctionAlloc, m.name)
+	}
+	ptr := api.DecodeU32(res[0])
+	if !mod.Memory().Write(ptr, input) {
+		return errors.Errorf("%s of the WebAssembly module %s returned a buffer out of range", FunctionAlloc, m.name)
+	}
+
+	res, err = mod.ExportedFunction(fn).Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return errors.Wrapf(err, "could not call %s of the WebAssembly module %s", fn, m.name)
+	}
+	if res[0] == 0 {
+		return nil
+	}
+	output, ok := mod.Memory().Read(uint32(res[0]>>32), uint32(res[0]))
+	if !ok {
+		return errors.Errorf("%s of the WebAssembly module %s returned a result out of range", fn, m.name)
+	}
+	if err := json.Unmarshal(output, out); err != nil {
+		return errors.Wrapf(err, "could not decode the result of %s of the WebAssembly module %s", fn, m.name)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package wasm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/extension/wasm"
+	"github.com/ory/hydra/v2/oauth2"
+)
+
+// The modules are encoded by hand, because there is no WebAssembly toolchain in the test environment. Each module
+// exports its memory, an alloc which always returns 1024, and the function hook whose result is at 2048.
+
+const resultPtr = 2048
+
+func uleb(v uint64) (b []byte) {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func sleb(v int64) (b []byte) {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func vec(items ...[]byte) []byte {
+	b := uleb(uint64(len(items)))
+	for _, i := range items {
+		b = append(b, i...)
+	}
+	return b
+}
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb(uint64(len(content)))...), content...)
+}
+
+func name(n string) []byte {
+	return append(uleb(uint64(len(n))), n...)
+}
+
+func module(memoryPages uint64, hook string, body []byte, result string) []byte {
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	b = append(b, section(1, vec(
+		[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},       // (i32) -> i32
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e}, // (i32, i32) -> i64
+	))...)
+	b = append(b, section(3, vec([]byte{0x00}, []byte{0x01}))...)
+	b = append(b, section(5, vec(append([]byte{0x00}, uleb(memoryPages)...)))...)
+	b = append(b, section(7, vec(
+		append(name("memory"), 0x02, 0x00),
+		append(name("alloc"), 0x00, 0x00),
+		append(name(hook), 0x00, 0x01),
+	))...)
+
+	alloc := append(append([]byte{0x00, 0x41}, sleb(1024)...), 0x0b)
+	body = append([]byte{0x00}, body...)
+	b = append(b, section(10, vec(
+		append(uleb(uint64(len(alloc))), alloc...),
+		append(uleb(uint64(len(body))), body...),
+	))...)
+
+	offset := append(append([]byte{0x41}, sleb(resultPtr)...), 0x0b)
+	b = append(b, section(11, vec(
+		append(append([]byte{0x00}, offset...), name(result)...),
+	))...)
+	return b
+}
+
+// returnResult returns the position of result.
+func returnResult(result string) []byte {
+	return append(append([]byte{0x42}, sleb(int64(resultPtr<<32|len(result)))...), 0x0b)
+}
+
+var (
+	// returnNothing returns 0.
+	returnNothing = []byte{0x42, 0x00, 0x0b}
+	// returnInput returns the position of the input.
+	returnInput = []byte{0x20, 0x00, 0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0xad, 0x84, 0x0b}
+	// loopForever never returns.
+	loopForever = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00, 0x0b}
+)
+
+func compile(t *testing.T, binary []byte) *wasm.Module {
+	m, err := wasm.Compile(context.Background(), "test.wasm", binary, 16, time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = m.Close(context.Background()) })
+	return m
+}
+
+func authorizeRequest() *fosite.AuthorizeRequest {
+	ar := fosite.NewAuthorizeRequest()
+	ar.Client = &client.Client{ID: "client"}
+	return ar
+}
+
+func accessRequest() *fosite.AccessRequest {
+	ar := fosite.NewAccessRequest(oauth2.NewSession("subject"))
+	ar.Client = &client.Client{ID: "client"}
+	ar.GrantTypes = fosite.Arguments{"client_credentials"}
+	return ar
+}
+
+func TestValidateAuthorizeRequest(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=rejects the request", func(t *testing.T) {
+		result := `{"error":"access_denied","error_description":"The client may not be used today."}`
+		m := compile(t, module(1, wasm.FunctionValidateAuthorizeRequest, returnResult(result), result))
+
+		err := m.ValidateAuthorizeRequest(ctx, authorizeRequest())
+		var rfcErr *fosite.RFC6749Error
+		require.True(t, errors.As(err, &rfcErr), "%+v", err)
+		assert.Equal(t, "access_denied", rfcErr.ErrorField)
+		assert.Equal(t, "The client may not be used today.", rfcErr.DescriptionField)
+	})
+
+	t.Run("case=accepts the request", func(t *testing.T) {
+		m := compile(t, module(1, wasm.FunctionValidateAuthorizeRequest, returnNothing, ""))
+		require.NoError(t, m.ValidateAuthorizeRequest(ctx, authorizeRequest()))
+
+		result := `{}`
+		m = compile(t, module(1, wasm.FunctionValidateAuthorizeRequest, returnResult(result), result))
+		require.NoError(t, m.ValidateAuthorizeRequest(ctx, authorizeRequest()))
+	})
+
+	t.Run("case=ignores modules without the extension point", func(t *testing.T) {
+		m := compile(t, module(1, wasm.FunctionShapeClaims, loopForever, ""))
+		require.NoError(t, m.ValidateAuthorizeRequest(ctx, authorizeRequest()))
+	})
+}
+
+func TestMutateClaims(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=replaces the claims", func(t *testing.T) {
+		result := `{"access_token":{"tenant":"acme"}}`
+		m := compile(t, module(1, wasm.FunctionShapeClaims, returnResult(result), result))
+
+		at, idt := map[string]interface{}{"foo": "bar"}, map[string]interface{}{"foo": "bar"}
+		require.NoError(t, m.MutateClaims(ctx, accessRequest(), at, idt))
+		assert.Equal(t, map[string]interface{}{"tenant": "acme"}, at)
+		assert.Equal(t, map[string]interface{}{"foo": "bar"}, idt)
+	})
+
+	t.Run("case=receives the claims", func(t *testing.T) {
+		m := compile(t, module(1, wasm.FunctionShapeClaims, returnInput, ""))
+
+		at, idt := map[string]interface{}{"foo": "bar"}, map[string]interface{}{"baz": "qux"}
+		require.NoError(t, m.MutateClaims(ctx, accessRequest(), at, idt))
+		assert.Equal(t, map[string]interface{}{"foo": "bar"}, at)
+		assert.Equal(t, map[string]interface{}{"baz": "qux"}, idt)
+	})
+}
+
+func TestLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=times out", func(t *testing.T) {
+		m, err := wasm.Compile(ctx, "test.wasm", module(1, wasm.FunctionShapeClaims, loopForever, ""), 16, 50*time.Millisecond)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = m.Close(ctx) })
+
+		start := time.Now()
+		require.Error(t, m.MutateClaims(ctx, accessRequest(), map[string]interface{}{}, map[string]interface{}{}))
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("case=limits the memory", func(t *testing.T) {
+		_, err := wasm.Compile(ctx, "test.wasm", module(32, wasm.FunctionShapeClaims, returnNothing, ""), 16, time.Second)
+		require.Error(t, err)
+	})
+
+	t.Run("case=requires alloc and memory", func(t *testing.T) {
+		_, err := wasm.Compile(ctx, "test.wasm", []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, 16, time.Second)
+		require.Error(t, err)
+	})
+}
@@ -395,6 +395,32 @@ func (n *OAuth2ConsentRequestOpenIDConnectContext) Value() (driver.Value, error)
 	return value, errorsx.WithStack(err)
 }
 
+// AuthorizationRequestParameters are the parameters of the original OAuth 2.0 Authorization Request, including the
+// parameters of request objects and pushed authorization requests. Client credentials and tokens, such as the
+// id_token_hint, are removed.
+//
+// swagger:model authorizationRequestParameters
+type AuthorizationRequestParameters map[string][]string
+
+func (p *AuthorizationRequestParameters) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v := fmt.Sprintf("%s", value)
+	if len(v) == 0 {
+		return nil
+	}
+	return errorsx.WithStack(json.Unmarshal([]byte(v), p))
+}
+
+func (p AuthorizationRequestParameters) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	value, err := json.Marshal(p)
+	return string(value), errorsx.WithStack(err)
+}
+
 // Contains information about an ongoing logout request.
 //
 // swagger:model oAuth2LogoutRequest
@@ -428,6 +454,11 @@ type LogoutRequest struct {
 	Rejected              bool           `db:"rejected" json:"-"`
 	ClientID              sql.NullString `json:"-" db:"client_id"`
 	Client                *client.Client `json:"client" db:"-"`
+	RequestedAt           sqlxx.NullTime `json:"-" db:"requested_at"`
+
+	// ExpiresAt is the time when the logout challenge expires. It is not set for logout requests which were
+	// created before logout challenges expired.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"-"`
 }
 
 func (LogoutRequest) TableName() string {
@@ -524,9 +555,14 @@ type LoginRequest struct {
 	ForceSubjectIdentifier string `json:"-"` // this is here but has no meaning apart from sql_helper working properly.
 	Verifier               string `json:"-"`
 	CSRF                   string `json:"-"`
+	CorrelationID          string `json:"-"`
 
 	AuthenticatedAt sqlxx.NullTime `json:"-"`
 	RequestedAt     time.Time      `json:"-"`
+
+	// ExpiresAt is the time when the login challenge expires. The login request must be accepted or rejected
+	// before then.
+	ExpiresAt time.Time `json:"expires_at" faker:"-"`
 }
 
 // Contains information on an ongoing consent request.
@@ -567,6 +603,10 @@ type OAuth2ConsentRequest struct {
 	// might come in handy if you want to deal with additional request parameters.
 	RequestURL string `json:"request_url"`
 
+	// RequestParameters are the parameters of the original OAuth 2.0 Authorization Request. Unlike the query of the
+	// request_url, they include the parameters of request objects, pushed authorization requests and POST requests.
+	RequestParameters AuthorizationRequestParameters `json:"request_parameters,omitempty"`
+
 	// LoginChallenge is the login challenge this consent challenge belongs to. It can be used to associate
 	// a login and consent request in the login & consent app.
 	LoginChallenge sqlxx.NullString `json:"login_challenge"`
@@ -602,6 +642,10 @@ type OAuth2ConsentRequest struct {
 	CSRF                   string         `json:"-"`
 	AuthenticatedAt        sqlxx.NullTime `json:"-"`
 	RequestedAt            time.Time      `json:"-"`
+
+	// ExpiresAt is the time when the consent challenge expires. The consent request must be accepted or rejected
+	// before then.
+	ExpiresAt time.Time `json:"expires_at" faker:"-"`
 }
 
 // Pass session data to a consent request.
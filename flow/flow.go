@@ -15,6 +15,7 @@ import (
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/oauth2/flowctx"
 	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/sqlxx"
 )
@@ -130,6 +131,13 @@ type Flow struct {
 	// required: true
 	RequestURL string `db:"request_url"`
 
+	// RequestParameters are the sanitized parameters of the original OAuth 2.0 Authorization Request.
+	RequestParameters AuthorizationRequestParameters `db:"request_parameters"`
+
+	// CorrelationID identifies the browser flow across the redirects between Ory Hydra, the login and consent apps,
+	// and the client. It is carried in the challenges and verifiers but not persisted.
+	CorrelationID string `json:",omitempty" db:"-"`
+
 	// SessionID is the login session ID. If the user-agent reuses a login session (via cookie / remember flag)
 	// this ID will remain the same. If the user-agent did not have an existing authentication session (e.g. remember is false)
 	// this will be a new random value. This value is used as the "sid" parameter in the ID Token and in OIDC Front-/Back-
@@ -253,6 +261,7 @@ func NewFlow(r *LoginRequest) *Flow {
 		Client:                 r.Client,
 		ClientID:               r.ClientID,
 		RequestURL:             r.RequestURL,
+		CorrelationID:          r.CorrelationID,
 		SessionID:              r.SessionID,
 		LoginWasUsed:           r.WasHandled,
 		ForceSubjectIdentifier: r.ForceSubjectIdentifier,
@@ -338,6 +347,7 @@ func (f *Flow) GetLoginRequest() *LoginRequest {
 		Client:                 f.Client,
 		ClientID:               f.ClientID,
 		RequestURL:             f.RequestURL,
+		CorrelationID:          f.CorrelationID,
 		SessionID:              f.SessionID,
 		WasHandled:             f.LoginWasUsed,
 		ForceSubjectIdentifier: f.ForceSubjectIdentifier,
@@ -426,6 +436,7 @@ func (f *Flow) GetConsentRequest() *OAuth2ConsentRequest {
 		Client:                 f.Client,
 		ClientID:               f.ClientID,
 		RequestURL:             f.RequestURL,
+		RequestParameters:      f.RequestParameters,
 		LoginChallenge:         sqlxx.NullString(f.ID),
 		LoginSessionID:         f.SessionID,
 		ACR:                    f.ACR,
@@ -467,6 +478,14 @@ func (f *Flow) GetHandledConsentRequest() *AcceptOAuth2ConsentRequest {
 	}
 }
 
+// PageToken encodes the position of the flow in a list ordered by requested_at (newest first) and login_challenge.
+func (f Flow) PageToken() keysetpagination.PageToken {
+	return keysetpagination.MapPageToken{
+		"login_challenge": f.ID,
+		"requested_at":    f.RequestedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
 func (Flow) TableName() string {
 	return "hydra_oauth2_flow"
 }
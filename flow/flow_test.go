@@ -25,6 +25,7 @@ func (f *Flow) setLoginRequest(r *LoginRequest) {
 	f.Client = r.Client
 	f.ClientID = r.ClientID
 	f.RequestURL = r.RequestURL
+	f.CorrelationID = r.CorrelationID
 	f.SessionID = r.SessionID
 	f.LoginWasUsed = r.WasHandled
 	f.ForceSubjectIdentifier = r.ForceSubjectIdentifier
@@ -61,6 +62,7 @@ func (f *Flow) setConsentRequest(r OAuth2ConsentRequest) {
 	f.Client = r.Client
 	f.ClientID = r.ClientID
 	f.RequestURL = r.RequestURL
+	f.RequestParameters = r.RequestParameters
 	f.ID = r.LoginChallenge.String()
 	f.SessionID = r.LoginSessionID
 	f.ACR = r.ACR
@@ -94,8 +94,15 @@ func (c *Config) GetJWKSFetcherStrategy(context.Context) fosite.JWKSFetcherStrat
 	return c.deps.GetJWKSFetcherStrategy()
 }
 
+// GetHTTPClient returns the HTTP client which fosite fetches request objects passed by reference with. Their size is
+// limited like the size of request objects passed by value.
 func (c *Config) GetHTTPClient(ctx context.Context) *retryablehttp.Client {
-	return c.deps.HTTPClient(ctx)
+	hc := c.deps.HTTPClient(ctx)
+	hc.HTTPClient.Transport = &limitedBodyTransport{
+		next: hc.HTTPClient.Transport,
+		max:  int64(c.DefaultProvider.OAuth2MaxRequestObjectSize(ctx)),
+	}
+	return hc
 }
 
 func (c *Config) GetAuthorizeEndpointHandlers(context.Context) fosite.AuthorizeEndpointHandlers {
@@ -159,9 +166,13 @@ func (c *Config) GetSendDebugMessagesToClients(ctx context.Context) bool {
 	return c.deps.Config().GetSendDebugMessagesToClients(ctx)
 }
 
-func (c *Config) GetMessageCatalog(context.Context) i18n.MessageCatalog {
-	// Fosite falls back to the default messages when this is nil.
-	return nil
+func (c *Config) GetMessageCatalog(ctx context.Context) i18n.MessageCatalog {
+	messages := c.deps.Config().I18nMessages(ctx)
+	if len(messages) == 0 {
+		// Fosite falls back to the default messages when this is nil.
+		return nil
+	}
+	return NewMessageCatalog(c.deps.Config().I18nDefaultLocale(ctx), messages)
 }
 
 func (c *Config) GetSecretsHasher(context.Context) fosite.Hasher {
@@ -0,0 +1,54 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package fositex
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+var _ openid.OpenIDConnectTokenStrategy = (*IDTokenStrategy)(nil)
+
+// IDTokenStrategy issues ID tokens as SD-JWT to the configured clients, and as JWT to all others.
+type IDTokenStrategy struct {
+	c   *config.DefaultProvider
+	jwt openid.OpenIDConnectTokenStrategy
+}
+
+// NewIDTokenStrategy returns a new IDTokenStrategy.
+func NewIDTokenStrategy(c *config.DefaultProvider, jwt openid.OpenIDConnectTokenStrategy) *IDTokenStrategy {
+	return &IDTokenStrategy{c: c, jwt: jwt}
+}
+
+// GenerateIDToken generates the ID token. The custom claims of SD-JWT ID tokens, except the session ID, are
+// replaced by the digests of their disclosures, which are appended to the ID token.
+func (s *IDTokenStrategy) GenerateIDToken(ctx context.Context, lifespan time.Duration, requester fosite.Requester) (string, error) {
+	sess, ok := requester.GetSession().(openid.Session)
+	if !ok || sess.IDTokenClaims() == nil || !stringslice.Has(s.c.OIDCSDJWTIDTokenClients(ctx), requester.GetClient().GetID()) {
+		return s.jwt.GenerateIDToken(ctx, lifespan, requester)
+	}
+
+	claims := sess.IDTokenClaims()
+	extra := claims.Extra
+	concealed, disclosures, err := x.ConcealClaims(extra, "sid")
+	if err != nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	claims.Extra = concealed
+	defer func() { claims.Extra = extra }()
+
+	token, err := s.jwt.GenerateIDToken(ctx, lifespan, requester)
+	if err != nil {
+		return "", err
+	}
+	return x.SDJWT(token, disclosures), nil
+}
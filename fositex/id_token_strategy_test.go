@@ -0,0 +1,75 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package fositex
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/logrusx"
+)
+
+type claimsRecorder struct {
+	claims map[string]interface{}
+}
+
+func (r *claimsRecorder) GenerateIDToken(_ context.Context, _ time.Duration, requester fosite.Requester) (string, error) {
+	r.claims = requester.GetSession().(openid.Session).IDTokenClaims().ToMap()
+	return "header.payload.signature", nil
+}
+
+func TestIDTokenStrategy(t *testing.T) {
+	ctx := context.Background()
+	c := config.MustNew(ctx, logrusx.New("", ""))
+	c.MustSet(ctx, config.KeyOIDCSDJWTIDTokenClients, []string{"wallet"})
+
+	newRequest := func(clientID string) *fosite.Request {
+		r := fosite.NewRequest()
+		r.Client = &client.Client{ID: clientID}
+		r.Session = &openid.DefaultSession{
+			Claims:  &jwt.IDTokenClaims{Subject: "alice", Extra: map[string]interface{}{"email": "alice@example.com", "sid": "session"}},
+			Headers: new(jwt.Headers),
+		}
+		return r
+	}
+
+	t.Run("case=issues JWT to other clients", func(t *testing.T) {
+		recorder := new(claimsRecorder)
+		token, err := NewIDTokenStrategy(c, recorder).GenerateIDToken(ctx, time.Hour, newRequest("other"))
+		require.NoError(t, err)
+		assert.Equal(t, "header.payload.signature", token)
+		assert.Equal(t, "alice@example.com", recorder.claims["email"])
+	})
+
+	t.Run("case=issues SD-JWT to configured clients", func(t *testing.T) {
+		recorder := new(claimsRecorder)
+		r := newRequest("wallet")
+		token, err := NewIDTokenStrategy(c, recorder).GenerateIDToken(ctx, time.Hour, r)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(token, "header.payload.signature~"))
+		assert.True(t, strings.HasSuffix(token, "~"))
+
+		assert.NotContains(t, recorder.claims, "email")
+		assert.Equal(t, "session", recorder.claims["sid"])
+		assert.Equal(t, "alice", recorder.claims["sub"])
+
+		disclosed, err := x.DisclosedClaims(recorder.claims, token)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"email": "alice@example.com"}, disclosed)
+
+		// The claims of the session are restored
+		assert.Equal(t, "alice@example.com", r.Session.(*openid.DefaultSession).Claims.Extra["email"])
+	})
+}
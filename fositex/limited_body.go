@@ -0,0 +1,46 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package fositex
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// limitedBodyTransport fails reading response bodies which are larger than max bytes. The size is checked while reading
+// rather than by the Content-Length header, which the retrying client would otherwise retry on.
+type limitedBodyTransport struct {
+	next http.RoundTripper
+	max  int64
+}
+
+func (t *limitedBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = &limitedBody{ReadCloser: res.Body, remaining: t.max, max: t.max}
+	return res, nil
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining, max int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, errors.Errorf("the response body exceeds the maximum size of %d bytes", b.max)
+	}
+	return n, err
+}
@@ -0,0 +1,97 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package fositex
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/ory/fosite/i18n"
+)
+
+var _ i18n.MessageCatalog = (*MessageCatalog)(nil)
+
+// MessageCatalog translates messages with the messages of the configuration. Messages which are not translated
+// are left to the English defaults of the caller.
+type MessageCatalog struct {
+	// tags are the configured locales, starting with the default locale.
+	tags     []language.Tag
+	messages map[language.Tag]map[string]string
+	matcher  language.Matcher
+}
+
+// NewMessageCatalog returns a message catalog of the given messages, keyed by locale and message ID. Locales which
+// are not valid BCP 47 language tags are ignored.
+func NewMessageCatalog(defaultLocale string, messages map[string]map[string]string) *MessageCatalog {
+	def, err := language.Parse(defaultLocale)
+	if err != nil {
+		def = language.English
+	}
+
+	c := &MessageCatalog{
+		tags:     []language.Tag{def},
+		messages: make(map[language.Tag]map[string]string, len(messages)),
+	}
+	for locale, m := range messages {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			continue
+		}
+		if tag != def {
+			c.tags = append(c.tags, tag)
+		}
+		c.messages[tag] = m
+	}
+	c.matcher = language.NewMatcher(c.tags)
+
+	return c
+}
+
+// GetMessage returns the message with the given ID in the given locale, or in the default locale if it is not
+// translated into the given one. It returns the ID if the message is not translated at all.
+func (c *MessageCatalog) GetMessage(id string, tag language.Tag, v ...interface{}) string {
+	_, i, _ := c.matcher.Match(tag)
+	for _, t := range []language.Tag{tag, c.tags[i], c.tags[0]} {
+		if message, ok := c.messages[t][id]; ok {
+			if len(v) > 0 {
+				return fmt.Sprintf(message, v...)
+			}
+			return message
+		}
+	}
+	return id
+}
+
+// GetLangFromRequest returns the configured locale which matches the ui_locales parameter, the lang cookie or the
+// Accept-Language header of the request best, in this order. It returns the default locale if none matches.
+func (c *MessageCatalog) GetLangFromRequest(r *http.Request) language.Tag {
+	uiLocales := r.URL.Query().Get("ui_locales")
+	if r.Form != nil {
+		uiLocales = r.Form.Get("ui_locales")
+	}
+
+	var preferred []language.Tag
+	for _, locale := range strings.Fields(uiLocales) {
+		if tag, err := language.Parse(locale); err == nil {
+			preferred = append(preferred, tag)
+		}
+	}
+	if cookie, err := r.Cookie("lang"); err == nil {
+		if tag, err := language.Parse(cookie.Value); err == nil {
+			preferred = append(preferred, tag)
+		}
+	}
+	if accept, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil {
+		preferred = append(preferred, accept...)
+	}
+
+	_, i, confidence := c.matcher.Match(preferred...)
+	if confidence == language.No {
+		return c.tags[0]
+	}
+	return c.tags[i]
+}
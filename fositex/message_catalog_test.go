@@ -0,0 +1,66 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package fositex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	"github.com/ory/fosite"
+)
+
+func TestMessageCatalog(t *testing.T) {
+	c := NewMessageCatalog("de", map[string]map[string]string{
+		"de": {
+			"invalid_request": "Die Anfrage ist fehlerhaft.",
+			"greeting":        "Hallo %s!",
+			"only_german":     "Nur auf Deutsch.",
+		},
+		"fr":      {"invalid_request": "La requête est invalide."},
+		"invalid": {"invalid_request": "ignored"},
+	})
+
+	t.Run("case=selects the language", func(t *testing.T) {
+		for _, tc := range []struct {
+			d        string
+			query    string
+			cookie   string
+			accept   string
+			expected language.Tag
+		}{
+			{d: "defaults to the default locale", expected: language.German},
+			{d: "prefers ui_locales", query: "ui_locales=es+fr-CA+de", accept: "de", expected: language.French},
+			{d: "prefers the cookie over the header", cookie: "fr", accept: "de", expected: language.French},
+			{d: "uses the header", accept: "en-US, fr;q=0.8", expected: language.French},
+			{d: "falls back to the default locale", query: "ui_locales=ja", accept: "es", expected: language.German},
+		} {
+			t.Run("case="+tc.d, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+				if tc.cookie != "" {
+					r.AddCookie(&http.Cookie{Name: "lang", Value: tc.cookie})
+				}
+				r.Header.Set("Accept-Language", tc.accept)
+				assert.Equal(t, tc.expected, c.GetLangFromRequest(r))
+			})
+		}
+	})
+
+	t.Run("case=translates messages", func(t *testing.T) {
+		assert.Equal(t, "La requête est invalide.", c.GetMessage("invalid_request", language.French))
+		assert.Equal(t, "La requête est invalide.", c.GetMessage("invalid_request", language.MustParse("fr-CA")))
+		assert.Equal(t, "Nur auf Deutsch.", c.GetMessage("only_german", language.French), "falls back to the default locale")
+		assert.Equal(t, "Hallo Alice!", c.GetMessage("greeting", language.German, "Alice"))
+		assert.Equal(t, "unknown", c.GetMessage("unknown", language.French), "returns the ID of unknown messages")
+	})
+
+	t.Run("case=localizes errors", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(fosite.ErrInvalidRequest.WithLocalizer(c, language.German).GetDescription(), "Die Anfrage ist fehlerhaft."))
+		assert.Equal(t, fosite.ErrInvalidClient.GetDescription(), fosite.ErrInvalidClient.WithLocalizer(c, language.German).GetDescription())
+	})
+}
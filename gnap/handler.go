@@ -0,0 +1,811 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package gnap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/i18n"
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/httprouterx"
+	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/stringslice"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	GrantPath    = "/gnap"
+	InteractPath = "/gnap/interact"
+	ContinuePath = "/gnap/continue"
+
+	// AuthorizationScheme is the scheme of the Authorization header with which continuation access tokens are sent.
+	AuthorizationScheme = "GNAP"
+
+	// GrantTypeGNAP is the grant type which is recorded for access tokens issued with GNAP.
+	GrantTypeGNAP fosite.GrantType = "gnap"
+
+	StartModeRedirect    = "redirect"
+	FinishMethodRedirect = "redirect"
+	FinishMethodPush     = "push"
+	HashMethodSHA256     = "sha-256"
+	FlagBearer           = "bearer"
+
+	// continueWait is how many seconds client instances should wait before they poll a pending grant request.
+	continueWait = 5
+)
+
+var (
+	ErrInvalidClient = &fosite.RFC6749Error{
+		ErrorField:       "invalid_client",
+		DescriptionField: "The client instance is unknown or its request is not signed with one of its keys.",
+		CodeField:        http.StatusUnauthorized,
+	}
+	ErrInvalidInteraction = &fosite.RFC6749Error{
+		ErrorField:       "invalid_interaction",
+		DescriptionField: "The interaction modes of the grant request are not supported.",
+		CodeField:        http.StatusBadRequest,
+	}
+	ErrInvalidFlag = &fosite.RFC6749Error{
+		ErrorField:       "invalid_flag",
+		DescriptionField: "The flags of the requested access token are not supported.",
+		CodeField:        http.StatusBadRequest,
+	}
+	ErrInvalidContinuation = &fosite.RFC6749Error{
+		ErrorField:       "invalid_continuation",
+		DescriptionField: "The grant request can not be continued with this continuation access token.",
+		CodeField:        http.StatusUnauthorized,
+	}
+	ErrUnknownInteraction = &fosite.RFC6749Error{
+		ErrorField:       "unknown_interaction",
+		DescriptionField: "The interaction reference does not belong to the grant request.",
+		CodeField:        http.StatusBadRequest,
+	}
+	ErrTooFast = &fosite.RFC6749Error{
+		ErrorField:       "too_fast",
+		DescriptionField: "The grant request was continued before the interaction finished.",
+		CodeField:        http.StatusBadRequest,
+	}
+	ErrUserDenied = &fosite.RFC6749Error{
+		ErrorField:       "user_denied",
+		DescriptionField: "The resource owner denied the grant request.",
+		CodeField:        http.StatusForbidden,
+	}
+	ErrRequestDenied = &fosite.RFC6749Error{
+		ErrorField:       "request_denied",
+		DescriptionField: "The grant request was denied.",
+		CodeField:        http.StatusForbidden,
+	}
+)
+
+// Handler serves an experimental implementation of the Grant Negotiation and Authorization Protocol (GNAP). Client
+// instances are registered OAuth 2.0 clients which sign their requests with their JSON Web Keys, and resource owners
+// approve grant requests in the login and consent flow.
+type Handler struct {
+	r InternalRegistry
+}
+
+func NewHandler(r InternalRegistry) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) SetRoutes(public *httprouterx.RouterPublic) {
+	public.POST(GrantPath, h.requestGNAPGrant)
+	public.GET(InteractPath+"/:id", h.interactGNAPGrant)
+	public.POST(ContinuePath+"/:id", h.continueGNAPGrant)
+	public.DELETE(ContinuePath+"/:id", h.revokeGNAPGrant)
+}
+
+// Request a GNAP Grant
+//
+// swagger:parameters requestGNAPGrant
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type requestGNAPGrant struct {
+	// in: body
+	// required: true
+	Body GrantRequest
+}
+
+// swagger:route POST /gnap gnap requestGNAPGrant
+//
+// # Request a GNAP Grant
+//
+// Requests an access token with the Grant Negotiation and Authorization Protocol. This endpoint is experimental.
+//
+// The request must be signed with a detached JSON Web Signature with one of the JSON Web Keys of the client instance.
+// If the grant request asks for interaction, the client instance redirects the resource owner to the returned
+// interaction URI, where the resource owner approves the grant request in the login and consent flow. Otherwise, the
+// access token is issued to the client instance itself.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: gnapGrantResponse
+//	  default: gnapErrorResponse
+func (h *Handler) requestGNAPGrant(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	if err := h.requireGNAP(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeError(w, r, errorsx.WithStack(err))
+		return
+	}
+	var req GrantRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.writeError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err)))
+		return
+	}
+
+	clientID, err := req.clientID()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	cl, err := h.r.ClientManager().GetConcreteClient(ctx, clientID)
+	if err != nil {
+		h.writeError(w, r, errorsx.WithStack(ErrInvalidClient.WithHint("The client instance is not a registered OAuth 2.0 client.")))
+		return
+	}
+	if err := h.verifyProof(r, body, cl, h.grantURL(ctx), ""); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	scopes, err := req.AccessToken.scopes()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, scope := range scopes {
+		if !h.r.Config().GetScopeStrategy(ctx)(cl.GetScopes(), scope) {
+			h.writeError(w, r, errorsx.WithStack(ErrRequestDenied.WithHintf("The client instance is not allowed to request access to '%s'.", scope)))
+			return
+		}
+	}
+	for _, flag := range req.AccessToken.Flags {
+		if flag != FlagBearer {
+			h.writeError(w, r, errorsx.WithStack(ErrInvalidFlag.WithHintf("The flag '%s' is not supported.", flag)))
+			return
+		}
+	}
+
+	if req.Interact == nil {
+		if !cl.GetGrantTypes().Has("client_credentials") {
+			h.writeError(w, r, errorsx.WithStack(ErrRequestDenied.WithHint("The client instance must interact with the resource owner, as it is not allowed to use the client_credentials grant.")))
+			return
+		}
+
+		token, err := h.issueAccessToken(ctx, cl, &Grant{
+			ID:           uuid.Must(uuid.NewV4()),
+			Subject:      cl.GetID(),
+			GrantedScope: scopes,
+		}, req.AccessToken.Label)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		h.r.Writer().Write(w, r, &GrantResponse{AccessToken: token})
+		return
+	}
+
+	g, err := h.newGrant(ctx, cl, &req, scopes)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	continuationToken, err := randomToken()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	g.ContinuationTokenSignature = tokenSignature(continuationToken)
+
+	if err := h.r.GNAPManager().CreateGNAPGrant(ctx, g); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	res := &GrantResponse{
+		Continue: h.continuation(ctx, g, continuationToken),
+		Interact: &InteractResponse{Redirect: h.interactionURL(ctx, g).String()},
+	}
+	if g.FinishMethod != "" {
+		res.Interact.Finish = g.ServerNonce
+	}
+	h.r.Writer().Write(w, r, res)
+}
+
+// newGrant validates the interaction modes of the grant request and returns the pending grant.
+func (h *Handler) newGrant(ctx context.Context, cl *client.Client, req *GrantRequest, scopes []string) (*Grant, error) {
+	if !stringslice.Has(req.Interact.Start, StartModeRedirect) {
+		return nil, errorsx.WithStack(ErrInvalidInteraction.WithHintf("The interaction start mode '%s' is required.", StartModeRedirect))
+	}
+
+	now := h.r.Clock().Now().UTC().Round(time.Second)
+	g := &Grant{
+		ID:             uuid.Must(uuid.NewV4()),
+		ClientID:       cl.GetID(),
+		Status:         GrantStatusPending,
+		RequestedScope: scopes,
+		ExpiresAt:      now.Add(h.r.Config().GNAPGrantRequestLifespan(ctx)),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if finish := req.Interact.Finish; finish != nil {
+		if finish.Method != FinishMethodRedirect && finish.Method != FinishMethodPush {
+			return nil, errorsx.WithStack(ErrInvalidInteraction.WithHintf("The interaction finish method '%s' is not supported.", finish.Method))
+		}
+		if finish.HashMethod != "" && finish.HashMethod != HashMethodSHA256 {
+			return nil, errorsx.WithStack(ErrInvalidInteraction.WithHintf("The hash method '%s' is not supported.", finish.HashMethod))
+		}
+		if finish.Nonce == "" {
+			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The interaction finish nonce must not be empty."))
+		}
+		if !stringslice.Has(cl.GetRedirectURIs(), finish.URI) {
+			return nil, errorsx.WithStack(ErrInvalidInteraction.WithHint("The interaction finish URI must be one of the redirect URIs of the client instance."))
+		}
+
+		serverNonce, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		g.FinishMethod = finish.Method
+		g.FinishURI = finish.URI
+		g.FinishNonce = finish.Nonce
+		g.ServerNonce = serverNonce
+	}
+	return g, nil
+}
+
+// GNAP Interaction Parameters
+//
+// swagger:parameters interactGNAPGrant
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type interactGNAPGrant struct {
+	// The ID of the grant request.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// The ID of the client instance.
+	//
+	// in: query
+	// required: true
+	ClientID string `json:"client_id"`
+}
+
+// swagger:route GET /gnap/interact/{id} gnap interactGNAPGrant
+//
+// # Interact with the Resource Owner
+//
+// Starts the login and consent flow in which the resource owner approves the grant request. The user agent returns
+// to this endpoint after login and consent and is then sent to the interaction finish URI of the client instance.
+// This endpoint is experimental.
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  302: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) interactGNAPGrant(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	if err := h.requireGNAP(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	g, err := h.getGrant(ctx, ps.ByName("id"))
+	if err != nil {
+		h.forwardError(w, r, err)
+		return
+	}
+	if g.Status != GrantStatusPending {
+		h.forwardError(w, r, errorsx.WithStack(ErrUnknownInteraction.WithHint("The interaction of the grant request has already finished.")))
+		return
+	}
+
+	cl, err := h.r.ClientManager().GetConcreteClient(ctx, g.ClientID)
+	if err != nil {
+		h.forwardError(w, r, err)
+		return
+	}
+
+	session, _, err := h.r.ConsentStrategy().HandleOAuth2AuthorizationRequest(ctx, w, r, h.interactionRequest(r, g, cl))
+	if errors.Is(err, consent.ErrAbortOAuth2Request) {
+		x.LogAudit(r, nil, h.r.AuditLogger())
+		return
+	} else if err != nil {
+		if fosite.ErrorToRFC6749Error(err).StatusCode() >= http.StatusInternalServerError {
+			x.LogError(r, err, h.r.Logger())
+			h.forwardError(w, r, err)
+			return
+		}
+
+		// The client instance learns that the grant request was denied when it continues the grant request.
+		x.LogAudit(r, err, h.r.AuditLogger())
+		g.Status = GrantStatusDenied
+	} else {
+		g.Status = GrantStatusApproved
+		g.Subject = session.ConsentRequest.Subject
+		g.ForceSubjectIdentifier = session.ConsentRequest.ForceSubjectIdentifier
+		g.ConsentChallenge = sqlxx.NullString(session.ID)
+		g.GrantedScope = session.GrantedScope
+		g.GrantedAudience = session.GrantedAudience
+		g.SessionAccessToken = session.Session.AccessToken
+	}
+
+	if g.FinishMethod != "" {
+		if g.InteractRef, err = randomToken(); err != nil {
+			h.forwardError(w, r, err)
+			return
+		}
+	}
+	g.UpdatedAt = h.r.Clock().Now().UTC().Round(time.Second)
+	if err := h.r.GNAPManager().UpdateGNAPGrant(ctx, g); err != nil {
+		h.forwardError(w, r, err)
+		return
+	}
+
+	h.finishInteraction(w, r, g)
+}
+
+// finishInteraction tells the client instance that the interaction has finished, using the finish method of the
+// grant request.
+func (h *Handler) finishInteraction(w http.ResponseWriter, r *http.Request, g *Grant) {
+	ctx := r.Context()
+	switch g.FinishMethod {
+	case FinishMethodRedirect:
+		finishURI, err := url.Parse(g.FinishURI)
+		if err != nil {
+			h.forwardError(w, r, errorsx.WithStack(err))
+			return
+		}
+		http.Redirect(w, r, urlx.SetQuery(finishURI, url.Values{
+			"hash":         {h.interactionHash(ctx, g)},
+			"interact_ref": {g.InteractRef},
+		}).String(), http.StatusFound)
+		return
+	case FinishMethodPush:
+		if err := h.pushInteraction(ctx, g); err != nil {
+			x.LogError(r, err, h.r.Logger())
+			h.forwardError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("The interaction has finished. You can close this window and return to the application."))
+}
+
+// pushInteraction sends the interaction hash and reference to the finish URI of the client instance.
+func (h *Handler) pushInteraction(ctx context.Context, g *Grant) error {
+	body, err := json.Marshal(map[string]string{
+		"hash":         h.interactionHash(ctx, g),
+		"interact_ref": g.InteractRef,
+	})
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.FinishURI, bytes.NewReader(body))
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.r.HTTPClient(ctx).StandardClient().Do(req)
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithHint("Unable to push the interaction to the client instance.").WithDebug(err.Error()))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errorsx.WithStack(fosite.ErrServerError.WithHint("Unable to push the interaction to the client instance.").WithDebugf("The client instance responded with HTTP status code: %s", res.Status))
+	}
+	return nil
+}
+
+// GNAP Continuation Parameters
+//
+// swagger:parameters continueGNAPGrant
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type continueGNAPGrant struct {
+	// The ID of the grant request.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Body ContinueRequest
+}
+
+// swagger:route POST /gnap/continue/{id} gnap continueGNAPGrant
+//
+// # Continue a GNAP Grant Request
+//
+// Returns the access token once the resource owner approved the grant request. The request must carry the
+// continuation access token in the Authorization header with the GNAP scheme, and must be signed like the grant
+// request. This endpoint is experimental.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: gnapGrantResponse
+//	  default: gnapErrorResponse
+func (h *Handler) continueGNAPGrant(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	if err := h.requireGNAP(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	g, cl, continuationToken, body, err := h.authenticateContinuation(r, ps.ByName("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	var req ContinueRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			h.writeError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err)))
+			return
+		}
+	}
+
+	switch g.Status {
+	case GrantStatusPending:
+		if g.FinishMethod != "" {
+			h.writeError(w, r, errorsx.WithStack(ErrTooFast))
+			return
+		}
+		h.r.Writer().Write(w, r, &GrantResponse{Continue: h.continuation(ctx, g, continuationToken)})
+		return
+	case GrantStatusDenied:
+		if err := h.r.GNAPManager().DeleteGNAPGrant(ctx, g.ID); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		h.writeError(w, r, errorsx.WithStack(ErrUserDenied))
+		return
+	}
+
+	if g.FinishMethod != "" && subtle.ConstantTimeCompare([]byte(req.InteractRef), []byte(g.InteractRef)) != 1 {
+		h.writeError(w, r, errorsx.WithStack(ErrUnknownInteraction))
+		return
+	}
+
+	// The grant request is deleted before the access token is issued, so that it can not be continued twice. If it
+	// is gone already, a concurrent continuation was faster.
+	if err := h.r.GNAPManager().DeleteGNAPGrant(ctx, g.ID); errors.Is(err, sqlcon.ErrNoRows) {
+		h.writeError(w, r, errorsx.WithStack(ErrInvalidContinuation.WithWrap(err).WithHint("The grant request has been continued already.")))
+		return
+	} else if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	token, err := h.issueAccessToken(ctx, cl, g, "")
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.r.Writer().Write(w, r, &GrantResponse{AccessToken: token})
+}
+
+// GNAP Revocation Parameters
+//
+// swagger:parameters revokeGNAPGrant
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type revokeGNAPGrant struct {
+	// The ID of the grant request.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /gnap/continue/{id} gnap revokeGNAPGrant
+//
+// # Revoke a GNAP Grant Request
+//
+// Cancels the grant request. The request must be authenticated like a continuation request. This endpoint is
+// experimental.
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: gnapErrorResponse
+func (h *Handler) revokeGNAPGrant(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.requireGNAP(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	g, _, _, _, err := h.authenticateContinuation(r, ps.ByName("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if err := h.r.GNAPManager().DeleteGNAPGrant(r.Context(), g.ID); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticateContinuation returns the grant request which is continued, after verifying the continuation access
+// token and the signature of the request.
+func (h *Handler) authenticateContinuation(r *http.Request, id string) (_ *Grant, _ *client.Client, continuationToken string, body []byte, err error) {
+	ctx := r.Context()
+	scheme, continuationToken, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, AuthorizationScheme) || continuationToken == "" {
+		return nil, nil, "", nil, errorsx.WithStack(ErrInvalidContinuation.WithHintf("The continuation access token must be sent with the '%s' authorization scheme.", AuthorizationScheme))
+	}
+
+	g, err := h.getGrant(ctx, id)
+	if err != nil {
+		return nil, nil, "", nil, errorsx.WithStack(ErrInvalidContinuation.WithWrap(err).WithHint("The grant request does not exist or has expired."))
+	}
+	if subtle.ConstantTimeCompare([]byte(tokenSignature(continuationToken)), []byte(g.ContinuationTokenSignature)) != 1 {
+		return nil, nil, "", nil, errorsx.WithStack(ErrInvalidContinuation.WithHint("The continuation access token is invalid."))
+	}
+
+	cl, err := h.r.ClientManager().GetConcreteClient(ctx, g.ClientID)
+	if err != nil {
+		return nil, nil, "", nil, errorsx.WithStack(ErrInvalidClient.WithWrap(err).WithHint("The client instance is not a registered OAuth 2.0 client."))
+	}
+
+	if body, err = io.ReadAll(r.Body); err != nil {
+		return nil, nil, "", nil, errorsx.WithStack(err)
+	}
+	if err := h.verifyProof(r, body, cl, h.continuationURL(ctx, g), continuationToken); err != nil {
+		return nil, nil, "", nil, err
+	}
+	return g, cl, continuationToken, body, nil
+}
+
+// getGrant returns the grant request if it exists and has not expired.
+func (h *Handler) getGrant(ctx context.Context, id string) (*Grant, error) {
+	gid, err := uuid.FromString(id)
+	if err != nil {
+		return nil, errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	g, err := h.r.GNAPManager().GetGNAPGrant(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+	if g.ExpiresAt.Before(h.r.Clock().Now()) {
+		return nil, errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return g, nil
+}
+
+// issueAccessToken issues a bearer access token with the scopes, audiences and claims which were granted.
+func (h *Handler) issueAccessToken(ctx context.Context, cl *client.Client, g *Grant, label string) (*AccessToken, error) {
+	now := h.r.Clock().Now().UTC()
+
+	subject := g.Subject
+	if g.ConsentChallenge != "" {
+		var err error
+		if subject, err = h.r.ConsentStrategy().ObfuscateSubjectIdentifier(ctx, cl, g.Subject, g.ForceSubjectIdentifier); err != nil {
+			return nil, err
+		}
+	}
+
+	session := oauth2.NewSessionWithCustomClaims(ctx, h.r.Config(), g.Subject)
+	session.DefaultSession.Claims.Subject = subject
+	session.DefaultSession.Claims.Issuer = h.r.Config().IssuerURL(ctx).String()
+	session.DefaultSession.Claims.IssuedAt = now
+	session.ClientID = cl.GetID()
+	session.GrantType = string(GrantTypeGNAP)
+	session.ConsentChallenge = g.ConsentChallenge.String()
+	session.ExcludeNotBeforeClaim = h.r.Config().ExcludeNotBeforeClaim(ctx)
+	if g.SessionAccessToken != nil {
+		session.Extra = g.SessionAccessToken
+	}
+	if h.r.Config().AccessTokenStrategy(ctx, client.AccessTokenStrategySource(cl)) == "jwt" {
+		kid, err := h.r.AccessTokenJWTStrategy().GetPublicKeyID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		session.KID = kid
+	}
+
+	lifespan := fosite.GetEffectiveLifespan(cl, GrantTypeGNAP, fosite.AccessToken, h.r.Config().GetAccessTokenLifespan(ctx))
+	session.SetExpiresAt(fosite.AccessToken, now.Add(lifespan))
+
+	ar := fosite.NewAccessRequest(session)
+	ar.SetID(g.ID.String())
+	if g.ConsentChallenge != "" {
+		// Tokens are revoked with the consent session which granted them.
+		ar.SetID(g.ConsentChallenge.String())
+	}
+	ar.Client = cl
+	ar.RequestedAt = now
+	ar.GrantTypes = fosite.Arguments{string(GrantTypeGNAP)}
+	ar.SetRequestedScopes(fosite.Arguments(g.RequestedScope))
+	for _, scope := range g.GrantedScope {
+		ar.GrantScope(scope)
+	}
+	for _, audience := range g.GrantedAudience {
+		ar.GrantAudience(audience)
+	}
+
+	helper := &foauth2.HandleHelper{
+		AccessTokenStrategy: h.r.OAuth2CoreStrategy(),
+		AccessTokenStorage:  h.r.OAuth2Storage(),
+		Config:              h.r.OAuth2ProviderConfig(),
+	}
+	res := fosite.NewAccessResponse()
+	if err := helper.IssueAccessToken(ctx, lifespan, ar, res); err != nil {
+		return nil, err
+	}
+
+	return &AccessToken{
+		Value:     res.GetAccessToken(),
+		Label:     label,
+		Access:    ar.GetGrantedScopes(),
+		ExpiresIn: int64(lifespan / time.Second),
+		Flags:     []string{FlagBearer},
+	}, nil
+}
+
+// interactionRequest returns the authorization request with which the resource owner approves the grant request in
+// the login and consent flow.
+func (h *Handler) interactionRequest(r *http.Request, g *Grant, cl *client.Client) consent.InteractionRequester {
+	ar := fosite.NewAuthorizeRequest()
+	ar.ID = g.ID.String()
+	ar.Client = cl
+	ar.RequestedAt = g.CreatedAt
+	ar.Form = r.URL.Query()
+	ar.SetRequestedScopes(fosite.Arguments(g.RequestedScope))
+
+	interactionURL := h.interactionURL(r.Context(), g)
+	ar.RedirectURI = interactionURL
+	if g.FinishURI != "" {
+		if finishURI, err := url.Parse(g.FinishURI); err == nil {
+			ar.RedirectURI = finishURI
+		}
+	}
+
+	return &interactionRequest{AuthorizeRequest: ar, interactionURL: interactionURL}
+}
+
+type interactionRequest struct {
+	*fosite.AuthorizeRequest
+	interactionURL *url.URL
+}
+
+func (r *interactionRequest) GetInteractionURL() *url.URL {
+	return urlx.Copy(r.interactionURL)
+}
+
+func (h *Handler) continuation(ctx context.Context, g *Grant, continuationToken string) *Continue {
+	return &Continue{
+		AccessToken: ContinueAccessToken{Value: continuationToken},
+		URI:         h.continuationURL(ctx, g),
+		Wait:        continueWait,
+	}
+}
+
+func (h *Handler) grantURL(ctx context.Context) string {
+	return urlx.AppendPaths(h.r.Config().PublicURL(ctx), GrantPath).String()
+}
+
+func (h *Handler) continuationURL(ctx context.Context, g *Grant) string {
+	return urlx.AppendPaths(h.r.Config().PublicURL(ctx), ContinuePath, g.ID.String()).String()
+}
+
+// interactionURL returns the URL to which the client instance redirects the resource owner. It carries the client ID,
+// which the consent flow checks when the user agent returns.
+func (h *Handler) interactionURL(ctx context.Context, g *Grant) *url.URL {
+	return urlx.SetQuery(urlx.AppendPaths(h.r.Config().PublicURL(ctx), InteractPath, g.ID.String()), url.Values{"client_id": {g.ClientID}})
+}
+
+// interactionHash returns the hash with which the client instance verifies that the interaction finished for its
+// grant request.
+func (h *Handler) interactionHash(ctx context.Context, g *Grant) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{g.FinishNonce, g.ServerNonce, g.InteractRef, h.grantURL(ctx)}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func (h *Handler) requireGNAP(r *http.Request) error {
+	if !h.r.Config().GNAPEnabled(r.Context()) {
+		return errorsx.WithStack(herodot.ErrNotFound.WithReason("GNAP is not enabled."))
+	}
+	return nil
+}
+
+// writeError writes the error in the format of GNAP error responses.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	rfcErr := fosite.ErrorToRFC6749Error(err).WithExposeDebug(h.r.Config().GetSendDebugMessagesToClients(r.Context()))
+	if rfcErr.StatusCode() >= http.StatusInternalServerError {
+		x.LogError(r, err, h.r.Logger())
+	} else {
+		x.LogAudit(r, err, h.r.AuditLogger())
+	}
+
+	h.r.Writer().WriteCode(w, r, rfcErr.StatusCode(), &ErrorResponse{Error: Error{
+		Code:        rfcErr.ErrorField,
+		Description: rfcErr.GetDescription(),
+	}})
+}
+
+// forwardError sends the user agent to the error page.
+func (h *Handler) forwardError(w http.ResponseWriter, r *http.Request, err error) {
+	catalog := h.r.OAuth2ProviderConfig().GetMessageCatalog(r.Context())
+	lang := i18n.GetLangFromRequest(catalog, r)
+	rfcErr := fosite.ErrorToRFC6749Error(err).WithExposeDebug(h.r.Config().GetSendDebugMessagesToClients(r.Context())).WithLocalizer(catalog, lang)
+	query := rfcErr.ToValues()
+	if catalog != nil {
+		query.Set("ui_locales", lang.String())
+	}
+	if id := x.CorrelationIDFromContext(r.Context()); id != "" {
+		query.Set(x.CorrelationIDParameter, id)
+	}
+	http.Redirect(w, r, urlx.CopyWithQuery(h.r.Config().ErrorURL(r.Context()), query).String(), http.StatusFound)
+}
+
+func randomToken() (string, error) {
+	token, err := randx.RuneSequence(32, randx.AlphaNum)
+	if err != nil {
+		return "", errorsx.WithStack(err)
+	}
+	return string(token), nil
+}
+
+// tokenSignature returns the signature with which continuation access tokens are stored.
+func tokenSignature(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
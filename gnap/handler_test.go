@@ -0,0 +1,396 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package gnap_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/gnap"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+)
+
+func TestGNAP(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	reg.Config().MustSet(ctx, config.KeyGNAPEnabled, true)
+	public, admin := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwk := jose.JSONWebKey{Key: key, KeyID: "gnap-key", Algorithm: string(jose.ES256)}
+
+	callback := testhelpers.NewCallbackURL(t, "callback", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	newClient := func(t *testing.T, grantTypes ...string) *hc.Client {
+		secret := uuid.New().String()
+		c := &hc.Client{
+			Secret:       secret,
+			GrantTypes:   grantTypes,
+			Scope:        "profile email",
+			RedirectURIs: []string{callback},
+			JSONWebKeys:  &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{jwk.Public()}}},
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		c.Secret = secret
+		return c
+	}
+
+	sign := func(t *testing.T, req *http.Request, body []byte, accessToken string) {
+		headers := map[jose.HeaderKey]interface{}{
+			jose.HeaderType: "gnap-binding-jwsd",
+			"htm":           req.Method,
+			"uri":           req.URL.String(),
+			"created":       time.Now().Unix(),
+		}
+		if accessToken != "" {
+			hash := sha256.Sum256([]byte(accessToken))
+			headers["ath"] = base64.RawURLEncoding.EncodeToString(hash[:])
+		}
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jwk}, &jose.SignerOptions{ExtraHeaders: headers})
+		require.NoError(t, err)
+		sig, err := signer.Sign(body)
+		require.NoError(t, err)
+		detached, err := sig.DetachedCompactSerialize()
+		require.NoError(t, err)
+		req.Header.Set(gnap.DetachedJWSHeader, detached)
+	}
+
+	do := func(t *testing.T, method, uri string, body interface{}, accessToken string, signed bool) (*http.Response, gjson.Result) {
+		var b []byte
+		if body != nil {
+			b, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		req, err := http.NewRequest(method, uri, bytes.NewReader(b))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if accessToken != "" {
+			req.Header.Set("Authorization", "GNAP "+accessToken)
+		}
+		if signed {
+			sign(t, req, b, accessToken)
+		}
+		res, err := public.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	introspect := func(t *testing.T, c *hc.Client, token string) gjson.Result {
+		return testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: c.GetID(), ClientSecret: c.Secret}, token, admin)
+	}
+
+	acceptLogin := func(t *testing.T, subject string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			redirectFromAdmin(t, w, admin.URL+"/admin/oauth2/auth/requests/login/accept?login_challenge="+r.URL.Query().Get("login_challenge"), map[string]interface{}{"subject": subject})
+		}
+	}
+	acceptConsent := func(t *testing.T, scope ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			redirectFromAdmin(t, w, admin.URL+"/admin/oauth2/auth/requests/consent/accept?consent_challenge="+r.URL.Query().Get("consent_challenge"), map[string]interface{}{
+				"grant_scope": scope,
+				"session":     map[string]interface{}{"access_token": map[string]interface{}{"foo": "bar"}},
+			})
+		}
+	}
+	rejectLogin := func(t *testing.T) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			redirectFromAdmin(t, w, admin.URL+"/admin/oauth2/auth/requests/login/reject?login_challenge="+r.URL.Query().Get("login_challenge"), map[string]interface{}{"error": "access_denied"})
+		}
+	}
+
+	t.Run("case=issues access tokens to client instances", func(t *testing.T) {
+		c := newClient(t, "client_credentials")
+
+		res, body := do(t, http.MethodPost, public.URL+gnap.GrantPath, map[string]interface{}{
+			"client":       c.GetID(),
+			"access_token": map[string]interface{}{"access": []string{"profile"}, "label": "api"},
+		}, "", true)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, "api", body.Get("access_token.label").String())
+		assert.Equal(t, `["profile"]`, body.Get("access_token.access").Raw)
+		assert.Equal(t, `["bearer"]`, body.Get("access_token.flags").Raw)
+
+		i := introspect(t, c, body.Get("access_token.value").String())
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.Equal(t, c.GetID(), i.Get("sub").String(), i.Raw)
+		assert.Equal(t, "profile", i.Get("scope").String(), i.Raw)
+	})
+
+	t.Run("case=rejects invalid grant requests", func(t *testing.T) {
+		c := newClient(t, "client_credentials")
+
+		for k, tc := range []struct {
+			d      string
+			body   interface{}
+			signed bool
+			status int
+			code   string
+		}{
+			{
+				d:      "unsigned",
+				body:   map[string]interface{}{"client": c.GetID(), "access_token": map[string]interface{}{"access": []string{"profile"}}},
+				status: http.StatusUnauthorized,
+				code:   "invalid_client",
+			},
+			{
+				d:      "unknown client",
+				body:   map[string]interface{}{"client": uuid.New().String(), "access_token": map[string]interface{}{"access": []string{"profile"}}},
+				signed: true,
+				status: http.StatusUnauthorized,
+				code:   "invalid_client",
+			},
+			{
+				d:      "scope not allowed",
+				body:   map[string]interface{}{"client": c.GetID(), "access_token": map[string]interface{}{"access": []string{"admin"}}},
+				signed: true,
+				status: http.StatusForbidden,
+				code:   "request_denied",
+			},
+			{
+				d:      "unsupported flag",
+				body:   map[string]interface{}{"client": c.GetID(), "access_token": map[string]interface{}{"access": []string{"profile"}, "flags": []string{"durable"}}},
+				signed: true,
+				status: http.StatusBadRequest,
+				code:   "invalid_flag",
+			},
+			{
+				d: "unsupported start mode",
+				body: map[string]interface{}{
+					"client":       c.GetID(),
+					"access_token": map[string]interface{}{"access": []string{"profile"}},
+					"interact":     map[string]interface{}{"start": []string{"user_code"}},
+				},
+				signed: true,
+				status: http.StatusBadRequest,
+				code:   "invalid_interaction",
+			},
+			{
+				d: "unregistered finish uri",
+				body: map[string]interface{}{
+					"client":       c.GetID(),
+					"access_token": map[string]interface{}{"access": []string{"profile"}},
+					"interact": map[string]interface{}{
+						"start":  []string{"redirect"},
+						"finish": map[string]interface{}{"method": "redirect", "uri": "https://evil.example.org/", "nonce": "nonce"},
+					},
+				},
+				signed: true,
+				status: http.StatusBadRequest,
+				code:   "invalid_interaction",
+			},
+		} {
+			t.Run("case="+tc.d, func(t *testing.T) {
+				res, body := do(t, http.MethodPost, public.URL+gnap.GrantPath, tc.body, "", tc.signed)
+				assert.Equal(t, tc.status, res.StatusCode, "%d: %s", k, body.Raw)
+				assert.Equal(t, tc.code, body.Get("error.code").String(), "%d: %s", k, body.Raw)
+			})
+		}
+	})
+
+	t.Run("case=issues access tokens after the interaction finished with a redirect", func(t *testing.T) {
+		c := newClient(t, "authorization_code")
+		testhelpers.NewLoginConsentUI(t, reg.Config(), acceptLogin(t, "alice"), acceptConsent(t, "profile"))
+
+		res, body := do(t, http.MethodPost, public.URL+gnap.GrantPath, map[string]interface{}{
+			"client":       c.GetID(),
+			"access_token": map[string]interface{}{"access": []string{"profile"}},
+			"interact": map[string]interface{}{
+				"start":  []string{"redirect"},
+				"finish": map[string]interface{}{"method": "redirect", "uri": callback, "nonce": "client-nonce"},
+			},
+		}, "", true)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		continueURI := body.Get("continue.uri").String()
+		continuationToken := body.Get("continue.access_token.value").String()
+		require.NotEmpty(t, continuationToken)
+		serverNonce := body.Get("interact.finish").String()
+		require.NotEmpty(t, serverNonce)
+		interactRedirect := body.Get("interact.redirect").String()
+		assert.True(t, strings.HasPrefix(continueURI, public.URL+gnap.ContinuePath+"/"), continueURI)
+
+		res, body = do(t, http.MethodPost, continueURI, nil, continuationToken, true)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "too_fast", body.Get("error.code").String(), body.Raw)
+
+		res, err := testhelpers.NewEmptyJarClient(t).Get(interactRedirect)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		finish := res.Request.URL.Query()
+		require.NotEmpty(t, finish.Get("interact_ref"), res.Request.URL.String())
+
+		hash := sha256.Sum256([]byte(strings.Join([]string{"client-nonce", serverNonce, finish.Get("interact_ref"), public.URL + gnap.GrantPath}, "\n")))
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(hash[:]), finish.Get("hash"))
+
+		res, body = do(t, http.MethodPost, continueURI, map[string]interface{}{"interact_ref": "wrong"}, continuationToken, true)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "unknown_interaction", body.Get("error.code").String(), body.Raw)
+
+		res, body = do(t, http.MethodPost, continueURI, map[string]interface{}{"interact_ref": finish.Get("interact_ref")}, continuationToken, false)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+
+		res, body = do(t, http.MethodPost, continueURI, map[string]interface{}{"interact_ref": finish.Get("interact_ref")}, continuationToken, true)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, `["profile"]`, body.Get("access_token.access").Raw)
+
+		i := introspect(t, c, body.Get("access_token.value").String())
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.Equal(t, "alice", i.Get("sub").String(), i.Raw)
+		assert.Equal(t, "bar", i.Get("ext.foo").String(), i.Raw)
+
+		res, body = do(t, http.MethodPost, continueURI, map[string]interface{}{"interact_ref": finish.Get("interact_ref")}, continuationToken, true)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_continuation", body.Get("error.code").String(), body.Raw)
+	})
+
+	t.Run("case=client instances poll without a finish method", func(t *testing.T) {
+		c := newClient(t, "authorization_code")
+		testhelpers.NewLoginConsentUI(t, reg.Config(), acceptLogin(t, "bob"), acceptConsent(t, "email"))
+
+		res, body := do(t, http.MethodPost, public.URL+gnap.GrantPath, map[string]interface{}{
+			"client":       c.GetID(),
+			"access_token": map[string]interface{}{"access": []string{"email"}},
+			"interact":     map[string]interface{}{"start": []string{"redirect"}},
+		}, "", true)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.False(t, body.Get("interact.finish").Exists(), body.Raw)
+		continueURI := body.Get("continue.uri").String()
+		continuationToken := body.Get("continue.access_token.value").String()
+
+		res, pending := do(t, http.MethodPost, continueURI, nil, continuationToken, true)
+		require.Equal(t, http.StatusOK, res.StatusCode, pending.Raw)
+		assert.Equal(t, continueURI, pending.Get("continue.uri").String())
+		assert.False(t, pending.Get("access_token").Exists(), pending.Raw)
+
+		res, err := testhelpers.NewEmptyJarClient(t).Get(body.Get("interact.redirect").String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		// Concurrent continuations must issue a single access token.
+		var wg sync.WaitGroup
+		responses := make([]gjson.Result, 5)
+		for k := range responses {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				_, responses[k] = do(t, http.MethodPost, continueURI, nil, continuationToken, true)
+			}(k)
+		}
+		wg.Wait()
+
+		var issued []gjson.Result
+		for _, body := range responses {
+			if body.Get("access_token").Exists() {
+				issued = append(issued, body)
+			} else {
+				assert.Equal(t, "invalid_continuation", body.Get("error.code").String(), body.Raw)
+			}
+		}
+		require.Len(t, issued, 1)
+		i := introspect(t, c, issued[0].Get("access_token.value").String())
+		assert.Equal(t, "bob", i.Get("sub").String(), i.Raw)
+		assert.Equal(t, "email", i.Get("scope").String(), i.Raw)
+
+		res, body = do(t, http.MethodPost, continueURI, nil, continuationToken, true)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_continuation", body.Get("error.code").String(), body.Raw)
+	})
+
+	t.Run("case=denies grant requests which the resource owner rejected", func(t *testing.T) {
+		c := newClient(t, "authorization_code")
+		testhelpers.NewLoginConsentUI(t, reg.Config(), rejectLogin(t), acceptConsent(t))
+
+		res, body := do(t, http.MethodPost, public.URL+gnap.GrantPath, map[string]interface{}{
+			"client":       c.GetID(),
+			"access_token": map[string]interface{}{"access": []string{"profile"}},
+			"interact":     map[string]interface{}{"start": []string{"redirect"}},
+		}, "", true)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		continueURI := body.Get("continue.uri").String()
+		continuationToken := body.Get("continue.access_token.value").String()
+
+		res, err := testhelpers.NewEmptyJarClient(t).Get(body.Get("interact.redirect").String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		res, body = do(t, http.MethodPost, continueURI, nil, continuationToken, true)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, body.Raw)
+		assert.Equal(t, "user_denied", body.Get("error.code").String(), body.Raw)
+	})
+
+	t.Run("case=revokes grant requests", func(t *testing.T) {
+		c := newClient(t, "authorization_code")
+
+		res, body := do(t, http.MethodPost, public.URL+gnap.GrantPath, map[string]interface{}{
+			"client":       c.GetID(),
+			"access_token": map[string]interface{}{"access": []string{"profile"}},
+			"interact":     map[string]interface{}{"start": []string{"redirect"}},
+		}, "", true)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		continueURI := body.Get("continue.uri").String()
+		continuationToken := body.Get("continue.access_token.value").String()
+
+		res, _ = do(t, http.MethodDelete, continueURI, nil, continuationToken, true)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		res, body = do(t, http.MethodPost, continueURI, nil, continuationToken, true)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+	})
+
+	t.Run("case=is disabled by default", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyGNAPEnabled, false)
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyGNAPEnabled, true) })
+
+		res, body := do(t, http.MethodPost, public.URL+gnap.GrantPath, map[string]interface{}{}, "", false)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, body.Raw)
+	})
+}
+
+func redirectFromAdmin(t *testing.T, w http.ResponseWriter, uri string, body interface{}) {
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, uri, bytes.NewReader(b))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	result := gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	require.Equal(t, http.StatusOK, res.StatusCode, result.Raw)
+
+	redirectTo, err := url.Parse(result.Get("redirect_to").String())
+	require.NoError(t, err)
+	http.Redirect(w, &http.Request{}, redirectTo.String(), http.StatusFound)
+}
@@ -0,0 +1,77 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package gnap
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlxx"
+)
+
+const (
+	// GrantStatusPending is the status of grant requests which wait for the interaction of the resource owner.
+	GrantStatusPending = "pending"
+	// GrantStatusApproved is the status of grant requests which the resource owner approved.
+	GrantStatusApproved = "approved"
+	// GrantStatusDenied is the status of grant requests which the resource owner or the login and consent app denied.
+	GrantStatusDenied = "denied"
+)
+
+// Grant is a GNAP grant request which the client instance continues after the interaction of the resource owner.
+type Grant struct {
+	ID  uuid.UUID `db:"id"`
+	NID uuid.UUID `db:"nid"`
+
+	// ClientID is the ID of the OAuth 2.0 client which is the client instance of the grant request.
+	ClientID string `db:"client_id"`
+	Status   string `db:"status"`
+
+	RequestedScope sqlxx.StringSliceJSONFormat `db:"requested_scope"`
+
+	// FinishMethod, FinishURI and FinishNonce are the interaction finish parameters of the client instance.
+	FinishMethod string `db:"finish_method"`
+	FinishURI    string `db:"finish_uri"`
+	FinishNonce  string `db:"finish_nonce"`
+
+	// ServerNonce is the nonce of the authorization server which is part of the interaction hash.
+	ServerNonce string `db:"server_nonce"`
+
+	// InteractRef is the interaction reference which the client instance presents when continuing the grant request.
+	InteractRef string `db:"interact_ref"`
+
+	// ContinuationTokenSignature is the signature of the continuation access token of the grant request.
+	ContinuationTokenSignature string `db:"continuation_token_signature"`
+
+	// The fields below are set once the resource owner approved the grant request.
+	Subject                string                      `db:"subject"`
+	ForceSubjectIdentifier string                      `db:"force_subject_identifier"`
+	ConsentChallenge       sqlxx.NullString            `db:"consent_challenge_id"`
+	GrantedScope           sqlxx.StringSliceJSONFormat `db:"granted_scope"`
+	GrantedAudience        sqlxx.StringSliceJSONFormat `db:"granted_audience"`
+	SessionAccessToken     sqlxx.MapStringInterface    `db:"session_access_token"`
+
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (Grant) TableName() string {
+	return "hydra_gnap_grant"
+}
+
+type Manager interface {
+	CreateGNAPGrant(ctx context.Context, g *Grant) error
+	GetGNAPGrant(ctx context.Context, id uuid.UUID) (*Grant, error)
+	UpdateGNAPGrant(ctx context.Context, g *Grant) error
+
+	// DeleteGNAPGrant deletes the grant request. It returns sqlcon.ErrNoRows if the grant request does not exist,
+	// for example because it was deleted concurrently.
+	DeleteGNAPGrant(ctx context.Context, id uuid.UUID) error
+
+	// FlushInactiveGNAPGrants deletes the grant requests which expired before notAfter.
+	FlushInactiveGNAPGrants(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
+}
@@ -0,0 +1,123 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package gnap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/x/errorsx"
+)
+
+const (
+	// ProofMethodJWSD is the key proofing method with which client instances sign their requests with detached JSON
+	// Web Signatures. It is the only supported proofing method.
+	ProofMethodJWSD = "jwsd"
+
+	// DetachedJWSHeader is the header which carries the detached JSON Web Signature of a request.
+	DetachedJWSHeader = "Detached-JWS"
+
+	// jwsdType is the value of the typ header of detached JSON Web Signatures.
+	jwsdType = "gnap-binding-jwsd"
+
+	// proofLeeway is how far the creation time of a signature may differ from the time of the server.
+	proofLeeway = 5 * time.Minute
+)
+
+// verifyProof verifies that the request was signed with one of the JSON Web Keys which the client registered. The
+// signature covers the body of the request, its method and target URI, and the continuation access token if there is
+// one.
+func (h *Handler) verifyProof(r *http.Request, body []byte, cl *client.Client, targetURI, accessToken string) error {
+	ctx := r.Context()
+	detached := r.Header.Get(DetachedJWSHeader)
+	if detached == "" {
+		return errorsx.WithStack(ErrInvalidClient.WithHintf("The request must be signed with the '%s' proofing method.", ProofMethodJWSD))
+	}
+
+	sig, err := jose.ParseDetached(detached, body)
+	if err != nil {
+		return errorsx.WithStack(ErrInvalidClient.WithWrap(err).WithHint("The detached JSON Web Signature of the request is malformed.").WithDebug(err.Error()))
+	}
+	if len(sig.Signatures) != 1 {
+		return errorsx.WithStack(ErrInvalidClient.WithHint("The detached JSON Web Signature of the request must have exactly one signature."))
+	}
+
+	header := sig.Signatures[0].Protected
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != jwsdType {
+		return errorsx.WithStack(ErrInvalidClient.WithHintf("The typ header of the detached JSON Web Signature must be '%s'.", jwsdType))
+	}
+	if htm, _ := header.ExtraHeaders["htm"].(string); htm != r.Method {
+		return errorsx.WithStack(ErrInvalidClient.WithHint("The htm header of the detached JSON Web Signature does not match the request method."))
+	}
+	if uri, _ := header.ExtraHeaders["uri"].(string); uri != targetURI {
+		return errorsx.WithStack(ErrInvalidClient.WithHint("The uri header of the detached JSON Web Signature does not match the request URI."))
+	}
+	created, _ := header.ExtraHeaders["created"].(float64)
+	if d := h.r.Clock().Now().Sub(time.Unix(int64(created), 0)); d > proofLeeway || d < -proofLeeway {
+		return errorsx.WithStack(ErrInvalidClient.WithHint("The created header of the detached JSON Web Signature is missing or too far from the current time."))
+	}
+	if accessToken != "" {
+		if ath, _ := header.ExtraHeaders["ath"].(string); ath != accessTokenHash(accessToken) {
+			return errorsx.WithStack(ErrInvalidClient.WithHint("The ath header of the detached JSON Web Signature does not match the access token."))
+		}
+	}
+
+	key, err := h.clientKey(ctx, cl, header.KeyID)
+	if err != nil {
+		return errorsx.WithStack(ErrInvalidClient.WithWrap(err).WithHint("The key of the detached JSON Web Signature is not registered.").WithDebug(err.Error()))
+	}
+	if err := sig.DetachedVerify(body, key); err != nil {
+		return errorsx.WithStack(ErrInvalidClient.WithWrap(err).WithHint("The detached JSON Web Signature of the request is invalid.").WithDebug(err.Error()))
+	}
+	return nil
+}
+
+// clientKey returns the JSON Web Key of the client with the key ID, or its only key if the key ID is empty.
+func (h *Handler) clientKey(ctx context.Context, cl *client.Client, kid string) (*jose.JSONWebKey, error) {
+	find := func(keys *jose.JSONWebKeySet) *jose.JSONWebKey {
+		if kid == "" && len(keys.Keys) == 1 {
+			return &keys.Keys[0]
+		}
+		if found := keys.Key(kid); kid != "" && len(found) > 0 {
+			return &found[0]
+		}
+		return nil
+	}
+
+	if keys := cl.GetJSONWebKeys(); keys != nil && len(keys.Keys) > 0 {
+		if key := find(keys); key != nil {
+			return key, nil
+		}
+		return nil, errors.Errorf("the client has no key with the key ID %q", kid)
+	}
+
+	if cl.GetJSONWebKeysURI() == "" {
+		return nil, errors.New("the client has no JSON Web Keys registered")
+	}
+
+	// The keys are fetched again if the key ID is unknown, as the client may have rotated its keys.
+	for _, ignoreCache := range []bool{false, true} {
+		keys, err := h.r.GetJWKSFetcherStrategy().Resolve(ctx, cl.GetJSONWebKeysURI(), ignoreCache)
+		if err != nil {
+			return nil, err
+		}
+		if key := find(keys); key != nil {
+			return key, nil
+		}
+	}
+	return nil, errors.Errorf("the client has no key with the key ID %q", kid)
+}
+
+// accessTokenHash returns the hash of an access token which is the ath header of detached JSON Web Signatures.
+func accessTokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,36 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package gnap
+
+import (
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/hydra/v2/x"
+)
+
+type InternalRegistry interface {
+	x.RegistryWriter
+	x.RegistryLogger
+	x.HTTPClientProvider
+	x.ClockProvider
+	config.Provider
+	client.Registry
+	Registry
+
+	ConsentStrategy() consent.Strategy
+	GetJWKSFetcherStrategy() fosite.JWKSFetcherStrategy
+	OAuth2Storage() x.FositeStorer
+	OAuth2CoreStrategy() foauth2.CoreStrategy
+	OAuth2ProviderConfig() fosite.Configurator
+	AccessTokenJWTStrategy() jwk.JWTSigner
+}
+
+type Registry interface {
+	GNAPManager() Manager
+	GNAPHandler() *Handler
+}
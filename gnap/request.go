@@ -0,0 +1,199 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package gnap
+
+import (
+	"encoding/json"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+)
+
+// GNAP Grant Request
+//
+// swagger:model gnapGrantRequest
+type GrantRequest struct {
+	// The access token which the client instance requests.
+	AccessToken *AccessTokenRequest `json:"access_token"`
+
+	// The client instance. It must be the ID of a registered OAuth 2.0 client with registered JSON Web Keys.
+	//
+	// required: true
+	Client json.RawMessage `json:"client"`
+
+	// The modes in which the client instance can interact with the resource owner. If it is not set, the access token
+	// is issued to the client instance itself, which then must be allowed to use the client_credentials grant.
+	Interact *InteractRequest `json:"interact,omitempty"`
+}
+
+// GNAP Access Token Request
+//
+// swagger:model gnapAccessTokenRequest
+type AccessTokenRequest struct {
+	// The rights of access which are requested. Only references to scopes are supported.
+	//
+	// required: true
+	Access []json.RawMessage `json:"access"`
+
+	// The label of the access token.
+	Label string `json:"label,omitempty"`
+
+	// The flags of the access token. Only bearer access tokens are issued.
+	Flags []string `json:"flags,omitempty"`
+}
+
+// GNAP Interaction Request
+//
+// swagger:model gnapInteractRequest
+type InteractRequest struct {
+	// The modes in which the client instance can start the interaction. Only the redirect mode is supported.
+	//
+	// required: true
+	Start []string `json:"start"`
+
+	// How the client instance is told that the interaction has finished.
+	Finish *InteractFinish `json:"finish,omitempty"`
+}
+
+// GNAP Interaction Finish
+//
+// swagger:model gnapInteractFinish
+type InteractFinish struct {
+	// The finish method, either redirect or push.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// The URI to which the interaction finishes. It must be a redirect URI of the client.
+	//
+	// required: true
+	URI string `json:"uri"`
+
+	// The nonce of the client instance which is part of the interaction hash.
+	//
+	// required: true
+	Nonce string `json:"nonce"`
+
+	// The hash method of the interaction hash. Only sha-256 is supported.
+	HashMethod string `json:"hash_method,omitempty"`
+}
+
+// GNAP Continuation Request
+//
+// swagger:model gnapContinueRequest
+type ContinueRequest struct {
+	// The interaction reference which the client instance received when the interaction finished.
+	InteractRef string `json:"interact_ref,omitempty"`
+}
+
+// GNAP Grant Response
+//
+// swagger:model gnapGrantResponse
+type GrantResponse struct {
+	// How the client instance continues the grant request.
+	Continue *Continue `json:"continue,omitempty"`
+
+	// The access token which was granted.
+	AccessToken *AccessToken `json:"access_token,omitempty"`
+
+	// How the client instance starts the interaction with the resource owner.
+	Interact *InteractResponse `json:"interact,omitempty"`
+}
+
+// GNAP Continuation
+//
+// swagger:model gnapContinue
+type Continue struct {
+	// The access token with which the client instance continues the grant request.
+	AccessToken ContinueAccessToken `json:"access_token"`
+
+	// The URI at which the client instance continues the grant request.
+	URI string `json:"uri"`
+
+	// How many seconds the client instance should wait before it continues the grant request.
+	Wait int `json:"wait,omitempty"`
+}
+
+// GNAP Continuation Access Token
+//
+// swagger:model gnapContinueAccessToken
+type ContinueAccessToken struct {
+	// The value of the continuation access token, which is sent with the GNAP authorization scheme.
+	Value string `json:"value"`
+}
+
+// GNAP Access Token
+//
+// swagger:model gnapAccessToken
+type AccessToken struct {
+	// The value of the access token.
+	Value string `json:"value"`
+
+	// The label of the access token as requested.
+	Label string `json:"label,omitempty"`
+
+	// The rights of access which were granted.
+	Access []string `json:"access"`
+
+	// The lifetime of the access token in seconds.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+
+	// The flags of the access token.
+	Flags []string `json:"flags"`
+}
+
+// GNAP Interaction
+//
+// swagger:model gnapInteractResponse
+type InteractResponse struct {
+	// The URI to which the client instance redirects the resource owner.
+	Redirect string `json:"redirect"`
+
+	// The nonce of the authorization server which is part of the interaction hash.
+	Finish string `json:"finish,omitempty"`
+}
+
+// GNAP Error Response
+//
+// swagger:model gnapErrorResponse
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// GNAP Error
+//
+// swagger:model gnapError
+type Error struct {
+	// The error code.
+	Code string `json:"code"`
+
+	// The human-readable description of the error.
+	Description string `json:"description,omitempty"`
+}
+
+// clientID returns the ID of the OAuth 2.0 client which is the client instance of the request.
+func (r *GrantRequest) clientID() (string, error) {
+	var id string
+	if err := json.Unmarshal(r.Client, &id); err != nil || id == "" {
+		return "", errorsx.WithStack(ErrInvalidClient.WithHint("The client instance must be the ID of a registered OAuth 2.0 client."))
+	}
+	return id, nil
+}
+
+// scopes returns the scopes which are referenced by the requested rights of access.
+func (r *AccessTokenRequest) scopes() ([]string, error) {
+	if r == nil || len(r.Access) == 0 {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The grant request must request an access token with at least one right of access."))
+	}
+
+	scopes := make([]string, 0, len(r.Access))
+	for _, access := range r.Access {
+		var scope string
+		if err := json.Unmarshal(access, &scope); err != nil || scope == "" {
+			return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Only references to scopes are supported as rights of access."))
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// keySetCache caches the key sets read from the Hardware Security Module, because reading a key set takes several
+// round trips to the token. Key sets are removed from the cache when they are changed through the key manager, and
+// expire after the TTL otherwise.
+type keySetCache struct {
+	sync.Mutex
+	keySets map[string]keySetCacheEntry
+	now     func() time.Time
+}
+
+type keySetCacheEntry struct {
+	keySet  *jose.JSONWebKeySet
+	expires time.Time
+}
+
+func newKeySetCache() *keySetCache {
+	return &keySetCache{
+		keySets: make(map[string]keySetCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached key set, because callers may modify the key sets they get.
+func (c *keySetCache) get(set string) (*jose.JSONWebKeySet, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.keySets[set]
+	if !ok {
+		return nil, false
+	} else if !c.now().Before(e.expires) {
+		delete(c.keySets, set)
+		return nil, false
+	}
+	return &jose.JSONWebKeySet{Keys: append([]jose.JSONWebKey{}, e.keySet.Keys...)}, true
+}
+
+// set caches the key set for ttl. Key sets are not cached if ttl is not positive.
+func (c *keySetCache) set(set string, keySet *jose.JSONWebKeySet, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.keySets[set] = keySetCacheEntry{
+		keySet:  &jose.JSONWebKeySet{Keys: append([]jose.JSONWebKey{}, keySet.Keys...)},
+		expires: c.now().Add(ttl),
+	}
+}
+
+func (c *keySetCache) delete(set string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.keySets, set)
+}
+
+func (c *keySetCache) clear() {
+	c.Lock()
+	defer c.Unlock()
+	c.keySets = make(map[string]keySetCacheEntry)
+}
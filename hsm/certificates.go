@@ -0,0 +1,85 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha1" // #nosec G505 - required by RFC 7517 for the x5t parameter
+	"crypto/sha256"
+	"crypto/x509"
+
+	"github.com/go-jose/go-jose/v3"
+)
+
+// addCertificates adds the certificate chains stored on the Hardware Security Module to the keys, if publishing
+// certificates is enabled. The certificates of a key are the certificate objects with the CKA_ID of the key pair.
+func (m *KeyManager) addCertificates(ctx context.Context, hsmContext Context, keys []jose.JSONWebKey) error {
+	if !m.c.HSMPublishCertificates(ctx) {
+		return nil
+	}
+
+	for i := range keys {
+		signer, ok := keys[i].Key.(jose.OpaqueSigner)
+		if !ok {
+			continue
+		}
+
+		certificates, err := hsmContext.FindCertificates([]byte(keys[i].KeyID))
+		if err != nil {
+			return err
+		}
+
+		chain := certificateChain(signer.Public().Key, certificates)
+		if len(chain) == 0 {
+			continue
+		}
+
+		sha1Thumbprint := sha1.Sum(chain[0].Raw) // #nosec G401 - required by RFC 7517 for the x5t parameter
+		sha256Thumbprint := sha256.Sum256(chain[0].Raw)
+		keys[i].Certificates = chain
+		keys[i].CertificateThumbprintSHA1 = sha1Thumbprint[:]
+		keys[i].CertificateThumbprintSHA256 = sha256Thumbprint[:]
+	}
+	return nil
+}
+
+// certificateChain orders the certificates starting with the certificate of the public key, followed by the
+// certificates which issued it. Certificates which are not part of the chain are left out. If no certificate is issued
+// for the public key, the chain is empty.
+func certificateChain(public crypto.PublicKey, certificates []*x509.Certificate) []*x509.Certificate {
+	var chain []*x509.Certificate
+	for _, c := range certificates {
+		if k, ok := c.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); ok && k.Equal(public) {
+			chain = append(chain, c)
+			break
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+
+	for {
+		last := chain[len(chain)-1]
+		if bytes.Equal(last.RawIssuer, last.RawSubject) {
+			return chain
+		}
+
+		var issuer *x509.Certificate
+		for _, c := range certificates {
+			if c != last && bytes.Equal(c.RawSubject, last.RawIssuer) && last.CheckSignatureFrom(c) == nil {
+				issuer = c
+				break
+			}
+		}
+		if issuer == nil || len(chain) == len(certificates) {
+			return chain
+		}
+		chain = append(chain, issuer)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+)
+
+// HealthCheck verifies that the tokens of the Hardware Security Module are reachable. The session with a token which
+// is unreachable is re-established, and the cached key sets are dropped because their keys are bound to the previous
+// session. It fails if a token is still unreachable afterwards.
+func (m *KeyManager) HealthCheck(ctx context.Context) error {
+	_, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "hsm.HealthCheck")
+	defer span.End()
+
+	for _, hsmContext := range m.contexts() {
+		m.RLock()
+		pingErr := hsmContext.Ping()
+		m.RUnlock()
+		if pingErr == nil {
+			continue
+		}
+
+		if err := m.reconnect(hsmContext); err != nil {
+			return errors.WithMessagef(err, "a token of the Hardware Security Module is unreachable (%s) and reconnecting failed", pingErr)
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) reconnect(hsmContext Context) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.cache.clear()
+	if err := hsmContext.Reconnect(); err != nil {
+		return err
+	}
+	return hsmContext.Ping()
+}
+
+// contexts returns the default context followed by the contexts of the partitions, each once.
+func (m *KeyManager) contexts() []Context {
+	contexts := []Context{m.Context}
+	seen := map[Context]bool{m.Context: true}
+	for _, hsmContext := range m.keySetContexts {
+		if !seen[hsmContext] {
+			seen[hsmContext] = true
+			contexts = append(contexts, hsmContext)
+		}
+	}
+	return contexts
+}
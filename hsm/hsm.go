@@ -8,8 +8,11 @@ package hsm
 
 import (
 	"crypto/elliptic"
+	"crypto/x509"
+	"fmt"
 
 	"github.com/ThalesIgnite/crypto11"
+	"github.com/pkg/errors"
 
 	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/x/logrusx"
@@ -21,9 +24,13 @@ type Context interface {
 	FindKeyPair(id []byte, label []byte) (crypto11.Signer, error)
 	FindKeyPairs(id []byte, label []byte) (signer []crypto11.Signer, err error)
 	GetAttribute(key interface{}, attribute crypto11.AttributeType) (a *crypto11.Attribute, err error)
+	SetKeyPairLabel(id []byte, label []byte) error
+	FindCertificates(id []byte) ([]*x509.Certificate, error)
+	Ping() error
+	Reconnect() error
 }
 
-func NewContext(c *config.DefaultProvider, l *logrusx.Logger) Context {
+func newConfig(c *config.DefaultProvider) *crypto11.Config {
 	config11 := &crypto11.Config{
 		Path: c.HSMLibraryPath(),
 		Pin:  c.HSMPin(),
@@ -35,14 +42,94 @@ func NewContext(c *config.DefaultProvider, l *logrusx.Logger) Context {
 		config11.SlotNumber = c.HSMSlotNumber()
 	}
 
+	return config11
+}
+
+func newPartitionConfig(c *config.DefaultProvider, partition config.HSMPartition) *crypto11.Config {
+	config11 := &crypto11.Config{
+		Path: c.HSMLibraryPath(),
+		Pin:  partition.Pin,
+	}
+
+	if partition.TokenLabel != "" {
+		config11.TokenLabel = partition.TokenLabel
+	} else {
+		config11.SlotNumber = partition.Slot
+	}
+
+	return config11
+}
+
+// Probe verifies that a session with the Hardware Security Module can be established using the given configuration,
+// for the default token as well as for the tokens of all partitions.
+func Probe(c *config.DefaultProvider) error {
+	partitions, err := c.HSMPartitions()
+	if err != nil {
+		return err
+	}
+
+	configs := []*crypto11.Config{newConfig(c)}
+	for _, partition := range partitions {
+		configs = append(configs, newPartitionConfig(c, partition))
+	}
+
+	for _, config11 := range configs {
+		ctx11, err := crypto11.Configure(config11)
+		if err != nil {
+			return errors.Wrapf(err, "unable to configure %s", describeToken(config11))
+		}
+		if err := ctx11.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func NewContext(c *config.DefaultProvider, l *logrusx.Logger) Context {
+	return newContext(newConfig(c), l)
+}
+
+// NewKeySetContexts configures a context for the token of every partition, and returns them by the key sets which
+// are stored on them.
+func NewKeySetContexts(c *config.DefaultProvider, l *logrusx.Logger) map[string]Context {
+	partitions, err := c.HSMPartitions()
+	if err != nil {
+		l.WithError(err).Fatalf("Unable to decode the partitions of the Hardware Security Module.")
+	}
+
+	contexts := make(map[string]Context)
+	for _, partition := range partitions {
+		hsmContext := newContext(newPartitionConfig(c, partition), l)
+		for _, set := range partition.KeySets {
+			contexts[set] = hsmContext
+		}
+	}
+	return contexts
+}
+
+func newContext(config11 *crypto11.Config, l *logrusx.Logger) Context {
 	ctx11, err := crypto11.Configure(config11)
 	if err != nil {
-		l.WithError(err).Fatalf("Unable to configure Hardware Security Module. Library path: %s, slot: %v, token label: %s",
-			c.HSMLibraryPath(), *c.HSMSlotNumber(), c.HSMTokenLabel())
+		l.WithError(err).Fatalf("Unable to configure Hardware Security Module. Library path: %s, %s",
+			config11.Path, describeToken(config11))
+	}
+
+	hsmContext, err := newPKCS11Context(ctx11, config11)
+	if err != nil {
+		l.WithError(err).Fatalf("Unable to configure Hardware Security Module. Library path: %s, %s",
+			config11.Path, describeToken(config11))
 	} else {
-		l.Info("Hardware Security Module is configured.")
+		l.WithField("token", describeToken(config11)).Info("Hardware Security Module is configured.")
 	}
 
-	var hsmContext Context = ctx11
 	return hsmContext
 }
+
+func describeToken(config11 *crypto11.Config) string {
+	if config11.TokenLabel != "" {
+		return fmt.Sprintf("token label: %s", config11.TokenLabel)
+	} else if config11.SlotNumber == nil {
+		return "neither slot nor token label"
+	}
+	return fmt.Sprintf("slot: %d", *config11.SlotNumber)
+}
@@ -12,6 +12,7 @@ package hsm_test
 
 import (
 	elliptic "crypto/elliptic"
+	x509 "crypto/x509"
 	reflect "reflect"
 
 	crypto11 "github.com/ThalesIgnite/crypto11"
@@ -115,3 +116,60 @@ func (mr *MockContextMockRecorder) GetAttribute(key, attribute interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAttribute", reflect.TypeOf((*MockContext)(nil).GetAttribute), key, attribute)
 }
+
+// SetKeyPairLabel mocks base method.
+func (m *MockContext) SetKeyPairLabel(id, label []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetKeyPairLabel", id, label)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetKeyPairLabel indicates an expected call of SetKeyPairLabel.
+func (mr *MockContextMockRecorder) SetKeyPairLabel(id, label interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetKeyPairLabel", reflect.TypeOf((*MockContext)(nil).SetKeyPairLabel), id, label)
+}
+
+// FindCertificates mocks base method.
+func (m *MockContext) FindCertificates(id []byte) ([]*x509.Certificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindCertificates", id)
+	ret0, _ := ret[0].([]*x509.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindCertificates indicates an expected call of FindCertificates.
+func (mr *MockContextMockRecorder) FindCertificates(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindCertificates", reflect.TypeOf((*MockContext)(nil).FindCertificates), id)
+}
+
+// Ping mocks base method.
+func (m *MockContext) Ping() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockContextMockRecorder) Ping() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockContext)(nil).Ping))
+}
+
+// Reconnect mocks base method.
+func (m *MockContext) Reconnect() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconnect")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reconnect indicates an expected call of Reconnect.
+func (mr *MockContextMockRecorder) Reconnect() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconnect", reflect.TypeOf((*MockContext)(nil).Reconnect))
+}
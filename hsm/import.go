@@ -0,0 +1,194 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm
+
+import (
+	"context"
+	"crypto"
+	"net/http"
+	"strings"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/otelx"
+)
+
+var ErrImportedKeyMismatch = &fosite.RFC6749Error{
+	CodeField:        http.StatusBadRequest,
+	ErrorField:       http.StatusText(http.StatusBadRequest),
+	DescriptionField: "The key does not match the key pair on the Hardware Security Module",
+}
+
+// AddKey adds a key pair which was provisioned on the Hardware Security Module out-of-band to the key set, if
+// importing keys is enabled.
+func (m *KeyManager) AddKey(ctx context.Context, set string, key *jose.JSONWebKey) error {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "hsm.AddKey")
+	defer span.End()
+	span.SetAttributes(otelx.StringAttrs(map[string]string{"set": set, "kid": key.KeyID})...)
+
+	return m.importKeys(ctx, set, []jose.JSONWebKey{*key})
+}
+
+// AddKeySet adds key pairs which were provisioned on the Hardware Security Module out-of-band to the key set, if
+// importing keys is enabled.
+func (m *KeyManager) AddKeySet(ctx context.Context, set string, keys *jose.JSONWebKeySet) error {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "hsm.AddKeySet")
+	defer span.End()
+	span.SetAttributes(otelx.StringAttrs(map[string]string{"set": set})...)
+
+	return m.importKeys(ctx, set, keys.Keys)
+}
+
+// UpdateKey adds the key pair to the key set like AddKey. Adding a key pair which is in the key set already has no
+// effect.
+func (m *KeyManager) UpdateKey(ctx context.Context, set string, key *jose.JSONWebKey) error {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "hsm.UpdateKey")
+	defer span.End()
+	span.SetAttributes(otelx.StringAttrs(map[string]string{"set": set, "kid": key.KeyID})...)
+
+	return m.importKeys(ctx, set, []jose.JSONWebKey{*key})
+}
+
+// UpdateKeySet adds the key pairs to the key set like AddKeySet. Key pairs of the key set which are not listed are
+// not deleted, because they may not have been generated by Hydra.
+func (m *KeyManager) UpdateKeySet(ctx context.Context, set string, keys *jose.JSONWebKeySet) error {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "hsm.UpdateKeySet")
+	defer span.End()
+	span.SetAttributes(otelx.StringAttrs(map[string]string{"set": set})...)
+
+	return m.importKeys(ctx, set, keys.Keys)
+}
+
+// importKeys adds key pairs which exist on the Hardware Security Module to the key set by setting their CKA_LABEL to
+// the key set. The key ID of each key must be the CKA_ID or the CKA_LABEL of the key pair, and its algorithm, use and
+// public key must match the key pair if they are given. No key pair is added unless all keys are valid.
+func (m *KeyManager) importKeys(ctx context.Context, set string, keys []jose.JSONWebKey) error {
+	if !m.c.HSMImportKeys(ctx) {
+		return errors.WithStack(ErrPreGeneratedKeys)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	set = m.prefixKeySet(set)
+	m.cache.delete(set)
+	hsmContext := m.keySetContext(set)
+
+	kids := make([]string, len(keys))
+	for k, key := range keys {
+		kid, err := m.validateImportedKey(ctx, hsmContext, set, key)
+		if err != nil {
+			return err
+		}
+		kids[k] = kid
+	}
+
+	for _, kid := range kids {
+		if err := hsmContext.SetKeyPairLabel([]byte(kid), []byte(set)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateImportedKey checks that the key matches a single key pair which does not belong to another key set yet, and
+// returns the CKA_ID of the key pair.
+func (m *KeyManager) validateImportedKey(ctx context.Context, hsmContext Context, set string, key jose.JSONWebKey) (string, error) {
+	if len(key.KeyID) == 0 {
+		return "", errors.WithStack(ErrImportedKeyMismatch.WithHint("The key ID must be the CKA_ID or the CKA_LABEL of the key pair."))
+	}
+
+	var kid []byte
+	keyPairs, err := hsmContext.FindKeyPairs([]byte(key.KeyID), nil)
+	if err != nil {
+		return "", err
+	}
+	if len(keyPairs) > 0 {
+		kid = []byte(key.KeyID)
+	} else {
+		// Key pairs which were provisioned out-of-band are often referenced by their label. Their CKA_ID becomes the
+		// key ID once they are added to the key set.
+		keyPairs, err = hsmContext.FindKeyPairs(nil, []byte(key.KeyID))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch len(keyPairs) {
+	case 0:
+		return "", errors.WithStack(x.ErrNotFound.WithHintf("No key pair with CKA_ID or CKA_LABEL '%s' exists on the Hardware Security Module.", key.KeyID))
+	case 1:
+	default:
+		return "", errors.WithStack(ErrImportedKeyMismatch.WithHintf("%d key pairs with CKA_ID or CKA_LABEL '%s' exist on the Hardware Security Module.", len(keyPairs), key.KeyID))
+	}
+	keyPair := keyPairs[0]
+
+	if kid == nil {
+		ckaId, err := hsmContext.GetAttribute(keyPair, crypto11.CkaId)
+		if err != nil {
+			return "", err
+		}
+		if ckaId == nil || len(ckaId.Value) == 0 {
+			return "", errors.WithStack(ErrImportedKeyMismatch.WithHintf("The key pair with CKA_LABEL '%s' has no CKA_ID.", key.KeyID))
+		}
+		kid = ckaId.Value
+	}
+
+	// Relabelling a key pair of another key set would move it out of that key set, which would keep serving it from its
+	// cache. Labels which provisioning tools set are replaced.
+	label, err := hsmContext.GetAttribute(keyPair, crypto11.CkaLabel)
+	if err != nil {
+		return "", err
+	}
+	if label != nil && string(label.Value) != set && m.isKeySetLabel(ctx, string(label.Value)) {
+		return "", errors.WithStack(ErrImportedKeyMismatch.WithHintf("The key pair '%s' belongs to key set '%s' already.", key.KeyID, label.Value))
+	}
+
+	_, alg, use, err := m.getKeySetAttributes(ctx, hsmContext, keyPair, kid)
+	if err != nil {
+		return "", err
+	}
+
+	if len(key.Algorithm) > 0 && key.Algorithm != alg {
+		return "", errors.WithStack(ErrImportedKeyMismatch.WithHintf("The key pair '%s' uses algorithm '%s', not '%s'.", key.KeyID, alg, key.Algorithm))
+	}
+	if len(key.Use) > 0 && key.Use != use {
+		return "", errors.WithStack(ErrImportedKeyMismatch.WithHintf("The key pair '%s' has use '%s', not '%s'.", key.KeyID, use, key.Use))
+	}
+	if key.Key != nil {
+		public, ok := keyPair.Public().(interface{ Equal(crypto.PublicKey) bool })
+		if !ok || !public.Equal(key.Public().Key) {
+			return "", errors.WithStack(ErrImportedKeyMismatch.WithHintf("The public key of the key pair '%s' differs.", key.KeyID))
+		}
+	}
+	return string(kid), nil
+}
+
+// isKeySetLabel returns true if the CKA_LABEL names a key set managed by Hydra: any label with the key set prefix, or
+// a configured or well-known key set if there is no prefix.
+func (m *KeyManager) isKeySetLabel(ctx context.Context, label string) bool {
+	if len(label) == 0 {
+		return false
+	}
+	if prefix := m.c.HSMKeySetPrefix(); len(prefix) > 0 {
+		return strings.HasPrefix(label, prefix)
+	}
+	if _, ok := m.keySetContexts[label]; ok {
+		return true
+	}
+	for _, set := range append([]string{x.OpenIDConnectKeyName, x.OAuth2JWTKeyName}, m.c.HSMKeySets(ctx)...) {
+		if label == set {
+			return true
+		}
+	}
+	return false
+}
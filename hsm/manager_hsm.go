@@ -15,6 +15,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/x/otelx"
@@ -42,7 +43,12 @@ type KeyManager struct {
 	jwk.Manager
 	sync.RWMutex
 	Context
-	c config.DefaultProvider
+	c     config.DefaultProvider
+	cache *keySetCache
+
+	// keySetContexts are the contexts of the key sets which are not stored on the default context, by prefixed key
+	// set.
+	keySetContexts map[string]Context
 }
 
 var ErrPreGeneratedKeys = &fosite.RFC6749Error{
@@ -55,7 +61,25 @@ func NewKeyManager(hsm Context, config *config.DefaultProvider) *KeyManager {
 	return &KeyManager{
 		Context: hsm,
 		c:       *config,
+		cache:   newKeySetCache(),
+	}
+}
+
+// WithKeySetContexts stores the key sets on the contexts which they are mapped to instead of the default context.
+func (m *KeyManager) WithKeySetContexts(contexts map[string]Context) *KeyManager {
+	m.keySetContexts = make(map[string]Context, len(contexts))
+	for set, hsmContext := range contexts {
+		m.keySetContexts[m.prefixKeySet(set)] = hsmContext
 	}
+	return m
+}
+
+// keySetContext returns the context which the prefixed key set is stored on.
+func (m *KeyManager) keySetContext(set string) Context {
+	if hsmContext, ok := m.keySetContexts[set]; ok {
+		return hsmContext
+	}
+	return m.Context
 }
 
 func (m *KeyManager) GenerateAndPersistKeySet(ctx context.Context, set, kid, alg, use string) (*jose.JSONWebKeySet, error) {
@@ -69,10 +93,35 @@ func (m *KeyManager) GenerateAndPersistKeySet(ctx context.Context, set, kid, alg
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
+	return m.generateAndPersistKeySet(set, kid, alg, use, 4096)
+}
+
+// GenerateAndPersistKeySetWithParameters generates a key set with explicit key parameters. Only RSA keys support
+// parameters other than those inferred from their algorithm.
+func (m *KeyManager) GenerateAndPersistKeySetWithParameters(ctx context.Context, set, kid, alg, use string, params jwk.KeyParameters) (*jose.JSONWebKeySet, error) {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "hsm.GenerateAndPersistKeySetWithParameters")
+	defer span.End()
+	attrs := map[string]string{
+		"set": set,
+		"kid": kid,
+		"alg": alg,
+		"use": use,
+	}
+	span.SetAttributes(otelx.StringAttrs(attrs)...)
+
+	params, err := jwk.ResolveKeyParameters(alg, params)
+	if err != nil {
+		return nil, err
+	}
+	return m.generateAndPersistKeySet(set, kid, alg, use, params.Bits)
+}
+
+func (m *KeyManager) generateAndPersistKeySet(set, kid, alg, use string, rsaBits int) (*jose.JSONWebKeySet, error) {
 	m.Lock()
 	defer m.Unlock()
 
 	set = m.prefixKeySet(set)
+	m.cache.delete(set)
 
 	err := m.deleteExistingKeySet(set)
 	if err != nil {
@@ -83,30 +132,31 @@ func (m *KeyManager) GenerateAndPersistKeySet(ctx context.Context, set, kid, alg
 		kid = uuid.New()
 	}
 
+	key, err := m.generateKeyPair(set, kid, alg, use, rsaBits)
+	if err != nil {
+		return nil, err
+	}
+	return createKeySet(key, kid, alg, use)
+}
+
+// generateKeyPair generates a key pair in the prefixed key set without touching the other keys of the set.
+func (m *KeyManager) generateKeyPair(set, kid, alg, use string, rsaBits int) (crypto11.Signer, error) {
 	privateAttrSet, publicAttrSet, err := getKeyPairAttributes(kid, set, use)
 	if err != nil {
 		return nil, err
 	}
 
+	hsmContext := m.keySetContext(set)
 	switch {
 	case alg == "RS256":
-		key, err := m.GenerateRSAKeyPairWithAttributes(publicAttrSet, privateAttrSet, 4096)
-		if err != nil {
-			return nil, err
-		}
-		return createKeySet(key, kid, alg, use)
+		return hsmContext.GenerateRSAKeyPairWithAttributes(publicAttrSet, privateAttrSet, rsaBits)
+	case alg == "PS256" || alg == "PS384" || alg == "PS512":
+		privateAttrSet.AddIfNotPresent([]*pkcs11.Attribute{pssAllowedMechanisms(alg)})
+		return hsmContext.GenerateRSAKeyPairWithAttributes(publicAttrSet, privateAttrSet, rsaBits)
 	case alg == "ES256":
-		key, err := m.GenerateECDSAKeyPairWithAttributes(publicAttrSet, privateAttrSet, elliptic.P256())
-		if err != nil {
-			return nil, err
-		}
-		return createKeySet(key, kid, alg, use)
+		return hsmContext.GenerateECDSAKeyPairWithAttributes(publicAttrSet, privateAttrSet, elliptic.P256())
 	case alg == "ES512":
-		key, err := m.GenerateECDSAKeyPairWithAttributes(publicAttrSet, privateAttrSet, elliptic.P521())
-		if err != nil {
-			return nil, err
-		}
-		return createKeySet(key, kid, alg, use)
+		return hsmContext.GenerateECDSAKeyPairWithAttributes(publicAttrSet, privateAttrSet, elliptic.P521())
 
 	// NOTE:
 	//	- HS256, HS512 not supported. Makes sense only if shared HSM is used between Hydra and authenticating client.
@@ -132,8 +182,9 @@ func (m *KeyManager) GetKey(ctx context.Context, set, kid string) (*jose.JSONWeb
 	defer m.RUnlock()
 
 	set = m.prefixKeySet(set)
+	hsmContext := m.keySetContext(set)
 
-	keyPair, err := m.FindKeyPair([]byte(kid), []byte(set))
+	keyPair, err := hsmContext.FindKeyPair([]byte(kid), []byte(set))
 	if err != nil {
 		return nil, err
 	}
@@ -142,12 +193,19 @@ func (m *KeyManager) GetKey(ctx context.Context, set, kid string) (*jose.JSONWeb
 		return nil, errors.WithStack(x.ErrNotFound)
 	}
 
-	id, alg, use, err := m.getKeySetAttributes(ctx, keyPair, []byte(kid))
+	id, alg, use, err := m.getKeySetAttributes(ctx, hsmContext, keyPair, []byte(kid))
 	if err != nil {
 		return nil, err
 	}
 
-	return createKeySet(keyPair, id, alg, use)
+	keySet, err := createKeySet(keyPair, id, alg, use)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.addCertificates(ctx, hsmContext, keySet.Keys); err != nil {
+		return nil, err
+	}
+	return keySet, nil
 }
 
 func (m *KeyManager) GetKeySet(ctx context.Context, set string) (*jose.JSONWebKeySet, error) {
@@ -163,7 +221,12 @@ func (m *KeyManager) GetKeySet(ctx context.Context, set string) (*jose.JSONWebKe
 
 	set = m.prefixKeySet(set)
 
-	keyPairs, err := m.FindKeyPairs(nil, []byte(set))
+	if keySet, ok := m.cache.get(set); ok {
+		return keySet, nil
+	}
+
+	hsmContext := m.keySetContext(set)
+	keyPairs, err := hsmContext.FindKeyPairs(nil, []byte(set))
 	if err != nil {
 		return nil, err
 	}
@@ -172,18 +235,26 @@ func (m *KeyManager) GetKeySet(ctx context.Context, set string) (*jose.JSONWebKe
 		return nil, errors.WithStack(x.ErrNotFound)
 	}
 
+	rotated, err := m.rotatedKeyPairs(ctx, hsmContext, keyPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys whose grace period expired are no longer published, even if they were not yet deleted by a rotation.
+	retained, _ := expireKeyPairs(rotated, time.Now(), m.c.HSMRotationGracePeriod(ctx))
 	var keys []jose.JSONWebKey
-	for _, keyPair := range keyPairs {
-		kid, alg, use, err := m.getKeySetAttributes(ctx, keyPair, nil)
-		if err != nil {
-			return nil, err
-		}
-		keys = append(keys, createKeys(keyPair, kid, alg, use)...)
+	for _, keyPair := range retained {
+		keys = append(keys, createKeys(keyPair.Signer, keyPair.kid, keyPair.alg, keyPair.use)...)
+	}
+	if err := m.addCertificates(ctx, hsmContext, keys); err != nil {
+		return nil, err
 	}
 
-	return &jose.JSONWebKeySet{
+	keySet := &jose.JSONWebKeySet{
 		Keys: keys,
-	}, nil
+	}
+	m.cache.set(set, keySet, m.c.HSMKeySetCacheTTL(ctx))
+	return keySet, nil
 }
 
 func (m *KeyManager) DeleteKey(ctx context.Context, set, kid string) error {
@@ -199,8 +270,9 @@ func (m *KeyManager) DeleteKey(ctx context.Context, set, kid string) error {
 	defer m.Unlock()
 
 	set = m.prefixKeySet(set)
+	m.cache.delete(set)
 
-	keyPair, err := m.FindKeyPair([]byte(kid), []byte(set))
+	keyPair, err := m.keySetContext(set).FindKeyPair([]byte(kid), []byte(set))
 	if err != nil {
 		return err
 	}
@@ -228,8 +300,9 @@ func (m *KeyManager) DeleteKeySet(ctx context.Context, set string) error {
 	defer m.Unlock()
 
 	set = m.prefixKeySet(set)
+	m.cache.delete(set)
 
-	keyPairs, err := m.FindKeyPairs(nil, []byte(set))
+	keyPairs, err := m.keySetContext(set).FindKeyPairs(nil, []byte(set))
 	if err != nil {
 		return err
 	}
@@ -247,25 +320,9 @@ func (m *KeyManager) DeleteKeySet(ctx context.Context, set string) error {
 	return nil
 }
 
-func (m *KeyManager) AddKey(_ context.Context, _ string, _ *jose.JSONWebKey) error {
-	return errors.WithStack(ErrPreGeneratedKeys)
-}
-
-func (m *KeyManager) AddKeySet(_ context.Context, _ string, _ *jose.JSONWebKeySet) error {
-	return errors.WithStack(ErrPreGeneratedKeys)
-}
-
-func (m *KeyManager) UpdateKey(_ context.Context, _ string, _ *jose.JSONWebKey) error {
-	return errors.WithStack(ErrPreGeneratedKeys)
-}
-
-func (m *KeyManager) UpdateKeySet(_ context.Context, _ string, _ *jose.JSONWebKeySet) error {
-	return errors.WithStack(ErrPreGeneratedKeys)
-}
-
-func (m *KeyManager) getKeySetAttributes(ctx context.Context, key crypto11.Signer, kid []byte) (string, string, string, error) {
+func (m *KeyManager) getKeySetAttributes(ctx context.Context, hsmContext Context, key crypto11.Signer, kid []byte) (string, string, string, error) {
 	if kid == nil {
-		ckaId, err := m.GetAttribute(key, crypto11.CkaId)
+		ckaId, err := hsmContext.GetAttribute(key, crypto11.CkaId)
 		if err != nil {
 			return "", "", "", err
 		}
@@ -275,10 +332,11 @@ func (m *KeyManager) getKeySetAttributes(ctx context.Context, key crypto11.Signe
 	var alg string
 	switch k := key.Public().(type) {
 	case *rsa.PublicKey:
-		alg = "RS256"
 		if k.N.BitLen() < 4096 && !m.c.IsDevelopmentMode(ctx) {
 			return "", "", "", errors.WithStack(jwk.ErrMinimalRsaKeyLength)
 		}
+		allowedMechanisms, _ := hsmContext.GetAttribute(key, crypto11.CkaAllowedMechanisms)
+		alg = rsaAlgorithm(allowedMechanisms)
 	case *ecdsa.PublicKey:
 		if k.Curve == elliptic.P521() {
 			alg = "ES512"
@@ -292,7 +350,7 @@ func (m *KeyManager) getKeySetAttributes(ctx context.Context, key crypto11.Signe
 	}
 
 	use := "sig"
-	ckaDecrypt, _ := m.GetAttribute(key, crypto11.CkaDecrypt)
+	ckaDecrypt, _ := hsmContext.GetAttribute(key, crypto11.CkaDecrypt)
 	if ckaDecrypt != nil && len(ckaDecrypt.Value) != 0 && ckaDecrypt.Value[0] == 0x1 {
 		use = "enc"
 	}
@@ -335,7 +393,7 @@ func getKeyPairAttributes(kid string, set string, use string) (crypto11.Attribut
 }
 
 func (m *KeyManager) deleteExistingKeySet(set string) error {
-	existingKeyPairs, err := m.FindKeyPairs(nil, []byte(set))
+	existingKeyPairs, err := m.keySetContext(set).FindKeyPairs(nil, []byte(set))
 	if err != nil {
 		return err
 	}
@@ -354,10 +412,15 @@ func createKeySet(key crypto11.Signer, kid, alg, use string) (*jose.JSONWebKeySe
 }
 
 func createKeys(key crypto11.Signer, kid, alg, use string) []jose.JSONWebKey {
+	var signer jose.OpaqueSigner = cryptosigner.Opaque(key)
+	if _, ok := pssAlgorithms[alg]; ok {
+		signer = newPSSSigner(key, alg)
+	}
+
 	return []jose.JSONWebKey{{
 		Algorithm:                   alg,
 		Use:                         use,
-		Key:                         cryptosigner.Opaque(key),
+		Key:                         signer,
 		KeyID:                       kid,
 		Certificates:                []*x509.Certificate{},
 		CertificateThumbprintSHA1:   []uint8{},
@@ -8,14 +8,23 @@ package hsm_test
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math/big"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ory/hydra/v2/jwk"
 	"github.com/ory/x/contextx"
@@ -56,6 +65,10 @@ func TestDefaultKeyManager_HSMEnabled(t *testing.T) {
 	assert.NoError(t, err)
 	assert.IsType(t, &jwk.ManagerStrategy{}, reg.KeyManager())
 	assert.IsType(t, &sql.Persister{}, reg.SoftwareKeyManager())
+
+	mockHsmContext.EXPECT().Ping().Return(nil)
+	require.Contains(t, reg.HealthHandler().ReadyChecks, "hsm")
+	assert.NoError(t, reg.HealthHandler().ReadyChecks["hsm"](httptest.NewRequest("GET", "/health/ready", nil)))
 }
 
 func TestKeyManager_HsmKeySetPrefix(t *testing.T) {
@@ -66,6 +79,7 @@ func TestKeyManager_HsmKeySetPrefix(t *testing.T) {
 	c := config.MustNew(context.Background(), l, configx.SkipValidation())
 	keySetPrefix := "application_specific_prefix."
 	c.MustSet(context.Background(), config.HSMKeySetPrefix, keySetPrefix)
+	c.MustSet(context.Background(), config.HSMKeySetCacheTTL, "0s")
 	m := hsm.NewKeyManager(hsmContext, c)
 
 	rsaKey3072, err := rsa.GenerateKey(rand.Reader, 3072)
@@ -105,6 +119,7 @@ func TestKeyManager_HsmKeySetPrefix(t *testing.T) {
 	t.Run("case=GetKey", func(t *testing.T) {
 		hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(expectedPrefixedOpenIDConnectKeyName))).Return(rsaKeyPair4096, nil)
 		hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair4096), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair4096), gomock.Eq(crypto11.CkaAllowedMechanisms)).Return(nil, nil)
 
 		got, err := m.GetKey(context.TODO(), x.OpenIDConnectKeyName, kid)
 
@@ -125,6 +140,7 @@ func TestKeyManager_HsmKeySetPrefix(t *testing.T) {
 		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(expectedPrefixedOpenIDConnectKeyName))).Return([]crypto11.Signer{rsaKeyPair4096}, nil)
 		hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair4096), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(kid)), nil)
 		hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair4096), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair4096), gomock.Eq(crypto11.CkaAllowedMechanisms)).Return(nil, nil)
 
 		got, err := m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
 
@@ -341,6 +357,73 @@ func TestKeyManager_GenerateAndPersistKeySet(t *testing.T) {
 	}
 }
 
+func TestKeyManager_RSASSAPSS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	hsmContext := NewMockContext(ctrl)
+	defer ctrl.Finish()
+	l := logrusx.New("", "")
+	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	m := hsm.NewKeyManager(hsmContext, c)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	require.NoError(t, err)
+	rsaKeyPair := NewMockSignerDecrypter(ctrl)
+	rsaKeyPair.EXPECT().Public().Return(&rsaKey.PublicKey).AnyTimes()
+	rsaKeyPair.EXPECT().Sign(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(r io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+		// PKCS#11 only supports salts of a fixed length.
+		pss, ok := opts.(*rsa.PSSOptions)
+		require.True(t, ok)
+		assert.Equal(t, rsa.PSSSaltLengthEqualsHash, pss.SaltLength)
+		return rsaKey.Sign(r, digest, opts)
+	}).AnyTimes()
+
+	var allowedMechanisms []byte
+	for _, mechanism := range []uint{pkcs11.CKM_RSA_PKCS_PSS, pkcs11.CKM_SHA256_RSA_PKCS_PSS} {
+		allowedMechanisms = append(allowedMechanisms, pkcs11.NewAttribute(pkcs11.CKA_ALLOWED_MECHANISMS, mechanism).Value...)
+	}
+	allowedMechanismsAttr := pkcs11.NewAttribute(pkcs11.CKA_ALLOWED_MECHANISMS, allowedMechanisms)
+
+	var kid = uuid.New()
+
+	verify := func(t *testing.T, key jose.JSONWebKey) {
+		assert.Equal(t, "PS256", key.Algorithm)
+		opaque, ok := key.Key.(jose.OpaqueSigner)
+		require.True(t, ok)
+		assert.Equal(t, []jose.SignatureAlgorithm{jose.PS256}, opaque.Algs())
+
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.PS256, Key: key}, nil)
+		require.NoError(t, err)
+		jws, err := signer.Sign([]byte("payload"))
+		require.NoError(t, err)
+		payload, err := jws.Verify(&rsaKey.PublicKey)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(payload))
+	}
+
+	t.Run("case=GenerateAndPersistKeySet", func(t *testing.T) {
+		privateAttrSet, publicAttrSet := expectedKeyAttributes(t, x.OpenIDConnectKeyName, kid)
+		privateAttrSet.AddIfNotPresent([]*pkcs11.Attribute{allowedMechanismsAttr})
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil, nil)
+		hsmContext.EXPECT().GenerateRSAKeyPairWithAttributes(gomock.Eq(publicAttrSet), gomock.Eq(privateAttrSet), gomock.Eq(4096)).Return(rsaKeyPair, nil)
+
+		got, err := m.GenerateAndPersistKeySet(context.TODO(), x.OpenIDConnectKeyName, kid, "PS256", "sig")
+		require.NoError(t, err)
+		require.Len(t, got.Keys, 1)
+		verify(t, got.Keys[0])
+	})
+
+	t.Run("case=GetKey", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(rsaKeyPair, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaAllowedMechanisms)).Return(allowedMechanismsAttr, nil)
+
+		got, err := m.GetKey(context.TODO(), x.OpenIDConnectKeyName, kid)
+		require.NoError(t, err)
+		require.Len(t, got.Keys, 1)
+		verify(t, got.Keys[0])
+	})
+}
+
 func TestKeyManager_GetKey(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	hsmContext := NewMockContext(ctrl)
@@ -394,6 +477,7 @@ func TestKeyManager_GetKey(t *testing.T) {
 			setup: func(t *testing.T) {
 				hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(rsaKeyPair, nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaAllowedMechanisms)).Return(nil, nil)
 			},
 			want: expectedKeySet(rsaKeyPair, kid, "RS256", "sig"),
 		},
@@ -407,6 +491,7 @@ func TestKeyManager_GetKey(t *testing.T) {
 			setup: func(t *testing.T) {
 				hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(rsaKeyPair, nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true), nil)
+				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaAllowedMechanisms)).Return(nil, nil)
 			},
 			want: expectedKeySet(rsaKeyPair, kid, "RS256", "enc"),
 		},
@@ -420,6 +505,7 @@ func TestKeyManager_GetKey(t *testing.T) {
 			setup: func(t *testing.T) {
 				hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(rsaKeyPair, nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, errors.New("GetAttributeError"))
+				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaAllowedMechanisms)).Return(nil, nil)
 			},
 			want: expectedKeySet(rsaKeyPair, kid, "RS256", "sig"),
 		},
@@ -537,6 +623,7 @@ func TestKeyManager_GetKeySet(t *testing.T) {
 	defer ctrl.Finish()
 	l := logrusx.New("", "")
 	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	c.MustSet(context.Background(), config.HSMKeySetCacheTTL, "0s")
 	m := hsm.NewKeyManager(hsmContext, c)
 
 	rsaKey, err := rsa.GenerateKey(rand.Reader, 4096)
@@ -592,6 +679,7 @@ func TestKeyManager_GetKeySet(t *testing.T) {
 				hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(allKeys, nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(rsaKid)), nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+				hsmContext.EXPECT().GetAttribute(gomock.Eq(rsaKeyPair), gomock.Eq(crypto11.CkaAllowedMechanisms)).Return(nil, nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(ecdsaP256KeyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(ecdsaP256Kid)), nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(ecdsaP256KeyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
 				hsmContext.EXPECT().GetAttribute(gomock.Eq(ecdsaP521KeyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(ecdsaP521Kid)), nil)
@@ -679,6 +767,236 @@ func TestKeyManager_GetKeySet(t *testing.T) {
 	}
 }
 
+func TestKeyManager_KeySetCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	hsmContext := NewMockContext(ctrl)
+	defer ctrl.Finish()
+	l := logrusx.New("", "")
+	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	c.MustSet(context.Background(), config.HSMKeySetCacheTTL, "1m")
+	m := hsm.NewKeyManager(hsmContext, c)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	kid := uuid.New()
+	keyPair := NewMockSignerDecrypter(ctrl)
+	keyPair.EXPECT().Public().Return(&key.PublicKey).AnyTimes()
+	hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(kid)), nil).AnyTimes()
+	hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil).AnyTimes()
+
+	t.Run("case=key sets are read from the HSM once", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{keyPair}, nil).Times(1)
+
+		for i := 0; i < 3; i++ {
+			got, err := m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+			require.NoError(t, err)
+			assert.Equal(t, expectedKeySet(keyPair, kid, "ES256", "sig"), got)
+			got.Keys = nil
+		}
+	})
+
+	t.Run("case=deleting a key invalidates the key set", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(keyPair, nil)
+		keyPair.EXPECT().Delete().Return(nil)
+		require.NoError(t, m.DeleteKey(context.TODO(), x.OpenIDConnectKeyName, kid))
+
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil, nil)
+		_, err := m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+		require.ErrorIs(t, err, x.ErrNotFound)
+	})
+
+	t.Run("case=generating a key set invalidates the key set", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{keyPair}, nil)
+		_, err := m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+		require.NoError(t, err)
+
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{keyPair}, nil)
+		keyPair.EXPECT().Delete().Return(nil)
+		hsmContext.EXPECT().GenerateECDSAKeyPairWithAttributes(gomock.Any(), gomock.Any(), gomock.Eq(elliptic.P256())).Return(keyPair, nil)
+		_, err = m.GenerateAndPersistKeySet(context.TODO(), x.OpenIDConnectKeyName, kid, "ES256", "sig")
+		require.NoError(t, err)
+
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{keyPair}, nil)
+		_, err = m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+		require.NoError(t, err)
+	})
+}
+
+func TestKeyManager_KeySetContexts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defaultContext := NewMockContext(ctrl)
+	partitionContext := NewMockContext(ctrl)
+	defer ctrl.Finish()
+	l := logrusx.New("", "")
+	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	c.MustSet(context.Background(), config.HSMKeySetPrefix, "app1.")
+	m := hsm.NewKeyManager(defaultContext, c).WithKeySetContexts(map[string]hsm.Context{
+		x.OAuth2JWTKeyName: partitionContext,
+	})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	kid := uuid.New()
+	keyPair := NewMockSignerDecrypter(ctrl)
+	keyPair.EXPECT().Public().Return(&key.PublicKey).AnyTimes()
+
+	t.Run("case=key sets of a partition are stored on its token", func(t *testing.T) {
+		partitionContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte("app1."+x.OAuth2JWTKeyName))).Return(nil, nil)
+		partitionContext.EXPECT().GenerateECDSAKeyPairWithAttributes(gomock.Any(), gomock.Any(), gomock.Eq(elliptic.P256())).Return(keyPair, nil)
+		_, err := m.GenerateAndPersistKeySet(context.TODO(), x.OAuth2JWTKeyName, kid, "ES256", "sig")
+		require.NoError(t, err)
+
+		partitionContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte("app1."+x.OAuth2JWTKeyName))).Return([]crypto11.Signer{keyPair}, nil)
+		partitionContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(kid)), nil)
+		partitionContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		got, err := m.GetKeySet(context.TODO(), x.OAuth2JWTKeyName)
+		require.NoError(t, err)
+		assert.Equal(t, expectedKeySet(keyPair, kid, "ES256", "sig"), got)
+
+		partitionContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte("app1."+x.OAuth2JWTKeyName))).Return(keyPair, nil)
+		keyPair.EXPECT().Delete().Return(nil)
+		require.NoError(t, m.DeleteKey(context.TODO(), x.OAuth2JWTKeyName, kid))
+	})
+
+	t.Run("case=other key sets are stored on the default token", func(t *testing.T) {
+		defaultContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte("app1."+x.OpenIDConnectKeyName))).Return(keyPair, nil)
+		defaultContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		got, err := m.GetKey(context.TODO(), x.OpenIDConnectKeyName, kid)
+		require.NoError(t, err)
+		assert.Equal(t, expectedKeySet(keyPair, kid, "ES256", "sig"), got)
+	})
+}
+
+func TestKeyManager_HealthCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defaultContext := NewMockContext(ctrl)
+	partitionContext := NewMockContext(ctrl)
+	defer ctrl.Finish()
+	l := logrusx.New("", "")
+	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	c.MustSet(context.Background(), config.HSMKeySetCacheTTL, "1m")
+	m := hsm.NewKeyManager(defaultContext, c).WithKeySetContexts(map[string]hsm.Context{
+		x.OAuth2JWTKeyName:   partitionContext,
+		"hydra.jwt.userinfo": partitionContext,
+	})
+
+	t.Run("case=reachable tokens are pinged once", func(t *testing.T) {
+		defaultContext.EXPECT().Ping().Return(nil)
+		partitionContext.EXPECT().Ping().Return(nil)
+		require.NoError(t, m.HealthCheck(context.TODO()))
+	})
+
+	t.Run("case=reconnects to unreachable tokens and drops the cached key sets", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		kid := uuid.New()
+		keyPair := NewMockSignerDecrypter(ctrl)
+		keyPair.EXPECT().Public().Return(&key.PublicKey).AnyTimes()
+		defaultContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(kid)), nil).AnyTimes()
+		defaultContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil).AnyTimes()
+		defaultContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{keyPair}, nil).Times(2)
+
+		_, err = m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+		require.NoError(t, err)
+
+		gomock.InOrder(
+			defaultContext.EXPECT().Ping().Return(pkcs11.Error(pkcs11.CKR_DEVICE_REMOVED)),
+			defaultContext.EXPECT().Reconnect().Return(nil),
+			defaultContext.EXPECT().Ping().Return(nil),
+		)
+		partitionContext.EXPECT().Ping().Return(nil)
+		require.NoError(t, m.HealthCheck(context.TODO()))
+
+		_, err = m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+		require.NoError(t, err)
+	})
+
+	t.Run("case=fails if reconnecting fails", func(t *testing.T) {
+		defaultContext.EXPECT().Ping().Return(nil)
+		partitionContext.EXPECT().Ping().Return(pkcs11.Error(pkcs11.CKR_DEVICE_REMOVED))
+		partitionContext.EXPECT().Reconnect().Return(pkcs11.Error(pkcs11.CKR_TOKEN_NOT_PRESENT))
+		err := m.HealthCheck(context.TODO())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reconnecting failed")
+	})
+}
+
+func TestKeyManager_Certificates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	hsmContext := NewMockContext(ctrl)
+	defer ctrl.Finish()
+	l := logrusx.New("", "")
+	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	c.MustSet(context.Background(), config.HSMPublishCertificates, true)
+	m := hsm.NewKeyManager(hsmContext, c)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	kid := uuid.New()
+	keyPair := NewMockSignerDecrypter(ctrl)
+	keyPair.EXPECT().Public().Return(&key.PublicKey).AnyTimes()
+	hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil).AnyTimes()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := createCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Root CA"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil, &caKey.PublicKey, caKey)
+	leaf := createCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "ID Token Signing"}}, ca, &key.PublicKey, caKey)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other := createCertificate(t, &x509.Certificate{SerialNumber: big.NewInt(3), Subject: pkix.Name{CommonName: "Other"}}, ca, &otherKey.PublicKey, caKey)
+
+	t.Run("case=the certificate chain is published with the key", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(keyPair, nil)
+		hsmContext.EXPECT().FindCertificates(gomock.Eq([]byte(kid))).Return([]*x509.Certificate{ca, other, leaf}, nil)
+
+		got, err := m.GetKey(context.TODO(), x.OpenIDConnectKeyName, kid)
+		require.NoError(t, err)
+		require.Len(t, got.Keys, 1)
+		sha1Thumbprint := sha1.Sum(leaf.Raw)
+		sha256Thumbprint := sha256.Sum256(leaf.Raw)
+		assert.Equal(t, []*x509.Certificate{leaf, ca}, got.Keys[0].Certificates)
+		assert.Equal(t, sha1Thumbprint[:], got.Keys[0].CertificateThumbprintSHA1)
+		assert.Equal(t, sha256Thumbprint[:], got.Keys[0].CertificateThumbprintSHA256)
+
+		published, err := json.Marshal(jwk.ExcludeOpaquePrivateKeys(got))
+		require.NoError(t, err)
+		var parsed jose.JSONWebKeySet
+		require.NoError(t, json.Unmarshal(published, &parsed))
+		assert.Equal(t, []*x509.Certificate{leaf, ca}, parsed.Keys[0].Certificates)
+	})
+
+	t.Run("case=keys without a certificate are published without certificates", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{keyPair}, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(kid)), nil)
+		hsmContext.EXPECT().FindCertificates(gomock.Eq([]byte(kid))).Return([]*x509.Certificate{ca, other}, nil)
+
+		got, err := m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+		require.NoError(t, err)
+		assert.Equal(t, expectedKeySet(keyPair, kid, "ES256", "sig"), got)
+	})
+
+	t.Run("case=errors finding certificates are returned", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPair(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(keyPair, nil)
+		hsmContext.EXPECT().FindCertificates(gomock.Eq([]byte(kid))).Return(nil, errors.New("FindCertificatesError"))
+
+		_, err := m.GetKey(context.TODO(), x.OpenIDConnectKeyName, kid)
+		require.EqualError(t, err, "FindCertificatesError")
+	})
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, public crypto.PublicKey, signer crypto.Signer) *x509.Certificate {
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, public, signer)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return certificate
+}
+
 func TestKeyManager_DeleteKey(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	hsmContext := NewMockContext(ctrl)
@@ -841,36 +1159,125 @@ func TestKeyManager_DeleteKeySet(t *testing.T) {
 	}
 }
 
-func TestKeyManager_AddKey(t *testing.T) {
-	m := &hsm.KeyManager{
-		Context: nil,
-	}
-	err := m.AddKey(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKey{})
-	assert.ErrorIs(t, err, hsm.ErrPreGeneratedKeys)
-}
+func TestKeyManager_ImportKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	hsmContext := NewMockContext(ctrl)
+	defer ctrl.Finish()
+	l := logrusx.New("", "")
+	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	m := hsm.NewKeyManager(hsmContext, c)
 
-func TestKeyManager_AddKeySet(t *testing.T) {
-	m := &hsm.KeyManager{
-		Context: nil,
-	}
-	err := m.AddKeySet(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKeySet{})
-	assert.ErrorIs(t, err, hsm.ErrPreGeneratedKeys)
-}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	kid := "provisioned-key"
+	keyPair := NewMockSignerDecrypter(ctrl)
+	keyPair.EXPECT().Public().Return(&key.PublicKey).AnyTimes()
+	jsonWebKey := &jose.JSONWebKey{Key: &key.PublicKey, KeyID: kid, Algorithm: "ES256", Use: "sig"}
+
+	t.Run("case=keys are refused if importing keys is disabled", func(t *testing.T) {
+		assert.ErrorIs(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey), hsm.ErrPreGeneratedKeys)
+		assert.ErrorIs(t, m.AddKeySet(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKeySet{}), hsm.ErrPreGeneratedKeys)
+		assert.ErrorIs(t, m.UpdateKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey), hsm.ErrPreGeneratedKeys)
+		assert.ErrorIs(t, m.UpdateKeySet(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKeySet{}), hsm.ErrPreGeneratedKeys)
+	})
 
-func TestKeyManager_UpdateKey(t *testing.T) {
-	m := &hsm.KeyManager{
-		Context: nil,
-	}
-	err := m.UpdateKey(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKey{})
-	assert.ErrorIs(t, err, hsm.ErrPreGeneratedKeys)
-}
+	c.MustSet(context.Background(), config.HSMImportKeys, true)
 
-func TestKeyManager_UpdateKeySet(t *testing.T) {
-	m := &hsm.KeyManager{
-		Context: nil,
+	expectKeyPair := func() {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte(kid)), gomock.Nil()).Return([]crypto11.Signer{keyPair}, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaLabel)).Return(nil, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
 	}
-	err := m.UpdateKeySet(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKeySet{})
-	assert.ErrorIs(t, err, hsm.ErrPreGeneratedKeys)
+
+	t.Run("case=key pairs are added to the key set", func(t *testing.T) {
+		expectKeyPair()
+		hsmContext.EXPECT().SetKeyPairLabel(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil)
+		require.NoError(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey))
+
+		expectKeyPair()
+		hsmContext.EXPECT().SetKeyPairLabel(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil)
+		require.NoError(t, m.UpdateKeySet(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{*jsonWebKey}}))
+	})
+
+	t.Run("case=key pairs of other key sets are refused", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte(kid)), gomock.Nil()).Return([]crypto11.Signer{keyPair}, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaLabel)).
+			Return(pkcs11.NewAttribute(pkcs11.CKA_LABEL, []byte(x.OpenIDConnectKeyName)), nil)
+		assert.ErrorIs(t, m.AddKey(context.TODO(), x.OAuth2JWTKeyName, jsonWebKey), hsm.ErrImportedKeyMismatch)
+
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte(kid)), gomock.Nil()).Return([]crypto11.Signer{keyPair}, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaLabel)).
+			Return(pkcs11.NewAttribute(pkcs11.CKA_LABEL, []byte(x.OpenIDConnectKeyName)), nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		hsmContext.EXPECT().SetKeyPairLabel(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil)
+		require.NoError(t, m.UpdateKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey))
+	})
+
+	t.Run("case=key pairs are referenced by their label", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte("ceremony-key")), gomock.Nil()).Return(nil, nil)
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte("ceremony-key"))).Return([]crypto11.Signer{keyPair}, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(kid)), nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaLabel)).
+			Return(pkcs11.NewAttribute(pkcs11.CKA_LABEL, []byte("ceremony-key")), nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		hsmContext.EXPECT().SetKeyPairLabel(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil)
+		require.NoError(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKey{Key: &key.PublicKey, KeyID: "ceremony-key", Algorithm: "ES256", Use: "sig"}))
+	})
+
+	t.Run("case=labels of other tools are replaced", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte(kid)), gomock.Nil()).Return([]crypto11.Signer{keyPair}, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaLabel)).
+			Return(pkcs11.NewAttribute(pkcs11.CKA_LABEL, []byte("pkcs11-tool-key")), nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil)
+		hsmContext.EXPECT().SetKeyPairLabel(gomock.Eq([]byte(kid)), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil)
+		require.NoError(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey))
+	})
+
+	t.Run("case=key pairs must exist", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte(kid)), gomock.Nil()).Return(nil, nil)
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(kid))).Return(nil, nil)
+		assert.ErrorIs(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey), x.ErrNotFound)
+
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte(kid)), gomock.Nil()).Return([]crypto11.Signer{keyPair, keyPair}, nil)
+		assert.ErrorIs(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey), hsm.ErrImportedKeyMismatch)
+
+		assert.ErrorIs(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKey{Key: &key.PublicKey}), hsm.ErrImportedKeyMismatch)
+	})
+
+	t.Run("case=keys must match the key pair", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		for _, k := range []*jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: kid, Algorithm: "ES512", Use: "sig"},
+			{Key: &key.PublicKey, KeyID: kid, Algorithm: "ES256", Use: "enc"},
+			{Key: &otherKey.PublicKey, KeyID: kid, Algorithm: "ES256", Use: "sig"},
+		} {
+			expectKeyPair()
+			assert.ErrorIs(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, k), hsm.ErrImportedKeyMismatch)
+		}
+	})
+
+	t.Run("case=no key pair is added unless all keys are valid", func(t *testing.T) {
+		expectKeyPair()
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte("unknown-key")), gomock.Nil()).Return(nil, nil)
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte("unknown-key"))).Return(nil, nil)
+		err := m.AddKeySet(context.TODO(), x.OpenIDConnectKeyName, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			*jsonWebKey,
+			{Key: &key.PublicKey, KeyID: "unknown-key"},
+		}})
+		assert.ErrorIs(t, err, x.ErrNotFound)
+	})
+
+	t.Run("case=key pairs of prefixed key sets are refused", func(t *testing.T) {
+		c.MustSet(context.Background(), config.HSMKeySetPrefix, "app1.")
+		t.Cleanup(func() { c.MustSet(context.Background(), config.HSMKeySetPrefix, "") })
+
+		hsmContext.EXPECT().FindKeyPairs(gomock.Eq([]byte(kid)), gomock.Nil()).Return([]crypto11.Signer{keyPair}, nil)
+		hsmContext.EXPECT().GetAttribute(gomock.Eq(keyPair), gomock.Eq(crypto11.CkaLabel)).
+			Return(pkcs11.NewAttribute(pkcs11.CKA_LABEL, []byte("app1.other-set")), nil)
+		assert.ErrorIs(t, m.AddKey(context.TODO(), x.OpenIDConnectKeyName, jsonWebKey), hsm.ErrImportedKeyMismatch)
+	})
 }
 
 func expectedKeyAttributes(t *testing.T, set, kid string) (crypto11.AttributeSet, crypto11.AttributeSet) {
@@ -37,14 +37,39 @@ func NewContext(c *config.DefaultProvider, l *logrusx.Logger) Context {
 	return nil
 }
 
+func Probe(c *config.DefaultProvider) error {
+	return errors.WithStack(ErrOpSysNotSupported)
+}
+
+func NewKeySetContexts(c *config.DefaultProvider, l *logrusx.Logger) map[string]Context {
+	l.Fatalf("Hardware Security Module is not supported on this platform.")
+	return nil
+}
+
 func NewKeyManager(hsm Context, config *config.DefaultProvider) *KeyManager {
 	return nil
 }
 
+func (m *KeyManager) WithKeySetContexts(map[string]Context) *KeyManager {
+	return m
+}
+
+func (m *KeyManager) HealthCheck(_ context.Context) error {
+	return errors.WithStack(ErrOpSysNotSupported)
+}
+
 func (m *KeyManager) GenerateAndPersistKeySet(_ context.Context, set, kid, alg, use string) (*jose.JSONWebKeySet, error) {
 	return nil, errors.WithStack(ErrOpSysNotSupported)
 }
 
+func (m *KeyManager) GenerateAndPersistKeySetWithParameters(_ context.Context, set, kid, alg, use string, params jwk.KeyParameters) (*jose.JSONWebKeySet, error) {
+	return nil, errors.WithStack(ErrOpSysNotSupported)
+}
+
+func (m *KeyManager) RotateKeySet(_ context.Context, set, alg, use string) (*jose.JSONWebKeySet, error) {
+	return nil, errors.WithStack(ErrOpSysNotSupported)
+}
+
 func (m *KeyManager) GetKey(_ context.Context, set, kid string) (*jose.JSONWebKeySet, error) {
 	return nil, errors.WithStack(ErrOpSysNotSupported)
 }
@@ -0,0 +1,197 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm
+
+import (
+	"crypto/x509"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+var errClosed = errors.New("the session with the token is closed")
+
+// pkcs11Context implements the operations which crypto11 does not provide directly on the PKCS#11 library. It shares
+// the library and the login state of the crypto11 context, so its sessions are logged in already.
+type pkcs11Context struct {
+	*crypto11.Context
+	p11      *pkcs11.Ctx
+	slot     uint
+	config11 *crypto11.Config
+}
+
+func newPKCS11Context(ctx11 *crypto11.Context, config11 *crypto11.Config) (*pkcs11Context, error) {
+	p11 := pkcs11.New(config11.Path)
+	if p11 == nil {
+		return nil, errors.Errorf("unable to load PKCS#11 library %s", config11.Path)
+	}
+
+	// The library was initialized by crypto11 already.
+	if err := p11.Initialize(); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		p11.Destroy()
+		return nil, errors.WithStack(err)
+	}
+
+	slot, err := findSlot(p11, config11)
+	if err != nil {
+		p11.Destroy()
+		return nil, err
+	}
+
+	return &pkcs11Context{Context: ctx11, p11: p11, slot: slot, config11: config11}, nil
+}
+
+func findSlot(p11 *pkcs11.Ctx, config11 *crypto11.Config) (uint, error) {
+	if config11.TokenLabel == "" {
+		return uint(*config11.SlotNumber), nil
+	}
+
+	slots, err := p11.GetSlotList(true)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	for _, slot := range slots {
+		tokenInfo, err := p11.GetTokenInfo(slot)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if tokenInfo.Label == config11.TokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, errors.Errorf("unable to find a token with label %s", config11.TokenLabel)
+}
+
+// SetKeyPairLabel sets the CKA_LABEL of the private and public key objects with the CKA_ID id.
+func (c *pkcs11Context) SetKeyPairLabel(id []byte, label []byte) error {
+	session, err := c.openSession(pkcs11.CKF_SERIAL_SESSION | pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.p11.CloseSession(session) }()
+
+	for _, class := range []uint{pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY} {
+		objects, err := c.findObjects(session, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+		})
+		if err != nil {
+			return err
+		}
+		for _, object := range objects {
+			if err := c.p11.SetAttributeValue(session, object, []*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// FindCertificates returns the certificates with the CKA_ID id.
+func (c *pkcs11Context) FindCertificates(id []byte) ([]*x509.Certificate, error) {
+	session, err := c.openSession(pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = c.p11.CloseSession(session) }()
+
+	objects, err := c.findObjects(session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_CERTIFICATE),
+		pkcs11.NewAttribute(pkcs11.CKA_CERTIFICATE_TYPE, pkcs11.CKC_X_509),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, id),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(objects))
+	for _, object := range objects {
+		attributes, err := c.p11.GetAttributeValue(session, object, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		certificate, err := x509.ParseCertificate(attributes[0].Value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, nil
+}
+
+func (c *pkcs11Context) openSession(flags uint) (pkcs11.SessionHandle, error) {
+	if c.p11 == nil {
+		return 0, errors.WithStack(errClosed)
+	}
+	session, err := c.p11.OpenSession(c.slot, flags)
+	return session, errors.WithStack(err)
+}
+
+func (c *pkcs11Context) findObjects(session pkcs11.SessionHandle, template []*pkcs11.Attribute) ([]pkcs11.ObjectHandle, error) {
+	if err := c.p11.FindObjectsInit(session, template); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() { _ = c.p11.FindObjectsFinal(session) }()
+
+	var objects []pkcs11.ObjectHandle
+	for {
+		found, _, err := c.p11.FindObjects(session, 16)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		} else if len(found) == 0 {
+			return objects, nil
+		}
+		objects = append(objects, found...)
+	}
+}
+
+// Ping reads the token info, which is a round trip to the token. It fails if the token is unreachable, for example
+// because the PKCS#11 daemon restarted or the network Hardware Security Module is down.
+func (c *pkcs11Context) Ping() error {
+	if c.p11 == nil {
+		return errors.WithStack(errClosed)
+	}
+	_, err := c.p11.GetTokenInfo(c.slot)
+	return errors.WithStack(err)
+}
+
+// Reconnect closes the context and configures it anew, which logs into the token again. Key pairs found before are
+// bound to the closed context and must be found again.
+func (c *pkcs11Context) Reconnect() error {
+	_ = c.Close()
+
+	ctx11, err := crypto11.Configure(c.config11)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	reconnected, err := newPKCS11Context(ctx11, c.config11)
+	if err != nil {
+		_ = ctx11.Close()
+		return err
+	}
+
+	*c = *reconnected
+	return nil
+}
+
+// Close closes the crypto11 context, which finalizes the PKCS#11 library, before unloading it. Closing a closed
+// context has no effect.
+func (c *pkcs11Context) Close() error {
+	if c.p11 == nil {
+		return nil
+	}
+	err := c.Context.Close()
+	c.p11.Destroy()
+	c.p11 = nil
+	return errors.WithStack(err)
+}
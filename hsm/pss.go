@@ -0,0 +1,91 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/cryptosigner"
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+// pssAlgorithms maps the RSASSA-PSS algorithms to their hash and to the PKCS#11 mechanism which marks a key pair as
+// generated for the algorithm.
+var pssAlgorithms = map[string]struct {
+	hash      crypto.Hash
+	mechanism uint
+}{
+	string(jose.PS256): {hash: crypto.SHA256, mechanism: pkcs11.CKM_SHA256_RSA_PKCS_PSS},
+	string(jose.PS384): {hash: crypto.SHA384, mechanism: pkcs11.CKM_SHA384_RSA_PKCS_PSS},
+	string(jose.PS512): {hash: crypto.SHA512, mechanism: pkcs11.CKM_SHA512_RSA_PKCS_PSS},
+}
+
+// pssAllowedMechanisms returns the CKA_ALLOWED_MECHANISMS attribute of RSA key pairs generated for the RSASSA-PSS
+// algorithm. It restricts the key pair to CKM_RSA_PKCS_PSS, which is used to sign, and to the hash specific mechanism,
+// which identifies the algorithm when the key pair is read.
+func pssAllowedMechanisms(alg string) *pkcs11.Attribute {
+	var value []byte
+	for _, mechanism := range []uint{pkcs11.CKM_RSA_PKCS_PSS, pssAlgorithms[alg].mechanism} {
+		// The attribute is an array of CK_ULONG, whose size depends on the platform.
+		value = append(value, pkcs11.NewAttribute(pkcs11.CKA_ALLOWED_MECHANISMS, mechanism).Value...)
+	}
+	return pkcs11.NewAttribute(pkcs11.CKA_ALLOWED_MECHANISMS, value)
+}
+
+// rsaAlgorithm returns the algorithm of an RSA key pair with the allowed mechanisms. Key pairs which are not
+// restricted to a RSASSA-PSS algorithm are used with RS256.
+func rsaAlgorithm(allowedMechanisms *pkcs11.Attribute) string {
+	if allowedMechanisms == nil {
+		return string(jose.RS256)
+	}
+	size := len(pkcs11.NewAttribute(pkcs11.CKA_ALLOWED_MECHANISMS, uint(0)).Value)
+	for i := 0; i+size <= len(allowedMechanisms.Value); i += size {
+		for alg, pss := range pssAlgorithms {
+			if bytes.Equal(allowedMechanisms.Value[i:i+size], pkcs11.NewAttribute(pkcs11.CKA_ALLOWED_MECHANISMS, pss.mechanism).Value) {
+				return alg
+			}
+		}
+	}
+	return string(jose.RS256)
+}
+
+// pssSigner signs with RSASSA-PSS using a salt as long as the hash, as required by RFC 7518. The signer of go-jose
+// asks for the maximum salt length instead, which PKCS#11 does not support.
+type pssSigner struct {
+	jose.OpaqueSigner
+	signer crypto.Signer
+	alg    jose.SignatureAlgorithm
+}
+
+func newPSSSigner(signer crypto.Signer, alg string) *pssSigner {
+	return &pssSigner{OpaqueSigner: cryptosigner.Opaque(signer), signer: signer, alg: jose.SignatureAlgorithm(alg)}
+}
+
+// Algs returns only the algorithm the key pair was generated for, so that it is used even if the algorithm of the
+// JSON Web Key is not known.
+func (s *pssSigner) Algs() []jose.SignatureAlgorithm {
+	return []jose.SignatureAlgorithm{s.alg}
+}
+
+func (s *pssSigner) SignPayload(payload []byte, alg jose.SignatureAlgorithm) ([]byte, error) {
+	pss, ok := pssAlgorithms[string(alg)]
+	if !ok || alg != s.alg {
+		return nil, errors.WithStack(jose.ErrUnsupportedAlgorithm)
+	}
+
+	hasher := pss.hash.New()
+	_, _ = hasher.Write(payload)
+	return s.signer.Sign(rand.Reader, hasher.Sum(nil), &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+		Hash:       pss.hash,
+	})
+}
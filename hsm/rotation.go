@@ -0,0 +1,144 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/otelx"
+)
+
+// rotatedKeyPair is a key pair of a key set together with the time it was generated.
+type rotatedKeyPair struct {
+	crypto11.Signer
+	kid, alg, use string
+	createdAt     time.Time
+}
+
+// RotateKeySet generates a new active key in the key set. The previous keys stay published until the grace period
+// has passed since they were superseded, and are deleted by the first rotation afterwards.
+//
+// Key pairs on the HSM cannot be modified once generated, so the time a key was generated is recorded in its key ID,
+// which is a time-based (version 1) UUID. Keys with other key IDs are considered to be older than every rotated key.
+func (m *KeyManager) RotateKeySet(ctx context.Context, set, alg, use string) (*jose.JSONWebKeySet, error) {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "hsm.RotateKeySet")
+	defer span.End()
+	attrs := map[string]string{
+		"set": set,
+		"alg": alg,
+		"use": use,
+	}
+	span.SetAttributes(otelx.StringAttrs(attrs)...)
+
+	m.Lock()
+	defer m.Unlock()
+
+	set = m.prefixKeySet(set)
+	m.cache.delete(set)
+
+	hsmContext := m.keySetContext(set)
+	keyPairs, err := hsmContext.FindKeyPairs(nil, []byte(set))
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := m.rotatedKeyPairs(ctx, hsmContext, keyPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(alg) == 0 {
+		if len(previous) == 0 {
+			return nil, errors.WithStack(x.ErrNotFound)
+		}
+		alg = previous[0].alg
+		if len(use) == 0 {
+			use = previous[0].use
+		}
+	}
+	if len(use) == 0 {
+		use = "sig"
+	}
+
+	id := uuid.NewUUID()
+	if id == nil {
+		return nil, errors.New("unable to generate a time-based key ID")
+	}
+	kid := id.String()
+
+	key, err := m.generateKeyPair(set, kid, alg, use, 4096)
+	if err != nil {
+		return nil, err
+	}
+
+	active := rotatedKeyPair{Signer: key, kid: kid, alg: alg, use: use, createdAt: keyCreatedAt(kid)}
+	retained, expired := expireKeyPairs(append([]rotatedKeyPair{active}, previous...), time.Now(), m.c.HSMRotationGracePeriod(ctx))
+	for _, keyPair := range expired {
+		if err := keyPair.Delete(); err != nil {
+			return nil, err
+		}
+	}
+
+	var keys []jose.JSONWebKey
+	for _, keyPair := range retained {
+		keys = append(keys, createKeys(keyPair.Signer, keyPair.kid, keyPair.alg, keyPair.use)...)
+	}
+	if err := m.addCertificates(ctx, hsmContext, keys); err != nil {
+		return nil, err
+	}
+	return &jose.JSONWebKeySet{Keys: keys}, nil
+}
+
+// rotatedKeyPairs returns the key pairs ordered from the newest to the oldest key.
+func (m *KeyManager) rotatedKeyPairs(ctx context.Context, hsmContext Context, keyPairs []crypto11.Signer) ([]rotatedKeyPair, error) {
+	rotated := make([]rotatedKeyPair, 0, len(keyPairs))
+	for _, keyPair := range keyPairs {
+		kid, alg, use, err := m.getKeySetAttributes(ctx, hsmContext, keyPair, nil)
+		if err != nil {
+			return nil, err
+		}
+		rotated = append(rotated, rotatedKeyPair{Signer: keyPair, kid: kid, alg: alg, use: use, createdAt: keyCreatedAt(kid)})
+	}
+
+	sort.SliceStable(rotated, func(i, j int) bool {
+		return rotated[i].createdAt.After(rotated[j].createdAt)
+	})
+	return rotated, nil
+}
+
+// expireKeyPairs splits key pairs ordered from the newest to the oldest key into the ones which are retained and the
+// ones whose grace period has expired. A key is superseded when the next newer key was generated. The newest key is
+// always retained, and so are keys superseded by keys of unknown age.
+func expireKeyPairs(keyPairs []rotatedKeyPair, now time.Time, gracePeriod time.Duration) (retained, expired []rotatedKeyPair) {
+	for i, keyPair := range keyPairs {
+		if i > 0 && !keyPairs[i-1].createdAt.IsZero() && keyPairs[i-1].createdAt.Add(gracePeriod).Before(now) {
+			expired = append(expired, keyPair)
+			continue
+		}
+		retained = append(retained, keyPair)
+	}
+	return retained, expired
+}
+
+// keyCreatedAt returns the time encoded in a time-based key ID, or the zero time for other key IDs.
+func keyCreatedAt(kid string) time.Time {
+	id := uuid.Parse(kid)
+	if version, ok := id.Version(); !ok || version != 1 {
+		return time.Time{}
+	}
+	t, _ := id.Time()
+	return time.Unix(t.UnixTime())
+}
@@ -0,0 +1,121 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build hsm
+// +build hsm
+
+package hsm_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/golang/mock/gomock"
+	"github.com/miekg/pkcs11"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/hsm"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/logrusx"
+)
+
+// timeBasedKeyID returns a version 1 UUID which was generated at t.
+func timeBasedKeyID(t time.Time) string {
+	ts := uint64(t.UnixNano()/100) + 0x01B21DD213814000
+	id := make(uuid.UUID, 16)
+	binary.BigEndian.PutUint32(id[0:4], uint32(ts))
+	binary.BigEndian.PutUint16(id[4:6], uint16(ts>>32))
+	binary.BigEndian.PutUint16(id[6:8], uint16(ts>>48)&0x0fff|0x1000)
+	id[8] = 0x80
+	return id.String()
+}
+
+// sameKeyPair matches the key pair itself rather than every mock key pair, which are all deeply equal.
+type sameKeyPair struct{ keyPair *MockSignerDecrypter }
+
+func (m sameKeyPair) Matches(x interface{}) bool { return x == m.keyPair }
+
+func (m sameKeyPair) String() string { return "is the same key pair" }
+
+func TestKeyManager_RotateKeySet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	hsmContext := NewMockContext(ctrl)
+	defer ctrl.Finish()
+	l := logrusx.New("", "")
+	c := config.MustNew(context.Background(), l, configx.SkipValidation())
+	c.MustSet(context.Background(), config.HSMRotationGracePeriod, "24h")
+	m := hsm.NewKeyManager(hsmContext, c)
+
+	newKeyPair := func(kid string) *MockSignerDecrypter {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		keyPair := NewMockSignerDecrypter(ctrl)
+		keyPair.EXPECT().Public().Return(&key.PublicKey).AnyTimes()
+		hsmContext.EXPECT().GetAttribute(sameKeyPair{keyPair}, gomock.Eq(crypto11.CkaId)).Return(pkcs11.NewAttribute(pkcs11.CKA_ID, []byte(kid)), nil).AnyTimes()
+		hsmContext.EXPECT().GetAttribute(sameKeyPair{keyPair}, gomock.Eq(crypto11.CkaDecrypt)).Return(nil, nil).AnyTimes()
+		return keyPair
+	}
+
+	legacyKid := uuid.New()
+	legacyKeyPair := newKeyPair(legacyKid)
+	oldKid := timeBasedKeyID(time.Now().Add(-72 * time.Hour))
+	oldKeyPair := newKeyPair(oldKid)
+	activeKid := timeBasedKeyID(time.Now().Add(-48 * time.Hour))
+	activeKeyPair := newKeyPair(activeKid)
+	rotatedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rotatedKeyPair := NewMockSignerDecrypter(ctrl)
+	rotatedKeyPair.EXPECT().Public().Return(&rotatedKey.PublicKey).AnyTimes()
+
+	t.Run("case=keys of unknown age are retained", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{legacyKeyPair}, nil)
+		hsmContext.EXPECT().GenerateECDSAKeyPairWithAttributes(gomock.Any(), gomock.Any(), gomock.Eq(elliptic.P256())).Return(rotatedKeyPair, nil)
+
+		got, err := m.RotateKeySet(context.TODO(), x.OpenIDConnectKeyName, "", "")
+		require.NoError(t, err)
+		require.Len(t, got.Keys, 2)
+		assert.Equal(t, "ES256", got.Keys[0].Algorithm)
+		assert.Equal(t, "sig", got.Keys[0].Use)
+		version, _ := uuid.Parse(got.Keys[0].KeyID).Version()
+		assert.EqualValues(t, 1, version)
+		assert.Equal(t, legacyKid, got.Keys[1].KeyID)
+	})
+
+	t.Run("case=keys whose grace period expired are deleted", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{oldKeyPair, activeKeyPair}, nil)
+		hsmContext.EXPECT().GenerateECDSAKeyPairWithAttributes(gomock.Any(), gomock.Any(), gomock.Eq(elliptic.P256())).Return(rotatedKeyPair, nil)
+		oldKeyPair.EXPECT().Delete().Return(nil)
+
+		got, err := m.RotateKeySet(context.TODO(), x.OpenIDConnectKeyName, "", "")
+		require.NoError(t, err)
+		require.Len(t, got.Keys, 2)
+		assert.NotEqual(t, activeKid, got.Keys[0].KeyID)
+		assert.Equal(t, activeKid, got.Keys[1].KeyID)
+	})
+
+	t.Run("case=key sets without keys require an algorithm", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return(nil, nil)
+
+		_, err := m.RotateKeySet(context.TODO(), x.OpenIDConnectKeyName, "", "")
+		require.ErrorIs(t, err, x.ErrNotFound)
+	})
+
+	t.Run("case=GetKeySet publishes the active key first and hides expired keys", func(t *testing.T) {
+		hsmContext.EXPECT().FindKeyPairs(gomock.Nil(), gomock.Eq([]byte(x.OpenIDConnectKeyName))).Return([]crypto11.Signer{oldKeyPair, activeKeyPair}, nil)
+
+		got, err := m.GetKeySet(context.TODO(), x.OpenIDConnectKeyName)
+		require.NoError(t, err)
+		require.Len(t, got.Keys, 1)
+		assert.Equal(t, activeKid, got.Keys[0].KeyID)
+	})
+}
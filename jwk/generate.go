@@ -5,7 +5,9 @@ package jwk
 
 import (
 	"context"
+	"crypto"
 	"crypto/x509"
+	"strings"
 
 	"github.com/gofrs/uuid"
 
@@ -26,6 +28,127 @@ func GenerateJWK(ctx context.Context, alg jose.SignatureAlgorithm, kid, use stri
 		return nil, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "%s", err)
 	}
 
+	return newKeySet(string(alg), kid, use, priv), nil
+}
+
+// KeyParameters are explicit parameters of a generated key, which are otherwise inferred from its algorithm.
+type KeyParameters struct {
+	// KeyType is the "kty" of the key, one of RSA, EC and OKP.
+	KeyType string
+	// Bits is the modulus size of RSA keys.
+	Bits int
+	// Curve is the "crv" of EC and OKP keys.
+	Curve string
+}
+
+// ResolveKeyParameters returns the parameters of a key of the algorithm. Parameters which are not given are inferred
+// from the algorithm, and given parameters must be supported by it.
+func ResolveKeyParameters(alg string, params KeyParameters) (KeyParameters, error) {
+	var kty string
+	switch jose.KeyAlgorithm(alg) {
+	case jose.RSA1_5, jose.RSA_OAEP, jose.RSA_OAEP_256:
+		kty = "RSA"
+	case jose.ECDH_ES, jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW:
+		kty = "EC"
+	}
+	switch jose.SignatureAlgorithm(alg) {
+	case jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512:
+		kty = "RSA"
+	case jose.ES256, jose.ES384, jose.ES512:
+		kty = "EC"
+	case jose.EdDSA:
+		kty = "OKP"
+	}
+	if kty == "" {
+		return params, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "key parameters are not supported for algorithm %s", alg)
+	}
+	if params.KeyType != "" && params.KeyType != kty {
+		return params, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "algorithm %s requires key type %s", alg, kty)
+	}
+	params.KeyType = kty
+
+	switch kty {
+	case "RSA":
+		if params.Curve != "" {
+			return params, errors.Wrapf(ErrUnsupportedEllipticCurve, "RSA keys have no curve")
+		}
+		if params.Bits == 0 {
+			params.Bits = 4096
+		}
+		if params.Bits < 2048 {
+			return params, errors.Wrapf(ErrMinimalRsaKeyLength, "RSA keys must have at least 2048 bits")
+		}
+	case "EC":
+		if params.Bits != 0 {
+			return params, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "the size of EC keys is defined by their curve")
+		}
+		curve, ok := signatureCurves[jose.SignatureAlgorithm(alg)]
+		if !ok {
+			// Key agreement works with every curve.
+			curve = params.Curve
+			if curve == "" {
+				curve = "P-256"
+			}
+		}
+		if _, supported := curveBits[curve]; !supported || params.Curve != "" && params.Curve != curve {
+			return params, errors.Wrapf(ErrUnsupportedEllipticCurve, "algorithm %s does not support curve %s", alg, params.Curve)
+		}
+		params.Curve = curve
+	case "OKP":
+		if params.Bits != 0 {
+			return params, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "the size of OKP keys is defined by their curve")
+		}
+		if params.Curve != "" && params.Curve != "Ed25519" {
+			return params, errors.Wrapf(ErrUnsupportedEllipticCurve, "algorithm %s does not support curve %s", alg, params.Curve)
+		}
+		params.Curve = "Ed25519"
+	}
+	return params, nil
+}
+
+// signatureCurves are the curves of the ECDSA algorithms.
+var signatureCurves = map[jose.SignatureAlgorithm]string{
+	jose.ES256: "P-256",
+	jose.ES384: "P-384",
+	jose.ES512: "P-521",
+}
+
+// curveBits are the key sizes of the supported elliptic curves.
+var curveBits = map[string]int{
+	"P-256": 256,
+	"P-384": 384,
+	"P-521": 521,
+}
+
+// GenerateJWKWithParameters generates a signing or key agreement key of the algorithm with explicit parameters.
+func GenerateJWKWithParameters(ctx context.Context, alg, kid, use string, params KeyParameters) (*jose.JSONWebKeySet, error) {
+	params, err := ResolveKeyParameters(alg, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var priv crypto.PrivateKey
+	switch {
+	case params.KeyType == "RSA" && strings.HasPrefix(alg, "RSA"):
+		_, priv, err = josex.NewEncryptionKey(jose.KeyAlgorithm(alg), params.Bits)
+	case params.KeyType == "RSA":
+		_, priv, err = josex.NewSigningKey(jose.SignatureAlgorithm(alg), params.Bits)
+	case params.KeyType == "EC" && strings.HasPrefix(alg, "ECDH-ES"):
+		_, priv, err = josex.NewEncryptionKey(jose.KeyAlgorithm(alg), curveBits[params.Curve])
+	default:
+		_, priv, err = josex.NewSigningKey(jose.SignatureAlgorithm(alg), 0)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "%s", err)
+	}
+
+	if len(use) == 0 && (strings.HasPrefix(alg, "RSA") || strings.HasPrefix(alg, "ECDH-ES")) {
+		use = "enc"
+	}
+	return newKeySet(alg, kid, use, priv), nil
+}
+
+func newKeySet(alg, kid, use string, priv crypto.PrivateKey) *jose.JSONWebKeySet {
 	if len(kid) == 0 {
 		kid = uuid.Must(uuid.NewV4()).String()
 	}
@@ -37,7 +160,7 @@ func GenerateJWK(ctx context.Context, alg jose.SignatureAlgorithm, kid, use stri
 	return &jose.JSONWebKeySet{
 		Keys: []jose.JSONWebKey{
 			{
-				Algorithm:                   string(alg),
+				Algorithm:                   alg,
 				Key:                         priv,
 				Use:                         use,
 				KeyID:                       kid,
@@ -46,5 +169,5 @@ func GenerateJWK(ctx context.Context, alg jose.SignatureAlgorithm, kid, use stri
 				CertificateThumbprintSHA1:   []byte{},
 			},
 		},
-	}, nil
+	}
 }
@@ -5,6 +5,11 @@ package jwk
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
 	"testing"
 
 	"github.com/go-jose/go-jose/v3"
@@ -20,3 +25,71 @@ func TestGenerateJWK(t *testing.T) {
 	assert.EqualValues(t, jose.RS256, jwks.Keys[0].Algorithm)
 	assert.EqualValues(t, "sig", jwks.Keys[0].Use)
 }
+
+func TestGenerateJWKWithParameters(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		alg, use string
+		params   KeyParameters
+		check    func(t *testing.T, key jose.JSONWebKey)
+		err      error
+	}{
+		{
+			alg:    string(jose.RS256),
+			params: KeyParameters{Bits: 3072},
+			check: func(t *testing.T, key jose.JSONWebKey) {
+				assert.Equal(t, 3072, key.Key.(*rsa.PrivateKey).N.BitLen())
+				assert.Equal(t, "sig", key.Use)
+			},
+		},
+		{
+			alg:    string(jose.RSA_OAEP_256),
+			params: KeyParameters{KeyType: "RSA", Bits: 2048},
+			check: func(t *testing.T, key jose.JSONWebKey) {
+				assert.Equal(t, 2048, key.Key.(*rsa.PrivateKey).N.BitLen())
+				assert.Equal(t, "enc", key.Use)
+			},
+		},
+		{
+			alg:    string(jose.ECDH_ES),
+			params: KeyParameters{Curve: "P-384"},
+			check: func(t *testing.T, key jose.JSONWebKey) {
+				assert.Equal(t, elliptic.P384(), key.Key.(*ecdsa.PrivateKey).Curve)
+			},
+		},
+		{
+			alg:    string(jose.ES512),
+			params: KeyParameters{KeyType: "EC", Curve: "P-521"},
+			check: func(t *testing.T, key jose.JSONWebKey) {
+				assert.Equal(t, elliptic.P521(), key.Key.(*ecdsa.PrivateKey).Curve)
+			},
+		},
+		{
+			alg:    string(jose.EdDSA),
+			params: KeyParameters{KeyType: "OKP", Curve: "Ed25519"},
+			check: func(t *testing.T, key jose.JSONWebKey) {
+				assert.IsType(t, ed25519.PrivateKey{}, key.Key)
+			},
+		},
+		{alg: string(jose.RS256), params: KeyParameters{Bits: 1024}, err: ErrMinimalRsaKeyLength},
+		{alg: string(jose.RS256), params: KeyParameters{KeyType: "EC"}, err: ErrUnsupportedKeyAlgorithm},
+		{alg: string(jose.ES256), params: KeyParameters{Curve: "P-384"}, err: ErrUnsupportedEllipticCurve},
+		{alg: string(jose.ECDH_ES), params: KeyParameters{Curve: "secp256k1"}, err: ErrUnsupportedEllipticCurve},
+		{alg: string(jose.EdDSA), params: KeyParameters{Bits: 256}, err: ErrUnsupportedKeyAlgorithm},
+		{alg: string(jose.HS256), params: KeyParameters{Bits: 256}, err: ErrUnsupportedKeyAlgorithm},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("alg=%s/params=%+v", tc.alg, tc.params), func(t *testing.T) {
+			t.Parallel()
+			jwks, err := GenerateJWKWithParameters(context.Background(), tc.alg, "", tc.use, tc.params)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.EqualValues(t, tc.alg, jwks.Keys[0].Algorithm)
+			tc.check(t, jwks.Keys[0])
+		})
+	}
+}
@@ -4,12 +4,17 @@
 package jwk
 
 import (
+	"context"
+	"crypto"
+	"encoding/base64"
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/audit"
 	"github.com/ory/x/httprouterx"
 
 	"github.com/gofrs/uuid"
@@ -29,6 +34,7 @@ import (
 
 const (
 	KeyHandlerPath    = "/keys"
+	rotateKeySetPath  = "rotate"
 	WellKnownKeysPath = "/.well-known/jwks.json"
 )
 
@@ -149,6 +155,7 @@ type getJsonWebKey struct {
 // # Get JSON Web Key
 //
 // This endpoint returns a singular JSON Web Key contained in a set. It is identified by the set and the specific key ID (kid).
+// The response contains an ETag header, which can be passed in the If-Match header when updating or deleting the key.
 //
 //	Consumes:
 //	- application/json
@@ -172,6 +179,13 @@ func (h *Handler) getJsonWebKey(w http.ResponseWriter, r *http.Request, ps httpr
 	}
 	keys = ExcludeOpaquePrivateKeys(keys)
 
+	etag, err := x.ETag(keys)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+
 	h.r.Writer().Write(w, r, keys)
 }
 
@@ -192,7 +206,8 @@ type getJsonWebKeySet struct {
 //
 // # Retrieve a JSON Web Key Set
 //
-// This endpoint can be used to retrieve JWK Sets stored in ORY Hydra.
+// This endpoint can be used to retrieve JWK Sets stored in ORY Hydra. The response contains an ETag header, which can
+// be passed in the If-Match header when updating or deleting the set.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -217,6 +232,13 @@ func (h *Handler) getJsonWebKeySet(w http.ResponseWriter, r *http.Request, ps ht
 	}
 	keys = ExcludeOpaquePrivateKeys(keys)
 
+	etag, err := x.ETag(keys)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+
 	h.r.Writer().Write(w, r, keys)
 }
 
@@ -263,6 +285,23 @@ type createJsonWebKeySetBody struct {
 	//
 	// required: true
 	KeyID string `json:"kid"`
+
+	// JSON Web Key Type
+	//
+	// The key type of the key to be created, one of `RSA`, `EC` and `OKP`. Must match the algorithm if set.
+	KeyType string `json:"kty,omitempty"`
+
+	// RSA Key Size
+	//
+	// The modulus size of the RSA key to be created in bits. Defaults to 4096.
+	Bits int `json:"bits,omitempty"`
+
+	// JSON Web Key Curve
+	//
+	// The curve of the EC or OKP key to be created, one of `P-256`, `P-384`, `P-521` and `Ed25519`. The curve of
+	// ECDSA and EdDSA keys is defined by their algorithm, while ECDH-ES keys support every curve. Defaults to `P-256`
+	// for ECDH-ES keys.
+	Curve string `json:"crv,omitempty"`
 }
 
 // swagger:route POST /admin/keys/{set} jwk createJsonWebKeySet
@@ -271,6 +310,9 @@ type createJsonWebKeySetBody struct {
 //
 // This endpoint is capable of generating JSON Web Key Sets for you. There a different strategies available, such as symmetric cryptographic keys (HS256, HS512) and asymetric cryptographic keys (RS256, ECDSA). If the specified JSON Web Key Set does not exist, it will be created.
 //
+// The key type, RSA key size and curve can be set explicitly instead of being inferred from the algorithm. They must
+// be allowed by `jwks.generation`. Explicit parameters also allow generating RSA-OAEP and ECDH-ES encryption keys.
+//
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
 //	Consumes:
@@ -293,7 +335,31 @@ func (h *Handler) createJsonWebKeySet(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
-	if keys, err := h.r.KeyManager().GenerateAndPersistKeySet(r.Context(), set, keyRequest.KeyID, keyRequest.Algorithm, keyRequest.Use); err == nil {
+	params := KeyParameters{KeyType: keyRequest.KeyType, Bits: keyRequest.Bits, Curve: keyRequest.Curve}
+	if resolved, err := ResolveKeyParameters(keyRequest.Algorithm, params); err == nil {
+		if err := h.checkKeyParameters(r.Context(), resolved); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	} else if params != (KeyParameters{}) {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	generate := h.r.KeyManager().GenerateAndPersistKeySet
+	if params != (KeyParameters{}) {
+		g, ok := h.r.KeyManager().(ParameterizedKeyGenerator)
+		if !ok {
+			h.r.Writer().WriteError(w, r, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "the key manager does not support key parameters"))
+			return
+		}
+		generate = func(ctx context.Context, set, kid, alg, use string) (*jose.JSONWebKeySet, error) {
+			return g.GenerateAndPersistKeySetWithParameters(ctx, set, kid, alg, use, params)
+		}
+	}
+
+	if keys, err := generate(r.Context(), set, keyRequest.KeyID, keyRequest.Algorithm, keyRequest.Use); err == nil {
+		h.r.AuditRecorder().Record(r, audit.ActionCreate, audit.ResourceJSONWebKeySet, set, nil, auditKeys(keys.Keys))
 		keys = ExcludeOpaquePrivateKeys(keys)
 		h.r.Writer().WriteCreated(w, r, urlx.AppendPaths(h.r.Config().IssuerURL(r.Context()), "/keys/"+set).String(), keys)
 	} else {
@@ -301,6 +367,21 @@ func (h *Handler) createJsonWebKeySet(w http.ResponseWriter, r *http.Request, ps
 	}
 }
 
+// checkKeyParameters checks the parameters of a key to be generated against `jwks.generation`.
+func (h *Handler) checkKeyParameters(ctx context.Context, params KeyParameters) error {
+	switch params.KeyType {
+	case "RSA":
+		if min, max := h.r.Config().JWKSGenerationRSABits(ctx); params.Bits < min || params.Bits > max {
+			return errors.WithStack(ErrMinimalRsaKeyLength.WithHintf("RSA keys must have between %d and %d bits, but %d bits were requested.", min, max, params.Bits))
+		}
+	case "EC", "OKP":
+		if !stringslice.Has(h.r.Config().JWKSGenerationCurves(ctx), params.Curve) {
+			return errors.WithStack(ErrUnsupportedEllipticCurve.WithHintf("Curve %s is not allowed.", params.Curve))
+		}
+	}
+	return nil
+}
+
 // Set JSON Web Key Set Request
 //
 // swagger:parameters setJsonWebKeySet
@@ -321,7 +402,8 @@ type setJsonWebKeySet struct {
 //
 // # Update a JSON Web Key Set
 //
-// Use this method if you do not want to let Hydra generate the JWKs for you, but instead save your own.
+// Use this method if you do not want to let Hydra generate the JWKs for you, but instead save your own. If you pass
+// the ETag of the set in the If-Match header, the set is only updated if it was not modified since.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -345,11 +427,18 @@ func (h *Handler) setJsonWebKeySet(w http.ResponseWriter, r *http.Request, ps ht
 		return
 	}
 
+	if err := h.checkIfMatch(r, set, ""); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	before := h.auditState(r.Context(), set, "")
 	if err := h.r.KeyManager().UpdateKeySet(r.Context(), set, &keySet); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionUpdate, audit.ResourceJSONWebKeySet, set, before, auditKeys(keySet.Keys))
 	h.r.Writer().Write(w, r, &keySet)
 }
 
@@ -379,7 +468,8 @@ type setJsonWebKey struct {
 //
 // # Set JSON Web Key
 //
-// Use this method if you do not want to let Hydra generate the JWKs for you, but instead save your own.
+// Use this method if you do not want to let Hydra generate the JWKs for you, but instead save your own. If you pass
+// the ETag of the key in the If-Match header, the key is only updated if it was not modified since.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -395,6 +485,12 @@ type setJsonWebKey struct {
 //	  200: jsonWebKey
 //	  default: errorOAuth2
 func (h *Handler) adminUpdateJsonWebKey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// The router does not allow registering PUT /keys/:set/rotate next to PUT /keys/:set/:key.
+	if ps.ByName("key") == rotateKeySetPath {
+		h.rotateJsonWebKeySet(w, r, ps)
+		return
+	}
+
 	var key jose.JSONWebKey
 	var set = ps.ByName("set")
 
@@ -403,14 +499,98 @@ func (h *Handler) adminUpdateJsonWebKey(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if err := h.checkIfMatch(r, set, key.KeyID); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	before := h.auditState(r.Context(), set, key.KeyID)
 	if err := h.r.KeyManager().UpdateKey(r.Context(), set, &key); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionUpdate, audit.ResourceJSONWebKey, set+"/"+key.KeyID, before, auditKeys([]jose.JSONWebKey{key}))
 	h.r.Writer().Write(w, r, key)
 }
 
+// Rotate JSON Web Key Set Request
+//
+// swagger:parameters rotateJsonWebKeySet
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type rotateJsonWebKeySet struct {
+	// The JSON Web Key Set ID
+	//
+	// in: path
+	// required: true
+	Set string `json:"set"`
+
+	// in: body
+	Body rotateJsonWebKeySetBody
+}
+
+// Rotate JSON Web Key Set Request Body
+//
+// swagger:model rotateJsonWebKeySet
+type rotateJsonWebKeySetBody struct {
+	// JSON Web Key Algorithm
+	//
+	// The algorithm of the new key. Defaults to the algorithm of the active key of the set.
+	Algorithm string `json:"alg"`
+
+	// JSON Web Key Use
+	//
+	// The "use" (public key use) parameter of the new key. Defaults to the use of the active key of the set.
+	Use string `json:"use"`
+}
+
+// swagger:route PUT /admin/keys/{set}/rotate jwk rotateJsonWebKeySet
+//
+// # Rotate JSON Web Key Set
+//
+// Generates a new active key in a JSON Web Key Set stored on the Hardware Security Module. Unlike recreating the set,
+// the previous keys stay published for `hsm.rotation.grace_period`, so that tokens signed with them can still be
+// verified. Keys whose grace period has expired are deleted. Because of this endpoint, keys with the ID `rotate`
+// cannot be updated.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: jsonWebKeySet
+//	  default: errorOAuth2
+func (h *Handler) rotateJsonWebKeySet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var body rotateJsonWebKeySetBody
+	var set = ps.ByName("set")
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	rotator, ok := h.r.KeyManager().(KeyRotator)
+	if !ok {
+		h.r.Writer().WriteError(w, r, errors.WithStack(ErrKeyRotationUnsupported))
+		return
+	}
+
+	before := h.auditState(r.Context(), set, "")
+	keys, err := rotator.RotateKeySet(r.Context(), set, body.Algorithm, body.Use)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.AuditRecorder().Record(r, audit.ActionRotate, audit.ResourceJSONWebKeySet, set, before, auditKeys(keys.Keys))
+	h.r.Writer().Write(w, r, ExcludeOpaquePrivateKeys(keys))
+}
+
 // Delete JSON Web Key Set Parameters
 //
 // swagger:parameters deleteJsonWebKeySet
@@ -421,13 +601,19 @@ type deleteJsonWebKeySet struct {
 	// in: path
 	// required: true
 	Set string `json:"set"`
+
+	// If set to `true`, the set is not deleted. Instead, the response lists how many keys would be deleted.
+	//
+	// in: query
+	DryRun bool `json:"dry_run"`
 }
 
 // swagger:route DELETE /admin/keys/{set} jwk deleteJsonWebKeySet
 //
 // # Delete JSON Web Key Set
 //
-// Use this endpoint to delete a complete JSON Web Key Set and all the keys in that set.
+// Use this endpoint to delete a complete JSON Web Key Set and all the keys in that set. If you pass the ETag of the
+// set in the If-Match header, the set is only deleted if it was not modified since.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -440,16 +626,35 @@ type deleteJsonWebKeySet struct {
 //	Schemes: http, https
 //
 //	Responses:
+//	  200: dryRunResult
 //	  204: emptyResponse
 //	  default: errorOAuth2
 func (h *Handler) adminDeleteJsonWebKeySet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var setName = ps.ByName("set")
 
+	if err := h.checkIfMatch(r, setName, ""); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if x.IsDryRun(r) {
+		keys, err := h.r.KeyManager().GetKeySet(r.Context(), setName)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		h.r.Writer().Write(w, r, &x.DryRunResult{Affected: map[string]int{"json_web_keys": len(keys.Keys)}})
+		return
+	}
+
+	before := h.auditState(r.Context(), setName, "")
 	if err := h.r.KeyManager().DeleteKeySet(r.Context(), setName); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceJSONWebKeySet, setName, before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -475,7 +680,8 @@ type deleteJsonWebKey struct {
 //
 // # Delete JSON Web Key
 //
-// Use this endpoint to delete a single JSON Web Key.
+// Use this endpoint to delete a single JSON Web Key. If you pass the ETag of the key in the If-Match header, the key
+// is only deleted if it was not modified since.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A
 // JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses
@@ -497,14 +703,89 @@ func (h *Handler) deleteJsonWebKey(w http.ResponseWriter, r *http.Request, ps ht
 	var setName = ps.ByName("set")
 	var keyName = ps.ByName("key")
 
+	if err := h.checkIfMatch(r, setName, keyName); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	before := h.auditState(r.Context(), setName, keyName)
 	if err := h.r.KeyManager().DeleteKey(r.Context(), setName, keyName); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceJSONWebKey, setName+"/"+keyName, before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// auditKey is the representation of a JSON Web Key in the audit log. It never contains any key material.
+type auditKey struct {
+	KeyID      string `json:"kid"`
+	Algorithm  string `json:"alg"`
+	Use        string `json:"use"`
+	Thumbprint string `json:"thumbprint,omitempty"`
+}
+
+func auditKeys(keys []jose.JSONWebKey) []auditKey {
+	out := make([]auditKey, len(keys))
+	for i, k := range keys {
+		out[i] = auditKey{KeyID: k.KeyID, Algorithm: k.Algorithm, Use: k.Use}
+		if public := k.Public(); public.Valid() {
+			if thumbprint, err := public.Thumbprint(crypto.SHA256); err == nil {
+				out[i].Thumbprint = base64.RawURLEncoding.EncodeToString(thumbprint)
+			}
+		}
+	}
+	return out
+}
+
+// checkIfMatch returns an error if the request has an If-Match header which does not match the entity tag of the key
+// set, or of a single key if kid is set. The keys are only loaded if the request has an If-Match header.
+func (h *Handler) checkIfMatch(r *http.Request, set, kid string) error {
+	if !x.HasIfMatch(r) {
+		return nil
+	}
+
+	var keys *jose.JSONWebKeySet
+	var err error
+	if kid == "" {
+		keys, err = h.r.KeyManager().GetKeySet(r.Context(), set)
+	} else {
+		keys, err = h.r.KeyManager().GetKey(r.Context(), set, kid)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The entity tag is computed like in getJsonWebKeySet and getJsonWebKey.
+	etag, err := x.ETag(ExcludeOpaquePrivateKeys(keys))
+	if err != nil {
+		return err
+	}
+	return x.CheckIfMatch(r, etag)
+}
+
+// auditState returns the current state of the key set, or of a single key if kid is set, for the audit log. It
+// returns nil if the audit log is disabled.
+func (h *Handler) auditState(ctx context.Context, set, kid string) []auditKey {
+	if !h.r.Config().AuditEnabled(ctx) {
+		return nil
+	}
+
+	var keys *jose.JSONWebKeySet
+	var err error
+	if kid == "" {
+		keys, err = h.r.KeyManager().GetKeySet(ctx, set)
+	} else {
+		keys, err = h.r.KeyManager().GetKey(ctx, set, kid)
+	}
+	if err != nil {
+		return nil
+	}
+	return auditKeys(keys.Keys)
+}
+
 // This function will not be called, OPTIONS request will be handled by cors
 // this is just a placeholder.
 func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request) {}
@@ -4,8 +4,13 @@
 package jwk_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -111,3 +116,185 @@ func canonicalizeThumbprints(js jose.JSONWebKey) jose.JSONWebKey {
 	}
 	return js
 }
+
+func TestHandlerETag(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	router := x.NewRouterPublic()
+	reg.KeyHandler().SetRoutes(httprouterx.NewRouterAdminWithPrefixAndRouter(router.Router, "/admin", conf.AdminURL), router, func(h http.Handler) http.Handler {
+		return h
+	})
+	testServer := httptest.NewServer(router)
+	t.Cleanup(testServer.Close)
+
+	keys, err := reg.KeyManager().GenerateAndPersistKeySet(ctx, "etag-set", "etag-key", string(jose.RS256), "sig")
+	require.NoError(t, err)
+
+	do := func(t *testing.T, method, path, ifMatch string, body interface{}) *http.Response {
+		var b bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&b).Encode(body))
+		}
+		r, err := http.NewRequest(method, testServer.URL+"/admin/keys/"+path, &b)
+		require.NoError(t, err)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		res, err := testServer.Client().Do(r)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res
+	}
+	etag := func(t *testing.T, path string) string {
+		res := do(t, "GET", path, "", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NotEmpty(t, res.Header.Get("ETag"))
+		return res.Header.Get("ETag")
+	}
+
+	setETag, keyETag := etag(t, "etag-set"), etag(t, "etag-set/etag-key")
+	assert.Equal(t, setETag, etag(t, "etag-set"))
+
+	key := keys.Keys[0]
+	key.Use = "enc"
+	require.NoError(t, reg.KeyManager().UpdateKey(ctx, "etag-set", &key))
+
+	assert.Equal(t, http.StatusPreconditionFailed, do(t, "PUT", "etag-set/etag-key", keyETag, key).StatusCode)
+	assert.Equal(t, http.StatusPreconditionFailed, do(t, "PUT", "etag-set", setETag, keys).StatusCode)
+	assert.Equal(t, http.StatusPreconditionFailed, do(t, "DELETE", "etag-set/etag-key", keyETag, nil).StatusCode)
+	assert.Equal(t, http.StatusPreconditionFailed, do(t, "DELETE", "etag-set", setETag, nil).StatusCode)
+
+	assert.Equal(t, http.StatusOK, do(t, "PUT", "etag-set/etag-key", etag(t, "etag-set/etag-key"), key).StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(t, "DELETE", "etag-set", etag(t, "etag-set"), nil).StatusCode)
+}
+
+func TestHandlerDeleteKeySetDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	router := x.NewRouterPublic()
+	reg.KeyHandler().SetRoutes(httprouterx.NewRouterAdminWithPrefixAndRouter(router.Router, "/admin", conf.AdminURL), router, func(h http.Handler) http.Handler {
+		return h
+	})
+	testServer := httptest.NewServer(router)
+	t.Cleanup(testServer.Close)
+
+	_, err := reg.KeyManager().GenerateAndPersistKeySet(ctx, "dry-run-set", "dry-run-key", string(jose.ES256), "sig")
+	require.NoError(t, err)
+
+	do := func(t *testing.T, set string) (int, string) {
+		r, err := http.NewRequest("DELETE", testServer.URL+"/admin/keys/"+set+"?dry_run=true", nil)
+		require.NoError(t, err)
+		res, err := testServer.Client().Do(r)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(body)
+	}
+
+	code, body := do(t, "dry-run-set")
+	require.Equal(t, http.StatusOK, code, body)
+	assert.JSONEq(t, `{"affected":{"json_web_keys":1}}`, body)
+
+	keys, err := reg.KeyManager().GetKeySet(ctx, "dry-run-set")
+	require.NoError(t, err)
+	assert.Len(t, keys.Keys, 1, "the key set must not be deleted")
+
+	code, _ = do(t, "unknown-set")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestHandlerCreateKeySetWithParameters(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	router := x.NewRouterPublic()
+	reg.KeyHandler().SetRoutes(httprouterx.NewRouterAdminWithPrefixAndRouter(router.Router, "/admin", conf.AdminURL), router, func(h http.Handler) http.Handler {
+		return h
+	})
+	testServer := httptest.NewServer(router)
+	t.Cleanup(testServer.Close)
+
+	create := func(t *testing.T, set string, body map[string]interface{}) (int, *jose.JSONWebKeySet) {
+		var b bytes.Buffer
+		require.NoError(t, json.NewEncoder(&b).Encode(body))
+		res, err := testServer.Client().Post(testServer.URL+"/admin/keys/"+set, "application/json", &b)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var keys jose.JSONWebKeySet
+		if res.StatusCode == http.StatusCreated {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&keys))
+		}
+		return res.StatusCode, &keys
+	}
+
+	t.Run("case=generates an RSA key of the requested size", func(t *testing.T) {
+		status, keys := create(t, "rsa-bits", map[string]interface{}{"alg": "RS256", "use": "sig", "kid": "rsa", "kty": "RSA", "bits": 3072})
+		require.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, 3072, keys.Keys[0].Key.(*rsa.PrivateKey).N.BitLen())
+	})
+
+	t.Run("case=generates an encryption key of the requested curve", func(t *testing.T) {
+		status, keys := create(t, "ecdh-curve", map[string]interface{}{"alg": "ECDH-ES", "use": "enc", "kid": "ecdh", "crv": "P-384"})
+		require.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, elliptic.P384(), keys.Keys[0].Key.(*ecdsa.PrivateKey).Curve)
+	})
+
+	t.Run("case=rejects parameters which do not match the algorithm", func(t *testing.T) {
+		status, _ := create(t, "mismatch", map[string]interface{}{"alg": "ES256", "use": "sig", "kid": "es", "crv": "P-521"})
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("case=rejects parameters which are not allowed by the policy", func(t *testing.T) {
+		conf.MustSet(ctx, config.KeyJWKSGenerationRSAMaxBits, 4096)
+		conf.MustSet(ctx, config.KeyJWKSGenerationCurves, []string{"P-256"})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.KeyJWKSGenerationRSAMaxBits, nil)
+			conf.MustSet(ctx, config.KeyJWKSGenerationCurves, nil)
+		})
+
+		status, _ := create(t, "policy", map[string]interface{}{"alg": "RS256", "use": "sig", "kid": "rsa", "bits": 8192})
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = create(t, "policy", map[string]interface{}{"alg": "ES384", "use": "sig", "kid": "es"})
+		assert.Equal(t, http.StatusBadRequest, status)
+		status, _ = create(t, "policy", map[string]interface{}{"alg": "ES256", "use": "sig", "kid": "es"})
+		assert.Equal(t, http.StatusCreated, status)
+	})
+}
+
+func TestHandlerRotateKeySet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	router := x.NewRouterPublic()
+	reg.KeyHandler().SetRoutes(httprouterx.NewRouterAdminWithPrefixAndRouter(router.Router, "/admin", conf.AdminURL), router, func(h http.Handler) http.Handler {
+		return h
+	})
+	testServer := httptest.NewServer(router)
+	t.Cleanup(testServer.Close)
+
+	_, err := reg.KeyManager().GenerateAndPersistKeySet(ctx, "rotate-set", "rotate-key", string(jose.ES256), "sig")
+	require.NoError(t, err)
+
+	r, err := http.NewRequest("PUT", testServer.URL+"/admin/keys/rotate-set/rotate", nil)
+	require.NoError(t, err)
+	res, err := testServer.Client().Do(r)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	// Only key sets stored on a Hardware Security Module can be rotated.
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	keys, err := reg.KeyManager().GetKeySet(ctx, "rotate-set")
+	require.NoError(t, err)
+	assert.Len(t, keys.Keys, 1)
+}
@@ -5,6 +5,9 @@ package jwk
 
 import (
 	"context"
+	"crypto/sha1" // #nosec G505 -- x5t is defined as the SHA-1 thumbprint of the certificate
+	"crypto/sha256"
+	"encoding/base64"
 	"net"
 
 	"github.com/ory/x/josex"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x"
 
 	"github.com/pkg/errors"
 
@@ -28,19 +32,31 @@ type JWTSigner interface {
 
 type DefaultJWTSigner struct {
 	*jwt.DefaultSigner
-	r     InternalRegistry
-	c     *config.DefaultProvider
-	setID string
+	r       InternalRegistry
+	c       *config.DefaultProvider
+	setID   func(ctx context.Context) string
+	typeKey string
 }
 
 func NewDefaultJWTSigner(c *config.DefaultProvider, r InternalRegistry, setID string) *DefaultJWTSigner {
-	j := &DefaultJWTSigner{c: c, r: r, setID: setID, DefaultSigner: &jwt.DefaultSigner{}}
+	typeKey := config.KeyJWTHeadersIDTokenType
+	if setID == x.OAuth2JWTKeyName {
+		typeKey = config.KeyJWTHeadersAccessTokenType
+	}
+	return NewConfiguredJWTSigner(c, r, func(context.Context) string { return setID }, typeKey)
+}
+
+// NewConfiguredJWTSigner returns a signer which signs with the key set returned by setID, which may depend on the
+// configuration of the context. typeKey is the configuration key of the typ header parameter of the signed JWTs.
+func NewConfiguredJWTSigner(c *config.DefaultProvider, r InternalRegistry, setID func(ctx context.Context) string, typeKey string) *DefaultJWTSigner {
+	j := &DefaultJWTSigner{c: c, r: r, setID: setID, typeKey: typeKey, DefaultSigner: &jwt.DefaultSigner{}}
 	j.DefaultSigner.GetPrivateKey = j.getPrivateKey
 	return j
 }
 
 func (j *DefaultJWTSigner) getKeys(ctx context.Context) (private *jose.JSONWebKey, err error) {
-	private, err = GetOrGenerateKeys(ctx, j.r, j.r.KeyManager(), j.setID, uuid.Must(uuid.NewV4()).String(), string(jose.RS256))
+	setID := j.setID(ctx)
+	private, err = GetOrGenerateKeys(ctx, j.r, j.r.KeyManager(), setID, uuid.Must(uuid.NewV4()).String(), string(jose.RS256))
 	if err == nil {
 		return private, nil
 	}
@@ -48,12 +64,12 @@ func (j *DefaultJWTSigner) getKeys(ctx context.Context) (private *jose.JSONWebKe
 	var netError net.Error
 	if errors.As(err, &netError) {
 		return nil, errors.WithStack(fosite.ErrServerError.
-			WithHintf(`Could not ensure that signing keys for "%s" exists. A network error occurred, see error for specific details.`, j.setID))
+			WithHintf(`Could not ensure that signing keys for "%s" exists. A network error occurred, see error for specific details.`, setID))
 	}
 
 	return nil, errors.WithStack(fosite.ErrServerError.
 		WithWrap(err).
-		WithHintf(`Could not ensure that signing keys for "%s" exists. If you are running against a persistent SQL database this is most likely because your "secrets.system" ("SECRETS_SYSTEM" environment variable) is not set or changed. When running with an SQL database backend you need to make sure that the secret is set and stays the same, unless when doing key rotation. This may also happen when you forget to run "hydra migrate sql..`, j.setID))
+		WithHintf(`Could not ensure that signing keys for "%s" exists. If you are running against a persistent SQL database this is most likely because your "secrets.system" ("SECRETS_SYSTEM" environment variable) is not set or changed. When running with an SQL database backend you need to make sure that the secret is set and stays the same, unless when doing key rotation. This may also happen when you forget to run "hydra migrate sql..`, setID))
 }
 
 func (j *DefaultJWTSigner) GetPublicKeyID(ctx context.Context) (string, error) {
@@ -80,3 +96,50 @@ func (j *DefaultJWTSigner) getPrivateKey(ctx context.Context) (interface{}, erro
 
 	return private, nil
 }
+
+// Generate signs the claims. The header always contains the ID of the signing key, the configured typ of the key
+// set and the configured extra header parameters, unless the header already contains them.
+func (j *DefaultJWTSigner) Generate(ctx context.Context, claims jwt.MapClaims, header jwt.Mapper) (string, string, error) {
+	key, err := j.GetPrivateKey(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	var private *jose.JSONWebKey
+	switch k := key.(type) {
+	case *jose.JSONWebKey:
+		private = k
+	case jose.JSONWebKey:
+		private = &k
+	default:
+		private = &jose.JSONWebKey{Key: key}
+	}
+
+	headers := jwt.NewHeaders()
+	for k, v := range j.c.JWTHeadersExtra(ctx) {
+		headers.Add(k, v)
+	}
+	delete(headers.Extra, "kid")
+	delete(headers.Extra, "typ")
+	if typ := j.c.JWTHeadersType(ctx, j.typeKey); typ != "" {
+		headers.Add("typ", typ)
+	}
+	if j.c.JWTHeadersX5T(ctx) && len(private.Certificates) > 0 {
+		sha1Thumbprint := sha1.Sum(private.Certificates[0].Raw) // #nosec G401
+		sha256Thumbprint := sha256.Sum256(private.Certificates[0].Raw)
+		headers.Add("x5t", base64.RawURLEncoding.EncodeToString(sha1Thumbprint[:]))
+		headers.Add("x5t#S256", base64.RawURLEncoding.EncodeToString(sha256Thumbprint[:]))
+	}
+	if header != nil {
+		for k, v := range header.ToMap() {
+			headers.Add(k, v)
+		}
+	}
+	if kid, _ := headers.Get("kid").(string); kid == "" && private.KeyID != "" {
+		headers.Add("kid", private.KeyID)
+	}
+
+	signer := &jwt.DefaultSigner{GetPrivateKey: func(context.Context) (interface{}, error) {
+		return key, nil
+	}}
+	return signer.Generate(ctx, claims, headers)
+}
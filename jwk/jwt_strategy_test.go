@@ -5,17 +5,28 @@ package jwk_test
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"math/big"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
 
 	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/hydra/v2/internal"
 	. "github.com/ory/hydra/v2/jwk"
+	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/contextx"
 )
 
@@ -38,6 +49,7 @@ func TestJWTStrategy(t *testing.T) {
 			token, err := base64.RawStdEncoding.DecodeString(strings.Split(a, ".")[0])
 			require.NoError(t, err)
 			assert.Equal(t, alg, gjson.GetBytes(token, "alg").String())
+			assert.Equal(t, "foo", gjson.GetBytes(token, "kid").String())
 
 			_, err = s.Validate(context.Background(), a)
 			require.NoError(t, err)
@@ -68,3 +80,106 @@ func TestJWTStrategy(t *testing.T) {
 		})
 	}
 }
+
+func TestJWTStrategyHeaders(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyJWTHeadersX5T, true)
+	conf.MustSet(ctx, config.KeyJWTHeadersAccessTokenType, "at+jwt")
+	conf.MustSet(ctx, config.KeyJWTHeadersExtra, map[string]interface{}{"x-tenant": "acme", "kid": "ignored"})
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "hydra"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	require.NoError(t, reg.KeyManager().AddKeySet(ctx, x.OAuth2JWTKeyName, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:          key,
+		KeyID:        "with-certificate",
+		Algorithm:    "RS256",
+		Use:          "sig",
+		Certificates: []*x509.Certificate{cert},
+	}}}))
+
+	header := func(t *testing.T, token string) []byte {
+		h, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+		require.NoError(t, err)
+		return h
+	}
+
+	t.Run("case=access tokens", func(t *testing.T) {
+		s := NewDefaultJWTSigner(conf, reg, x.OAuth2JWTKeyName)
+		token, _, err := s.Generate(ctx, jwt.MapClaims{"foo": "bar"}, &jwt.Headers{Extra: map[string]interface{}{"kid": ""}})
+		require.NoError(t, err)
+
+		h := header(t, token)
+		thumbprint := sha256.Sum256(cert.Raw)
+		assert.Equal(t, "with-certificate", gjson.GetBytes(h, "kid").String())
+		assert.Equal(t, "at+jwt", gjson.GetBytes(h, "typ").String())
+		assert.Equal(t, "acme", gjson.GetBytes(h, "x-tenant").String())
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(thumbprint[:]), gjson.GetBytes(h, "x5t#S256").String())
+		assert.NotEmpty(t, gjson.GetBytes(h, "x5t").String())
+
+		_, err = s.Validate(ctx, token)
+		require.NoError(t, err)
+	})
+
+	t.Run("case=headers of the caller take precedence", func(t *testing.T) {
+		s := NewDefaultJWTSigner(conf, reg, x.OAuth2JWTKeyName)
+		token, _, err := s.Generate(ctx, jwt.MapClaims{"foo": "bar"}, &jwt.Headers{Extra: map[string]interface{}{"typ": "logout+jwt", "x-tenant": "globex"}})
+		require.NoError(t, err)
+
+		h := header(t, token)
+		assert.Equal(t, "logout+jwt", gjson.GetBytes(h, "typ").String())
+		assert.Equal(t, "globex", gjson.GetBytes(h, "x-tenant").String())
+	})
+
+	t.Run("case=ID tokens", func(t *testing.T) {
+		s := NewDefaultJWTSigner(conf, reg, x.OpenIDConnectKeyName)
+		token, _, err := s.Generate(ctx, jwt.MapClaims{"foo": "bar"}, jwt.NewHeaders())
+		require.NoError(t, err)
+
+		h := header(t, token)
+		kid, err := s.GetPublicKeyID(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, kid, gjson.GetBytes(h, "kid").String())
+		assert.Equal(t, "JWT", gjson.GetBytes(h, "typ").String())
+		assert.False(t, gjson.GetBytes(h, "x5t").Exists())
+	})
+}
+
+func TestConfiguredJWTSigner(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	_, err := reg.KeyManager().GenerateAndPersistKeySet(ctx, "logout-set", "logout-key", "ES256", "sig")
+	require.NoError(t, err)
+
+	s := NewConfiguredJWTSigner(conf, reg, conf.LogoutTokenSigningKeySet, config.KeyJWTHeadersLogoutTokenType)
+
+	kid, err := s.GetPublicKeyID(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, "logout-key", kid, "defaults to the key set of ID tokens")
+
+	conf.MustSet(ctx, config.KeyJWKSLogoutTokenSigningKeySet, "logout-set")
+	conf.MustSet(ctx, config.KeyJWTHeadersLogoutTokenType, "logout+jwt")
+
+	token, _, err := s.Generate(ctx, jwt.MapClaims{"foo": "bar"}, &jwt.Headers{})
+	require.NoError(t, err)
+	h, err := base64.RawStdEncoding.DecodeString(strings.Split(token, ".")[0])
+	require.NoError(t, err)
+	assert.Equal(t, "logout-key", gjson.GetBytes(h, "kid").String())
+	assert.Equal(t, "ES256", gjson.GetBytes(h, "alg").String())
+	assert.Equal(t, "logout+jwt", gjson.GetBytes(h, "typ").String())
+
+	assert.Contains(t, conf.WellKnownKeys(ctx), "logout-set")
+}
@@ -26,6 +26,12 @@ var ErrUnsupportedEllipticCurve = &fosite.RFC6749Error{
 	DescriptionField: "Unsupported elliptic curve",
 }
 
+var ErrKeyRotationUnsupported = &fosite.RFC6749Error{
+	CodeField:        http.StatusBadRequest,
+	ErrorField:       http.StatusText(http.StatusBadRequest),
+	DescriptionField: "Key set rotation is only supported for key sets stored on a Hardware Security Module",
+}
+
 var ErrMinimalRsaKeyLength = &fosite.RFC6749Error{
 	CodeField:        http.StatusBadRequest,
 	ErrorField:       http.StatusText(http.StatusBadRequest),
@@ -53,6 +59,24 @@ type (
 		DeleteKeySet(ctx context.Context, set string) error
 	}
 
+	// ParameterizedKeyGenerator generates key sets with explicit key parameters.
+	ParameterizedKeyGenerator interface {
+		GenerateAndPersistKeySetWithParameters(ctx context.Context, set, kid, alg, use string, params KeyParameters) (*jose.JSONWebKeySet, error)
+	}
+
+	// KeyRotator rotates key sets without invalidating the tokens signed by their previous keys.
+	KeyRotator interface {
+		// RotateKeySet adds a new active key to the set. The previous keys stay published for the configured grace
+		// period. If alg is empty, the new key uses the algorithm of the active key.
+		RotateKeySet(ctx context.Context, set, alg, use string) (*jose.JSONWebKeySet, error)
+	}
+
+	// Pruner deletes the keys which are no longer retained by the key set pruning policy.
+	Pruner interface {
+		// PruneKeySets prunes every key set.
+		PruneKeySets(ctx context.Context) error
+	}
+
 	SQLData struct {
 		ID  uuid.UUID `db:"pk"`
 		NID uuid.UUID `json:"-" db:"nid"`
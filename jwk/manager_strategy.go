@@ -12,6 +12,7 @@ import (
 
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/otelx"
+	"github.com/ory/x/stringslice"
 )
 
 const tracingComponent = "github.com/ory/hydra/v2/jwk"
@@ -19,6 +20,8 @@ const tracingComponent = "github.com/ory/hydra/v2/jwk"
 type ManagerStrategy struct {
 	hardwareKeyManager Manager
 	softwareKeyManager Manager
+	hardwareKeySets    func(ctx context.Context) []string
+	hardwareKeyImport  func(ctx context.Context) bool
 }
 
 func NewManagerStrategy(hardwareKeyManager Manager, softwareKeyManager Manager) *ManagerStrategy {
@@ -28,6 +31,37 @@ func NewManagerStrategy(hardwareKeyManager Manager, softwareKeyManager Manager)
 	}
 }
 
+// WithHardwareKeySets restricts the hardware key manager to the key sets returned by keySets. All other key sets are
+// generated, read and deleted by the software key manager only. If keySets returns no key sets, every key set is
+// generated by the hardware key manager.
+func (m *ManagerStrategy) WithHardwareKeySets(keySets func(ctx context.Context) []string) *ManagerStrategy {
+	m.hardwareKeySets = keySets
+	return m
+}
+
+// WithHardwareKeyImport sends keys which are added to or updated in hardware key sets to the hardware key manager if
+// importKeys returns true. Otherwise, they are stored by the software key manager.
+func (m *ManagerStrategy) WithHardwareKeyImport(importKeys func(ctx context.Context) bool) *ManagerStrategy {
+	m.hardwareKeyImport = importKeys
+	return m
+}
+
+// keyManagerForImport returns the key manager which stores the keys added to the key set.
+func (m ManagerStrategy) keyManagerForImport(ctx context.Context, set string) Manager {
+	if m.hardwareKeyImport != nil && m.hardwareKeyImport(ctx) && m.isHardwareKeySet(ctx, set) {
+		return m.hardwareKeyManager
+	}
+	return m.softwareKeyManager
+}
+
+func (m ManagerStrategy) isHardwareKeySet(ctx context.Context, set string) bool {
+	if m.hardwareKeySets == nil {
+		return true
+	}
+	keySets := m.hardwareKeySets(ctx)
+	return len(keySets) == 0 || stringslice.Has(keySets, set)
+}
+
 func (m ManagerStrategy) GenerateAndPersistKeySet(ctx context.Context, set, kid, alg, use string) (*jose.JSONWebKeySet, error) {
 	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "jwk.GenerateAndPersistKeySet")
 	defer span.End()
@@ -39,9 +73,58 @@ func (m ManagerStrategy) GenerateAndPersistKeySet(ctx context.Context, set, kid,
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
+	if !m.isHardwareKeySet(ctx, set) {
+		return m.softwareKeyManager.GenerateAndPersistKeySet(ctx, set, kid, alg, use)
+	}
 	return m.hardwareKeyManager.GenerateAndPersistKeySet(ctx, set, kid, alg, use)
 }
 
+// GenerateAndPersistKeySetWithParameters generates the key set with the key manager of the set, which must support key
+// parameters.
+func (m ManagerStrategy) GenerateAndPersistKeySetWithParameters(ctx context.Context, set, kid, alg, use string, params KeyParameters) (*jose.JSONWebKeySet, error) {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "jwk.GenerateAndPersistKeySetWithParameters")
+	defer span.End()
+	attrs := map[string]string{
+		"set": set,
+		"kid": kid,
+		"alg": alg,
+		"use": use,
+	}
+	span.SetAttributes(otelx.StringAttrs(attrs)...)
+
+	if !m.isHardwareKeySet(ctx, set) {
+		g, ok := m.softwareKeyManager.(ParameterizedKeyGenerator)
+		if !ok {
+			return nil, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "the software key manager does not support key parameters")
+		}
+		return g.GenerateAndPersistKeySetWithParameters(ctx, set, kid, alg, use, params)
+	}
+
+	g, ok := m.hardwareKeyManager.(ParameterizedKeyGenerator)
+	if !ok {
+		return nil, errors.Wrapf(ErrUnsupportedKeyAlgorithm, "the hardware key manager does not support key parameters")
+	}
+	return g.GenerateAndPersistKeySetWithParameters(ctx, set, kid, alg, use, params)
+}
+
+// RotateKeySet rotates the key set with the hardware key manager, which must support key set rotation.
+func (m ManagerStrategy) RotateKeySet(ctx context.Context, set, alg, use string) (*jose.JSONWebKeySet, error) {
+	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "jwk.RotateKeySet")
+	defer span.End()
+	attrs := map[string]string{
+		"set": set,
+		"alg": alg,
+		"use": use,
+	}
+	span.SetAttributes(otelx.StringAttrs(attrs)...)
+
+	r, ok := m.hardwareKeyManager.(KeyRotator)
+	if !ok || !m.isHardwareKeySet(ctx, set) {
+		return nil, errors.WithStack(ErrKeyRotationUnsupported)
+	}
+	return r.RotateKeySet(ctx, set, alg, use)
+}
+
 func (m ManagerStrategy) AddKey(ctx context.Context, set string, key *jose.JSONWebKey) error {
 	ctx, span := otel.GetTracerProvider().Tracer(tracingComponent).Start(ctx, "jwk.GenerateAndPersistKeySet")
 	defer span.End()
@@ -50,7 +133,7 @@ func (m ManagerStrategy) AddKey(ctx context.Context, set string, key *jose.JSONW
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
-	return m.softwareKeyManager.AddKey(ctx, set, key)
+	return m.keyManagerForImport(ctx, set).AddKey(ctx, set, key)
 }
 
 func (m ManagerStrategy) AddKeySet(ctx context.Context, set string, keys *jose.JSONWebKeySet) error {
@@ -61,7 +144,7 @@ func (m ManagerStrategy) AddKeySet(ctx context.Context, set string, keys *jose.J
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
-	return m.softwareKeyManager.AddKeySet(ctx, set, keys)
+	return m.keyManagerForImport(ctx, set).AddKeySet(ctx, set, keys)
 }
 
 func (m ManagerStrategy) UpdateKey(ctx context.Context, set string, key *jose.JSONWebKey) error {
@@ -72,7 +155,7 @@ func (m ManagerStrategy) UpdateKey(ctx context.Context, set string, key *jose.JS
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
-	return m.softwareKeyManager.UpdateKey(ctx, set, key)
+	return m.keyManagerForImport(ctx, set).UpdateKey(ctx, set, key)
 }
 
 func (m ManagerStrategy) UpdateKeySet(ctx context.Context, set string, keys *jose.JSONWebKeySet) error {
@@ -83,7 +166,7 @@ func (m ManagerStrategy) UpdateKeySet(ctx context.Context, set string, keys *jos
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
-	return m.softwareKeyManager.UpdateKeySet(ctx, set, keys)
+	return m.keyManagerForImport(ctx, set).UpdateKeySet(ctx, set, keys)
 }
 
 func (m ManagerStrategy) GetKey(ctx context.Context, set, kid string) (*jose.JSONWebKeySet, error) {
@@ -95,6 +178,10 @@ func (m ManagerStrategy) GetKey(ctx context.Context, set, kid string) (*jose.JSO
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
+	if !m.isHardwareKeySet(ctx, set) {
+		return m.softwareKeyManager.GetKey(ctx, set, kid)
+	}
+
 	keySet, err := m.hardwareKeyManager.GetKey(ctx, set, kid)
 	if err != nil && !errors.Is(err, x.ErrNotFound) {
 		return nil, err
@@ -113,6 +200,10 @@ func (m ManagerStrategy) GetKeySet(ctx context.Context, set string) (*jose.JSONW
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
+	if !m.isHardwareKeySet(ctx, set) {
+		return m.softwareKeyManager.GetKeySet(ctx, set)
+	}
+
 	keySet, err := m.hardwareKeyManager.GetKeySet(ctx, set)
 	if err != nil && !errors.Is(err, x.ErrNotFound) {
 		return nil, err
@@ -132,6 +223,10 @@ func (m ManagerStrategy) DeleteKey(ctx context.Context, set, kid string) error {
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
+	if !m.isHardwareKeySet(ctx, set) {
+		return m.softwareKeyManager.DeleteKey(ctx, set, kid)
+	}
+
 	err := m.hardwareKeyManager.DeleteKey(ctx, set, kid)
 	if err != nil && !errors.Is(err, x.ErrNotFound) {
 		return err
@@ -150,6 +245,10 @@ func (m ManagerStrategy) DeleteKeySet(ctx context.Context, set string) error {
 	}
 	span.SetAttributes(otelx.StringAttrs(attrs)...)
 
+	if !m.isHardwareKeySet(ctx, set) {
+		return m.softwareKeyManager.DeleteKeySet(ctx, set)
+	}
+
 	err := m.hardwareKeyManager.DeleteKeySet(ctx, set)
 	if err != nil && !errors.Is(err, x.ErrNotFound) {
 		return err
@@ -208,3 +208,92 @@ func TestKeyManagerStrategy(t *testing.T) {
 		assert.Error(t, err, "Not Found")
 	})
 }
+
+func TestKeyManagerStrategyHardwareKeySets(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	softwareKeyManager := NewMockManager(ctrl)
+	hardwareKeyManager := NewMockManager(ctrl)
+	keyManager := jwk.NewManagerStrategy(hardwareKeyManager, softwareKeyManager).WithHardwareKeySets(func(context.Context) []string {
+		return []string{"hsm-set"}
+	})
+	defer ctrl.Finish()
+	hwKeySet := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{KeyID: "hwKeyID"}}}
+	swKeySet := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{KeyID: "swKeyID"}}}
+
+	t.Run("case=key sets on the allow-list are generated by the hardware key manager", func(t *testing.T) {
+		hardwareKeyManager.EXPECT().GenerateAndPersistKeySet(gomock.Any(), gomock.Eq("hsm-set"), gomock.Eq("kid1"), gomock.Any(), gomock.Any()).Return(hwKeySet, nil)
+		resultKeySet, err := keyManager.GenerateAndPersistKeySet(context.TODO(), "hsm-set", "kid1", "RS256", "sig")
+		assert.NoError(t, err)
+		assert.Equal(t, hwKeySet, resultKeySet)
+	})
+
+	t.Run("case=other key sets are generated by the software key manager", func(t *testing.T) {
+		softwareKeyManager.EXPECT().GenerateAndPersistKeySet(gomock.Any(), gomock.Eq("sql-set"), gomock.Eq("kid1"), gomock.Any(), gomock.Any()).Return(swKeySet, nil)
+		resultKeySet, err := keyManager.GenerateAndPersistKeySet(context.TODO(), "sql-set", "kid1", "RS256", "sig")
+		assert.NoError(t, err)
+		assert.Equal(t, swKeySet, resultKeySet)
+	})
+
+	t.Run("case=other key sets are not looked up on the hardware key manager", func(t *testing.T) {
+		softwareKeyManager.EXPECT().GetKeySet(gomock.Any(), gomock.Eq("sql-set")).Return(swKeySet, nil)
+		softwareKeyManager.EXPECT().GetKey(gomock.Any(), gomock.Eq("sql-set"), gomock.Eq("kid1")).Return(swKeySet, nil)
+		softwareKeyManager.EXPECT().DeleteKey(gomock.Any(), gomock.Eq("sql-set"), gomock.Eq("kid1")).Return(nil)
+		softwareKeyManager.EXPECT().DeleteKeySet(gomock.Any(), gomock.Eq("sql-set")).Return(nil)
+
+		resultKeySet, err := keyManager.GetKeySet(context.TODO(), "sql-set")
+		assert.NoError(t, err)
+		assert.Equal(t, swKeySet, resultKeySet)
+		resultKeySet, err = keyManager.GetKey(context.TODO(), "sql-set", "kid1")
+		assert.NoError(t, err)
+		assert.Equal(t, swKeySet, resultKeySet)
+		assert.NoError(t, keyManager.DeleteKey(context.TODO(), "sql-set", "kid1"))
+		assert.NoError(t, keyManager.DeleteKeySet(context.TODO(), "sql-set"))
+	})
+
+	t.Run("case=other key sets cannot be rotated", func(t *testing.T) {
+		_, err := keyManager.RotateKeySet(context.TODO(), "sql-set", "", "")
+		assert.ErrorIs(t, err, jwk.ErrKeyRotationUnsupported)
+	})
+}
+
+func TestKeyManagerStrategyHardwareKeyImport(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	softwareKeyManager := NewMockManager(ctrl)
+	hardwareKeyManager := NewMockManager(ctrl)
+	importKeys := false
+	keyManager := jwk.NewManagerStrategy(hardwareKeyManager, softwareKeyManager).WithHardwareKeySets(func(context.Context) []string {
+		return []string{"hsm-set"}
+	}).WithHardwareKeyImport(func(context.Context) bool {
+		return importKeys
+	})
+	defer ctrl.Finish()
+	key := &jose.JSONWebKey{KeyID: "kid1"}
+	keySet := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{*key}}
+
+	t.Run("case=keys are stored by the software key manager if importing keys is disabled", func(t *testing.T) {
+		softwareKeyManager.EXPECT().AddKey(gomock.Any(), gomock.Eq("hsm-set"), gomock.Eq(key)).Return(nil)
+		softwareKeyManager.EXPECT().UpdateKeySet(gomock.Any(), gomock.Eq("hsm-set"), gomock.Eq(keySet)).Return(nil)
+		assert.NoError(t, keyManager.AddKey(context.TODO(), "hsm-set", key))
+		assert.NoError(t, keyManager.UpdateKeySet(context.TODO(), "hsm-set", keySet))
+	})
+
+	importKeys = true
+
+	t.Run("case=keys of hardware key sets are imported by the hardware key manager", func(t *testing.T) {
+		hardwareKeyManager.EXPECT().AddKey(gomock.Any(), gomock.Eq("hsm-set"), gomock.Eq(key)).Return(nil)
+		hardwareKeyManager.EXPECT().AddKeySet(gomock.Any(), gomock.Eq("hsm-set"), gomock.Eq(keySet)).Return(nil)
+		hardwareKeyManager.EXPECT().UpdateKey(gomock.Any(), gomock.Eq("hsm-set"), gomock.Eq(key)).Return(nil)
+		hardwareKeyManager.EXPECT().UpdateKeySet(gomock.Any(), gomock.Eq("hsm-set"), gomock.Eq(keySet)).Return(nil)
+		assert.NoError(t, keyManager.AddKey(context.TODO(), "hsm-set", key))
+		assert.NoError(t, keyManager.AddKeySet(context.TODO(), "hsm-set", keySet))
+		assert.NoError(t, keyManager.UpdateKey(context.TODO(), "hsm-set", key))
+		assert.NoError(t, keyManager.UpdateKeySet(context.TODO(), "hsm-set", keySet))
+	})
+
+	t.Run("case=keys of other key sets are stored by the software key manager", func(t *testing.T) {
+		softwareKeyManager.EXPECT().UpdateKey(gomock.Any(), gomock.Eq("sql-set"), gomock.Eq(key)).Return(nil)
+		assert.NoError(t, keyManager.UpdateKey(context.TODO(), "sql-set", key))
+	})
+}
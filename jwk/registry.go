@@ -5,6 +5,7 @@ package jwk
 
 import (
 	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/audit"
 	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/hydra/v2/x"
 )
@@ -12,6 +13,7 @@ import (
 type InternalRegistry interface {
 	x.RegistryWriter
 	x.RegistryLogger
+	audit.Registry
 	Registry
 }
 
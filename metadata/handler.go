@@ -0,0 +1,146 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/persistence"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/httprouterx"
+	"github.com/ory/x/popx"
+)
+
+const (
+	MigrationStatusPath = "/version/migrations"
+)
+
+type (
+	InternalRegistry interface {
+		x.RegistryWriter
+		x.RegistryLogger
+		config.Provider
+		persistence.Provider
+	}
+
+	Handler struct {
+		r InternalRegistry
+
+		openAPIOnce sync.Once
+		openAPI     []byte
+		openAPIErr  error
+	}
+)
+
+func NewHandler(r InternalRegistry) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
+	admin.GET(MigrationStatusPath, h.getMigrationStatus)
+	admin.GET(OpenAPIPath, h.getOpenAPIDocument)
+	admin.GET(SwaggerUIPath, h.getSwaggerUI)
+}
+
+// Migration Status
+//
+// swagger:model migrationStatus
+type MigrationStatus struct {
+	// Compatible is true if all migrations required by this version of Ory Hydra have been applied. Pending
+	// contract migrations do not affect compatibility, as they only remove schema elements which are no longer used.
+	//
+	// required: true
+	Compatible bool `json:"compatible"`
+
+	// UpToDate is true if there are no pending migrations at all.
+	//
+	// required: true
+	UpToDate bool `json:"up_to_date"`
+
+	// Applied is the number of applied migrations.
+	//
+	// required: true
+	Applied int `json:"applied"`
+
+	// Pending is the number of pending migrations.
+	//
+	// required: true
+	Pending int `json:"pending"`
+
+	// Migrations lists all migrations known to this version of Ory Hydra.
+	//
+	// required: true
+	Migrations []Migration `json:"migrations"`
+}
+
+// Migration
+//
+// swagger:model migration
+type Migration struct {
+	// The migration version.
+	Version string `json:"version"`
+
+	// The migration name.
+	Name string `json:"name"`
+
+	// The migration state, either "Applied" or "Pending".
+	State string `json:"state"`
+
+	// The migration phase, either "expand" for backwards-compatible migrations or "contract" for migrations
+	// which must only be applied once no previous version of Ory Hydra is running anymore.
+	Phase string `json:"phase"`
+}
+
+// NewMigrationStatus summarizes the given migration statuses.
+func NewMigrationStatus(statuses popx.MigrationStatuses) *MigrationStatus {
+	s := &MigrationStatus{Compatible: true, Migrations: make([]Migration, len(statuses))}
+	for k, m := range statuses {
+		phase := "expand"
+		if sql.IsContractMigration(m.Version, m.Name) {
+			phase = "contract"
+		}
+
+		s.Migrations[k] = Migration{Version: m.Version, Name: m.Name, State: m.State, Phase: phase}
+		if m.State == popx.Pending {
+			s.Pending++
+			if phase == "expand" {
+				s.Compatible = false
+			}
+		} else {
+			s.Applied++
+		}
+	}
+	s.UpToDate = s.Pending == 0
+	return s
+}
+
+// swagger:route GET /admin/version/migrations metadata getMigrationStatus
+//
+// # Get Database Migration Status
+//
+// This endpoint returns the applied and pending database migrations and whether the database schema is compatible
+// with the running version of Ory Hydra. It can be used by orchestration tooling to gate rollouts.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: migrationStatus
+//	  default: errorOAuth2
+func (h *Handler) getMigrationStatus(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	status, err := h.r.Persister().MigrationStatus(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, NewMigrationStatus(status))
+}
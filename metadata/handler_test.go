@@ -0,0 +1,64 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/metadata"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/popx"
+)
+
+func TestMigrationStatusHandler(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	admin := x.NewRouterAdmin(conf.AdminURL)
+	reg.RegisterRoutes(ctx, admin, x.NewRouterPublic())
+
+	ts := httptest.NewServer(admin)
+	t.Cleanup(ts.Close)
+
+	res, err := http.Get(ts.URL + "/admin" + metadata.MigrationStatusPath)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var status metadata.MigrationStatus
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.True(t, status.Compatible)
+	assert.True(t, status.UpToDate)
+	assert.Zero(t, status.Pending)
+	assert.NotZero(t, status.Applied)
+	assert.Len(t, status.Migrations, status.Applied)
+}
+
+func TestNewMigrationStatus(t *testing.T) {
+	status := metadata.NewMigrationStatus(popx.MigrationStatuses{
+		{Version: "1", Name: "add_column", State: popx.Applied},
+		{Version: "2", Name: "drop_column_contract", State: popx.Pending},
+	})
+	assert.True(t, status.Compatible)
+	assert.False(t, status.UpToDate)
+	assert.Equal(t, 1, status.Applied)
+	assert.Equal(t, 1, status.Pending)
+	assert.Equal(t, "contract", status.Migrations[1].Phase)
+
+	status = metadata.NewMigrationStatus(popx.MigrationStatuses{
+		{Version: "1", Name: "add_column", State: popx.Pending},
+	})
+	assert.False(t, status.Compatible)
+	assert.Equal(t, "expand", status.Migrations[0].Phase)
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/spec"
+	"github.com/ory/x/errorsx"
+)
+
+const (
+	OpenAPIPath   = "/openapi.json"
+	SwaggerUIPath = "/openapi"
+)
+
+var swaggerUI = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Ory Hydra API {{ .Version }}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: {{ .URL }}, dom_id: "#swagger-ui" }) }
+  </script>
+</body>
+</html>
+`))
+
+// openAPIDocument returns the embedded OpenAPI document with the version of the running instance.
+func (h *Handler) openAPIDocument() ([]byte, error) {
+	h.openAPIOnce.Do(func() {
+		h.openAPI, h.openAPIErr = sjson.SetBytes(spec.API, "info.version", config.Version)
+	})
+	return h.openAPI, h.openAPIErr
+}
+
+// getOpenAPIDocument serves the OpenAPI document of the running instance, so that tooling does not have to guess
+// the API from the release notes.
+func (h *Handler) getOpenAPIDocument(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	doc, err := h.openAPIDocument()
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(doc)
+}
+
+// getSwaggerUI serves a Swagger UI for the OpenAPI document if enabled in the configuration.
+func (h *Handler) getSwaggerUI(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !h.r.Config().AdminSwaggerUIEnabled(r.Context()) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrNotFound.WithReason("The Swagger UI is disabled. Set serve.admin.swagger_ui.enabled to enable it.")))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := swaggerUI.Execute(w, map[string]string{
+		"Version": config.Version,
+		"URL":     "openapi.json",
+	}); err != nil {
+		h.r.Logger().WithRequest(r).WithError(err).Error("Unable to render the Swagger UI.")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/metadata"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+)
+
+func TestOpenAPIHandler(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	admin := x.NewRouterAdmin(conf.AdminURL)
+	reg.RegisterRoutes(ctx, admin, x.NewRouterPublic())
+
+	ts := httptest.NewServer(admin)
+	t.Cleanup(ts.Close)
+
+	get := func(t *testing.T, path string) (*http.Response, string) {
+		res, err := http.Get(ts.URL + "/admin" + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	t.Run("case=serves the OpenAPI document", func(t *testing.T) {
+		res, body := get(t, metadata.OpenAPIPath)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, res.Header.Get("Content-Type"), "application/json")
+		assert.Equal(t, config.Version, gjson.Get(body, "info.version").String())
+		for _, path := range []string{"/admin/clients", "/admin/version/migrations", "/admin/oauth2/tokens/jwt", "/admin/audit/events"} {
+			assert.True(t, gjson.Get(body, "paths."+path).Exists(), path)
+		}
+	})
+
+	t.Run("case=serves the Swagger UI if enabled", func(t *testing.T) {
+		res, _ := get(t, metadata.SwaggerUIPath)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		conf.MustSet(ctx, config.KeyAdminSwaggerUIEnabled, true)
+		res, body := get(t, metadata.SwaggerUIPath)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, res.Header.Get("Content-Type"), "text/html")
+		assert.Contains(t, body, `url: "openapi.json"`)
+	})
+}
@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+)
+
+// applyAccessTokenClaimsProfile configures which claims the JWT access tokens of the session contain. It is applied
+// whenever tokens are issued, so that changes of the configuration also apply to refreshed tokens.
+func (h *Handler) applyAccessTokenClaimsProfile(ctx context.Context, session *Session) {
+	session.MinimalClaims = h.c.JWTClaimsProfile(ctx) == "minimal"
+	session.HashedSubject = ""
+	if h.c.JWTHashSubject(ctx) && session.Subject != "" {
+		session.HashedSubject = hashSubject(session.Subject, h.c.SubjectIdentifierAlgorithmSalt(ctx))
+	}
+}
+
+func hashSubject(subject, salt string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(subject+salt)))
+}
@@ -0,0 +1,260 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+)
+
+// AuthorizationRequest is an authorization endpoint's request context.
+//
+// swagger:ignore
+type AuthorizationRequest struct {
+	// ClientID is the identifier of the OAuth 2.0 client.
+	ClientID string `json:"client_id"`
+	// RedirectURI is the redirect URI of the request.
+	RedirectURI string `json:"redirect_uri"`
+	// ResponseTypes is the list of response types of the request.
+	ResponseTypes []string `json:"response_types"`
+	// RequestedScope is the list of scopes requested by the OAuth 2.0 client.
+	RequestedScope []string `json:"requested_scope"`
+	// RequestedAudience is the list of audiences requested by the OAuth 2.0 client.
+	RequestedAudience []string `json:"requested_audience"`
+	// ACRValues is the list of requested authentication context class references.
+	ACRValues []string `json:"acr_values"`
+	// Payload is the requests payload.
+	Payload map[string][]string `json:"payload"`
+}
+
+// AuthorizationRequestHookContext is the HTTP context of the authorization request.
+//
+// swagger:ignore
+type AuthorizationRequestHookContext struct {
+	// ClientIP is the IP address of the user agent.
+	ClientIP string `json:"client_ip"`
+	// UserAgent is the User-Agent header of the request.
+	UserAgent string `json:"user_agent"`
+}
+
+// AuthorizationRequestHookRequest is the request body sent to the authorization request hook, or the input of the
+// Open Policy Agent.
+//
+// swagger:ignore
+type AuthorizationRequestHookRequest struct {
+	// Request is the authorization request.
+	Request AuthorizationRequest `json:"request"`
+	// Context is the HTTP context of the authorization request.
+	Context AuthorizationRequestHookContext `json:"context"`
+}
+
+// AuthorizationRequestHookResponse is the response body received from the authorization request hook, or the
+// result of the Open Policy Agent.
+//
+// swagger:ignore
+type AuthorizationRequestHookResponse struct {
+	// Allow admits the request. Webhooks admit requests unless it is false, the Open Policy Agent only if it is
+	// true.
+	Allow *bool `json:"allow"`
+	// ErrorDescription is added to the error shown to the user if the request is denied.
+	ErrorDescription string `json:"error_description"`
+	// RequestedScope replaces the requested scope if set.
+	RequestedScope []string `json:"requested_scope"`
+	// RequestedAudience replaces the requested audience if set.
+	RequestedAudience []string `json:"requested_audience"`
+	// ACRValues replaces the requested authentication context class references if set.
+	ACRValues []string `json:"acr_values"`
+}
+
+type opaRequest struct {
+	Input *AuthorizationRequestHookRequest `json:"input"`
+}
+
+type opaResponse struct {
+	Result *AuthorizationRequestHookResponse `json:"result"`
+}
+
+// executeAuthorizationRequestHook calls the authorization request hook, if configured, which may deny the request
+// or modify its scope, audience and ACR values.
+func (h *Handler) executeAuthorizationRequestHook(ctx context.Context, r *http.Request, ar fosite.AuthorizeRequester) error {
+	hookConfig := h.c.AuthorizationRequestHookConfig(ctx)
+	if hookConfig == nil {
+		return nil
+	}
+	opa := h.c.AuthorizationRequestHookType(ctx) == "opa"
+
+	reqBody := &AuthorizationRequestHookRequest{
+		Request: AuthorizationRequest{
+			ClientID:          ar.GetClient().GetID(),
+			ResponseTypes:     ar.GetResponseTypes(),
+			RequestedScope:    ar.GetRequestedScopes(),
+			RequestedAudience: ar.GetRequestedAudience(),
+			ACRValues:         strings.Fields(ar.GetRequestForm().Get("acr_values")),
+			Payload:           ar.GetRequestForm(),
+		},
+		Context: AuthorizationRequestHookContext{
+			ClientIP:  x.ClientIP(r),
+			UserAgent: r.UserAgent(),
+		},
+	}
+	if u := ar.GetRedirectURI(); u != nil {
+		reqBody.Request.RedirectURI = u.String()
+	}
+
+	var body interface{} = reqBody
+	if opa {
+		body = &opaRequest{Input: reqBody}
+	}
+	reqBodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while encoding the authorization request hook.").
+				WithDebugf("Unable to encode the authorization request hook body: %s", err),
+		)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, hookConfig.URL, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while preparing the authorization request hook.").
+				WithDebugf("Unable to prepare the HTTP Request: %s", err),
+		)
+	}
+	if err := hookConfig.Auth.Apply(req.Request); err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while applying the authorization request hook authentication.").
+				WithDebugf("Unable to apply the authorization request hook authentication: %s", err))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := h.r.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while executing the authorization request hook.").
+				WithDebugf("Unable to execute HTTP Request: %s", err),
+		)
+	}
+	defer resp.Body.Close()
+
+	decode := decodeAuthorizationRequestHookResponse
+	if opa {
+		decode = decodeOPADecision
+	}
+	respBody, err := decode(resp)
+	if err != nil || respBody == nil {
+		return err
+	}
+	return h.modifyAuthorizeRequest(ctx, ar, respBody)
+}
+
+// decodeAuthorizationRequestHookResponse returns the modifications of the webhook, or nil if there are none.
+func decodeAuthorizationRequestHookResponse(resp *http.Response) (*AuthorizationRequestHookResponse, error) {
+	var respBody AuthorizationRequestHookResponse
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Request is admitted, possibly with modifications
+	case http.StatusNoContent:
+		// Request is admitted without modifications
+		return nil, nil
+	case http.StatusForbidden:
+		// The error description is optional
+		_ = json.NewDecoder(resp.Body).Decode(&respBody)
+		return nil, authorizationRequestDenied(respBody.ErrorDescription)
+	default:
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The authorization request hook target responded with an error.").
+				WithDebugf("Authorization request hook responded with HTTP status code: %s", resp.Status),
+		)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("The authorization request hook target responded with an error.").
+				WithDebugf("Response from authorization request hook could not be decoded: %s", err),
+		)
+	}
+	if respBody.Allow != nil && !*respBody.Allow {
+		return nil, authorizationRequestDenied(respBody.ErrorDescription)
+	}
+	return &respBody, nil
+}
+
+// decodeOPADecision returns the decision of the Open Policy Agent. Undefined decisions deny the request.
+func decodeOPADecision(resp *http.Response) (*AuthorizationRequestHookResponse, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The authorization request hook target responded with an error.").
+				WithDebugf("Open Policy Agent responded with HTTP status code: %s", resp.Status),
+		)
+	}
+
+	var decision opaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("The authorization request hook target responded with an error.").
+				WithDebugf("Decision of the Open Policy Agent could not be decoded: %s", err),
+		)
+	}
+	if decision.Result == nil {
+		return nil, authorizationRequestDenied("")
+	}
+	if decision.Result.Allow == nil || !*decision.Result.Allow {
+		return nil, authorizationRequestDenied(decision.Result.ErrorDescription)
+	}
+	return decision.Result, nil
+}
+
+// modifyAuthorizeRequest applies the modifications of the authorization request hook, which must still be
+// permitted for the client.
+func (h *Handler) modifyAuthorizeRequest(ctx context.Context, ar fosite.AuthorizeRequester, m *AuthorizationRequestHookResponse) error {
+	if m.RequestedScope != nil {
+		for _, scope := range m.RequestedScope {
+			if !h.c.GetScopeStrategy(ctx)(ar.GetClient().GetScopes(), scope) {
+				return errorsx.WithStack(fosite.ErrInvalidScope.
+					WithHintf("The authorization request hook requested the scope '%s' which is not allowed for the client.", scope))
+			}
+		}
+		ar.SetRequestedScopes(m.RequestedScope)
+	}
+	if m.RequestedAudience != nil {
+		if err := h.r.AudienceStrategy()(ar.GetClient().GetAudience(), m.RequestedAudience); err != nil {
+			return err
+		}
+		ar.SetRequestedAudience(m.RequestedAudience)
+	}
+	if m.ACRValues != nil {
+		ar.GetRequestForm().Set("acr_values", strings.Join(m.ACRValues, " "))
+	}
+	return nil
+}
+
+func authorizationRequestDenied(hint string) error {
+	if hint == "" {
+		hint = "The authorization request was denied by policy."
+	}
+	return errorsx.WithStack(fosite.ErrAccessDenied.WithHint(hint))
+}
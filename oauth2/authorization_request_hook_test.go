@@ -0,0 +1,162 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/httprouterx"
+)
+
+func TestAuthorizationRequestHook(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	r := x.NewRouterAdmin(conf.AdminURL)
+	reg.OAuth2Handler().SetRoutes(r, &httprouterx.RouterPublic{Router: r.Router}, func(h http.Handler) http.Handler {
+		return h
+	})
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	c := &client.Client{
+		ID:            "authorization-request-hook",
+		ResponseTypes: []string{"code"},
+		GrantTypes:    []string{"authorization_code"},
+		RedirectURIs:  []string{"https://client.example/callback"},
+		Scope:         "openid profile email",
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	authorize := func(t *testing.T) *url.URL {
+		query := url.Values{
+			"client_id":     {c.GetID()},
+			"redirect_uri":  {c.GetRedirectURIs()[0]},
+			"response_type": {"code"},
+			"scope":         {"openid profile"},
+			"state":         {"state-state-state"},
+			"acr_values":    {"silver"},
+		}
+		cl := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		res, err := cl.Get(ts.URL + oauth2.AuthPath + "?" + query.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		location, err := res.Location()
+		require.NoError(t, err)
+		return location
+	}
+
+	hook := func(t *testing.T, hookType string, handler func(t *testing.T, input *oauth2.AuthorizationRequestHookRequest) (int, interface{})) {
+		hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var input oauth2.AuthorizationRequestHookRequest
+			if hookType == "opa" {
+				var body struct {
+					Input *oauth2.AuthorizationRequestHookRequest `json:"input"`
+				}
+				body.Input = &input
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			} else {
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+			}
+			assert.Equal(t, c.GetID(), input.Request.ClientID)
+			assert.Equal(t, []string{"openid", "profile"}, input.Request.RequestedScope)
+			assert.Equal(t, []string{"silver"}, input.Request.ACRValues)
+			assert.Equal(t, "Go-http-client/1.1", input.Context.UserAgent)
+
+			status, body := handler(t, &input)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			if body != nil {
+				require.NoError(t, json.NewEncoder(w).Encode(body))
+			}
+		}))
+		t.Cleanup(hs.Close)
+
+		conf.MustSet(ctx, config.KeyAuthorizationRequestHook, map[string]interface{}{"url": hs.URL, "type": hookType})
+		t.Cleanup(func() { conf.MustSet(ctx, config.KeyAuthorizationRequestHook, nil) })
+	}
+
+	loginRequestScope := func(t *testing.T, location *url.URL) []string {
+		require.Equal(t, conf.LoginURL(ctx).Path, location.Path, "%s", location)
+		lr, err := reg.ConsentManager().GetLoginRequest(ctx, location.Query().Get("login_challenge"))
+		require.NoError(t, err)
+		return lr.RequestedScope
+	}
+
+	t.Run("case=webhook admits the request", func(t *testing.T) {
+		hook(t, "webhook", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusNoContent, nil
+		})
+		assert.Equal(t, []string{"openid", "profile"}, loginRequestScope(t, authorize(t)))
+	})
+
+	t.Run("case=webhook denies the request", func(t *testing.T) {
+		hook(t, "webhook", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusForbidden, map[string]string{"error_description": "Not during maintenance."}
+		})
+		location := authorize(t)
+		assert.Equal(t, "access_denied", location.Query().Get("error"))
+		assert.Contains(t, location.Query().Get("error_description"), "Not during maintenance.")
+	})
+
+	t.Run("case=webhook modifies the request", func(t *testing.T) {
+		hook(t, "webhook", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"requested_scope": []string{"openid", "email"}}
+		})
+		assert.Equal(t, []string{"openid", "email"}, loginRequestScope(t, authorize(t)))
+	})
+
+	t.Run("case=webhook may not grant scopes the client is not allowed", func(t *testing.T) {
+		hook(t, "webhook", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"requested_scope": []string{"admin"}}
+		})
+		assert.Equal(t, "invalid_scope", authorize(t).Query().Get("error"))
+	})
+
+	t.Run("case=webhook fails", func(t *testing.T) {
+		hook(t, "webhook", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusInternalServerError, nil
+		})
+		assert.Equal(t, "server_error", authorize(t).Query().Get("error"))
+	})
+
+	t.Run("case=opa allows the request", func(t *testing.T) {
+		hook(t, "opa", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{"allow": true, "requested_scope": []string{"openid"}}}
+		})
+		assert.Equal(t, []string{"openid"}, loginRequestScope(t, authorize(t)))
+	})
+
+	t.Run("case=opa denies the request", func(t *testing.T) {
+		hook(t, "opa", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"result": map[string]interface{}{"allow": false, "error_description": "Denied by policy."}}
+		})
+		location := authorize(t)
+		assert.Equal(t, "access_denied", location.Query().Get("error"))
+		assert.Contains(t, location.Query().Get("error_description"), "Denied by policy.")
+	})
+
+	t.Run("case=opa denies undefined decisions", func(t *testing.T) {
+		hook(t, "opa", func(*testing.T, *oauth2.AuthorizationRequestHookRequest) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{}
+		})
+		assert.Equal(t, "access_denied", authorize(t).Query().Get("error"))
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+const (
+	// ClientAttestationTypeOAuth is evidence of the OAuth 2.0 Attestation-Based Client Authentication draft.
+	ClientAttestationTypeOAuth = "oauth_client_attestation"
+	// ClientAttestationTypeAppleAppAttest is an Apple App Attest assertion.
+	ClientAttestationTypeAppleAppAttest = "apple_app_attest"
+	// ClientAttestationTypePlayIntegrity is a Google Play Integrity token.
+	ClientAttestationTypePlayIntegrity = "play_integrity"
+
+	ClientAttestationHeader    = "OAuth-Client-Attestation"
+	ClientAttestationPoPHeader = "OAuth-Client-Attestation-PoP"
+)
+
+// ClientAttestationHookRequest is the request body sent to the client attestation hook.
+//
+// swagger:ignore
+type ClientAttestationHookRequest struct {
+	// ClientID is the identifier of the OAuth 2.0 client.
+	ClientID string `json:"client_id"`
+	// GrantTypes are the grant types of the token request.
+	GrantTypes []string `json:"grant_types"`
+	// Type is the type of the attestation evidence.
+	Type string `json:"type"`
+	// Attestation is the attestation evidence, for example the client attestation JWT, the App Attest assertion or the
+	// Play Integrity token.
+	Attestation string `json:"attestation"`
+	// PoP is the client attestation proof of possession JWT. It is only set for OAuth client attestations.
+	PoP string `json:"pop,omitempty"`
+	// TokenURL is the URL of the token endpoint, which is the audience of the proof of possession.
+	TokenURL string `json:"token_url"`
+	// Context is the context of the HTTP request.
+	Context AuthorizationRequestHookContext `json:"context"`
+}
+
+// validateClientAttestation requires clients which are configured in oauth2.client_attestation.clients to present
+// attestation evidence, which is validated by the client attestation hook.
+func (h *Handler) validateClientAttestation(ctx context.Context, r *http.Request, ar fosite.AccessRequester) error {
+	requirement := h.c.OAuth2ClientAttestationRequirement(ctx, ar.GetClient().GetID())
+	if requirement == nil {
+		return nil
+	}
+
+	reqBody := ClientAttestationHookRequest{
+		ClientID:   ar.GetClient().GetID(),
+		GrantTypes: ar.GetGrantTypes(),
+		TokenURL:   h.c.OAuth2TokenURL(ctx).String(),
+		Context: AuthorizationRequestHookContext{
+			ClientIP:  x.ClientIP(r),
+			UserAgent: r.UserAgent(),
+		},
+	}
+	if attestation := r.Header.Get(ClientAttestationHeader); attestation != "" {
+		reqBody.Type = ClientAttestationTypeOAuth
+		reqBody.Attestation = attestation
+		reqBody.PoP = r.Header.Get(ClientAttestationPoPHeader)
+	} else {
+		reqBody.Type = ar.GetRequestForm().Get("client_attestation_type")
+		reqBody.Attestation = ar.GetRequestForm().Get("client_attestation")
+	}
+
+	if reqBody.Attestation == "" {
+		return errorsx.WithStack(fosite.ErrInvalidClient.WithHint("The client must present attestation evidence."))
+	} else if !stringslice.Has(requirement.Types, reqBody.Type) {
+		return errorsx.WithStack(fosite.ErrInvalidClient.WithHintf("The client attestation type '%s' is not allowed for this client.", reqBody.Type))
+	} else if reqBody.Type == ClientAttestationTypeOAuth && reqBody.PoP == "" {
+		return errorsx.WithStack(fosite.ErrInvalidClient.WithHintf("The client attestation must be accompanied by the '%s' header.", ClientAttestationPoPHeader))
+	}
+
+	hookConfig := h.c.OAuth2ClientAttestationHookConfig(ctx)
+	if hookConfig == nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The client must present attestation evidence, but no client attestation hook is configured."),
+		)
+	}
+
+	reqBodyBytes, err := json.Marshal(&reqBody)
+	if err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while encoding the client attestation hook.").
+				WithDebugf("Unable to encode the client attestation hook body: %s", err),
+		)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, hookConfig.URL, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while preparing the client attestation hook.").
+				WithDebugf("Unable to prepare the HTTP Request: %s", err),
+		)
+	}
+	if err := hookConfig.Auth.Apply(req.Request); err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while applying the client attestation hook authentication.").
+				WithDebugf("Unable to apply the client attestation hook authentication: %s", err))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := h.r.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while executing the client attestation hook.").
+				WithDebugf("Unable to execute HTTP Request: %s", err),
+		)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		// The attestation evidence is valid
+		return nil
+	case http.StatusForbidden:
+		return errorsx.WithStack(
+			fosite.ErrInvalidClient.
+				WithHint("The client attestation evidence is invalid.").
+				WithDebugf("Client attestation hook responded with HTTP status code: %s", resp.Status),
+		)
+	default:
+		return errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The client attestation hook target responded with an error.").
+				WithDebugf("Client attestation hook responded with HTTP status code: %s", resp.Status),
+		)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+)
+
+func TestClientAttestation(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	public, _ := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	secret := uuid.New().String()
+	c := &hc.Client{
+		Secret:     secret,
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "foo",
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	var hookRequest oauth2.ClientAttestationHookRequest
+	hookStatus := http.StatusNoContent
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&hookRequest))
+		w.WriteHeader(hookStatus)
+	}))
+	t.Cleanup(hook.Close)
+	reg.Config().MustSet(ctx, config.KeyOAuth2ClientAttestationHook, hook.URL)
+
+	requestToken := func(t *testing.T, form url.Values, header http.Header) (*http.Response, gjson.Result) {
+		form.Set("grant_type", "client_credentials")
+		req, err := http.NewRequest(http.MethodPost, public.URL+oauth2.TokenPath, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.GetID(), secret)
+		res, err := public.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	t.Run("case=does not require evidence of other clients", func(t *testing.T) {
+		res, body := requestToken(t, url.Values{}, nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+	})
+
+	reg.Config().MustSet(ctx, config.KeyOAuth2ClientAttestationClients, []map[string]interface{}{
+		{"client_id": c.GetID(), "types": []string{oauth2.ClientAttestationTypeOAuth, oauth2.ClientAttestationTypePlayIntegrity}},
+	})
+
+	t.Run("case=requires evidence", func(t *testing.T) {
+		res, body := requestToken(t, url.Values{}, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=rejects types which are not allowed for the client", func(t *testing.T) {
+		res, body := requestToken(t, url.Values{
+			"client_attestation_type": {oauth2.ClientAttestationTypeAppleAppAttest},
+			"client_attestation":      {"assertion"},
+		}, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=validates OAuth client attestations with the hook", func(t *testing.T) {
+		res, body := requestToken(t, url.Values{}, http.Header{
+			oauth2.ClientAttestationHeader:    {"attestation-jwt"},
+			oauth2.ClientAttestationPoPHeader: {"pop-jwt"},
+		})
+		assert.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, c.GetID(), hookRequest.ClientID)
+		assert.Equal(t, oauth2.ClientAttestationTypeOAuth, hookRequest.Type)
+		assert.Equal(t, "attestation-jwt", hookRequest.Attestation)
+		assert.Equal(t, "pop-jwt", hookRequest.PoP)
+		assert.Equal(t, public.URL+oauth2.TokenPath, hookRequest.TokenURL)
+		assert.Equal(t, []string{"client_credentials"}, hookRequest.GrantTypes)
+	})
+
+	t.Run("case=validates Play Integrity tokens with the hook", func(t *testing.T) {
+		res, body := requestToken(t, url.Values{
+			"client_attestation_type": {oauth2.ClientAttestationTypePlayIntegrity},
+			"client_attestation":      {"integrity-token"},
+		}, nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, oauth2.ClientAttestationTypePlayIntegrity, hookRequest.Type)
+		assert.Equal(t, "integrity-token", hookRequest.Attestation)
+	})
+
+	t.Run("case=rejects evidence which the hook denies", func(t *testing.T) {
+		hookStatus = http.StatusForbidden
+		t.Cleanup(func() { hookStatus = http.StatusNoContent })
+
+		res, body := requestToken(t, url.Values{
+			"client_attestation_type": {oauth2.ClientAttestationTypePlayIntegrity},
+			"client_attestation":      {"integrity-token"},
+		}, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+)
+
+// clientCredentialsTokenCache caches the access tokens issued by the client credentials grant for clients which set
+// client_credentials_grant_access_token_cache_lifespan, so that clients which request a token for every call do not
+// overwhelm the token endpoint. Tokens are cached per issuer, client, granted scope and granted audience.
+type clientCredentialsTokenCache struct {
+	c *ristretto.Cache
+}
+
+// cachedAccessResponse is an access response which is returned again until it expires.
+type cachedAccessResponse struct {
+	accessToken string
+	tokenType   string
+	extra       map[string]interface{}
+	expiresAt   time.Time
+}
+
+// newClientCredentialsTokenCache returns a cache of up to maxEntries access tokens, or nil if maxEntries is not
+// positive.
+func newClientCredentialsTokenCache(maxEntries int) *clientCredentialsTokenCache {
+	if maxEntries <= 0 {
+		return nil
+	}
+	c, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: int64(maxEntries) * 10,
+		MaxCost:     int64(maxEntries),
+		BufferItems: 64,
+		// Each token costs 1, so MaxCost is the maximum number of tokens.
+		IgnoreInternalCost: true,
+	})
+	if err != nil {
+		return nil
+	}
+	return &clientCredentialsTokenCache{c: c}
+}
+
+// clientCredentialsTokenCacheKey returns the cache key of the token request and how long its access token is cached,
+// which is 0 if the request is not cacheable.
+func (h *Handler) clientCredentialsTokenCacheKey(ctx context.Context, ar fosite.AccessRequester) (string, time.Duration) {
+	if h.ccTokens == nil || !ar.GetGrantTypes().ExactOne(string(fosite.GrantTypeClientCredentials)) {
+		return "", 0
+	}
+	c, ok := ar.GetClient().(*client.Client)
+	if !ok {
+		return "", 0
+	}
+	lifespan := c.GetClientCredentialsTokenCacheLifespan()
+	if lifespan <= 0 {
+		return "", 0
+	}
+
+	scopes := append([]string{}, ar.GetGrantedScopes()...)
+	sort.Strings(scopes)
+	audience := append([]string{}, ar.GetGrantedAudience()...)
+	sort.Strings(audience)
+
+	return strings.Join([]string{
+		h.c.IssuerURL(ctx).String(),
+		c.GetID(),
+		strings.Join(scopes, " "),
+		strings.Join(audience, " "),
+	}, "\x00"), lifespan
+}
+
+// cachedClientCredentialsToken returns the cached access response of the key, or nil if there is none. Tokens which
+// were revoked in the meantime are removed from the cache.
+func (h *Handler) cachedClientCredentialsToken(ctx context.Context, key string) fosite.AccessResponder {
+	v, ok := h.ccTokens.c.Get(key)
+	if !ok {
+		return nil
+	}
+	cached := v.(*cachedAccessResponse)
+
+	remaining := cached.expiresAt.Sub(h.r.Clock().Now().UTC())
+	if remaining < time.Second {
+		h.ccTokens.c.Del(key)
+		return nil
+	}
+	if _, _, err := h.r.OAuth2Provider().IntrospectToken(ctx, cached.accessToken, fosite.AccessToken, NewSessionWithCustomClaims(ctx, h.c, "")); err != nil {
+		h.ccTokens.c.Del(key)
+		return nil
+	}
+
+	response := fosite.NewAccessResponse()
+	for k, v := range cached.extra {
+		response.SetExtra(k, v)
+	}
+	response.SetAccessToken(cached.accessToken)
+	response.SetTokenType(cached.tokenType)
+	response.SetExpiresIn(remaining)
+	return response
+}
+
+// cacheClientCredentialsToken caches the access response for the lifespan, but not beyond the expiry of the access
+// token.
+func (h *Handler) cacheClientCredentialsToken(key string, lifespan time.Duration, ar fosite.AccessRequester, response fosite.AccessResponder) {
+	expiresAt := ar.GetSession().GetExpiresAt(fosite.AccessToken)
+	if expiresAt.IsZero() {
+		return
+	}
+	if ttl := expiresAt.Sub(h.r.Clock().Now().UTC()); ttl < lifespan {
+		lifespan = ttl
+	}
+	if lifespan <= 0 {
+		return
+	}
+
+	extra := map[string]interface{}{}
+	for k, v := range response.ToMap() {
+		switch k {
+		case "access_token", "token_type", "expires_in":
+		default:
+			extra[k] = v
+		}
+	}
+
+	h.ccTokens.c.SetWithTTL(key, &cachedAccessResponse{
+		accessToken: response.GetAccessToken(),
+		tokenType:   response.GetTokenType(),
+		extra:       extra,
+		expiresAt:   expiresAt,
+	}, 1, lifespan)
+	h.ccTokens.c.Wait()
+}
@@ -0,0 +1,143 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+)
+
+const (
+	// DerivedTokenPath points to the admin endpoint which translates opaque access tokens to JSON Web Tokens.
+	DerivedTokenPath = "/oauth2/tokens/jwt" // #nosec G101
+
+	// DerivedClaim marks JSON Web Tokens which were derived from an opaque access token.
+	DerivedClaim = "derived"
+
+	// TokenTypeJWT is the token type identifier of derived tokens, as defined in RFC 8693.
+	TokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt" // #nosec G101
+)
+
+// Derive a JSON Web Token from an Opaque Access Token Request
+//
+// swagger:parameters deriveOAuth2Token
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deriveOAuth2Token struct {
+	// The opaque access token.
+	//
+	// required: true
+	// in: formData
+	Token string `json:"token"`
+}
+
+// Derived OAuth 2.0 Access Token
+//
+// swagger:model derivedOAuth2Token
+type derivedOAuth2Token struct {
+	// The JSON Web Token which represents the access token.
+	AccessToken string `json:"access_token"`
+
+	// The type of the derived token, which is always urn:ietf:params:oauth:token-type:jwt.
+	IssuedTokenType string `json:"issued_token_type"`
+
+	// The type of the access token, which is always N_A as the derived token is not meant to be sent by clients.
+	TokenType string `json:"token_type"`
+
+	// The lifetime of the derived token in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// swagger:route POST /admin/oauth2/tokens/jwt oAuth2 deriveOAuth2Token
+//
+// # Derive a JSON Web Token from an Opaque Access Token
+//
+// Translates an active opaque access token into a short-lived JSON Web Token with the same claims, signed with the
+// access token signing key. API gateways can validate the derived token locally, while clients keep using the opaque
+// access token. The derived token carries the `derived` claim and expires after `ttl.derived_access_token` or together
+// with the access token, whichever comes first. It is not stored and can not be revoked on its own.
+//
+//	Consumes:
+//	- application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: derivedOAuth2Token
+//	  default: errorOAuth2
+func (h *Handler) deriveOAuth2Token(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse HTTP body, make sure to send a properly formatted form request body.").WithDebug(err.Error())))
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The POST body parameter 'token' must be set.")))
+		return
+	}
+
+	tt, ar, err := h.r.OAuth2Provider().IntrospectToken(ctx, token, fosite.AccessToken, NewSessionWithCustomClaims(ctx, h.c, ""))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInactiveToken.WithHint("An introspection strategy indicated that the token is inactive.").WithDebug(err.Error())))
+		return
+	} else if tt != fosite.AccessToken {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Only access tokens can be derived.")))
+		return
+	}
+
+	session, ok := ar.GetSession().(*Session)
+	if !ok {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrServerError.WithHint("Expected session to be of type *Session, but got another type.").WithDebug(fmt.Sprintf("Got type %s", reflect.TypeOf(ar.GetSession())))))
+		return
+	}
+	session = session.Clone().(*Session)
+
+	now := time.Now().UTC()
+	expiresAt := session.GetExpiresAt(fosite.AccessToken)
+	if expiresAt.IsZero() {
+		expiresAt = ar.GetRequestedAt().Add(h.c.GetAccessTokenLifespan(ctx))
+	}
+	if maxExpiresAt := now.Add(h.c.GetDerivedAccessTokenLifespan(ctx)); expiresAt.After(maxExpiresAt) {
+		expiresAt = maxExpiresAt
+	}
+
+	kid, err := h.r.AccessTokenJWTStrategy().GetPublicKeyID(ctx)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	session.KID = kid
+
+	claims := session.GetJWTClaims().
+		With(expiresAt, ar.GetGrantedScopes(), ar.GetGrantedAudience()).
+		WithDefaults(now, h.c.IssuerURL(ctx).String()).
+		WithScopeField(h.c.GetJWTScopeField(ctx)).
+		ToMapClaims()
+	claims[DerivedClaim] = true
+
+	derived, _, err := h.r.AccessTokenJWTStrategy().Generate(ctx, claims, session.GetJWTHeader())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, &derivedOAuth2Token{
+		AccessToken:     derived,
+		IssuedTokenType: TokenTypeJWT,
+		TokenType:       "N_A",
+		ExpiresIn:       int64(expiresAt.Sub(now).Round(time.Second) / time.Second),
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+)
+
+func TestDeriveOAuth2Token(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	public, admin := testhelpers.NewOAuth2Server(ctx, t, reg)
+	reg.Config().MustSet(ctx, config.KeyAccessTokenLifespan, time.Hour)
+	reg.Config().MustSet(ctx, config.KeyDerivedAccessTokenLifespan, time.Minute)
+
+	secret := uuid.New().String()
+	c := &hc.Client{
+		Secret:     secret,
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "foo bar",
+		Audience:   []string{"https://api.example.org"},
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	token, err := (&clientcredentials.Config{
+		ClientID:       c.GetID(),
+		ClientSecret:   secret,
+		TokenURL:       public.URL + oauth2.TokenPath,
+		Scopes:         []string{"foo"},
+		EndpointParams: url.Values{"audience": {"https://api.example.org"}},
+		AuthStyle:      goauth2.AuthStyleInHeader,
+	}).Token(ctx)
+	require.NoError(t, err)
+
+	derive := func(t *testing.T, token string) (*http.Response, gjson.Result) {
+		res, err := admin.Client().PostForm(admin.URL+"/admin"+oauth2.DerivedTokenPath, url.Values{"token": {token}})
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	t.Run("case=derives a signed JSON Web Token", func(t *testing.T) {
+		res, body := derive(t, token.AccessToken)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, oauth2.TokenTypeJWT, body.Get("issued_token_type").String())
+		assert.Equal(t, "N_A", body.Get("token_type").String())
+		assert.InDelta(t, 60, body.Get("expires_in").Int(), 2)
+
+		parsed, err := jwt.ParseSigned(body.Get("access_token").String())
+		require.NoError(t, err)
+		key, err := reg.AccessTokenJWTStrategy().GetPublicKey(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, key.KeyID, parsed.Headers[0].KeyID)
+
+		claims := map[string]interface{}{}
+		require.NoError(t, parsed.Claims(key.Key, &claims))
+		assert.Equal(t, true, claims[oauth2.DerivedClaim])
+		assert.Equal(t, c.GetID(), claims["sub"])
+		assert.Equal(t, c.GetID(), claims["client_id"])
+		assert.Equal(t, public.URL, claims["iss"])
+		assert.EqualValues(t, []interface{}{"foo"}, claims["scp"])
+		assert.EqualValues(t, []interface{}{"https://api.example.org"}, claims["aud"])
+		assert.InDelta(t, time.Now().Add(time.Minute).Unix(), claims["exp"], 2)
+	})
+
+	t.Run("case=rejects inactive tokens", func(t *testing.T) {
+		res, body := derive(t, "not-a-token")
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+	})
+
+	t.Run("case=requires a token", func(t *testing.T) {
+		res, body := derive(t, "")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+	})
+}
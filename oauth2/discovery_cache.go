@@ -0,0 +1,75 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+)
+
+// discoveryDocument is the prebuilt representation of an OpenID Connect Discovery document.
+type discoveryDocument struct {
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// discoveryCache caches the OpenID Connect Discovery documents by issuer, because every tenant has its own.
+type discoveryCache struct {
+	mu        sync.RWMutex
+	documents map[string]*discoveryDocument
+}
+
+func newDiscoveryCache() *discoveryCache {
+	return &discoveryCache{documents: map[string]*discoveryDocument{}}
+}
+
+// get returns the cached document of the issuer, or builds and caches it for ttl if it is missing or expired. A
+// ttl of 0 disables the cache.
+func (c *discoveryCache) get(issuer string, ttl time.Duration, build func() (map[string]interface{}, error)) (*discoveryDocument, error) {
+	c.mu.RLock()
+	doc, ok := c.documents[issuer]
+	c.mu.RUnlock()
+	if ok && ttl > 0 && time.Now().Before(doc.expires) {
+		return doc, nil
+	}
+
+	fields, err := build()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	etag, err := x.ETag(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	doc = &discoveryDocument{body: body, etag: etag, expires: time.Now().Add(ttl)}
+	if ttl > 0 {
+		c.mu.Lock()
+		c.documents[issuer] = doc
+		c.mu.Unlock()
+	}
+	return doc, nil
+}
+
+// structToMap returns the fields of the JSON representation of v.
+func structToMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return m, nil
+}
@@ -0,0 +1,184 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/hydra/v2/driver/config"
+)
+
+// errorFields are the fields of an OAuth 2.0 error response, either the parameters of a redirect or the members of a
+// JSON object.
+type errorFields interface {
+	Get(key string) string
+	Set(key, value string)
+	Del(key string)
+}
+
+// jsonErrorFields are the members of a JSON error response.
+type jsonErrorFields map[string]interface{}
+
+func (f jsonErrorFields) Get(key string) string {
+	s, _ := f[key].(string)
+	return s
+}
+
+func (f jsonErrorFields) Set(key, value string) {
+	f[key] = value
+}
+
+func (f jsonErrorFields) Del(key string) {
+	delete(f, key)
+}
+
+// customizeErrors customizes the error responses of next as configured in `oauth2.error_responses`.
+func (h *Handler) customizeErrors(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responses := h.c.OAuth2ErrorResponses(r.Context())
+		if len(responses) == 0 {
+			next(w, r)
+			return
+		}
+
+		rw := &errorResponseWriter{ResponseWriter: w, customize: func(fields errorFields) bool {
+			return h.customizeErrorResponse(r, responses, fields)
+		}}
+		next(rw, r)
+		rw.flush()
+	}
+}
+
+// customizeErrorsHandle customizes the error responses of next as configured in `oauth2.error_responses`.
+func (h *Handler) customizeErrorsHandle(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		h.customizeErrors(func(w http.ResponseWriter, r *http.Request) { next(w, r, ps) })(w, r)
+	}
+}
+
+// customizeErrorResponse replaces the fields of the error response by the customization of its error for the client
+// of the request. It reports whether the response was customized.
+func (h *Handler) customizeErrorResponse(r *http.Request, responses config.ErrorResponses, fields errorFields) bool {
+	code := fields.Get("error")
+	if code == "" {
+		return false
+	}
+	c := responses.Find(errorResponseClientID(r), code)
+	if c == nil {
+		return false
+	}
+
+	if c.Response.Error != "" {
+		fields.Set("error", c.Response.Error)
+	}
+	if c.Response.ErrorDescription != "" {
+		fields.Set("error_description", h.localizer(r).text(fields.Get("error"), c.Response.ErrorDescription))
+		// The hint and debug information are part of the replaced description.
+		fields.Del("error_hint")
+		fields.Del("error_debug")
+	}
+	if c.Response.ErrorURI != "" {
+		fields.Set("error_uri", c.Response.ErrorURI)
+	}
+	return true
+}
+
+// errorResponseClientID returns the ID of the client which sent the request, if known.
+func errorResponseClientID(r *http.Request) string {
+	id := r.Form.Get("client_id")
+	if id == "" {
+		id = r.URL.Query().Get("client_id")
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		if unescaped, err := url.QueryUnescape(user); err == nil {
+			id = unescaped
+		}
+	}
+	return id
+}
+
+// errorResponseWriter customizes the error of redirects when their header is written, and buffers JSON error
+// responses until flush customizes and writes them.
+type errorResponseWriter struct {
+	http.ResponseWriter
+	customize func(errorFields) bool
+	code      int
+	body      bytes.Buffer
+}
+
+func (w *errorResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+		if code >= 300 && code < 400 {
+			w.customizeLocation()
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *errorResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffered() {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorResponseWriter) Flush() {
+	if w.buffered() {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *errorResponseWriter) buffered() bool {
+	return w.code >= 400 && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+// customizeLocation customizes the error in the query or the fragment of the redirect URL.
+func (w *errorResponseWriter) customizeLocation() {
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		return
+	}
+
+	if query := location.Query(); query.Get("error") != "" {
+		if w.customize(query) {
+			location.RawQuery = query.Encode()
+			w.Header().Set("Location", location.String())
+		}
+	} else if fragment, err := url.ParseQuery(location.Fragment); err == nil && fragment.Get("error") != "" {
+		if w.customize(fragment) {
+			location.Fragment = ""
+			w.Header().Set("Location", location.String()+"#"+fragment.Encode())
+		}
+	}
+}
+
+// flush customizes and writes the buffered JSON error response.
+func (w *errorResponseWriter) flush() {
+	if w.body.Len() == 0 {
+		return
+	}
+
+	body := w.body.Bytes()
+	fields := jsonErrorFields{}
+	if err := json.Unmarshal(body, &fields); err == nil && w.customize(fields) {
+		if customized, err := json.Marshal(fields); err == nil {
+			body = customized
+		}
+	}
+	// ignoring the error because the connection is broken when it happens
+	_, _ = w.ResponseWriter.Write(body)
+}
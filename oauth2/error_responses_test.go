@@ -0,0 +1,134 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+)
+
+func TestErrorResponses(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	public, _ := testhelpers.NewOAuth2Server(ctx, t, reg)
+	server := httptest.NewServer(public.Config.Handler)
+	t.Cleanup(server.Close)
+
+	newClient := func(t *testing.T) *hc.Client {
+		c := &hc.Client{
+			Secret:        uuid.New().String(),
+			GrantTypes:    []string{"client_credentials", "authorization_code"},
+			ResponseTypes: []string{"code"},
+			RedirectURIs:  []string{"https://client.example.com/callback"},
+			Scope:         "openid",
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		return c
+	}
+	partner, other := newClient(t), newClient(t)
+
+	reg.Config().MustSet(ctx, config.KeyOAuth2ErrorResponses, []map[string]interface{}{
+		{"client_id": "*", "error": "invalid_client", "response": map[string]interface{}{
+			"error_description": "Client authentication failed.",
+			"error_uri":         "https://errors.example.com/invalid_client",
+		}},
+		{"client_id": partner.GetID(), "error": "invalid_client", "response": map[string]interface{}{
+			"error": "unauthorized_client",
+		}},
+		{"client_id": "*", "error": "invalid_scope", "response": map[string]interface{}{
+			"error_uri": "https://errors.example.com/invalid_scope",
+		}},
+	})
+	t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2ErrorResponses, nil) })
+
+	requestToken := func(t *testing.T, c *hc.Client, header http.Header) (*http.Response, gjson.Result) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+oauth2.TokenPath, strings.NewReader(url.Values{"grant_type": {"client_credentials"}}.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k := range header {
+			req.Header.Set(k, header.Get(k))
+		}
+		req.SetBasicAuth(c.GetID(), "wrong-secret")
+		res, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	t.Run("case=customizes the error for all clients", func(t *testing.T) {
+		res, body := requestToken(t, other, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_client", body.Get("error").String(), body.Raw)
+		assert.Equal(t, "Client authentication failed.", body.Get("error_description").String(), body.Raw)
+		assert.Equal(t, "https://errors.example.com/invalid_client", body.Get("error_uri").String(), body.Raw)
+	})
+
+	t.Run("case=customizes the error for a client", func(t *testing.T) {
+		res, body := requestToken(t, partner, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "unauthorized_client", body.Get("error").String(), body.Raw)
+		assert.False(t, body.Get("error_uri").Exists(), body.Raw)
+	})
+
+	t.Run("case=translates the customized description", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyI18nMessages, map[string]interface{}{
+			"de": map[string]interface{}{"invalid_client": "Die Authentifizierung des Clients ist fehlgeschlagen."},
+		})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyI18nMessages, nil) })
+
+		res, body := requestToken(t, other, http.Header{"Accept-Language": {"de"}})
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, body.Raw)
+		assert.Equal(t, "Die Authentifizierung des Clients ist fehlgeschlagen.", body.Get("error_description").String(), body.Raw)
+	})
+
+	t.Run("case=customizes the error of authorization redirects", func(t *testing.T) {
+		hc := server.Client()
+		hc.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		res, err := hc.Get(server.URL + oauth2.AuthPath + "?" + url.Values{
+			"client_id":     {other.GetID()},
+			"response_type": {"code"},
+			"redirect_uri":  {"https://client.example.com/callback"},
+			"scope":         {"openid unknown"},
+			"state":         {uuid.New().String()},
+		}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_scope", location.Query().Get("error"), location.String())
+		assert.Equal(t, "https://errors.example.com/invalid_scope", location.Query().Get("error_uri"), location.String())
+		assert.NotEmpty(t, location.Query().Get("state"), location.String())
+	})
+
+	t.Run("case=does not customize other errors", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+oauth2.TokenPath, strings.NewReader(url.Values{"grant_type": {"password"}}.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(other.GetID(), other.Secret)
+		res, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body := gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+		assert.NotEqual(t, "invalid_client", body.Get("error").String(), body.Raw)
+		assert.False(t, body.Get("error_uri").Exists(), body.Raw)
+	})
+}
@@ -0,0 +1,365 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/x/errorsx"
+)
+
+// FallbackUIIdentityRequest is the request body sent to the identity hook of the fallback UI.
+//
+// swagger:ignore
+type FallbackUIIdentityRequest struct {
+	// Username is the username entered at the login page.
+	Username string `json:"username"`
+	// Password is the password entered at the login page.
+	Password string `json:"password"`
+	// ClientID is the identifier of the OAuth 2.0 client which requested the login.
+	ClientID string `json:"client_id"`
+}
+
+// FallbackUIIdentityResponse is the response body received from the identity hook of the fallback UI if the
+// credentials are valid.
+//
+// swagger:ignore
+type FallbackUIIdentityResponse struct {
+	// Subject is the subject of the user.
+	Subject string `json:"subject"`
+	// Claims are added to the ID token.
+	Claims map[string]interface{} `json:"claims"`
+}
+
+// fallbackUILoginContext is the context of login requests accepted by the fallback UI, which carries the claims of
+// the user to the consent page.
+type fallbackUILoginContext struct {
+	Claims map[string]interface{} `json:"claims"`
+}
+
+var fallbackUILoginTemplate = template.Must(template.New("fallback_ui_login").Parse(`<html lang="{{ .Lang }}">
+<head>
+	<title>{{ .Title }}</title>
+</head>
+<body>
+<h1>{{ .Heading }}</h1>
+{{ if .Error }}<p>{{ .Error }}</p>{{ end }}
+<form method="post">
+	<input type="hidden" name="login_challenge" value="{{ .Challenge }}">
+	<p><label>{{ .UsernameLabel }} <input type="text" name="username" value="{{ .Username }}" autocomplete="username" required autofocus></label></p>
+	<p><label>{{ .PasswordLabel }} <input type="password" name="password" autocomplete="current-password" required></label></p>
+	<p><label><input type="checkbox" name="remember" value="true"> {{ .RememberLabel }}</label></p>
+	<p><button type="submit">{{ .Submit }}</button></p>
+</form>
+</body>
+</html>`))
+
+var fallbackUIConsentTemplate = template.Must(template.New("fallback_ui_consent").Parse(`<html lang="{{ .Lang }}">
+<head>
+	<title>{{ .Title }}</title>
+</head>
+<body>
+<h1>{{ .Heading }}</h1>
+{{ if .Scopes }}<p>{{ .ScopesLabel }}</p>
+<ul>
+	{{ range .Scopes }}<li>{{ . }}</li>
+	{{ end }}
+</ul>{{ end }}
+<form method="post">
+	<input type="hidden" name="consent_challenge" value="{{ .Challenge }}">
+	<button type="submit" name="action" value="allow">{{ .Allow }}</button>
+	<button type="submit" name="action" value="deny">{{ .Deny }}</button>
+</form>
+</body>
+</html>`))
+
+// The redirect back to the authorization endpoint is a page instead of a Location header, because browsers apply the
+// form-action directive of the Content-Security-Policy to all redirects following a form submission, which includes
+// the redirect to the client.
+var fallbackUIRedirectTemplate = template.Must(template.New("fallback_ui_redirect").Parse(`<html lang="{{ .Lang }}">
+<head>
+	<meta http-equiv="refresh" content="0; url={{ .RedirectTo }}">
+	<title>{{ .Title }}</title>
+</head>
+<body>
+<p><a href="{{ .RedirectTo }}">{{ .Title }}</a></p>
+</body>
+</html>`))
+
+// fallbackUI serves the handle of the fallback UI if it is enabled, and the fallback page otherwise.
+func (h *Handler) fallbackUI(ui, fallback httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !h.c.OAuth2FallbackUIEnabled(r.Context()) {
+			fallback(w, r, ps)
+			return
+		}
+		ui(w, r, ps)
+	}
+}
+
+func (h *Handler) fallbackUILogin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	if h.c.OAuth2FallbackUIIdentityHookConfig(ctx) == nil {
+		h.fallbackHandler("", "", "", http.StatusOK, config.KeyOAuth2FallbackUIIdentityHook)(w, r, ps)
+		return
+	}
+
+	challenge := r.URL.Query().Get("login_challenge")
+	if r.Method == http.MethodPost {
+		challenge = r.PostFormValue("login_challenge")
+	}
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Parameter 'login_challenge' is not defined but should have been.`)))
+		return
+	}
+
+	lr, err := h.r.ConsentManager().GetLoginRequest(ctx, challenge)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	if lr.Skip {
+		h.acceptFallbackUILogin(w, r, challenge, &flow.HandledLoginRequest{Subject: lr.Subject})
+		return
+	}
+
+	l := h.localizer(r)
+	var message string
+	if r.Method == http.MethodPost {
+		identity, err := h.checkFallbackUIIdentity(ctx, &FallbackUIIdentityRequest{
+			Username: r.PostFormValue("username"),
+			Password: r.PostFormValue("password"),
+			ClientID: lr.Client.GetID(),
+		})
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		if identity != nil {
+			loginContext, err := json.Marshal(&fallbackUILoginContext{Claims: identity.Claims})
+			if err != nil {
+				h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+				return
+			}
+			h.acceptFallbackUILogin(w, r, challenge, &flow.HandledLoginRequest{
+				Subject:  identity.Subject,
+				Remember: r.PostFormValue("remember") == "true",
+				Context:  loginContext,
+			})
+			return
+		}
+		message = l.text("fallback_ui.login.invalid_credentials", "The username or password is incorrect.")
+	}
+
+	h.writeFallbackUIPage(w, r, fallbackUILoginTemplate, map[string]interface{}{
+		"Lang":          l.lang.String(),
+		"Title":         l.text("fallback_ui.login.title", "Sign in"),
+		"Heading":       l.html("fallback_ui.login.heading", "Sign in to continue to %s", fallbackUIClientName(lr.Client)),
+		"Error":         message,
+		"Challenge":     challenge,
+		"Username":      r.PostFormValue("username"),
+		"UsernameLabel": l.text("fallback_ui.login.username", "Username"),
+		"PasswordLabel": l.text("fallback_ui.login.password", "Password"),
+		"RememberLabel": l.text("fallback_ui.login.remember", "Remember me"),
+		"Submit":        l.text("fallback_ui.login.submit", "Sign in"),
+	})
+}
+
+func (h *Handler) fallbackUIConsent(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	challenge := r.URL.Query().Get("consent_challenge")
+	if r.Method == http.MethodPost {
+		challenge = r.PostFormValue("consent_challenge")
+	}
+	if challenge == "" {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint(`Parameter 'consent_challenge' is not defined but should have been.`)))
+		return
+	}
+
+	cr, err := h.r.ConsentManager().GetConsentRequest(ctx, challenge)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.PostFormValue("action") == "deny":
+		redirectTo, err := consent.RejectConsentRequest(r, h.r, challenge, &flow.RequestDeniedError{
+			Name:        fosite.ErrAccessDenied.ErrorField,
+			Description: "The resource owner denied the request.",
+			Code:        fosite.ErrAccessDenied.CodeField,
+		})
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		h.redirectFallbackUI(w, r, redirectTo)
+		return
+	case r.Method == http.MethodPost && r.PostFormValue("action") == "allow", cr.Skip:
+		var loginContext fallbackUILoginContext
+		if len(cr.Context) > 0 {
+			// The context was set by another login provider if it does not decode.
+			_ = json.Unmarshal(cr.Context, &loginContext)
+		}
+		session := flow.NewConsentRequestSessionData()
+		if loginContext.Claims != nil {
+			session.IDToken = loginContext.Claims
+		}
+		redirectTo, err := consent.AcceptConsentRequest(r, h.r, challenge, &flow.AcceptOAuth2ConsentRequest{
+			GrantedScope:    cr.RequestedScope,
+			GrantedAudience: cr.RequestedAudience,
+			Session:         session,
+		})
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		h.redirectFallbackUI(w, r, redirectTo)
+		return
+	}
+
+	l := h.localizer(r)
+	h.writeFallbackUIPage(w, r, fallbackUIConsentTemplate, map[string]interface{}{
+		"Lang":        l.lang.String(),
+		"Title":       l.text("fallback_ui.consent.title", "Authorize"),
+		"Heading":     l.html("fallback_ui.consent.heading", "%s wants to access your account", fallbackUIClientName(cr.Client)),
+		"Challenge":   challenge,
+		"ScopesLabel": l.text("fallback_ui.consent.scopes", "It requests the following permissions:"),
+		"Scopes":      []string(cr.RequestedScope),
+		"Allow":       l.text("fallback_ui.consent.allow", "Allow"),
+		"Deny":        l.text("fallback_ui.consent.deny", "Deny"),
+	})
+}
+
+func (h *Handler) acceptFallbackUILogin(w http.ResponseWriter, r *http.Request, challenge string, handledLoginRequest *flow.HandledLoginRequest) {
+	redirectTo, err := consent.AcceptLoginRequest(r, h.r, challenge, handledLoginRequest)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	h.redirectFallbackUI(w, r, redirectTo)
+}
+
+func (h *Handler) redirectFallbackUI(w http.ResponseWriter, r *http.Request, redirectTo *flow.OAuth2RedirectTo) {
+	l := h.localizer(r)
+	h.writeFallbackUIPage(w, r, fallbackUIRedirectTemplate, map[string]interface{}{
+		"Lang":       l.lang.String(),
+		"Title":      l.text("fallback_ui.redirect.title", "Continue"),
+		"RedirectTo": redirectTo.RedirectTo,
+	})
+}
+
+func (h *Handler) writeFallbackUIPage(w http.ResponseWriter, r *http.Request, t *template.Template, data map[string]interface{}) {
+	if _, err := h.writeSecurityHeaders(w, r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		h.r.Logger().WithRequest(r).WithError(err).Error("Unable to render the fallback UI.")
+	}
+}
+
+// checkFallbackUIIdentity checks the credentials entered at the login page with the identity hook. It returns nil if
+// the credentials are invalid.
+func (h *Handler) checkFallbackUIIdentity(ctx context.Context, identity *FallbackUIIdentityRequest) (*FallbackUIIdentityResponse, error) {
+	hookConfig := h.c.OAuth2FallbackUIIdentityHookConfig(ctx)
+	if hookConfig == nil {
+		return nil, errorsx.WithStack(fosite.ErrServerError.WithHint("The identity hook of the fallback UI is not configured."))
+	}
+
+	reqBodyBytes, err := json.Marshal(identity)
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while encoding the identity hook.").
+				WithDebugf("Unable to encode the identity hook body: %s", err),
+		)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, hookConfig.URL, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while preparing the identity hook.").
+				WithDebugf("Unable to prepare the HTTP Request: %s", err),
+		)
+	}
+	if err := hookConfig.Auth.Apply(req.Request); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while applying the identity hook authentication.").
+				WithDebugf("Unable to apply the identity hook authentication: %s", err))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := h.r.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while executing the identity hook.").
+				WithDebugf("Unable to execute HTTP Request: %s", err),
+		)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The credentials are valid
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, nil
+	default:
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The identity hook target responded with an error.").
+				WithDebugf("Identity hook responded with HTTP status code: %s", resp.Status),
+		)
+	}
+
+	var respBody FallbackUIIdentityResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("The identity hook target responded with an error.").
+				WithDebugf("Response from identity hook could not be decoded: %s", err),
+		)
+	}
+	if respBody.Subject == "" {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The identity hook target responded with an error.").
+				WithDebug("Response from identity hook does not contain the subject."),
+		)
+	}
+	return &respBody, nil
+}
+
+// fallbackUIClientName returns the escaped name of the client shown by the fallback UI.
+func fallbackUIClientName(c *client.Client) string {
+	if c.Name != "" {
+		return template.HTMLEscapeString(c.Name)
+	}
+	return template.HTMLEscapeString(c.GetID())
+}
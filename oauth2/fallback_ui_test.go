@@ -0,0 +1,175 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	goauth2 "golang.org/x/oauth2"
+
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+)
+
+func TestFallbackUI(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	public, _ := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var identity oauth2.FallbackUIIdentityRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&identity))
+		if identity.Username != "alice" || identity.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(&oauth2.FallbackUIIdentityResponse{
+			Subject: "alice-subject",
+			Claims:  map[string]interface{}{"email": "alice@example.com"},
+		}))
+	}))
+	t.Cleanup(hook.Close)
+
+	reg.Config().MustSet(ctx, config.KeyOAuth2FallbackUIEnabled, true)
+	reg.Config().MustSet(ctx, config.KeyOAuth2FallbackUIIdentityHook, hook.URL)
+
+	secret := uuid.New().String()
+	c := &hc.Client{
+		Name:          "Example App",
+		Secret:        secret,
+		GrantTypes:    []string{"authorization_code"},
+		ResponseTypes: []string{"code"},
+		RedirectURIs:  []string{"https://client.example.com/callback"},
+		Scope:         "openid",
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+	conf := &goauth2.Config{
+		ClientID:     c.GetID(),
+		ClientSecret: secret,
+		Endpoint:     goauth2.Endpoint{AuthURL: public.URL + oauth2.AuthPath, TokenURL: public.URL + oauth2.TokenPath},
+		RedirectURL:  c.RedirectURIs[0],
+		Scopes:       []string{"openid"},
+	}
+
+	newBrowser := func(t *testing.T) *http.Client {
+		return &http.Client{
+			Jar: testhelpers.NewEmptyCookieJar(t),
+			CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+				if req.URL.Host == "client.example.com" {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+		}
+	}
+	refresh := regexp.MustCompile(`content="0; url=([^"]+)"`)
+	// submit posts the form of the page and follows the redirect page of the response.
+	submit := func(t *testing.T, browser *http.Client, page *http.Response, form url.Values) (*http.Response, string) {
+		res, err := browser.PostForm(page.Request.URL.String(), form)
+		require.NoError(t, err)
+		body := string(ioutilx.MustReadAll(res.Body))
+		require.NoError(t, res.Body.Close())
+
+		match := refresh.FindStringSubmatch(body)
+		if match == nil {
+			return res, body
+		}
+		res, err = browser.Get(html.UnescapeString(match[1]))
+		require.NoError(t, err)
+		body = string(ioutilx.MustReadAll(res.Body))
+		require.NoError(t, res.Body.Close())
+		return res, body
+	}
+	start := func(t *testing.T, browser *http.Client) (*http.Response, string) {
+		res, err := browser.Get(conf.AuthCodeURL(uuid.New().String()))
+		require.NoError(t, err)
+		body := string(ioutilx.MustReadAll(res.Body))
+		require.NoError(t, res.Body.Close())
+		return res, body
+	}
+
+	t.Run("case=logs in and grants consent", func(t *testing.T) {
+		browser := newBrowser(t)
+		login, body := start(t, browser)
+		require.Equal(t, oauth2.DefaultLoginPath, login.Request.URL.Path)
+		assert.Contains(t, body, "Sign in to continue to Example App")
+
+		consent, body := submit(t, browser, login, url.Values{
+			"login_challenge": {login.Request.URL.Query().Get("login_challenge")},
+			"username":        {"alice"},
+			"password":        {"secret"},
+		})
+		require.Equal(t, oauth2.DefaultConsentPath, consent.Request.URL.Path, body)
+		assert.Contains(t, body, "Example App wants to access your account")
+		assert.Contains(t, body, "<li>openid</li>")
+
+		callback, _ := submit(t, browser, consent, url.Values{
+			"consent_challenge": {consent.Request.URL.Query().Get("consent_challenge")},
+			"action":            {"allow"},
+		})
+		location, err := callback.Location()
+		require.NoError(t, err)
+		require.NotEmpty(t, location.Query().Get("code"), location)
+
+		token, err := conf.Exchange(ctx, location.Query().Get("code"))
+		require.NoError(t, err)
+		claims := testhelpers.DecodeIDToken(t, token)
+		assert.Equal(t, "alice-subject", claims.Get("sub").String(), claims.Raw)
+		assert.Equal(t, "alice@example.com", claims.Get("email").String(), claims.Raw)
+	})
+
+	t.Run("case=rejects invalid credentials", func(t *testing.T) {
+		browser := newBrowser(t)
+		login, _ := start(t, browser)
+
+		res, body := submit(t, browser, login, url.Values{
+			"login_challenge": {login.Request.URL.Query().Get("login_challenge")},
+			"username":        {"alice"},
+			"password":        {"wrong"},
+		})
+		assert.Equal(t, oauth2.DefaultLoginPath, res.Request.URL.Path)
+		assert.Contains(t, body, "The username or password is incorrect.")
+	})
+
+	t.Run("case=denies consent", func(t *testing.T) {
+		browser := newBrowser(t)
+		login, _ := start(t, browser)
+		consent, _ := submit(t, browser, login, url.Values{
+			"login_challenge": {login.Request.URL.Query().Get("login_challenge")},
+			"username":        {"alice"},
+			"password":        {"secret"},
+		})
+
+		callback, _ := submit(t, browser, consent, url.Values{
+			"consent_challenge": {consent.Request.URL.Query().Get("consent_challenge")},
+			"action":            {"deny"},
+		})
+		location, err := callback.Location()
+		require.NoError(t, err)
+		assert.Equal(t, "access_denied", location.Query().Get("error"), location)
+	})
+
+	t.Run("case=shows the fallback page if disabled", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOAuth2FallbackUIEnabled, false)
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2FallbackUIEnabled, true) })
+
+		_, body := start(t, newBrowser(t))
+		assert.Contains(t, body, config.KeyLoginURL)
+	})
+}
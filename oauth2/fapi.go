@@ -0,0 +1,173 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/stringslice"
+)
+
+const FAPIReportPath = "/oauth2/fapi/report"
+
+// fapiSigningAlgorithms are the signing algorithms permitted by FAPI 1.0 Advanced.
+var fapiSigningAlgorithms = []string{"PS256", "ES256"}
+
+// FAPI 1.0 Advanced Compliance Report
+//
+// swagger:model fapiReport
+type fapiReport struct {
+	// Enabled is true if the FAPI 1.0 Advanced requirements are enforced.
+	Enabled bool `json:"enabled"`
+
+	// Server lists the requirements which Ory Hydra does not meet with the current configuration.
+	Server []string `json:"server"`
+
+	// Clients lists the clients which do not meet the requirements.
+	Clients []fapiClientReport `json:"clients"`
+}
+
+// FAPI 1.0 Advanced Client Compliance Report
+//
+// swagger:model fapiClientReport
+type fapiClientReport struct {
+	ClientID   string   `json:"client_id"`
+	ClientName string   `json:"client_name,omitempty"`
+	Violations []string `json:"violations"`
+}
+
+// fapiClientViolations returns the FAPI 1.0 Advanced requirements which the client does not meet.
+func fapiClientViolations(c *client.Client) (violations []string) {
+	if c.GetTokenEndpointAuthMethod() != "private_key_jwt" {
+		violations = append(violations, fmt.Sprintf("token_endpoint_auth_method must be private_key_jwt but is %s", c.GetTokenEndpointAuthMethod()))
+	}
+	if !stringslice.Has(fapiSigningAlgorithms, c.GetTokenEndpointAuthSigningAlgorithm()) {
+		violations = append(violations, fmt.Sprintf("token_endpoint_auth_signing_alg must be PS256 or ES256 but is %s", c.GetTokenEndpointAuthSigningAlgorithm()))
+	}
+	if !stringslice.Has(fapiSigningAlgorithms, c.GetRequestObjectSigningAlgorithm()) {
+		violations = append(violations, fmt.Sprintf("request_object_signing_alg must be PS256 or ES256 but is %q", c.GetRequestObjectSigningAlgorithm()))
+	}
+	if c.UserinfoSignedResponseAlg != "" && c.UserinfoSignedResponseAlg != "none" && !stringslice.Has(fapiSigningAlgorithms, c.UserinfoSignedResponseAlg) {
+		violations = append(violations, fmt.Sprintf("userinfo_signed_response_alg must be PS256 or ES256 but is %s", c.UserinfoSignedResponseAlg))
+	}
+	// Response type code would require JWT Secured Authorization Responses (JARM), which are not supported.
+	for _, rt := range c.GetResponseTypes() {
+		if !fosite.Arguments(strings.Fields(rt)).Matches("code", "id_token") {
+			violations = append(violations, fmt.Sprintf("response type %q is not permitted, only code id_token is", rt))
+		}
+	}
+	for _, uri := range c.GetRedirectURIs() {
+		if u, err := url.Parse(uri); err != nil || u.Scheme != "https" {
+			violations = append(violations, fmt.Sprintf("redirect URI %q must use https", uri))
+		}
+	}
+	return violations
+}
+
+// validateFAPIClient returns an error if the FAPI 1.0 Advanced requirements are enforced and the client does not
+// meet them.
+func (h *Handler) validateFAPIClient(ctx context.Context, c fosite.Client) error {
+	if !h.c.OAuth2FAPIEnabled(ctx) {
+		return nil
+	}
+	cl, ok := c.(*client.Client)
+	if !ok {
+		return nil
+	}
+	if violations := fapiClientViolations(cl); len(violations) > 0 {
+		return fosite.ErrUnauthorizedClient.WithHintf("The client does not meet the FAPI 1.0 Advanced requirements: %s.", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// validateFAPIAuthorizeRequest returns an error if the FAPI 1.0 Advanced requirements are enforced and the
+// authorization request does not meet them.
+func (h *Handler) validateFAPIAuthorizeRequest(ctx context.Context, ar fosite.AuthorizeRequester) error {
+	if !h.c.OAuth2FAPIEnabled(ctx) {
+		return nil
+	}
+	if err := h.validateFAPIClient(ctx, ar.GetClient()); err != nil {
+		return err
+	}
+
+	// The claims of the request object are merged into the form, which keeps the request object itself. The
+	// signature algorithm is enforced through the request_object_signing_alg of the client.
+	if ar.GetRequestForm().Get("request") == "" && ar.GetRequestForm().Get("request_uri") == "" {
+		return fosite.ErrInvalidRequest.WithHint("FAPI 1.0 Advanced requires a signed request object in the request or request_uri parameter.")
+	}
+	if !ar.GetResponseTypes().Matches("code", "id_token") {
+		return fosite.ErrUnsupportedResponseType.WithHint("FAPI 1.0 Advanced only permits the code id_token response type.")
+	}
+	if ar.GetRequestForm().Get("code_challenge_method") != "S256" {
+		return fosite.ErrInvalidRequest.WithHint("FAPI 1.0 Advanced requires a PKCE code challenge using the S256 method.")
+	}
+	return nil
+}
+
+// swagger:route GET /admin/oauth2/fapi/report oAuth2 getFapiReport
+//
+// # Get the FAPI 1.0 Advanced Compliance Report
+//
+// This endpoint lists the FAPI 1.0 Advanced requirements which Ory Hydra and its clients do not meet. It can be
+// used before enabling the FAPI 1.0 Advanced mode, which rejects requests of non-compliant clients.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: fapiReport
+//	  default: errorOAuth2
+func (h *Handler) getFAPIReport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	report := fapiReport{
+		Enabled: h.c.OAuth2FAPIEnabled(ctx),
+		// Sender-constrained access tokens (RFC 8705) require mutual TLS at the token endpoint.
+		Server:  []string{"certificate-bound access tokens are not supported"},
+		Clients: []fapiClientReport{},
+	}
+
+	key, err := h.r.OpenIDJWTStrategy().GetPublicKey(ctx)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	if !stringslice.Has(fapiSigningAlgorithms, key.Algorithm) {
+		report.Server = append(report.Server, fmt.Sprintf("ID tokens must be signed with PS256 or ES256 but are signed with %s", key.Algorithm))
+	}
+
+	opts := []keysetpagination.Option{keysetpagination.WithSize(500)}
+	for {
+		clients, next, err := h.r.ClientManager().GetClients(ctx, client.Filter{PageOpts: opts})
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		for i := range clients {
+			if violations := fapiClientViolations(&clients[i]); len(violations) > 0 {
+				report.Clients = append(report.Clients, fapiClientReport{
+					ClientID:   clients[i].GetID(),
+					ClientName: clients[i].Name,
+					Violations: violations,
+				})
+			}
+		}
+		if next.IsLast() {
+			break
+		}
+		opts = next.ToOptions()
+	}
+
+	h.r.Writer().Write(w, r, &report)
+}
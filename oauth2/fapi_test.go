@@ -0,0 +1,125 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/httprouterx"
+)
+
+func TestFAPI(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	r := x.NewRouterAdmin(conf.AdminURL)
+	reg.OAuth2Handler().SetRoutes(r, &httprouterx.RouterPublic{Router: r.Router}, func(h http.Handler) http.Handler {
+		return h
+	})
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	compliant := &client.Client{
+		ID:                                "fapi-compliant",
+		TokenEndpointAuthMethod:           "private_key_jwt",
+		TokenEndpointAuthSigningAlgorithm: "PS256",
+		RequestObjectSigningAlgorithm:     "ES256",
+		ResponseTypes:                     []string{"code id_token"},
+		GrantTypes:                        []string{"authorization_code", "implicit"},
+		RedirectURIs:                      []string{"https://client.example/callback"},
+		Scope:                             "openid",
+		JSONWebKeysURI:                    "https://client.example/jwks.json",
+	}
+	nonCompliant := &client.Client{
+		ID:            "fapi-non-compliant",
+		Name:          "legacy",
+		ResponseTypes: []string{"code", "code id_token"},
+		RedirectURIs:  []string{"http://client.example/callback"},
+		Scope:         "openid",
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, compliant))
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, nonCompliant))
+
+	t.Run("case=report", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/admin" + oauth2.FAPIReportPath)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var report struct {
+			Enabled bool     `json:"enabled"`
+			Server  []string `json:"server"`
+			Clients []struct {
+				ClientID   string   `json:"client_id"`
+				ClientName string   `json:"client_name"`
+				Violations []string `json:"violations"`
+			} `json:"clients"`
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+
+		assert.False(t, report.Enabled)
+		assert.Contains(t, report.Server, "certificate-bound access tokens are not supported")
+		assert.Contains(t, report.Server, "ID tokens must be signed with PS256 or ES256 but are signed with RS256")
+		require.Len(t, report.Clients, 1)
+		assert.Equal(t, "fapi-non-compliant", report.Clients[0].ClientID)
+		assert.Equal(t, "legacy", report.Clients[0].ClientName)
+		assert.ElementsMatch(t, []string{
+			"token_endpoint_auth_method must be private_key_jwt but is client_secret_basic",
+			"token_endpoint_auth_signing_alg must be PS256 or ES256 but is RS256",
+			`request_object_signing_alg must be PS256 or ES256 but is ""`,
+			`response type "code" is not permitted, only code id_token is`,
+			`redirect URI "http://client.example/callback" must use https`,
+		}, report.Clients[0].Violations)
+	})
+
+	t.Run("case=authorization requests", func(t *testing.T) {
+		conf.MustSet(ctx, config.KeyOAuth2FAPIEnabled, true)
+		t.Cleanup(func() { conf.MustSet(ctx, config.KeyOAuth2FAPIEnabled, false) })
+
+		authorize := func(t *testing.T, c *client.Client, query url.Values) url.Values {
+			query.Set("client_id", c.GetID())
+			query.Set("redirect_uri", c.GetRedirectURIs()[0])
+			query.Set("scope", "openid")
+			query.Set("state", "state-state-state")
+			query.Set("nonce", "nonce-nonce-nonce")
+
+			cl := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			res, err := cl.Get(ts.URL + oauth2.AuthPath + "?" + query.Encode())
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			location, err := res.Location()
+			require.NoError(t, err)
+			if location.Fragment != "" {
+				values, err := url.ParseQuery(location.EscapedFragment())
+				require.NoError(t, err)
+				return values
+			}
+			return location.Query()
+		}
+
+		res := authorize(t, nonCompliant, url.Values{"response_type": {"code id_token"}})
+		assert.Equal(t, "unauthorized_client", res.Get("error"))
+		assert.Contains(t, res.Get("error_description"), "FAPI 1.0 Advanced")
+
+		res = authorize(t, compliant, url.Values{"response_type": {"code id_token"}, "code_challenge": {"challenge-challenge-challenge-challenge-challenge"}, "code_challenge_method": {"S256"}})
+		assert.Equal(t, "invalid_request", res.Get("error"))
+		assert.Contains(t, res.Get("error_description"), "signed request object")
+	})
+}
@@ -194,6 +194,8 @@ func TestHelperRunner(t *testing.T, store InternalRegistry, k string) {
 	t.Run(fmt.Sprintf("case=testHelperFlushTokensWithLimitAndBatchSize/db=%s", k), testHelperFlushTokensWithLimitAndBatchSize(store, 3, 2))
 	t.Run(fmt.Sprintf("case=testFositeStoreSetClientAssertionJWT/db=%s", k), testFositeStoreSetClientAssertionJWT(store))
 	t.Run(fmt.Sprintf("case=testFositeStoreClientAssertionJWTValid/db=%s", k), testFositeStoreClientAssertionJWTValid(store))
+	t.Run(fmt.Sprintf("case=testFositeStoreUseOpenIDConnectNonce/db=%s", k), testFositeStoreUseOpenIDConnectNonce(store))
+	t.Run(fmt.Sprintf("case=testFositeStoreIncrementTokenQuotaUsage/db=%s", k), testFositeStoreIncrementTokenQuotaUsage(store))
 	t.Run(fmt.Sprintf("case=testHelperDeleteAccessTokens/db=%s", k), testHelperDeleteAccessTokens(store))
 	t.Run(fmt.Sprintf("case=testHelperRevokeAccessToken/db=%s", k), testHelperRevokeAccessToken(store))
 	t.Run(fmt.Sprintf("case=testFositeJWTBearerGrantStorage/db=%s", k), testFositeJWTBearerGrantStorage(store))
@@ -727,6 +729,69 @@ func testFositeStoreSetClientAssertionJWT(m InternalRegistry) func(*testing.T) {
 	}
 }
 
+func testFositeStoreIncrementTokenQuotaUsage(m InternalRegistry) func(*testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		store := m.OAuth2Storage()
+		clientID := uuid.New()
+		start := time.Now().UTC().Truncate(time.Hour)
+
+		for i := 1; i <= 3; i++ {
+			count, err := store.IncrementTokenQuotaUsage(ctx, clientID, "hour", start, start.Add(time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, i, count)
+		}
+
+		t.Run("case=windows are counted separately", func(t *testing.T) {
+			count, err := store.IncrementTokenQuotaUsage(ctx, clientID, "day", start, start.Add(24*time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			count, err = store.IncrementTokenQuotaUsage(ctx, clientID, "hour", start.Add(time.Hour), start.Add(2*time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+
+		t.Run("case=clients are counted separately", func(t *testing.T) {
+			count, err := store.IncrementTokenQuotaUsage(ctx, uuid.New(), "hour", start, start.Add(time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+	}
+}
+
+func testFositeStoreUseOpenIDConnectNonce(m InternalRegistry) func(*testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		store := m.OAuth2Storage()
+		nonce := uuid.New()
+
+		require.NoError(t, store.UseOpenIDConnectNonce(ctx, "nonce-client", nonce, time.Now().Add(time.Minute)))
+		assert.ErrorIs(t, store.UseOpenIDConnectNonce(ctx, "nonce-client", nonce, time.Now().Add(time.Minute)), fosite.ErrInvalidRequest)
+
+		t.Run("case=nonces are scoped to the client", func(t *testing.T) {
+			require.NoError(t, store.UseOpenIDConnectNonce(ctx, "other-nonce-client", nonce, time.Now().Add(time.Minute)))
+		})
+
+		t.Run("case=nonces of clients with colons in their ID do not collide", func(t *testing.T) {
+			prefix := uuid.New()
+			require.NoError(t, store.UseOpenIDConnectNonce(ctx, prefix+":a", "b", time.Now().Add(time.Minute)))
+			require.NoError(t, store.UseOpenIDConnectNonce(ctx, prefix, "a:b", time.Now().Add(time.Minute)))
+		})
+
+		t.Run("case=nonces do not collide with jti values", func(t *testing.T) {
+			require.NoError(t, store.ClientAssertionJWTValid(ctx, nonce))
+			require.NoError(t, store.SetClientAssertionJWT(ctx, nonce, time.Now().Add(time.Minute)))
+		})
+
+		t.Run("case=expired nonces may be used again", func(t *testing.T) {
+			expired := uuid.New()
+			require.NoError(t, store.UseOpenIDConnectNonce(ctx, "nonce-client", expired, time.Now().Add(-time.Minute)))
+			require.NoError(t, store.UseOpenIDConnectNonce(ctx, "nonce-client", expired, time.Now().Add(time.Minute)))
+		})
+	}
+}
+
 func testFositeStoreClientAssertionJWTValid(m InternalRegistry) func(*testing.T) {
 	return func(t *testing.T) {
 		t.Run("case=returns valid on unknown JTI", func(t *testing.T) {
@@ -56,6 +56,7 @@ func TestManagers(t *testing.T) {
 	tests := []struct {
 		name                   string
 		enableSessionEncrypted bool
+		batchTokenWrites       bool
 	}{
 		{
 			name:                   "DisableSessionEncrypted",
@@ -65,6 +66,10 @@ func TestManagers(t *testing.T) {
 			name:                   "EnableSessionEncrypted",
 			enableSessionEncrypted: true,
 		},
+		{
+			name:             "BatchTokenWrites",
+			batchTokenWrites: true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run("suite="+tc.name, func(t *testing.T) {
@@ -76,6 +81,7 @@ func TestManagers(t *testing.T) {
 				net := &networkx.Network{}
 				require.NoError(t, store.Persister().Connection(context.Background()).First(net))
 				store.Config().MustSet(ctx, config.KeyEncryptSessionData, tc.enableSessionEncrypted)
+				store.Config().MustSet(ctx, config.KeyDBBatchTokenWritesEnabled, tc.batchTokenWrites)
 				store.WithContextualizer(&contextx.Static{NID: net.ID, C: store.Config().Source(ctx)})
 				TestHelperRunner(t, store, k)
 			}
@@ -4,6 +4,7 @@
 package oauth2
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -20,6 +21,7 @@ import (
 	"github.com/ory/hydra/v2/x/events"
 	"github.com/ory/x/httprouterx"
 	"github.com/ory/x/josex"
+	"github.com/ory/x/stringslice"
 	"github.com/ory/x/stringsx"
 
 	jwtV5 "github.com/golang-jwt/jwt/v5"
@@ -28,6 +30,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/handler/openid"
@@ -37,6 +40,7 @@ import (
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/consent"
 	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/flow"
 	"github.com/ory/hydra/v2/x"
 )
 
@@ -64,28 +68,40 @@ const (
 type Handler struct {
 	r InternalRegistry
 	c *config.DefaultProvider
+	m *Metrics
+
+	discovery *discoveryCache
+	ccTokens  *clientCredentialsTokenCache
 }
 
 func NewHandler(r InternalRegistry, c *config.DefaultProvider) *Handler {
 	return &Handler{
-		r: r,
-		c: c,
+		r:         r,
+		c:         c,
+		m:         NewMetrics(prometheus.DefaultRegisterer, c),
+		discovery: newDiscoveryCache(),
+		ccTokens:  newClientCredentialsTokenCache(c.ClientCredentialsTokenCacheMaxEntries()),
 	}
 }
 
 func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin, public *httprouterx.RouterPublic, corsMiddleware func(http.Handler) http.Handler) {
 	public.Handler("OPTIONS", TokenPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
-	public.Handler("POST", TokenPath, corsMiddleware(http.HandlerFunc(h.oauth2TokenExchange)))
+	public.Handler("POST", TokenPath, corsMiddleware(h.customizeErrors(h.m.Handler(metricsEndpointToken, h.oauth2TokenExchange))))
 
-	public.GET(AuthPath, h.oAuth2Authorize)
-	public.POST(AuthPath, h.oAuth2Authorize)
+	public.GET(AuthPath, h.customizeErrorsHandle(h.m.Handle(metricsEndpointAuthorize, h.oAuth2Authorize)))
+	public.POST(AuthPath, h.customizeErrorsHandle(h.m.Handle(metricsEndpointAuthorize, h.oAuth2Authorize)))
 	public.GET(LogoutPath, h.performOidcFrontOrBackChannelLogout)
 	public.POST(LogoutPath, h.performOidcFrontOrBackChannelLogout)
 
-	public.GET(DefaultLoginPath, h.fallbackHandler("", "", http.StatusOK, config.KeyLoginURL))
-	public.GET(DefaultConsentPath, h.fallbackHandler("", "", http.StatusOK, config.KeyConsentURL))
-	public.GET(DefaultLogoutPath, h.fallbackHandler("", "", http.StatusOK, config.KeyLogoutURL))
+	loginFallback := h.fallbackHandler("", "", "", http.StatusOK, config.KeyLoginURL)
+	consentFallback := h.fallbackHandler("", "", "", http.StatusOK, config.KeyConsentURL)
+	public.GET(DefaultLoginPath, h.fallbackUI(h.fallbackUILogin, loginFallback))
+	public.POST(DefaultLoginPath, h.fallbackUI(h.fallbackUILogin, loginFallback))
+	public.GET(DefaultConsentPath, h.fallbackUI(h.fallbackUIConsent, consentFallback))
+	public.POST(DefaultConsentPath, h.fallbackUI(h.fallbackUIConsent, consentFallback))
+	public.GET(DefaultLogoutPath, h.fallbackHandler("", "", "", http.StatusOK, config.KeyLogoutURL))
 	public.GET(DefaultPostLogoutPath, h.fallbackHandler(
+		"post_logout_page",
 		"You logged out successfully!",
 		"The Default Post Logout URL is not set which is why you are seeing this fallback page. Your log out request however succeeded.",
 		http.StatusOK,
@@ -94,7 +110,7 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin, public *httprouterx.
 	public.GET(DefaultErrorPath, h.DefaultErrorHandler)
 
 	public.Handler("OPTIONS", RevocationPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
-	public.Handler("POST", RevocationPath, corsMiddleware(http.HandlerFunc(h.revokeOAuth2Token)))
+	public.Handler("POST", RevocationPath, corsMiddleware(h.customizeErrors(h.m.Handler(metricsEndpointRevoke, h.revokeOAuth2Token))))
 	public.Handler("OPTIONS", WellKnownPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
 	public.Handler("GET", WellKnownPath, corsMiddleware(http.HandlerFunc(h.discoverOidcConfiguration)))
 	public.Handler("OPTIONS", UserinfoPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
@@ -104,8 +120,10 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin, public *httprouterx.
 	public.Handler("OPTIONS", VerifiableCredentialsPath, corsMiddleware(http.HandlerFunc(h.handleOptions)))
 	public.Handler("POST", VerifiableCredentialsPath, corsMiddleware(http.HandlerFunc(h.createVerifiableCredential)))
 
-	admin.POST(IntrospectPath, h.introspectOAuth2Token)
+	admin.POST(IntrospectPath, h.customizeErrorsHandle(h.m.Handle(metricsEndpointIntrospect, h.introspectOAuth2Token)))
+	admin.GET(FAPIReportPath, h.getFAPIReport)
 	admin.DELETE(DeleteTokensPath, h.deleteOAuth2Token)
+	admin.POST(DerivedTokenPath, h.deriveOAuth2Token)
 }
 
 // swagger:route GET /oauth2/sessions/logout oidc revokeOidcSession
@@ -145,10 +163,24 @@ func (h *Handler) performOidcFrontOrBackChannelLogout(w http.ResponseWriter, r *
 <head>
     <meta http-equiv="refresh" content="7; URL={{ .RedirectTo }}">
 </head>
-<style type="text/css">
+<style type="text/css" nonce="{{ .Nonce }}">
     iframe { position: absolute; left: 0; top: 0; height: 0; width: 0; border: none; }
 </style>
-<script>
+<body>
+<noscript>
+    <p>
+        JavaScript is disabled - you should be redirected in 5 seconds but if not, click <a
+            href="{{ .RedirectTo }}">here</a> to continue.
+    </p>
+</noscript>
+
+<p id="redir" hidden>
+    Redirection takes unusually long. If you are not being redirected within the next seconds, click <a href="{{ .RedirectTo }}">here</a> to continue.
+</p>
+
+{{ range .FrontChannelLogoutURLs }}<iframe src="{{ . }}"></iframe>
+{{ end }}
+<script nonce="{{ .Nonce }}">
     var total = {{ len .FrontChannelLogoutURLs }};
     var redir = {{ .RedirectTo }};
 
@@ -185,27 +217,20 @@ func (h *Handler) performOidcFrontOrBackChannelLogout(w http.ResponseWriter, r *
         }
     }
 
+	// The listeners are registered here instead of inline, because the Content-Security-Policy forbids inline
+	// event handlers.
+	var frames = document.getElementsByTagName("iframe");
+	for (var i=0; i<frames.length; i++) {
+		frames[i].addEventListener("load", done);
+	}
+
 	setAndRegisterTimeout(redirect, 7000); // redirect after 7 seconds if e.g. an iframe doesn't load
 
 	// If the redirect takes unusually long, show a message
 	setTimeout(function () {
-		document.getElementById("redir").style.display = "block";
+		document.getElementById("redir").hidden = false;
 	}, 2000);
 </script>
-<body>
-<noscript>
-    <p>
-        JavaScript is disabled - you should be redirected in 5 seconds but if not, click <a
-            href="{{ .RedirectTo }}">here</a> to continue.
-    </p>
-</noscript>
-
-<p id="redir" style="display: none">
-    Redirection takes unusually long. If you are not being redirected within the next seconds, click <a href="{{ .RedirectTo }}">here</a> to continue.
-</p>
-
-{{ range .FrontChannelLogoutURLs }}<iframe src="{{ . }}" onload="done(this)"></iframe>
-{{ end }}
 </body>
 </html>`)
 	if err != nil {
@@ -214,7 +239,17 @@ func (h *Handler) performOidcFrontOrBackChannelLogout(w http.ResponseWriter, r *
 		return
 	}
 
-	if err := t.Execute(w, handled); err != nil {
+	nonce, err := h.writeSecurityHeaders(w, r)
+	if err != nil {
+		x.LogError(r, err, h.r.Logger())
+		h.forwardError(w, r, err)
+		return
+	}
+
+	if err := t.Execute(w, struct {
+		*flow.LogoutResult
+		Nonce string
+	}{LogoutResult: handled, Nonce: nonce}); err != nil {
 		x.LogError(r, err, h.r.Logger())
 		h.forwardError(w, r, err)
 		return
@@ -473,12 +508,30 @@ type CredentialSupportedDraft00 struct {
 //	  default: errorOAuth2
 func (h *Handler) discoverOidcConfiguration(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	key, err := h.r.OpenIDJWTStrategy().GetPublicKey(ctx)
+	doc, err := h.discovery.get(h.c.IssuerURL(ctx).String(), h.c.OIDCDiscoveryCacheTTL(ctx), func() (map[string]interface{}, error) {
+		return h.oidcConfiguration(ctx)
+	})
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
-	h.r.Writer().Write(w, r, &oidcConfiguration{
+
+	w.Header().Set("ETag", doc.etag)
+	if x.IfNoneMatch(r, doc.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(doc.body)
+}
+
+// oidcConfiguration returns the OpenID Connect Discovery document with the configured extra fields.
+func (h *Handler) oidcConfiguration(ctx context.Context) (map[string]interface{}, error) {
+	key, err := h.r.OpenIDJWTStrategy().GetPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := structToMap(&oidcConfiguration{
 		Issuer:                                 h.c.IssuerURL(ctx).String(),
 		AuthURL:                                h.c.OAuth2AuthURL(ctx).String(),
 		TokenURL:                               h.c.OAuth2TokenURL(ctx).String(),
@@ -512,14 +565,21 @@ func (h *Handler) discoverOidcConfiguration(w http.ResponseWriter, r *http.Reque
 			Format:                               "jwt_vc_json",
 			Types:                                []string{"VerifiableCredential", "UserInfoCredential"},
 			CryptographicBindingMethodsSupported: []string{"jwk"},
-			CryptographicSuitesSupported: []string{
-				"PS256", "RS256", "ES256",
-				"PS384", "RS384", "ES384",
-				"PS512", "RS512", "ES512",
-				"EdDSA",
-			},
+			CryptographicSuitesSupported:         verifiableCredentialCryptographicSuites,
+		}, {
+			Format:                               VerifiableCredentialFormatSDJWT,
+			Types:                                []string{"UserInfoCredential"},
+			CryptographicBindingMethodsSupported: []string{"jwk"},
+			CryptographicSuitesSupported:         verifiableCredentialCryptographicSuites,
 		}},
 	})
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h.c.OIDCDiscoveryExtraFields(ctx) {
+		doc[k] = v
+	}
+	return doc, nil
 }
 
 // OpenID Connect Userinfo
@@ -661,7 +721,7 @@ func (h *Handler) getOidcUserInfo(w http.ResponseWriter, r *http.Request) {
 
 	if c.UserinfoSignedResponseAlg == "RS256" {
 		interim["jti"] = uuid.New()
-		interim["iat"] = time.Now().Unix()
+		interim["iat"] = h.r.Clock().Now().Unix()
 
 		keyID, err := h.r.OpenIDJWTStrategy().GetPublicKeyID(r.Context())
 		if err != nil {
@@ -725,11 +785,18 @@ type revokeOAuth2Token struct {
 //	  default: errorOAuth2
 func (h *Handler) revokeOAuth2Token(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	events.Trace(r.Context(), events.AccessTokenRevoked)
 
+	revoked := h.revokedRequest(ctx, r)
 	err := h.r.OAuth2Provider().NewRevocationRequest(ctx, r)
 	if err != nil {
 		x.LogError(r, err, h.r.Logger())
+		traceClientAuthenticationError(r, err)
+		events.Trace(ctx, events.AccessTokenRevoked, events.WithError(err))
+	} else {
+		events.Trace(ctx, events.AccessTokenRevoked)
+		if revoked != nil {
+			h.notifyRevocation(ctx, revoked)
+		}
 	}
 
 	h.r.OAuth2Provider().WriteRevocationResponse(ctx, w, err)
@@ -845,7 +912,7 @@ func (h *Handler) introspectOAuth2Token(w http.ResponseWriter, r *http.Request,
 	}
 
 	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
-	if err = json.NewEncoder(w).Encode(&Introspection{
+	if err = json.NewEncoder(w).Encode((&Introspection{
 		Active:            resp.IsActive(),
 		ClientID:          resp.GetAccessRequester().GetClient().GetID(),
 		Scope:             strings.Join(resp.GetAccessRequester().GetGrantedScopes(), " "),
@@ -860,7 +927,7 @@ func (h *Handler) introspectOAuth2Token(w http.ResponseWriter, r *http.Request,
 		TokenType:         resp.GetAccessTokenType(),
 		TokenUse:          string(resp.GetTokenUse()),
 		NotBefore:         resp.GetAccessRequester().GetRequestedAt().Unix(),
-	}); err != nil {
+	}).withMetadata(h.c.OAuth2IntrospectionMetadata(ctx), resp.GetAccessRequester(), session)); err != nil {
 		x.LogError(r, errorsx.WithStack(err), h.r.Logger())
 	}
 
@@ -868,6 +935,7 @@ func (h *Handler) introspectOAuth2Token(w http.ResponseWriter, r *http.Request,
 		events.AccessTokenInspected,
 		events.WithSubject(session.GetSubject()),
 		events.WithClientID(resp.GetAccessRequester().GetClient().GetID()),
+		events.WithGrant(resp.GetAccessRequester()),
 	)
 }
 
@@ -954,7 +1022,30 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logOrAudit(err, r)
 		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
-		events.Trace(ctx, events.TokenExchangeError)
+		events.Trace(ctx, events.TokenExchangeError, events.WithError(err))
+		traceClientAuthenticationError(r, err)
+		traceRefreshTokenReuse(r, accessRequest, err)
+		return
+	}
+
+	if err := h.validateFAPIClient(ctx, accessRequest.GetClient()); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+		events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest), events.WithError(err))
+		return
+	}
+
+	if err := h.validateClientAttestation(ctx, r, accessRequest); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+		events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest), events.WithError(err))
+		return
+	}
+
+	if err := h.enforceTokenQuota(ctx, w, accessRequest); err != nil {
+		h.logOrAudit(err, r)
+		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
+		events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest), events.WithError(err))
 		return
 	}
 
@@ -966,7 +1057,7 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 			if err != nil {
 				x.LogError(r, err, h.r.Logger())
 				h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
-				events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest))
+				events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest), events.WithError(err))
 				return
 			}
 		}
@@ -978,7 +1069,7 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 		session.ClientID = accessRequest.GetClient().GetID()
 		session.KID = accessTokenKeyID
 		session.DefaultSession.Claims.Issuer = h.c.IssuerURL(r.Context()).String()
-		session.DefaultSession.Claims.IssuedAt = time.Now().UTC()
+		session.DefaultSession.Claims.IssuedAt = h.r.Clock().Now().UTC()
 
 		scopes := accessRequest.GetRequestedScopes()
 
@@ -1006,7 +1097,23 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 		if err := hook(ctx, accessRequest); err != nil {
 			h.logOrAudit(err, r)
 			h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
-			events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest))
+			events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest), events.WithError(err))
+			return
+		}
+	}
+
+	if session, ok := accessRequest.GetSession().(*Session); ok {
+		if !accessRequest.GetGrantTypes().ExactOne("refresh_token") || session.GrantType == "" {
+			session.GrantType = accessRequest.GetGrantTypes()[0]
+		}
+		session.GrantChain = append(session.GrantChain, accessRequest.GetGrantTypes()[0])
+		h.applyAccessTokenClaimsProfile(ctx, session)
+	}
+
+	cacheKey, cacheLifespan := h.clientCredentialsTokenCacheKey(ctx, accessRequest)
+	if cacheLifespan > 0 {
+		if cached := h.cachedClientCredentialsToken(ctx, cacheKey); cached != nil {
+			h.r.OAuth2Provider().WriteAccessResponse(ctx, w, accessRequest, cached)
 			return
 		}
 	}
@@ -1015,10 +1122,14 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logOrAudit(err, r)
 		h.r.OAuth2Provider().WriteAccessError(ctx, w, accessRequest, err)
-		events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest))
+		events.Trace(ctx, events.TokenExchangeError, events.WithRequest(accessRequest), events.WithError(err))
 		return
 	}
 
+	if cacheLifespan > 0 {
+		h.cacheClientCredentialsToken(cacheKey, cacheLifespan, accessRequest, accessResponse)
+	}
+
 	h.r.OAuth2Provider().WriteAccessResponse(ctx, w, accessRequest, accessResponse)
 }
 
@@ -1040,15 +1151,41 @@ func (h *Handler) oauth2TokenExchange(w http.ResponseWriter, r *http.Request) {
 //	  302: emptyResponse
 //	  default: errorOAuth2
 func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	ctx := r.Context()
+	ctx := x.WithCorrelationID(r.Context(), x.CorrelationIDFromRequest(r))
+	r = r.WithContext(ctx)
+
+	if err := h.limitAuthorizeRequest(w, r); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.forwardError(w, r, err)
+		return
+	}
 
-	authorizeRequest, err := h.r.OAuth2Provider().NewAuthorizeRequest(ctx, r)
+	// Request objects are verified with the dedicated request object signing keys of the client, if it has any.
+	authorizeRequest, err := h.r.OAuth2Provider().NewAuthorizeRequest(client.WithRequestObjectSigningKeys(ctx), r)
 	if err != nil {
 		x.LogError(r, err, h.r.Logger())
 		h.writeAuthorizeError(w, r, authorizeRequest, err)
 		return
 	}
 
+	if err := h.validateFAPIAuthorizeRequest(ctx, authorizeRequest); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
+	}
+
+	if err := validatePluginAuthorizeRequest(ctx, h.r, authorizeRequest); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
+	}
+
+	if err := h.executeAuthorizationRequestHook(ctx, r, authorizeRequest); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
+	}
+
 	session, flow, err := h.r.ConsentStrategy().HandleOAuth2AuthorizationRequest(ctx, w, r, authorizeRequest)
 	if errors.Is(err, consent.ErrAbortOAuth2Request) {
 		x.LogAudit(r, nil, h.r.AuditLogger())
@@ -1072,6 +1209,12 @@ func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ http
 		authorizeRequest.GrantAudience(audience)
 	}
 
+	if err := h.useOpenIDConnectNonce(ctx, authorizeRequest); err != nil {
+		x.LogAudit(r, err, h.r.AuditLogger())
+		h.writeAuthorizeError(w, r, authorizeRequest, err)
+		return
+	}
+
 	openIDKeyID, err := h.r.OpenIDJWTStrategy().GetPublicKeyID(ctx)
 	if err != nil {
 		x.LogError(r, err, h.r.Logger())
@@ -1113,7 +1256,7 @@ func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ http
 		// These are required for work around https://github.com/ory/fosite/issues/530
 		Nonce:    authorizeRequest.GetRequestForm().Get("nonce"),
 		Audience: []string{authorizeRequest.GetClient().GetID()},
-		IssuedAt: time.Now().Truncate(time.Second).UTC(),
+		IssuedAt: h.r.Clock().Now().Truncate(time.Second).UTC(),
 
 		// This is set by the fosite strategy
 		// ExpiresAt:   time.Now().Add(h.IDTokenLifespan).UTC(),
@@ -1121,7 +1264,7 @@ func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ http
 	claims.Add("sid", session.ConsentRequest.LoginSessionID)
 
 	// done
-	response, err := h.r.OAuth2Provider().NewAuthorizeResponse(ctx, authorizeRequest, &Session{
+	authorizeSession := &Session{
 		DefaultSession: &openid.DefaultSession{
 			Claims: claims,
 			Headers: &jwt.Headers{Extra: map[string]interface{}{
@@ -1138,7 +1281,22 @@ func (h *Handler) oAuth2Authorize(w http.ResponseWriter, r *http.Request, _ http
 		AllowedTopLevelClaims: h.c.AllowedTopLevelClaims(ctx),
 		MirrorTopLevelClaims:  h.c.MirrorTopLevelClaims(ctx),
 		Flow:                  flow,
-	})
+	}
+	if authorizeRequest.GetResponseTypes().Has("token") {
+		// Access tokens issued at the token endpoint record their grant type there.
+		authorizeSession.GrantType = "implicit"
+		authorizeSession.GrantChain = []string{"implicit"}
+	}
+	if stringslice.Has(h.c.OAuth2IntrospectionMetadata(ctx), "authorization_request") {
+		authorizeSession.AuthorizationRequest = flow.RequestParameters
+	}
+	h.applyAccessTokenClaimsProfile(ctx, authorizeSession)
+
+	// Authorization codes are issued with the lifespan of the client, if it overrides the configured one.
+	ctx = config.WithAuthorizeCodeLifespan(ctx, fosite.GetEffectiveLifespan(authorizeRequest.GetClient(),
+		fosite.GrantTypeAuthorizationCode, fosite.AuthorizeCode, h.c.GetAuthorizeCodeLifespan(ctx)))
+
+	response, err := h.r.OAuth2Provider().NewAuthorizeResponse(ctx, authorizeRequest, authorizeSession)
 	if err != nil {
 		x.LogError(r, err, h.r.Logger())
 		h.writeAuthorizeError(w, r, authorizeRequest, err)
@@ -1195,8 +1353,16 @@ func (h *Handler) deleteOAuth2Token(w http.ResponseWriter, r *http.Request, _ ht
 func (h *Handler) handleOptions(http.ResponseWriter, *http.Request) {}
 
 func (h *Handler) forwardError(w http.ResponseWriter, r *http.Request, err error) {
-	rfcErr := fosite.ErrorToRFC6749Error(err).WithExposeDebug(h.c.GetSendDebugMessagesToClients(r.Context()))
+	l := h.localizer(r)
+	rfcErr := fosite.ErrorToRFC6749Error(err).WithExposeDebug(h.c.GetSendDebugMessagesToClients(r.Context())).WithLocalizer(l.catalog, l.lang)
 	query := rfcErr.ToValues()
+	if l.catalog != nil {
+		// Lets the error UI show its own texts in the language of the error description.
+		query.Set("ui_locales", l.lang.String())
+	}
+	if id := x.CorrelationIDFromContext(r.Context()); id != "" {
+		query.Set(x.CorrelationIDParameter, id)
+	}
 	http.Redirect(w, r, urlx.CopyWithQuery(h.c.ErrorURL(r.Context()), query).String(), http.StatusFound)
 }
 
@@ -1209,6 +1375,27 @@ func (h *Handler) writeAuthorizeError(w http.ResponseWriter, r *http.Request, ar
 	h.r.OAuth2Provider().WriteAuthorizeError(r.Context(), w, ar, err)
 }
 
+// traceClientAuthenticationError emits an event if the error was caused by a failed client authentication.
+func traceClientAuthenticationError(r *http.Request, err error) {
+	if !errors.Is(err, fosite.ErrInvalidClient) {
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if id, _, ok := r.BasicAuth(); ok {
+		clientID = id
+	}
+	events.Trace(r.Context(), events.ClientAuthenticationFailed, events.WithClientID(clientID))
+}
+
+// traceRefreshTokenReuse emits an event if the request presented a refresh token which was already used.
+func traceRefreshTokenReuse(r *http.Request, ar fosite.AccessRequester, err error) {
+	if ar == nil || r.PostForm.Get("grant_type") != "refresh_token" || !errors.Is(err, fosite.ErrInactiveToken) {
+		return
+	}
+	events.Trace(r.Context(), events.RefreshTokenReused, events.WithRequest(ar))
+}
+
 func (h *Handler) logOrAudit(err error, r *http.Request) {
 	if errors.Is(err, fosite.ErrServerError) || errors.Is(err, fosite.ErrTemporarilyUnavailable) || errors.Is(err, fosite.ErrMisconfiguration) {
 		x.LogError(r, err, h.r.Logger())
@@ -1258,14 +1445,14 @@ func (h *Handler) createVerifiableCredential(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if request.Format != "jwt_vc_json" {
+	if request.Format != "jwt_vc_json" && request.Format != VerifiableCredentialFormatSDJWT {
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The format %q is not supported.", request.Format)))
 		return
 	}
 	if request.Proof == nil {
 		// Handle priming request
 		nonceLifespan := h.r.Config().GetVerifiableCredentialsNonceLifespan(ctx)
-		nonceExpiresIn := time.Now().Add(nonceLifespan).UTC()
+		nonceExpiresIn := h.r.Clock().Now().Add(nonceLifespan).UTC()
 		nonce, err := h.r.OAuth2Storage().NewNonce(ctx, accessToken, nonceExpiresIn)
 		if err != nil {
 			h.r.Writer().WriteError(w, r, err)
@@ -1308,6 +1495,9 @@ func (h *Handler) createVerifiableCredential(w http.ResponseWriter, r *http.Requ
 	token, err := jwt.Parse(request.Proof.JWT, func(token *jwt.Token) (any, error) {
 		return proofJWK, nil
 	})
+	if token != nil {
+		err = x.RevalidateTimeClaimsWithLeeway(err, token.Claims, h.r.Clock().Now(), h.c.ClockSkew(ctx))
+	}
 	if err != nil {
 		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The JWT was not signed with the correct key supplied in the JWK header.")))
 		return
@@ -1325,7 +1515,7 @@ func (h *Handler) createVerifiableCredential(w http.ResponseWriter, r *http.Requ
 	}
 
 	var response VerifiableCredentialResponse
-	response.Format = "jwt_vc_json"
+	response.Format = request.Format
 
 	proofJWKJSON, err := json.Marshal(proofJWK)
 	if err != nil {
@@ -1333,6 +1523,17 @@ func (h *Handler) createVerifiableCredential(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if request.Format == VerifiableCredentialFormatSDJWT {
+		credential, err := h.createSDJWTVerifiableCredential(ctx, session, proofJWKJSON)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		response.Credential = credential
+		h.r.Writer().Write(w, r, &response)
+		return
+	}
+
 	// Encode ID according to https://github.com/quartzjer/did-jwk/blob/main/spec.md
 	vcID := fmt.Sprintf("did:jwk:%s", base64.RawURLEncoding.EncodeToString(proofJWKJSON))
 	vcClaims := &VerifableCredentialClaims{
@@ -4,15 +4,57 @@
 package oauth2
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
 
+	"golang.org/x/text/language"
+
+	"github.com/ory/fosite/i18n"
 	"github.com/ory/hydra/v2/driver/config"
 
 	"github.com/julienschmidt/httprouter"
 )
 
-func (h *Handler) fallbackHandler(title, heading string, sc int, configKey string) httprouter.Handle {
+// pageLocalizer translates the messages of the pages which Ory Hydra serves itself into the language of the user.
+type pageLocalizer struct {
+	catalog i18n.MessageCatalog
+	lang    language.Tag
+}
+
+func (h *Handler) localizer(r *http.Request) *pageLocalizer {
+	catalog := h.r.OAuth2ProviderConfig().GetMessageCatalog(r.Context())
+	return &pageLocalizer{catalog: catalog, lang: i18n.GetLangFromRequest(catalog, r)}
+}
+
+// text returns the translation of the message with the given ID, or the given default if it is not translated.
+func (l *pageLocalizer) text(id, def string) string {
+	return i18n.GetMessageOrDefault(l.catalog, id, l.lang, def)
+}
+
+// html returns the escaped translation of the message with the given ID, with its verbs replaced by the given HTML
+// snippets.
+func (l *pageLocalizer) html(id, def string, args ...interface{}) template.HTML {
+	return template.HTML(fmt.Sprintf(template.HTMLEscapeString(l.text(id, def)), args...)) // #nosec G203 -- the message is escaped and the arguments are trusted snippets
+}
+
+func (l *pageLocalizer) notConfigured(key string) template.HTML {
+	return l.html("fallback_page.not_configured", "You are seeing this page because configuration key %s is not set.",
+		"<code>"+template.HTMLEscapeString(key)+"</code>")
+}
+
+func (l *pageLocalizer) help() template.HTML {
+	return l.html("fallback_page.help", "If you are an administrator, please read %s to understand what you need to do. If you are a user, please contact the administrator.",
+		`<a href="https://www.ory.sh/docs">`+template.HTMLEscapeString(l.text("fallback_page.guide", "the guide"))+"</a>")
+}
+
+// fallbackHandler serves the page which is shown if the URL of the given configuration key is not set. The title and
+// heading are translated with the messages <page>.title and <page>.heading.
+func (h *Handler) fallbackHandler(page, title, heading string, sc int, configKey string) httprouter.Handle {
+	if page == "" {
+		page = "fallback_page"
+	}
+
 	if title == "" {
 		title = "The request could not be executed because a mandatory configuration key is missing or malformed"
 	}
@@ -24,7 +66,7 @@ func (h *Handler) fallbackHandler(title, heading string, sc int, configKey strin
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		h.r.Logger().Errorf(`A request failed because configuration key "%s" is missing or malformed.`, configKey)
 
-		t, err := template.New(configKey).Parse(`<html>
+		t, err := template.New(configKey).Parse(`<html lang="{{ .Lang }}">
 <head>
 	<title>{{ .Title }}</title>
 </head>
@@ -33,11 +75,10 @@ func (h *Handler) fallbackHandler(title, heading string, sc int, configKey strin
 	{{ .Heading }}
 </h1>
 <p>
-	You are seeing this page because configuration key <code>{{ .Key }}</code> is not set.
+	{{ .NotConfigured }}
 </p>
 <p>
-	If you are an administrator, please read <a href="https://www.ory.sh/docs">the guide</a> to understand what you
-	need to do. If you are a user, please contact the administrator.
+	{{ .Help }}
 </p>
 </body>
 </html>`)
@@ -46,12 +87,26 @@ func (h *Handler) fallbackHandler(title, heading string, sc int, configKey strin
 			return
 		}
 
+		if _, err := h.writeSecurityHeaders(w, r); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		l := h.localizer(r)
 		w.WriteHeader(sc)
 		if err := t.Execute(w, struct {
-			Title   string
-			Heading string
-			Key     string
-		}{Title: title, Heading: heading, Key: configKey}); err != nil {
+			Lang          string
+			Title         string
+			Heading       string
+			NotConfigured template.HTML
+			Help          template.HTML
+		}{
+			Lang:          l.lang.String(),
+			Title:         l.text(page+".title", title),
+			Heading:       l.text(page+".heading", heading),
+			NotConfigured: l.notConfigured(configKey),
+			Help:          l.help(),
+		}); err != nil {
 			h.r.Writer().WriteError(w, r, err)
 			return
 		}
@@ -62,26 +117,25 @@ func (h *Handler) DefaultErrorHandler(w http.ResponseWriter, r *http.Request, _
 	h.r.Logger().WithRequest(r).Error("A client requested the default error URL, environment variable URLS_ERROR is probably not set.")
 
 	t, err := template.New("consent").Parse(`
-<html>
+<html lang="{{ .Lang }}">
 <head>
-	<title>An OAuth 2.0 Error Occurred</title>
+	<title>{{ .Title }}</title>
 </head>
 <body>
 <h1>
-	The OAuth2 request resulted in an error.
+	{{ .Heading }}
 </h1>
 <ul>
-	<li>Error: {{ .Name }}</li>
-	<li>Description: {{ .Description }}</li>
-	<li>Hint: {{ .Hint }}</li>
-	<li>Debug: {{ .Debug }}</li>
+	<li>{{ .NameLabel }}: {{ .Name }}</li>
+	<li>{{ .DescriptionLabel }}: {{ .Description }}</li>
+	<li>{{ .HintLabel }}: {{ .Hint }}</li>
+	<li>{{ .DebugLabel }}: {{ .Debug }}</li>
 </ul>
 <p>
-	You are seeing this page because configuration key <code>{{ .Key }}</code> is not set.
+	{{ .NotConfigured }}
 </p>
 <p>
-	If you are an administrator, please read <a href="https://www.ory.sh/docs">the guide</a> to understand what you
-	need to do. If you are a user, please contact the administrator.
+	{{ .Help }}
 </p>
 </body>
 </html>
@@ -91,19 +145,41 @@ func (h *Handler) DefaultErrorHandler(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
+	if _, err := h.writeSecurityHeaders(w, r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	l := h.localizer(r)
 	w.WriteHeader(http.StatusInternalServerError)
 	if err := t.Execute(w, struct {
-		Name        string
-		Description string
-		Hint        string
-		Debug       string
-		Key         string
+		Lang             string
+		Title            string
+		Heading          string
+		NameLabel        string
+		Name             string
+		DescriptionLabel string
+		Description      string
+		HintLabel        string
+		Hint             string
+		DebugLabel       string
+		Debug            string
+		NotConfigured    template.HTML
+		Help             template.HTML
 	}{
-		Name:        r.URL.Query().Get("error"),
-		Description: r.URL.Query().Get("error_description"),
-		Hint:        r.URL.Query().Get("error_hint"),
-		Debug:       r.URL.Query().Get("error_debug"),
-		Key:         config.KeyErrorURL,
+		Lang:             l.lang.String(),
+		Title:            l.text("error_page.title", "An OAuth 2.0 Error Occurred"),
+		Heading:          l.text("error_page.heading", "The OAuth2 request resulted in an error."),
+		NameLabel:        l.text("error_page.error", "Error"),
+		Name:             r.URL.Query().Get("error"),
+		DescriptionLabel: l.text("error_page.description", "Description"),
+		Description:      r.URL.Query().Get("error_description"),
+		HintLabel:        l.text("error_page.hint", "Hint"),
+		Hint:             r.URL.Query().Get("error_hint"),
+		DebugLabel:       l.text("error_page.debug", "Debug"),
+		Debug:            r.URL.Query().Get("error_debug"),
+		NotConfigured:    l.notConfigured(config.KeyErrorURL),
+		Help:             l.help(),
 	}); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
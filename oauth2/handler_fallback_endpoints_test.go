@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/ory/x/httprouterx"
@@ -20,6 +22,7 @@ import (
 	"github.com/ory/hydra/v2/oauth2"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandlerConsent(t *testing.T) {
@@ -44,3 +47,130 @@ func TestHandlerConsent(t *testing.T) {
 
 	assert.NotEmpty(t, body)
 }
+
+func TestHandlerSecurityHeaders(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	r := x.NewRouterAdmin(conf.AdminURL)
+	reg.OAuth2Handler().SetRoutes(r, &httprouterx.RouterPublic{Router: r.Router}, func(h http.Handler) http.Handler {
+		return h
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	get := func(t *testing.T, forwardedProto string) http.Header {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+oauth2.DefaultErrorPath+"?error=invalid_request", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Forwarded-Proto", forwardedProto)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.Header
+	}
+
+	t.Run("case=defaults", func(t *testing.T) {
+		h := get(t, "http")
+		assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+		assert.Equal(t, "no-referrer", h.Get("Referrer-Policy"))
+		assert.Equal(t, "DENY", h.Get("X-Frame-Options"))
+		assert.Regexp(t, `^default-src 'none'; script-src 'nonce-[A-Za-z0-9+/=]{24}'; style-src 'nonce-[A-Za-z0-9+/=]{24}';`, h.Get("Content-Security-Policy"))
+		assert.Empty(t, h.Get("Strict-Transport-Security"), "HSTS is only sent over HTTPS")
+
+		assert.Equal(t, "max-age=31536000", get(t, "https").Get("Strict-Transport-Security"))
+	})
+
+	t.Run("case=custom", func(t *testing.T) {
+		conf.MustSet(ctx, config.KeyPublicSecurityHeadersCSP, "default-src 'self'")
+		conf.MustSet(ctx, config.KeyPublicSecurityHeadersFrameOptions, "")
+		conf.MustSet(ctx, config.KeyPublicSecurityHeadersHSTSMaxAge, "1h")
+		conf.MustSet(ctx, config.KeyPublicSecurityHeadersHSTSSubdomains, true)
+		conf.MustSet(ctx, config.KeyPublicSecurityHeadersHSTSPreload, true)
+
+		h := get(t, "https")
+		assert.Equal(t, "default-src 'self'", h.Get("Content-Security-Policy"))
+		assert.Empty(t, h.Get("X-Frame-Options"))
+		assert.Equal(t, "max-age=3600; includeSubDomains; preload", h.Get("Strict-Transport-Security"))
+	})
+
+	t.Run("case=disabled", func(t *testing.T) {
+		conf.MustSet(ctx, config.KeyPublicSecurityHeadersEnabled, false)
+
+		h := get(t, "https")
+		assert.Empty(t, h.Get("Content-Security-Policy"))
+		assert.Empty(t, h.Get("Strict-Transport-Security"))
+	})
+}
+
+func TestHandlerLocalizedPages(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	r := x.NewRouterAdmin(conf.AdminURL)
+	reg.OAuth2Handler().SetRoutes(r, &httprouterx.RouterPublic{Router: r.Router}, func(h http.Handler) http.Handler {
+		return h
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	get := func(t *testing.T, path, acceptLanguage string) string {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("case=english by default", func(t *testing.T) {
+		body := get(t, oauth2.DefaultErrorPath+"?error=invalid_request", "de")
+		assert.Contains(t, body, `<html lang="en">`)
+		assert.Contains(t, body, "An OAuth 2.0 Error Occurred")
+		assert.Contains(t, body, "configuration key <code>urls.error</code> is not set")
+	})
+
+	conf.MustSet(ctx, config.KeyI18nMessages, map[string]interface{}{
+		"de": map[string]interface{}{
+			"error_page.title":             "Ein OAuth 2.0-Fehler ist aufgetreten",
+			"fallback_page.not_configured": "Diese Seite wird angezeigt, weil <%s> nicht gesetzt ist.",
+			"post_logout_page.title":       "Sie haben sich erfolgreich abgemeldet!",
+			"invalid_client":               "Die Client-Authentifizierung ist fehlgeschlagen.",
+		},
+	})
+
+	t.Run("case=translates the error page", func(t *testing.T) {
+		body := get(t, oauth2.DefaultErrorPath+"?error=invalid_request", "de-CH, en;q=0.5")
+		assert.Contains(t, body, `<html lang="de">`)
+		assert.Contains(t, body, "Ein OAuth 2.0-Fehler ist aufgetreten")
+		assert.Contains(t, body, "Diese Seite wird angezeigt, weil &lt;<code>urls.error</code>&gt; nicht gesetzt ist.", "translations are escaped")
+		assert.Contains(t, body, "The OAuth2 request resulted in an error.", "untranslated messages are shown in english")
+	})
+
+	t.Run("case=translates the post logout page", func(t *testing.T) {
+		assert.Contains(t, get(t, oauth2.DefaultPostLogoutPath, "de"), "Sie haben sich erfolgreich abgemeldet!")
+	})
+
+	t.Run("case=forwards translated errors to the error page", func(t *testing.T) {
+		hc := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		res, err := hc.Get(ts.URL + oauth2.AuthPath + "?" + url.Values{
+			"client_id":     {"unknown"},
+			"response_type": {"code"},
+			"state":         {"state-value"},
+			"ui_locales":    {"de"},
+		}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusFound, res.StatusCode)
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "invalid_client", location.Query().Get("error"))
+		assert.True(t, strings.HasPrefix(location.Query().Get("error_description"), "Die Client-Authentifizierung ist fehlgeschlagen."), location.Query().Get("error_description"))
+		assert.Equal(t, "de", location.Query().Get("ui_locales"))
+	})
+}
@@ -369,3 +369,62 @@ func TestHandlerWellKnown(t *testing.T) {
 		snapshotx.SnapshotT(t, wellKnownResp)
 	})
 }
+
+func TestHandlerWellKnownCache(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyOIDCDiscoveryExtraFields, map[string]interface{}{
+		"service_documentation":    "https://example.org/docs",
+		"response_modes_supported": []string{"query"},
+	})
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	r := x.NewRouterAdmin(conf.AdminURL)
+	oauth2.NewHandler(reg, conf).SetRoutes(r, &httprouterx.RouterPublic{Router: r.Router}, func(h http.Handler) http.Handler {
+		return h
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	get := func(t *testing.T, etag string) (*http.Response, map[string]interface{}) {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+oauth2.WellKnownPath, nil)
+		require.NoError(t, err)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var doc map[string]interface{}
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&doc))
+		}
+		return res, doc
+	}
+
+	res, doc := get(t, "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "https://example.org/docs", doc["service_documentation"])
+	assert.Equal(t, []interface{}{"query"}, doc["response_modes_supported"])
+	assert.Equal(t, conf.IssuerURL(ctx).String(), doc["issuer"])
+	etag := res.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	res, _ = get(t, etag)
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+	assert.Equal(t, etag, res.Header.Get("ETag"))
+
+	t.Run("case=serves the cached document until it expires", func(t *testing.T) {
+		conf.MustSet(ctx, config.KeyOIDCDiscoveryExtraFields, map[string]interface{}{"service_documentation": "https://example.org/v2"})
+		res, doc := get(t, "")
+		assert.Equal(t, "https://example.org/docs", doc["service_documentation"])
+		assert.Equal(t, etag, res.Header.Get("ETag"))
+
+		conf.MustSet(ctx, config.KeyOIDCDiscoveryCacheTTL, "0s")
+		res, doc = get(t, etag)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "https://example.org/v2", doc["service_documentation"])
+		assert.NotEqual(t, etag, res.Header.Get("ETag"))
+	})
+}
@@ -3,6 +3,13 @@
 
 package oauth2
 
+import (
+	"encoding/json"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+)
+
 // Introspection contains an access token's session data as specified by
 // [IETF RFC 7662](https://tools.ietf.org/html/rfc7662)
 //
@@ -70,4 +77,76 @@ type Introspection struct {
 
 	// Extra is arbitrary data set by the session.
 	Extra map[string]interface{} `json:"ext,omitempty"`
+
+	// ClientName is the name of the OAuth 2.0 client. It is only set if enabled in `oauth2.introspection.metadata`.
+	ClientName string `json:"client_name,omitempty"`
+
+	// ClientOwner is the owner of the OAuth 2.0 client. It is only set if enabled in `oauth2.introspection.metadata`.
+	ClientOwner string `json:"client_owner,omitempty"`
+
+	// ClientMetadata is the metadata of the OAuth 2.0 client. It is only set if enabled in
+	// `oauth2.introspection.metadata`.
+	ClientMetadata json.RawMessage `json:"client_metadata,omitempty"`
+
+	// ACR is the authentication context class reference of the login. It is only set if enabled in
+	// `oauth2.introspection.metadata`.
+	ACR string `json:"acr,omitempty"`
+
+	// AMR are the authentication methods references of the login. They are only set if enabled in
+	// `oauth2.introspection.metadata`.
+	AMR []string `json:"amr,omitempty"`
+
+	// GrantType is the grant type with which the token was originally issued. It is only set if enabled in
+	// `oauth2.introspection.metadata`.
+	GrantType string `json:"grant_type,omitempty"`
+
+	// AuthorizationRequest are the parameters of the authorization request with which the token was originally issued.
+	// They are only set if enabled in `oauth2.introspection.metadata` when the token was issued.
+	AuthorizationRequest map[string][]string `json:"authorization_request,omitempty"`
+
+	// GrantID identifies the grant with which the token was issued. All tokens issued by refreshing the grant share it,
+	// so it identifies the refresh token family. It is only set if enabled in `oauth2.introspection.metadata`.
+	GrantID string `json:"grant_id,omitempty"`
+
+	// GrantChain are the grant types with which the tokens of the grant were issued, in order, for example
+	// `["authorization_code", "refresh_token"]`. It is only set if enabled in `oauth2.introspection.metadata`.
+	GrantChain []string `json:"grant_chain,omitempty"`
+}
+
+// withMetadata adds the client and grant metadata which is enabled in the configuration.
+func (i *Introspection) withMetadata(enabled []string, ar fosite.Requester, session *Session) *Introspection {
+	cl, _ := ar.GetClient().(*client.Client)
+	for _, field := range enabled {
+		switch field {
+		case "client_name":
+			if cl != nil {
+				i.ClientName = cl.Name
+			}
+		case "client_owner":
+			if cl != nil {
+				i.ClientOwner = cl.Owner
+			}
+		case "client_metadata":
+			if cl != nil && len(cl.Metadata) > 0 && string(cl.Metadata) != "null" {
+				i.ClientMetadata = json.RawMessage(cl.Metadata)
+			}
+		case "acr":
+			if claims := session.IDTokenClaims(); claims != nil {
+				i.ACR = claims.AuthenticationContextClassReference
+			}
+		case "amr":
+			if claims := session.IDTokenClaims(); claims != nil {
+				i.AMR = claims.AuthenticationMethodsReferences
+			}
+		case "grant_type":
+			i.GrantType = session.GrantType
+		case "authorization_request":
+			i.AuthorizationRequest = session.AuthorizationRequest
+		case "grant_id":
+			i.GrantID = ar.GetID()
+		case "grant_chain":
+			i.GrantChain = session.GrantChain
+		}
+	}
+	return i
 }
@@ -12,7 +12,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	goauth2 "golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
 	hydra "github.com/ory/hydra-client-go/v2"
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
 
 	"github.com/ory/x/httprouterx"
 
@@ -165,3 +172,55 @@ func TestIntrospectorSDK(t *testing.T) {
 		}
 	})
 }
+
+func TestIntrospectionMetadata(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	public, admin := testhelpers.NewOAuth2Server(ctx, t, reg)
+	reg.Config().MustSet(ctx, config.KeyAccessTokenLifespan, time.Hour)
+
+	secret := uuid.New().String()
+	c := &hc.Client{
+		Name:       "gateway test",
+		Owner:      "team-a",
+		Metadata:   []byte(`{"labels":["internal"]}`),
+		Secret:     secret,
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "foo",
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+	conf := &goauth2.Config{ClientID: c.GetID(), ClientSecret: secret}
+
+	token, err := (&clientcredentials.Config{
+		ClientID:     c.GetID(),
+		ClientSecret: secret,
+		TokenURL:     public.URL + oauth2.TokenPath,
+		Scopes:       []string{"foo"},
+		AuthStyle:    goauth2.AuthStyleInHeader,
+	}).Token(ctx)
+	require.NoError(t, err)
+
+	t.Run("case=omits metadata by default", func(t *testing.T) {
+		i := testhelpers.IntrospectToken(t, conf, token.AccessToken, admin)
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		for _, field := range []string{"client_name", "client_owner", "client_metadata", "grant_type", "grant_id", "grant_chain"} {
+			assert.False(t, i.Get(field).Exists(), "%s: %s", field, i.Raw)
+		}
+	})
+
+	t.Run("case=adds the enabled metadata", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, []string{"client_name", "client_owner", "client_metadata", "acr", "grant_type", "grant_id", "grant_chain"})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, nil) })
+
+		i := testhelpers.IntrospectToken(t, conf, token.AccessToken, admin)
+		assert.True(t, i.Get("active").Bool(), i.Raw)
+		assert.Equal(t, "gateway test", i.Get("client_name").String(), i.Raw)
+		assert.Equal(t, "team-a", i.Get("client_owner").String(), i.Raw)
+		assert.Equal(t, "internal", i.Get("client_metadata.labels.0").String(), i.Raw)
+		assert.Equal(t, "client_credentials", i.Get("grant_type").String(), i.Raw)
+		assert.NotEmpty(t, i.Get("grant_id").String(), i.Raw)
+		assert.Equal(t, `["client_credentials"]`, i.Get("grant_chain").Raw, i.Raw)
+		assert.False(t, i.Get("acr").Exists(), i.Raw)
+	})
+}
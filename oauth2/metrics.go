@@ -0,0 +1,269 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/driver/config"
+)
+
+const (
+	metricsEndpointToken      = "token"
+	metricsEndpointAuthorize  = "authorize"
+	metricsEndpointIntrospect = "introspect"
+	metricsEndpointRevoke     = "revoke"
+
+	// metricsOther is the label value of client IDs and grant types which are not recorded individually.
+	metricsOther = "other"
+)
+
+// metricsGrantTypes are the grant types which are recorded individually. Other grant types are recorded as "other",
+// because the grant type is chosen by the client.
+var metricsGrantTypes = map[string]bool{
+	string(fosite.GrantTypeAuthorizationCode): true,
+	string(fosite.GrantTypeRefreshToken):      true,
+	string(fosite.GrantTypeClientCredentials): true,
+	string(fosite.GrantTypePassword):          true,
+	string(fosite.GrantTypeJWTBearer):         true,
+	"implicit":                                true,
+	"hybrid":                                  true,
+}
+
+// Metrics records the requests to the token, authorization, introspection and revocation endpoints, and the token
+// requests which exceed a token quota.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	quotas   *prometheus.CounterVec
+	c        *config.DefaultProvider
+
+	sync.Mutex
+	clients map[string]struct{}
+}
+
+// NewMetrics registers the metrics with the registerer. Metrics which are already registered, for example by another
+// registry in the same process, are reused.
+func NewMetrics(reg prometheus.Registerer, c *config.DefaultProvider) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "hydra",
+			Name:      "oauth2_requests_total",
+			Help:      "The number of requests to the OAuth 2.0 endpoints.",
+		}, []string{"endpoint", "grant_type", "client_id", "status", "error"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "hydra",
+			Name:      "oauth2_request_duration_seconds",
+			Help:      "The duration of requests to the OAuth 2.0 endpoints.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint", "grant_type", "status"}),
+		quotas: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "hydra",
+			Name:      "oauth2_token_quota_exceeded_total",
+			Help:      "The number of token requests which exceed the soft or hard limit of a token quota.",
+		}, []string{"client_id", "window", "limit"}),
+		c:       c,
+		clients: map[string]struct{}{},
+	}
+
+	if err := reg.Register(m.requests); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			m.requests = are.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+	if err := reg.Register(m.duration); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			m.duration = are.ExistingCollector.(*prometheus.HistogramVec)
+		}
+	}
+	if err := reg.Register(m.quotas); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			m.quotas = are.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+	return m
+}
+
+// recordTokenQuotaExceeded records a token request which exceeds the soft or hard limit of a token quota. It is
+// labeled with the client ID of the quota, which is "*" for all clients without an explicitly configured quota, so
+// that the number of label values is bounded by the configuration.
+func (m *Metrics) recordTokenQuotaExceeded(quota *config.TokenQuota, window, limit string) {
+	m.quotas.WithLabelValues(quota.ClientID, window, limit).Inc()
+}
+
+// Handler records the requests handled by next.
+func (m *Metrics) Handler(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &metricsResponseWriter{ResponseWriter: w}
+		start := time.Now()
+		next(rw, r)
+		m.record(endpoint, r, rw, time.Since(start))
+	}
+}
+
+// Handle records the requests handled by next.
+func (m *Metrics) Handle(endpoint string, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		m.Handler(endpoint, func(w http.ResponseWriter, r *http.Request) { next(w, r, ps) })(w, r)
+	}
+}
+
+func (m *Metrics) record(endpoint string, r *http.Request, rw *metricsResponseWriter, d time.Duration) {
+	status := strconv.Itoa(rw.status())
+	grantType := metricsGrantType(endpoint, r)
+	m.requests.WithLabelValues(endpoint, grantType, m.clientID(endpoint, r), status, rw.oauth2Error()).Inc()
+	m.duration.WithLabelValues(endpoint, grantType, status).Observe(d.Seconds())
+}
+
+// clientID returns the client_id label of the request. Requests of clients which are not allowed, or which exceed the
+// maximum number of client IDs, are labeled "other".
+func (m *Metrics) clientID(endpoint string, r *http.Request) string {
+	if !m.c.OAuth2MetricsClientIDsEnabled() || endpoint == metricsEndpointIntrospect {
+		return ""
+	}
+
+	id := r.Form.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		if unescaped, err := url.QueryUnescape(user); err == nil {
+			id = unescaped
+		}
+	}
+	if id == "" {
+		return ""
+	}
+
+	if allowed := m.c.OAuth2MetricsClientIDsAllowed(); len(allowed) > 0 {
+		for _, a := range allowed {
+			if a == id {
+				return id
+			}
+		}
+		return metricsOther
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.clients[id]; ok {
+		return id
+	}
+	if len(m.clients) >= m.c.OAuth2MetricsClientIDsMax() {
+		return metricsOther
+	}
+	m.clients[id] = struct{}{}
+	return id
+}
+
+// metricsGrantType returns the grant_type label of the request. Authorization requests are labeled with the grant
+// type of their response type.
+func metricsGrantType(endpoint string, r *http.Request) string {
+	var grantType string
+	switch endpoint {
+	case metricsEndpointToken:
+		grantType = r.PostForm.Get("grant_type")
+	case metricsEndpointAuthorize:
+		responseTypes := fosite.RemoveEmpty(strings.Split(r.Form.Get("response_type"), " "))
+		switch {
+		case len(responseTypes) == 0:
+			return ""
+		case len(responseTypes) == 1 && responseTypes[0] == "code":
+			grantType = string(fosite.GrantTypeAuthorizationCode)
+		case fosite.Arguments(responseTypes).Has("code"):
+			grantType = "hybrid"
+		default:
+			grantType = "implicit"
+		}
+	default:
+		return ""
+	}
+
+	if grantType == "" || metricsGrantTypes[grantType] {
+		return grantType
+	}
+	return metricsOther
+}
+
+// metricsResponseWriter records the status code of the response and the beginning of error responses.
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+const metricsMaxErrorBody = 4096
+
+func (w *metricsResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *metricsResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.code >= 400 && w.body.Len() < metricsMaxErrorBody {
+		w.body.Write(b[:min(len(b), metricsMaxErrorBody-w.body.Len())])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *metricsResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *metricsResponseWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// oauth2Error returns the OAuth 2.0 error code of the response, which is either contained in the JSON body or, for
+// authorization requests, in the redirect URL.
+func (w *metricsResponseWriter) oauth2Error() string {
+	var code string
+	switch status := w.status(); {
+	case status >= 300 && status < 400:
+		location, err := url.Parse(w.Header().Get("Location"))
+		if err != nil {
+			return ""
+		}
+		code = location.Query().Get("error")
+		if fragment, err := url.ParseQuery(location.Fragment); code == "" && err == nil {
+			code = fragment.Get("error")
+		}
+	case status >= 400:
+		if res := gjson.GetBytes(w.body.Bytes(), "error"); res.Type == gjson.String {
+			code = res.String()
+		} else {
+			code = gjson.GetBytes(w.body.Bytes(), "error.id").String()
+		}
+	}
+
+	// Error codes consist of a limited set of ASCII characters, anything else was not written by an OAuth 2.0 error.
+	if len(code) > 64 || strings.ContainsFunc(code, func(r rune) bool {
+		return !(r == '_' || r == '.' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		return metricsOther
+	}
+	return code
+}
@@ -0,0 +1,88 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyOAuth2MetricsClientIDsEnabled, true)
+	conf.MustSet(ctx, config.KeyOAuth2MetricsClientIDsMax, 2)
+
+	reg := prometheus.NewRegistry()
+	m := oauth2.NewMetrics(reg, conf)
+
+	tokenEndpoint := m.Handler("token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") == "invalid" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"The code is invalid."}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"token"}`))
+	})
+	authorizeEndpoint := m.Handler("authorize", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		http.Redirect(w, r, "https://client.example/callback#error=access_denied&state=state", http.StatusSeeOther)
+	})
+	requestToken := func(clientID string, form url.Values) {
+		r := httptest.NewRequest("POST", "/oauth2/token", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.SetBasicAuth(url.QueryEscape(clientID), "secret")
+		tokenEndpoint(httptest.NewRecorder(), r)
+	}
+	requests := func(labels ...string) float64 {
+		families, err := reg.Gather()
+		assert.NoError(t, err)
+		for _, f := range families {
+			if f.GetName() != "hydra_oauth2_requests_total" {
+				continue
+			}
+		metrics:
+			for _, metric := range f.GetMetric() {
+				for i, l := range metric.GetLabel() {
+					if l.GetValue() != labels[i] {
+						continue metrics
+					}
+				}
+				return metric.GetCounter().GetValue()
+			}
+		}
+		return 0
+	}
+
+	requestToken("client-a", url.Values{"grant_type": {"authorization_code"}, "code": {"invalid"}})
+	requestToken("client-a", url.Values{"grant_type": {"authorization_code"}, "code": {"invalid"}})
+	requestToken("client-b", url.Values{"grant_type": {"client_credentials"}})
+	requestToken("client-c", url.Values{"grant_type": {"client_credentials"}})
+	requestToken("client-a", url.Values{"grant_type": {"made-up"}})
+
+	// Labels are sorted by name: client_id, endpoint, error, grant_type, status.
+	assert.EqualValues(t, 2, requests("client-a", "token", "invalid_grant", "authorization_code", "400"))
+	assert.EqualValues(t, 1, requests("client-b", "token", "", "client_credentials", "200"))
+	assert.EqualValues(t, 1, requests("other", "token", "", "client_credentials", "200"), "the client IDs are limited to the maximum")
+	assert.EqualValues(t, 1, requests("client-a", "token", "", "other", "200"), "unknown grant types are not recorded individually")
+
+	authorizeEndpoint(httptest.NewRecorder(), httptest.NewRequest("GET", "/oauth2/auth?response_type=id_token+token&client_id=client-b", nil))
+	assert.EqualValues(t, 1, requests("client-b", "authorize", "access_denied", "implicit", "303"))
+
+	assert.Equal(t, 4, testutil.CollectAndCount(reg, "hydra_oauth2_request_duration_seconds"), "the durations are not labeled with the client ID")
+}
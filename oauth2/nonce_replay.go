@@ -0,0 +1,23 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+
+	"github.com/ory/fosite"
+)
+
+// useOpenIDConnectNonce rejects the authentication request if nonce replay protection is enabled and the client
+// already used its nonce while the ID tokens issued for it are valid. The ID token may be issued as late as the
+// authorization code is exchanged.
+func (h *Handler) useOpenIDConnectNonce(ctx context.Context, ar fosite.AuthorizeRequester) error {
+	nonce := ar.GetRequestForm().Get("nonce")
+	if !h.c.OIDCNonceReplayProtection(ctx) || nonce == "" || !ar.GetGrantedScopes().Has("openid") {
+		return nil
+	}
+
+	exp := h.r.Clock().Now().Add(h.c.GetAuthorizeCodeLifespan(ctx) + h.c.GetIDTokenLifespan(ctx))
+	return h.r.OAuth2Storage().UseOpenIDConnectNonce(ctx, ar.GetClient().GetID(), nonce, exp)
+}
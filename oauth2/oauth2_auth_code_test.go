@@ -408,6 +408,46 @@ func TestAuthCodeWithDefaultStrategy(t *testing.T) {
 				}
 			})
 
+			t.Run("followup=successfully create an SD-JWT verifiable credential", func(t *testing.T) {
+				t.Parallel()
+
+				pubKey, privKey, err := josex.NewSigningKey(jose.ES256, 0)
+				require.NoError(t, err)
+				pubKeyJWK := &jose.JSONWebKey{Key: pubKey, Algorithm: string(jose.ES256)}
+				vc, vcErr := createVerifiableCredential(t, reg, token, &hydraoauth2.CreateVerifiableCredentialRequestBody{
+					Format: hydraoauth2.VerifiableCredentialFormatSDJWT,
+					Types:  []string{"UserInfoCredential"},
+					Proof: &hydraoauth2.VerifiableCredentialProof{
+						ProofType: "jwt",
+						JWT:       createVCProofJWT(t, pubKeyJWK, privKey, vcNonce),
+					},
+				})
+				require.Nil(t, vcErr)
+				require.NotNil(t, vc)
+				assert.Equal(t, hydraoauth2.VerifiableCredentialFormatSDJWT, vc.Format)
+
+				issuerSigned, _, ok := strings.Cut(vc.Credential, "~")
+				require.True(t, ok)
+				parsed, err := jwt.Parse(issuerSigned, func(token *jwt.Token) (interface{}, error) {
+					return x.Must(reg.OpenIDJWTStrategy().GetPublicKey(ctx)).Key, nil
+				})
+				require.NoError(t, err)
+				assert.Equal(t, hydraoauth2.VerifiableCredentialFormatSDJWT, parsed.Header["typ"])
+
+				claims := parsed.Claims.(jwt.MapClaims)
+				assert.Equal(t, "UserInfoCredential", claims["vct"])
+				assert.NotContains(t, claims, "sub")
+				cnf, err := json.Marshal(claims["cnf"].(map[string]interface{})["jwk"])
+				require.NoError(t, err)
+				expected, err := pubKeyJWK.MarshalJSON()
+				require.NoError(t, err)
+				assert.JSONEq(t, string(expected), string(cnf))
+
+				disclosed, err := x.DisclosedClaims(claims, vc.Credential)
+				require.NoError(t, err)
+				assert.Equal(t, subject, disclosed["sub"])
+			})
+
 			t.Run("followup=get new nonce from priming request", func(t *testing.T) {
 				t.Parallel()
 				// Assert that we can fetch a verifiable credential with the nonce.
@@ -679,6 +719,58 @@ func TestAuthCodeWithDefaultStrategy(t *testing.T) {
 		assertIDToken(t, token, conf, subject, nonce, time.Now().Add(reg.Config().GetIDTokenLifespan(ctx)))
 	})
 
+	t.Run("case=introspection exposes the authorization request if enabled", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, []string{"authorization_request"})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, nil) })
+
+		c, conf := newOAuth2Client(t, reg, testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler))
+		testhelpers.NewLoginConsentUI(t, reg.Config(),
+			acceptLoginHandler(t, c, subject, nil),
+			acceptConsentHandler(t, c, subject, nil))
+
+		code, _ := getAuthorizeCode(t, conf, nil,
+			oauth2.SetAuthURLParam("audience", "https://api.ory.sh/"),
+			oauth2.SetAuthURLParam("nonce", nonce))
+		require.NotEmpty(t, code)
+
+		token, err := conf.Exchange(context.Background(), code)
+		require.NoError(t, err)
+
+		claims := introspectAccessToken(t, conf, token, subject)
+		assert.Equal(t, "https://api.ory.sh/", claims.Get("authorization_request.audience.0").String(), claims.Raw)
+		assert.Equal(t, nonce, claims.Get("authorization_request.nonce.0").String(), claims.Raw)
+		assert.Equal(t, c.GetID(), claims.Get("authorization_request.client_id.0").String(), claims.Raw)
+	})
+
+	t.Run("case=introspection exposes the grant chain if enabled", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, []string{"grant_id", "grant_chain"})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, nil) })
+
+		c, conf := newOAuth2Client(t, reg, testhelpers.NewCallbackURL(t, "callback", testhelpers.HTTPServerNotImplementedHandler))
+		testhelpers.NewLoginConsentUI(t, reg.Config(),
+			acceptLoginHandler(t, c, subject, nil),
+			acceptConsentHandler(t, c, subject, nil))
+
+		code, _ := getAuthorizeCode(t, conf, nil, oauth2.SetAuthURLParam("nonce", nonce))
+		require.NotEmpty(t, code)
+		token, err := conf.Exchange(context.Background(), code)
+		require.NoError(t, err)
+
+		claims := introspectAccessToken(t, conf, token, subject)
+		grantID := claims.Get("grant_id").String()
+		assert.NotEmpty(t, grantID, claims.Raw)
+		assert.Equal(t, `["authorization_code"]`, claims.Get("grant_chain").Raw, claims.Raw)
+
+		token.Expiry = token.Expiry.Add(-time.Hour * 24)
+		refreshed, err := conf.TokenSource(context.Background(), token).Token()
+		require.NoError(t, err)
+		require.NotEqual(t, token.AccessToken, refreshed.AccessToken)
+
+		claims = introspectAccessToken(t, conf, refreshed, subject)
+		assert.Equal(t, grantID, claims.Get("grant_id").String(), claims.Raw)
+		assert.Equal(t, `["authorization_code","refresh_token"]`, claims.Get("grant_chain").Raw, claims.Raw)
+	})
+
 	t.Run("case=respects client token lifespan configuration", func(t *testing.T) {
 		run := func(t *testing.T, strategy string, c *client.Client, conf *oauth2.Config, expectedLifespans client.Lifespans) {
 			testhelpers.NewLoginConsentUI(t, reg.Config(),
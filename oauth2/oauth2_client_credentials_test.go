@@ -389,4 +389,71 @@ func TestClientCredentials(t *testing.T) {
 		t.Run("strategy=opaque", run("opaque"))
 		t.Run("strategy=jwt", run("jwt"))
 	})
+
+	t.Run("case=should return cached tokens to clients which opted in", func(t *testing.T) {
+		run := func(strategy string) func(t *testing.T) {
+			return func(t *testing.T) {
+				reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, strategy)
+
+				newCachingClient := func(t *testing.T) clientcredentials.Config {
+					_, conf := newCustomClient(t, &hc.Client{
+						Secret:     uuid.New().String(),
+						GrantTypes: []string{"client_credentials"},
+						Scope:      "foo bar",
+						Audience:   []string{"https://api.ory.sh/"},
+						Lifespans: hc.Lifespans{
+							ClientCredentialsGrantAccessTokenCacheLifespan: x.NullDuration{Duration: time.Hour, Valid: true},
+						},
+					})
+					return conf
+				}
+
+				t.Run("case=same scope and audience", func(t *testing.T) {
+					conf := newCachingClient(t)
+					first, err := getToken(t, conf)
+					require.NoError(t, err)
+					second, err := getToken(t, conf)
+					require.NoError(t, err)
+
+					assert.Equal(t, first.AccessToken, second.AccessToken)
+					assert.WithinDuration(t, first.Expiry, second.Expiry, 2*time.Second, "the cached token expires at the same time")
+				})
+
+				t.Run("case=different scope", func(t *testing.T) {
+					conf := newCachingClient(t)
+					first, err := getToken(t, conf)
+					require.NoError(t, err)
+					conf.Scopes = []string{"foo"}
+					second, err := getToken(t, conf)
+					require.NoError(t, err)
+
+					assert.NotEqual(t, first.AccessToken, second.AccessToken)
+				})
+
+				t.Run("case=revoked token", func(t *testing.T) {
+					conf := newCachingClient(t)
+					first, err := getToken(t, conf)
+					require.NoError(t, err)
+					require.NoError(t, reg.OAuth2Storage().DeleteAccessTokens(ctx, conf.ClientID))
+					second, err := getToken(t, conf)
+					require.NoError(t, err)
+
+					assert.NotEqual(t, first.AccessToken, second.AccessToken)
+				})
+
+				t.Run("case=client did not opt in", func(t *testing.T) {
+					_, conf := newClient(t)
+					first, err := getToken(t, conf)
+					require.NoError(t, err)
+					second, err := getToken(t, conf)
+					require.NoError(t, err)
+
+					assert.NotEqual(t, first.AccessToken, second.AccessToken)
+				})
+			}
+		}
+
+		t.Run("strategy=opaque", run("opaque"))
+		t.Run("strategy=jwt", run("jwt"))
+	})
 }
@@ -0,0 +1,73 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/extension"
+	"github.com/ory/x/errorsx"
+)
+
+// PluginHook is an AccessRequestHook called for all grant types. It lets the plugins authorize the token request
+// and then mutate the claims of the tokens.
+func PluginHook(reg extension.Registry) AccessRequestHook {
+	return func(ctx context.Context, requester fosite.AccessRequester) error {
+		for _, p := range extension.Hooks[extension.GrantAuthorizer](reg.Plugins()) {
+			if err := p.AuthorizeGrant(ctx, requester); err != nil {
+				return pluginError(p, err)
+			}
+		}
+
+		mutators := extension.Hooks[extension.ClaimsMutator](reg.Plugins())
+		if len(mutators) == 0 {
+			return nil
+		}
+
+		session, ok := requester.GetSession().(*Session)
+		if !ok {
+			return nil
+		}
+		if session.Extra == nil {
+			session.Extra = map[string]interface{}{}
+		}
+		idTokenClaims := session.IDTokenClaims()
+		if idTokenClaims.Extra == nil {
+			idTokenClaims.Extra = map[string]interface{}{}
+		}
+
+		for _, p := range mutators {
+			if err := p.MutateClaims(ctx, requester, session.Extra, idTokenClaims.Extra); err != nil {
+				return pluginError(p, err)
+			}
+		}
+		return nil
+	}
+}
+
+// validatePluginAuthorizeRequest lets the plugins validate the authorization request before the user is sent to
+// the login provider.
+func validatePluginAuthorizeRequest(ctx context.Context, reg extension.Registry, ar fosite.AuthorizeRequester) error {
+	for _, p := range extension.Hooks[extension.AuthorizeRequestValidator](reg.Plugins()) {
+		if err := p.ValidateAuthorizeRequest(ctx, ar); err != nil {
+			return pluginError(p, err)
+		}
+	}
+	return nil
+}
+
+// pluginError returns OAuth 2.0 errors of plugins as they are and all other errors as server errors.
+func pluginError(p extension.Plugin, err error) error {
+	var rfcErr *fosite.RFC6749Error
+	if errors.As(err, &rfcErr) {
+		return err
+	}
+	return errorsx.WithStack(fosite.ErrServerError.
+		WithWrap(err).
+		WithDescription("An error occurred while executing a plugin.").
+		WithDebugf("Plugin %s failed: %s", p.Name(), err))
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ory/hydra/v2/aead"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/extension"
 	"github.com/ory/hydra/v2/jwk"
 	"github.com/ory/hydra/v2/oauth2/trust"
 	"github.com/ory/hydra/v2/x"
@@ -20,7 +21,10 @@ type InternalRegistry interface {
 	trust.Registry
 	x.RegistryWriter
 	x.RegistryLogger
+	x.HTTPClientProvider
+	x.ClockProvider
 	consent.Registry
+	extension.Registry
 	Registry
 	FlowCipher() *aead.XChaCha20Poly1305
 }
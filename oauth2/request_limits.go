@@ -0,0 +1,43 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+)
+
+// limitAuthorizeRequest rejects authorization requests which exceed the configured request limits before fosite
+// parses them. The body of posted authorization requests is read up to the limit only.
+func (h *Handler) limitAuthorizeRequest(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	maxLength := h.c.OAuth2MaxAuthorizationURLLength(ctx)
+	if len(r.URL.RawQuery) > maxLength {
+		return errorsx.WithStack(x.ErrRequestTooLarge.WithHintf("The authorization request URL exceeds the maximum length of %d bytes.", maxLength))
+	}
+
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(maxLength))
+	}
+	if err := r.ParseForm(); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return errorsx.WithStack(x.ErrRequestTooLarge.WithHintf("The authorization request body exceeds the maximum length of %d bytes.", maxLength))
+		}
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Unable to parse HTTP body, make sure to send a properly formatted form request body.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	// Request objects passed by reference are limited when they are fetched, see fositex.Config.GetHTTPClient.
+	maxSize := h.c.OAuth2MaxRequestObjectSize(ctx)
+	if len(r.Form.Get("request")) > maxSize {
+		return errorsx.WithStack(fosite.ErrInvalidRequestObject.WithHintf("The request object exceeds the maximum size of %d bytes.", maxSize))
+	}
+	return nil
+}
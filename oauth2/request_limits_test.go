@@ -0,0 +1,127 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/httprouterx"
+)
+
+func TestAuthorizeRequestLimits(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyOAuth2MaxAuthorizationURLLength, 4096)
+	conf.MustSet(ctx, config.KeyOAuth2MaxRequestObjectSize, 1024)
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	r := x.NewRouterAdmin(conf.AdminURL)
+	reg.OAuth2Handler().SetRoutes(r, &httprouterx.RouterPublic{Router: r.Router}, func(h http.Handler) http.Handler {
+		return h
+	})
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	requestObjects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 2048)))
+	}))
+	t.Cleanup(requestObjects.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	c := &client.Client{
+		ID:            "request-limits",
+		ResponseTypes: []string{"code"},
+		GrantTypes:    []string{"authorization_code"},
+		RedirectURIs:  []string{"https://client.example/callback"},
+		RequestURIs:   []string{requestObjects.URL},
+		Scope:         "openid",
+		JSONWebKeys: &x.JoseJSONWebKeySet{JSONWebKeySet: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: key.Public(), KeyID: "request-object", Use: "sig", Algorithm: string(jose.ES256)},
+		}}},
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	query := func(params url.Values) url.Values {
+		q := url.Values{
+			"client_id":     {c.GetID()},
+			"redirect_uri":  {c.GetRedirectURIs()[0]},
+			"response_type": {"code"},
+			"scope":         {"openid"},
+			"state":         {"state-state-state"},
+		}
+		for k, v := range params {
+			q[k] = v
+		}
+		return q
+	}
+
+	cl := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	location := func(t *testing.T, req *http.Request) *url.URL {
+		res, err := cl.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		l, err := res.Location()
+		require.NoError(t, err)
+		return l
+	}
+	get := func(t *testing.T, params url.Values) *url.URL {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+oauth2.AuthPath+"?"+query(params).Encode(), nil)
+		require.NoError(t, err)
+		return location(t, req)
+	}
+	post := func(t *testing.T, params url.Values) *url.URL {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+oauth2.AuthPath, strings.NewReader(query(params).Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return location(t, req)
+	}
+
+	t.Run("case=accepts authorization requests within the limits", func(t *testing.T) {
+		l := get(t, nil)
+		assert.Equal(t, conf.LoginURL(ctx).Path, l.Path, "%s", l)
+	})
+
+	t.Run("case=rejects authorization request URLs exceeding the limit", func(t *testing.T) {
+		l := get(t, url.Values{"nonce": {strings.Repeat("a", 4096)}})
+		assert.Equal(t, conf.ErrorURL(ctx).Path, l.Path, "%s", l)
+		assert.Equal(t, "request_too_large", l.Query().Get("error"), "%s", l)
+	})
+
+	t.Run("case=rejects posted authorization requests exceeding the limit", func(t *testing.T) {
+		l := post(t, url.Values{"nonce": {strings.Repeat("a", 4096)}})
+		assert.Equal(t, conf.ErrorURL(ctx).Path, l.Path, "%s", l)
+		assert.Equal(t, "request_too_large", l.Query().Get("error"), "%s", l)
+	})
+
+	t.Run("case=rejects request objects exceeding the limit", func(t *testing.T) {
+		l := get(t, url.Values{"request": {strings.Repeat("a", 2048)}})
+		assert.Equal(t, "invalid_request_object", l.Query().Get("error"), "%s", l)
+	})
+
+	t.Run("case=rejects request objects fetched by reference exceeding the limit", func(t *testing.T) {
+		l := get(t, url.Values{"request_uri": {requestObjects.URL}})
+		assert.Equal(t, "invalid_request_uri", l.Query().Get("error"), "%s", l)
+		assert.Contains(t, l.Query().Get("error_description"), "exceeds the maximum size of 1024 bytes", "%s", l)
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v3"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/httprouterx"
+)
+
+func TestRequestObjectSigningKeys(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	r := x.NewRouterAdmin(conf.AdminURL)
+	reg.OAuth2Handler().SetRoutes(r, &httprouterx.RouterPublic{Router: r.Router}, func(h http.Handler) http.Handler {
+		return h
+	})
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+
+	newKey := func(t *testing.T, kid string) *jose.JSONWebKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return &jose.JSONWebKey{Key: key, KeyID: kid, Use: "sig", Algorithm: string(jose.ES256)}
+	}
+	publicKeys := func(keys ...*jose.JSONWebKey) *x.JoseJSONWebKeySet {
+		set := &jose.JSONWebKeySet{}
+		for _, k := range keys {
+			set.Keys = append(set.Keys, k.Public())
+		}
+		return &x.JoseJSONWebKeySet{JSONWebKeySet: set}
+	}
+
+	authKey, firstKey, secondKey := newKey(t, "auth"), newKey(t, "request-object-1"), newKey(t, "request-object-2")
+	c := &client.Client{
+		ID:                              "request-object-signing-keys",
+		ResponseTypes:                   []string{"code"},
+		GrantTypes:                      []string{"authorization_code"},
+		RedirectURIs:                    []string{"https://client.example/callback"},
+		Scope:                           "openid",
+		TokenEndpointAuthMethod:         "private_key_jwt",
+		RequestObjectSigningAlgorithm:   string(jose.ES256),
+		JSONWebKeys:                     publicKeys(authKey),
+		RequestObjectSigningJSONWebKeys: publicKeys(firstKey),
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	authorize := func(t *testing.T, key *jose.JSONWebKey) *url.URL {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", key.KeyID))
+		require.NoError(t, err)
+		request, err := josejwt.Signed(signer).Claims(map[string]interface{}{
+			"client_id":     c.GetID(),
+			"redirect_uri":  c.GetRedirectURIs()[0],
+			"response_type": "code",
+			"scope":         "openid",
+			"state":         "state-state-state",
+		}).CompactSerialize()
+		require.NoError(t, err)
+
+		query := url.Values{
+			"client_id":     {c.GetID()},
+			"response_type": {"code"},
+			"scope":         {"openid"},
+			"request":       {request},
+		}
+		cl := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		res, err := cl.Get(ts.URL + oauth2.AuthPath + "?" + query.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		location, err := res.Location()
+		require.NoError(t, err)
+		return location
+	}
+
+	t.Run("case=accepts request objects signed with a request object signing key", func(t *testing.T) {
+		location := authorize(t, firstKey)
+		assert.Equal(t, conf.LoginURL(ctx).Path, location.Path, "%s", location)
+	})
+
+	t.Run("case=rejects request objects signed with an authentication key", func(t *testing.T) {
+		location := authorize(t, authKey)
+		assert.Equal(t, "invalid_request_object", location.Query().Get("error"), "%s", location)
+	})
+
+	t.Run("case=rotates the request object signing keys", func(t *testing.T) {
+		c.RequestObjectSigningJSONWebKeys = publicKeys(firstKey, secondKey)
+		require.NoError(t, reg.ClientManager().UpdateClient(ctx, c))
+		assert.Equal(t, conf.LoginURL(ctx).Path, authorize(t, firstKey).Path)
+		assert.Equal(t, conf.LoginURL(ctx).Path, authorize(t, secondKey).Path)
+
+		c.RequestObjectSigningJSONWebKeys = publicKeys(secondKey)
+		require.NoError(t, reg.ClientManager().UpdateClient(ctx, c))
+		assert.Equal(t, "invalid_request_object", authorize(t, firstKey).Query().Get("error"))
+		assert.Equal(t, conf.LoginURL(ctx).Path, authorize(t, secondKey).Path)
+	})
+
+	t.Run("case=keeps the keys of the client outside of request objects", func(t *testing.T) {
+		cl, err := reg.OAuth2Storage().GetClient(ctx, c.GetID())
+		require.NoError(t, err)
+		assert.Equal(t, "auth", cl.(*client.Client).GetJSONWebKeys().Keys[0].KeyID)
+
+		cl, err = reg.OAuth2Storage().GetClient(client.WithRequestObjectSigningKeys(ctx), c.GetID())
+		require.NoError(t, err)
+		assert.Equal(t, "request-object-2", cl.(*client.Client).GetJSONWebKeys().Keys[0].KeyID)
+	})
+}
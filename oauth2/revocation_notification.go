@@ -0,0 +1,133 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x/events"
+)
+
+// RevocationNotification is the request body sent to the resource servers configured in
+// `oauth2.revocation_notifications` when tokens issued for their audience are revoked.
+//
+// swagger:ignore
+type RevocationNotification struct {
+	// GrantID identifies the grant whose access and refresh tokens were revoked. It is the grant_id of introspection
+	// responses.
+	GrantID string `json:"grant_id"`
+	// ClientID is the OAuth 2.0 Client the tokens were issued to.
+	ClientID string `json:"client_id"`
+	// Subject is the subject of the tokens.
+	Subject string `json:"subject,omitempty"`
+	// Audience are the audiences the tokens were granted.
+	Audience []string `json:"audience"`
+	// RevokedAt is the time at which the tokens were revoked.
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// revokedRequest returns the request of the token which is about to be revoked, if any resource server is notified
+// of revocations. Tokens which are invalid or expired are not revoked, so there is nothing to notify of.
+func (h *Handler) revokedRequest(ctx context.Context, r *http.Request) fosite.Requester {
+	hooks, err := h.c.OAuth2RevocationNotifications(ctx)
+	if err != nil {
+		h.r.Logger().WithError(err).Error("Unable to read the revocation notifications, resource servers will not be notified.")
+		return nil
+	} else if len(hooks) == 0 {
+		return nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil
+	}
+	_, ar, err := h.r.OAuth2Provider().IntrospectToken(ctx, r.PostForm.Get("token"),
+		fosite.TokenType(r.PostForm.Get("token_type_hint")), NewSessionWithCustomClaims(ctx, h.c, ""))
+	if err != nil {
+		return nil
+	}
+	return ar
+}
+
+// notifyRevocation notifies the resource servers of the audiences granted to the revoked request in the background,
+// so that slow resource servers do not delay the revocation response.
+func (h *Handler) notifyRevocation(ctx context.Context, ar fosite.Requester) {
+	hooks, err := h.c.OAuth2RevocationNotifications(ctx)
+	if err != nil {
+		h.r.Logger().WithError(err).Error("Unable to read the revocation notifications, resource servers will not be notified.")
+		return
+	}
+
+	notification := &RevocationNotification{
+		GrantID:   ar.GetID(),
+		ClientID:  ar.GetClient().GetID(),
+		Audience:  ar.GetGrantedAudience(),
+		RevokedAt: time.Now().UTC().Round(time.Second),
+	}
+	if session := ar.GetSession(); session != nil {
+		notification.Subject = session.GetSubject()
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		h.r.Logger().WithError(err).Error("Unable to encode the revocation notification.")
+		return
+	}
+
+	for _, hook := range hooks {
+		if !grantsAnyAudience(ar, hook.Audience) {
+			continue
+		}
+
+		client := h.r.HTTPClient(ctx)
+		client.RetryMax = *hook.MaxRetries
+		go func(hook config.RevocationNotification) {
+			if err := sendRevocationNotification(context.WithoutCancel(ctx), client, &hook, body); err != nil {
+				h.r.Logger().WithError(err).WithField("grant_id", notification.GrantID).WithField("url", hook.URL).
+					Error("Unable to notify the resource server of the revocation.")
+			}
+		}(hook)
+	}
+}
+
+func grantsAnyAudience(ar fosite.Requester, audience []string) bool {
+	for _, a := range audience {
+		if ar.GetGrantedAudience().Has(a) {
+			return true
+		}
+	}
+	return false
+}
+
+func sendRevocationNotification(ctx context.Context, client *retryablehttp.Client, hook *config.RevocationNotification, body []byte) error {
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := hook.Auth.Apply(req.Request); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	if hook.Secret != "" {
+		req.Header.Set(events.SignatureHeader, events.Sign(hook.Secret, body))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("the resource server responded with status code %d", res.StatusCode)
+	}
+	return nil
+}
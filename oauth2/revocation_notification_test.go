@@ -0,0 +1,128 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	goauth2 "golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/contextx"
+)
+
+func TestRevocationNotifications(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	public, admin := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	type notification struct {
+		body      []byte
+		signature string
+	}
+	newResourceServer := func(t *testing.T) (*httptest.Server, chan notification) {
+		notifications := make(chan notification, 10)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			notifications <- notification{body: body, signature: r.Header.Get(events.SignatureHeader)}
+		}))
+		t.Cleanup(ts.Close)
+		return ts, notifications
+	}
+	api, apiNotifications := newResourceServer(t)
+	other, otherNotifications := newResourceServer(t)
+
+	reg.Config().MustSet(ctx, config.KeyOAuth2RevocationNotifications, []map[string]interface{}{
+		{"url": api.URL, "audience": []string{"https://api.example.com"}, "secret": "notification-secret"},
+		{"url": other.URL, "audience": []string{"https://other.example.com"}},
+	})
+	t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2RevocationNotifications, nil) })
+
+	secret := uuid.New().String()
+	c := &hc.Client{
+		Secret:     secret,
+		GrantTypes: []string{"client_credentials"},
+		Audience:   []string{"https://api.example.com", "https://other.example.com"},
+	}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+
+	issue := func(t *testing.T, audience string) string {
+		token, err := (&clientcredentials.Config{
+			ClientID:       c.GetID(),
+			ClientSecret:   secret,
+			TokenURL:       public.URL + oauth2.TokenPath,
+			EndpointParams: url.Values{"audience": {audience}},
+			AuthStyle:      goauth2.AuthStyleInHeader,
+		}).Token(ctx)
+		require.NoError(t, err)
+		return token.AccessToken
+	}
+	revoke := func(t *testing.T, token string) {
+		req, err := http.NewRequest(http.MethodPost, public.URL+oauth2.RevocationPath, strings.NewReader(url.Values{"token": {token}}.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.GetID(), secret)
+		res, err := public.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	t.Run("case=notifies the resource servers of the audience", func(t *testing.T) {
+		token := issue(t, "https://api.example.com")
+		conf := &goauth2.Config{ClientID: c.GetID(), ClientSecret: secret}
+		reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, []string{"grant_id"})
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyOAuth2IntrospectionMetadata, nil) })
+		grantID := testhelpers.IntrospectToken(t, conf, token, admin).Get("grant_id").String()
+
+		revoke(t, token)
+
+		select {
+		case n := <-apiNotifications:
+			assert.Equal(t, events.Sign("notification-secret", n.body), n.signature)
+			var got oauth2.RevocationNotification
+			require.NoError(t, json.Unmarshal(n.body, &got))
+			assert.Equal(t, grantID, got.GrantID)
+			assert.Equal(t, c.GetID(), got.ClientID)
+			assert.Equal(t, []string{"https://api.example.com"}, got.Audience)
+			assert.WithinDuration(t, time.Now(), got.RevokedAt, time.Minute)
+		case <-time.After(10 * time.Second):
+			t.Fatal("the resource server was not notified")
+		}
+
+		select {
+		case n := <-otherNotifications:
+			t.Fatalf("a resource server of another audience was notified: %s", n.body)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("case=does not notify of unknown tokens", func(t *testing.T) {
+		revoke(t, "unknown-token")
+
+		select {
+		case n := <-apiNotifications:
+			t.Fatalf("the resource server was notified: %s", n.body)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
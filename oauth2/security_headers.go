@@ -0,0 +1,54 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ory/hydra/v2/driver/config"
+)
+
+// writeSecurityHeaders sets the configured security headers for a page served by Ory Hydra. It returns the nonce
+// which the scripts and styles of the page must carry to satisfy the Content-Security-Policy.
+func (h *Handler) writeSecurityHeaders(w http.ResponseWriter, r *http.Request) (string, error) {
+	c := h.c.PublicSecurityHeaders(r.Context())
+	if !c.Enabled {
+		return "", nil
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+	n := base64.StdEncoding.EncodeToString(nonce[:])
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if c.ContentSecurityPolicy != "" {
+		w.Header().Set("Content-Security-Policy", strings.ReplaceAll(c.ContentSecurityPolicy, config.CSPNoncePlaceholder, n))
+	}
+	if c.ReferrerPolicy != "" {
+		w.Header().Set("Referrer-Policy", c.ReferrerPolicy)
+	}
+	if c.FrameOptions != "" {
+		w.Header().Set("X-Frame-Options", c.FrameOptions)
+	}
+
+	// Browsers ignore the header over plain HTTP, which is only served behind a proxy terminating TLS.
+	if c.HSTSEnabled && (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https") {
+		hsts := fmt.Sprintf("max-age=%d", int64(c.HSTSMaxAge.Seconds()))
+		if c.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if c.HSTSPreload {
+			hsts += "; preload"
+		}
+		w.Header().Set("Strict-Transport-Security", hsts)
+	}
+
+	return n, nil
+}
@@ -34,6 +34,19 @@ type Session struct {
 	ExcludeNotBeforeClaim  bool                   `json:"exclude_not_before_claim"`
 	AllowedTopLevelClaims  []string               `json:"allowed_top_level_claims"`
 	MirrorTopLevelClaims   bool                   `json:"mirror_top_level_claims"`
+	MinimalClaims          bool                   `json:"minimal_claims,omitempty"`
+	HashedSubject          string                 `json:"hashed_subject,omitempty"`
+
+	// GrantType is the grant type with which the session was originally established. Refreshing tokens keeps it.
+	GrantType string `json:"grant_type,omitempty"`
+
+	// GrantChain are the grant types with which the tokens of the session were issued, in order. Refreshing tokens
+	// appends to it, so its length is the number of times the grant was exercised.
+	GrantChain []string `json:"grant_chain,omitempty"`
+
+	// AuthorizationRequest are the parameters of the authorization request which established the session. They are only
+	// kept if they are exposed by token introspection.
+	AuthorizationRequest flow.AuthorizationRequestParameters `json:"authorization_request,omitempty"`
 
 	Flow *flow.Flow `json:"-"`
 }
@@ -71,20 +84,28 @@ func (s *Session) GetJWTClaims() jwt.JWTClaimsContainer {
 	//our new extra map which will be added to the jwt
 	var topLevelExtraWithMirrorExt = map[string]interface{}{}
 
-	//setting every allowed claim top level in jwt with respective value
-	for _, allowedClaim := range allowedClaimsFromConfigWithoutReserved {
-		if cl, ok := s.Extra[allowedClaim]; ok {
-			topLevelExtraWithMirrorExt[allowedClaim] = cl
+	//the minimal profile omits the custom claims of the session
+	if !s.MinimalClaims {
+		//setting every allowed claim top level in jwt with respective value
+		for _, allowedClaim := range allowedClaimsFromConfigWithoutReserved {
+			if cl, ok := s.Extra[allowedClaim]; ok {
+				topLevelExtraWithMirrorExt[allowedClaim] = cl
+			}
+		}
+
+		//for every other claim that was already reserved and for mirroring, add original extra under "ext"
+		if s.MirrorTopLevelClaims {
+			topLevelExtraWithMirrorExt["ext"] = s.Extra
 		}
 	}
 
-	//for every other claim that was already reserved and for mirroring, add original extra under "ext"
-	if s.MirrorTopLevelClaims {
-		topLevelExtraWithMirrorExt["ext"] = s.Extra
+	subject := s.Subject
+	if s.HashedSubject != "" {
+		subject = s.HashedSubject
 	}
 
 	claims := &jwt.JWTClaims{
-		Subject: s.Subject,
+		Subject: subject,
 		Issuer:  s.DefaultSession.Claims.Issuer,
 		//set our custom extra map as claims.Extra
 		Extra:     topLevelExtraWithMirrorExt,
@@ -104,7 +125,7 @@ func (s *Session) GetJWTClaims() jwt.JWTClaimsContainer {
 		// IssuedAt:  s.DefaultSession.Claims.IssuedAt,
 		// NotBefore: s.DefaultSession.Claims.IssuedAt,
 	}
-	if !s.ExcludeNotBeforeClaim {
+	if !s.ExcludeNotBeforeClaim && !s.MinimalClaims {
 		claims.NotBefore = claims.IssuedAt
 	}
 
@@ -122,6 +143,11 @@ func (s *Session) GetJWTHeader() *jwt.Headers {
 	}
 }
 
+// GetGrantChain returns the grant types with which the tokens of the session were issued.
+func (s *Session) GetGrantChain() []string {
+	return s.GrantChain
+}
+
 func (s *Session) Clone() fosite.Session {
 	if s == nil {
 		return nil
@@ -161,18 +187,18 @@ func (s *Session) UnmarshalJSON(original []byte) (err error) {
 	transformed := original
 	originalParsed := gjson.ParseBytes(original)
 
+	// Sessions written by recent versions do not contain any of the legacy keys, in which case the payload is
+	// decoded as is. Every rewrite copies the payload, so only the keys which are present are rewritten.
 	for oldKey, newKey := range keyRewrites {
-		if !originalParsed.Get(oldKey).Exists() {
+		value := originalParsed.Get(oldKey)
+		if !value.Exists() {
 			continue
 		}
-		transformed, err = sjson.SetRawBytes(transformed, newKey, []byte(originalParsed.Get(oldKey).Raw))
+		transformed, err = sjson.SetRawBytes(transformed, newKey, []byte(value.Raw))
 		if err != nil {
 			return errors.WithStack(err)
 		}
-	}
-
-	for orig := range keyRewrites {
-		transformed, err = sjson.DeleteBytes(transformed, orig)
+		transformed, err = sjson.DeleteBytes(transformed, oldKey)
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ory/fosite"
@@ -92,3 +93,47 @@ func TestUnmarshalSession(t *testing.T) {
 		snapshotx.SnapshotTExcept(t, &actual, nil)
 	})
 }
+
+func TestSessionMinimalClaims(t *testing.T) {
+	newSession := func() *Session {
+		return &Session{
+			DefaultSession: &openid.DefaultSession{
+				Claims:  &jwt.IDTokenClaims{Issuer: "https://hydra.example/"},
+				Headers: new(jwt.Headers),
+				Subject: "foo@bar.com",
+			},
+			Extra:                 map[string]interface{}{"email": "foo@bar.com", "tenant": "acme"},
+			ClientID:              "client",
+			AllowedTopLevelClaims: []string{"tenant"},
+			MirrorTopLevelClaims:  true,
+		}
+	}
+
+	t.Run("case=default profile", func(t *testing.T) {
+		claims := newSession().GetJWTClaims().ToMapClaims()
+		assert.Equal(t, "foo@bar.com", claims["sub"])
+		assert.Equal(t, "acme", claims["tenant"])
+		assert.Contains(t, claims, "ext")
+		assert.Contains(t, claims, "nbf")
+	})
+
+	t.Run("case=minimal profile", func(t *testing.T) {
+		s := newSession()
+		s.MinimalClaims = true
+		claims := s.GetJWTClaims().ToMapClaims()
+		assert.Equal(t, "foo@bar.com", claims["sub"])
+		assert.Equal(t, "client", claims["client_id"])
+		assert.NotContains(t, claims, "tenant")
+		assert.NotContains(t, claims, "ext")
+		assert.NotContains(t, claims, "nbf")
+	})
+
+	t.Run("case=hashed subject", func(t *testing.T) {
+		s := newSession()
+		s.HashedSubject = hashSubject(s.Subject, "salt")
+		claims := s.GetJWTClaims().ToMapClaims()
+		assert.Equal(t, hashSubject("foo@bar.com", "salt"), claims["sub"])
+		assert.NotEqual(t, hashSubject("foo@bar.com", "other"), claims["sub"])
+		assert.Len(t, claims["sub"], 64)
+	})
+}
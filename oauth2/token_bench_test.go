@@ -0,0 +1,114 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/contextx"
+)
+
+// BenchmarkTokenIssuance measures the token endpoint without network and database round trips, so that the
+// allocations of the token issuance path itself can be compared with -benchmem.
+func BenchmarkTokenIssuance(b *testing.B) {
+	ctx := context.Background()
+
+	for _, strategy := range []string{"opaque", "jwt"} {
+		b.Run("strategy="+strategy, func(b *testing.B) {
+			conf := internal.NewConfigurationWithDefaults()
+			conf.MustSet(ctx, config.KeyAccessTokenStrategy, strategy)
+			reg := internal.NewRegistryMemory(b, conf, &contextx.Default{})
+			reg.Logger().Logrus().SetLevel(0)
+			public, _ := testhelpers.NewOAuth2Server(ctx, b, reg)
+
+			secret := uuid.New().String()
+			c := &hc.Client{
+				Secret:     secret,
+				GrantTypes: []string{"client_credentials"},
+				Scope:      "foo bar",
+				Audience:   []string{"https://api.example.org"},
+			}
+			require.NoError(b, reg.ClientManager().CreateClient(ctx, c))
+			body := url.Values{"grant_type": {"client_credentials"}, "scope": {"foo bar"}}.Encode()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest(http.MethodPost, oauth2.TokenPath, strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				req.SetBasicAuth(c.GetID(), secret)
+				res := httptest.NewRecorder()
+				public.Config.Handler.ServeHTTP(res, req)
+				if res.Code != http.StatusOK {
+					b.Fatalf("unexpected status code %d: %s", res.Code, res.Body)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSessionJSON measures the serialization of sessions, which happens for every token which is stored or
+// looked up.
+func BenchmarkSessionJSON(b *testing.B) {
+	now := time.Now().UTC()
+	session := &oauth2.Session{
+		DefaultSession: &openid.DefaultSession{
+			Claims: &jwt.IDTokenClaims{
+				Subject:   "alice",
+				Issuer:    "https://hydra.example.org/",
+				Audience:  []string{"client"},
+				IssuedAt:  now,
+				ExpiresAt: now.Add(time.Hour),
+				AuthTime:  now,
+				Extra:     map[string]interface{}{"email": "alice@example.org"},
+			},
+			Headers:  &jwt.Headers{Extra: map[string]interface{}{"kid": "public:key"}},
+			Subject:  "alice",
+			Username: "alice",
+		},
+		Extra:            map[string]interface{}{"tenant": "acme", "roles": []string{"admin", "user"}},
+		KID:              "public:key",
+		ClientID:         "client",
+		ConsentChallenge: uuid.New().String(),
+		GrantType:        "authorization_code",
+	}
+
+	b.Run("case=marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(session); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	raw, err := json.Marshal(session)
+	require.NoError(b, err)
+
+	b.Run("case=unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := json.Unmarshal(raw, new(oauth2.Session)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -9,8 +9,6 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/pkg/errors"
-
 	"github.com/hashicorp/go-retryablehttp"
 
 	"github.com/ory/hydra/v2/flow"
@@ -64,25 +62,6 @@ type APIKeyAuthConfig struct {
 	Value string `json:"value"`
 }
 
-func applyAuth(req *retryablehttp.Request, auth *config.Auth) error {
-	if auth == nil {
-		return nil
-	}
-
-	switch auth.Type {
-	case "api_key":
-		switch auth.Config.In {
-		case "header":
-			req.Header.Set(auth.Config.Name, auth.Config.Value)
-		case "cookie":
-			req.AddCookie(&http.Cookie{Name: auth.Config.Name, Value: auth.Config.Value})
-		}
-	default:
-		return errors.Errorf("unsupported auth type %q", auth.Type)
-	}
-	return nil
-}
-
 func executeHookAndUpdateSession(ctx context.Context, reg x.HTTPClientProvider, hookConfig *config.HookConfig, reqBodyBytes []byte, session *Session) error {
 	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, hookConfig.URL, bytes.NewReader(reqBodyBytes))
 	if err != nil {
@@ -93,7 +72,7 @@ func executeHookAndUpdateSession(ctx context.Context, reg x.HTTPClientProvider,
 				WithDebugf("Unable to prepare the HTTP Request: %s", err),
 		)
 	}
-	if err := applyAuth(req, hookConfig.Auth); err != nil {
+	if err := hookConfig.Auth.Apply(req.Request); err != nil {
 		return errorsx.WithStack(
 			fosite.ErrServerError.
 				WithWrap(err).
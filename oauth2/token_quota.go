@@ -0,0 +1,76 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/errorsx"
+)
+
+// ErrTokenQuotaExceeded is returned by the token endpoint if the client exceeded the hard limit of its token quota.
+var ErrTokenQuotaExceeded = &fosite.RFC6749Error{
+	ErrorField:       "quota_exceeded",
+	DescriptionField: "The client has exceeded its token quota.",
+	CodeField:        http.StatusTooManyRequests,
+}
+
+const (
+	tokenQuotaLimitSoft = "soft"
+	tokenQuotaLimitHard = "hard"
+)
+
+// enforceTokenQuota counts the token request against the hourly and daily token quota of the client. If the request
+// exceeds a hard limit, it sets the Retry-After header to the end of the window and returns ErrTokenQuotaExceeded.
+func (h *Handler) enforceTokenQuota(ctx context.Context, w http.ResponseWriter, ar fosite.AccessRequester) error {
+	quota := h.c.OAuth2TokenQuota(ctx, ar.GetClient().GetID())
+	if quota == nil {
+		return nil
+	}
+
+	now := h.r.Clock().Now().UTC()
+	for _, window := range []struct {
+		name     string
+		duration time.Duration
+		limits   config.TokenQuotaLimits
+	}{
+		{name: "hour", duration: time.Hour, limits: quota.PerHour},
+		{name: "day", duration: 24 * time.Hour, limits: quota.PerDay},
+	} {
+		if window.limits.Soft <= 0 && window.limits.Hard <= 0 {
+			continue
+		}
+
+		start := now.Truncate(window.duration)
+		end := start.Add(window.duration)
+		count, err := h.r.OAuth2Storage().IncrementTokenQuotaUsage(ctx, ar.GetClient().GetID(), window.name, start, end)
+		if err != nil {
+			return err
+		}
+
+		if hard := window.limits.Hard; hard > 0 && count > hard {
+			if count == hard+1 {
+				events.Trace(ctx, events.TokenQuotaExceeded, events.WithRequest(ar), events.WithQuota(window.name, hard))
+			}
+			h.m.recordTokenQuotaExceeded(quota, window.name, tokenQuotaLimitHard)
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(end.Sub(now).Round(time.Second).Seconds())))
+			return errorsx.WithStack(ErrTokenQuotaExceeded.WithHintf("The client may request %d tokens per %s.", hard, window.name))
+		}
+
+		if soft := window.limits.Soft; soft > 0 && count > soft {
+			if count == soft+1 {
+				events.Trace(ctx, events.TokenQuotaSoftLimitReached, events.WithRequest(ar), events.WithQuota(window.name, soft))
+			}
+			h.m.recordTokenQuotaExceeded(quota, window.name, tokenQuotaLimitSoft)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+)
+
+type recordingEmitter struct {
+	sync.Mutex
+	events []events.Event
+}
+
+func (e *recordingEmitter) Emit(_ context.Context, ev events.Event) {
+	e.Lock()
+	defer e.Unlock()
+	e.events = append(e.events, ev)
+}
+
+func (e *recordingEmitter) ofType(typ string) (found []events.Event) {
+	e.Lock()
+	defer e.Unlock()
+	for _, ev := range e.events {
+		if ev.Type == typ {
+			found = append(found, ev)
+		}
+	}
+	return found
+}
+
+func TestTokenQuota(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	public, _ := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	emitter := new(recordingEmitter)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		public.Config.Handler.ServeHTTP(w, r.WithContext(events.WithEmitter(r.Context(), emitter)))
+	}))
+	t.Cleanup(server.Close)
+
+	newClient := func(t *testing.T) (*hc.Client, string) {
+		secret := uuid.New().String()
+		c := &hc.Client{Secret: secret, GrantTypes: []string{"client_credentials"}}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		return c, secret
+	}
+	limited, limitedSecret := newClient(t)
+	other, otherSecret := newClient(t)
+
+	reg.Config().MustSet(ctx, config.KeyOAuth2TokenQuotas, []map[string]interface{}{
+		{"client_id": limited.GetID(), "per_hour": map[string]interface{}{"soft": 1, "hard": 2}},
+		{"client_id": "*", "per_day": map[string]interface{}{"hard": 100}},
+	})
+
+	requestToken := func(t *testing.T, c *hc.Client, secret string) (*http.Response, gjson.Result) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+oauth2.TokenPath, strings.NewReader(url.Values{"grant_type": {"client_credentials"}}.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.GetID(), secret)
+		res, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+	quotaExceeded := func(clientID, window, limit string) float64 {
+		families, err := prometheus.DefaultGatherer.Gather()
+		require.NoError(t, err)
+		for _, f := range families {
+			if f.GetName() != "hydra_oauth2_token_quota_exceeded_total" {
+				continue
+			}
+		metrics:
+			for _, metric := range f.GetMetric() {
+				// Labels are sorted by name: client_id, limit, window.
+				for i, l := range []string{clientID, limit, window} {
+					if metric.GetLabel()[i].GetValue() != l {
+						continue metrics
+					}
+				}
+				return metric.GetCounter().GetValue()
+			}
+		}
+		return 0
+	}
+
+	t.Run("case=issues tokens below the soft limit", func(t *testing.T) {
+		res, body := requestToken(t, limited, limitedSecret)
+		assert.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Empty(t, emitter.ofType(string(events.TokenQuotaSoftLimitReached)))
+	})
+
+	t.Run("case=issues tokens above the soft limit and emits an alert", func(t *testing.T) {
+		res, body := requestToken(t, limited, limitedSecret)
+		assert.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+
+		alerts := emitter.ofType(string(events.TokenQuotaSoftLimitReached))
+		require.Len(t, alerts, 1)
+		assert.Equal(t, limited.GetID(), alerts[0].Attributes[events.AttributeKeyOAuth2ClientID])
+		assert.Equal(t, "hour", alerts[0].Attributes[events.AttributeKeyOAuth2QuotaWindow])
+		assert.EqualValues(t, 1, quotaExceeded(limited.GetID(), "hour", "soft"))
+	})
+
+	t.Run("case=rejects tokens above the hard limit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			res, body := requestToken(t, limited, limitedSecret)
+			assert.Equal(t, http.StatusTooManyRequests, res.StatusCode, body.Raw)
+			assert.Equal(t, "quota_exceeded", body.Get("error").String(), body.Raw)
+
+			retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+			require.NoError(t, err)
+			assert.True(t, retryAfter > 0 && retryAfter <= 3600, "%d", retryAfter)
+		}
+
+		assert.Len(t, emitter.ofType(string(events.TokenQuotaExceeded)), 1, "the alert is only emitted once per window")
+		assert.Len(t, emitter.ofType(string(events.TokenQuotaSoftLimitReached)), 1)
+		assert.EqualValues(t, 2, quotaExceeded(limited.GetID(), "hour", "hard"))
+	})
+
+	t.Run("case=applies the default quota to other clients", func(t *testing.T) {
+		res, body := requestToken(t, other, otherSecret)
+		assert.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package trust
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+// ExpiryMetrics exports the number of trust relationships which are about to expire, so that it is possible to alert
+// before the assertions of an issuer are rejected.
+type ExpiryMetrics struct {
+	expiring prometheus.Gauge
+}
+
+// NewExpiryMetrics registers the metrics with the registerer. Metrics which are already registered are reused.
+func NewExpiryMetrics(reg prometheus.Registerer) *ExpiryMetrics {
+	return &ExpiryMetrics{
+		expiring: x.RegisterOrExisting(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "hydra",
+			Name:      "trust_grants_expiring",
+			Help:      "The number of trust relationships of JWT issuers which expire within the configured warning period.",
+		})),
+	}
+}
+
+// WarnExpiringGrants emits the TrustedJwtGrantIssuerExpiring event for every trust relationship which entered the
+// warning period since the previous check, which ran one interval before now, and records the number of trust
+// relationships in the warning period.
+func WarnExpiringGrants(ctx context.Context, r InternalRegistry, metrics *ExpiryMetrics, now time.Time) error {
+	before, interval := r.Config().GrantJWTExpiryWarningBefore(ctx), r.Config().GrantJWTExpiryWarningInterval(ctx)
+
+	var expiring int
+	pageOpts := []keysetpagination.Option{}
+	for {
+		grants, nextPage, err := r.GrantManager().GetGrants(ctx, "", pageOpts...)
+		if err != nil {
+			return err
+		}
+
+		inPeriod, entered := expiringGrants(grants, now, before, interval)
+		expiring += len(inPeriod)
+		for _, g := range entered {
+			r.Logger().WithField("grant_id", g.ID).WithField("issuer", g.Issuer).WithField("expires_at", g.ExpiresAt).
+				Warn("The trust relationship of a JWT issuer is about to expire.")
+			events.Trace(ctx, events.TrustedJwtGrantIssuerExpiring, events.WithTrustGrant(g.ID, g.Issuer, g.ExpiresAt), events.WithSubject(g.Subject))
+		}
+
+		if nextPage.IsLast() {
+			break
+		}
+		pageOpts = nextPage.ToOptions()
+	}
+
+	metrics.expiring.Set(float64(expiring))
+	return nil
+}
+
+// expiringGrants returns the grants which have not yet expired but expire within before, and those of them which
+// entered the warning period within the last interval or were created within the last interval.
+func expiringGrants(grants []Grant, now time.Time, before, interval time.Duration) (inPeriod, entered []Grant) {
+	for _, g := range grants {
+		if !g.ExpiresAt.After(now) || g.ExpiresAt.After(now.Add(before)) {
+			continue
+		}
+		inPeriod = append(inPeriod, g)
+
+		if warnAt := g.ExpiresAt.Add(-before); warnAt.After(now.Add(-interval)) || g.CreatedAt.After(now.Add(-interval)) {
+			entered = append(entered, g)
+		}
+	}
+	return inPeriod, entered
+}
@@ -0,0 +1,50 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package trust
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiringGrants(t *testing.T) {
+	now := time.Now()
+	created := now.Add(-30 * 24 * time.Hour)
+	grants := []Grant{
+		{ID: "expired", CreatedAt: created, ExpiresAt: now.Add(-time.Minute)},
+		{ID: "entered", CreatedAt: created, ExpiresAt: now.Add(7*24*time.Hour - 30*time.Minute)},
+		{ID: "warned", CreatedAt: created, ExpiresAt: now.Add(24 * time.Hour)},
+		{ID: "created", CreatedAt: now.Add(-time.Minute), ExpiresAt: now.Add(24 * time.Hour)},
+		{ID: "valid", CreatedAt: created, ExpiresAt: now.Add(8 * 24 * time.Hour)},
+	}
+
+	inPeriod, entered := expiringGrants(grants, now, 7*24*time.Hour, time.Hour)
+
+	var inPeriodIDs, enteredIDs []string
+	for _, g := range inPeriod {
+		inPeriodIDs = append(inPeriodIDs, g.ID)
+	}
+	for _, g := range entered {
+		enteredIDs = append(enteredIDs, g.ID)
+	}
+
+	if expected := []string{"entered", "warned", "created"}; !equalIDs(inPeriodIDs, expected) {
+		t.Errorf("expected grants %v to be in the warning period, got %v", expected, inPeriodIDs)
+	}
+	if expected := []string{"entered", "created"}; !equalIDs(enteredIDs, expected) {
+		t.Errorf("expected grants %v to have entered the warning period, got %v", expected, enteredIDs)
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -9,7 +9,8 @@ import (
 	"time"
 
 	"github.com/ory/fosite"
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/ory/hydra/v2/x"
 
@@ -39,6 +40,7 @@ func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
 	admin.GET(grantJWTBearerPath, h.adminListTrustedOAuth2JwtGrantIssuers)
 	admin.POST(grantJWTBearerPath, h.trustOAuth2JwtGrantIssuer)
 	admin.DELETE(grantJWTBearerPath+"/:id", h.deleteTrustedOAuth2JwtGrantIssuer)
+	admin.POST(grantJWTBearerPath+"/:id/renew", h.renewTrustedOAuth2JwtGrantIssuer)
 }
 
 // Trust OAuth2 JWT Bearer Grant Type Issuer Request Body
@@ -144,6 +146,7 @@ func (h *Handler) trustOAuth2JwtGrantIssuer(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	h.registry.AuditRecorder().Record(r, audit.ActionCreate, audit.ResourceTrustedJwtGrantIssuer, grant.ID, nil, &grant)
 	h.registry.Writer().WriteCreated(w, r, grantJWTBearerPath+"/"+grant.ID, &grant)
 }
 
@@ -226,14 +229,106 @@ type deleteTrustedOAuth2JwtGrantIssuer struct {
 func (h *Handler) deleteTrustedOAuth2JwtGrantIssuer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var id = ps.ByName("id")
 
+	var before *Grant
+	if h.registry.Config().AuditEnabled(r.Context()) {
+		if grant, err := h.registry.GrantManager().GetConcreteGrant(r.Context(), id); err == nil {
+			before = &grant
+		}
+	}
+
 	if err := h.registry.GrantManager().DeleteGrant(r.Context(), id); err != nil {
 		h.registry.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.registry.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceTrustedJwtGrantIssuer, id, before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Renew Trusted OAuth2 JWT Bearer Grant Type Issuer Request Body
+//
+// swagger:model renewTrustedOAuth2JwtGrantIssuer
+type renewTrustedOAuth2JwtGrantIssuerBody struct {
+	// The new time at which the grant expires.
+	//
+	// required:true
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Renew Trusted OAuth2 JWT Bearer Grant Type Issuer Request
+//
+// swagger:parameters renewTrustedOAuth2JwtGrantIssuer
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type renewTrustedOAuth2JwtGrantIssuer struct {
+	// The id of the desired grant
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Body renewTrustedOAuth2JwtGrantIssuerBody
+}
+
+// swagger:route POST /admin/trust/grants/jwt-bearer/issuers/{id}/renew oAuth2 renewTrustedOAuth2JwtGrantIssuer
+//
+// # Renew Trusted OAuth2 JWT Bearer Grant Type Issuer
+//
+// Use this endpoint to change when a trusted JWT Bearer Grant Type Issuer expires. Unlike deleting and recreating it,
+// the trust relationship keeps its ID and public key.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: trustedOAuth2JwtGrantIssuer
+//	  default: genericError
+func (h *Handler) renewTrustedOAuth2JwtGrantIssuer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var id = ps.ByName("id")
+
+	var body renewTrustedOAuth2JwtGrantIssuerBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.registry.Writer().WriteError(w, r,
+			errorsx.WithStack(&fosite.RFC6749Error{
+				ErrorField:       "error",
+				DescriptionField: err.Error(),
+				CodeField:        http.StatusBadRequest,
+			}))
+		return
+	}
+
+	if body.ExpiresAt.IsZero() {
+		h.registry.Writer().WriteError(w, r, errorsx.WithStack(ErrMissingRequiredParameter.WithHint("Field 'expires_at' is required.")))
+		return
+	} else if !body.ExpiresAt.After(time.Now()) {
+		h.registry.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Field 'expires_at' must be in the future.")))
+		return
+	}
+
+	var before *Grant
+	if h.registry.Config().AuditEnabled(r.Context()) {
+		if grant, err := h.registry.GrantManager().GetConcreteGrant(r.Context(), id); err == nil {
+			before = &grant
+		}
+	}
+
+	grant, err := h.registry.GrantManager().RenewGrant(r.Context(), id, body.ExpiresAt.UTC().Round(time.Second))
+	if err != nil {
+		h.registry.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.registry.AuditRecorder().Record(r, audit.ActionUpdate, audit.ResourceTrustedJwtGrantIssuer, id, before, &grant)
+	h.registry.Writer().Write(w, r, grant)
+}
+
 // List Trusted OAuth2 JWT Bearer Grant Type Issuers Request
 //
 // swagger:parameters listTrustedOAuth2JwtGrantIssuers
@@ -246,7 +341,7 @@ type listTrustedOAuth2JwtGrantIssuers struct {
 	// required: false
 	Issuer string `json:"issuer"`
 
-	tokenpagination.TokenPaginator
+	keysetpagination.RequestParameters
 }
 
 // swagger:route GET /admin/trust/grants/jwt-bearer/issuers oAuth2 listTrustedOAuth2JwtGrantIssuers
@@ -267,10 +362,14 @@ type listTrustedOAuth2JwtGrantIssuers struct {
 //	  200: trustedOAuth2JwtGrantIssuers
 //	  default: genericError
 func (h *Handler) adminListTrustedOAuth2JwtGrantIssuers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	page, itemsPerPage := x.ParsePagination(r)
+	pageOpts, err := x.ParsePagination(r)
+	if err != nil {
+		h.registry.Writer().WriteError(w, r, err)
+		return
+	}
 	optionalIssuer := r.URL.Query().Get("issuer")
 
-	grants, err := h.registry.GrantManager().GetGrants(r.Context(), itemsPerPage, page*itemsPerPage, optionalIssuer)
+	grants, nextPage, err := h.registry.GrantManager().GetGrants(r.Context(), optionalIssuer, pageOpts...)
 	if err != nil {
 		h.registry.Writer().WriteError(w, r, err)
 		return
@@ -282,7 +381,7 @@ func (h *Handler) adminListTrustedOAuth2JwtGrantIssuers(w http.ResponseWriter, r
 		return
 	}
 
-	x.PaginationHeader(w, r.URL, int64(n), page, itemsPerPage)
+	x.PaginationHeader(w, r.URL, int64(n), nextPage)
 	if grants == nil {
 		grants = []Grant{}
 	}
@@ -295,6 +295,47 @@ func (s *HandlerTestSuite) TestGrantCanBeDeleted() {
 	s.Error(err, "expected error, because grant has been already deleted")
 }
 
+func (s *HandlerTestSuite) TestGrantCanBeRenewed() {
+	createRequestParams := s.newCreateJwtBearerGrantParams(
+		"ory",
+		"hackerman@example.com",
+		false,
+		[]string{"openid", "offline", "profile"},
+		time.Now().Add(time.Hour),
+	)
+
+	createResult, _, err := s.hydraClient.OAuth2Api.TrustOAuth2JwtGrantIssuer(context.Background()).TrustOAuth2JwtGrantIssuer(createRequestParams).Execute()
+	s.Require().NoError(err, "no errors expected on grant creation")
+
+	renew := func(id string, expiresAt time.Time) (int, []byte) {
+		var b bytes.Buffer
+		s.Require().NoError(json.NewEncoder(&b).Encode(map[string]interface{}{"expires_at": expiresAt}))
+		res, err := http.Post(s.server.URL+"/admin/trust/grants/jwt-bearer/issuers/"+id+"/renew", "application/json", &b)
+		s.Require().NoError(err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		s.Require().NoError(err)
+		return res.StatusCode, body
+	}
+
+	code, body := renew(*createResult.Id, time.Now().Add(-time.Hour))
+	s.Equal(http.StatusBadRequest, code, "expiration date must be in the future")
+	s.Contains(gjson.GetBytes(body, "error_description").String(), "expires_at")
+
+	code, _ = renew(uuid.New().String(), time.Now().Add(time.Hour))
+	s.Equal(http.StatusNotFound, code, "unknown grants can not be renewed")
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	code, body = renew(*createResult.Id, expiresAt)
+	s.Require().Equal(http.StatusOK, code, "%s", body)
+	s.Equal(*createResult.Id, gjson.GetBytes(body, "id").String(), "grant id must not change")
+
+	getResult, _, err := s.hydraClient.OAuth2Api.GetTrustedOAuth2JwtGrantIssuer(context.Background(), *createResult.Id).Execute()
+	s.Require().NoError(err, "no error expected on grant fetching")
+	s.Equal(expiresAt.Round(time.Second).UTC().String(), getResult.ExpiresAt.Round(time.Second).UTC().String(), "expiration date must be renewed")
+	s.Equal(createResult.PublicKey.Kid, getResult.PublicKey.Kid, "public key must not change")
+}
+
 func (s *HandlerTestSuite) generateJWK(publicKey *rsa.PublicKey) hydra.JsonWebKey {
 	var b bytes.Buffer
 	s.Require().NoError(json.NewEncoder(&b).Encode(&jose.JSONWebKey{
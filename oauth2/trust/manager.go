@@ -9,13 +9,16 @@ import (
 
 	"github.com/go-jose/go-jose/v3"
 	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 type GrantManager interface {
 	CreateGrant(ctx context.Context, g Grant, publicKey jose.JSONWebKey) error
 	GetConcreteGrant(ctx context.Context, id string) (Grant, error)
 	DeleteGrant(ctx context.Context, id string) error
-	GetGrants(ctx context.Context, limit, offset int, optionalIssuer string) ([]Grant, error)
+	RenewGrant(ctx context.Context, id string, expiresAt time.Time) (Grant, error)
+	GetGrants(ctx context.Context, optionalIssuer string, pageOpts ...keysetpagination.Option) ([]Grant, *keysetpagination.Paginator, error)
 	CountGrants(ctx context.Context) (int, error)
 	FlushInactiveGrants(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
 }
@@ -36,3 +39,7 @@ type SQLData struct {
 func (SQLData) TableName() string {
 	return "hydra_oauth2_trusted_jwt_bearer_issuer"
 }
+
+func (d SQLData) PageToken() keysetpagination.PageToken {
+	return keysetpagination.MapPageToken{"id": d.ID}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/ory/x/josex"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/go-jose/go-jose/v3"
 	"github.com/google/uuid"
@@ -44,7 +45,7 @@ func TestHelperGrantManagerCreateGetDeleteGrant(t1 GrantManager, km jwk.Manager,
 		require.NoError(t, err)
 		mikePubKey = josex.ToPublicKey(&keySet.Keys[0])
 
-		storedGrants, err := t1.GetGrants(context.TODO(), 100, 0, "")
+		storedGrants, _, err := t1.GetGrants(context.TODO(), "")
 		require.NoError(t, err)
 		assert.Len(t, storedGrants, 0)
 
@@ -80,6 +81,16 @@ func TestHelperGrantManagerCreateGetDeleteGrant(t1 GrantManager, km jwk.Manager,
 		assert.Equal(t, grant.CreatedAt.Format(time.RFC3339), storedGrant.CreatedAt.Format(time.RFC3339))
 		assert.Equal(t, grant.ExpiresAt.Format(time.RFC3339), storedGrant.ExpiresAt.Format(time.RFC3339))
 
+		renewedAt := expiresAt.AddDate(1, 0, 0)
+		renewedGrant, err := t1.RenewGrant(context.TODO(), grant.ID, renewedAt)
+		require.NoError(t, err)
+		assert.Equal(t, grant.ID, renewedGrant.ID)
+		assert.Equal(t, grant.PublicKey, renewedGrant.PublicKey)
+		assert.Equal(t, renewedAt.Format(time.RFC3339), renewedGrant.ExpiresAt.Format(time.RFC3339))
+		storedGrant, err = t1.GetConcreteGrant(context.TODO(), grant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, renewedAt.Format(time.RFC3339), storedGrant.ExpiresAt.Format(time.RFC3339))
+
 		grant2 := Grant{
 			ID:      uuid.New().String(),
 			Issuer:  set,
@@ -115,7 +126,9 @@ func TestHelperGrantManagerCreateGetDeleteGrant(t1 GrantManager, km jwk.Manager,
 		require.NoError(t, err)
 		assert.Equal(t, 3, count)
 
-		storedGrants, err = t1.GetGrants(context.TODO(), 100, 0, "")
+		storedGrants, nextPage, err := t1.GetGrants(context.TODO(), "")
+		require.NoError(t, err)
+		assert.True(t, nextPage.IsLast())
 		sort.Slice(storedGrants, func(i, j int) bool {
 			return storedGrants[i].CreatedAt.Before(storedGrants[j].CreatedAt)
 		})
@@ -125,7 +138,17 @@ func TestHelperGrantManagerCreateGetDeleteGrant(t1 GrantManager, km jwk.Manager,
 		assert.Equal(t, grant2.ID, storedGrants[1].ID)
 		assert.Equal(t, grant3.ID, storedGrants[2].ID)
 
-		storedGrants, err = t1.GetGrants(context.TODO(), 100, 0, set)
+		pagedGrants, nextPage, err := t1.GetGrants(context.TODO(), "", keysetpagination.WithSize(2))
+		require.NoError(t, err)
+		assert.Len(t, pagedGrants, 2)
+		assert.False(t, nextPage.IsLast())
+
+		pagedGrants, nextPage, err = t1.GetGrants(context.TODO(), "", nextPage.ToOptions()...)
+		require.NoError(t, err)
+		assert.Len(t, pagedGrants, 1)
+		assert.True(t, nextPage.IsLast())
+
+		storedGrants, _, err = t1.GetGrants(context.TODO(), set)
 		sort.Slice(storedGrants, func(i, j int) bool {
 			return storedGrants[i].CreatedAt.Before(storedGrants[j].CreatedAt)
 		})
@@ -209,5 +232,8 @@ func TestHelperGrantManagerErrors(m GrantManager, km jwk.Manager, parallel bool)
 
 		_, err = m.GetConcreteGrant(context.TODO(), nonExistingGrantID)
 		require.Error(t, err, "expect error, when fetching non-existing grant")
+
+		_, err = m.RenewGrant(context.TODO(), nonExistingGrantID, expiresAt)
+		require.Error(t, err, "expect error, when renewing non-existing grant")
 	}
 }
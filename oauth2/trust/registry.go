@@ -4,12 +4,16 @@
 package trust
 
 import (
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/driver/config"
 	"github.com/ory/hydra/v2/x"
 )
 
 type InternalRegistry interface {
 	x.RegistryWriter
 	x.RegistryLogger
+	config.Provider
+	audit.Registry
 	Registry
 }
 
@@ -4,13 +4,31 @@
 package oauth2
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/pborman/uuid"
 
 	"github.com/ory/fosite"
+	fjwt "github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringsx"
 )
 
+// VerifiableCredentialFormatSDJWT is the format of verifiable credentials issued as SD-JWT, whose claims are
+// selectively disclosable and which are bound to the key of the holder.
+const VerifiableCredentialFormatSDJWT = "vc+sd-jwt"
+
+var verifiableCredentialCryptographicSuites = []string{
+	"PS256", "RS256", "ES256",
+	"PS384", "RS384", "ES384",
+	"PS512", "RS512", "ES512",
+	"EdDSA",
+}
+
 // Request a Verifiable Credential
 //
 // swagger:parameters createVerifiableCredential
@@ -85,3 +103,44 @@ func (v *VerifableCredentialClaims) ToMapClaims() (res map[string]any, err error
 
 	return res, nil
 }
+
+// createSDJWTVerifiableCredential issues the user info of the session as SD-JWT verifiable credential. The subject
+// and the claims of the user are selectively disclosable, and the credential is bound to the proof key of the holder
+// through the cnf claim.
+func (h *Handler) createSDJWTVerifiableCredential(ctx context.Context, session *Session, proofJWK json.RawMessage) (string, error) {
+	userInfo := map[string]interface{}{"sub": session.Claims.Subject}
+	for claim, val := range session.Claims.Extra {
+		userInfo[claim] = val
+	}
+	concealed, disclosures, err := x.ConcealClaims(userInfo)
+	if err != nil {
+		return "", errorsx.WithStack(fosite.ErrServerError.WithWrap(err).WithDebug(err.Error()))
+	}
+
+	claims := fjwt.MapClaims{
+		"iss": session.Claims.Issuer,
+		"jti": stringsx.Coalesce(session.Claims.JTI, uuid.New()),
+		"iat": session.Claims.IssuedAt.Unix(),
+		"nbf": session.Claims.IssuedAt.Unix(),
+		"exp": session.Claims.IssuedAt.Add(1 * time.Hour).Unix(),
+		"vct": "UserInfoCredential",
+		"cnf": map[string]interface{}{"jwk": proofJWK},
+	}
+	for k, v := range concealed {
+		claims[k] = v
+	}
+
+	signingKeyID, err := h.r.OpenIDJWTStrategy().GetPublicKeyID(ctx)
+	if err != nil {
+		return "", errorsx.WithStack(err)
+	}
+	headers := fjwt.NewHeaders()
+	headers.Add("kid", signingKeyID)
+	headers.Add("typ", VerifiableCredentialFormatSDJWT)
+
+	rawToken, _, err := h.r.OpenIDJWTStrategy().Generate(ctx, claims, headers)
+	if err != nil {
+		return "", errorsx.WithStack(err)
+	}
+	return x.SDJWT(rawToken, disclosures), nil
+}
@@ -8,32 +8,60 @@ import (
 
 	"github.com/gofrs/uuid"
 
+	"github.com/ory/fosite"
+	"github.com/ory/x/contextx"
 	"github.com/ory/x/networkx"
 
 	"github.com/gobuffalo/pop/v6"
 
+	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/backup"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/extension"
+	"github.com/ory/hydra/v2/gnap"
+	"github.com/ory/hydra/v2/internal/kratos"
 	"github.com/ory/hydra/v2/jwk"
 	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/stats"
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/hydra/v2/uma"
 	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/popx"
 )
 
 type (
+	// Persister stores all data of Ory Hydra. The SQL persister in package sql is the default implementation. Other
+	// storage backends are registered with Register.
+	//
+	// Persisters which are not backed by a SQL database return nil from Connection, and implement the migration
+	// methods as no-ops if their backend does not need migrations.
 	Persister interface {
 		consent.Manager
 		client.Manager
 		x.FositeStorer
 		jwk.Manager
+		jwk.Pruner
 		trust.GrantManager
+		audit.Manager
+		backup.Manager
+		stats.Manager
+		tenant.Manager
+		uma.Manager
+		gnap.Manager
 
 		MigrationStatus(ctx context.Context) (popx.MigrationStatuses, error)
 		MigrateDown(context.Context, int) error
 		MigrateUp(context.Context) error
+		MigrateUpExpand(context.Context) (int, error)
 		PrepareMigration(context.Context) error
 		Connection(context.Context) *pop.Connection
 		Ping() error
+		TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+		// Checkpoint releases the space of deleted rows which is held by the write-ahead log of SQLite databases. It
+		// is a no-op for other databases.
+		Checkpoint(ctx context.Context) error
 		Networker
 	}
 	Provider interface {
@@ -43,5 +71,18 @@ type (
 	Networker interface {
 		NetworkID(ctx context.Context) uuid.UUID
 		DetermineNetwork(ctx context.Context) (*networkx.Network, error)
+		WithFallbackNetworkID(nid uuid.UUID) Persister
+	}
+
+	// Dependencies are the dependencies of a persister which are provided by the registry.
+	Dependencies interface {
+		ClientHasher() fosite.Hasher
+		KeyCipher() *aead.AESGCM
+		FlowCipher() *aead.XChaCha20Poly1305
+		Kratos() kratos.Client
+		extension.Registry
+		contextx.Provider
+		x.RegistryLogger
+		x.TracingProvider
 	}
 )
@@ -0,0 +1,51 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package persistence
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ory/hydra/v2/driver/config"
+)
+
+// Factory creates a persister for the data source name configured in dsn.
+type Factory func(ctx context.Context, c *config.DefaultProvider, d Dependencies) (Persister, error)
+
+var (
+	factories   = map[string]Factory{}
+	factoriesMu sync.RWMutex
+)
+
+// Register makes a persister available for data source names with the given scheme, for example "dynamodb" for
+// "dynamodb://table-prefix". Storage backends call Register in the init function of their package, which is
+// included in a custom build of Ory Hydra by importing the package, usually behind a build tag:
+//
+//	//go:build dynamodb
+//
+//	package main
+//
+//	import _ "example.com/hydra-dynamodb"
+//
+// Register panics if a persister is already registered for the scheme. Registered persisters take precedence over
+// the SQL persister.
+func Register(scheme string, f Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, ok := factories[scheme]; ok {
+		panic("persistence: a persister is already registered for scheme " + scheme)
+	}
+	factories[scheme] = f
+}
+
+// FactoryFor returns the registered persister for the scheme of the data source name.
+func FactoryFor(dsn string) (Factory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	f, ok := factories[strings.Split(dsn, "://")[0]]
+	return f, ok
+}
@@ -0,0 +1,85 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"database/sql"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	statsProvider interface {
+		Stats() sql.DBStats
+	}
+
+	poolMetric struct {
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+		value     func(s sql.DBStats) float64
+	}
+
+	// PoolCollector exports the connection pool statistics of a database connection. The metrics have the same names
+	// as the ones of the Prometheus DBStatsCollector, so that existing dashboards can be used.
+	PoolCollector struct {
+		db      statsProvider
+		metrics []poolMetric
+	}
+)
+
+var _ prometheus.Collector = (*PoolCollector)(nil)
+
+// NewPoolCollector returns a collector for the connection pool of the given connection, labeled with the database
+// name. The connection must be open.
+func NewPoolCollector(c *pop.Connection, dbName string) (*PoolCollector, error) {
+	db, ok := c.Store.(statsProvider)
+	if !ok {
+		return nil, errors.Errorf("the connection of type %T does not expose connection pool statistics", c.Store)
+	}
+
+	labels := prometheus.Labels{"db_name": dbName}
+	metric := func(name, help string, valueType prometheus.ValueType, value func(s sql.DBStats) float64) poolMetric {
+		return poolMetric{
+			desc:      prometheus.NewDesc("go_sql_"+name, help, nil, labels),
+			valueType: valueType,
+			value:     value,
+		}
+	}
+
+	return &PoolCollector{db: db, metrics: []poolMetric{
+		metric("max_open_connections", "Maximum number of open connections to the database.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }),
+		metric("open_connections", "The number of established connections both in use and idle.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }),
+		metric("in_use_connections", "The number of connections currently in use.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.InUse) }),
+		metric("idle_connections", "The number of idle connections.", prometheus.GaugeValue,
+			func(s sql.DBStats) float64 { return float64(s.Idle) }),
+		metric("wait_count_total", "The total number of connections waited for.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }),
+		metric("wait_duration_seconds_total", "The total time blocked waiting for a new connection.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }),
+		metric("max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }),
+		metric("max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }),
+		metric("max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", prometheus.CounterValue,
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }),
+	}}, nil
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	for _, m := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(stats))
+	}
+}
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS hydra_audit_event
+(
+    id            CHAR(36)     PRIMARY KEY,
+    nid           CHAR(36)     NOT NULL,
+    created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    actor         VARCHAR(255) NOT NULL,
+    source_ip     VARCHAR(255) NOT NULL,
+    request_id    VARCHAR(255) NOT NULL,
+    action        VARCHAR(32)  NOT NULL,
+    resource_type VARCHAR(64)  NOT NULL,
+    resource_id   VARCHAR(255) NOT NULL,
+    before_state  TEXT         NULL,
+    after_state   TEXT         NULL,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_audit_event_nid_created_at_idx ON hydra_audit_event (nid, created_at DESC, id);
+CREATE INDEX hydra_audit_event_nid_resource_idx ON hydra_audit_event (nid, resource_type, resource_id);
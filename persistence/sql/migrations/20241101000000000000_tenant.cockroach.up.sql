@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS hydra_tenant
+(
+    id         UUID                    NOT NULL,
+    name       VARCHAR(64)             NOT NULL,
+    issuer_url VARCHAR(255) DEFAULT '' NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW() NOT NULL,
+    FOREIGN KEY (id) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE,
+    CONSTRAINT "primary" PRIMARY KEY (id ASC)
+);
+
+CREATE UNIQUE INDEX hydra_tenant_name_idx ON hydra_tenant (name);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS hydra_tenant
+(
+    id         CHAR(36)     PRIMARY KEY,
+    name       VARCHAR(64)  NOT NULL,
+    issuer_url VARCHAR(255) DEFAULT '' NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (id) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX hydra_tenant_name_idx ON hydra_tenant (name);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS hydra_uma_resource_set
+(
+    id              CHAR(36)                            PRIMARY KEY,
+    nid             CHAR(36)                            NOT NULL,
+    resource_server VARCHAR(255)                        NOT NULL,
+    owner           VARCHAR(255)                        NOT NULL,
+    name            VARCHAR(255)                        NOT NULL,
+    type            VARCHAR(255) DEFAULT ''             NOT NULL,
+    resource_scopes TEXT                                NOT NULL,
+    description     TEXT                                NOT NULL,
+    icon_uri        TEXT                                NOT NULL,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_uma_resource_set_nid_owner_idx ON hydra_uma_resource_set (nid, resource_server, owner);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS hydra_gnap_grant
+(
+    id                           CHAR(36)                PRIMARY KEY,
+    nid                          CHAR(36)                NOT NULL,
+    client_id                    VARCHAR(255)            NOT NULL,
+    status                       VARCHAR(32)             NOT NULL,
+    requested_scope              TEXT                    NOT NULL,
+    finish_method                VARCHAR(32)  DEFAULT '' NOT NULL,
+    finish_uri                   TEXT                    NOT NULL,
+    finish_nonce                 VARCHAR(255) DEFAULT '' NOT NULL,
+    server_nonce                 VARCHAR(255) DEFAULT '' NOT NULL,
+    interact_ref                 VARCHAR(255) DEFAULT '' NOT NULL,
+    continuation_token_signature VARCHAR(255)            NOT NULL,
+    subject                      VARCHAR(255) DEFAULT '' NOT NULL,
+    force_subject_identifier     VARCHAR(255) DEFAULT '' NOT NULL,
+    consent_challenge_id         VARCHAR(40)             NULL,
+    granted_scope                TEXT                    NOT NULL,
+    granted_audience             TEXT                    NOT NULL,
+    session_access_token         TEXT                    NOT NULL,
+    expires_at                   TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    created_at                   TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at                   TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_gnap_grant_nid_expires_at_idx ON hydra_gnap_grant (nid, expires_at);
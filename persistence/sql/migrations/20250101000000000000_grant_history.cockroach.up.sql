@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS hydra_oauth2_grant_history
+(
+    id               UUID         NOT NULL,
+    nid              UUID         NOT NULL,
+    subject          VARCHAR(255) NOT NULL,
+    client_id        VARCHAR(255) NOT NULL,
+    type             VARCHAR(16)  NOT NULL,
+    value            TEXT         NOT NULL,
+    grant_count      INTEGER      NOT NULL,
+    first_granted_at TIMESTAMP DEFAULT NOW() NOT NULL,
+    last_granted_at  TIMESTAMP DEFAULT NOW() NOT NULL,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE,
+    CONSTRAINT "primary" PRIMARY KEY (id ASC)
+);
+
+CREATE INDEX hydra_oauth2_grant_history_nid_subject_idx ON hydra_oauth2_grant_history (nid, subject, last_granted_at DESC, id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS hydra_oauth2_grant_history
+(
+    id               CHAR(36)     PRIMARY KEY,
+    nid              CHAR(36)     NOT NULL,
+    subject          VARCHAR(255) NOT NULL,
+    client_id        VARCHAR(255) NOT NULL,
+    type             VARCHAR(16)  NOT NULL,
+    value            TEXT         NOT NULL,
+    grant_count      INTEGER      NOT NULL,
+    first_granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    last_granted_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_oauth2_grant_history_nid_subject_idx ON hydra_oauth2_grant_history (nid, subject, last_granted_at DESC, id);
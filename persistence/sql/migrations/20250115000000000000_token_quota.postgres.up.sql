@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS hydra_oauth2_token_quota
+(
+    id            UUID         PRIMARY KEY,
+    nid           UUID         NOT NULL,
+    client_id     VARCHAR(255) NOT NULL,
+    period        VARCHAR(16)  NOT NULL,
+    starts_at     TIMESTAMP DEFAULT NOW() NOT NULL,
+    expires_at    TIMESTAMP DEFAULT NOW() NOT NULL,
+    request_count INTEGER      NOT NULL,
+    FOREIGN KEY (nid) REFERENCES networks (id) ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE INDEX hydra_oauth2_token_quota_client_id_idx ON hydra_oauth2_token_quota (client_id, nid, expires_at);
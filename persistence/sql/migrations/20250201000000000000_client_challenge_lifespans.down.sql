@@ -0,0 +1,5 @@
+ALTER TABLE hydra_oauth2_logout_request DROP COLUMN requested_at;
+ALTER TABLE hydra_client DROP COLUMN logout_challenge_lifespan;
+ALTER TABLE hydra_client DROP COLUMN consent_challenge_lifespan;
+ALTER TABLE hydra_client DROP COLUMN login_challenge_lifespan;
+ALTER TABLE hydra_client DROP COLUMN authorization_code_lifespan;
@@ -0,0 +1,5 @@
+ALTER TABLE hydra_client ADD COLUMN authorization_code_lifespan BIGINT NULL DEFAULT NULL;
+ALTER TABLE hydra_client ADD COLUMN login_challenge_lifespan BIGINT NULL DEFAULT NULL;
+ALTER TABLE hydra_client ADD COLUMN consent_challenge_lifespan BIGINT NULL DEFAULT NULL;
+ALTER TABLE hydra_client ADD COLUMN logout_challenge_lifespan BIGINT NULL DEFAULT NULL;
+ALTER TABLE hydra_oauth2_logout_request ADD COLUMN requested_at TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE hydra_client DROP COLUMN backchannel_logout_token_encrypted_response_enc;
+ALTER TABLE hydra_client DROP COLUMN backchannel_logout_token_encrypted_response_alg;
@@ -0,0 +1,2 @@
+ALTER TABLE hydra_client ADD COLUMN backchannel_logout_token_encrypted_response_alg VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE hydra_client ADD COLUMN backchannel_logout_token_encrypted_response_enc VARCHAR(20) NOT NULL DEFAULT '';
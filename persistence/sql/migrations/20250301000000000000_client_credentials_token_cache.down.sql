@@ -0,0 +1 @@
+ALTER TABLE hydra_client DROP COLUMN client_credentials_grant_access_token_cache_lifespan;
@@ -0,0 +1 @@
+ALTER TABLE hydra_oauth2_flow ADD COLUMN request_parameters TEXT NULL;
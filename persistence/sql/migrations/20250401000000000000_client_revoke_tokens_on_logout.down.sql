@@ -0,0 +1 @@
+ALTER TABLE hydra_client DROP COLUMN revoke_tokens_on_logout;
@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN revoke_tokens_on_logout BOOLEAN NOT NULL DEFAULT false;
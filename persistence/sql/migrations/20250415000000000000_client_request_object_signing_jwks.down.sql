@@ -0,0 +1 @@
+ALTER TABLE hydra_client DROP COLUMN request_object_signing_jwks;
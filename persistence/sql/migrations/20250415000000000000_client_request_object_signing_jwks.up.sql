@@ -0,0 +1 @@
+ALTER TABLE hydra_client ADD COLUMN request_object_signing_jwks TEXT NULL;
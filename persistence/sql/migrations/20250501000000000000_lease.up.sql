@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS hydra_lease
+(
+    name       VARCHAR(255) NOT NULL PRIMARY KEY,
+    holder     VARCHAR(36)  NOT NULL,
+    expires_at TIMESTAMP    NOT NULL
+);
@@ -6,6 +6,7 @@ package sql
 import (
 	"context"
 	"database/sql"
+	stderrors "errors"
 	"io/fs"
 	"reflect"
 
@@ -13,14 +14,10 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
-	"github.com/ory/fosite"
 	"github.com/ory/fosite/storage"
-	"github.com/ory/hydra/v2/aead"
 	"github.com/ory/hydra/v2/driver/config"
-	"github.com/ory/hydra/v2/internal/kratos"
 	"github.com/ory/hydra/v2/persistence"
-	"github.com/ory/hydra/v2/x"
-	"github.com/ory/x/contextx"
+	"github.com/ory/hydra/v2/stats"
 	"github.com/ory/x/errorsx"
 	"github.com/ory/x/fsx"
 	"github.com/ory/x/logrusx"
@@ -40,6 +37,10 @@ var (
 type (
 	Persister struct {
 		conn        *pop.Connection
+		replicas    *replicaSet
+		redis       *redisStore
+		clients     *clientCache
+		stats       *statsCache
 		mb          *popx.MigrationBox
 		mbs         popx.MigrationStatuses
 		r           Dependencies
@@ -48,15 +49,7 @@ type (
 		fallbackNID uuid.UUID
 		p           *networkx.Manager
 	}
-	Dependencies interface {
-		ClientHasher() fosite.Hasher
-		KeyCipher() *aead.AESGCM
-		FlowCipher() *aead.XChaCha20Poly1305
-		Kratos() kratos.Client
-		contextx.Provider
-		x.RegistryLogger
-		x.TracingProvider
-	}
+	Dependencies = persistence.Dependencies
 )
 
 func (p *Persister) BeginTX(ctx context.Context) (_ context.Context, err error) {
@@ -78,7 +71,7 @@ func (p *Persister) BeginTX(ctx context.Context) (_ context.Context, err error)
 		ID:      uuid.Must(uuid.NewV4()).String(),
 		Dialect: p.conn.Dialect,
 	}
-	return popx.WithTransaction(ctx, c), err
+	return popx.WithTransaction(p.withTokenBatch(ctx), c), err
 }
 
 func (p *Persister) Commit(ctx context.Context) (err error) {
@@ -91,6 +84,10 @@ func (p *Persister) Commit(ctx context.Context) (err error) {
 		return errorsx.WithStack(ErrNoTransactionOpen)
 	}
 
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return err
+	}
+
 	return errorsx.WithStack(tx.TX.Commit())
 }
 
@@ -104,6 +101,7 @@ func (p *Persister) Rollback(ctx context.Context) (err error) {
 		return errorsx.WithStack(ErrNoTransactionOpen)
 	}
 
+	discardTokenBatch(ctx)
 	return errorsx.WithStack(tx.TX.Rollback())
 }
 
@@ -123,6 +121,7 @@ func NewPersister(ctx context.Context, c *pop.Connection, r Dependencies, config
 		config: config,
 		l:      r.Logger(),
 		p:      networkx.NewManager(c, r.Logger(), r.Tracer(ctx)),
+		stats:  &statsCache{statistics: map[uuid.UUID]*stats.Statistics{}},
 	}, nil
 }
 
@@ -161,6 +160,21 @@ func (p *Persister) QueryWithNetwork(ctx context.Context) *pop.Query {
 	return p.Connection(ctx).Where("nid = ?", p.NetworkID(ctx))
 }
 
+// Close closes the connections to the primary database, its read replicas and Redis.
+func (p *Persister) Close() error {
+	var errs []error
+	if p.replicas != nil {
+		for _, c := range p.replicas.conns {
+			errs = append(errs, c.Close())
+		}
+	}
+	if p.redis != nil {
+		errs = append(errs, p.redis.c.Close())
+	}
+	errs = append(errs, p.conn.Close())
+	return errors.WithStack(stderrors.Join(errs...))
+}
+
 func (p *Persister) Connection(ctx context.Context) *pop.Connection {
 	return popx.GetConnection(ctx, p.conn)
 }
@@ -0,0 +1,106 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlcon"
+)
+
+func (p *Persister) CreateAuditEvent(ctx context.Context, e *audit.Event) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateAuditEvent")
+	defer otelx.End(span, &err)
+
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, e))
+}
+
+func (p *Persister) GetAuditEvents(ctx context.Context, filters audit.Filter) (_ []audit.Event, _ *keysetpagination.Paginator, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetAuditEvents")
+	defer otelx.End(span, &err)
+
+	paginator := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{}),
+	}, filters.PageOpts...)...)
+
+	query := p.QueryWithNetwork(ctx).
+		Scope(paginateNewestFirst(paginator, "created_at", "id"))
+	if filters.Actor != "" {
+		query = query.Where("actor = ?", filters.Actor)
+	}
+	if filters.ResourceType != "" {
+		query = query.Where("resource_type = ?", filters.ResourceType)
+	}
+	if filters.ResourceID != "" {
+		query = query.Where("resource_id = ?", filters.ResourceID)
+	}
+
+	events := make([]audit.Event, 0)
+	if err := query.All(&events); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	events, nextPage := keysetpagination.Result(events, paginator)
+	return events, nextPage, nil
+}
+
+// FlushAuditEvents deletes the audit events which were created before notAfter.
+func (p *Persister) FlushAuditEvents(ctx context.Context, notAfter time.Time, limit int, batchSize int) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushAuditEvents")
+	defer otelx.End(span, &err)
+
+	var e audit.Event
+	ids := []string{}
+	/* #nosec G201 limit is an integer */
+	if err := p.Connection(ctx).RawQuery(
+		fmt.Sprintf("SELECT id FROM %s WHERE created_at < ? AND nid = ? ORDER BY id LIMIT %d", e.TableName(), limit),
+		notAfter, p.NetworkID(ctx),
+	).All(&ids); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for i := 0; i < len(ids); i += batchSize {
+		j := i + batchSize
+		if j > len(ids) {
+			j = len(ids)
+		}
+
+		/* #nosec G201 table is static */
+		n, err := p.Connection(ctx).RawQuery(
+			fmt.Sprintf("DELETE FROM %s WHERE id in (?) AND nid = ?", e.TableName()),
+			ids[i:j],
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		x.RecordJanitorDeletedRows(ctx, e.TableName(), int64(n))
+	}
+
+	return nil
+}
+
+// paginateNewestFirst orders rows by the given timestamp column (newest first) and ID column, and continues after the
+// row encoded in the page token. The generic keyset scope can not be used here because it compares the timestamp as a
+// string.
+func paginateNewestFirst(paginator *keysetpagination.Paginator, timeColumn, idColumn string) pop.ScopeFunc {
+	return func(q *pop.Query) *pop.Query {
+		token := paginator.Token().Parse(idColumn)
+		if at, err := time.Parse(time.RFC3339Nano, token[timeColumn]); err == nil {
+			q = q.Where(fmt.Sprintf("(%[1]s < ? OR (%[1]s = ? AND %[2]s > ?))", timeColumn, idColumn), at, at, token[idColumn])
+		}
+		return q.
+			Order(timeColumn + " DESC").
+			Order(idColumn + " ASC").
+			Limit(paginator.Size() + 1)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/backup"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ backup.Manager = &Persister{}
+
+func (p *Persister) ExportBundle(ctx context.Context) (_ *backup.Bundle, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ExportBundle")
+	defer otelx.End(span, &err)
+
+	b := &backup.Bundle{
+		Version:                backup.BundleVersion,
+		CreatedAt:              time.Now().UTC().Round(time.Second),
+		Clients:                []backup.Client{},
+		JSONWebKeySets:         map[string]*jose.JSONWebKeySet{},
+		TrustedJwtGrantIssuers: []trust.Grant{},
+	}
+
+	var clients []client.Client
+	if err := p.QueryWithNetwork(ctx).Order("id ASC").All(&clients); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	for _, c := range clients {
+		b.Clients = append(b.Clients, backup.NewClient(c))
+	}
+
+	var keys []jwk.SQLData
+	if err := p.QueryWithNetwork(ctx).Order("sid ASC, created_at DESC").All(&keys); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	for _, d := range keys {
+		decrypted, err := p.r.KeyCipher().Decrypt(ctx, d.Key, nil)
+		if err != nil {
+			return nil, errorsx.WithStack(err)
+		}
+
+		var key jose.JSONWebKey
+		if err := json.Unmarshal(decrypted, &key); err != nil {
+			return nil, errorsx.WithStack(err)
+		}
+
+		set, ok := b.JSONWebKeySets[d.Set]
+		if !ok {
+			set = &jose.JSONWebKeySet{}
+			b.JSONWebKeySets[d.Set] = set
+		}
+		set.Keys = append(set.Keys, key)
+	}
+
+	var grants []trust.SQLData
+	if err := p.QueryWithNetwork(ctx).Order("created_at ASC, id ASC").All(&grants); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	for _, g := range grants {
+		b.TrustedJwtGrantIssuers = append(b.TrustedJwtGrantIssuers, p.jwtGrantFromSQlData(g))
+	}
+
+	return b, nil
+}
+
+func (p *Persister) ImportBundle(ctx context.Context, b *backup.Bundle) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ImportBundle")
+	defer otelx.End(span, &err)
+
+	return p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		// Keys are imported first, because trusted JWT grant issuers reference them.
+		for _, set := range b.JSONWebKeySetIDs() {
+			for _, key := range b.JSONWebKeySets[set].Keys {
+				key := key
+				if err := p.importKey(ctx, set, &key); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, bc := range b.Clients {
+			if err := p.importClient(ctx, c, bc); err != nil {
+				return err
+			}
+		}
+
+		for _, g := range b.TrustedJwtGrantIssuers {
+			// Unlike DeleteGrant, this keeps the public key which other grants might reference.
+			if err := p.QueryWithNetwork(ctx).Where("id = ?", g.ID).Delete(&trust.SQLData{}); err != nil {
+				return sqlcon.HandleError(err)
+			}
+			if err := p.CreateGrant(ctx, g, b.JSONWebKeySets[g.PublicKey.Set].Key(g.PublicKey.KeyID)[0]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// importKey creates or replaces the key. Unlike UpdateKey, it replaces the key in place, so that trusted JWT grant
+// issuers referencing the key are kept.
+func (p *Persister) importKey(ctx context.Context, set string, key *jose.JSONWebKey) error {
+	out, err := json.Marshal(key)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	encrypted, err := p.r.KeyCipher().Encrypt(ctx, out, nil)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	n, err := p.Connection(ctx).
+		RawQuery("UPDATE hydra_jwk SET keydata = ? WHERE nid = ? AND sid = ? AND kid = ?", encrypted, p.NetworkID(ctx), set, key.KeyID).
+		ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if n > 0 {
+		return nil
+	}
+
+	return p.AddKey(ctx, set, key)
+}
+
+// importClient creates or replaces the client. Unlike CreateClient and UpdateClient, it keeps the hashed secret.
+func (p *Persister) importClient(ctx context.Context, c *pop.Connection, bc backup.Client) error {
+	cl := bc.Client
+	if cl.Secret != "" {
+		h, err := p.r.ClientHasher().Hash(ctx, []byte(cl.Secret))
+		if err != nil {
+			return errorsx.WithStack(err)
+		}
+		cl.Secret = string(h)
+	} else {
+		cl.Secret = bc.HashedSecret
+	}
+
+	if err := cl.BeforeSave(c); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	if _, err := p.GetConcreteClient(ctx, cl.ID); errors.Is(err, sqlcon.ErrNoRows) {
+		return sqlcon.HandleError(p.CreateWithNetwork(ctx, &cl))
+	} else if err != nil {
+		return err
+	}
+
+	_, err := p.UpdateWithNetwork(ctx, &cl)
+	p.evictClient(ctx, cl.ID)
+	return sqlcon.HandleError(err)
+}
@@ -10,12 +10,15 @@ import (
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/ory/x/errorsx"
 	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
 
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/extension"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -23,21 +26,49 @@ func (p *Persister) GetConcreteClient(ctx context.Context, id string) (c *client
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetConcreteClient")
 	defer otelx.End(span, &err)
 
+	if cl, ok := p.cachedClient(ctx, id); ok {
+		return cl, nil
+	}
+
 	var cl client.Client
-	if err := p.QueryWithNetwork(ctx).Where("id = ?", id).First(&cl); err != nil {
+	if err := p.read(ctx, func(q *pop.Query) error {
+		return q.Where("id = ?", id).First(&cl)
+	}); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
+	p.cacheClient(ctx, &cl)
 	return &cl, nil
 }
 
+// GetClient returns the client for OAuth 2.0 and OpenID Connect flows. Clients which are not stored in the database
+// are resolved by the plugins.
 func (p *Persister) GetClient(ctx context.Context, id string) (fosite.Client, error) {
-	return p.GetConcreteClient(ctx, id)
+	c, err := p.GetConcreteClient(ctx, id)
+	if err == nil {
+		return c.ForContext(ctx), nil
+	} else if !errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, err
+	}
+
+	for _, r := range extension.Hooks[extension.ClientResolver](p.r.Plugins()) {
+		resolved, rerr := r.ResolveClient(ctx, id)
+		if rerr != nil {
+			return nil, errors.Wrapf(rerr, "plugin %s failed to resolve the client", r.Name())
+		} else if resolved != nil {
+			return resolved, nil
+		}
+	}
+	return nil, err
 }
 
 func (p *Persister) UpdateClient(ctx context.Context, cl *client.Client) (err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateClient")
 	defer otelx.End(span, &err)
 
+	// The client is evicted once the transaction is committed, so that concurrent lookups do not cache it again
+	// before.
+	defer p.evictClient(ctx, cl.GetID())
+
 	return p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
 		o, err := p.GetConcreteClient(ctx, cl.GetID())
 		if err != nil {
@@ -128,6 +159,7 @@ func (p *Persister) DeleteClient(ctx context.Context, id string) (err error) {
 	if err := sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("id = ?", id).Delete(&client.Client{})); err != nil {
 		return err
 	}
+	p.evictClient(ctx, id)
 
 	events.Trace(ctx, events.ClientDeleted,
 		events.WithClientID(c.ID),
@@ -136,15 +168,18 @@ func (p *Persister) DeleteClient(ctx context.Context, id string) (err error) {
 	return nil
 }
 
-func (p *Persister) GetClients(ctx context.Context, filters client.Filter) (_ []client.Client, err error) {
+func (p *Persister) GetClients(ctx context.Context, filters client.Filter) (_ []client.Client, _ *keysetpagination.Paginator, err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetClients")
 	defer otelx.End(span, &err)
 
+	paginator := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{"id": ""}),
+	}, filters.PageOpts...)...)
+
 	cs := make([]client.Client, 0)
 
 	query := p.QueryWithNetwork(ctx).
-		Paginate(filters.Offset/filters.Limit+1, filters.Limit).
-		Order("id")
+		Scope(keysetpagination.Paginate[client.Client](paginator))
 
 	if filters.Name != "" {
 		query.Where("client_name = ?", filters.Name)
@@ -154,9 +189,11 @@ func (p *Persister) GetClients(ctx context.Context, filters client.Filter) (_ []
 	}
 
 	if err := query.All(&cs); err != nil {
-		return nil, sqlcon.HandleError(err)
+		return nil, nil, sqlcon.HandleError(err)
 	}
-	return cs, nil
+
+	cs, nextPage := keysetpagination.Result(cs, paginator)
+	return cs, nextPage, nil
 }
 
 func (p *Persister) CountClients(ctx context.Context) (n int, err error) {
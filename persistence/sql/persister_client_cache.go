@@ -0,0 +1,81 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/popx"
+)
+
+// clientCache caches OAuth 2.0 Clients in memory. Clients are removed from the cache when they are updated or deleted
+// through this persister, and expire after the TTL otherwise.
+type clientCache struct {
+	c   *ristretto.Cache
+	ttl time.Duration
+}
+
+// WithClientCache returns a persister which caches up to maxClients OAuth 2.0 Clients in memory for ttl.
+func (p Persister) WithClientCache(maxClients int, ttl time.Duration) (*Persister, error) {
+	c, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: int64(maxClients) * 10,
+		MaxCost:     int64(maxClients),
+		BufferItems: 64,
+		// Each client costs 1, so MaxCost is the maximum number of clients.
+		IgnoreInternalCost: true,
+	})
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	p.clients = &clientCache{c: c, ttl: ttl}
+	return &p, nil
+}
+
+func (p *Persister) clientCacheKey(ctx context.Context, id string) string {
+	return p.NetworkID(ctx).String() + ":" + id
+}
+
+// useClientCache returns whether clients are cached. Transactions do not use the cache, because they must see their
+// own changes, and must not cache changes which are not committed yet.
+func (p *Persister) useClientCache(ctx context.Context) bool {
+	if p.clients == nil {
+		return false
+	}
+	fallback := &pop.Connection{TX: &pop.Tx{}}
+	return popx.GetConnection(ctx, fallback).TX == fallback.TX
+}
+
+// cachedClient returns a copy of the cached client, because callers modify the clients they get.
+func (p *Persister) cachedClient(ctx context.Context, id string) (*client.Client, bool) {
+	if !p.useClientCache(ctx) {
+		return nil, false
+	}
+
+	v, ok := p.clients.c.Get(p.clientCacheKey(ctx, id))
+	if !ok {
+		return nil, false
+	}
+	cl := v.(client.Client)
+	return &cl, true
+}
+
+func (p *Persister) cacheClient(ctx context.Context, cl *client.Client) {
+	if !p.useClientCache(ctx) {
+		return
+	}
+	p.clients.c.SetWithTTL(p.clientCacheKey(ctx, cl.ID), *cl, 1, p.clients.ttl)
+}
+
+func (p *Persister) evictClient(ctx context.Context, id string) {
+	if p.clients == nil {
+		return
+	}
+	p.clients.c.Del(p.clientCacheKey(ctx, id))
+}
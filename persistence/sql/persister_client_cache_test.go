@@ -0,0 +1,80 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+)
+
+func TestClientCache(t *testing.T) {
+	ctx := context.Background()
+	p, err := internal.NewMockedRegistry(t, new(contextx.Default)).Persister().(*sql.Persister).WithClientCache(10, time.Hour)
+	require.NoError(t, err)
+
+	rename := func(t *testing.T, id, name string) {
+		require.NoError(t, p.Connection(ctx).RawQuery("UPDATE hydra_client SET client_name = ? WHERE id = ?", name, id).Exec())
+	}
+	// cached waits until the client is cached, which happens asynchronously.
+	cached := func(t *testing.T, id string) {
+		var i int
+		require.Eventually(t, func() bool {
+			_, err := p.GetConcreteClient(ctx, id)
+			require.NoError(t, err)
+
+			i++
+			name := fmt.Sprintf("renamed-%d", i)
+			rename(t, id, name)
+			cl, err := p.GetConcreteClient(ctx, id)
+			require.NoError(t, err)
+			return cl.Name != name
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("case=returns copies of cached clients", func(t *testing.T) {
+		require.NoError(t, p.CreateClient(ctx, &client.Client{ID: "copied-client", Name: "original"}))
+		cached(t, "copied-client")
+
+		cl, err := p.GetConcreteClient(ctx, "copied-client")
+		require.NoError(t, err)
+		cl.Name = "modified"
+
+		cl, err = p.GetConcreteClient(ctx, "copied-client")
+		require.NoError(t, err)
+		assert.NotEqual(t, "modified", cl.Name)
+	})
+
+	t.Run("case=evicts updated clients", func(t *testing.T) {
+		require.NoError(t, p.CreateClient(ctx, &client.Client{ID: "updated-client", Name: "original"}))
+		cached(t, "updated-client")
+
+		require.NoError(t, p.UpdateClient(ctx, &client.Client{ID: "updated-client", Name: "updated"}))
+		assert.Eventually(t, func() bool {
+			cl, err := p.GetConcreteClient(ctx, "updated-client")
+			require.NoError(t, err)
+			return cl.Name == "updated"
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("case=evicts deleted clients", func(t *testing.T) {
+		require.NoError(t, p.CreateClient(ctx, &client.Client{ID: "deleted-client", Name: "original"}))
+		cached(t, "deleted-client")
+
+		require.NoError(t, p.DeleteClient(ctx, "deleted-client"))
+		assert.Eventually(t, func() bool {
+			_, err := p.GetConcreteClient(ctx, "deleted-client")
+			return err != nil
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ory/hydra/v2/oauth2/flowctx"
 	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlxx"
 
 	"github.com/ory/x/errorsx"
@@ -35,6 +36,10 @@ func (p *Persister) RevokeSubjectConsentSession(ctx context.Context, user string
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSubjectConsentSession")
 	defer span.End()
 
+	if err := p.evictRememberedConsents(ctx, user); err != nil {
+		return err
+	}
+
 	return p.transaction(ctx, p.revokeConsentSession("consent_challenge_id IS NOT NULL AND subject = ?", user))
 }
 
@@ -42,6 +47,10 @@ func (p *Persister) RevokeSubjectClientConsentSession(ctx context.Context, user,
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSubjectClientConsentSession")
 	defer span.End()
 
+	if err := p.evictRememberedConsents(ctx, user); err != nil {
+		return err
+	}
+
 	return p.transaction(ctx, p.revokeConsentSession("consent_challenge_id IS NOT NULL AND subject = ? AND client_id = ?", user, client))
 }
 
@@ -113,6 +122,46 @@ func (p *Persister) RevokeSubjectLoginSession(ctx context.Context, subject strin
 	return nil
 }
 
+func (p *Persister) RevokeLoginSessionTokens(ctx context.Context, subject, sid string) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeLoginSessionTokens")
+	defer otelx.End(span, &err)
+
+	where, args := "f.login_session_id IS NOT NULL", []interface{}{}
+	if sid != "" {
+		where, args = "f.login_session_id = ?", []interface{}{sid}
+	}
+
+	challenges := []string{}
+	if err := p.Connection(ctx).RawQuery(
+		/* #nosec G201 - where is static */
+		fmt.Sprintf(`
+SELECT f.consent_challenge_id FROM hydra_oauth2_flow as f
+JOIN hydra_client as c ON (c.id = f.client_id AND c.nid = f.nid)
+WHERE
+	f.subject = ? AND
+	%s AND
+	f.consent_challenge_id IS NOT NULL AND
+	c.revoke_tokens_on_logout = ? AND
+	f.nid = ?`, where),
+		append(append([]interface{}{subject}, args...), true, p.NetworkID(ctx))...,
+	).All(&challenges); errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for _, challenge := range challenges {
+		if err := p.RevokeAccessToken(ctx, challenge); err != nil && !errors.Is(err, fosite.ErrNotFound) {
+			return err
+		}
+		if err := p.RevokeRefreshToken(ctx, challenge); err != nil && !errors.Is(err, fosite.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (p *Persister) CreateForcedObfuscatedLoginSession(ctx context.Context, session *consent.ForcedObfuscatedLoginSession) error {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateForcedObfuscatedLoginSession")
 	defer span.End()
@@ -192,7 +241,7 @@ func (p *Persister) GetFlowByConsentChallenge(ctx context.Context, challenge str
 	if f.NID != p.NetworkID(ctx) {
 		return nil, errorsx.WithStack(x.ErrNotFound)
 	}
-	if f.RequestedAt.Add(p.config.ConsentRequestMaxAge(ctx)).Before(time.Now()) {
+	if f.RequestedAt.Add(f.Client.GetConsentChallengeLifespan(p.config.ConsentChallengeLifespan(ctx))).Before(time.Now()) {
 		return nil, errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The consent request has expired, please try again."))
 	}
 
@@ -214,7 +263,9 @@ func (p *Persister) GetConsentRequest(ctx context.Context, challenge string) (*f
 	// We need to overwrite the ID with the encoded flow (challenge) so that the client is not confused.
 	f.ConsentChallengeID = sqlxx.NullString(challenge)
 
-	return f.GetConsentRequest(), nil
+	cr := f.GetConsentRequest()
+	cr.ExpiresAt = f.RequestedAt.Add(f.Client.GetConsentChallengeLifespan(p.config.ConsentChallengeLifespan(ctx))).UTC()
+	return cr, nil
 }
 
 func (p *Persister) CreateLoginRequest(ctx context.Context, req *flow.LoginRequest) (*flow.Flow, error) {
@@ -242,6 +293,9 @@ func (p *Persister) GetFlow(ctx context.Context, loginChallenge string) (*flow.F
 		}
 		return nil, sqlcon.HandleError(err)
 	}
+	if err := p.decryptConsentPayloads(ctx, &f); err != nil {
+		return nil, err
+	}
 	return &f, nil
 }
 
@@ -256,13 +310,15 @@ func (p *Persister) GetLoginRequest(ctx context.Context, loginChallenge string)
 	if f.NID != p.NetworkID(ctx) {
 		return nil, errorsx.WithStack(x.ErrNotFound)
 	}
-	if f.RequestedAt.Add(p.config.ConsentRequestMaxAge(ctx)).Before(time.Now()) {
+	expiresAt := f.RequestedAt.Add(f.Client.GetLoginChallengeLifespan(p.config.LoginChallengeLifespan(ctx)))
+	if expiresAt.Before(time.Now()) {
 		return nil, errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The login request has expired, please try again."))
 	}
 	lr := f.GetLoginRequest()
 	// Restore the short challenge ID, which was previously sent to the encoded flow,
 	// to make sure that the challenge ID in the returned flow matches the param.
 	lr.ID = loginChallenge
+	lr.ExpiresAt = expiresAt.UTC()
 
 	return lr, nil
 }
@@ -307,9 +363,19 @@ func (p *Persister) VerifyAndInvalidateConsentRequest(ctx context.Context, verif
 	// without encoding the whole flow.
 	f.ConsentChallengeID = sqlxx.NullString(uuid.Must(uuid.NewV4()).String())
 
-	if err = p.Connection(ctx).Create(f); err != nil {
+	// A cached lookup would not find the new consent until it expires.
+	if err := p.evictRememberedConsents(ctx, f.Subject); err != nil {
+		p.l.WithError(err).Warn("Unable to evict the remembered consents from the cache.")
+	}
+
+	encrypted, _, err := p.encryptConsentPayloads(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.Connection(ctx).Create(encrypted); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
+	f.AfterSave(p.Connection(ctx))
 
 	return f.GetHandledConsentRequest(), nil
 }
@@ -488,10 +554,20 @@ func (p *Persister) FindGrantedAndRememberedConsentRequests(ctx context.Context,
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindGrantedAndRememberedConsentRequests")
 	defer span.End()
 
-	var f flow.Flow
-	if err = p.Connection(ctx).
-		Where(
-			strings.TrimSpace(fmt.Sprintf(`
+	// Returning users look up their remembered consent on every authorization request, so the lookup is cached.
+	f, cached := p.cachedRememberedConsent(ctx, client, subject)
+	if cached && f == nil {
+		return nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
+	} else if cached {
+		if f.Client, err = p.GetConcreteClient(ctx, f.ClientID); err != nil {
+			return nil, err
+		}
+		f.AfterSave(p.Connection(ctx))
+	} else {
+		f = new(flow.Flow)
+		if err = p.Connection(ctx).
+			Where(
+				strings.TrimSpace(fmt.Sprintf(`
 (state = %d OR state = %d) AND
 subject = ? AND
 client_id = ? AND
@@ -499,24 +575,34 @@ consent_skip=FALSE AND
 consent_error='{}' AND
 consent_remember=TRUE AND
 nid = ?`, flow.FlowStateConsentUsed, flow.FlowStateConsentUnused,
-			)),
-			subject, client, p.NetworkID(ctx)).
-		Order("requested_at DESC").
-		Limit(1).
-		First(&f); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
+				)),
+				subject, client, p.NetworkID(ctx)).
+			Order("requested_at DESC").
+			Limit(1).
+			First(f); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				p.cacheRememberedConsent(ctx, client, subject, nil)
+				return nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
+			}
+			return nil, sqlcon.HandleError(err)
 		}
-		return nil, sqlcon.HandleError(err)
+		p.cacheRememberedConsent(ctx, client, subject, f)
+	}
+	if err := p.decryptConsentPayloads(ctx, f); err != nil {
+		return nil, err
 	}
 
 	return p.filterExpiredConsentRequests(ctx, []flow.AcceptOAuth2ConsentRequest{*f.GetHandledConsentRequest()})
 }
 
-func (p *Persister) FindSubjectsGrantedConsentRequests(ctx context.Context, subject string, limit, offset int) ([]flow.AcceptOAuth2ConsentRequest, error) {
+func (p *Persister) FindSubjectsGrantedConsentRequests(ctx context.Context, subject string, pageOpts ...keysetpagination.Option) ([]flow.AcceptOAuth2ConsentRequest, *keysetpagination.Paginator, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindSubjectsGrantedConsentRequests")
 	defer span.End()
 
+	paginator := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{}),
+	}, pageOpts...)...)
+
 	var fs []flow.Flow
 	c := p.Connection(ctx)
 
@@ -530,27 +616,25 @@ consent_error='{}' AND
 nid = ?`, flow.FlowStateConsentUsed, flow.FlowStateConsentUnused,
 			)),
 			subject, p.NetworkID(ctx)).
-		Order("requested_at DESC").
-		Paginate(offset/limit+1, limit).
+		Scope(paginateNewestFirst(paginator, "requested_at", "login_challenge")).
 		All(&fs); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
+			return nil, nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
 		}
-		return nil, sqlcon.HandleError(err)
-	}
-
-	var rs []flow.AcceptOAuth2ConsentRequest
-	for _, f := range fs {
-		rs = append(rs, *f.GetHandledConsentRequest())
+		return nil, nil, sqlcon.HandleError(err)
 	}
 
-	return p.filterExpiredConsentRequests(ctx, rs)
+	return p.consentRequestsFromFlows(ctx, fs, paginator)
 }
 
-func (p *Persister) FindSubjectsSessionGrantedConsentRequests(ctx context.Context, subject, sid string, limit, offset int) ([]flow.AcceptOAuth2ConsentRequest, error) {
+func (p *Persister) FindSubjectsSessionGrantedConsentRequests(ctx context.Context, subject, sid string, pageOpts ...keysetpagination.Option) ([]flow.AcceptOAuth2ConsentRequest, *keysetpagination.Paginator, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindSubjectsSessionGrantedConsentRequests")
 	defer span.End()
 
+	paginator := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{}),
+	}, pageOpts...)...)
+
 	var fs []flow.Flow
 	c := p.Connection(ctx)
 
@@ -565,21 +649,37 @@ consent_error='{}' AND
 nid = ?`, flow.FlowStateConsentUsed, flow.FlowStateConsentUnused,
 			)),
 			subject, sid, p.NetworkID(ctx)).
-		Order("requested_at DESC").
-		Paginate(offset/limit+1, limit).
+		Scope(paginateNewestFirst(paginator, "requested_at", "login_challenge")).
 		All(&fs); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
+			return nil, nil, errorsx.WithStack(consent.ErrNoPreviousConsentFound)
 		}
-		return nil, sqlcon.HandleError(err)
+		return nil, nil, sqlcon.HandleError(err)
 	}
 
+	return p.consentRequestsFromFlows(ctx, fs, paginator)
+}
+
+func (p *Persister) consentRequestsFromFlows(ctx context.Context, fs []flow.Flow, paginator *keysetpagination.Paginator) ([]flow.AcceptOAuth2ConsentRequest, *keysetpagination.Paginator, error) {
+	fs, nextPage := keysetpagination.Result(fs, paginator)
+
 	var rs []flow.AcceptOAuth2ConsentRequest
 	for _, f := range fs {
+		if err := p.decryptConsentPayloads(ctx, &f); err != nil {
+			return nil, nil, err
+		}
 		rs = append(rs, *f.GetHandledConsentRequest())
 	}
 
-	return p.filterExpiredConsentRequests(ctx, rs)
+	rs, err := p.filterExpiredConsentRequests(ctx, rs)
+	if errors.Is(err, consent.ErrNoPreviousConsentFound) && !nextPage.IsLast() {
+		// All consent requests on this page have expired, but there might be more on the next page.
+		return []flow.AcceptOAuth2ConsentRequest{}, nextPage, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return rs, nextPage, nil
 }
 
 func (p *Persister) CountSubjectsGrantedConsentRequests(ctx context.Context, subject string) (int, error) {
@@ -698,7 +798,20 @@ func (p *Persister) GetLogoutRequest(ctx context.Context, challenge string) (*fl
 	defer span.End()
 
 	var lr flow.LogoutRequest
-	return &lr, sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("challenge = ? AND rejected = FALSE", challenge).First(&lr))
+	if err := sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("challenge = ? AND rejected = FALSE", challenge).First(&lr)); err != nil {
+		return &lr, err
+	}
+
+	// Logout requests which were created before logout challenges expired have no requested_at and never expire.
+	if requestedAt := time.Time(lr.RequestedAt); !requestedAt.IsZero() {
+		expiresAt := requestedAt.Add(lr.Client.GetLogoutChallengeLifespan(p.config.LogoutChallengeLifespan(ctx))).UTC()
+		if expiresAt.Before(time.Now()) {
+			return nil, errorsx.WithStack(fosite.ErrRequestUnauthorized.WithHint("The logout request has expired, please try again."))
+		}
+		lr.ExpiresAt = &expiresAt
+	}
+
+	return &lr, nil
 }
 
 func (p *Persister) VerifyAndInvalidateLogoutRequest(ctx context.Context, verifier string) (*flow.LogoutRequest, error) {
@@ -737,7 +850,11 @@ func (p *Persister) FlushInactiveLoginConsentRequests(ctx context.Context, notAf
 	var f flow.Flow
 
 	// The value of notAfter should be the minimum between input parameter and request max expire based on its configured age
-	requestMaxExpire := time.Now().Add(-p.config.ConsentRequestMaxAge(ctx))
+	requestMaxAge := p.config.LoginChallengeLifespan(ctx)
+	if consentMaxAge := p.config.ConsentChallengeLifespan(ctx); consentMaxAge > requestMaxAge {
+		requestMaxAge = consentMaxAge
+	}
+	requestMaxExpire := time.Now().Add(-requestMaxAge)
 	if requestMaxExpire.Before(notAfter) {
 		notAfter = requestMaxExpire
 	}
@@ -763,7 +880,7 @@ func (p *Persister) FlushInactiveLoginConsentRequests(ctx context.Context, notAf
 	// - flow.login_error has valid error (login rejected)
 	// - flow.consent_error has valid error (consent rejected)
 	// AND timed-out
-	// - flow.requested_at < minimum of ttl.login_consent_request and notAfter
+	// - flow.requested_at < minimum of the longer of ttl.login_challenge and ttl.consent_challenge, and notAfter
 	q := p.Connection(ctx).RawQuery(fmt.Sprintf(queryFormat, limit), flow.FlowStateConsentUsed, notAfter, p.NetworkID(ctx))
 
 	if err := q.All(&challenges); err == sql.ErrNoRows {
@@ -783,9 +900,48 @@ func (p *Persister) FlushInactiveLoginConsentRequests(ctx context.Context, notAf
 			p.NetworkID(ctx),
 		)
 
-		if err := q.Exec(); err != nil {
+		n, err := q.ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		x.RecordJanitorDeletedRows(ctx, f.TableName(), int64(n))
+	}
+
+	return nil
+}
+
+// FlushConsentSessions deletes granted consent sessions which were requested before notAfter. The tokens issued from
+// the consent sessions are deleted in cascade.
+func (p *Persister) FlushConsentSessions(ctx context.Context, notAfter time.Time, limit int, batchSize int) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushConsentSessions")
+	defer otelx.End(span, &err)
+
+	var f flow.Flow
+	challenges := []string{}
+	/* #nosec G201 limit is an integer */
+	if err := p.Connection(ctx).RawQuery(
+		fmt.Sprintf("SELECT login_challenge FROM %s WHERE state = ? AND requested_at < ? AND nid = ? ORDER BY login_challenge LIMIT %d", f.TableName(), limit),
+		flow.FlowStateConsentUsed, notAfter, p.NetworkID(ctx),
+	).All(&challenges); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for i := 0; i < len(challenges); i += batchSize {
+		j := i + batchSize
+		if j > len(challenges) {
+			j = len(challenges)
+		}
+
+		/* #nosec G201 table is static */
+		n, err := p.Connection(ctx).RawQuery(
+			fmt.Sprintf("DELETE FROM %s WHERE login_challenge in (?) AND nid = ?", f.TableName()),
+			challenges[i:j],
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		if err != nil {
 			return sqlcon.HandleError(err)
 		}
+		x.RecordJanitorDeletedRows(ctx, f.TableName(), int64(n))
 	}
 
 	return nil
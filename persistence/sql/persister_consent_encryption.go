@@ -0,0 +1,176 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// Set the aadConsentPayloadPrefix to something unique to avoid ciphertext confusion with other usages of the AEAD
+// cipher.
+var aadConsentPayloadPrefix = "consent-payload:" // nolint:gosec
+
+// The consent payload columns hold JSON, so encrypted payloads are stored as a JSON object with the ciphertext as the
+// only value.
+const consentPayloadCiphertextKey = "hydra_ciphertext"
+
+func consentPayloadAAD(f *flow.Flow, column string) []byte {
+	return []byte(aadConsentPayloadPrefix + column + ":" + f.ID)
+}
+
+func consentPayloadCiphertext(payload map[string]interface{}) (string, bool) {
+	if len(payload) != 1 {
+		return "", false
+	}
+	ciphertext, ok := payload[consentPayloadCiphertextKey].(string)
+	return ciphertext, ok
+}
+
+// encryptConsentPayload returns the encrypted payload, or the payload itself if it is empty or already encrypted.
+func (p *Persister) encryptConsentPayload(ctx context.Context, f *flow.Flow, column string, payload map[string]interface{}) (map[string]interface{}, bool, error) {
+	if _, ok := consentPayloadCiphertext(payload); ok || len(payload) == 0 {
+		return payload, false, nil
+	}
+
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return nil, false, errorsx.WithStack(err)
+	}
+	ciphertext, err := p.r.KeyCipher().Encrypt(ctx, plaintext, consentPayloadAAD(f, column))
+	if err != nil {
+		return nil, false, errorsx.WithStack(err)
+	}
+	return map[string]interface{}{consentPayloadCiphertextKey: ciphertext}, true, nil
+}
+
+// decryptConsentPayload returns the decrypted payload, or the payload itself if it is not encrypted.
+func (p *Persister) decryptConsentPayload(ctx context.Context, f *flow.Flow, column string, payload map[string]interface{}) (map[string]interface{}, error) {
+	ciphertext, ok := consentPayloadCiphertext(payload)
+	if !ok {
+		return payload, nil
+	}
+
+	plaintext, err := p.r.KeyCipher().Decrypt(ctx, ciphertext, consentPayloadAAD(f, column))
+	if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	decrypted := map[string]interface{}{}
+	if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return decrypted, nil
+}
+
+// encryptConsentPayloads returns a copy of the flow with the ID token claims, access token claims and context of the
+// consent session encrypted, if session data is encrypted at rest. It also returns whether any payload was encrypted.
+func (p *Persister) encryptConsentPayloads(ctx context.Context, f *flow.Flow) (*flow.Flow, bool, error) {
+	if !p.config.EncryptSessionData(ctx) {
+		return f, false, nil
+	}
+
+	encrypted := *f
+	var idTokenChanged, accessTokenChanged, contextChanged bool
+	var err error
+	if encrypted.SessionIDToken, idTokenChanged, err = p.encryptConsentPayload(ctx, f, "session_id_token", f.SessionIDToken); err != nil {
+		return nil, false, err
+	}
+	if encrypted.SessionAccessToken, accessTokenChanged, err = p.encryptConsentPayload(ctx, f, "session_access_token", f.SessionAccessToken); err != nil {
+		return nil, false, err
+	}
+
+	// The context may hold any JSON value, but only objects carry data worth encrypting.
+	var payload map[string]interface{}
+	if err := json.Unmarshal(f.Context, &payload); err == nil {
+		if payload, contextChanged, err = p.encryptConsentPayload(ctx, f, "context", payload); err != nil {
+			return nil, false, err
+		} else if contextChanged {
+			if encrypted.Context, err = json.Marshal(payload); err != nil {
+				return nil, false, errorsx.WithStack(err)
+			}
+		}
+	}
+
+	return &encrypted, idTokenChanged || accessTokenChanged || contextChanged, nil
+}
+
+// decryptConsentPayloads decrypts the ID token claims, access token claims and context of the consent session in
+// place. Payloads which were stored before encryption was enabled are left as they are.
+func (p *Persister) decryptConsentPayloads(ctx context.Context, f *flow.Flow) (err error) {
+	if f.SessionIDToken, err = p.decryptConsentPayload(ctx, f, "session_id_token", f.SessionIDToken); err != nil {
+		return err
+	}
+	if f.SessionAccessToken, err = p.decryptConsentPayload(ctx, f, "session_access_token", f.SessionAccessToken); err != nil {
+		return err
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(f.Context, &payload); err != nil {
+		return nil
+	}
+	if _, ok := consentPayloadCiphertext(payload); !ok {
+		return nil
+	}
+	if payload, err = p.decryptConsentPayload(ctx, f, "context", payload); err != nil {
+		return err
+	}
+	f.Context, err = json.Marshal(payload)
+	return errorsx.WithStack(err)
+}
+
+// EncryptConsentPayloads encrypts the ID token claims, access token claims and context of all consent sessions which
+// were stored before session data was encrypted at rest. The consent sessions of all networks are encrypted in
+// batches of batchSize, so it is safe to run while Ory Hydra is serving requests. It returns the number of encrypted
+// consent sessions.
+func (p *Persister) EncryptConsentPayloads(ctx context.Context, batchSize int) (n int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.EncryptConsentPayloads")
+	defer otelx.End(span, &err)
+
+	if !p.config.EncryptSessionData(ctx) {
+		return 0, errors.New("session data is not encrypted at rest, enable oauth2.session.encrypt_at_rest first")
+	}
+
+	var last string
+	for {
+		var fs []flow.Flow
+		if err := p.Connection(ctx).
+			Where("login_challenge > ?", last).
+			Order("login_challenge ASC").
+			Limit(batchSize).
+			All(&fs); err != nil {
+			return n, sqlcon.HandleError(err)
+		}
+
+		for i := range fs {
+			f := &fs[i]
+			last = f.ID
+
+			encrypted, changed, err := p.encryptConsentPayloads(ctx, f)
+			if err != nil {
+				return n, err
+			} else if !changed {
+				continue
+			}
+
+			if err := p.Connection(ctx).RawQuery(
+				"UPDATE hydra_oauth2_flow SET session_id_token = ?, session_access_token = ?, context = ? WHERE login_challenge = ? AND nid = ?",
+				encrypted.SessionIDToken, encrypted.SessionAccessToken, encrypted.Context, f.ID, f.NID,
+			).Exec(); err != nil {
+				return n, sqlcon.HandleError(err)
+			}
+			n++
+		}
+
+		if len(fs) < batchSize {
+			return n, nil
+		}
+	}
+}
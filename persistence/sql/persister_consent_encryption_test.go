@@ -0,0 +1,96 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/sqlxx"
+)
+
+func TestConsentPayloadEncryption(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, new(contextx.Default))
+	p := reg.Persister().(*sql.Persister)
+
+	cl := &client.Client{ID: "encrypted-consent-client"}
+	require.NoError(t, p.CreateClient(ctx, cl))
+
+	newConsentFlow := func(subject string) *flow.Flow {
+		f := newFlow(p.NetworkID(ctx), cl.ID, subject, "")
+		f.ConsentChallengeID = sqlxx.NullString(uuid.Must(uuid.NewV4()).String())
+		f.GrantedScope = sqlxx.StringSliceJSONFormat{"openid"}
+		rememberFor := 0
+		f.ConsentRememberFor = &rememberFor
+		f.SessionIDToken = map[string]interface{}{"email": "alice@example.com"}
+		f.SessionAccessToken = map[string]interface{}{"role": "admin"}
+		f.Context = sqlxx.JSONRawMessage(`{"ssn":"078-05-1120"}`)
+		return f
+	}
+	plaintextRows := func(t *testing.T) int {
+		n, err := p.Connection(ctx).RawQuery(
+			"SELECT * FROM hydra_oauth2_flow WHERE session_id_token LIKE ? OR session_access_token LIKE ? OR context LIKE ?",
+			"%alice@example.com%", "%admin%", "%078-05-1120%",
+		).Count(&flow.Flow{})
+		require.NoError(t, err)
+		return n
+	}
+	assertDecrypted := func(t *testing.T, subject string) {
+		rs, _, err := p.FindSubjectsGrantedConsentRequests(ctx, subject)
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, "alice@example.com", rs[0].SessionIDToken["email"])
+		assert.Equal(t, "admin", rs[0].SessionAccessToken["role"])
+		assert.JSONEq(t, `{"ssn":"078-05-1120"}`, string(rs[0].ConsentRequest.Context))
+	}
+
+	t.Run("case=encrypts new consent sessions", func(t *testing.T) {
+		f := newConsentFlow("encrypted-subject")
+		verifier, err := f.ToConsentVerifier(ctx, reg)
+		require.NoError(t, err)
+
+		r, err := p.VerifyAndInvalidateConsentRequest(ctx, verifier)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", r.SessionIDToken["email"])
+
+		assert.Zero(t, plaintextRows(t))
+		assertDecrypted(t, "encrypted-subject")
+	})
+
+	t.Run("case=encrypts existing consent sessions", func(t *testing.T) {
+		require.NoError(t, p.Connection(ctx).Create(newConsentFlow("plaintext-subject")))
+		assert.Equal(t, 1, plaintextRows(t))
+		assertDecrypted(t, "plaintext-subject")
+
+		n, err := p.EncryptConsentPayloads(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Zero(t, plaintextRows(t))
+		assertDecrypted(t, "plaintext-subject")
+
+		n, err = p.EncryptConsentPayloads(ctx, 1)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("case=reads encrypted consent sessions if encryption is disabled", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyEncryptSessionData, false)
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyEncryptSessionData, true) })
+
+		assertDecrypted(t, "encrypted-subject")
+		_, err := p.EncryptConsentPayloads(ctx, 1)
+		assert.ErrorContains(t, err, "not encrypted at rest")
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+type consentSessionCounts struct {
+	ConsentSessions int `db:"consent_sessions"`
+	AccessTokens    int `db:"access_tokens"`
+	RefreshTokens   int `db:"refresh_tokens"`
+}
+
+// countConsentSessions counts the consent sessions of the network which match the condition on the flow table f, and
+// the access tokens and active refresh tokens issued in them.
+func (p *Persister) countConsentSessions(ctx context.Context, where string, args ...interface{}) (*consentSessionCounts, error) {
+	nid := p.NetworkID(ctx)
+	/* #nosec G201 - where is static */
+	flows := fmt.Sprintf("SELECT f.consent_challenge_id FROM hydra_oauth2_flow AS f WHERE f.nid = ? AND f.consent_challenge_id IS NOT NULL AND %s", where)
+	flowArgs := append([]interface{}{nid}, args...)
+
+	var counts consentSessionCounts
+	if err := p.Connection(ctx).RawQuery(
+		/* #nosec G201 - flows is static */
+		fmt.Sprintf(`
+SELECT
+	(SELECT COUNT(*) FROM (%[1]s) AS c) AS consent_sessions,
+	(SELECT COUNT(*) FROM hydra_oauth2_access WHERE nid = ? AND request_id IN (%[1]s)) AS access_tokens,
+	(SELECT COUNT(*) FROM hydra_oauth2_refresh WHERE nid = ? AND active = ? AND request_id IN (%[1]s)) AS refresh_tokens`, flows),
+		append(append(append(append(append([]interface{}{}, flowArgs...), nid), flowArgs...), nid, true), flowArgs...)...,
+	).First(&counts); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &counts, nil
+}
+
+// DeleteClientDryRun counts the resources which DeleteClient deletes together with the client.
+func (p *Persister) DeleteClientDryRun(ctx context.Context, id string) (_ map[string]int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteClientDryRun")
+	defer otelx.End(span, &err)
+
+	if _, err := p.GetConcreteClient(ctx, id); err != nil {
+		return nil, err
+	}
+
+	nid := p.NetworkID(ctx)
+	var counts consentSessionCounts
+	if err := p.Connection(ctx).RawQuery(`
+SELECT
+	(SELECT COUNT(*) FROM hydra_oauth2_flow WHERE nid = ? AND client_id = ? AND consent_challenge_id IS NOT NULL) AS consent_sessions,
+	(SELECT COUNT(*) FROM hydra_oauth2_access WHERE nid = ? AND client_id = ?) AS access_tokens,
+	(SELECT COUNT(*) FROM hydra_oauth2_refresh WHERE nid = ? AND client_id = ? AND active = ?) AS refresh_tokens`,
+		nid, id,
+		nid, id,
+		nid, id, true,
+	).First(&counts); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return map[string]int{
+		"oauth2_clients":   1,
+		"consent_sessions": counts.ConsentSessions,
+		"access_tokens":    counts.AccessTokens,
+		"refresh_tokens":   counts.RefreshTokens,
+	}, nil
+}
+
+// RevokeSubjectConsentSessionDryRun counts the resources which RevokeSubjectClientConsentSession revokes, or
+// RevokeSubjectConsentSession if client is empty.
+func (p *Persister) RevokeSubjectConsentSessionDryRun(ctx context.Context, subject, client string) (_ map[string]int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSubjectConsentSessionDryRun")
+	defer otelx.End(span, &err)
+
+	where, args := "f.subject = ?", []interface{}{subject}
+	if client != "" {
+		where, args = "f.subject = ? AND f.client_id = ?", []interface{}{subject, client}
+	}
+
+	counts, err := p.countConsentSessions(ctx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]int{
+		"consent_sessions": counts.ConsentSessions,
+		"access_tokens":    counts.AccessTokens,
+		"refresh_tokens":   counts.RefreshTokens,
+	}, nil
+}
+
+// RevokeSubjectLoginSessionDryRun counts the resources which are revoked when the login sessions of the subject are
+// revoked, or the remembered login session sid if it is not empty. This includes the tokens revoked by
+// RevokeLoginSessionTokens.
+func (p *Persister) RevokeSubjectLoginSessionDryRun(ctx context.Context, subject, sid string) (_ map[string]int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSubjectLoginSessionDryRun")
+	defer otelx.End(span, &err)
+
+	sessionWhere, sessionArgs := "subject = ?", []interface{}{subject}
+	tokenWhere, tokenArgs := "f.subject = ? AND f.login_session_id IS NOT NULL", []interface{}{subject}
+	if sid != "" {
+		// Only remembered login sessions are revoked by their ID, see HandleHeadlessLogout.
+		sessionWhere, sessionArgs = "id = ? AND remember = TRUE", []interface{}{sid}
+		tokenWhere, tokenArgs = "f.login_session_id = ?", []interface{}{sid}
+	}
+
+	var loginSessions int
+	if err := p.Connection(ctx).RawQuery(
+		/* #nosec G201 - sessionWhere is static */
+		fmt.Sprintf("SELECT COUNT(*) AS login_sessions FROM hydra_oauth2_authentication_session WHERE nid = ? AND %s", sessionWhere),
+		append([]interface{}{p.NetworkID(ctx)}, sessionArgs...)...,
+	).First(&loginSessions); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	affected := map[string]int{
+		"login_sessions": loginSessions,
+		"access_tokens":  0,
+		"refresh_tokens": 0,
+	}
+	if loginSessions == 0 && sid != "" {
+		return affected, nil
+	}
+
+	counts, err := p.countConsentSessions(ctx,
+		tokenWhere+" AND f.client_id IN (SELECT c.id FROM hydra_client AS c WHERE c.nid = f.nid AND c.revoke_tokens_on_logout = ?)",
+		append(tokenArgs, true)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	affected["access_tokens"] = counts.AccessTokens
+	affected["refresh_tokens"] = counts.RefreshTokens
+	return affected, nil
+}
@@ -0,0 +1,111 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, new(contextx.Default))
+	p := reg.Persister().(*sql.Persister)
+
+	revoking := &client.Client{ID: "dry-run-revoking-client", RevokeTokensOnLogout: true}
+	require.NoError(t, p.CreateClient(ctx, revoking))
+	other := &client.Client{ID: "dry-run-other-client"}
+	require.NoError(t, p.CreateClient(ctx, other))
+
+	require.NoError(t, p.ConfirmLoginSession(ctx, &flow.LoginSession{
+		ID:              "dry-run-sid",
+		Subject:         "alice",
+		AuthenticatedAt: sqlxx.NullTime(time.Now()),
+		Remember:        true,
+	}))
+
+	createConsentSession := func(cl *client.Client, challenge string, refreshToken bool) {
+		f := newFlow(p.NetworkID(ctx), cl.ID, "alice", "dry-run-sid")
+		f.ConsentChallengeID = sqlxx.NullString(challenge)
+		require.NoError(t, p.Connection(ctx).Create(f))
+
+		r := &fosite.Request{ID: challenge, RequestedAt: time.Now().UTC().Round(time.Second), Client: cl, Session: oauth2.NewSession("alice")}
+		require.NoError(t, p.CreateAccessTokenSession(ctx, challenge+"-access", r))
+		if refreshToken {
+			require.NoError(t, p.CreateRefreshTokenSession(ctx, challenge+"-refresh", r))
+		}
+	}
+	createConsentSession(revoking, "dry-run-revoking-challenge", true)
+	createConsentSession(other, "dry-run-other-challenge", false)
+
+	t.Run("case=delete client", func(t *testing.T) {
+		affected, err := p.DeleteClientDryRun(ctx, revoking.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"oauth2_clients": 1, "consent_sessions": 1, "access_tokens": 1, "refresh_tokens": 1}, affected)
+
+		_, err = p.DeleteClientDryRun(ctx, "unknown-client")
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+
+	t.Run("case=revoke consent sessions", func(t *testing.T) {
+		affected, err := p.RevokeSubjectConsentSessionDryRun(ctx, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"consent_sessions": 2, "access_tokens": 2, "refresh_tokens": 1}, affected)
+
+		affected, err = p.RevokeSubjectConsentSessionDryRun(ctx, "alice", other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"consent_sessions": 1, "access_tokens": 1, "refresh_tokens": 0}, affected)
+
+		affected, err = p.RevokeSubjectConsentSessionDryRun(ctx, "bob", "")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"consent_sessions": 0, "access_tokens": 0, "refresh_tokens": 0}, affected)
+	})
+
+	t.Run("case=revoke login sessions", func(t *testing.T) {
+		expected := map[string]int{"login_sessions": 1, "access_tokens": 1, "refresh_tokens": 1}
+
+		affected, err := p.RevokeSubjectLoginSessionDryRun(ctx, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, expected, affected)
+
+		affected, err = p.RevokeSubjectLoginSessionDryRun(ctx, "", "dry-run-sid")
+		require.NoError(t, err)
+		assert.Equal(t, expected, affected)
+
+		affected, err = p.RevokeSubjectLoginSessionDryRun(ctx, "", "unknown-sid")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"login_sessions": 0, "access_tokens": 0, "refresh_tokens": 0}, affected)
+	})
+
+	t.Run("case=dry runs match the operations", func(t *testing.T) {
+		require.NoError(t, p.RevokeSubjectClientConsentSession(ctx, "alice", other.ID))
+		affected, err := p.RevokeSubjectConsentSessionDryRun(ctx, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"consent_sessions": 1, "access_tokens": 1, "refresh_tokens": 1}, affected)
+
+		require.NoError(t, p.RevokeLoginSessionTokens(ctx, "alice", ""))
+		require.NoError(t, p.RevokeSubjectLoginSession(ctx, "alice"))
+		affected, err = p.RevokeSubjectLoginSessionDryRun(ctx, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"login_sessions": 0, "access_tokens": 0, "refresh_tokens": 0}, affected)
+
+		require.NoError(t, p.DeleteClient(ctx, revoking.ID))
+		_, err = p.DeleteClientDryRun(ctx, revoking.ID)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/pop/v6/columns"
+
+	"github.com/ory/x/popx"
+)
+
+// followerRead reads the row of the model's table matching the condition with a follower read on CockroachDB. It
+// returns false if follower reads are disabled, a transaction is open, or the row could not be read, in which case
+// the caller reads the row from the primary database.
+func (p *Persister) followerRead(ctx context.Context, model interface{ TableName() string }, where string, args ...interface{}) bool {
+	if !p.config.DBFollowerReadsEnabled() || p.conn.Dialect.Name() != "cockroach" {
+		return false
+	}
+
+	// CockroachDB only supports AS OF SYSTEM TIME in explicit transactions if the transaction was started with it.
+	fallback := &pop.Connection{TX: &pop.Tx{}}
+	if popx.GetConnection(ctx, fallback).TX != fallback.TX {
+		return false
+	}
+
+	asOf := "follower_read_timestamp()"
+	if staleness := p.config.DBFollowerReadsMaxStaleness(); staleness > 0 {
+		// Bounded staleness reads are only supported for lookups of a single row in an implicit transaction.
+		asOf = fmt.Sprintf("with_max_staleness('%dms')", staleness.Milliseconds())
+	}
+
+	table := model.TableName()
+	/* #nosec G201 table and columns are static */
+	err := p.conn.WithContext(ctx).RawQuery(
+		fmt.Sprintf("SELECT %s FROM %s AS OF SYSTEM TIME %s WHERE %s AND nid = ?",
+			columns.ForStruct(model, table, "").Readable().SelectString(), table, asOf, where),
+		append(args, p.NetworkID(ctx))...,
+	).First(model)
+	if err != nil {
+		p.l.WithError(err).Debug("Unable to read with a follower read, reading from the primary database.")
+		return false
+	}
+	return true
+}
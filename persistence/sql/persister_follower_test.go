@@ -0,0 +1,60 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/contextx"
+)
+
+func TestFollowerReads(t *testing.T) {
+	ctx := context.Background()
+
+	run := func(t *testing.T, reg driver.Registry) {
+		for _, staleness := range []time.Duration{0, 10 * time.Second} {
+			reg.Config().MustSet(ctx, config.KeyDBFollowerReadsEnabled, true)
+			reg.Config().MustSet(ctx, config.KeyDBFollowerReadsMaxStaleness, staleness)
+
+			cl := &client.Client{ID: "follower-client-" + staleness.String()}
+			require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+			r := fosite.NewRequest()
+			r.ID = "follower-request-" + staleness.String()
+			r.Client = cl
+			r.Session = oauth2.NewSession("alice")
+			require.NoError(t, reg.OAuth2Storage().CreateAccessTokenSession(ctx, r.ID, r))
+
+			// The access token was just created, so it is found even if a follower read does not return it yet.
+			got, err := reg.OAuth2Storage().GetAccessTokenSession(ctx, r.ID, oauth2.NewSession(""))
+			require.NoError(t, err, staleness)
+			assert.Equal(t, r.ID, got.GetID())
+
+			_, err = reg.OAuth2Storage().GetAccessTokenSession(ctx, "unknown-signature", oauth2.NewSession(""))
+			assert.ErrorIs(t, err, fosite.ErrNotFound)
+		}
+	}
+
+	t.Run("case=ignored for sqlite", func(t *testing.T) {
+		run(t, internal.NewMockedRegistry(t, new(contextx.Default)))
+	})
+
+	t.Run("case=cockroach", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("requires docker")
+		}
+
+		run(t, internal.NewRegistrySQLFromURL(t, internal.ConnectToCRDB(t), true, &contextx.Default{}))
+	})
+}
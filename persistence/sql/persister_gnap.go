@@ -0,0 +1,86 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/hydra/v2/gnap"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+func (p *Persister) CreateGNAPGrant(ctx context.Context, g *gnap.Grant) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateGNAPGrant")
+	defer otelx.End(span, &err)
+
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, g))
+}
+
+func (p *Persister) GetGNAPGrant(ctx context.Context, id uuid.UUID) (_ *gnap.Grant, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetGNAPGrant")
+	defer otelx.End(span, &err)
+
+	var g gnap.Grant
+	if err := p.QueryWithNetwork(ctx).Where("id = ?", id).First(&g); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &g, nil
+}
+
+func (p *Persister) UpdateGNAPGrant(ctx context.Context, g *gnap.Grant) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateGNAPGrant")
+	defer otelx.End(span, &err)
+
+	count, err := p.UpdateWithNetwork(ctx, g)
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return sqlcon.HandleError(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteGNAPGrant(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteGNAPGrant")
+	defer otelx.End(span, &err)
+
+	/* #nosec G201 table is static */
+	count, err := p.Connection(ctx).RawQuery(
+		fmt.Sprintf("DELETE FROM %s WHERE id = ? AND nid = ?", gnap.Grant{}.TableName()),
+		id, p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) FlushInactiveGNAPGrants(ctx context.Context, notAfter time.Time, _ int, _ int) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FlushInactiveGNAPGrants")
+	defer otelx.End(span, &err)
+
+	deleteUntil := time.Now().UTC()
+	if deleteUntil.After(notAfter) {
+		deleteUntil = notAfter
+	}
+	/* #nosec G201 table is static */
+	n, err := p.Connection(ctx).RawQuery(
+		fmt.Sprintf("DELETE FROM %s WHERE expires_at < ? AND nid = ?", gnap.Grant{}.TableName()),
+		deleteUntil, p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	x.RecordJanitorDeletedRows(ctx, gnap.Grant{}.TableName(), int64(n))
+	return nil
+}
@@ -0,0 +1,35 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/gnap"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestPersister_DeleteGNAPGrant(t *testing.T) {
+	ctx := context.Background()
+	p := internal.NewMockedRegistry(t, new(contextx.Default)).Persister()
+
+	g := &gnap.Grant{
+		ID:        uuid.Must(uuid.NewV4()),
+		ClientID:  "client",
+		Status:    gnap.GrantStatusApproved,
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+	}
+	require.NoError(t, p.CreateGNAPGrant(ctx, g))
+
+	require.NoError(t, p.DeleteGNAPGrant(ctx, g.ID))
+	assert.ErrorIs(t, p.DeleteGNAPGrant(ctx, g.ID), sqlcon.ErrNoRows)
+}
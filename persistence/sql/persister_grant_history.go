@@ -0,0 +1,97 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/stringslice"
+)
+
+func (p *Persister) RecordGrantHistory(ctx context.Context, subject, client string, scopes, audiences []string, at time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RecordGrantHistory")
+	defer otelx.End(span, &err)
+
+	at = at.UTC().Truncate(time.Second)
+	for _, v := range stringslice.Unique(scopes) {
+		if err := p.recordGrantHistoryEntry(ctx, subject, client, consent.GrantHistoryTypeScope, v, at); err != nil {
+			return err
+		}
+	}
+	for _, v := range stringslice.Unique(audiences) {
+		if err := p.recordGrantHistoryEntry(ctx, subject, client, consent.GrantHistoryTypeAudience, v, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordGrantHistoryEntry increments the grant count of an existing entry, or creates it. The ID is derived from the
+// entry's key, so that concurrent inserts of the same entry collide on the primary key.
+func (p *Persister) recordGrantHistoryEntry(ctx context.Context, subject, client, typ, value string, at time.Time) error {
+	nid := p.NetworkID(ctx)
+	id := uuid.NewV5(nid, strings.Join([]string{subject, client, typ, value}, "\x00"))
+
+	increment := func() (int, error) {
+		return p.Connection(ctx).RawQuery(
+			"UPDATE hydra_oauth2_grant_history SET grant_count = grant_count + 1, last_granted_at = ? WHERE id = ? AND nid = ?",
+			at, id, nid,
+		).ExecWithCount()
+	}
+
+	if count, err := increment(); err != nil {
+		return sqlcon.HandleError(err)
+	} else if count > 0 {
+		return nil
+	}
+
+	err := sqlcon.HandleError(p.CreateWithNetwork(ctx, &consent.GrantHistoryEntry{
+		ID:             id,
+		Subject:        subject,
+		ClientID:       client,
+		Type:           typ,
+		Value:          value,
+		GrantCount:     1,
+		FirstGrantedAt: at,
+		LastGrantedAt:  at,
+	}))
+	if errors.Is(err, sqlcon.ErrUniqueViolation) {
+		_, err = increment()
+		return sqlcon.HandleError(err)
+	}
+	return err
+}
+
+func (p *Persister) ListGrantHistory(ctx context.Context, subject, client string, pageOpts ...keysetpagination.Option) (_ []consent.GrantHistoryEntry, _ *keysetpagination.Paginator, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListGrantHistory")
+	defer otelx.End(span, &err)
+
+	paginator := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{}),
+	}, pageOpts...)...)
+
+	query := p.QueryWithNetwork(ctx).
+		Where("subject = ?", subject).
+		Scope(paginateNewestFirst(paginator, "last_granted_at", "id"))
+	if client != "" {
+		query = query.Where("client_id = ?", client)
+	}
+
+	entries := make([]consent.GrantHistoryEntry, 0)
+	if err := query.All(&entries); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	entries, nextPage := keysetpagination.Result(entries, paginator)
+	return entries, nextPage, nil
+}
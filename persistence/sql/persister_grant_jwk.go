@@ -5,6 +5,7 @@ package sql
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -12,9 +13,12 @@ import (
 
 	"github.com/go-jose/go-jose/v3"
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
 
 	"github.com/ory/hydra/v2/oauth2/trust"
+	"github.com/ory/hydra/v2/x"
 	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/stringsx"
 
 	"github.com/ory/x/sqlcon"
@@ -73,29 +77,58 @@ func (p *Persister) DeleteGrant(ctx context.Context, id string) (err error) {
 	})
 }
 
-func (p *Persister) GetGrants(ctx context.Context, limit, offset int, optionalIssuer string) (_ []trust.Grant, err error) {
+// RenewGrant changes when the grant expires. The grant keeps its ID and public key.
+func (p *Persister) RenewGrant(ctx context.Context, id string, expiresAt time.Time) (_ trust.Grant, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RenewGrant")
+	defer otelx.End(span, &err)
+
+	var grant trust.Grant
+	if err := p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		grant, err = p.GetConcreteGrant(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		grant.ExpiresAt = expiresAt
+		/* #nosec G201 table is static */
+		return sqlcon.HandleError(c.RawQuery(
+			fmt.Sprintf("UPDATE %s SET expires_at = ? WHERE id = ? AND nid = ?", trust.SQLData{}.TableName()),
+			expiresAt, id, p.NetworkID(ctx),
+		).Exec())
+	}); err != nil {
+		return trust.Grant{}, err
+	}
+	return grant, nil
+}
+
+func (p *Persister) GetGrants(ctx context.Context, optionalIssuer string, pageOpts ...keysetpagination.Option) (_ []trust.Grant, _ *keysetpagination.Paginator, err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetGrants")
 	defer otelx.End(span, &err)
 
+	paginator := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{"id": uuid.Nil.String()}),
+	}, pageOpts...)...)
+
 	grantsData := make([]trust.SQLData, 0)
 
 	query := p.QueryWithNetwork(ctx).
-		Paginate(offset/limit+1, limit).
-		Order("id")
+		Scope(keysetpagination.Paginate[trust.SQLData](paginator))
 	if optionalIssuer != "" {
 		query = query.Where("issuer = ?", optionalIssuer)
 	}
 
 	if err := query.All(&grantsData); err != nil {
-		return nil, sqlcon.HandleError(err)
+		return nil, nil, sqlcon.HandleError(err)
 	}
 
+	grantsData, nextPage := keysetpagination.Result(grantsData, paginator)
+
 	grants := make([]trust.Grant, 0, len(grantsData))
 	for _, data := range grantsData {
 		grants = append(grants, p.jwtGrantFromSQlData(data))
 	}
 
-	return grants, nil
+	return grants, nextPage, nil
 }
 
 func (p *Persister) CountGrants(ctx context.Context) (n int, err error) {
@@ -239,5 +272,14 @@ func (p *Persister) FlushInactiveGrants(ctx context.Context, notAfter time.Time,
 	if deleteUntil.After(notAfter) {
 		deleteUntil = notAfter
 	}
-	return sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("expires_at < ?", deleteUntil).Delete(&trust.SQLData{}))
+	/* #nosec G201 table is static */
+	n, err := p.Connection(ctx).RawQuery(
+		fmt.Sprintf("DELETE FROM %s WHERE expires_at < ? AND nid = ?", trust.SQLData{}.TableName()),
+		deleteUntil, p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	x.RecordJanitorDeletedRows(ctx, trust.SQLData{}.TableName(), int64(n))
+	return nil
 }
@@ -6,11 +6,14 @@ package sql
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/go-jose/go-jose/v3"
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
 
 	"github.com/ory/x/errorsx"
+	"github.com/ory/x/otelx"
 
 	"github.com/pkg/errors"
 
@@ -20,6 +23,7 @@ import (
 )
 
 var _ jwk.Manager = &Persister{}
+var _ jwk.ParameterizedKeyGenerator = &Persister{}
 
 func (p *Persister) GenerateAndPersistKeySet(ctx context.Context, set, kid, alg, use string) (*jose.JSONWebKeySet, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GenerateAndPersistKey")
@@ -38,6 +42,22 @@ func (p *Persister) GenerateAndPersistKeySet(ctx context.Context, set, kid, alg,
 	return keys, nil
 }
 
+func (p *Persister) GenerateAndPersistKeySetWithParameters(ctx context.Context, set, kid, alg, use string, params jwk.KeyParameters) (*jose.JSONWebKeySet, error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GenerateAndPersistKeySetWithParameters")
+	defer span.End()
+
+	keys, err := jwk.GenerateJWKWithParameters(ctx, alg, kid, use, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.AddKeySet(ctx, set, keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
 func (p *Persister) AddKey(ctx context.Context, set string, key *jose.JSONWebKey) error {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.AddKey")
 	defer span.End()
@@ -52,12 +72,16 @@ func (p *Persister) AddKey(ctx context.Context, set string, key *jose.JSONWebKey
 		return errorsx.WithStack(err)
 	}
 
-	return sqlcon.HandleError(p.CreateWithNetwork(ctx, &jwk.SQLData{
+	if err := p.CreateWithNetwork(ctx, &jwk.SQLData{
 		Set:     set,
 		KID:     key.KeyID,
 		Version: 0,
 		Key:     encrypted,
-	}))
+	}); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	return p.pruneKeySet(ctx, set)
 }
 
 func (p *Persister) AddKeySet(ctx context.Context, set string, keys *jose.JSONWebKeySet) error {
@@ -85,7 +109,7 @@ func (p *Persister) AddKeySet(ctx context.Context, set string, keys *jose.JSONWe
 				return sqlcon.HandleError(err)
 			}
 		}
-		return nil
+		return p.pruneKeySet(ctx, set)
 	})
 }
 
@@ -126,10 +150,11 @@ func (p *Persister) GetKey(ctx context.Context, set, kid string) (*jose.JSONWebK
 	defer span.End()
 
 	var j jwk.SQLData
-	if err := p.QueryWithNetwork(ctx).
-		Where("sid = ? AND kid = ?", set, kid).
-		Order("created_at DESC").
-		First(&j); err != nil {
+	if err := p.read(ctx, func(q *pop.Query) error {
+		return q.Where("sid = ? AND kid = ?", set, kid).
+			Order("created_at DESC").
+			First(&j)
+	}); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -153,17 +178,19 @@ func (p *Persister) GetKeySet(ctx context.Context, set string) (keys *jose.JSONW
 	defer span.End()
 
 	var js []jwk.SQLData
-	if err := p.QueryWithNetwork(ctx).
-		Where("sid = ?", set).
-		Order("created_at DESC").
-		All(&js); err != nil {
+	if err := p.read(ctx, func(q *pop.Query) error {
+		if err := q.Where("sid = ?", set).
+			Order("created_at DESC").
+			All(&js); err != nil {
+			return err
+		} else if len(js) == 0 {
+			return errors.Wrap(x.ErrNotFound, "")
+		}
+		return nil
+	}); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
-	if len(js) == 0 {
-		return nil, errors.Wrap(x.ErrNotFound, "")
-	}
-
 	keys = &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
 	for _, d := range js {
 		key, err := p.r.KeyCipher().Decrypt(ctx, d.Key, nil)
@@ -200,3 +227,66 @@ func (p *Persister) DeleteKeySet(ctx context.Context, set string) error {
 	err := p.QueryWithNetwork(ctx).Where("sid=?", set).Delete(&jwk.SQLData{})
 	return sqlcon.HandleError(err)
 }
+
+// PruneKeySets prunes every key set. It is run by the janitor to prune key sets to which no key was added since the
+// pruning policy was configured.
+func (p *Persister) PruneKeySets(ctx context.Context) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.PruneKeySets")
+	defer otelx.End(span, &err)
+
+	if p.config.JWKSPruningKeepNewest(ctx) < 1 {
+		return nil
+	}
+
+	var sets []string
+	if err := p.Connection(ctx).RawQuery("SELECT DISTINCT sid FROM hydra_jwk WHERE nid = ?", p.NetworkID(ctx)).All(&sets); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for _, set := range sets {
+		if err := p.pruneKeySet(ctx, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneKeySet deletes the keys of the set which are neither among the newest jwks.pruning.keep_newest keys of the
+// set nor younger than jwks.pruning.keep_if_younger.
+func (p *Persister) pruneKeySet(ctx context.Context, set string) error {
+	keepNewest := p.config.JWKSPruningKeepNewest(ctx)
+	if keepNewest < 1 {
+		return nil
+	}
+
+	var js []jwk.SQLData
+	if err := p.QueryWithNetwork(ctx).
+		Select("pk", "created_at").
+		Where("sid = ?", set).
+		Order("created_at DESC").
+		All(&js); err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if len(js) <= keepNewest {
+		return nil
+	}
+
+	keepIfCreatedAfter := time.Now().UTC().Add(-p.config.JWKSPruningKeepIfYounger(ctx))
+	var pruned []uuid.UUID
+	for _, j := range js[keepNewest:] {
+		if j.CreatedAt.Before(keepIfCreatedAfter) {
+			pruned = append(pruned, j.ID)
+		}
+	}
+	if len(pruned) == 0 {
+		return nil
+	}
+
+	if err := p.QueryWithNetwork(ctx).Where("pk IN (?)", pruned).Delete(&jwk.SQLData{}); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	x.RecordJanitorDeletedRows(ctx, jwk.SQLData{}.TableName(), int64(len(pruned)))
+	p.l.WithField("set", set).WithField("pruned", len(pruned)).Info("Pruned old keys from the key set.")
+	return nil
+}
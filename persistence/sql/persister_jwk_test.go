@@ -0,0 +1,97 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/x/contextx"
+)
+
+func TestPersister_PruneKeySets(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, new(contextx.Default))
+	p := reg.Persister()
+
+	// Pruning compares the creation time of keys with the current time, so keys must not be created at the fixed
+	// time of the other tests.
+	pop.SetNowFunc(time.Now)
+	t.Cleanup(func() {
+		pop.SetNowFunc(func() time.Time { return time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC) })
+	})
+
+	// addKeys adds keys to the set which were created the given durations ago.
+	addKeys := func(t *testing.T, set string, ages ...time.Duration) {
+		for _, age := range ages {
+			keys, err := jwk.GenerateJWK(ctx, jose.ES256, age.String(), "sig")
+			require.NoError(t, err)
+			require.NoError(t, p.AddKeySet(ctx, set, keys))
+			require.NoError(t, p.Connection(ctx).
+				RawQuery("UPDATE hydra_jwk SET created_at = ? WHERE sid = ? AND kid = ?", time.Now().UTC().Add(-age), set, age.String()).
+				Exec())
+		}
+	}
+
+	kids := func(t *testing.T, set string) (kids []string) {
+		keys, err := p.GetKeySet(ctx, set)
+		require.NoError(t, err)
+		for _, k := range keys.Keys {
+			kids = append(kids, k.KeyID)
+		}
+		return kids
+	}
+
+	enablePruning := func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyJWKSPruningKeepNewest, 2)
+		reg.Config().MustSet(ctx, config.KeyJWKSPruningKeepIfYounger, "24h")
+		t.Cleanup(func() {
+			reg.Config().MustSet(ctx, config.KeyJWKSPruningKeepNewest, 0)
+			reg.Config().MustSet(ctx, config.KeyJWKSPruningKeepIfYounger, "0s")
+		})
+	}
+
+	t.Run("case=keeps all keys if pruning is disabled", func(t *testing.T) {
+		set := uuid.Must(uuid.NewV4()).String()
+		addKeys(t, set, 120*time.Hour, 96*time.Hour, 72*time.Hour, 2*time.Hour)
+
+		require.NoError(t, p.PruneKeySets(ctx))
+		assert.Len(t, kids(t, set), 4)
+	})
+
+	t.Run("case=prunes the set when a key is added", func(t *testing.T) {
+		set := uuid.Must(uuid.NewV4()).String()
+		addKeys(t, set, 120*time.Hour, 96*time.Hour, 72*time.Hour, 2*time.Hour, time.Hour)
+
+		enablePruning(t)
+		keys, err := jwk.GenerateJWK(ctx, jose.ES256, "new", "sig")
+		require.NoError(t, err)
+		require.NoError(t, p.AddKey(ctx, set, &keys.Keys[0]))
+
+		assert.Equal(t, []string{"new", "1h0m0s", "2h0m0s"}, kids(t, set),
+			"keeps the two newest keys and the key which is younger than a day")
+	})
+
+	t.Run("case=prunes every set in the janitor run", func(t *testing.T) {
+		set, other := uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String()
+		addKeys(t, set, 120*time.Hour, 96*time.Hour, 72*time.Hour)
+		addKeys(t, other, 48*time.Hour)
+
+		enablePruning(t)
+		require.NoError(t, p.PruneKeySets(ctx))
+
+		assert.Equal(t, []string{"72h0m0s", "96h0m0s"}, kids(t, set))
+		assert.Equal(t, []string{"48h0m0s"}, kids(t, other))
+	})
+}
@@ -0,0 +1,146 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+const (
+	// leaseTTL is how long a lease stays valid unless it is renewed. Leases of crashed instances expire after it.
+	leaseTTL = time.Minute
+	// leaseRenewalInterval is how often a held lease is renewed.
+	leaseRenewalInterval = leaseTTL / 3
+)
+
+// TryLock attempts to acquire the advisory lock with the given name without blocking. If the lock was acquired, the
+// returned function must be called to release it again.
+//
+// On PostgreSQL and MySQL, advisory locks are used. They are bound to a database session, which is why a dedicated
+// connection is taken from the pool and held until the lock is released. Other databases use a lease stored in the
+// database instead.
+func (p *Persister) TryLock(ctx context.Context, name string) (_ func(), acquired bool, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.TryLock")
+	defer otelx.End(span, &err)
+
+	var lock, unlock string
+	var arg interface{}
+	switch p.conn.Dialect.Name() {
+	case "postgres":
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(name))
+		arg = int64(h.Sum64())
+		lock, unlock = "SELECT pg_try_advisory_lock($1)", "SELECT pg_advisory_unlock($1)"
+	case "mysql":
+		arg = name
+		lock, unlock = "SELECT COALESCE(GET_LOCK(?, 0), 0) = 1", "SELECT RELEASE_LOCK(?)"
+	default:
+		// CockroachDB does not implement advisory locks, and SQLite is not necessarily used by a single process only.
+		return p.tryLease(ctx, name)
+	}
+
+	db, ok := p.conn.Store.(interface {
+		Conn(ctx context.Context) (*sql.Conn, error)
+	})
+	if !ok {
+		return nil, false, errors.Errorf("the database store of type %T does not support dedicated connections", p.conn.Store)
+	}
+
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return nil, false, errorsx.WithStack(err)
+	}
+
+	if err := c.QueryRowContext(ctx, lock, arg).Scan(&acquired); err != nil {
+		_ = c.Close()
+		return nil, false, errorsx.WithStack(err)
+	}
+
+	if !acquired {
+		_ = c.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		var released sql.NullBool
+		if err := c.QueryRowContext(context.WithoutCancel(ctx), unlock, arg).Scan(&released); err != nil {
+			p.l.WithError(err).WithField("lock", name).Warn("Unable to release advisory lock, discarding the database connection.")
+			// Discarding the connection ends the session which releases the lock.
+			_ = c.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		_ = c.Close()
+	}, true, nil
+}
+
+// tryLease attempts to acquire the lease with the given name, which is stored in the database so that it is shared by
+// all instances. The lease is renewed while it is held, and can be taken over once it expired.
+func (p *Persister) tryLease(ctx context.Context, name string) (func(), bool, error) {
+	holder := uuid.Must(uuid.NewV4()).String()
+	now := time.Now().UTC()
+
+	err := sqlcon.HandleError(p.Connection(ctx).RawQuery(
+		"INSERT INTO hydra_lease (name, holder, expires_at) VALUES (?, ?, ?)",
+		name, holder, now.Add(leaseTTL),
+	).Exec())
+	if errors.Is(err, sqlcon.ErrUniqueViolation) {
+		count, err := p.Connection(ctx).RawQuery(
+			"UPDATE hydra_lease SET holder = ?, expires_at = ? WHERE name = ? AND expires_at < ?",
+			holder, now.Add(leaseTTL), name, now,
+		).ExecWithCount()
+		if err != nil {
+			return nil, false, sqlcon.HandleError(err)
+		} else if count == 0 {
+			return nil, false, nil
+		}
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(leaseRenewalInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				count, err := p.Connection(ctx).RawQuery(
+					"UPDATE hydra_lease SET expires_at = ? WHERE name = ? AND holder = ?",
+					time.Now().UTC().Add(leaseTTL), name, holder,
+				).ExecWithCount()
+				if err != nil {
+					p.l.WithError(err).WithField("lock", name).Warn("Unable to renew the lease.")
+				} else if count == 0 {
+					p.l.WithField("lock", name).Warn("The lease was lost because it expired before it could be renewed.")
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if err := p.Connection(ctx).RawQuery(
+			"DELETE FROM hydra_lease WHERE name = ? AND holder = ?", name, holder,
+		).Exec(); err != nil {
+			p.l.WithError(err).WithField("lock", name).Warn("Unable to release the lease, it is released once it expires.")
+		}
+	}, true, nil
+}
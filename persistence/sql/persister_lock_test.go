@@ -0,0 +1,64 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/x/contextx"
+)
+
+func TestPersister_TryLock(t *testing.T) {
+	ctx := context.Background()
+	p := internal.NewMockedRegistry(t, new(contextx.Default)).Persister()
+
+	unlock, acquired, err := p.TryLock(ctx, "test-lock")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	_, acquired, err = p.TryLock(ctx, "test-lock")
+	require.NoError(t, err)
+	assert.False(t, acquired, "lock must not be acquired twice")
+
+	otherUnlock, acquired, err := p.TryLock(ctx, "other-lock")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	otherUnlock()
+
+	unlock()
+
+	unlock, acquired, err = p.TryLock(ctx, "test-lock")
+	require.NoError(t, err)
+	assert.True(t, acquired, "lock must be acquirable after release")
+	unlock()
+}
+
+func TestPersister_TryLockExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	p := internal.NewMockedRegistry(t, new(contextx.Default)).Persister()
+
+	crashed, acquired, err := p.TryLock(ctx, "expiring-lock")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// The instance holding the lease crashed and did not renew it.
+	require.NoError(t, p.Connection(ctx).RawQuery("UPDATE hydra_lease SET expires_at = ? WHERE name = ?", time.Now().UTC().Add(-time.Hour), "expiring-lock").Exec())
+
+	unlock, acquired, err := p.TryLock(ctx, "expiring-lock")
+	require.NoError(t, err)
+	require.True(t, acquired, "an expired lease must be taken over")
+
+	crashed()
+	_, acquired, err = p.TryLock(ctx, "expiring-lock")
+	require.NoError(t, err)
+	assert.False(t, acquired, "the previous holder must not release the lease of the new holder")
+
+	unlock()
+}
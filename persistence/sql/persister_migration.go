@@ -59,6 +59,77 @@ func (p *Persister) MigrateUpTo(ctx context.Context, steps int) (int, error) {
 	return n, errorsx.WithStack(err)
 }
 
+// ContractMigrationSuffix marks migrations which are not backwards compatible with the previous release, for
+// example because they drop or rename columns. All other migrations are expand migrations which only add to the
+// schema and may therefore be applied while older versions of Hydra are still running.
+const ContractMigrationSuffix = "_contract"
+
+// contractMigrationNames and contractMigrationVersions list the migrations which predate ContractMigrationSuffix but
+// are not backwards compatible either, because they drop, rename or retype columns or rebuild tables. Migrations which
+// consist of several steps are listed by name, all others by version.
+var (
+	contractMigrationNames = map[string]struct{}{
+		"drop_uq_oauth2":                      {},
+		"set_null_time":                       {},
+		"change_client_primary_key":           {},
+		"change_jwk_primary_key":              {},
+		"merge_authentication_request_tables": {},
+		"nid":                                 {},
+		"string_slice_json":                   {},
+		"change_client_pk":                    {},
+	}
+	contractMigrationVersions = map[string]struct{}{
+		"20190100000004000000": {},
+		"20190100000005000000": {},
+		"20190100000008000000": {},
+		"20190100000011000000": {},
+		"20190100000014000000": {},
+		"20190400000009000000": {},
+	}
+)
+
+// IsContractMigration returns true if the migration with the given version and name belongs to the contract phase.
+func IsContractMigration(version, name string) bool {
+	if _, ok := contractMigrationNames[name]; ok {
+		return true
+	}
+	if _, ok := contractMigrationVersions[version]; ok {
+		return true
+	}
+	return strings.HasSuffix(name, ContractMigrationSuffix)
+}
+
+// MigrateUpExpand applies all pending migrations up to, but excluding, the first pending contract migration. This
+// allows rolling upgrades where old and new versions of Hydra run concurrently against the same database. It returns
+// the number of applied migrations.
+func (p *Persister) MigrateUpExpand(ctx context.Context) (int, error) {
+	if err := p.migrateOldMigrationTables(); err != nil {
+		return 0, err
+	}
+
+	status, err := p.mb.Status(ctx)
+	if err != nil {
+		return 0, errorsx.WithStack(err)
+	}
+
+	var steps int
+	for _, s := range status {
+		if s.State != popx.Pending {
+			continue
+		}
+		if IsContractMigration(s.Version, s.Name) {
+			break
+		}
+		steps++
+	}
+
+	if steps == 0 {
+		return 0, nil
+	}
+
+	return p.MigrateUpTo(ctx, steps)
+}
+
 func (p *Persister) PrepareMigration(_ context.Context) error {
 	return p.migrateOldMigrationTables()
 }
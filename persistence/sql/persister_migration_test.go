@@ -0,0 +1,93 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/dbal"
+	"github.com/ory/x/logrusx"
+)
+
+func TestIsContractMigration(t *testing.T) {
+	assert.True(t, sql.IsContractMigration("20250101000000000000", "drop_legacy_columns_contract"))
+	assert.False(t, sql.IsContractMigration("20250101000000000000", "add_kratos_session_id"))
+	assert.False(t, sql.IsContractMigration("20250101000000000000", "contract_add_column"))
+
+	// Destructive migrations which predate the contract suffix.
+	assert.True(t, sql.IsContractMigration("20230908104443000000", "change_client_pk"))
+	assert.True(t, sql.IsContractMigration("20220210000001000007", "nid"))
+	assert.True(t, sql.IsContractMigration("20190100000005000000", "client"))
+	assert.False(t, sql.IsContractMigration("20190100000001000000", "client"))
+}
+
+func TestPersister_MigrateUpExpand(t *testing.T) {
+	ctx := context.Background()
+	p := internal.NewRegistrySQLFromURL(t, dbal.NewSQLiteTestDatabase(t), true, &contextx.Default{}).Persister()
+
+	require.NoError(t, p.MigrateDown(ctx, 2))
+
+	status, err := p.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.True(t, status.HasPending())
+
+	n, err := p.MigrateUpExpand(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "all pending migrations are expand migrations and must be applied")
+
+	status, err = p.MigrationStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.HasPending())
+
+	n, err = p.MigrateUpExpand(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestPersister_MigrateUpExpandStopsAtContractMigrations(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyDSN, "sqlite://"+filepath.Join(t.TempDir(), "hydra.sqlite")+"?_fk=true")
+	reg, err := driver.NewRegistryFromDSN(ctx, conf, logrusx.New("", ""), true, false, &contextx.Default{})
+	require.NoError(t, err)
+	p := reg.Persister()
+
+	// On an empty database, every expand run must stop right before the next pending contract migration, which is
+	// then applied by a regular migration run.
+	for contracts := 0; ; contracts++ {
+		_, err := p.MigrateUpExpand(ctx)
+		require.NoError(t, err)
+
+		status, err := p.MigrationStatus(ctx)
+		require.NoError(t, err)
+		if !status.HasPending() {
+			assert.Greater(t, contracts, 0)
+			return
+		}
+
+		for k, m := range status {
+			if m.State == "Pending" {
+				require.True(t, sql.IsContractMigration(m.Version, m.Name), "%s_%s must not have been skipped", m.Version, m.Name)
+				for _, later := range status[k:] {
+					assert.Equal(t, "Pending", later.State, "%s_%s must not have been applied", later.Version, later.Name)
+				}
+				break
+			}
+		}
+
+		n, err := p.(*sql.Persister).MigrateUpTo(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/audit"
 	"github.com/ory/hydra/v2/client"
 	"github.com/ory/hydra/v2/consent"
 	"github.com/ory/hydra/v2/driver"
@@ -36,6 +37,7 @@ import (
 	"github.com/ory/x/contextx"
 	"github.com/ory/x/dbal"
 	"github.com/ory/x/networkx"
+	"github.com/ory/x/pagination/keysetpagination"
 	"github.com/ory/x/sqlxx"
 )
 
@@ -812,11 +814,11 @@ func (s *PersisterTestSuite) TestFindSubjectsGrantedConsentRequests() {
 			_, err := r.Persister().HandleConsentRequest(s.t1, f, hcr)
 			require.NoError(t, err)
 
-			actual, err := r.Persister().FindSubjectsGrantedConsentRequests(s.t2, f.Subject, 100, 0)
+			actual, _, err := r.Persister().FindSubjectsGrantedConsentRequests(s.t2, f.Subject)
 			require.Error(t, err)
 			require.Equal(t, 0, len(actual))
 
-			actual, err = r.Persister().FindSubjectsGrantedConsentRequests(s.t1, f.Subject, 100, 0)
+			actual, _, err = r.Persister().FindSubjectsGrantedConsentRequests(s.t1, f.Subject)
 			require.NoError(t, err)
 			require.Equal(t, 1, len(actual))
 		})
@@ -908,6 +910,59 @@ func (s *PersisterTestSuite) TestFlushInactiveLoginConsentRequests() {
 	}
 }
 
+func (s *PersisterTestSuite) TestFlushConsentSessions() {
+	t := s.T()
+	for k, r := range s.registries {
+		t.Run(k, func(t *testing.T) {
+			client := &client.Client{ID: "client-id"}
+			f := newFlow(s.t1NID, client.ID, "sub", "")
+			f.RequestedAt = time.Now().Add(-24 * time.Hour)
+			f.State = flow.FlowStateConsentUsed
+			f.GrantedScope = sqlxx.StringSliceJSONFormat{}
+			crf := 0
+			f.ConsentRememberFor = &crf
+			f.SessionAccessToken = map[string]interface{}{}
+			f.SessionIDToken = map[string]interface{}{}
+			require.NoError(t, r.Persister().CreateClient(s.t1, client))
+			require.NoError(t, r.Persister().Connection(context.Background()).Create(f))
+
+			actual := flow.Flow{}
+
+			require.NoError(t, r.Persister().FlushConsentSessions(s.t1, time.Now().Add(-48*time.Hour), 100, 100))
+			require.NoError(t, r.Persister().Connection(context.Background()).Find(&actual, f.ID))
+			require.NoError(t, r.Persister().FlushConsentSessions(s.t2, time.Now(), 100, 100))
+			require.NoError(t, r.Persister().Connection(context.Background()).Find(&actual, f.ID))
+			require.NoError(t, r.Persister().FlushConsentSessions(s.t1, time.Now(), 100, 100))
+			require.Error(t, r.Persister().Connection(context.Background()).Find(&actual, f.ID))
+		})
+	}
+}
+
+func (s *PersisterTestSuite) TestFlushAuditEvents() {
+	t := s.T()
+	for k, r := range s.registries {
+		t.Run(k, func(t *testing.T) {
+			e := &audit.Event{
+				ID:           uuid.Must(uuid.NewV4()),
+				CreatedAt:    time.Now().Add(-24 * time.Hour).UTC(),
+				Action:       audit.ActionCreate,
+				ResourceType: audit.ResourceOAuth2Client,
+				ResourceID:   "client-id",
+			}
+			require.NoError(t, r.Persister().CreateAuditEvent(s.t1, e))
+
+			actual := audit.Event{}
+
+			require.NoError(t, r.Persister().FlushAuditEvents(s.t1, time.Now().Add(-48*time.Hour), 100, 100))
+			require.NoError(t, r.Persister().Connection(context.Background()).Find(&actual, e.ID))
+			require.NoError(t, r.Persister().FlushAuditEvents(s.t2, time.Now(), 100, 100))
+			require.NoError(t, r.Persister().Connection(context.Background()).Find(&actual, e.ID))
+			require.NoError(t, r.Persister().FlushAuditEvents(s.t1, time.Now(), 100, 100))
+			require.Error(t, r.Persister().Connection(context.Background()).Find(&actual, e.ID))
+		})
+	}
+}
+
 func (s *PersisterTestSuite) TestFlushInactiveRefreshTokens() {
 	t := s.T()
 	for k, r := range s.registries {
@@ -1009,6 +1064,46 @@ func (s *PersisterTestSuite) TestGetClientAssertionJWT() {
 	}
 }
 
+func (s *PersisterTestSuite) TestFindSubjectsGrantedConsentRequestsPagination() {
+	t := s.T()
+	for k, r := range s.registries {
+		t.Run(k, func(t *testing.T) {
+			sessionID := uuid.Must(uuid.NewV4()).String()
+			client := &client.Client{ID: "client-id"}
+			persistLoginSession(s.t1, t, r.Persister(), &flow.LoginSession{ID: sessionID})
+			require.NoError(t, r.Persister().CreateClient(s.t1, client))
+
+			now := time.Now().UTC().Truncate(time.Second)
+			var expected []string
+			for _, requestedAt := range []time.Time{now, now.Add(-time.Minute), now.Add(-time.Minute), now.Add(-2 * time.Minute)} {
+				f := newFlow(s.t1NID, client.ID, "paginated-sub", sqlxx.NullString(sessionID))
+				f.RequestedAt = requestedAt
+				f.ConsentChallengeID = sqlxx.NullString(f.ID)
+				require.NoError(t, r.Persister().Connection(context.Background()).Create(f))
+				expected = append(expected, f.ID)
+			}
+			if expected[1] > expected[2] {
+				expected[1], expected[2] = expected[2], expected[1]
+			}
+
+			var actual []string
+			pageOpts := []keysetpagination.Option{keysetpagination.WithSize(3)}
+			for {
+				page, nextPage, err := r.Persister().FindSubjectsGrantedConsentRequests(s.t1, "paginated-sub", pageOpts...)
+				require.NoError(t, err)
+				for _, c := range page {
+					actual = append(actual, c.ID)
+				}
+				if nextPage.IsLast() {
+					break
+				}
+				pageOpts = nextPage.ToOptions()
+			}
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
 func (s *PersisterTestSuite) TestGetClients() {
 	t := s.T()
 	for k, r := range s.registries {
@@ -1016,10 +1111,10 @@ func (s *PersisterTestSuite) TestGetClients() {
 			c := &client.Client{ID: "client-id"}
 			require.NoError(t, r.Persister().CreateClient(s.t1, c))
 
-			actual, err := r.Persister().GetClients(s.t2, client.Filter{Offset: 0, Limit: 100})
+			actual, _, err := r.Persister().GetClients(s.t2, client.Filter{})
 			require.NoError(t, err)
 			require.Equal(t, 0, len(actual))
-			actual, err = r.Persister().GetClients(s.t1, client.Filter{Offset: 0, Limit: 100})
+			actual, _, err = r.Persister().GetClients(s.t1, client.Filter{})
 			require.NoError(t, err)
 			require.Equal(t, 1, len(actual))
 		})
@@ -1182,11 +1277,11 @@ func (s *PersisterTestSuite) TestGetGrants() {
 			require.NoError(t, r.Persister().AddKeySet(s.t1, "ks-id", ks))
 			require.NoError(t, r.Persister().CreateGrant(s.t1, grant, ks.Keys[0]))
 
-			actual, err := r.Persister().GetGrants(s.t2, 100, 0, "")
+			actual, _, err := r.Persister().GetGrants(s.t2, "")
 			require.NoError(t, err)
 			require.Equal(t, 0, len(actual))
 
-			actual, err = r.Persister().GetGrants(s.t1, 100, 0, "")
+			actual, _, err = r.Persister().GetGrants(s.t1, "")
 			require.NoError(t, err)
 			require.Equal(t, 1, len(actual))
 		})
@@ -17,6 +17,7 @@ import (
 
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
@@ -24,6 +25,7 @@ import (
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/storage"
 	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/x"
 	"github.com/ory/hydra/v2/x/events"
 	"github.com/ory/x/errorsx"
 	"github.com/ory/x/otelx"
@@ -164,6 +166,15 @@ func (p *Persister) ClientAssertionJWTValid(ctx context.Context, jti string) (er
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ClientAssertionJWTValid")
 	defer otelx.End(span, &err)
 
+	if p.hasReplayProtection() {
+		if used, err := p.isJTIUsed(ctx, jti); err != nil {
+			return err
+		} else if used {
+			return errorsx.WithStack(fosite.ErrJTIKnown)
+		}
+		return nil
+	}
+
 	j, err := p.GetClientAssertionJWT(ctx, jti)
 	if errors.Is(err, sqlcon.ErrNoRows) {
 		// the jti is not known => valid
@@ -183,6 +194,10 @@ func (p *Persister) SetClientAssertionJWT(ctx context.Context, jti string, exp t
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.SetClientAssertionJWT")
 	defer otelx.End(span, &err)
 
+	if p.hasReplayProtection() {
+		return p.useJTI(ctx, jti, exp)
+	}
+
 	// delete expired; this cleanup spares us the need for a background worker
 	if err := p.QueryWithNetwork(ctx).Where("expires_at < CURRENT_TIMESTAMP").Delete(&oauth2.BlacklistedJTI{}); err != nil {
 		return sqlcon.HandleError(err)
@@ -199,6 +214,22 @@ func (p *Persister) SetClientAssertionJWT(ctx context.Context, jti string, exp t
 	return nil
 }
 
+// UseOpenIDConnectNonce marks the nonce of an OpenID Connect authentication request of the client as used until exp.
+// The nonces are stored like used jti values, in their own namespace. The client ID is length-prefixed, because both
+// the client ID and the nonce may contain the separator.
+func (p *Persister) UseOpenIDConnectNonce(ctx context.Context, clientID, nonce string, exp time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseOpenIDConnectNonce")
+	defer otelx.End(span, &err)
+
+	key := fmt.Sprintf("oidc-nonce:%d:%s:%s", len(clientID), clientID, nonce)
+	if err := p.SetClientAssertionJWT(ctx, key, exp); errors.Is(err, fosite.ErrJTIKnown) {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The nonce has already been used."))
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (p *Persister) GetClientAssertionJWT(ctx context.Context, j string) (_ *oauth2.BlacklistedJTI, err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetClientAssertionJWT")
 	defer otelx.End(span, &err)
@@ -220,6 +251,14 @@ func (p *Persister) createSession(ctx context.Context, signature string, request
 		return err
 	}
 
+	if p.isEphemeral(table) {
+		return p.createEphemeralSession(ctx, req, requester)
+	}
+
+	if p.batchSession(ctx, req) {
+		return nil
+	}
+
 	if err = sqlcon.HandleError(p.CreateWithNetwork(ctx, req)); errors.Is(err, sqlcon.ErrConcurrentUpdate) {
 		return errors.Wrap(fosite.ErrSerializationFailure, err.Error())
 	} else if err != nil {
@@ -229,8 +268,13 @@ func (p *Persister) createSession(ctx context.Context, signature string, request
 }
 
 func (p *Persister) findSessionBySignature(ctx context.Context, signature string, session fosite.Session, table tableName) (fosite.Requester, error) {
-	r := OAuth2RequestSQL{Table: table}
-	err := p.QueryWithNetwork(ctx).Where("signature = ?", signature).First(&r)
+	r := &OAuth2RequestSQL{Table: table}
+	var err error
+	if p.isEphemeral(table) {
+		r, err = p.findEphemeralSession(ctx, signature, table)
+	} else if err = p.flushTokenBatch(ctx); err == nil {
+		err = p.QueryWithNetwork(ctx).Where("signature = ?", signature).First(r)
+	}
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, errorsx.WithStack(fosite.ErrNotFound)
 	}
@@ -252,6 +296,14 @@ func (p *Persister) findSessionBySignature(ctx context.Context, signature string
 }
 
 func (p *Persister) deleteSessionBySignature(ctx context.Context, signature string, table tableName) error {
+	if p.isEphemeral(table) {
+		return p.deleteEphemeralSession(ctx, signature, table)
+	}
+
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return err
+	}
+
 	err := sqlcon.HandleError(
 		p.QueryWithNetwork(ctx).
 			Where("signature = ?", signature).
@@ -269,6 +321,10 @@ func (p *Persister) deleteSessionByRequestID(ctx context.Context, id string, tab
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.deleteSessionByRequestID")
 	defer otelx.End(span, &err)
 
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return err
+	}
+
 	err = p.QueryWithNetwork(ctx).
 		Where("request_id=?", id).
 		Delete(&OAuth2RequestSQL{Table: table})
@@ -291,6 +347,10 @@ func (p *Persister) deactivateSessionByRequestID(ctx context.Context, id string,
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.deactivateSessionByRequestID")
 	defer otelx.End(span, &err)
 
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return err
+	}
+
 	/* #nosec G201 table is static */
 	return sqlcon.HandleError(
 		p.Connection(ctx).
@@ -313,13 +373,25 @@ func (p *Persister) GetAuthorizeCodeSession(ctx context.Context, signature strin
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetAuthorizeCodeSession")
 	defer otelx.End(span, &err)
 
-	return p.findSessionBySignature(ctx, signature, session, sqlTableCode)
+	request, err = p.findSessionBySignature(ctx, signature, session, sqlTableCode)
+	if errors.Is(err, fosite.ErrInvalidatedAuthorizeCode) {
+		events.Trace(ctx, events.AuthorizationCodeReused, events.WithRequest(request))
+	}
+	return request, err
 }
 
 func (p *Persister) InvalidateAuthorizeCodeSession(ctx context.Context, signature string) (err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.InvalidateAuthorizeCodeSession")
 	defer otelx.End(span, &err)
 
+	if p.isEphemeral(sqlTableCode) {
+		return p.invalidateEphemeralAuthorizeCode(ctx, signature)
+	}
+
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return err
+	}
+
 	/* #nosec G201 table is static */
 	return sqlcon.HandleError(
 		p.Connection(ctx).
@@ -353,8 +425,23 @@ func (p *Persister) GetAccessTokenSession(ctx context.Context, signature string,
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetAccessTokenSession")
 	defer otelx.End(span, &err)
 
+	if r, ok := p.cachedAccessToken(ctx, SignatureHash(signature)); ok {
+		return r.toRequest(ctx, session, p)
+	}
+
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return nil, err
+	}
+
+	// Access tokens are looked up by every introspection request, so they are read from a read replica if one is
+	// configured. Replication lag is acceptable here, unlike for authorization codes and refresh tokens which must not
+	// be used twice. For the same reason they are read with a follower read on CockroachDB if enabled.
 	r := OAuth2RequestSQL{Table: sqlTableAccess}
-	err = p.QueryWithNetwork(ctx).Where("signature = ?", SignatureHash(signature)).First(&r)
+	if !p.followerRead(ctx, &r, "signature = ?", SignatureHash(signature)) {
+		err = p.read(ctx, func(q *pop.Query) error {
+			return q.Where("signature = ?", SignatureHash(signature)).First(&r)
+		})
+	}
 	if errors.Is(err, sql.ErrNoRows) {
 		// Backwards compatibility: we previously did not always hash the
 		// signature before inserting. In case there are still very old (but
@@ -375,13 +462,26 @@ func (p *Persister) GetAccessTokenSession(ctx context.Context, signature string,
 		return fr, errorsx.WithStack(fosite.ErrInactiveToken)
 	}
 
-	return r.toRequest(ctx, session, p)
+	fr, err := r.toRequest(ctx, session, p)
+	if err != nil {
+		return nil, err
+	}
+	p.cacheAccessToken(ctx, SignatureHash(signature), &r, fr)
+	return fr, nil
 }
 
 func (p *Persister) DeleteAccessTokenSession(ctx context.Context, signature string) (err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteAccessTokenSession")
 	defer otelx.End(span, &err)
 
+	if err := p.evictAccessToken(ctx, SignatureHash(signature)); err != nil {
+		return err
+	}
+
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return err
+	}
+
 	err = sqlcon.HandleError(
 		p.QueryWithNetwork(ctx).
 			Where("signature = ?", SignatureHash(signature)).
@@ -415,6 +515,7 @@ func toEventOptions(requester fosite.Requester) []trace.EventOption {
 		events.WithSubject(sub),
 		events.WithRequest(requester),
 		events.WithClientID(requester.GetClient().GetID()),
+		events.WithGrant(requester),
 	}
 }
 
@@ -489,6 +590,10 @@ func (p *Persister) RevokeRefreshTokenMaybeGracePeriod(ctx context.Context, id s
 func (p *Persister) RevokeAccessToken(ctx context.Context, id string) (err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeAccessToken")
 	defer otelx.End(span, &err)
+
+	if err := p.evictAccessTokensByIndex(ctx, "access-by-request", id); err != nil {
+		return err
+	}
 	return p.deleteSessionByRequestID(ctx, id, sqlTableAccess)
 }
 
@@ -500,6 +605,14 @@ func (p *Persister) flushInactiveTokens(ctx context.Context, notAfter time.Time,
 		notAfter = requestMaxExpire
 	}
 
+	// Partitioned token tables are cleaned up by dropping partitions. Only the tokens in the default partition, which
+	// are not covered by any other partition, are deleted row by row.
+	if partitioned, err := p.maintainTokenPartitions(ctx, table, notAfter); err != nil {
+		return err
+	} else if partitioned {
+		table += "_default"
+	}
+
 	totalDeletedCount := 0
 	for deletedRecords := batchSize; totalDeletedCount < limit && deletedRecords == batchSize; {
 		d := batchSize
@@ -516,6 +629,7 @@ func (p *Persister) flushInactiveTokens(ctx context.Context, notAfter time.Time,
 			p.NetworkID(ctx),
 		).ExecWithCount()
 		totalDeletedCount += deletedRecords
+		x.RecordJanitorDeletedRows(ctx, OAuth2RequestSQL{Table: table}.TableName(), int64(deletedRecords))
 
 		if err != nil {
 			break
@@ -541,6 +655,14 @@ func (p *Persister) FlushInactiveRefreshTokens(ctx context.Context, notAfter tim
 func (p *Persister) DeleteAccessTokens(ctx context.Context, clientID string) (err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteAccessTokens")
 	defer otelx.End(span, &err)
+
+	if err := p.evictAccessTokensByIndex(ctx, "access-by-client", clientID); err != nil {
+		return err
+	}
+
+	if err := p.flushTokenBatch(ctx); err != nil {
+		return err
+	}
 	/* #nosec G201 table is static */
 	return sqlcon.HandleError(
 		p.QueryWithNetwork(ctx).Where("client_id=?", clientID).Delete(&OAuth2RequestSQL{Table: sqlTableAccess}),
@@ -0,0 +1,171 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// tokenBatch buffers the token rows created in a transaction. The rows are written together when the transaction is
+// committed, or before any other statement reads or modifies the token tables in the transaction.
+type tokenBatch struct {
+	sync.Mutex
+	rows []*OAuth2RequestSQL
+}
+
+type tokenBatchKey struct{}
+
+// oauth2RequestColumns are the columns of the token tables, in the order of OAuth2RequestSQL.values.
+var oauth2RequestColumns = []string{
+	"signature",
+	"nid",
+	"request_id",
+	"challenge_id",
+	"requested_at",
+	"client_id",
+	"scope",
+	"granted_scope",
+	"requested_audience",
+	"granted_audience",
+	"form_data",
+	"subject",
+	"active",
+	"session_data",
+}
+
+func (r *OAuth2RequestSQL) values() []interface{} {
+	return []interface{}{
+		r.ID,
+		r.NID,
+		r.Request,
+		r.ConsentChallenge,
+		r.RequestedAt,
+		r.Client,
+		r.Scopes,
+		r.GrantedScope,
+		r.RequestedAudience,
+		r.GrantedAudience,
+		r.Form,
+		r.Subject,
+		r.Active,
+		r.Session,
+	}
+}
+
+// withTokenBatch returns a context which buffers the token rows created in the transaction of the context, if batching
+// is enabled.
+func (p *Persister) withTokenBatch(ctx context.Context) context.Context {
+	if !p.config.DBBatchTokenWritesEnabled() {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenBatchKey{}, new(tokenBatch))
+}
+
+// batchSession adds the row to the batch of the context. It returns false if the context has no batch.
+func (p *Persister) batchSession(ctx context.Context, r *OAuth2RequestSQL) bool {
+	b, ok := ctx.Value(tokenBatchKey{}).(*tokenBatch)
+	if !ok {
+		return false
+	}
+
+	r.NID = p.NetworkID(ctx)
+	b.Lock()
+	defer b.Unlock()
+	b.rows = append(b.rows, r)
+	return true
+}
+
+// discardTokenBatch drops the buffered rows of the context, for example because the transaction was rolled back.
+func discardTokenBatch(ctx context.Context) {
+	if b, ok := ctx.Value(tokenBatchKey{}).(*tokenBatch); ok {
+		b.Lock()
+		defer b.Unlock()
+		b.rows = nil
+	}
+}
+
+// flushTokenBatch writes the buffered rows of the context. On PostgreSQL and CockroachDB all rows are written with a
+// single statement, on other databases with one multi-row insert per table.
+func (p *Persister) flushTokenBatch(ctx context.Context) (err error) {
+	b, ok := ctx.Value(tokenBatchKey{}).(*tokenBatch)
+	if !ok {
+		return nil
+	}
+	b.Lock()
+	defer b.Unlock()
+	if len(b.rows) == 0 {
+		return nil
+	}
+
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.flushTokenBatch")
+	defer otelx.End(span, &err)
+
+	var tables []tableName
+	byTable := map[tableName][]*OAuth2RequestSQL{}
+	for _, r := range b.rows {
+		if _, ok := byTable[r.Table]; !ok {
+			tables = append(tables, r.Table)
+		}
+		byTable[r.Table] = append(byTable[r.Table], r)
+	}
+
+	var statements []string
+	var args [][]interface{}
+	for _, t := range tables {
+		stmt, a := insertTokenRows(t, byTable[t])
+		statements = append(statements, stmt)
+		args = append(args, a)
+	}
+
+	switch p.conn.Dialect.Name() {
+	case "postgres", "cockroach":
+		// Data-modifying common table expressions are all executed, even if the main statement does not use them.
+		ctes := make([]string, len(statements))
+		var all []interface{}
+		for i := range statements {
+			ctes[i] = fmt.Sprintf("t%d AS (%s)", i, statements[i])
+			all = append(all, args[i]...)
+		}
+		err = p.Connection(ctx).RawQuery("WITH "+strings.Join(ctes, ", ")+" SELECT 1", all...).Exec()
+	default:
+		for i := range statements {
+			if err = p.Connection(ctx).RawQuery(statements[i], args[i]...).Exec(); err != nil {
+				break
+			}
+		}
+	}
+
+	b.rows = nil
+	if err = sqlcon.HandleError(err); errors.Is(err, sqlcon.ErrConcurrentUpdate) {
+		return errors.Wrap(fosite.ErrSerializationFailure, err.Error())
+	}
+	return err
+}
+
+// insertTokenRows returns a multi-row insert of the rows into the token table.
+func insertTokenRows(table tableName, rows []*OAuth2RequestSQL) (string, []interface{}) {
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(oauth2RequestColumns)), ", ") + ")"
+	values := make([]string, len(rows))
+	args := make([]interface{}, 0, len(rows)*len(oauth2RequestColumns))
+	for i, r := range rows {
+		values[i] = placeholders
+		args = append(args, r.values()...)
+	}
+
+	/* #nosec G201 table is static */
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		OAuth2RequestSQL{Table: table}.TableName(),
+		strings.Join(oauth2RequestColumns, ", "),
+		strings.Join(values, ", "),
+	), args
+}
@@ -0,0 +1,104 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/logrusx"
+)
+
+func TestTokenBatch(t *testing.T) {
+	ctx := context.Background()
+
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyDSN, "sqlite://"+filepath.Join(t.TempDir(), "hydra.sqlite")+"?_fk=true")
+	conf.MustSet(ctx, config.KeyDBBatchTokenWritesEnabled, true)
+	reg, err := driver.NewRegistryFromDSN(ctx, conf, logrusx.New("test_hydra", "master"), false, true, &contextx.Default{})
+	require.NoError(t, err)
+	p := reg.Persister().(*sql.Persister)
+
+	cl := &client.Client{ID: "batch-client"}
+	require.NoError(t, p.CreateClient(ctx, cl))
+	request := func(id string) *fosite.Request {
+		return &fosite.Request{
+			ID:          id,
+			RequestedAt: time.Now().UTC().Round(time.Second),
+			Client:      cl,
+			Session:     oauth2.NewSession("subject"),
+		}
+	}
+	count := func(t *testing.T, table string) (n int) {
+		n, err := p.Connection(ctx).RawQuery("SELECT * FROM " + table).Count(&struct{}{})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("case=writes the tokens on commit", func(t *testing.T) {
+		txCtx, err := p.BeginTX(ctx)
+		require.NoError(t, err)
+		require.NoError(t, p.CreateAccessTokenSession(txCtx, "access-committed", request("committed")))
+		require.NoError(t, p.CreateRefreshTokenSession(txCtx, "refresh-committed", request("committed")))
+		require.NoError(t, p.CreateOpenIDConnectSession(txCtx, "oidc-committed", request("committed")))
+
+		assert.Zero(t, count(t, "hydra_oauth2_access"))
+		assert.Zero(t, count(t, "hydra_oauth2_refresh"))
+		assert.Zero(t, count(t, "hydra_oauth2_oidc"))
+
+		require.NoError(t, p.Commit(txCtx))
+
+		_, err = p.GetAccessTokenSession(ctx, "access-committed", oauth2.NewSession(""))
+		require.NoError(t, err)
+		_, err = p.GetRefreshTokenSession(ctx, "refresh-committed", oauth2.NewSession(""))
+		require.NoError(t, err)
+		_, err = p.GetOpenIDConnectSession(ctx, "oidc-committed", request("committed"))
+		require.NoError(t, err)
+	})
+
+	t.Run("case=reads in the transaction see the tokens", func(t *testing.T) {
+		txCtx, err := p.BeginTX(ctx)
+		require.NoError(t, err)
+		require.NoError(t, p.CreateRefreshTokenSession(txCtx, "refresh-read", request("read")))
+
+		_, err = p.GetRefreshTokenSession(txCtx, "refresh-read", oauth2.NewSession(""))
+		require.NoError(t, err)
+		require.NoError(t, p.RevokeRefreshToken(txCtx, "read"))
+		require.NoError(t, p.Commit(txCtx))
+
+		_, err = p.GetRefreshTokenSession(ctx, "refresh-read", oauth2.NewSession(""))
+		assert.ErrorIs(t, err, fosite.ErrInactiveToken)
+	})
+
+	t.Run("case=discards the tokens on rollback", func(t *testing.T) {
+		txCtx, err := p.BeginTX(ctx)
+		require.NoError(t, err)
+		require.NoError(t, p.CreateAccessTokenSession(txCtx, "access-rolled-back", request("rolled-back")))
+		require.NoError(t, p.Rollback(txCtx))
+
+		_, err = p.GetAccessTokenSession(ctx, "access-rolled-back", oauth2.NewSession(""))
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+	})
+
+	t.Run("case=reports conflicts on commit", func(t *testing.T) {
+		txCtx, err := p.BeginTX(ctx)
+		require.NoError(t, err)
+		require.NoError(t, p.CreateAccessTokenSession(txCtx, "access-committed", request("conflict")))
+		assert.Error(t, p.Commit(txCtx))
+		require.NoError(t, p.Rollback(txCtx))
+	})
+}
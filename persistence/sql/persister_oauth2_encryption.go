@@ -0,0 +1,98 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// tokenSessionTables are the tables whose rows hold the session of an OAuth 2.0 token.
+var tokenSessionTables = []tableName{sqlTableOpenID, sqlTableAccess, sqlTableRefresh, sqlTableCode, sqlTablePKCE}
+
+type tokenSession struct {
+	ID      string    `db:"signature"`
+	NID     uuid.UUID `db:"nid"`
+	Session []byte    `db:"session_data"`
+}
+
+// encryptTokenSession returns the session encrypted with the current system secret, and whether it changed. Sessions
+// stored in plaintext are encrypted, and sessions encrypted with a rotated system secret are encrypted again.
+func (p *Persister) encryptTokenSession(ctx context.Context, session []byte) ([]byte, bool, error) {
+	if len(session) == 0 {
+		return session, false, nil
+	} else if gjson.ValidBytes(session) {
+		ciphertext, err := p.r.KeyCipher().Encrypt(ctx, session, nil)
+		if err != nil {
+			return nil, false, errorsx.WithStack(err)
+		}
+		return []byte(ciphertext), true, nil
+	}
+
+	ciphertext, changed, err := p.r.KeyCipher().Reencrypt(ctx, string(session), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(ciphertext), changed, nil
+}
+
+// EncryptTokenSessions encrypts the sessions of all OAuth 2.0 tokens which were stored before session data was
+// encrypted at rest, or which were encrypted with a rotated system secret, with the current system secret. The tokens
+// of all networks are encrypted in batches of batchSize, so it is safe to run while Ory Hydra is serving requests. It
+// returns the number of encrypted token sessions.
+func (p *Persister) EncryptTokenSessions(ctx context.Context, batchSize int) (n int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.EncryptTokenSessions")
+	defer otelx.End(span, &err)
+
+	if !p.config.EncryptSessionData(ctx) {
+		return 0, errors.New("session data is not encrypted at rest, enable oauth2.session.encrypt_at_rest first")
+	}
+
+	for _, table := range tokenSessionTables {
+		tableName := OAuth2RequestSQL{Table: table}.TableName()
+
+		var last string
+		for {
+			var sessions []tokenSession
+			if err := p.Connection(ctx).RawQuery(
+				fmt.Sprintf("SELECT signature, nid, session_data FROM %s WHERE signature > ? ORDER BY signature ASC LIMIT ?", tableName),
+				last, batchSize,
+			).All(&sessions); err != nil {
+				return n, sqlcon.HandleError(err)
+			}
+
+			for _, s := range sessions {
+				last = s.ID
+
+				session, changed, err := p.encryptTokenSession(ctx, s.Session)
+				if err != nil {
+					return n, errors.WithMessagef(err, "unable to encrypt the session of a token in %s", tableName)
+				} else if !changed {
+					continue
+				}
+
+				if err := p.Connection(ctx).RawQuery(
+					fmt.Sprintf("UPDATE %s SET session_data = ? WHERE signature = ? AND nid = ?", tableName),
+					session, s.ID, s.NID,
+				).Exec(); err != nil {
+					return n, sqlcon.HandleError(err)
+				}
+				n++
+			}
+
+			if len(sessions) < batchSize {
+				break
+			}
+		}
+	}
+	return n, nil
+}
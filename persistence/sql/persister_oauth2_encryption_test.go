@@ -0,0 +1,96 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+)
+
+func TestTokenSessionEncryption(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, new(contextx.Default))
+	p := reg.Persister().(*sql.Persister)
+
+	cl := &client.Client{ID: "encrypted-token-client"}
+	require.NoError(t, p.CreateClient(ctx, cl))
+	request := func(subject string) *fosite.Request {
+		session := oauth2.NewSession(subject)
+		session.Extra = map[string]interface{}{"ssn": "078-05-1120"}
+		return &fosite.Request{
+			ID:          subject,
+			RequestedAt: time.Now().UTC().Round(time.Second),
+			Client:      cl,
+			Session:     session,
+		}
+	}
+	storedSession := func(t *testing.T, table, signature string) []byte {
+		var session []byte
+		require.NoError(t, p.Connection(ctx).RawQuery("SELECT session_data FROM "+table+" WHERE signature = ?", signature).First(&session))
+		return session
+	}
+	assertDecrypted := func(t *testing.T) {
+		r, err := p.GetAccessTokenSession(ctx, "access-signature", oauth2.NewSession(""))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", r.GetSession().GetSubject())
+		r, err = p.GetRefreshTokenSession(ctx, "refresh-signature", oauth2.NewSession(""))
+		require.NoError(t, err)
+		assert.Equal(t, "078-05-1120", r.GetSession().(*oauth2.Session).Extra["ssn"])
+	}
+
+	oldSecret := "an-old-system-secret-of-sufficient-length"
+	reg.Config().MustSet(ctx, config.KeyGetSystemSecret, []string{oldSecret})
+	reg.Config().MustSet(ctx, config.KeyEncryptSessionData, false)
+	require.NoError(t, p.CreateAccessTokenSession(ctx, "access-signature", request("alice")))
+	require.NoError(t, p.CreateRefreshTokenSession(ctx, "refresh-signature", request("alice")))
+	require.True(t, gjson.ValidBytes(storedSession(t, "hydra_oauth2_access", sql.SignatureHash("access-signature"))))
+
+	t.Run("case=requires encryption at rest", func(t *testing.T) {
+		_, err := p.EncryptTokenSessions(ctx, 1)
+		assert.ErrorContains(t, err, "not encrypted at rest")
+	})
+
+	reg.Config().MustSet(ctx, config.KeyEncryptSessionData, true)
+
+	t.Run("case=encrypts existing token sessions", func(t *testing.T) {
+		n, err := p.EncryptTokenSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.False(t, gjson.ValidBytes(storedSession(t, "hydra_oauth2_access", sql.SignatureHash("access-signature"))))
+		assert.False(t, gjson.ValidBytes(storedSession(t, "hydra_oauth2_refresh", "refresh-signature")))
+		assertDecrypted(t)
+
+		n, err = p.EncryptTokenSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("case=encrypts token sessions with the current system secret", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.KeyGetSystemSecret, []string{"a-new-system-secret-of-sufficient-length", oldSecret})
+
+		n, err := p.EncryptTokenSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		reg.Config().MustSet(ctx, config.KeyGetSystemSecret, []string{"a-new-system-secret-of-sufficient-length"})
+		assertDecrypted(t)
+
+		n, err = p.EncryptTokenSessions(ctx, 1)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+}
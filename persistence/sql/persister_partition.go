@@ -0,0 +1,190 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// The range partitions of the token tables are named after the exclusive upper bound of the time the tokens in the
+// partition were requested, for example hydra_oauth2_access_until_20240102000000.
+const tokenPartitionTimeLayout = "20060102150405"
+
+var tokenPartitionName = regexp.MustCompile(`_until_(\d{14})$`)
+
+// partitionedTokenTables are the tables which can be partitioned by the time the tokens were requested.
+var partitionedTokenTables = []tableName{sqlTableAccess, sqlTableRefresh}
+
+type tokenPartition struct {
+	Name  string `db:"relname"`
+	until time.Time
+}
+
+// tokenPartitionStatements returns the statements which convert the table into a partitioned table. The existing
+// table becomes the partition for all tokens requested before until.
+func tokenPartitionStatements(table tableName, until time.Time) []string {
+	t := OAuth2RequestSQL{Table: table}.TableName()
+	legacy := tokenPartitionTableName(table, until)
+
+	stmts := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", t, legacy),
+		fmt.Sprintf("ALTER INDEX %s_pkey RENAME TO %s_pkey", t, legacy),
+	}
+	for _, idx := range []string{"requested_at_idx", "client_id_idx", "challenge_id_idx", "client_id_subject_idx", "request_id_idx"} {
+		stmts = append(stmts, fmt.Sprintf("ALTER INDEX IF EXISTS %s_%s RENAME TO %s_%s", t, idx, legacy, idx))
+	}
+
+	requestedAtIdx := "requested_at, nid"
+	if table == sqlTableRefresh {
+		requestedAtIdx = "nid, requested_at"
+	}
+
+	return append(stmts,
+		fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS) PARTITION BY RANGE (requested_at)", t, legacy),
+		// The partition key must be part of the primary key.
+		fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (signature, requested_at)", t),
+		fmt.Sprintf("CREATE INDEX %s_requested_at_idx ON %s (%s)", t, t, requestedAtIdx),
+		fmt.Sprintf("CREATE INDEX %s_client_id_idx ON %s (client_id, nid)", t, t),
+		fmt.Sprintf("CREATE INDEX %s_challenge_id_idx ON %s (challenge_id)", t, t),
+		fmt.Sprintf("CREATE INDEX %s_client_id_subject_idx ON %s (client_id, subject, nid)", t, t),
+		fmt.Sprintf("CREATE INDEX %s_request_id_idx ON %s (request_id, nid)", t, t),
+		fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s_client_id_fk FOREIGN KEY (client_id, nid) REFERENCES hydra_client(id, nid) ON DELETE CASCADE", t, t),
+		fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s_challenge_id_fk FOREIGN KEY (challenge_id) REFERENCES hydra_oauth2_flow(consent_challenge_id) ON DELETE CASCADE", t, t),
+		fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s_nid_fk_idx FOREIGN KEY (nid) REFERENCES networks(id) ON UPDATE RESTRICT ON DELETE CASCADE", t, t),
+		fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (MINVALUE) TO ('%s')", t, legacy, until.Format(time.DateTime)),
+		fmt.Sprintf("CREATE TABLE %s_default PARTITION OF %s DEFAULT", t, t),
+	)
+}
+
+func tokenPartitionTableName(table tableName, until time.Time) string {
+	return OAuth2RequestSQL{Table: table}.TableName() + "_until_" + until.Format(tokenPartitionTimeLayout)
+}
+
+// PartitionTokenTables converts the access and refresh token tables into tables which are partitioned by the time the
+// tokens were requested. The existing tokens are kept in one partition which is dropped once all of them expired.
+// Only PostgreSQL is supported.
+func (p *Persister) PartitionTokenTables(ctx context.Context) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.PartitionTokenTables")
+	defer otelx.End(span, &err)
+
+	if name := p.conn.Dialect.Name(); name != "postgres" {
+		return errors.Errorf("partitioning the token tables is only supported on PostgreSQL but the database is %s", name)
+	}
+
+	until := time.Now().UTC().Truncate(p.config.DBTokenPartitionsInterval()).Add(p.config.DBTokenPartitionsInterval())
+	if err := p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		for _, table := range partitionedTokenTables {
+			if _, partitioned, err := p.tokenPartitions(ctx, table); err != nil {
+				return err
+			} else if partitioned {
+				return errors.Errorf("table %s is already partitioned", OAuth2RequestSQL{Table: table}.TableName())
+			}
+
+			for _, stmt := range tokenPartitionStatements(table, until) {
+				if err := c.RawQuery(stmt).Exec(); err != nil {
+					return sqlcon.HandleError(err)
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, table := range partitionedTokenTables {
+		if _, err := p.maintainTokenPartitions(ctx, table, time.Time{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenPartitions returns the range partitions of the token table ordered by their upper bound, and whether the
+// table is partitioned at all.
+func (p *Persister) tokenPartitions(ctx context.Context, table tableName) (_ []tokenPartition, partitioned bool, _ error) {
+	if p.conn.Dialect.Name() != "postgres" {
+		return nil, false, nil
+	}
+
+	var rows []tokenPartition
+	if err := p.Connection(ctx).RawQuery(
+		"SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = to_regclass(?)",
+		OAuth2RequestSQL{Table: table}.TableName(),
+	).All(&rows); err != nil {
+		return nil, false, sqlcon.HandleError(err)
+	}
+
+	partitions := make([]tokenPartition, 0, len(rows))
+	for _, r := range rows {
+		m := tokenPartitionName.FindStringSubmatch(r.Name)
+		if m == nil {
+			// The default partition.
+			continue
+		}
+		until, err := time.ParseInLocation(tokenPartitionTimeLayout, m[1], time.UTC)
+		if err != nil {
+			return nil, false, errors.WithStack(err)
+		}
+		partitions = append(partitions, tokenPartition{Name: r.Name, until: until})
+	}
+
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].until.Before(partitions[j].until) })
+	return partitions, len(rows) > 0, nil
+}
+
+// maintainTokenPartitions drops the partitions of the token table which only contain tokens requested before
+// notAfter, and creates the partitions for the configured number of intervals ahead. It returns false if the table
+// is not partitioned.
+//
+// Partitions contain the tokens of all networks.
+func (p *Persister) maintainTokenPartitions(ctx context.Context, table tableName, notAfter time.Time) (bool, error) {
+	partitions, partitioned, err := p.tokenPartitions(ctx, table)
+	if err != nil || !partitioned {
+		return false, err
+	}
+
+	var last time.Time
+	for _, partition := range partitions {
+		last = partition.until
+		if partition.until.After(notAfter) {
+			continue
+		}
+		/* #nosec G201 partition names are generated */
+		if err := p.Connection(ctx).RawQuery(fmt.Sprintf("DROP TABLE IF EXISTS %s", partition.Name)).Exec(); err != nil {
+			return true, sqlcon.HandleError(err)
+		}
+		p.l.WithField("partition", partition.Name).Info("Dropped expired token partition.")
+	}
+
+	interval := p.config.DBTokenPartitionsInterval()
+	now := time.Now().UTC().Truncate(interval)
+	if last.IsZero() {
+		last = now
+	}
+	for until := now.Add(time.Duration(p.config.DBTokenPartitionsPremake()) * interval); last.Before(until); {
+		next := last.Truncate(interval).Add(interval)
+		name := tokenPartitionTableName(table, next)
+		/* #nosec G201 partition names are generated */
+		if err := p.Connection(ctx).RawQuery(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			name, OAuth2RequestSQL{Table: table}.TableName(), last.Format(time.DateTime), next.Format(time.DateTime),
+		)).Exec(); err != nil {
+			return true, sqlcon.HandleError(err)
+		}
+		p.l.WithField("partition", name).Debug("Created token partition.")
+		last = next
+	}
+
+	return true, nil
+}
@@ -0,0 +1,77 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+)
+
+func TestPartitionTokenTables(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=sqlite is not supported", func(t *testing.T) {
+		p := internal.NewMockedRegistry(t, new(contextx.Default)).Persister().(*sql.Persister)
+		assert.ErrorContains(t, p.PartitionTokenTables(ctx), "only supported on PostgreSQL")
+	})
+
+	t.Run("case=postgres", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("requires docker")
+		}
+
+		reg := internal.NewRegistrySQLFromURL(t, internal.ConnectToPG(t), true, &contextx.Default{})
+		p := reg.Persister().(*sql.Persister)
+
+		cl := &client.Client{ID: "partitioned-client"}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+		newRequest := func(id string, requestedAt time.Time) *fosite.Request {
+			r := fosite.NewRequest()
+			r.ID = id
+			r.RequestedAt = requestedAt
+			r.Client = cl
+			r.Session = oauth2.NewSession("alice")
+			return r
+		}
+
+		require.NoError(t, p.CreateAccessTokenSession(ctx, "before-partitioning", newRequest("before", time.Now().UTC().Add(-48*time.Hour))))
+		require.NoError(t, p.CreateRefreshTokenSession(ctx, "before-partitioning", newRequest("before", time.Now().UTC().Add(-48*time.Hour))))
+
+		require.NoError(t, p.PartitionTokenTables(ctx))
+		assert.ErrorContains(t, p.PartitionTokenTables(ctx), "already partitioned")
+
+		for _, table := range []string{"hydra_oauth2_access", "hydra_oauth2_refresh"} {
+			count, err := p.Connection(ctx).RawQuery(
+				"SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = to_regclass(?)", table,
+			).Count(&client.Client{})
+			require.NoError(t, err)
+			// The existing tokens, the default partition and three partitions ahead.
+			assert.Equal(t, 5, count, table)
+		}
+
+		require.NoError(t, p.CreateAccessTokenSession(ctx, "after-partitioning", newRequest("after", time.Now().UTC())))
+		for _, signature := range []string{"before-partitioning", "after-partitioning"} {
+			_, err := p.GetAccessTokenSession(ctx, signature, oauth2.NewSession(""))
+			assert.NoError(t, err, signature)
+		}
+		_, err := p.GetRefreshTokenSession(ctx, "before-partitioning", oauth2.NewSession(""))
+		assert.NoError(t, err)
+
+		require.NoError(t, p.FlushInactiveAccessTokens(ctx, time.Now(), 100, 10))
+		require.NoError(t, p.FlushInactiveRefreshTokens(ctx, time.Now(), 100, 10))
+		_, err = p.GetAccessTokenSession(ctx, "after-partitioning", oauth2.NewSession(""))
+		assert.NoError(t, err)
+	})
+}
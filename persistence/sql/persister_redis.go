@@ -0,0 +1,383 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+)
+
+// accessTokenEvictionTTL is how long an evicted access token can not be cached again. It covers lookups which read
+// the access token from the database before it was deleted, but cache it afterwards, and transactions which delete
+// the access token but are not committed yet.
+const accessTokenEvictionTTL = time.Minute
+
+const (
+	// rememberedConsentCacheTTL is how long remembered consent lookups are cached. Remembered consents are evicted
+	// when consent is granted or revoked, so this only limits how long consent sessions deleted by the janitor or
+	// with their client are cached.
+	rememberedConsentCacheTTL = time.Hour
+
+	// rememberedConsentEvictionTTL is how long remembered consent lookups of an evicted subject can not be cached
+	// again, see accessTokenEvictionTTL.
+	rememberedConsentEvictionTTL = time.Minute
+)
+
+var (
+	// cacheAccessTokenScript caches an access token and adds it to the indices by request and client ID, unless the
+	// access token, request or client were evicted recently. The indices expire with their last access token.
+	//
+	// KEYS: access token, request index, client index, access token eviction, request eviction, client eviction
+	// ARGV: cached request, TTL in milliseconds, signature
+	cacheAccessTokenScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[4], KEYS[5], KEYS[6]) > 0 then
+  return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+for i = 2, 3 do
+  redis.call("SADD", KEYS[i], ARGV[3])
+  if redis.call("PTTL", KEYS[i]) < tonumber(ARGV[2]) then
+    redis.call("PEXPIRE", KEYS[i], ARGV[2])
+  end
+end
+return 1
+`)
+
+	// cacheRememberedConsentScript caches the remembered consent of a subject for a client, unless the remembered
+	// consents of the subject were evicted recently.
+	//
+	// KEYS: remembered consents of the subject, remembered consent eviction of the subject
+	// ARGV: client ID, cached remembered consent, TTL in milliseconds
+	cacheRememberedConsentScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2]) > 0 then
+  return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return 1
+`)
+
+	// invalidateAuthorizeCodeScript marks an authorization code as used without changing its expiry.
+	//
+	// KEYS: authorization code
+	// ARGV: invalidated authorization code
+	invalidateAuthorizeCodeScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+  return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "KEEPTTL")
+return 1
+`)
+)
+
+// redisStore stores short-lived OAuth 2.0 sessions and used one-time values, and caches access tokens and
+// remembered consents in Redis.
+type redisStore struct {
+	c                      redis.UniversalClient
+	ephemeralSessions      bool
+	accessTokenCache       bool
+	rememberedConsentCache bool
+	replayProtection       bool
+}
+
+// WithRedis returns a persister which stores authorization codes, PKCE and OpenID Connect sessions in Redis if
+// ephemeralSessions is set, caches access token lookups in Redis if accessTokenCache is set, caches remembered
+// consent lookups in Redis if rememberedConsentCache is set, and stores used jti values and nonces in Redis if
+// replayProtection is set.
+func (p Persister) WithRedis(c redis.UniversalClient, ephemeralSessions, accessTokenCache, rememberedConsentCache, replayProtection bool) *Persister {
+	if c == nil || !(ephemeralSessions || accessTokenCache || rememberedConsentCache || replayProtection) {
+		p.redis = nil
+	} else {
+		p.redis = &redisStore{
+			c:                      c,
+			ephemeralSessions:      ephemeralSessions,
+			accessTokenCache:       accessTokenCache,
+			rememberedConsentCache: rememberedConsentCache,
+			replayProtection:       replayProtection,
+		}
+	}
+	return &p
+}
+
+// hasReplayProtection returns whether used jti values and nonces are stored in Redis.
+func (p *Persister) hasReplayProtection() bool {
+	return p.redis != nil && p.redis.replayProtection
+}
+
+// isJTIUsed returns whether the jti was used and has not expired yet.
+func (p *Persister) isJTIUsed(ctx context.Context, jti string) (bool, error) {
+	n, err := p.redis.c.Exists(ctx, p.redisKey(ctx, "jti", oauth2.NewBlacklistedJTI(jti, time.Time{}).ID)).Result()
+	if err != nil {
+		return false, errorsx.WithStack(err)
+	}
+	return n > 0, nil
+}
+
+// useJTI marks the jti as used until it expires. It returns fosite.ErrJTIKnown if the jti was already used.
+func (p *Persister) useJTI(ctx context.Context, jti string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if ttl < time.Millisecond {
+		// the jti is expired and would be valid anyway
+		return nil
+	}
+
+	created, err := p.redis.c.SetNX(ctx, p.redisKey(ctx, "jti", oauth2.NewBlacklistedJTI(jti, time.Time{}).ID), 1, ttl).Result()
+	if err != nil {
+		return errorsx.WithStack(err)
+	} else if !created {
+		return errorsx.WithStack(fosite.ErrJTIKnown)
+	}
+	return nil
+}
+
+func (p *Persister) redisKey(ctx context.Context, kind, id string) string {
+	return "hydra:" + p.NetworkID(ctx).String() + ":" + kind + ":" + id
+}
+
+// isEphemeral returns whether the sessions of the table are stored in Redis. The sessions of these tables are only
+// used until the authorization code is exchanged.
+func (p *Persister) isEphemeral(table tableName) bool {
+	return p.redis != nil && p.redis.ephemeralSessions &&
+		(table == sqlTableCode || table == sqlTablePKCE || table == sqlTableOpenID)
+}
+
+func (p *Persister) createEphemeralSession(ctx context.Context, r *OAuth2RequestSQL, requester fosite.Requester) error {
+	ttl := p.config.GetAuthorizeCodeLifespan(ctx)
+	if s := requester.GetSession(); s != nil && !s.GetExpiresAt(fosite.AuthorizeCode).IsZero() {
+		ttl = time.Until(s.GetExpiresAt(fosite.AuthorizeCode))
+	}
+
+	r.NID = p.NetworkID(ctx)
+	value, err := json.Marshal(r)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	created, err := p.redis.c.SetNX(ctx, p.redisKey(ctx, string(r.Table), r.ID), value, ttl).Result()
+	if err != nil {
+		return errorsx.WithStack(err)
+	} else if !created {
+		return errorsx.WithStack(sqlcon.ErrUniqueViolation)
+	}
+	return nil
+}
+
+func (p *Persister) findEphemeralSession(ctx context.Context, signature string, table tableName) (*OAuth2RequestSQL, error) {
+	value, err := p.redis.c.Get(ctx, p.redisKey(ctx, string(table), signature)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errorsx.WithStack(fosite.ErrNotFound)
+	} else if err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+
+	r := OAuth2RequestSQL{Table: table}
+	if err := json.Unmarshal(value, &r); err != nil {
+		return nil, errorsx.WithStack(err)
+	}
+	return &r, nil
+}
+
+func (p *Persister) deleteEphemeralSession(ctx context.Context, signature string, table tableName) error {
+	deleted, err := p.redis.c.Del(ctx, p.redisKey(ctx, string(table), signature)).Result()
+	if err != nil {
+		return errorsx.WithStack(err)
+	} else if deleted == 0 {
+		return errorsx.WithStack(fosite.ErrNotFound)
+	}
+	return nil
+}
+
+func (p *Persister) invalidateEphemeralAuthorizeCode(ctx context.Context, signature string) error {
+	r, err := p.findEphemeralSession(ctx, signature, sqlTableCode)
+	if errors.Is(err, fosite.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	r.Active = false
+	value, err := json.Marshal(r)
+	if err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	return errorsx.WithStack(invalidateAuthorizeCodeScript.Run(ctx, p.redis.c,
+		[]string{p.redisKey(ctx, string(sqlTableCode), signature)},
+		value,
+	).Err())
+}
+
+// cachedAccessToken returns the cached access token. Errors are logged and treated as a cache miss.
+func (p *Persister) cachedAccessToken(ctx context.Context, signature string) (*OAuth2RequestSQL, bool) {
+	if p.redis == nil || !p.redis.accessTokenCache {
+		return nil, false
+	}
+
+	value, err := p.redis.c.Get(ctx, p.redisKey(ctx, string(sqlTableAccess), signature)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	} else if err != nil {
+		p.l.WithError(err).Warn("Unable to look up the access token in the cache.")
+		return nil, false
+	}
+
+	r := OAuth2RequestSQL{Table: sqlTableAccess}
+	if err := json.Unmarshal(value, &r); err != nil {
+		p.l.WithError(err).Warn("Unable to decode the cached access token.")
+		return nil, false
+	}
+	return &r, true
+}
+
+// cacheAccessToken caches an active access token until it expires. Errors are logged.
+func (p *Persister) cacheAccessToken(ctx context.Context, signature string, r *OAuth2RequestSQL, requester fosite.Requester) {
+	if p.redis == nil || !p.redis.accessTokenCache || !r.Active {
+		return
+	}
+
+	expiresAt := r.RequestedAt.Add(p.config.GetAccessTokenLifespan(ctx))
+	if s := requester.GetSession(); s != nil && !s.GetExpiresAt(fosite.AccessToken).IsZero() {
+		expiresAt = s.GetExpiresAt(fosite.AccessToken)
+	}
+	ttl := time.Until(expiresAt)
+	if ttl < time.Millisecond {
+		return
+	}
+
+	value, err := json.Marshal(r)
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to cache the access token.")
+		return
+	}
+
+	if err := cacheAccessTokenScript.Run(ctx, p.redis.c,
+		[]string{
+			p.redisKey(ctx, string(sqlTableAccess), signature),
+			p.redisKey(ctx, "access-by-request", r.Request),
+			p.redisKey(ctx, "access-by-client", r.Client),
+			p.redisKey(ctx, "access-evicted", signature),
+			p.redisKey(ctx, "access-by-request-evicted", r.Request),
+			p.redisKey(ctx, "access-by-client-evicted", r.Client),
+		},
+		value, ttl.Milliseconds(), signature,
+	).Err(); err != nil {
+		p.l.WithError(err).Warn("Unable to cache the access token.")
+	}
+}
+
+// evictAccessToken removes an access token from the cache.
+func (p *Persister) evictAccessToken(ctx context.Context, signature string) error {
+	if p.redis == nil || !p.redis.accessTokenCache {
+		return nil
+	}
+
+	_, err := p.redis.c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, p.redisKey(ctx, "access-evicted", signature), 1, accessTokenEvictionTTL)
+		pipe.Del(ctx, p.redisKey(ctx, string(sqlTableAccess), signature))
+		return nil
+	})
+	return errorsx.WithStack(err)
+}
+
+// evictAccessTokensByIndex removes all access tokens of a request or client from the cache. The index is either
+// "access-by-request" or "access-by-client".
+func (p *Persister) evictAccessTokensByIndex(ctx context.Context, index, id string) error {
+	if p.redis == nil || !p.redis.accessTokenCache {
+		return nil
+	}
+
+	var members *redis.StringSliceCmd
+	if _, err := p.redis.c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, p.redisKey(ctx, index+"-evicted", id), 1, accessTokenEvictionTTL)
+		members = pipe.SMembers(ctx, p.redisKey(ctx, index, id))
+		return nil
+	}); err != nil {
+		return errorsx.WithStack(err)
+	}
+
+	keys := []string{p.redisKey(ctx, index, id)}
+	for _, signature := range members.Val() {
+		keys = append(keys, p.redisKey(ctx, string(sqlTableAccess), signature))
+	}
+	return errorsx.WithStack(p.redis.c.Del(ctx, keys...).Err())
+}
+
+// cachedRememberedConsent returns the cached remembered consent of the subject for the client, which is nil if the
+// subject did not grant and remember consent. The client of the flow is not cached. Errors are logged and treated as
+// a cache miss.
+func (p *Persister) cachedRememberedConsent(ctx context.Context, client, subject string) (_ *flow.Flow, cached bool) {
+	if p.redis == nil || !p.redis.rememberedConsentCache {
+		return nil, false
+	}
+
+	value, err := p.redis.c.HGet(ctx, p.redisKey(ctx, "remembered-consent", subject), client).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	} else if err != nil {
+		p.l.WithError(err).Warn("Unable to look up the remembered consent in the cache.")
+		return nil, false
+	} else if len(value) == 0 {
+		return nil, true
+	}
+
+	var f flow.Flow
+	if err := json.Unmarshal(value, &f); err != nil {
+		p.l.WithError(err).Warn("Unable to decode the cached remembered consent.")
+		return nil, false
+	}
+	return &f, true
+}
+
+// cacheRememberedConsent caches the remembered consent of the subject for the client, or that there is none if f is
+// nil. The consent payloads are cached as stored in the database, so they stay encrypted. Errors are logged.
+func (p *Persister) cacheRememberedConsent(ctx context.Context, client, subject string, f *flow.Flow) {
+	if p.redis == nil || !p.redis.rememberedConsentCache {
+		return
+	}
+
+	var value []byte
+	if f != nil {
+		cached := *f
+		cached.Client = nil
+		var err error
+		if value, err = json.Marshal(cached); err != nil {
+			p.l.WithError(err).Warn("Unable to cache the remembered consent.")
+			return
+		}
+	}
+
+	if err := cacheRememberedConsentScript.Run(ctx, p.redis.c,
+		[]string{
+			p.redisKey(ctx, "remembered-consent", subject),
+			p.redisKey(ctx, "remembered-consent-evicted", subject),
+		},
+		client, value, rememberedConsentCacheTTL.Milliseconds(),
+	).Err(); err != nil {
+		p.l.WithError(err).Warn("Unable to cache the remembered consent.")
+	}
+}
+
+// evictRememberedConsents removes the remembered consents of the subject for all clients from the cache.
+func (p *Persister) evictRememberedConsents(ctx context.Context, subject string) error {
+	if p.redis == nil || !p.redis.rememberedConsentCache {
+		return nil
+	}
+
+	_, err := p.redis.c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, p.redisKey(ctx, "remembered-consent-evicted", subject), 1, rememberedConsentEvictionTTL)
+		pipe.Del(ctx, p.redisKey(ctx, "remembered-consent", subject))
+		return nil
+	})
+	return errorsx.WithStack(err)
+}
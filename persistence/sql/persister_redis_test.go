@@ -0,0 +1,236 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/consent"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/sqlxx"
+)
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	c := internal.NewConfigurationWithDefaults()
+	c.MustSet(ctx, config.KeyDBRedisURL, "redis://"+mr.Addr())
+	reg := internal.NewRegistryMemory(t, c, &contextx.Default{})
+	store := reg.OAuth2Storage()
+
+	cl := &client.Client{ID: "redis-client"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+
+	newRequest := func(id string) *fosite.Request {
+		r := fosite.NewRequest()
+		r.ID = id
+		r.Client = cl
+		r.Session = oauth2.NewSession("alice")
+		r.Session.SetExpiresAt(fosite.AuthorizeCode, time.Now().Add(time.Minute))
+		r.Session.SetExpiresAt(fosite.AccessToken, time.Now().Add(time.Hour))
+		return r
+	}
+	count := func(t *testing.T, table string) int {
+		n, err := reg.Persister().Connection(ctx).RawQuery("SELECT * FROM " + table).Count(&sql.OAuth2RequestSQL{})
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("case=stores authorization codes in redis", func(t *testing.T) {
+		require.NoError(t, store.CreateAuthorizeCodeSession(ctx, "code-signature", newRequest("code-request")))
+		assert.Zero(t, count(t, "hydra_oauth2_code"))
+
+		keys := mr.Keys()
+		require.Len(t, keys, 1)
+		assert.InDelta(t, time.Minute, mr.TTL(keys[0]), float64(5*time.Second))
+
+		r, err := store.GetAuthorizeCodeSession(ctx, "code-signature", oauth2.NewSession(""))
+		require.NoError(t, err)
+		assert.Equal(t, "code-request", r.GetID())
+		assert.Equal(t, "alice", r.GetSession().GetSubject())
+
+		require.NoError(t, store.InvalidateAuthorizeCodeSession(ctx, "code-signature"))
+		_, err = store.GetAuthorizeCodeSession(ctx, "code-signature", oauth2.NewSession(""))
+		assert.ErrorIs(t, err, fosite.ErrInvalidatedAuthorizeCode)
+		assert.InDelta(t, time.Minute, mr.TTL(keys[0]), float64(5*time.Second))
+
+		_, err = store.GetAuthorizeCodeSession(ctx, "unknown-signature", oauth2.NewSession(""))
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+	})
+
+	t.Run("case=stores PKCE and OpenID Connect sessions in redis", func(t *testing.T) {
+		require.NoError(t, store.CreatePKCERequestSession(ctx, "pkce-signature", newRequest("pkce-request")))
+		require.NoError(t, store.CreateOpenIDConnectSession(ctx, "oidc-signature", newRequest("oidc-request")))
+		assert.Zero(t, count(t, "hydra_oauth2_pkce"))
+		assert.Zero(t, count(t, "hydra_oauth2_oidc"))
+
+		_, err := store.GetPKCERequestSession(ctx, "pkce-signature", oauth2.NewSession(""))
+		require.NoError(t, err)
+		_, err = store.GetOpenIDConnectSession(ctx, "oidc-signature", &fosite.Request{Session: &oauth2.Session{DefaultSession: &openid.DefaultSession{}}})
+		require.NoError(t, err)
+
+		require.NoError(t, store.DeletePKCERequestSession(ctx, "pkce-signature"))
+		_, err = store.GetPKCERequestSession(ctx, "pkce-signature", oauth2.NewSession(""))
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+		assert.ErrorIs(t, store.DeletePKCERequestSession(ctx, "pkce-signature"), fosite.ErrNotFound)
+	})
+
+	deleteFromDatabase := func(t *testing.T, signature string) {
+		require.NoError(t, reg.Persister().Connection(ctx).RawQuery("DELETE FROM hydra_oauth2_access WHERE signature = ?", sql.SignatureHash(signature)).Exec())
+	}
+
+	t.Run("case=caches access tokens", func(t *testing.T) {
+		require.NoError(t, store.CreateAccessTokenSession(ctx, "cached-token", newRequest("cached-request")))
+
+		_, err := store.GetAccessTokenSession(ctx, "cached-token", oauth2.NewSession(""))
+		require.NoError(t, err)
+
+		deleteFromDatabase(t, "cached-token")
+		r, err := store.GetAccessTokenSession(ctx, "cached-token", oauth2.NewSession(""))
+		require.NoError(t, err)
+		assert.Equal(t, "cached-request", r.GetID())
+		assert.Equal(t, "alice", r.GetSession().GetSubject())
+	})
+
+	for _, tc := range []struct {
+		name   string
+		delete func(t *testing.T, signature, requestID string) error
+	}{
+		{name: "signature", delete: func(t *testing.T, signature, _ string) error {
+			return store.DeleteAccessTokenSession(ctx, signature)
+		}},
+		{name: "request", delete: func(t *testing.T, _, requestID string) error {
+			return store.RevokeAccessToken(ctx, requestID)
+		}},
+		{name: "client", delete: func(t *testing.T, _, _ string) error {
+			return reg.Persister().DeleteAccessTokens(ctx, cl.GetID())
+		}},
+	} {
+		t.Run("case=evicts access tokens by "+tc.name, func(t *testing.T) {
+			signature, requestID := "evicted-by-"+tc.name, "evicted-request-by-"+tc.name
+			require.NoError(t, store.CreateAccessTokenSession(ctx, signature, newRequest(requestID)))
+			_, err := store.GetAccessTokenSession(ctx, signature, oauth2.NewSession(""))
+			require.NoError(t, err)
+
+			require.NoError(t, tc.delete(t, signature, requestID))
+			_, err = store.GetAccessTokenSession(ctx, signature, oauth2.NewSession(""))
+			assert.ErrorIs(t, err, fosite.ErrNotFound)
+		})
+	}
+
+	t.Run("case=does not cache evicted access tokens", func(t *testing.T) {
+		require.NoError(t, store.CreateAccessTokenSession(ctx, "revoked-token", newRequest("revoked-request")))
+		require.NoError(t, store.RevokeAccessToken(ctx, "revoked-request"))
+
+		// The access token was read from the database before it was revoked.
+		require.NoError(t, store.CreateAccessTokenSession(ctx, "revoked-token", newRequest("revoked-request")))
+		_, err := store.GetAccessTokenSession(ctx, "revoked-token", oauth2.NewSession(""))
+		require.NoError(t, err)
+
+		deleteFromDatabase(t, "revoked-token")
+		_, err = store.GetAccessTokenSession(ctx, "revoked-token", oauth2.NewSession(""))
+		assert.ErrorIs(t, err, fosite.ErrNotFound)
+	})
+
+	newRememberedConsent := func(subject string) *flow.Flow {
+		f := newFlow(reg.Persister().NetworkID(ctx), cl.ID, subject, "")
+		f.ConsentChallengeID = sqlxx.NullString(uuid.Must(uuid.NewV4()).String())
+		f.GrantedScope = sqlxx.StringSliceJSONFormat{"openid"}
+		f.ConsentRemember = true
+		rememberFor := 0
+		f.ConsentRememberFor = &rememberFor
+		return f
+	}
+
+	t.Run("case=caches remembered consents", func(t *testing.T) {
+		f := newRememberedConsent("remembered-subject")
+		verifier, err := f.ToConsentVerifier(ctx, reg)
+		require.NoError(t, err)
+		_, err = reg.ConsentManager().VerifyAndInvalidateConsentRequest(ctx, verifier)
+		require.NoError(t, err)
+
+		// Consent which was just granted is not cached until the eviction expired.
+		mr.FastForward(time.Minute)
+		_, err = reg.ConsentManager().FindGrantedAndRememberedConsentRequests(ctx, cl.ID, "remembered-subject")
+		require.NoError(t, err)
+
+		require.NoError(t, reg.Persister().Connection(ctx).RawQuery("DELETE FROM hydra_oauth2_flow WHERE subject = ?", "remembered-subject").Exec())
+		rs, err := reg.ConsentManager().FindGrantedAndRememberedConsentRequests(ctx, cl.ID, "remembered-subject")
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, []string{"openid"}, []string(rs[0].GrantedScope))
+		assert.Equal(t, cl.ID, rs[0].ConsentRequest.Client.GetID())
+	})
+
+	t.Run("case=evicts remembered consents when consent is granted", func(t *testing.T) {
+		_, err := reg.ConsentManager().FindGrantedAndRememberedConsentRequests(ctx, cl.ID, "granting-subject")
+		require.ErrorIs(t, err, consent.ErrNoPreviousConsentFound)
+
+		verifier, err := newRememberedConsent("granting-subject").ToConsentVerifier(ctx, reg)
+		require.NoError(t, err)
+		_, err = reg.ConsentManager().VerifyAndInvalidateConsentRequest(ctx, verifier)
+		require.NoError(t, err)
+
+		_, err = reg.ConsentManager().FindGrantedAndRememberedConsentRequests(ctx, cl.ID, "granting-subject")
+		assert.NoError(t, err)
+	})
+
+	t.Run("case=evicts remembered consents when consent is revoked", func(t *testing.T) {
+		verifier, err := newRememberedConsent("revoking-subject").ToConsentVerifier(ctx, reg)
+		require.NoError(t, err)
+		_, err = reg.ConsentManager().VerifyAndInvalidateConsentRequest(ctx, verifier)
+		require.NoError(t, err)
+		mr.FastForward(time.Minute)
+		_, err = reg.ConsentManager().FindGrantedAndRememberedConsentRequests(ctx, cl.ID, "revoking-subject")
+		require.NoError(t, err)
+
+		require.NoError(t, reg.ConsentManager().RevokeSubjectClientConsentSession(ctx, "revoking-subject", cl.ID))
+		_, err = reg.ConsentManager().FindGrantedAndRememberedConsentRequests(ctx, cl.ID, "revoking-subject")
+		assert.ErrorIs(t, err, consent.ErrNoPreviousConsentFound)
+	})
+
+	t.Run("case=stores used jti values and nonces in redis", func(t *testing.T) {
+		require.NoError(t, store.ClientAssertionJWTValid(ctx, "redis-jti"))
+		require.NoError(t, store.SetClientAssertionJWT(ctx, "redis-jti", time.Now().Add(time.Minute)))
+		assert.ErrorIs(t, store.ClientAssertionJWTValid(ctx, "redis-jti"), fosite.ErrJTIKnown)
+		assert.ErrorIs(t, store.SetClientAssertionJWT(ctx, "redis-jti", time.Now().Add(time.Minute)), fosite.ErrJTIKnown)
+
+		require.NoError(t, store.UseOpenIDConnectNonce(ctx, cl.ID, "redis-nonce", time.Now().Add(time.Minute)))
+		assert.ErrorIs(t, store.UseOpenIDConnectNonce(ctx, cl.ID, "redis-nonce", time.Now().Add(time.Minute)), fosite.ErrInvalidRequest)
+
+		n, err := reg.Persister().Connection(ctx).RawQuery("SELECT * FROM hydra_oauth2_jti_blacklist").Count(&oauth2.BlacklistedJTI{})
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		mr.FastForward(2 * time.Minute)
+		require.NoError(t, store.ClientAssertionJWTValid(ctx, "redis-jti"))
+		require.NoError(t, store.UseOpenIDConnectNonce(ctx, cl.ID, "redis-nonce", time.Now().Add(time.Minute)))
+	})
+
+	t.Run("case=falls back to the database if redis is unavailable", func(t *testing.T) {
+		require.NoError(t, store.CreateAccessTokenSession(ctx, "uncached-token", newRequest("uncached-request")))
+		mr.SetError("unavailable")
+		t.Cleanup(func() { mr.SetError("") })
+
+		r, err := store.GetAccessTokenSession(ctx, "uncached-token", oauth2.NewSession(""))
+		require.NoError(t, err)
+		assert.Equal(t, "uncached-request", r.GetID())
+	})
+}
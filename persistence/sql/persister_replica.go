@@ -0,0 +1,50 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/ory/x/popx"
+)
+
+// replicaSet distributes reads across the connections to the read replicas.
+type replicaSet struct {
+	conns []*pop.Connection
+	next  atomic.Uint64
+}
+
+func (s *replicaSet) pick() *pop.Connection {
+	return s.conns[s.next.Add(1)%uint64(len(s.conns))]
+}
+
+// WithReadReplicas returns a persister which reads from the given read replicas where replication lag is
+// acceptable.
+func (p Persister) WithReadReplicas(conns ...*pop.Connection) *Persister {
+	if len(conns) == 0 {
+		p.replicas = nil
+	} else {
+		p.replicas = &replicaSet{conns: conns}
+	}
+	return &p
+}
+
+// read runs the query on a read replica unless there is a transaction in the context. If the query fails on the
+// replica, for example because the replica has not replicated the row yet or is unavailable, it is repeated on the
+// primary database.
+func (p *Persister) read(ctx context.Context, query func(q *pop.Query) error) error {
+	fallback := &pop.Connection{TX: &pop.Tx{}}
+	if p.replicas != nil && popx.GetConnection(ctx, fallback).TX == fallback.TX {
+		err := query(p.replicas.pick().WithContext(ctx).Where("nid = ?", p.NetworkID(ctx)))
+		if err == nil {
+			return nil
+		}
+		p.l.WithError(err).Debug("Unable to read from the read replica, reading from the primary database.")
+	}
+
+	return query(p.QueryWithNetwork(ctx))
+}
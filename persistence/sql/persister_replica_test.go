@@ -0,0 +1,68 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/dbal"
+	"github.com/ory/x/networkx"
+	"github.com/ory/x/popx"
+)
+
+func TestReadReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	replicaDSN := dbal.NewSQLiteTestDatabase(t)
+	replica := internal.NewRegistrySQLFromURL(t, replicaDSN, true, &contextx.Default{})
+
+	c := internal.NewConfigurationWithDefaults()
+	c.MustSet(ctx, config.KeyDBReadReplicas, []string{replicaDSN})
+	primary := internal.NewRegistryMemory(t, c, &contextx.Default{})
+
+	nid := uuid.Must(uuid.NewV4())
+	require.NoError(t, primary.Persister().Connection(ctx).Create(&networkx.Network{ID: nid}))
+	require.NoError(t, replica.Persister().Connection(ctx).Create(&networkx.Network{ID: nid}))
+	primary.WithContextualizer(&contextx.Static{NID: nid, C: primary.Config().Source(ctx)})
+	replica.WithContextualizer(&contextx.Static{NID: nid, C: replica.Config().Source(ctx)})
+
+	t.Run("case=reads from the replica", func(t *testing.T) {
+		require.NoError(t, primary.ClientManager().CreateClient(ctx, &client.Client{ID: "replicated", Name: "primary"}))
+		require.NoError(t, replica.ClientManager().CreateClient(ctx, &client.Client{ID: "replicated", Name: "replica"}))
+
+		cl, err := primary.ClientManager().GetConcreteClient(ctx, "replicated")
+		require.NoError(t, err)
+		assert.Equal(t, "replica", cl.Name)
+	})
+
+	t.Run("case=falls back to the primary if the replica lags behind", func(t *testing.T) {
+		require.NoError(t, primary.ClientManager().CreateClient(ctx, &client.Client{ID: "not-replicated", Name: "primary"}))
+
+		cl, err := primary.ClientManager().GetConcreteClient(ctx, "not-replicated")
+		require.NoError(t, err)
+		assert.Equal(t, "primary", cl.Name)
+
+		_, err = primary.ClientManager().GetConcreteClient(ctx, "does-not-exist")
+		assert.Error(t, err)
+	})
+
+	t.Run("case=reads from the primary within transactions", func(t *testing.T) {
+		require.NoError(t, popx.Transaction(ctx, primary.Persister().Connection(ctx), func(ctx context.Context, _ *pop.Connection) error {
+			cl, err := primary.ClientManager().GetConcreteClient(ctx, "replicated")
+			require.NoError(t, err)
+			assert.Equal(t, "primary", cl.Name)
+			return nil
+		}))
+	})
+}
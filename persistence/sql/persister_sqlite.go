@@ -0,0 +1,40 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// Checkpoint transfers the write-ahead log of a SQLite database into the database file and truncates it, which
+// releases the space of rows deleted by the janitor. It is a no-op for other databases and SQLite databases which
+// do not use write-ahead logging.
+func (p *Persister) Checkpoint(ctx context.Context) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.Checkpoint")
+	defer otelx.End(span, &err)
+
+	if p.conn.Dialect.Name() != "sqlite3" {
+		return nil
+	}
+
+	return sqlcon.HandleError(p.Connection(ctx).RawQuery("PRAGMA wal_checkpoint(TRUNCATE)").Exec())
+}
+
+// SnapshotDatabase writes a consistent copy of the SQLite database to the file at path, which must not exist yet.
+// The database remains available for reads and writes while the snapshot is taken.
+func (p *Persister) SnapshotDatabase(ctx context.Context, path string) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.SnapshotDatabase")
+	defer otelx.End(span, &err)
+
+	if p.conn.Dialect.Name() != "sqlite3" {
+		return errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Database snapshots are only supported for SQLite, but the database is %s.", p.conn.Dialect.Name()))
+	}
+
+	return sqlcon.HandleError(p.Connection(ctx).RawQuery("VACUUM INTO ?", path).Exec())
+}
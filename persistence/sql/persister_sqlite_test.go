@@ -0,0 +1,74 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/logrusx"
+)
+
+func TestSQLiteWAL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyDSN, "sqlite://"+filepath.Join(dir, "hydra.sqlite")+"?_fk=true")
+	conf.MustSet(ctx, config.KeyDBSQLiteWAL, true)
+	conf.MustSet(ctx, config.KeyDBSQLiteBusyTimeout, 12*time.Second)
+	reg, err := driver.NewRegistryFromDSN(ctx, conf, logrusx.New("test_hydra", "master"), false, true, &contextx.Default{})
+	require.NoError(t, err)
+	c := reg.Persister().Connection(ctx)
+
+	var journalMode string
+	require.NoError(t, c.RawQuery("PRAGMA journal_mode").First(&journalMode))
+	assert.Equal(t, "wal", journalMode)
+
+	var busyTimeout int
+	require.NoError(t, c.RawQuery("PRAGMA busy_timeout").First(&busyTimeout))
+	assert.Equal(t, 12000, busyTimeout)
+
+	// Concurrent writes wait for each other instead of failing with "database is locked".
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- reg.ClientManager().CreateClient(ctx, &client.Client{ID: "wal-client-" + string(rune('a'+i))})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "hydra.sqlite-wal"))
+	require.NoError(t, err)
+
+	require.NoError(t, reg.Persister().Checkpoint(ctx))
+	info, err := os.Stat(filepath.Join(dir, "hydra.sqlite-wal"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	snapshot := filepath.Join(dir, "snapshot.sqlite")
+	require.NoError(t, reg.BackupManager().SnapshotDatabase(ctx, snapshot))
+	info, err = os.Stat(snapshot)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size())
+}
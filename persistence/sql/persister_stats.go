@@ -0,0 +1,82 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/stats"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ stats.Manager = &Persister{}
+
+// statsCache caches the statistics by network, so that dashboards polling them do not run the count queries on
+// every request.
+type statsCache struct {
+	mu         sync.Mutex
+	statistics map[uuid.UUID]*stats.Statistics
+}
+
+func (p *Persister) GetStatistics(ctx context.Context) (_ *stats.Statistics, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetStatistics")
+	defer otelx.End(span, &err)
+
+	nid := p.NetworkID(ctx)
+	ttl := p.config.StatsCacheTTL(ctx)
+
+	p.stats.mu.Lock()
+	defer p.stats.mu.Unlock()
+
+	// Holding the lock while counting makes concurrent requests wait for the result instead of counting as well.
+	if s, ok := p.stats.statistics[nid]; ok && ttl > 0 && time.Since(s.ComputedAt) < ttl {
+		c := *s
+		return &c, nil
+	}
+
+	now := time.Now().UTC()
+	flowLifespan := p.config.LoginChallengeLifespan(ctx)
+	if consentLifespan := p.config.ConsentChallengeLifespan(ctx); consentLifespan > flowLifespan {
+		flowLifespan = consentLifespan
+	}
+
+	var counts struct {
+		Clients       int `db:"clients"`
+		LoginSessions int `db:"login_sessions"`
+		AccessTokens  int `db:"access_tokens"`
+		Flows         int `db:"flows"`
+	}
+	if err := p.Connection(ctx).RawQuery(`
+SELECT
+	(SELECT COUNT(*) FROM hydra_client WHERE nid = ?) AS clients,
+	(SELECT COUNT(*) FROM hydra_oauth2_authentication_session WHERE nid = ? AND remember = TRUE) AS login_sessions,
+	(SELECT COUNT(*) FROM hydra_oauth2_access WHERE nid = ? AND requested_at >= ?) AS access_tokens,
+	(SELECT COUNT(*) FROM hydra_oauth2_flow WHERE nid = ? AND state IN (?, ?, ?, ?) AND requested_at >= ?) AS flows`,
+		nid,
+		nid,
+		nid, now.Add(-24*time.Hour),
+		nid, flow.FlowStateLoginInitialized, flow.FlowStateLoginUnused, flow.FlowStateConsentInitialized, flow.FlowStateConsentUnused, now.Add(-flowLifespan),
+	).First(&counts); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	s := &stats.Statistics{
+		ActiveClients:       counts.Clients,
+		ActiveLoginSessions: counts.LoginSessions,
+		TokensIssuedLast24h: counts.AccessTokens,
+		PendingFlows:        counts.Flows,
+		ComputedAt:          now.Truncate(time.Second),
+	}
+	if ttl > 0 {
+		c := *s
+		p.stats.statistics[nid] = &c
+	}
+	return s, nil
+}
@@ -0,0 +1,79 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/x/networkx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlcon"
+)
+
+// Tenants are not scoped to a network: each tenant owns the network which holds its data.
+
+func (p *Persister) CreateTenant(ctx context.Context, t *tenant.Tenant) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateTenant")
+	defer otelx.End(span, &err)
+
+	return p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		n := networkx.NewNetwork()
+		if err := c.Create(n); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		t.ID = n.ID
+		return sqlcon.HandleError(c.Create(t))
+	})
+}
+
+func (p *Persister) GetTenant(ctx context.Context, name string) (_ *tenant.Tenant, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetTenant")
+	defer otelx.End(span, &err)
+
+	var t tenant.Tenant
+	if err := p.Connection(ctx).Where("name = ?", name).First(&t); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &t, nil
+}
+
+func (p *Persister) GetTenants(ctx context.Context, pageOpts ...keysetpagination.Option) (_ []tenant.Tenant, _ *keysetpagination.Paginator, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetTenants")
+	defer otelx.End(span, &err)
+
+	paginator := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{"id": ""}),
+	}, pageOpts...)...)
+
+	ts := make([]tenant.Tenant, 0)
+	if err := p.Connection(ctx).
+		Scope(keysetpagination.Paginate[tenant.Tenant](paginator)).
+		All(&ts); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	ts, nextPage := keysetpagination.Result(ts, paginator)
+	return ts, nextPage, nil
+}
+
+// DeleteTenant deletes the network of the tenant. All data of the tenant, including the tenant itself, is removed
+// by the database through cascading deletes.
+func (p *Persister) DeleteTenant(ctx context.Context, name string) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteTenant")
+	defer otelx.End(span, &err)
+
+	return p.transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		var t tenant.Tenant
+		if err := c.Where("name = ?", name).First(&t); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		return sqlcon.HandleError(c.Destroy(&networkx.Network{ID: t.ID}))
+	})
+}
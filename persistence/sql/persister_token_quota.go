@@ -0,0 +1,86 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// tokenQuotaUsage counts the token requests of a client in a quota window.
+type tokenQuotaUsage struct {
+	ID        uuid.UUID `db:"id"`
+	NID       uuid.UUID `db:"nid"`
+	ClientID  string    `db:"client_id"`
+	Period    string    `db:"period"`
+	StartsAt  time.Time `db:"starts_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+	Count     int       `db:"request_count"`
+}
+
+func (tokenQuotaUsage) TableName() string {
+	return "hydra_oauth2_token_quota"
+}
+
+func (p *Persister) IncrementTokenQuotaUsage(ctx context.Context, clientID, window string, start, end time.Time) (_ int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.IncrementTokenQuotaUsage")
+	defer otelx.End(span, &err)
+
+	// The ID is derived from the window, so that concurrent requests which open the same window collide on the
+	// primary key.
+	nid := p.NetworkID(ctx)
+	start = start.UTC()
+	id := uuid.NewV5(nid, strings.Join([]string{clientID, window, start.Format(time.RFC3339)}, "\x00"))
+
+	increment := func() (int, error) {
+		if _, err := p.Connection(ctx).RawQuery(
+			"UPDATE hydra_oauth2_token_quota SET request_count = request_count + 1 WHERE id = ? AND nid = ?",
+			id, nid,
+		).ExecWithCount(); err != nil {
+			return 0, sqlcon.HandleError(err)
+		}
+
+		var u tokenQuotaUsage
+		if err := p.QueryWithNetwork(ctx).Find(&u, id); err != nil {
+			return 0, sqlcon.HandleError(err)
+		}
+		return u.Count, nil
+	}
+
+	if count, err := increment(); err == nil {
+		return count, nil
+	} else if !errors.Is(err, sqlcon.ErrNoRows) {
+		return 0, err
+	}
+
+	// This is the first request of the window, which is a good time to delete the expired windows of the client.
+	if err := p.Connection(ctx).RawQuery(
+		"DELETE FROM hydra_oauth2_token_quota WHERE client_id = ? AND nid = ? AND expires_at < ?",
+		clientID, nid, start,
+	).Exec(); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	err = sqlcon.HandleError(p.CreateWithNetwork(ctx, &tokenQuotaUsage{
+		ID:        id,
+		ClientID:  clientID,
+		Period:    window,
+		StartsAt:  start,
+		ExpiresAt: end.UTC(),
+		Count:     1,
+	}))
+	if errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return increment()
+	} else if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
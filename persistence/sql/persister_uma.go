@@ -0,0 +1,66 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/hydra/v2/uma"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+func (p *Persister) CreateResourceSet(ctx context.Context, rs *uma.ResourceSet) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateResourceSet")
+	defer otelx.End(span, &err)
+
+	return sqlcon.HandleError(p.CreateWithNetwork(ctx, rs))
+}
+
+func (p *Persister) GetResourceSet(ctx context.Context, id uuid.UUID) (_ *uma.ResourceSet, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetResourceSet")
+	defer otelx.End(span, &err)
+
+	var rs uma.ResourceSet
+	if err := p.QueryWithNetwork(ctx).Where("id = ?", id).First(&rs); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &rs, nil
+}
+
+func (p *Persister) GetResourceSets(ctx context.Context, resourceServer, owner string) (_ []uma.ResourceSet, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetResourceSets")
+	defer otelx.End(span, &err)
+
+	rss := make([]uma.ResourceSet, 0)
+	if err := p.QueryWithNetwork(ctx).
+		Where("resource_server = ? AND owner = ?", resourceServer, owner).
+		Order("created_at, id").
+		All(&rss); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return rss, nil
+}
+
+func (p *Persister) UpdateResourceSet(ctx context.Context, rs *uma.ResourceSet) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateResourceSet")
+	defer otelx.End(span, &err)
+
+	count, err := p.UpdateWithNetwork(ctx, rs)
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return sqlcon.HandleError(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteResourceSet(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteResourceSet")
+	defer otelx.End(span, &err)
+
+	return sqlcon.HandleError(p.QueryWithNetwork(ctx).Where("id = ?", id).Delete(&uma.ResourceSet{}))
+}
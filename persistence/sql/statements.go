@@ -0,0 +1,260 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/luna-duclos/instrumentedsql"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/x/logrusx"
+)
+
+// StatementOptions configures the instrumentation of the SQL statements sent to the database.
+type StatementOptions struct {
+	// Comments annotates every statement with the Hydra function which issued it, for example
+	// /*action='persistence/sql.Persister.GetConcreteClient'*/.
+	Comments bool
+
+	// Traceparent additionally annotates every statement with the W3C trace context of the request. Because this
+	// makes every statement unique, it defeats statement caches of the database driver and the database.
+	Traceparent bool
+
+	// SlowThreshold is the duration after which a statement is logged as slow. Slow statements are not logged if it
+	// is zero.
+	SlowThreshold time.Duration
+	Logger        *logrusx.Logger
+
+	// Tracer creates a span for every statement if set.
+	Tracer instrumentedsql.Tracer
+}
+
+var statementDrivers atomic.Int64
+
+// RegisterStatementDriver registers a driver which wraps the driver registered under the given name, for example
+// "pgx", and instruments its statements. It returns the name of the new driver.
+//
+// Drivers can not be unregistered, so every call registers a new driver. It should only be called once per
+// connection.
+func RegisterStatementDriver(base string, opts StatementOptions) (string, error) {
+	db, err := sql.Open(base, "")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	parent := db.Driver()
+	if err := db.Close(); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if opts.Comments {
+		parent = &commentingDriver{parent: parent, traceparent: opts.Traceparent}
+	}
+
+	instrumentation := []instrumentedsql.Opt{
+		instrumentedsql.WithOmitArgs(), // don't risk leaking PII or secrets
+		instrumentedsql.WithOpsExcluded(instrumentedsql.OpSQLRowsNext),
+	}
+	if opts.Tracer != nil {
+		instrumentation = append(instrumentation, instrumentedsql.WithTracer(opts.Tracer))
+	}
+	if opts.SlowThreshold > 0 && opts.Logger != nil {
+		instrumentation = append(instrumentation, instrumentedsql.WithLogger(instrumentedsql.LoggerFunc(opts.logSlowStatement)))
+	}
+
+	name := fmt.Sprintf("hydra-%s-%d", base, statementDrivers.Add(1))
+	sql.Register(name, instrumentedsql.WrapDriver(parent, instrumentation...))
+	sqlx.BindDriver(name, sqlx.BindType(base))
+	return name, nil
+}
+
+// logSlowStatement is called by the instrumented driver after every operation.
+func (opts *StatementOptions) logSlowStatement(ctx context.Context, op string, keyvals ...interface{}) {
+	fields := make(map[string]interface{}, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if k, ok := keyvals[i].(string); ok {
+			fields[k] = keyvals[i+1]
+		}
+	}
+
+	// Operations without a statement, such as connecting or committing a transaction, are not logged.
+	query, ok := fields["query"].(string)
+	if !ok {
+		return
+	}
+	duration, _ := fields["duration"].(time.Duration)
+	if duration < opts.SlowThreshold {
+		return
+	}
+
+	l := opts.Logger.
+		WithField("sql_operation", op).
+		WithField("sql_statement", RedactStatement(query)).
+		WithField("duration", duration)
+	if err, ok := fields["err"].(error); ok && err != nil {
+		l = l.WithError(err)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		l = l.WithField("trace_id", sc.TraceID().String())
+	}
+	l.Warn("Slow SQL statement.")
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^\w$.])-?\d+(?:\.\d+)?`)
+)
+
+// RedactStatement replaces the string and numeric literals of a statement with placeholders. Parameters are never
+// logged, but some statements contain literals, for example the limits of batch deletes.
+func RedactStatement(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	return numericLiteral.ReplaceAllString(query, "${1}?")
+}
+
+const modulePrefix = "github.com/ory/hydra/v2/"
+
+var funcSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// statementAction returns the Hydra function which issued the statement, for example
+// "persistence/sql.Persister.GetConcreteClient", or an empty string if it could not be determined.
+func statementAction() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, modulePrefix) && !strings.Contains(frame.Function, "persistence/sql.(*commenting") {
+			action := funcSuffix.ReplaceAllString(strings.TrimPrefix(frame.Function, modulePrefix), "")
+			return strings.NewReplacer("(*", "", ")", "").Replace(action)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// commentingDriver annotates every statement with the Hydra function which issued it, and optionally with the
+// W3C trace context.
+type commentingDriver struct {
+	parent      driver.Driver
+	traceparent bool
+}
+
+func (d *commentingDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.parent.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &commentingConn{parent: c, traceparent: d.traceparent}, nil
+}
+
+type commentingConn struct {
+	parent      driver.Conn
+	traceparent bool
+}
+
+var (
+	_ driver.Conn               = (*commentingConn)(nil)
+	_ driver.ConnBeginTx        = (*commentingConn)(nil)
+	_ driver.ConnPrepareContext = (*commentingConn)(nil)
+	_ driver.ExecerContext      = (*commentingConn)(nil)
+	_ driver.QueryerContext     = (*commentingConn)(nil)
+	_ driver.Pinger             = (*commentingConn)(nil)
+	_ driver.SessionResetter    = (*commentingConn)(nil)
+	_ driver.Validator          = (*commentingConn)(nil)
+	_ driver.NamedValueChecker  = (*commentingConn)(nil)
+)
+
+func (c *commentingConn) comment(ctx context.Context, query string) string {
+	var values []string
+	if action := statementAction(); action != "" {
+		values = append(values, fmt.Sprintf("action='%s'", action))
+	}
+	if sc := trace.SpanContextFromContext(ctx); c.traceparent && sc.IsValid() {
+		values = append(values, fmt.Sprintf("traceparent='00-%s-%s-%s'", sc.TraceID(), sc.SpanID(), sc.TraceFlags()))
+	}
+	if len(values) == 0 {
+		return query
+	}
+	return "/*" + strings.Join(values, ",") + "*/ " + query
+}
+
+func (c *commentingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.parent.Prepare(c.comment(context.Background(), query))
+}
+
+func (c *commentingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query = c.comment(ctx, query)
+	if pc, ok := c.parent.(driver.ConnPrepareContext); ok {
+		return pc.PrepareContext(ctx, query)
+	}
+	return c.parent.Prepare(query)
+}
+
+func (c *commentingConn) Close() error {
+	return c.parent.Close()
+}
+
+func (c *commentingConn) Begin() (driver.Tx, error) {
+	return c.parent.Begin() //nolint:staticcheck
+}
+
+func (c *commentingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.parent.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	return c.parent.Begin() //nolint:staticcheck
+}
+
+func (c *commentingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if ec, ok := c.parent.(driver.ExecerContext); ok {
+		return ec.ExecContext(ctx, c.comment(ctx, query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *commentingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if qc, ok := c.parent.(driver.QueryerContext); ok {
+		return qc.QueryContext(ctx, c.comment(ctx, query), args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *commentingConn) Ping(ctx context.Context) error {
+	if p, ok := c.parent.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *commentingConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.parent.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *commentingConn) IsValid() bool {
+	if v, ok := c.parent.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *commentingConn) CheckNamedValue(v *driver.NamedValue) error {
+	if nc, ok := c.parent.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,136 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/persistence/sql"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/logrusx"
+)
+
+type recordingDriver struct {
+	sync.Mutex
+	queries []string
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) {
+	return &recordingConn{d: d}, nil
+}
+
+type recordingConn struct {
+	d *recordingDriver
+}
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.d.Lock()
+	defer c.d.Unlock()
+	c.d.queries = append(c.d.queries, query)
+	return driver.RowsAffected(0), nil
+}
+
+var recorder = new(recordingDriver)
+
+func init() {
+	stdsql.Register("hydra-test-recording", recorder)
+}
+
+func TestRedactStatement(t *testing.T) {
+	for _, tc := range []struct{ in, out string }{
+		{in: "SELECT * FROM hydra_client WHERE id = ? AND nid = ?", out: "SELECT * FROM hydra_client WHERE id = ? AND nid = ?"},
+		{in: "SELECT * FROM hydra_client WHERE id = $1 AND nid = $2", out: "SELECT * FROM hydra_client WHERE id = $1 AND nid = $2"},
+		{in: "SELECT id FROM hydra_oauth2_flow WHERE state = 'it''s secret' LIMIT 100", out: "SELECT id FROM hydra_oauth2_flow WHERE state = ? LIMIT ?"},
+		{in: "SELECT t1.id FROM hydra_jwk AS t1 WHERE pk > -1.5", out: "SELECT t1.id FROM hydra_jwk AS t1 WHERE pk > ?"},
+	} {
+		assert.Equal(t, tc.out, sql.RedactStatement(tc.in))
+	}
+}
+
+func TestStatementDriver(t *testing.T) {
+	hook := test.NewLocal(logrus.New())
+	l := logrusx.New("test_hydra", "master", logrusx.WithHook(hook))
+
+	name, err := sql.RegisterStatementDriver("hydra-test-recording", sql.StatementOptions{
+		Comments:      true,
+		Traceparent:   true,
+		SlowThreshold: time.Nanosecond,
+		Logger:        l,
+	})
+	require.NoError(t, err)
+
+	db, err := stdsql.Open(name, "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	_, err = db.ExecContext(ctx, "DELETE FROM hydra_oauth2_flow WHERE state = 'secret' LIMIT 10")
+	require.NoError(t, err)
+
+	recorder.Lock()
+	require.NotEmpty(t, recorder.queries)
+	assert.Equal(t,
+		"/*action='persistence/sql_test.TestStatementDriver',traceparent='00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01'*/ DELETE FROM hydra_oauth2_flow WHERE state = 'secret' LIMIT 10",
+		recorder.queries[len(recorder.queries)-1])
+	recorder.Unlock()
+
+	var slow []*logrus.Entry
+	for _, e := range hook.AllEntries() {
+		if e.Message == "Slow SQL statement." {
+			slow = append(slow, e)
+		}
+	}
+	require.Len(t, slow, 1)
+	assert.Equal(t, logrus.WarnLevel, slow[0].Level)
+	assert.Equal(t, "DELETE FROM hydra_oauth2_flow WHERE state = ? LIMIT ?", slow[0].Data["sql_statement"])
+	assert.Equal(t, sc.TraceID().String(), slow[0].Data["trace_id"])
+	assert.NotContains(t, slow[0].Data, "args")
+}
+
+func TestStatementInstrumentation(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(ctx, config.KeyDBStatementCommentsEnabled, true)
+	conf.MustSet(ctx, config.KeyDBSlowQueryThreshold, "1h")
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+
+	cl := &client.Client{ID: "instrumented-client"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+	got, err := reg.ClientManager().GetConcreteClient(ctx, cl.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, cl.GetID(), got.GetID())
+}
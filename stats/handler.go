@@ -0,0 +1,52 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/x/httprouterx"
+)
+
+const StatisticsPath = "/stats"
+
+type Handler struct {
+	r InternalRegistry
+}
+
+func NewHandler(r InternalRegistry) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
+	admin.GET(StatisticsPath, h.getStatistics)
+}
+
+// swagger:route GET /admin/stats stats getStatistics
+//
+// # Get Statistics
+//
+// This endpoint returns aggregate counts of OAuth 2.0 Clients, login sessions, issued access tokens and pending
+// login and consent flows, for example to populate dashboards. The counts are cached for a configurable duration
+// to keep the load on the database low, so they may lag behind slightly.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: statistics
+//	  default: errorOAuth2
+func (h *Handler) getStatistics(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s, err := h.r.StatsManager().GetStatistics(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, s)
+}
@@ -0,0 +1,86 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package stats_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/flow"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/stats"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/sqlxx"
+)
+
+func TestStatsHandler(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults()
+	reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+	admin := x.NewRouterAdmin(conf.AdminURL)
+	reg.RegisterRoutes(ctx, admin, x.NewRouterPublic())
+	ts := httptest.NewServer(admin)
+	t.Cleanup(ts.Close)
+
+	get := func(t *testing.T) *stats.Statistics {
+		res, err := http.Get(ts.URL + "/admin" + stats.StatisticsPath)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var s stats.Statistics
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&s))
+		return &s
+	}
+
+	cl := &client.Client{ID: "stats-client"}
+	require.NoError(t, reg.ClientManager().CreateClient(ctx, cl))
+	for _, requestedAt := range []time.Time{time.Now().UTC(), time.Now().UTC().Add(-48 * time.Hour)} {
+		r := fosite.NewRequest()
+		r.ID = uuid.New().String()
+		r.RequestedAt = requestedAt
+		r.Client = cl
+		r.Session = oauth2.NewSession("alice")
+		require.NoError(t, reg.OAuth2Storage().CreateAccessTokenSession(ctx, r.ID, r))
+	}
+	for _, remember := range []bool{true, false} {
+		require.NoError(t, reg.ConsentManager().ConfirmLoginSession(ctx, &flow.LoginSession{
+			ID:              uuid.New().String(),
+			Subject:         "alice",
+			AuthenticatedAt: sqlxx.NullTime(time.Now().UTC()),
+			Remember:        remember,
+		}))
+	}
+
+	t.Run("case=returns the aggregate counts", func(t *testing.T) {
+		s := get(t)
+		assert.Equal(t, 1, s.ActiveClients)
+		assert.Equal(t, 1, s.ActiveLoginSessions)
+		assert.Equal(t, 1, s.TokensIssuedLast24h)
+		assert.Equal(t, 0, s.PendingFlows)
+		assert.WithinDuration(t, time.Now(), s.ComputedAt, time.Minute)
+	})
+
+	t.Run("case=caches the counts", func(t *testing.T) {
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, &client.Client{ID: "another-stats-client"}))
+		assert.Equal(t, 1, get(t).ActiveClients)
+
+		reg.Config().MustSet(ctx, config.KeyStatsCacheTTL, "0s")
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyStatsCacheTTL, "1m") })
+		assert.Equal(t, 2, get(t).ActiveClients)
+	})
+}
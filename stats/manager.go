@@ -0,0 +1,46 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"context"
+	"time"
+)
+
+// Statistics
+//
+// swagger:model statistics
+type Statistics struct {
+	// The number of registered OAuth 2.0 Clients.
+	//
+	// required: true
+	ActiveClients int `json:"active_clients"`
+
+	// The number of remembered login sessions.
+	//
+	// required: true
+	ActiveLoginSessions int `json:"active_login_sessions"`
+
+	// The number of access tokens issued during the last 24 hours, including access tokens issued by refreshing
+	// a grant.
+	//
+	// required: true
+	TokensIssuedLast24h int `json:"tokens_issued_last_24h"`
+
+	// The number of login and consent flows which have not been completed, rejected or timed out yet.
+	//
+	// required: true
+	PendingFlows int `json:"pending_flows"`
+
+	// The time at which the counts were computed. Counts are cached, so this may lie in the past.
+	//
+	// required: true
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+type Manager interface {
+	// GetStatistics returns the aggregate counts of the network. The counts are cached for the duration configured
+	// by stats.cache_ttl.
+	GetStatistics(ctx context.Context) (*Statistics, error)
+}
@@ -0,0 +1,17 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package stats
+
+import (
+	"github.com/ory/hydra/v2/x"
+)
+
+type InternalRegistry interface {
+	x.RegistryWriter
+	Registry
+}
+
+type Registry interface {
+	StatsManager() Manager
+}
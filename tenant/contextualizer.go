@@ -0,0 +1,40 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package tenant
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/contextx"
+)
+
+type contextKey struct{}
+
+// NewContext returns a context in which all data is read from and written to the network of the tenant.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant of the context, if any.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok
+}
+
+// Contextualizer returns the network of the tenant if the context has a tenant, and otherwise delegates to the
+// wrapped contextualizer.
+type Contextualizer struct {
+	contextx.Contextualizer
+}
+
+var _ contextx.Contextualizer = (*Contextualizer)(nil)
+
+func (c *Contextualizer) Network(ctx context.Context, network uuid.UUID) uuid.UUID {
+	if t, ok := FromContext(ctx); ok {
+		return t.ID
+	}
+	return c.Contextualizer.Network(ctx, network)
+}
@@ -0,0 +1,261 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package tenant
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/v2/audit"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/httprouterx"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+const (
+	TenantsHandlerPath = "/tenants"
+)
+
+var nameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type Handler struct {
+	r InternalRegistry
+}
+
+func NewHandler(r InternalRegistry) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) SetRoutes(admin *httprouterx.RouterAdmin) {
+	admin.GET(TenantsHandlerPath, h.listTenants)
+	admin.POST(TenantsHandlerPath, h.createTenant)
+	admin.GET(TenantsHandlerPath+"/:name", h.getTenant)
+	admin.DELETE(TenantsHandlerPath+"/:name", h.deleteTenant)
+}
+
+// Create Tenant Request Body
+//
+// swagger:parameters createTenant
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createTenant struct {
+	// in: body
+	// required: true
+	Body Tenant
+}
+
+// swagger:route POST /admin/tenants tenant createTenant
+//
+// # Create Tenant
+//
+// Creates a tenant. The tenant's APIs are served at /tenants/{name}/ and /admin/tenants/{name}/, and its data is
+// isolated from all other tenants. This endpoint is only available if tenancy is enabled.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  201: tenant
+//	  default: errorOAuth2
+func (h *Handler) createTenant(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := h.requireTenancy(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var t Tenant
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	if !nameRegex.MatchString(t.Name) {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Field name must consist of up to 63 lowercase letters, digits and dashes, and must start and end with a letter or digit.")))
+		return
+	}
+	if t.IssuerURL != "" {
+		if u, err := url.Parse(t.IssuerURL); err != nil || !u.IsAbs() {
+			h.r.Writer().WriteError(w, r, errorsx.WithStack(herodot.ErrBadRequest.WithReasonf("Field issuer_url must be an absolute URL.")))
+			return
+		}
+	}
+
+	t.CreatedAt = time.Now().UTC().Round(time.Second)
+	if err := h.r.TenantManager().CreateTenant(r.Context(), &t); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.AuditRecorder().Record(r, audit.ActionCreate, audit.ResourceTenant, t.Name, nil, &t)
+	h.r.Writer().WriteCreated(w, r, "/admin"+TenantsHandlerPath+"/"+t.Name, &t)
+}
+
+// Paginated Tenant List Response
+//
+// swagger:response listTenants
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listTenantsResponse struct {
+	keysetpagination.ResponseHeaders
+
+	// List of Tenants
+	//
+	// in:body
+	Body []Tenant
+}
+
+// Paginated Tenant List Parameters
+//
+// swagger:parameters listTenants
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listTenantsParameters struct {
+	keysetpagination.RequestParameters
+}
+
+// swagger:route GET /admin/tenants tenant listTenants
+//
+// # List Tenants
+//
+// This endpoint lists all tenants. It is only available if tenancy is enabled.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: listTenants
+//	  default: errorOAuth2
+func (h *Handler) listTenants(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := h.requireTenancy(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	pageOpts, err := x.ParsePagination(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	tenants, nextPage, err := h.r.TenantManager().GetTenants(r.Context(), pageOpts...)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	keysetpagination.Header(w, r.URL, nextPage)
+	h.r.Writer().Write(w, r, tenants)
+}
+
+// Get Tenant Request
+//
+// swagger:parameters getTenant
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getTenant struct {
+	// The name of the tenant.
+	//
+	// in: path
+	// required: true
+	Name string `json:"name"`
+}
+
+// swagger:route GET /admin/tenants/{name} tenant getTenant
+//
+// # Get Tenant
+//
+// This endpoint returns a tenant. It is only available if tenancy is enabled.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: tenant
+//	  default: errorOAuth2
+func (h *Handler) getTenant(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.requireTenancy(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	t, err := h.r.TenantManager().GetTenant(r.Context(), ps.ByName("name"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, t)
+}
+
+// Delete Tenant Request
+//
+// swagger:parameters deleteTenant
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteTenant struct {
+	// The name of the tenant.
+	//
+	// in: path
+	// required: true
+	Name string `json:"name"`
+}
+
+// swagger:route DELETE /admin/tenants/{name} tenant deleteTenant
+//
+// # Delete Tenant
+//
+// Deletes a tenant together with all of its OAuth 2.0 Clients, JSON Web Keys, sessions and tokens. This cannot be
+// undone. This endpoint is only available if tenancy is enabled.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) deleteTenant(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.requireTenancy(r); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	t, err := h.r.TenantManager().GetTenant(r.Context(), ps.ByName("name"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.TenantManager().DeleteTenant(r.Context(), t.Name); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.AuditRecorder().Record(r, audit.ActionDelete, audit.ResourceTenant, t.Name, t, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) requireTenancy(r *http.Request) error {
+	if !h.r.Config().TenancyEnabled(r.Context()) {
+		return errorsx.WithStack(herodot.ErrNotFound.WithReason("Tenancy is not enabled."))
+	}
+	return nil
+}
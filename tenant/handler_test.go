@@ -0,0 +1,161 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package tenant_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni"
+
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/hydra/v2/tenant"
+	"github.com/ory/hydra/v2/x"
+	"github.com/ory/x/contextx"
+)
+
+func TestTenantHandler(t *testing.T) {
+	ctx := context.Background()
+
+	newServers := func(t *testing.T, conf *config.DefaultProvider) (admin, public *httptest.Server) {
+		reg := internal.NewRegistryMemory(t, conf, &contextx.Default{})
+		adminRouter, publicRouter := x.NewRouterAdmin(conf.AdminURL), x.NewRouterPublic()
+		reg.RegisterRoutes(ctx, adminRouter, publicRouter)
+
+		serve := func(h http.Handler) *httptest.Server {
+			n := negroni.New()
+			n.UseFunc(tenant.Middleware(reg))
+			n.UseHandler(h)
+			ts := httptest.NewServer(n)
+			t.Cleanup(ts.Close)
+			return ts
+		}
+		return serve(adminRouter), serve(publicRouter)
+	}
+
+	do := func(t *testing.T, method, url string, body interface{}) *http.Response {
+		var b bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&b).Encode(body))
+		}
+		req, err := http.NewRequest(method, url, &b)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		return res
+	}
+
+	decode := func(t *testing.T, res *http.Response, v interface{}) {
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+	}
+
+	t.Run("case=tenants are not available if tenancy is disabled", func(t *testing.T) {
+		admin, public := newServers(t, internal.NewConfigurationWithDefaults())
+
+		res := do(t, http.MethodPost, admin.URL+"/admin"+tenant.TenantsHandlerPath, &tenant.Tenant{Name: "acme"})
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res = do(t, http.MethodGet, public.URL+"/tenants/acme"+oauth2.WellKnownPath, nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("case=validates tenants", func(t *testing.T) {
+		conf := internal.NewConfigurationWithDefaults()
+		conf.MustSet(ctx, config.KeyTenancyEnabled, true)
+		admin, _ := newServers(t, conf)
+
+		for _, tn := range []tenant.Tenant{
+			{Name: ""},
+			{Name: "Acme"},
+			{Name: "-acme"},
+			{Name: "acme/foo"},
+			{Name: "acme", IssuerURL: "/relative"},
+		} {
+			res := do(t, http.MethodPost, admin.URL+"/admin"+tenant.TenantsHandlerPath, &tn)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%+v", tn)
+		}
+	})
+
+	t.Run("case=isolates tenants", func(t *testing.T) {
+		conf := internal.NewConfigurationWithDefaults()
+		conf.MustSet(ctx, config.KeyTenancyEnabled, true)
+		conf.MustSet(ctx, config.KeyTenancyIssuers, []map[string]interface{}{{
+			"host":           "globex.example.com",
+			"oidc_discovery": map[string]interface{}{"supported_scope": []string{"globex.read"}},
+		}})
+		admin, public := newServers(t, conf)
+
+		res := do(t, http.MethodPost, admin.URL+"/admin"+tenant.TenantsHandlerPath, &tenant.Tenant{Name: "acme"})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		res = do(t, http.MethodPost, admin.URL+"/admin"+tenant.TenantsHandlerPath, &tenant.Tenant{Name: "globex", IssuerURL: "https://globex.example.com/"})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		res = do(t, http.MethodPost, admin.URL+"/admin"+tenant.TenantsHandlerPath, &tenant.Tenant{Name: "acme"})
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+		var tenants []tenant.Tenant
+		decode(t, do(t, http.MethodGet, admin.URL+"/admin"+tenant.TenantsHandlerPath, nil), &tenants)
+		assert.Len(t, tenants, 2)
+
+		res = do(t, http.MethodPost, admin.URL+"/admin/tenants/acme"+client.ClientsHandlerPath, &client.Client{ID: "acme-client"})
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		var clients []client.Client
+		decode(t, do(t, http.MethodGet, admin.URL+"/admin/tenants/acme"+client.ClientsHandlerPath, nil), &clients)
+		require.Len(t, clients, 1)
+		assert.Equal(t, "acme-client", clients[0].GetID())
+
+		decode(t, do(t, http.MethodGet, admin.URL+"/admin"+client.ClientsHandlerPath, nil), &clients)
+		assert.Empty(t, clients)
+		decode(t, do(t, http.MethodGet, admin.URL+"/admin/tenants/globex"+client.ClientsHandlerPath, nil), &clients)
+		assert.Empty(t, clients)
+
+		res = do(t, http.MethodGet, admin.URL+"/admin/tenants/unknown"+client.ClientsHandlerPath, nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		var discovery struct {
+			Issuer   string   `json:"issuer"`
+			TokenURL string   `json:"token_endpoint"`
+			Scopes   []string `json:"scopes_supported"`
+		}
+		decode(t, do(t, http.MethodGet, public.URL+"/tenants/acme"+oauth2.WellKnownPath, nil), &discovery)
+		assert.Equal(t, conf.PublicURL(ctx).String()+"tenants/acme/", discovery.Issuer)
+		assert.Equal(t, conf.PublicURL(ctx).String()+"tenants/acme/oauth2/token", discovery.TokenURL)
+
+		decode(t, do(t, http.MethodGet, public.URL+"/tenants/globex"+oauth2.WellKnownPath, nil), &discovery)
+		assert.Equal(t, "https://globex.example.com/", discovery.Issuer)
+		assert.Contains(t, discovery.Scopes, "globex.read")
+
+		decode(t, do(t, http.MethodGet, public.URL+oauth2.WellKnownPath, nil), &discovery)
+		assert.Equal(t, conf.IssuerURL(ctx).String(), discovery.Issuer)
+		assert.NotContains(t, discovery.Scopes, "globex.read")
+
+		var defaultKeys, acmeKeys jose.JSONWebKeySet
+		decode(t, do(t, http.MethodGet, public.URL+jwk.WellKnownKeysPath, nil), &defaultKeys)
+		decode(t, do(t, http.MethodGet, public.URL+"/tenants/acme"+jwk.WellKnownKeysPath, nil), &acmeKeys)
+		require.NotEmpty(t, defaultKeys.Keys)
+		require.NotEmpty(t, acmeKeys.Keys)
+		assert.NotEqual(t, defaultKeys.Keys[0].KeyID, acmeKeys.Keys[0].KeyID)
+
+		res = do(t, http.MethodDelete, admin.URL+"/admin/tenants/acme", nil)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		res = do(t, http.MethodGet, admin.URL+"/admin/tenants/acme", nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		res = do(t, http.MethodGet, admin.URL+"/admin/tenants/acme"+client.ClientsHandlerPath, nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
@@ -0,0 +1,53 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package tenant
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+// Tenant
+//
+// A tenant is an isolated realm with its own OAuth 2.0 Clients, JSON Web Keys, login and consent sessions and tokens,
+// served under its own issuer.
+//
+// swagger:model tenant
+type Tenant struct {
+	// The ID of the tenant. It is also the ID of the network which holds the data of the tenant.
+	ID uuid.UUID `json:"id" db:"id"`
+
+	// The name of the tenant. It consists of lowercase letters, digits and dashes, and is part of the paths of the
+	// tenant-scoped APIs, for example /tenants/{name}/oauth2/auth.
+	Name string `json:"name" db:"name"`
+
+	// The issuer URL of the tenant. Defaults to the public URL of the tenant, for example
+	// https://my-hydra/tenants/{name}/.
+	IssuerURL string `json:"issuer_url,omitempty" db:"issuer_url"`
+
+	// The time at which the tenant was created.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (Tenant) TableName() string {
+	return "hydra_tenant"
+}
+
+func (t Tenant) PageToken() keysetpagination.PageToken {
+	return keysetpagination.MapPageToken{"id": t.ID.String()}
+}
+
+type Manager interface {
+	// CreateTenant creates the tenant and the network which holds its data.
+	CreateTenant(ctx context.Context, t *Tenant) error
+	GetTenant(ctx context.Context, name string) (*Tenant, error)
+	GetTenants(ctx context.Context, pageOpts ...keysetpagination.Option) ([]Tenant, *keysetpagination.Paginator, error)
+
+	// DeleteTenant deletes the tenant and all of its data.
+	DeleteTenant(ctx context.Context, name string) error
+}
@@ -0,0 +1,77 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package tenant
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/urfave/negroni"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/x/urlx"
+)
+
+const pathPrefix = "/tenants/"
+
+// Middleware serves the tenant-scoped APIs. Requests to /tenants/{name}/... and /admin/tenants/{name}/... are
+// rewritten to the path without the tenant prefix, and handled in the context of the tenant: all data is read from
+// and written to the network of the tenant, and the tenant's issuer and public URLs are used.
+//
+// Requests which do not address a tenant are passed through unchanged, as are all requests if tenancy is disabled.
+func Middleware(reg InternalRegistry) negroni.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		ctx := r.Context()
+		if !reg.Config().TenancyEnabled(ctx) {
+			next(w, r)
+			return
+		}
+
+		prefix, name, rest, ok := splitPath(r.URL.Path)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		t, err := reg.TenantManager().GetTenant(ctx, name)
+		if err != nil {
+			reg.Writer().WriteError(w, r, err)
+			return
+		}
+
+		public := urlx.AppendPaths(reg.Config().PublicURL(ctx), "tenants", t.Name, "/")
+		issuer := public
+		if t.IssuerURL != "" {
+			issuer, err = url.Parse(t.IssuerURL)
+			if err != nil {
+				reg.Writer().WriteError(w, r, err)
+				return
+			}
+		}
+
+		r = r.WithContext(config.WithSelfURLs(NewContext(ctx, t), issuer, public))
+		r.URL = urlx.Copy(r.URL)
+		r.URL.Path = prefix + rest
+		r.URL.RawPath = ""
+		next(w, r)
+	}
+}
+
+// splitPath splits /tenants/{name}/rest and /admin/tenants/{name}/rest into the prefix ("" or "/admin"), the name
+// of the tenant and /rest. The tenant management endpoints at /admin/tenants/{name} are not tenant-scoped.
+func splitPath(path string) (prefix, name, rest string, ok bool) {
+	if strings.HasPrefix(path, "/admin"+pathPrefix) {
+		prefix, path = "/admin", strings.TrimPrefix(path, "/admin")
+	}
+	if !strings.HasPrefix(path, pathPrefix) {
+		return "", "", "", false
+	}
+
+	name, rest, found := strings.Cut(strings.TrimPrefix(path, pathPrefix), "/")
+	if !found || name == "" || rest == "" {
+		return "", "", "", false
+	}
+	return prefix, name, "/" + rest, true
+}
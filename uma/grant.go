@@ -0,0 +1,228 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package uma
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+const (
+	// GrantTypeUMATicket is the grant type with which clients exchange permission tickets for requesting party
+	// tokens (RPTs).
+	GrantTypeUMATicket = "urn:ietf:params:oauth:grant-type:uma-ticket"
+
+	// ClaimTokenFormatIDToken is the format of claim tokens which are ID tokens issued by Ory.
+	ClaimTokenFormatIDToken = "http://openid.net/specs/openid-connect-core-1_0.html#IDToken"
+
+	// PermissionsClaim is the claim of requesting party tokens which holds the granted permissions.
+	PermissionsClaim = "permissions"
+)
+
+var (
+	ErrRequestDenied = &fosite.RFC6749Error{
+		ErrorField:       "request_denied",
+		DescriptionField: "The client is not authorized to have these permissions.",
+		CodeField:        http.StatusForbidden,
+	}
+	ErrInvalidResourceID = &fosite.RFC6749Error{
+		ErrorField:       "invalid_resource_id",
+		DescriptionField: "The resource set could not be found.",
+		CodeField:        http.StatusBadRequest,
+	}
+	ErrInvalidScope = &fosite.RFC6749Error{
+		ErrorField:       "invalid_scope",
+		DescriptionField: "The scope is not a scope of the resource set.",
+		CodeField:        http.StatusBadRequest,
+	}
+)
+
+var _ fosite.TokenEndpointHandler = (*GrantHandler)(nil)
+
+// GrantHandler issues requesting party tokens (RPTs) for permission tickets with the UMA 2.0 grant. The granted
+// permissions are decided by the policy hook and are stored in the permissions claim of the RPT.
+type GrantHandler struct {
+	*foauth2.HandleHelper
+	r InternalRegistry
+}
+
+// NewGrantHandlerFactory returns a factory of the UMA 2.0 grant handler, which is loaded with the other handlers of
+// the token endpoint.
+func NewGrantHandlerFactory(r InternalRegistry) func(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+	return func(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+		return &GrantHandler{
+			HandleHelper: &foauth2.HandleHelper{
+				AccessTokenStrategy: strategy.(foauth2.AccessTokenStrategy),
+				AccessTokenStorage:  storage.(foauth2.AccessTokenStorage),
+				Config:              config,
+			},
+			r: r,
+		}
+	}
+}
+
+func (g *GrantHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !g.CanHandleTokenEndpointRequest(ctx, request) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+	if !request.GetClient().GetGrantTypes().Has(GrantTypeUMATicket) {
+		return errorsx.WithStack(fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant '%s'.", GrantTypeUMATicket))
+	}
+
+	session, ok := request.GetSession().(*oauth2.Session)
+	if !ok {
+		return errorsx.WithStack(fosite.ErrServerError.WithHint("Expected session to be of type *Session, but got another type."))
+	}
+
+	form := request.GetRequestForm()
+	t, err := decodeTicket(ctx, g.r, form.Get("ticket"))
+	if err != nil {
+		return errorsx.WithStack(fosite.ErrInvalidGrant.WithWrap(err).WithHint("The permission ticket is invalid or has expired.").WithDebug(err.Error()))
+	}
+
+	requestingParty, claims, err := g.requestingParty(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := g.requestedPermissions(ctx, t, request.GetRequestedScopes())
+	if err != nil {
+		return err
+	}
+
+	granted, err := g.decide(ctx, &PolicyHookRequest{
+		ResourceOwner:   t.Owner,
+		ResourceServer:  t.ResourceServer,
+		RequestingParty: requestingParty,
+		ClientID:        request.GetClient().GetID(),
+		Claims:          claims,
+		Permissions:     permissions,
+	})
+	if err != nil {
+		return err
+	}
+	if len(granted) == 0 {
+		return errorsx.WithStack(ErrRequestDenied)
+	}
+
+	var accessTokenKeyID string
+	if g.r.Config().AccessTokenStrategy(ctx, client.AccessTokenStrategySource(request.GetClient())) == "jwt" {
+		if accessTokenKeyID, err = g.r.AccessTokenJWTStrategy().GetPublicKeyID(ctx); err != nil {
+			return err
+		}
+	}
+
+	session.Subject = requestingParty
+	session.ClientID = request.GetClient().GetID()
+	session.KID = accessTokenKeyID
+	session.DefaultSession.Claims.Issuer = g.r.Config().IssuerURL(ctx).String()
+	session.DefaultSession.Claims.IssuedAt = g.r.Clock().Now().UTC()
+	if session.Extra == nil {
+		session.Extra = map[string]interface{}{}
+	}
+	session.Extra[PermissionsClaim] = granted
+
+	atLifespan := fosite.GetEffectiveLifespan(request.GetClient(), GrantTypeUMATicket, fosite.AccessToken, g.r.Config().GetAccessTokenLifespan(ctx))
+	session.SetExpiresAt(fosite.AccessToken, g.r.Clock().Now().UTC().Add(atLifespan))
+	return nil
+}
+
+func (g *GrantHandler) PopulateTokenEndpointResponse(ctx context.Context, request fosite.AccessRequester, response fosite.AccessResponder) error {
+	if !g.CanHandleTokenEndpointRequest(ctx, request) {
+		return errorsx.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	atLifespan := fosite.GetEffectiveLifespan(request.GetClient(), GrantTypeUMATicket, fosite.AccessToken, g.r.Config().GetAccessTokenLifespan(ctx))
+	return g.IssueAccessToken(ctx, atLifespan, request, response)
+}
+
+func (g *GrantHandler) CanSkipClientAuth(context.Context, fosite.AccessRequester) bool {
+	return false
+}
+
+func (g *GrantHandler) CanHandleTokenEndpointRequest(_ context.Context, request fosite.AccessRequester) bool {
+	return request.GetGrantTypes().ExactOne(GrantTypeUMATicket)
+}
+
+// requestingParty returns the subject and the claims of the requesting party, which is identified by the claim token
+// if there is one, and is the client otherwise.
+func (g *GrantHandler) requestingParty(ctx context.Context, request fosite.AccessRequester) (string, map[string]interface{}, error) {
+	form := request.GetRequestForm()
+	claimToken := form.Get("claim_token")
+	if claimToken == "" {
+		return request.GetClient().GetID(), nil, nil
+	}
+	if format := form.Get("claim_token_format"); format != ClaimTokenFormatIDToken {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHintf("The claim token format '%s' is not supported.", format))
+	}
+
+	token, err := g.r.OpenIDJWTStrategy().Decode(ctx, claimToken)
+	if err != nil {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithWrap(err).WithHint("The claim token is not a valid ID token.").WithDebug(err.Error()))
+	}
+	if !token.Claims.VerifyIssuer(g.r.Config().IssuerURL(ctx).String(), true) {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The claim token was not issued by this authorization server."))
+	}
+	if !token.Claims.VerifyAudience(request.GetClient().GetID(), true) {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The claim token was not issued to the client."))
+	}
+	// Logout tokens are signed with the same key as ID tokens and also carry the subject, so they must be told apart.
+	if _, ok := token.Claims["events"]; ok {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithHint("The claim token is not an ID token."))
+	}
+	if typ, _ := token.Header["typ"].(string); typ != "" && !strings.EqualFold(typ, "JWT") &&
+		typ != g.r.Config().JWTHeadersType(ctx, config.KeyJWTHeadersIDTokenType) {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithHintf("The claim token has the unexpected type '%s'.", typ))
+	}
+	subject, _ := token.Claims["sub"].(string)
+	if subject == "" {
+		return "", nil, errorsx.WithStack(fosite.ErrInvalidGrant.WithHint(`The claim token does not contain the "sub" claim.`))
+	}
+	return subject, token.Claims, nil
+}
+
+// requestedPermissions returns the permissions of the ticket with their resource sets, restricted to the requested
+// scopes if there are any. Permissions of resource sets which have been deleted since are left out.
+func (g *GrantHandler) requestedPermissions(ctx context.Context, t *ticket, scopes []string) ([]PolicyHookPermission, error) {
+	permissions := make([]PolicyHookPermission, 0, len(t.Permissions))
+	for _, p := range t.Permissions {
+		id, err := uuid.FromString(p.ResourceID)
+		if err != nil {
+			continue
+		}
+		rs, err := g.r.UMAManager().GetResourceSet(ctx, id)
+		if err != nil || rs.ResourceServer != t.ResourceServer || rs.Owner != t.Owner {
+			continue
+		}
+
+		if len(p.ResourceScopes) == 0 {
+			// Requesting a resource set without scopes requests all of its scopes.
+			p.ResourceScopes = rs.Scopes
+		}
+		if len(scopes) > 0 {
+			p.ResourceScopes = stringslice.Filter(p.ResourceScopes, func(scope string) bool {
+				return !stringslice.Has(scopes, scope)
+			})
+			if len(p.ResourceScopes) == 0 {
+				continue
+			}
+		}
+		permissions = append(permissions, PolicyHookPermission{Permission: p, Resource: rs})
+	}
+	if len(permissions) == 0 {
+		return nil, errorsx.WithStack(ErrRequestDenied.WithHint("None of the permissions of the permission ticket can be requested."))
+	}
+	return permissions, nil
+}
@@ -0,0 +1,511 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package uma
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/oauth2"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/httprouterx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/stringslice"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	ResourceSetPath = "/uma/resource_set"
+	PermissionPath  = "/uma/permission"
+	WellKnownPath   = "/.well-known/uma2-configuration"
+
+	// ProtectionScope is the scope of protection API access tokens (PATs), with which resource servers register
+	// resource sets and request permission tickets on behalf of resource owners.
+	ProtectionScope = "uma_protection"
+)
+
+type Handler struct {
+	r InternalRegistry
+}
+
+func NewHandler(r InternalRegistry) *Handler {
+	return &Handler{r: r}
+}
+
+func (h *Handler) SetRoutes(public *httprouterx.RouterPublic) {
+	public.GET(WellKnownPath, h.discoverUMAConfiguration)
+
+	public.POST(ResourceSetPath, h.createUMAResourceSet)
+	public.GET(ResourceSetPath, h.listUMAResourceSets)
+	public.GET(ResourceSetPath+"/:id", h.getUMAResourceSet)
+	public.PUT(ResourceSetPath+"/:id", h.updateUMAResourceSet)
+	public.DELETE(ResourceSetPath+"/:id", h.deleteUMAResourceSet)
+
+	public.POST(PermissionPath, h.createUMAPermissionTicket)
+}
+
+// UMA 2.0 Authorization Server Metadata
+//
+// swagger:model umaConfiguration
+type Configuration struct {
+	// The issuer URL of the authorization server.
+	Issuer string `json:"issuer"`
+
+	// The URL of the token endpoint, at which requesting party tokens are issued.
+	TokenEndpoint string `json:"token_endpoint"`
+
+	// The URL of the JSON Web Key Set of the authorization server.
+	JWKsURI string `json:"jwks_uri"`
+
+	// The grant types supported by the token endpoint.
+	GrantTypesSupported []string `json:"grant_types_supported"`
+
+	// The formats of claim tokens which identify the requesting party.
+	ClaimTokenProfilesSupported []string `json:"claim_token_profiles_supported"`
+
+	// The UMA profiles supported by the authorization server.
+	UMAProfilesSupported []string `json:"uma_profiles_supported"`
+
+	// The URL of the resource registration endpoint.
+	ResourceRegistrationEndpoint string `json:"resource_registration_endpoint"`
+
+	// The URL of the permission endpoint.
+	PermissionEndpoint string `json:"permission_endpoint"`
+}
+
+// swagger:route GET /.well-known/uma2-configuration uma discoverUMAConfiguration
+//
+// # UMA 2.0 Discovery
+//
+// Returns the metadata of the authorization server as defined by UMA 2.0 Grant for OAuth 2.0 Authorization and
+// Federated Authorization for UMA 2.0.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: umaConfiguration
+//	  default: errorOAuth2
+func (h *Handler) discoverUMAConfiguration(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	h.r.Writer().Write(w, r, &Configuration{
+		Issuer:                       h.r.Config().IssuerURL(ctx).String(),
+		TokenEndpoint:                h.r.Config().OAuth2TokenURL(ctx).String(),
+		JWKsURI:                      h.r.Config().JWKSURL(ctx).String(),
+		GrantTypesSupported:          []string{GrantTypeUMATicket},
+		ClaimTokenProfilesSupported:  []string{ClaimTokenFormatIDToken},
+		UMAProfilesSupported:         []string{},
+		ResourceRegistrationEndpoint: urlx.AppendPaths(h.r.Config().IssuerURL(ctx), ResourceSetPath).String(),
+		PermissionEndpoint:           urlx.AppendPaths(h.r.Config().IssuerURL(ctx), PermissionPath).String(),
+	})
+}
+
+// Create UMA 2.0 Resource Set Request
+//
+// swagger:parameters createUMAResourceSet
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createUMAResourceSet struct {
+	// in: body
+	// required: true
+	Body ResourceSet
+}
+
+// UMA 2.0 Resource Set Reference
+//
+// swagger:model umaResourceSetReference
+type resourceSetReference struct {
+	// The ID of the resource set.
+	ID string `json:"_id"`
+}
+
+// swagger:route POST /uma/resource_set uma createUMAResourceSet
+//
+// # Register an UMA 2.0 Resource Set
+//
+// Registers a resource set of the resource owner of the protection API access token (PAT). The PAT must have the
+// uma_protection scope.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  201: umaResourceSetReference
+//	  default: errorOAuth2
+func (h *Handler) createUMAResourceSet(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	pat, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var rs ResourceSet
+	if err := decodeResourceSet(r.Body, &rs); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	rs.ID = uuid.Must(uuid.NewV4())
+	rs.ResourceServer = pat.GetClient().GetID()
+	rs.Owner = pat.GetSession().GetSubject()
+	rs.CreatedAt = time.Now().UTC().Round(time.Second)
+	rs.UpdatedAt = rs.CreatedAt
+	if err := h.r.UMAManager().CreateResourceSet(r.Context(), &rs); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	location := urlx.AppendPaths(h.r.Config().IssuerURL(r.Context()), ResourceSetPath, rs.ID.String()).String()
+	h.r.Writer().WriteCreated(w, r, location, &resourceSetReference{ID: rs.ID.String()})
+}
+
+// swagger:route GET /uma/resource_set uma listUMAResourceSets
+//
+// # List UMA 2.0 Resource Sets
+//
+// Returns the IDs of the resource sets of the resource owner which the resource server registered.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: umaResourceSetIDs
+//	  default: errorOAuth2
+func (h *Handler) listUMAResourceSets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	pat, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	rss, err := h.r.UMAManager().GetResourceSets(r.Context(), pat.GetClient().GetID(), pat.GetSession().GetSubject())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	ids := make([]string, len(rss))
+	for k, rs := range rss {
+		ids[k] = rs.ID.String()
+	}
+	h.r.Writer().Write(w, r, ids)
+}
+
+// UMA 2.0 Resource Set IDs
+//
+// swagger:response umaResourceSetIDs
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type umaResourceSetIDs struct {
+	// in: body
+	Body []string
+}
+
+// UMA 2.0 Resource Set Parameters
+//
+// swagger:parameters getUMAResourceSet deleteUMAResourceSet
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type umaResourceSetID struct {
+	// The ID of the resource set.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:route GET /uma/resource_set/{id} uma getUMAResourceSet
+//
+// # Get an UMA 2.0 Resource Set
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: umaResourceSet
+//	  default: errorOAuth2
+func (h *Handler) getUMAResourceSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pat, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := h.getOwnResourceSet(r, pat, ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, rs)
+}
+
+// Update UMA 2.0 Resource Set Request
+//
+// swagger:parameters updateUMAResourceSet
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type updateUMAResourceSet struct {
+	// The ID of the resource set.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	// required: true
+	Body ResourceSet
+}
+
+// swagger:route PUT /uma/resource_set/{id} uma updateUMAResourceSet
+//
+// # Update an UMA 2.0 Resource Set
+//
+// Replaces the description of the resource set.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  200: umaResourceSetReference
+//	  default: errorOAuth2
+func (h *Handler) updateUMAResourceSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pat, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.getOwnResourceSet(r, pat, ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var rs ResourceSet
+	if err := decodeResourceSet(r.Body, &rs); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	rs.ID = existing.ID
+	rs.ResourceServer = existing.ResourceServer
+	rs.Owner = existing.Owner
+	rs.CreatedAt = existing.CreatedAt
+	rs.UpdatedAt = time.Now().UTC().Round(time.Second)
+	if err := h.r.UMAManager().UpdateResourceSet(r.Context(), &rs); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, &resourceSetReference{ID: rs.ID.String()})
+}
+
+// swagger:route DELETE /uma/resource_set/{id} uma deleteUMAResourceSet
+//
+// # Delete an UMA 2.0 Resource Set
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: errorOAuth2
+func (h *Handler) deleteUMAResourceSet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	pat, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	rs, err := h.getOwnResourceSet(r, pat, ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.UMAManager().DeleteResourceSet(r.Context(), rs.ID); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Create UMA 2.0 Permission Ticket Request
+//
+// swagger:parameters createUMAPermissionTicket
+//
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createUMAPermissionTicket struct {
+	// in: body
+	// required: true
+	Body []Permission
+}
+
+// UMA 2.0 Permission Ticket
+//
+// swagger:model umaPermissionTicket
+type permissionTicket struct {
+	// The permission ticket, which the client exchanges for a requesting party token.
+	Ticket string `json:"ticket"`
+}
+
+// swagger:route POST /uma/permission uma createUMAPermissionTicket
+//
+// # Request an UMA 2.0 Permission Ticket
+//
+// Returns a permission ticket for the permissions which a client requires to access resource sets of the resource
+// owner of the protection API access token (PAT). The body is either a single permission or an array of
+// permissions.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  bearer:
+//
+//	Responses:
+//	  201: umaPermissionTicket
+//	  default: errorOAuth2
+func (h *Handler) createUMAPermissionTicket(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	pat, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(err))
+		return
+	}
+	var permissions []Permission
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '{' {
+		permissions = make([]Permission, 1)
+		err = json.Unmarshal(body, &permissions[0])
+	} else {
+		err = json.Unmarshal(body, &permissions)
+	}
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err)))
+		return
+	}
+	if len(permissions) == 0 {
+		h.r.Writer().WriteError(w, r, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("At least one permission must be requested.")))
+		return
+	}
+
+	for _, p := range permissions {
+		rs, err := h.getOwnResourceSet(r, pat, p.ResourceID)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, errorsx.WithStack(ErrInvalidResourceID.WithHintf("The resource set %q is not registered.", p.ResourceID)))
+			return
+		}
+		for _, scope := range p.ResourceScopes {
+			if !stringslice.Has(rs.Scopes, scope) {
+				h.r.Writer().WriteError(w, r, errorsx.WithStack(ErrInvalidScope.WithHintf("The scope %q is not a scope of the resource set %q.", scope, p.ResourceID)))
+				return
+			}
+		}
+	}
+
+	t, err := encodeTicket(ctx, h.r, &ticket{
+		ResourceServer: pat.GetClient().GetID(),
+		Owner:          pat.GetSession().GetSubject(),
+		Permissions:    permissions,
+		ExpiresAt:      h.r.Clock().Now().Add(h.r.Config().UMAPermissionTicketLifespan(ctx)),
+	})
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().WriteCode(w, r, http.StatusCreated, &permissionTicket{Ticket: t})
+}
+
+// authenticate returns the protection API access token (PAT) of the request, or writes an error if the request does
+// not carry an active access token with the uma_protection scope.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (fosite.AccessRequester, bool) {
+	ctx := r.Context()
+	session := oauth2.NewSessionWithCustomClaims(ctx, h.r.Config(), "")
+	_, pat, err := h.r.OAuth2Provider().IntrospectToken(ctx, fosite.AccessTokenFromRequest(r), fosite.AccessToken, session, ProtectionScope)
+	if err != nil {
+		rfcerr := fosite.ErrorToRFC6749Error(err)
+		if rfcerr.StatusCode() == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="%s",error_description="%s"`, rfcerr.ErrorField, rfcerr.GetDescription()))
+		}
+		h.r.Writer().WriteError(w, r, err)
+		return nil, false
+	}
+	return pat, true
+}
+
+// getOwnResourceSet returns the resource set if it belongs to the resource server and the resource owner of the
+// protection API access token.
+func (h *Handler) getOwnResourceSet(r *http.Request, pat fosite.AccessRequester, id string) (*ResourceSet, error) {
+	rid, err := uuid.FromString(id)
+	if err != nil {
+		return nil, errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	rs, err := h.r.UMAManager().GetResourceSet(r.Context(), rid)
+	if err != nil {
+		return nil, err
+	}
+	if rs.ResourceServer != pat.GetClient().GetID() || rs.Owner != pat.GetSession().GetSubject() {
+		return nil, errorsx.WithStack(sqlcon.ErrNoRows)
+	}
+	return rs, nil
+}
+
+func decodeResourceSet(body io.Reader, rs *ResourceSet) error {
+	if err := json.NewDecoder(body).Decode(rs); err != nil {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithWrap(err).WithHintf("Unable to decode body because: %s", err))
+	}
+	if rs.Name == "" {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Field 'name' must not be empty."))
+	}
+	if len(rs.Scopes) == 0 {
+		return errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("Field 'resource_scopes' must not be empty."))
+	}
+	return nil
+}
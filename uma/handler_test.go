@@ -0,0 +1,346 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package uma_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	goauth2 "golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/ory/fosite/token/jwt"
+	hc "github.com/ory/hydra/v2/client"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	"github.com/ory/hydra/v2/internal/testhelpers"
+	"github.com/ory/hydra/v2/uma"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/ioutilx"
+)
+
+func TestUMA(t *testing.T) {
+	ctx := context.Background()
+	reg := internal.NewMockedRegistry(t, &contextx.Default{})
+	reg.Config().MustSet(ctx, config.KeyAccessTokenStrategy, "opaque")
+	public, admin := testhelpers.NewOAuth2Server(ctx, t, reg)
+
+	newClient := func(t *testing.T, grantType, scope string) *hc.Client {
+		secret := uuid.New().String()
+		c := &hc.Client{
+			Secret:     secret,
+			GrantTypes: []string{grantType},
+			Scope:      scope,
+		}
+		require.NoError(t, reg.ClientManager().CreateClient(ctx, c))
+		c.Secret = secret
+		return c
+	}
+
+	resourceServer := newClient(t, "client_credentials", uma.ProtectionScope)
+	pat, err := (&clientcredentials.Config{
+		ClientID:     resourceServer.GetID(),
+		ClientSecret: resourceServer.Secret,
+		TokenURL:     reg.Config().OAuth2TokenURL(ctx).String(),
+		Scopes:       []string{uma.ProtectionScope},
+		AuthStyle:    goauth2.AuthStyleInHeader,
+	}).Token(ctx)
+	require.NoError(t, err)
+
+	do := func(t *testing.T, method, path string, body interface{}) (*http.Response, gjson.Result) {
+		var b bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&b).Encode(body))
+		}
+		req, err := http.NewRequest(method, public.URL+path, &b)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+pat.AccessToken)
+		res, err := public.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	createResourceSet := func(t *testing.T) string {
+		res, body := do(t, http.MethodPost, uma.ResourceSetPath, map[string]interface{}{
+			"name":            "Medical records",
+			"type":            "https://example.org/medical-records",
+			"resource_scopes": []string{"read", "write"},
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode, body.Raw)
+		return body.Get("_id").String()
+	}
+
+	requestTicket := func(t *testing.T, permissions interface{}) string {
+		res, body := do(t, http.MethodPost, uma.PermissionPath, permissions)
+		require.Equal(t, http.StatusCreated, res.StatusCode, body.Raw)
+		require.NotEmpty(t, body.Get("ticket").String())
+		return body.Get("ticket").String()
+	}
+
+	requestRPTWithClaimToken := func(t *testing.T, c *hc.Client, ticket, claimToken string) (*http.Response, gjson.Result) {
+		form := url.Values{
+			"grant_type": {uma.GrantTypeUMATicket},
+			"ticket":     {ticket},
+		}
+		if claimToken != "" {
+			form.Set("claim_token", claimToken)
+			form.Set("claim_token_format", uma.ClaimTokenFormatIDToken)
+		}
+		req, err := http.NewRequest(http.MethodPost, reg.Config().OAuth2TokenURL(ctx).String(), strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.GetID(), c.Secret)
+		res, err := public.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+	}
+
+	requestRPT := func(t *testing.T, c *hc.Client, ticket string) (*http.Response, gjson.Result) {
+		return requestRPTWithClaimToken(t, c, ticket, "")
+	}
+
+	introspect := func(t *testing.T, token string) gjson.Result {
+		return testhelpers.IntrospectToken(t, &goauth2.Config{ClientID: resourceServer.GetID(), ClientSecret: resourceServer.Secret}, token, admin)
+	}
+
+	setHook := func(t *testing.T, h http.HandlerFunc) {
+		ts := httptest.NewServer(h)
+		t.Cleanup(ts.Close)
+		reg.Config().MustSet(ctx, config.KeyUMAPolicyHook, ts.URL)
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyUMAPolicyHook, nil) })
+	}
+
+	t.Run("case=serves the discovery document", func(t *testing.T) {
+		res, err := public.Client().Get(public.URL + uma.WellKnownPath)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		body := gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+		assert.Equal(t, public.URL+uma.ResourceSetPath, body.Get("resource_registration_endpoint").String())
+		assert.Equal(t, public.URL+uma.PermissionPath, body.Get("permission_endpoint").String())
+		assert.Equal(t, uma.GrantTypeUMATicket, body.Get("grant_types_supported.0").String())
+	})
+
+	t.Run("case=requires a protection API access token", func(t *testing.T) {
+		res, err := public.Client().Post(public.URL+uma.ResourceSetPath, "application/json", strings.NewReader("{}"))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Contains(t, res.Header.Get("WWW-Authenticate"), "Bearer")
+	})
+
+	t.Run("case=manages resource sets", func(t *testing.T) {
+		id := createResourceSet(t)
+
+		res, body := do(t, http.MethodGet, uma.ResourceSetPath+"/"+id, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, "Medical records", body.Get("name").String())
+		assert.Equal(t, resourceServer.GetID(), body.Get("owner").String())
+		assert.Equal(t, `["read","write"]`, body.Get("resource_scopes").Raw)
+
+		res, body = do(t, http.MethodPut, uma.ResourceSetPath+"/"+id, map[string]interface{}{
+			"name":            "Lab results",
+			"resource_scopes": []string{"read"},
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+
+		res, body = do(t, http.MethodGet, uma.ResourceSetPath+"/"+id, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, "Lab results", body.Get("name").String())
+		assert.Equal(t, `["read"]`, body.Get("resource_scopes").Raw)
+
+		res, body = do(t, http.MethodGet, uma.ResourceSetPath, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Contains(t, body.Value(), id)
+
+		res, _ = do(t, http.MethodDelete, uma.ResourceSetPath+"/"+id, nil)
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		res, _ = do(t, http.MethodGet, uma.ResourceSetPath+"/"+id, nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("case=rejects invalid resource sets", func(t *testing.T) {
+		res, body := do(t, http.MethodPost, uma.ResourceSetPath, map[string]interface{}{"name": "Medical records"})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+	})
+
+	t.Run("case=rejects permissions of unknown resource sets and scopes", func(t *testing.T) {
+		res, body := do(t, http.MethodPost, uma.PermissionPath, []uma.Permission{{ResourceID: uuid.New().String()}})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_resource_id", body.Get("error").String(), body.Raw)
+
+		id := createResourceSet(t)
+		res, body = do(t, http.MethodPost, uma.PermissionPath, uma.Permission{ResourceID: id, ResourceScopes: []string{"delete"}})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_scope", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=grants the resource owner without a policy hook", func(t *testing.T) {
+		owner := newClient(t, uma.GrantTypeUMATicket, "")
+		require.NoError(t, reg.ClientManager().UpdateClient(ctx, &hc.Client{
+			ID:         resourceServer.GetID(),
+			Secret:     resourceServer.Secret,
+			GrantTypes: []string{"client_credentials", uma.GrantTypeUMATicket},
+			Scope:      uma.ProtectionScope,
+		}))
+		t.Cleanup(func() {
+			require.NoError(t, reg.ClientManager().UpdateClient(ctx, &hc.Client{
+				ID:         resourceServer.GetID(),
+				Secret:     resourceServer.Secret,
+				GrantTypes: []string{"client_credentials"},
+				Scope:      uma.ProtectionScope,
+			}))
+		})
+
+		id := createResourceSet(t)
+		ticket := requestTicket(t, uma.Permission{ResourceID: id, ResourceScopes: []string{"read"}})
+
+		res, body := requestRPT(t, owner, ticket)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, body.Raw)
+		assert.Equal(t, "request_denied", body.Get("error").String(), body.Raw)
+
+		res, body = requestRPT(t, resourceServer, ticket)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+
+		rpt := introspect(t, body.Get("access_token").String())
+		assert.True(t, rpt.Get("active").Bool(), rpt.Raw)
+		assert.Equal(t, resourceServer.GetID(), rpt.Get("sub").String(), rpt.Raw)
+		assert.Equal(t, id, rpt.Get("ext.permissions.0.resource_id").String(), rpt.Raw)
+		assert.Equal(t, `["read"]`, rpt.Get("ext.permissions.0.resource_scopes").Raw, rpt.Raw)
+	})
+
+	t.Run("case=grants the permissions of the policy hook", func(t *testing.T) {
+		requestingParty := newClient(t, uma.GrantTypeUMATicket, "")
+		id := createResourceSet(t)
+
+		var hookReq uma.PolicyHookRequest
+		setHook(t, func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&hookReq))
+			w.Header().Set("Content-Type", "application/json")
+			require.NoError(t, json.NewEncoder(w).Encode(&uma.PolicyHookResponse{Permissions: []uma.Permission{
+				{ResourceID: id, ResourceScopes: []string{"read", "delete"}},
+				{ResourceID: uuid.New().String(), ResourceScopes: []string{"read"}},
+			}}))
+		})
+
+		res, body := requestRPT(t, requestingParty, requestTicket(t, []uma.Permission{{ResourceID: id}}))
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+
+		assert.Equal(t, resourceServer.GetID(), hookReq.ResourceOwner)
+		assert.Equal(t, resourceServer.GetID(), hookReq.ResourceServer)
+		assert.Equal(t, requestingParty.GetID(), hookReq.RequestingParty)
+		require.Len(t, hookReq.Permissions, 1)
+		assert.Equal(t, []string{"read", "write"}, hookReq.Permissions[0].ResourceScopes)
+		assert.Equal(t, "Medical records", hookReq.Permissions[0].Resource.Name)
+
+		rpt := introspect(t, body.Get("access_token").String())
+		assert.Equal(t, requestingParty.GetID(), rpt.Get("sub").String(), rpt.Raw)
+		assert.Equal(t, `[{"resource_id":"`+id+`","resource_scopes":["read"]}]`, rpt.Get("ext.permissions").Raw, rpt.Raw)
+	})
+
+	t.Run("case=grants all permissions if the policy hook has no content", func(t *testing.T) {
+		requestingParty := newClient(t, uma.GrantTypeUMATicket, "")
+		id := createResourceSet(t)
+		setHook(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		res, body := requestRPT(t, requestingParty, requestTicket(t, []uma.Permission{{ResourceID: id, ResourceScopes: []string{"write"}}}))
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+
+		rpt := introspect(t, body.Get("access_token").String())
+		assert.Equal(t, `[{"resource_id":"`+id+`","resource_scopes":["write"]}]`, rpt.Get("ext.permissions").Raw, rpt.Raw)
+	})
+
+	t.Run("case=denies the request if the policy hook forbids it", func(t *testing.T) {
+		requestingParty := newClient(t, uma.GrantTypeUMATicket, "")
+		id := createResourceSet(t)
+		setHook(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		res, body := requestRPT(t, requestingParty, requestTicket(t, []uma.Permission{{ResourceID: id}}))
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, body.Raw)
+		assert.Equal(t, "request_denied", body.Get("error").String(), body.Raw)
+	})
+
+	t.Run("case=identifies the requesting party by the claim token", func(t *testing.T) {
+		requestingParty := newClient(t, uma.GrantTypeUMATicket, "")
+		id := createResourceSet(t)
+		setHook(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		claims := func(aud string) jwt.MapClaims {
+			return jwt.MapClaims{
+				"iss": reg.Config().IssuerURL(ctx).String(),
+				"aud": []string{aud},
+				"sub": "alice",
+				"iat": time.Now().Unix(),
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}
+		}
+
+		idToken, _, err := reg.OpenIDJWTStrategy().Generate(ctx, claims(requestingParty.GetID()), jwt.NewHeaders())
+		require.NoError(t, err)
+		res, body := requestRPTWithClaimToken(t, requestingParty, requestTicket(t, []uma.Permission{{ResourceID: id}}), idToken)
+		require.Equal(t, http.StatusOK, res.StatusCode, body.Raw)
+		assert.Equal(t, "alice", introspect(t, body.Get("access_token").String()).Get("sub").String())
+
+		t.Run("case=rejects ID tokens issued to other clients", func(t *testing.T) {
+			idToken, _, err := reg.OpenIDJWTStrategy().Generate(ctx, claims(resourceServer.GetID()), jwt.NewHeaders())
+			require.NoError(t, err)
+			res, body := requestRPTWithClaimToken(t, requestingParty, requestTicket(t, []uma.Permission{{ResourceID: id}}), idToken)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+			assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+		})
+
+		t.Run("case=rejects logout tokens", func(t *testing.T) {
+			c := claims(requestingParty.GetID())
+			c["events"] = map[string]struct{}{"http://schemas.openid.net/event/backchannel-logout": {}}
+			logoutToken, _, err := reg.LogoutTokenJWTStrategy().Generate(ctx, c, jwt.NewHeaders())
+			require.NoError(t, err)
+			res, body := requestRPTWithClaimToken(t, requestingParty, requestTicket(t, []uma.Permission{{ResourceID: id}}), logoutToken)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+			assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+
+			reg.Config().MustSet(ctx, config.KeyJWTHeadersLogoutTokenType, "logout+jwt")
+			t.Cleanup(func() { reg.Config().MustSet(ctx, config.KeyJWTHeadersLogoutTokenType, nil) })
+			delete(c, "events")
+			logoutToken, _, err = reg.LogoutTokenJWTStrategy().Generate(ctx, c, jwt.NewHeaders())
+			require.NoError(t, err)
+			res, body = requestRPTWithClaimToken(t, requestingParty, requestTicket(t, []uma.Permission{{ResourceID: id}}), logoutToken)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+			assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+		})
+	})
+
+	t.Run("case=rejects invalid tickets and unauthorized clients", func(t *testing.T) {
+		requestingParty := newClient(t, uma.GrantTypeUMATicket, "")
+		res, body := requestRPT(t, requestingParty, "not-a-ticket")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "invalid_grant", body.Get("error").String(), body.Raw)
+
+		other := newClient(t, "client_credentials", "")
+		res, body = requestRPT(t, other, requestTicket(t, []uma.Permission{{ResourceID: createResourceSet(t)}}))
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, body.Raw)
+		assert.Equal(t, "unauthorized_client", body.Get("error").String(), body.Raw)
+	})
+}
@@ -0,0 +1,72 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package uma
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlxx"
+)
+
+// Resource Set
+//
+// A resource set is a set of protected resources of a resource owner which a resource server registered, so that
+// requesting parties can be granted access to it with UMA 2.0 permission tickets.
+//
+// swagger:model umaResourceSet
+type ResourceSet struct {
+	// The ID of the resource set.
+	//
+	// readOnly: true
+	ID uuid.UUID `json:"_id" db:"id"`
+
+	NID uuid.UUID `json:"-" db:"nid"`
+
+	// The client ID of the resource server which registered the resource set.
+	ResourceServer string `json:"-" db:"resource_server"`
+
+	// The subject of the resource owner.
+	//
+	// readOnly: true
+	Owner string `json:"owner" db:"owner"`
+
+	// The human-readable name of the resource set.
+	//
+	// required: true
+	Name string `json:"name" db:"name"`
+
+	// The type of the resource set, for example a URI.
+	Type string `json:"type,omitempty" db:"type"`
+
+	// The scopes of access which can be granted to the resource set.
+	//
+	// required: true
+	Scopes sqlxx.StringSliceJSONFormat `json:"resource_scopes" db:"resource_scopes"`
+
+	// The human-readable description of the resource set.
+	Description string `json:"description,omitempty" db:"description"`
+
+	// The URI of an icon of the resource set.
+	IconURI string `json:"icon_uri,omitempty" db:"icon_uri"`
+
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+func (ResourceSet) TableName() string {
+	return "hydra_uma_resource_set"
+}
+
+type Manager interface {
+	CreateResourceSet(ctx context.Context, rs *ResourceSet) error
+	GetResourceSet(ctx context.Context, id uuid.UUID) (*ResourceSet, error)
+
+	// GetResourceSets returns the resource sets of the resource owner which the resource server registered.
+	GetResourceSets(ctx context.Context, resourceServer, owner string) ([]ResourceSet, error)
+	UpdateResourceSet(ctx context.Context, rs *ResourceSet) error
+	DeleteResourceSet(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,156 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package uma
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/stringslice"
+)
+
+// PolicyHookPermission is a permission requested with a permission ticket.
+//
+// swagger:ignore
+type PolicyHookPermission struct {
+	Permission
+
+	// Resource is the resource set of the permission.
+	Resource *ResourceSet `json:"resource"`
+}
+
+// PolicyHookRequest is the request body sent to the policy hook.
+//
+// swagger:ignore
+type PolicyHookRequest struct {
+	// ResourceOwner is the subject of the resource owner of the resource sets.
+	ResourceOwner string `json:"resource_owner"`
+	// ResourceServer is the client ID of the resource server which requested the permission ticket.
+	ResourceServer string `json:"resource_server"`
+	// RequestingParty is the subject of the claim token, or the client ID if there is no claim token.
+	RequestingParty string `json:"requesting_party"`
+	// ClientID is the client ID of the client which requests the requesting party token.
+	ClientID string `json:"client_id"`
+	// Claims are the claims of the claim token.
+	Claims map[string]interface{} `json:"claims,omitempty"`
+	// Permissions are the requested permissions.
+	Permissions []PolicyHookPermission `json:"permissions"`
+}
+
+// PolicyHookResponse is the response body received from the policy hook.
+//
+// swagger:ignore
+type PolicyHookResponse struct {
+	// Permissions are the granted permissions. Permissions which were not requested are ignored.
+	Permissions []Permission `json:"permissions"`
+}
+
+// decide returns the requested permissions which are granted to the requesting party. Without a policy hook, only
+// the resource owner is granted access to its resource sets.
+func (g *GrantHandler) decide(ctx context.Context, reqBody *PolicyHookRequest) ([]Permission, error) {
+	requested := make([]Permission, len(reqBody.Permissions))
+	for k, p := range reqBody.Permissions {
+		requested[k] = p.Permission
+	}
+
+	hookConfig := g.r.Config().UMAPolicyHookConfig(ctx)
+	if hookConfig == nil {
+		if reqBody.RequestingParty != reqBody.ResourceOwner {
+			return nil, nil
+		}
+		return requested, nil
+	}
+
+	reqBodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while encoding the policy hook.").
+				WithDebugf("Unable to encode the policy hook body: %s", err),
+		)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, hookConfig.URL, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while preparing the policy hook.").
+				WithDebugf("Unable to prepare the HTTP Request: %s", err),
+		)
+	}
+	if err := hookConfig.Auth.Apply(req.Request); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while applying the policy hook authentication.").
+				WithDebugf("Unable to apply the policy hook authentication: %s", err))
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := g.r.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("An error occurred while executing the policy hook.").
+				WithDebugf("Unable to execute HTTP Request: %s", err),
+		)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Some of the permissions are granted
+	case http.StatusNoContent:
+		// All of the permissions are granted
+		return requested, nil
+	case http.StatusForbidden:
+		return nil, nil
+	default:
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithDescription("The policy hook target responded with an error.").
+				WithDebugf("Policy hook responded with HTTP status code: %s", resp.Status),
+		)
+	}
+
+	var respBody PolicyHookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, errorsx.WithStack(
+			fosite.ErrServerError.
+				WithWrap(err).
+				WithDescription("The policy hook target responded with an error.").
+				WithDebugf("Response from policy hook could not be decoded: %s", err),
+		)
+	}
+	return grantedPermissions(requested, respBody.Permissions), nil
+}
+
+// grantedPermissions returns the permissions which were both requested and granted.
+func grantedPermissions(requested, granted []Permission) []Permission {
+	var result []Permission
+	for _, g := range granted {
+		for _, r := range requested {
+			if g.ResourceID != r.ResourceID {
+				continue
+			}
+			scopes := stringslice.Filter(g.ResourceScopes, func(scope string) bool {
+				return !stringslice.Has(r.ResourceScopes, scope)
+			})
+			if len(scopes) > 0 {
+				result = append(result, Permission{ResourceID: r.ResourceID, ResourceScopes: scopes})
+			}
+			break
+		}
+	}
+	return result
+}
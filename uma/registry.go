@@ -0,0 +1,31 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package uma
+
+import (
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/v2/aead"
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/jwk"
+	"github.com/ory/hydra/v2/x"
+)
+
+type InternalRegistry interface {
+	x.RegistryWriter
+	x.RegistryLogger
+	x.HTTPClientProvider
+	x.ClockProvider
+	config.Provider
+	Registry
+
+	OAuth2Provider() fosite.OAuth2Provider
+	OpenIDJWTStrategy() jwk.JWTSigner
+	AccessTokenJWTStrategy() jwk.JWTSigner
+	FlowCipher() *aead.XChaCha20Poly1305
+}
+
+type Registry interface {
+	UMAManager() Manager
+	UMAHandler() *Handler
+}
@@ -0,0 +1,65 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package uma
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Permission
+//
+// A permission is a set of scopes of access to a resource set.
+//
+// swagger:model umaPermission
+type Permission struct {
+	// The ID of the resource set.
+	//
+	// required: true
+	ResourceID string `json:"resource_id"`
+
+	// The scopes of access to the resource set.
+	ResourceScopes []string `json:"resource_scopes"`
+}
+
+// ticket is the content of a permission ticket. Permission tickets are encrypted, so that they need not be stored.
+type ticket struct {
+	// ResourceServer is the client ID of the resource server which requested the permission ticket.
+	ResourceServer string `json:"rs"`
+
+	// Owner is the subject of the resource owner of the resource sets.
+	Owner string `json:"owner"`
+
+	Permissions []Permission `json:"permissions"`
+	ExpiresAt   time.Time    `json:"exp"`
+}
+
+var ticketAAD = []byte("uma_permission_ticket")
+
+func encodeTicket(ctx context.Context, r InternalRegistry, t *ticket) (string, error) {
+	plaintext, err := json.Marshal(t)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return r.FlowCipher().Encrypt(ctx, plaintext, ticketAAD)
+}
+
+func decodeTicket(ctx context.Context, r InternalRegistry, encoded string) (*ticket, error) {
+	plaintext, err := r.FlowCipher().Decrypt(ctx, encoded, ticketAAD)
+	if err != nil {
+		return nil, err
+	}
+
+	var t ticket
+	if err := json.Unmarshal(plaintext, &t); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if t.ExpiresAt.Before(r.Clock().Now()) {
+		return nil, errors.New("the permission ticket has expired")
+	}
+	return &t, nil
+}
@@ -15,6 +15,9 @@ func LogAudit(r *http.Request, message interface{}, logger *logrusx.Logger) {
 	}
 
 	logger = logger.WithRequest(r)
+	if id := CorrelationIDFromContext(r.Context()); id != "" {
+		logger = logger.WithField("correlation_id", id)
+	}
 
 	if err, ok := message.(error); ok {
 		logger.WithError(err).Infoln("access denied")
@@ -23,6 +23,13 @@ func DeleteHydraRows(t *testing.T, c *pop.Connection) {
 		"hydra_oauth2_jti_blacklist",
 		"hydra_oauth2_trusted_jwt_bearer_issuer",
 		"hydra_jwk",
+		"hydra_audit_event",
+		"hydra_gnap_grant",
+		"hydra_oauth2_grant_history",
+		"hydra_oauth2_token_quota",
+		"hydra_uma_resource_set",
+		"hydra_tenant",
+		"hydra_lease",
 		"hydra_client",
 	} {
 		if err := c.RawQuery("DELETE FROM " + tb).Exec(); err != nil {
@@ -46,6 +53,13 @@ func CleanSQLPop(t *testing.T, c *pop.Connection) {
 		"hydra_oauth2_jti_blacklist",
 		"hydra_oauth2_trusted_jwt_bearer_issuer",
 		"hydra_jwk",
+		"hydra_audit_event",
+		"hydra_gnap_grant",
+		"hydra_oauth2_grant_history",
+		"hydra_oauth2_token_quota",
+		"hydra_uma_resource_set",
+		"hydra_tenant",
+		"hydra_lease",
 		"hydra_client",
 		// Migrations
 		"hydra_oauth2_authentication_consent_migration",
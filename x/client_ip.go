@@ -0,0 +1,137 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/urfave/negroni"
+
+	"github.com/ory/x/httpx"
+)
+
+// The headers which can carry the IP address of the client.
+const (
+	ClientIPHeaderXForwardedFor = "x-forwarded-for"
+	ClientIPHeaderForwarded     = "forwarded"
+	ClientIPHeaderXRealIP       = "x-real-ip"
+	ClientIPHeaderTrueClientIP  = "true-client-ip"
+)
+
+type trustedProxiesConfig interface {
+	Enabled() bool
+	IsTrusted(ip net.IP) bool
+	Headers() []string
+}
+
+type clientIPContextKey struct{}
+
+// ClientIPMiddleware derives the IP address of the client and stores it in the request context, from where it is read
+// by ClientIP.
+func ClientIPMiddleware(c trustedProxiesConfig) negroni.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		next(rw, r.WithContext(context.WithValue(r.Context(), clientIPContextKey{}, DeriveClientIP(r, c))))
+	}
+}
+
+// ClientIP returns the IP address of the client which sent the request. It is the address derived by
+// ClientIPMiddleware or, if the middleware did not handle the request, the address derived without trusted proxies.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok {
+		return ip
+	}
+	return DeriveClientIP(r, nil)
+}
+
+// DeriveClientIP derives the IP address of the client which sent the request.
+//
+// Without trusted proxies, the True-Client-IP, X-Real-IP and X-Forwarded-For headers are honored regardless of
+// who set them, for backwards compatibility. With trusted proxies, the headers are only honored if the peer is a
+// trusted proxy, and the configured headers are checked in order. Lists of addresses are walked from right to left,
+// and the first address which does not belong to a trusted proxy is the client IP address, so that clients can not
+// spoof their address by sending the header themselves.
+func DeriveClientIP(r *http.Request, c trustedProxiesConfig) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+
+	if c == nil || !c.Enabled() {
+		if r.Header.Get("True-Client-IP") == "" && r.Header.Get("X-Real-IP") == "" && r.Header.Get("X-Forwarded-For") == "" {
+			return peer
+		}
+		return httpx.ClientIP(r)
+	}
+
+	if ip := net.ParseIP(peer); ip == nil || !c.IsTrusted(ip) {
+		return peer
+	}
+
+	for _, header := range c.Headers() {
+		var addresses []string
+		switch header {
+		case ClientIPHeaderXForwardedFor:
+			for _, value := range r.Header.Values("X-Forwarded-For") {
+				for _, address := range strings.Split(value, ",") {
+					addresses = append(addresses, strings.TrimSpace(address))
+				}
+			}
+		case ClientIPHeaderForwarded:
+			addresses = forwardedForAddresses(r.Header.Values("Forwarded"))
+		case ClientIPHeaderXRealIP:
+			addresses = []string{strings.TrimSpace(r.Header.Get("X-Real-IP"))}
+		case ClientIPHeaderTrueClientIP:
+			addresses = []string{strings.TrimSpace(r.Header.Get("True-Client-IP"))}
+		}
+
+		if ip := rightmostUntrustedIP(addresses, c); ip != "" {
+			return ip
+		}
+	}
+
+	return peer
+}
+
+// rightmostUntrustedIP returns the rightmost address which does not belong to a trusted proxy, or the leftmost
+// address if all of them do. Invalid addresses end the walk, because everything left of them can not be trusted.
+func rightmostUntrustedIP(addresses []string, c trustedProxiesConfig) string {
+	var leftmost string
+	for i := len(addresses) - 1; i >= 0; i-- {
+		ip := parseForwardedIP(addresses[i])
+		if ip == nil {
+			return leftmost
+		}
+		if !c.IsTrusted(ip) {
+			return ip.String()
+		}
+		leftmost = ip.String()
+	}
+	return leftmost
+}
+
+// forwardedForAddresses returns the values of the "for" parameters of the Forwarded headers (RFC 7239).
+func forwardedForAddresses(values []string) (addresses []string) {
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					addresses = append(addresses, strings.Trim(value, `"`))
+				}
+			}
+		}
+	}
+	return addresses
+}
+
+// parseForwardedIP parses an address which may carry a port and, for IPv6, brackets, as in "[2001:db8::1]:4711".
+func parseForwardedIP(address string) net.IP {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(address, "["), "]"))
+}
@@ -0,0 +1,75 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	. "github.com/ory/hydra/v2/x"
+)
+
+func TestDeriveClientIP(t *testing.T) {
+	ctx := context.Background()
+
+	newRequest := func(remoteAddr string, headers map[string]string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/oauth2/auth", nil)
+		r.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		return r
+	}
+
+	t.Run("case=without trusted proxies", func(t *testing.T) {
+		c := internal.NewConfigurationWithDefaults()
+		tp := c.TrustedProxies(config.PublicInterface)
+
+		assert.Equal(t, "192.0.2.1", DeriveClientIP(newRequest("192.0.2.1:1234", nil), tp))
+		assert.Equal(t, "203.0.113.7", DeriveClientIP(newRequest("192.0.2.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"}), tp))
+		assert.Equal(t, "203.0.113.8", DeriveClientIP(newRequest("192.0.2.1:1234", map[string]string{"True-Client-IP": "203.0.113.8"}), tp))
+	})
+
+	c := internal.NewConfigurationWithDefaults()
+	c.MustSet(ctx, config.PublicInterface.Key(config.KeySuffixTrustedProxiesCIDRs), []string{"10.0.0.0/8", "fd00::/8"})
+	c.MustSet(ctx, config.PublicInterface.Key(config.KeySuffixTrustedProxiesHeaders), []string{"forwarded", "x-forwarded-for"})
+	tp := c.TrustedProxies(config.PublicInterface)
+	assert.False(t, c.TrustedProxies(config.AdminInterface).Enabled())
+
+	for _, tc := range []struct {
+		d, remoteAddr string
+		headers       map[string]string
+		expected      string
+	}{
+		{d: "untrusted peer", remoteAddr: "192.0.2.1:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, expected: "192.0.2.1"},
+		{d: "trusted peer without headers", remoteAddr: "10.0.0.1:1234", expected: "10.0.0.1"},
+		{d: "trusted peer", remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, expected: "203.0.113.7"},
+		{d: "spoofed address", remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.2"}, expected: "203.0.113.7"},
+		{d: "only trusted proxies", remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, expected: "10.0.0.3"},
+		{d: "invalid address", remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7, unknown"}, expected: "10.0.0.1"},
+		{d: "header order", remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7", "Forwarded": `for="[2001:db8::1]:4711";proto=https, for=fd00::2`}, expected: "2001:db8::1"},
+		{d: "ignored header", remoteAddr: "10.0.0.1:1234", headers: map[string]string{"X-Real-IP": "203.0.113.7"}, expected: "10.0.0.1"},
+		{d: "trusted ipv6 peer", remoteAddr: "[fd00::1]:1234", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, expected: "203.0.113.7"},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			assert.Equal(t, tc.expected, DeriveClientIP(newRequest(tc.remoteAddr, tc.headers), tp))
+		})
+	}
+
+	t.Run("case=middleware", func(t *testing.T) {
+		r := newRequest("10.0.0.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+		assert.Equal(t, "203.0.113.7", ClientIP(r), "falls back to the legacy behavior")
+
+		r = newRequest("192.0.2.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+		ClientIPMiddleware(tp)(httptest.NewRecorder(), r, func(_ http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "192.0.2.1", ClientIP(r))
+		})
+	})
+}
@@ -0,0 +1,64 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ory/fosite/token/jwt"
+)
+
+// Clock tells the time when tokens are issued and validated.
+type Clock interface {
+	Now() time.Time
+}
+
+type ClockProvider interface {
+	Clock() Clock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the Clock of the operating system.
+var SystemClock Clock = systemClock{}
+
+const timeValidationErrors = jwt.ValidationErrorExpired | jwt.ValidationErrorIssuedAt | jwt.ValidationErrorNotValidYet
+
+// VerifyTimeClaimsWithLeeway validates the exp, iat and nbf claims at now, tolerating a clock skew of leeway
+// between the issuer and Ory Hydra. Missing claims are valid.
+func VerifyTimeClaimsWithLeeway(claims jwt.MapClaims, now time.Time, leeway time.Duration) error {
+	vErr := new(jwt.ValidationError)
+	if !claims.VerifyExpiresAt(now.Add(-leeway).Unix(), false) {
+		vErr.Inner = errors.New("Token is expired")
+		vErr.Errors |= jwt.ValidationErrorExpired
+	}
+	if !claims.VerifyIssuedAt(now.Add(leeway).Unix(), false) {
+		vErr.Inner = errors.New("Token used before issued")
+		vErr.Errors |= jwt.ValidationErrorIssuedAt
+	}
+	if !claims.VerifyNotBefore(now.Add(leeway).Unix(), false) {
+		vErr.Inner = errors.New("Token is not valid yet")
+		vErr.Errors |= jwt.ValidationErrorNotValidYet
+	}
+	if vErr.Errors == 0 {
+		return nil
+	}
+	return vErr
+}
+
+// RevalidateTimeClaimsWithLeeway validates the time based claims of a JWT again with the leeway if parsing it
+// failed only because they are invalid, in which case its signature has already been verified. Otherwise, it
+// returns err.
+func RevalidateTimeClaimsWithLeeway(err error, claims jwt.MapClaims, now time.Time, leeway time.Duration) error {
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) || ve.Errors == 0 || ve.Errors&^timeValidationErrors != 0 || claims == nil {
+		return err
+	}
+	return VerifyTimeClaimsWithLeeway(claims, now, leeway)
+}
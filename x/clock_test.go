@@ -0,0 +1,59 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite/token/jwt"
+)
+
+func TestVerifyTimeClaimsWithLeeway(t *testing.T) {
+	now := time.Now()
+
+	for k, tc := range []struct {
+		claims jwt.MapClaims
+		leeway time.Duration
+		valid  bool
+	}{
+		{claims: jwt.MapClaims{}, valid: true},
+		{claims: jwt.MapClaims{"exp": float64(now.Add(time.Minute).Unix())}, valid: true},
+		{claims: jwt.MapClaims{"exp": float64(now.Add(-10 * time.Second).Unix())}, valid: false},
+		{claims: jwt.MapClaims{"exp": float64(now.Add(-10 * time.Second).Unix())}, leeway: 30 * time.Second, valid: true},
+		{claims: jwt.MapClaims{"exp": float64(now.Add(-time.Minute).Unix())}, leeway: 30 * time.Second, valid: false},
+		{claims: jwt.MapClaims{"iat": float64(now.Add(10 * time.Second).Unix())}, valid: false},
+		{claims: jwt.MapClaims{"iat": float64(now.Add(10 * time.Second).Unix())}, leeway: 30 * time.Second, valid: true},
+		{claims: jwt.MapClaims{"nbf": float64(now.Add(10 * time.Second).Unix())}, valid: false},
+		{claims: jwt.MapClaims{"nbf": float64(now.Add(10 * time.Second).Unix())}, leeway: 30 * time.Second, valid: true},
+		{claims: jwt.MapClaims{"nbf": float64(now.Add(time.Minute).Unix())}, leeway: 30 * time.Second, valid: false},
+	} {
+		err := VerifyTimeClaimsWithLeeway(tc.claims, now, tc.leeway)
+		if tc.valid {
+			assert.NoError(t, err, "%d", k)
+		} else {
+			assert.Error(t, err, "%d", k)
+		}
+	}
+}
+
+func TestRevalidateTimeClaimsWithLeeway(t *testing.T) {
+	now := time.Now()
+	claims := jwt.MapClaims{"iat": float64(now.Add(10 * time.Second).Unix())}
+
+	expired := &jwt.ValidationError{Errors: jwt.ValidationErrorIssuedAt}
+	require.NoError(t, RevalidateTimeClaimsWithLeeway(expired, claims, now, 30*time.Second))
+	require.Error(t, RevalidateTimeClaimsWithLeeway(expired, claims, now, 0))
+
+	invalidSignature := &jwt.ValidationError{Errors: jwt.ValidationErrorSignatureInvalid | jwt.ValidationErrorIssuedAt}
+	assert.Equal(t, invalidSignature, RevalidateTimeClaimsWithLeeway(invalidSignature, claims, now, 30*time.Second))
+
+	other := errors.New("other")
+	assert.Equal(t, other, RevalidateTimeClaimsWithLeeway(other, claims, now, 30*time.Second))
+	assert.NoError(t, RevalidateTimeClaimsWithLeeway(nil, claims, now, 30*time.Second))
+}
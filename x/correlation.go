@@ -0,0 +1,48 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CorrelationIDParameter is the query parameter which carries the correlation ID of a browser flow through the
+// redirects between Ory Hydra, the login and consent apps, and the error page.
+const CorrelationIDParameter = "correlation_id"
+
+var correlationIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type correlationIDContextKey struct{}
+
+// CorrelationIDFromRequest returns the correlation ID of the request. It is the correlation_id query parameter set
+// by a previous hop, the X-Request-Id header, or the trace ID of the request, in this order. A random ID is returned
+// if none of these is set. Values which are too long or contain unexpected characters are ignored, because the
+// correlation ID is logged and added to redirect URLs.
+func CorrelationIDFromRequest(r *http.Request) string {
+	for _, id := range []string{r.URL.Query().Get(CorrelationIDParameter), r.Header.Get("X-Request-Id")} {
+		if correlationIDPattern.MatchString(id) {
+			return id
+		}
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+// WithCorrelationID returns a context which carries the correlation ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDContextKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID of the context or an empty string.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDContextKey{}).(string)
+	return id
+}
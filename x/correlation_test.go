@@ -0,0 +1,58 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCorrelationIDFromRequest(t *testing.T) {
+	traceID := trace.TraceID{1, 2, 3}
+	for _, tc := range []struct {
+		d, query, header string
+		traced           bool
+		expected         string
+	}{
+		{d: "query parameter", query: "from-query", header: "from-header", expected: "from-query"},
+		{d: "request ID header", header: "from-header", expected: "from-header"},
+		{d: "invalid query parameter", query: "<script>", header: "from-header", expected: "from-header"},
+		{d: "too long header", header: strings.Repeat("a", 129), traced: true, expected: traceID.String()},
+		{d: "trace ID", traced: true, expected: traceID.String()},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/oauth2/auth", nil)
+			if tc.query != "" {
+				r.URL.RawQuery = "correlation_id=" + tc.query
+			}
+			if tc.header != "" {
+				r.Header.Set("X-Request-Id", tc.header)
+			}
+			if tc.traced {
+				r = r.WithContext(trace.ContextWithSpanContext(r.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: traceID,
+					SpanID:  trace.SpanID{1},
+				})))
+			}
+			assert.Equal(t, tc.expected, CorrelationIDFromRequest(r))
+		})
+	}
+
+	t.Run("case=random", func(t *testing.T) {
+		id := CorrelationIDFromRequest(httptest.NewRequest(http.MethodGet, "/oauth2/auth", nil))
+		assert.NotEqual(t, uuid.Nil, uuid.FromStringOrNil(id))
+	})
+
+	t.Run("case=context", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/oauth2/auth", nil)
+		assert.Empty(t, CorrelationIDFromContext(r.Context()))
+		assert.Equal(t, "id", CorrelationIDFromContext(WithCorrelationID(r.Context(), "id")))
+	})
+}
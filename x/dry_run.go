@@ -0,0 +1,27 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"net/http"
+)
+
+// Dry Run Result
+//
+// Destructive admin endpoints called with the dry_run query parameter set to true return what they would delete or
+// revoke instead of applying the operation.
+//
+// swagger:model dryRunResult
+type DryRunResult struct {
+	// The number of resources which would be deleted or revoked, by type of resource, for example
+	// `{"oauth2_clients": 1, "access_tokens": 42}`.
+	//
+	// required: true
+	Affected map[string]int `json:"affected"`
+}
+
+// IsDryRun returns whether the request asks to only report what a destructive operation would affect.
+func IsDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dry_run") == "true"
+}
@@ -21,6 +21,11 @@ var (
 		ErrorField:       http.StatusText(http.StatusConflict),
 		DescriptionField: "Unable to process the requested resource because of conflict in the current state",
 	}
+	ErrRequestTooLarge = &fosite.RFC6749Error{
+		CodeField:        http.StatusRequestEntityTooLarge,
+		ErrorField:       "request_too_large",
+		DescriptionField: "The request exceeds the maximum size allowed",
+	}
 )
 
 func LogError(r *http.Request, err error, logger *logrusx.Logger) {
@@ -28,8 +33,12 @@ func LogError(r *http.Request, err error, logger *logrusx.Logger) {
 		logger = logrusx.New("", "")
 	}
 
-	logger.WithRequest(r).
-		WithError(err).Errorln("An error occurred")
+	logger = logger.WithRequest(r)
+	if id := CorrelationIDFromContext(r.Context()); id != "" {
+		logger = logger.WithField("correlation_id", id)
+	}
+
+	logger.WithError(err).Errorln("An error occurred")
 }
 
 func Must[T any](t T, err error) T {
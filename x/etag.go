@@ -0,0 +1,70 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+)
+
+var ErrPreconditionFailed = &fosite.RFC6749Error{
+	CodeField:        http.StatusPreconditionFailed,
+	ErrorField:       http.StatusText(http.StatusPreconditionFailed),
+	DescriptionField: "The resource was modified since it was retrieved, retrieve it again and retry the request",
+}
+
+// ETag returns a strong entity tag of the JSON representation of v. The JSON representation may contain secrets, such
+// as hashed client secrets or private keys, because only its hash is part of the entity tag.
+func ETag(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", errorsx.WithStack(err)
+	}
+	h := sha256.Sum256(b)
+	return `"` + base64.RawURLEncoding.EncodeToString(h[:]) + `"`, nil
+}
+
+// HasIfMatch returns whether the request has an If-Match header, so that handlers only compute the entity tag of
+// the current resource if it is needed.
+func HasIfMatch(r *http.Request) bool {
+	return r.Header.Get("If-Match") != ""
+}
+
+// CheckIfMatch returns ErrPreconditionFailed if the request has an If-Match header which does not match the entity
+// tag of the current resource. Weak entity tags never match, as If-Match uses the strong comparison.
+func CheckIfMatch(r *http.Request, etag string) error {
+	if !HasIfMatch(r) {
+		return nil
+	}
+
+	for _, header := range r.Header.Values("If-Match") {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || candidate == etag {
+				return nil
+			}
+		}
+	}
+	return errorsx.WithStack(ErrPreconditionFailed)
+}
+
+// IfNoneMatch returns whether the request has an If-None-Match header which matches the entity tag of the current
+// resource, in which case it has not been modified. If-None-Match uses the weak comparison.
+func IfNoneMatch(r *http.Request, etag string) bool {
+	for _, header := range r.Header.Values("If-None-Match") {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+	}
+	return false
+}
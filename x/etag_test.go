@@ -0,0 +1,49 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	etag, err := ETag(map[string]string{"id": "foo"})
+	require.NoError(t, err)
+
+	other, err := ETag(map[string]string{"id": "bar"})
+	require.NoError(t, err)
+	require.NotEqual(t, etag, other)
+
+	for _, tc := range []struct {
+		d       string
+		ifMatch []string
+		ok      bool
+	}{
+		{d: "without header"},
+		{d: "matching", ifMatch: []string{etag}, ok: true},
+		{d: "any", ifMatch: []string{"*"}, ok: true},
+		{d: "list", ifMatch: []string{other + ", " + etag}, ok: true},
+		{d: "multiple headers", ifMatch: []string{other, etag}, ok: true},
+		{d: "not matching", ifMatch: []string{other}},
+		{d: "weak", ifMatch: []string{"W/" + etag}},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/", nil)
+			for _, v := range tc.ifMatch {
+				r.Header.Add("If-Match", v)
+			}
+
+			err := CheckIfMatch(r, etag)
+			if tc.ok || len(tc.ifMatch) == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrPreconditionFailed)
+			}
+		})
+	}
+}
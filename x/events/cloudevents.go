@@ -0,0 +1,151 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/stringslice"
+)
+
+const (
+	// CloudEventTypePrefix prefixes the type of every CloudEvent, for example sh.ory.hydra.OAuth2LoginAccepted.
+	CloudEventTypePrefix = "sh.ory.hydra."
+
+	// SignatureHeader contains the hex-encoded HMAC-SHA256 of the request body, prefixed with "sha256=".
+	SignatureHeader = "X-Hydra-Signature"
+)
+
+// CloudEvent is an event in the structured content mode of the CloudEvents 1.0 HTTP binding.
+type CloudEvent struct {
+	SpecVersion     string                 `json:"specversion"`
+	ID              string                 `json:"id"`
+	Source          string                 `json:"source"`
+	Type            string                 `json:"type"`
+	Subject         string                 `json:"subject,omitempty"`
+	Time            time.Time              `json:"time"`
+	DataContentType string                 `json:"datacontenttype"`
+	Data            map[string]interface{} `json:"data"`
+}
+
+// CloudEventsWebhook receives events as CloudEvents.
+type CloudEventsWebhook struct {
+	// Client sends the requests and is responsible for retries.
+	Client *retryablehttp.Client
+
+	URL string
+
+	// Auth is applied to every request if not nil.
+	Auth func(*http.Request) error
+
+	// Secret signs every request if not empty.
+	Secret string
+
+	// Types are the events sent to the webhook. If empty, all events are sent.
+	Types []string
+}
+
+func (h *CloudEventsWebhook) subscribed(eventType string) bool {
+	return len(h.Types) == 0 || stringslice.Has(h.Types, eventType)
+}
+
+func (h *CloudEventsWebhook) send(ctx context.Context, body []byte) error {
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if h.Auth != nil {
+		if err := h.Auth(req.Request); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=UTF-8")
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+
+	res, err := h.Client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("the webhook responded with status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the value of the signature header for the given request body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CloudEventsEmitter sends every event as a CloudEvent to the webhooks subscribed to its type.
+type CloudEventsEmitter struct {
+	l      *logrusx.Logger
+	source string
+	hooks  []CloudEventsWebhook
+}
+
+var _ Emitter = (*CloudEventsEmitter)(nil)
+
+// NewCloudEventsEmitter returns an emitter for the given webhooks. The source identifies this instance in the
+// CloudEvents, usually it is the issuer URL.
+func NewCloudEventsEmitter(l *logrusx.Logger, source string, hooks []CloudEventsWebhook) *CloudEventsEmitter {
+	return &CloudEventsEmitter{l: l, source: source, hooks: hooks}
+}
+
+// Emit sends the event in the background, so that slow webhooks do not delay the request which caused it.
+func (c *CloudEventsEmitter) Emit(ctx context.Context, e Event) {
+	var body []byte
+	for i := range c.hooks {
+		hook := &c.hooks[i]
+		if !hook.subscribed(e.Type) {
+			continue
+		}
+
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(c.newCloudEvent(e)); err != nil {
+				c.l.WithError(err).WithField("event_id", e.ID).Error("Unable to encode the event.")
+				return
+			}
+		}
+
+		go func() {
+			if err := hook.send(context.WithoutCancel(ctx), body); err != nil {
+				c.l.WithError(err).WithField("event_id", e.ID).WithField("event_type", e.Type).
+					Error("Unable to send the event to the webhook.")
+			}
+		}()
+	}
+}
+
+func (c *CloudEventsEmitter) newCloudEvent(e Event) *CloudEvent {
+	subject, _ := e.Attributes[AttributeKeyOAuth2Subject].(string)
+	return &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              e.ID.String(),
+		Source:          c.source,
+		Type:            CloudEventTypePrefix + e.Type,
+		Subject:         subject,
+		Time:            e.Time,
+		DataContentType: "application/json",
+		Data:            e.Attributes,
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/logrusx"
+)
+
+func TestCloudEventsEmitter(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		attempts int
+		received []events.CloudEvent
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "application/cloudevents+json; charset=UTF-8", r.Header.Get("Content-Type"))
+		assert.Equal(t, events.Sign("secret", body), r.Header.Get(events.SignatureHeader))
+
+		var e events.CloudEvent
+		require.NoError(t, json.Unmarshal(body, &e))
+		received = append(received, e)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+
+	client := retryablehttp.NewClient()
+	client.RetryMax = 1
+	client.RetryWaitMin = time.Millisecond
+	client.RetryWaitMax = time.Millisecond
+
+	emitter := events.NewCloudEventsEmitter(logrusx.New("", ""), "https://hydra.example.com", []events.CloudEventsWebhook{{
+		Client: client,
+		URL:    ts.URL,
+		Secret: "secret",
+		Types:  []string{string(events.LoginAccepted)},
+	}})
+
+	ctx := events.WithEmitter(context.Background(), emitter)
+	events.Trace(ctx, events.ConsentAccepted, events.WithSubject("alice"))
+	events.Trace(ctx, events.LoginAccepted, events.WithSubject("alice"), events.WithClientID("my-client"))
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 2, attempts, "the failed request is retried")
+	e := received[0]
+	assert.Equal(t, "1.0", e.SpecVersion)
+	assert.Equal(t, "sh.ory.hydra.OAuth2LoginAccepted", e.Type)
+	assert.Equal(t, "https://hydra.example.com", e.Source)
+	assert.Equal(t, "alice", e.Subject)
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, "application/json", e.DataContentType)
+	assert.Equal(t, "my-client", e.Data["OAuth2ClientID"])
+}
@@ -0,0 +1,96 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	otelattr "go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Event is the structured representation of an event which is published to the event stream.
+type Event struct {
+	// ID uniquely identifies the event, so that consumers can deduplicate events which were delivered more than once.
+	ID uuid.UUID `json:"id"`
+
+	// Type is the name of the event, for example OAuth2AccessTokenIssued.
+	Type string `json:"type"`
+
+	// Time is the time at which the event occurred.
+	Time time.Time `json:"time"`
+
+	// Attributes contains the attributes of the event, such as the client ID and the subject.
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// Emitter receives every event emitted with Trace in addition to the trace span.
+type Emitter interface {
+	Emit(ctx context.Context, e Event)
+}
+
+// Emitters emits every event to all emitters.
+type Emitters []Emitter
+
+var _ Emitter = Emitters(nil)
+
+func (es Emitters) Emit(ctx context.Context, e Event) {
+	for _, emitter := range es {
+		emitter.Emit(ctx, e)
+	}
+}
+
+type emitterContextKey struct{}
+
+// WithEmitter returns a context which makes Trace emit events to the given emitter.
+func WithEmitter(ctx context.Context, e Emitter) context.Context {
+	return context.WithValue(ctx, emitterContextKey{}, e)
+}
+
+// EmitterFromContext returns the emitter of the context or nil.
+func EmitterFromContext(ctx context.Context) Emitter {
+	e, _ := ctx.Value(emitterContextKey{}).(Emitter)
+	return e
+}
+
+func newEvent(name string, attributes []otelattr.KeyValue) Event {
+	e := Event{
+		ID:         uuid.Must(uuid.NewV4()),
+		Type:       name,
+		Time:       time.Now().UTC(),
+		Attributes: make(map[string]interface{}, len(attributes)),
+	}
+	for _, a := range attributes {
+		e.Attributes[string(a.Key)] = a.Value.AsInterface()
+	}
+	return e
+}
+
+func emit(ctx context.Context, name string, opts []trace.EventOption) {
+	if e := EmitterFromContext(ctx); e != nil {
+		config := trace.NewEventConfig(opts...)
+		e.Emit(ctx, newEvent(name, config.Attributes()))
+	}
+}
+
+type requestContextKey struct{}
+
+// RequestFromContext returns the HTTP request during which the event was emitted or nil. Emitters can use it to
+// record the source of the event.
+func RequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestContextKey{}).(*http.Request)
+	return r
+}
+
+// Middleware adds the emitter to the context of every request, so that events emitted while handling the request are
+// published.
+func Middleware(e Emitter) func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		ctx := context.WithValue(WithEmitter(r.Context(), e), requestContextKey{}, r)
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -5,6 +5,8 @@ package events
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	otelattr "go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -55,59 +57,146 @@ const (
 
 	// IdentityTokenIssued will be emitted when a refresh token is issued.
 	IdentityTokenIssued semconv.Event = "OIDCIdentityTokenIssued" //nolint:gosec
+
+	// LogoutAccepted will be emitted when the logout UI accepts a logout request.
+	LogoutAccepted semconv.Event = "OIDCLogoutAccepted"
+
+	// LogoutRejected will be emitted when the logout UI rejects a logout request.
+	LogoutRejected semconv.Event = "OIDCLogoutRejected"
+
+	// RefreshTokenReused will be emitted by requests to POST /oauth2/token which present a refresh token that was
+	// already used. All tokens issued from the same authorization are revoked in this case.
+	RefreshTokenReused semconv.Event = "OAuth2RefreshTokenReused" //nolint:gosec
+
+	// AuthorizationCodeReused will be emitted by requests to POST /oauth2/token which present an authorization code
+	// that was already used. All tokens issued from the same authorization are revoked in this case.
+	AuthorizationCodeReused semconv.Event = "OAuth2AuthorizationCodeReused"
+
+	// ClientAuthenticationFailed will be emitted by requests to POST /oauth2/token and POST /oauth2/revoke in case
+	// the client could not be authenticated.
+	ClientAuthenticationFailed semconv.Event = "OAuth2ClientAuthenticationFailed"
+
+	// TokenQuotaSoftLimitReached will be emitted by the first request to POST /oauth2/token in a quota window which
+	// exceeds the soft limit of the client's token quota.
+	TokenQuotaSoftLimitReached semconv.Event = "OAuth2TokenQuotaSoftLimitReached" //nolint:gosec
+
+	// TokenQuotaExceeded will be emitted by the first request to POST /oauth2/token in a quota window which exceeds
+	// the hard limit of the client's token quota, and is therefore rejected.
+	TokenQuotaExceeded semconv.Event = "OAuth2TokenQuotaExceeded" //nolint:gosec
+
+	// TrustedJwtGrantIssuerExpiring will be emitted once when the trust relationship of a JWT issuer enters the
+	// configured expiry warning period.
+	TrustedJwtGrantIssuerExpiring semconv.Event = "OAuth2TrustedJwtGrantIssuerExpiring"
 )
 
+// The keys of the attributes of events.
 const (
-	attributeKeyOAuth2ClientName  = "OAuth2ClientName"
-	attributeKeyOAuth2ClientID    = "OAuth2ClientID"
-	attributeKeyOAuth2Subject     = "OAuth2Subject"
-	attributeKeyOAuth2GrantType   = "OAuth2GrantType"
-	attributeKeyOAuth2TokenFormat = "OAuth2TokenFormat" //nolint:gosec
+	AttributeKeyOAuth2ClientName           = "OAuth2ClientName"
+	AttributeKeyOAuth2ClientID             = "OAuth2ClientID"
+	AttributeKeyOAuth2Subject              = "OAuth2Subject"
+	AttributeKeyOAuth2GrantType            = "OAuth2GrantType"
+	AttributeKeyOAuth2TokenFormat          = "OAuth2TokenFormat" //nolint:gosec
+	AttributeKeyOAuth2Error                = "OAuth2Error"
+	AttributeKeyOAuth2QuotaWindow          = "OAuth2QuotaWindow"
+	AttributeKeyOAuth2QuotaLimit           = "OAuth2QuotaLimit"
+	AttributeKeyOAuth2AuthorizationRequest = "OAuth2AuthorizationRequest"
+	AttributeKeyOAuth2GrantID              = "OAuth2GrantID"
+	AttributeKeyOAuth2GrantChain           = "OAuth2GrantChain"
+	AttributeKeyOAuth2TrustGrantID         = "OAuth2TrustGrantID"
+	AttributeKeyOAuth2TrustGrantIssuer     = "OAuth2TrustGrantIssuer"
+	AttributeKeyOAuth2TrustGrantExpiresAt  = "OAuth2TrustGrantExpiresAt"
 )
 
 // WithTokenFormat emits the token format as part of the event.
 func WithTokenFormat(format string) trace.EventOption {
-	return trace.WithAttributes(otelattr.String(attributeKeyOAuth2TokenFormat, format))
+	return trace.WithAttributes(otelattr.String(AttributeKeyOAuth2TokenFormat, format))
 }
 
 // WithGrantType emits the token format as part of the event.
 func WithGrantType(grantType string) trace.EventOption {
-	return trace.WithAttributes(otelattr.String(attributeKeyOAuth2GrantType, grantType))
+	return trace.WithAttributes(otelattr.String(AttributeKeyOAuth2GrantType, grantType))
 }
 
 // WithClientID emits the client ID as part of the event.
 func WithClientID(clientID string) trace.EventOption {
-	return trace.WithAttributes(otelattr.String(attributeKeyOAuth2ClientID, clientID))
+	return trace.WithAttributes(otelattr.String(AttributeKeyOAuth2ClientID, clientID))
 }
 
 // WithClientName emits the client name as part of the event.
 func WithClientName(clientID string) trace.EventOption {
-	return trace.WithAttributes(otelattr.String(attributeKeyOAuth2ClientName, clientID))
+	return trace.WithAttributes(otelattr.String(AttributeKeyOAuth2ClientName, clientID))
 }
 
 // WithSubject emits the subject as part of the event.
 func WithSubject(subject string) trace.EventOption {
-	return trace.WithAttributes(otelattr.String(attributeKeyOAuth2Subject, subject))
+	return trace.WithAttributes(otelattr.String(AttributeKeyOAuth2Subject, subject))
+}
+
+// WithError emits the OAuth 2.0 error code of the error, for example invalid_grant, as part of the event.
+func WithError(err error) trace.EventOption {
+	return trace.WithAttributes(otelattr.String(AttributeKeyOAuth2Error, fosite.ErrorToRFC6749Error(err).ErrorField))
+}
+
+// WithQuota emits the window ("hour" or "day") and the exceeded limit of a token quota as part of the event.
+func WithQuota(window string, limit int) trace.EventOption {
+	return trace.WithAttributes(
+		otelattr.String(AttributeKeyOAuth2QuotaWindow, window),
+		otelattr.Int(AttributeKeyOAuth2QuotaLimit, limit),
+	)
+}
+
+// WithTrustGrant emits the ID, issuer and expiry, formatted as RFC 3339, of the trust relationship of a JWT issuer as
+// part of the event.
+func WithTrustGrant(id, issuer string, expiresAt time.Time) trace.EventOption {
+	return trace.WithAttributes(
+		otelattr.String(AttributeKeyOAuth2TrustGrantID, id),
+		otelattr.String(AttributeKeyOAuth2TrustGrantIssuer, issuer),
+		otelattr.String(AttributeKeyOAuth2TrustGrantExpiresAt, expiresAt.UTC().Format(time.RFC3339)),
+	)
+}
+
+// WithAuthorizationRequest emits the parameters of the authorization request, encoded as a JSON object, as part of the
+// event.
+func WithAuthorizationRequest(params map[string][]string) trace.EventOption {
+	if len(params) == 0 {
+		return trace.WithAttributes()
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return trace.WithAttributes()
+	}
+	return trace.WithAttributes(otelattr.String(AttributeKeyOAuth2AuthorizationRequest, string(encoded)))
+}
+
+// WithGrant emits the ID of the grant of the fosite request, which all tokens issued by refreshing the grant share, and
+// the grant types with which its tokens were issued, if the session records them, as part of the event.
+func WithGrant(request fosite.Requester) trace.EventOption {
+	attributes := []otelattr.KeyValue{otelattr.String(AttributeKeyOAuth2GrantID, request.GetID())}
+	if session, ok := request.GetSession().(interface{ GetGrantChain() []string }); ok && len(session.GetGrantChain()) > 0 {
+		attributes = append(attributes, otelattr.StringSlice(AttributeKeyOAuth2GrantChain, session.GetGrantChain()))
+	}
+	return trace.WithAttributes(attributes...)
 }
 
 // WithRequest emits the subject and client ID from the fosite request as part of the event.
 func WithRequest(request fosite.Requester) trace.EventOption {
 	var attributes []otelattr.KeyValue
 	if client := request.GetClient(); client != nil {
-		attributes = append(attributes, otelattr.String(attributeKeyOAuth2ClientID, client.GetID()))
+		attributes = append(attributes, otelattr.String(AttributeKeyOAuth2ClientID, client.GetID()))
 	}
 	if session := request.GetSession(); session != nil {
-		attributes = append(attributes, otelattr.String(attributeKeyOAuth2Subject, session.GetSubject()))
+		attributes = append(attributes, otelattr.String(AttributeKeyOAuth2Subject, session.GetSubject()))
 	}
 
 	return trace.WithAttributes(attributes...)
 }
 
-// Trace emits an event with the given attributes.
+// Trace emits an event with the given attributes to the trace span and the emitter of the context, if any.
 func Trace(ctx context.Context, event semconv.Event, opts ...trace.EventOption) {
 	allOpts := append([]trace.EventOption{trace.WithAttributes(semconv.AttributesFromContext(ctx)...)}, opts...)
 	trace.SpanFromContext(ctx).AddEvent(
 		string(event),
 		allOpts...,
 	)
+	emit(ctx, string(event), allOpts)
 }
@@ -0,0 +1,45 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher writes every event as a JSON-encoded message keyed by the event ID to a Kafka topic.
+type KafkaPublisher struct {
+	w *kafka.Writer
+}
+
+var _ Publisher = (*KafkaPublisher)(nil)
+
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{w: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, events []Event) error {
+	messages := make([]kafka.Message, len(events))
+	for i, e := range events {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		messages[i] = kafka.Message{Key: e.ID.Bytes(), Value: value, Time: e.Time}
+	}
+
+	return errors.WithStack(p.w.WriteMessages(ctx, messages...))
+}
+
+func (p *KafkaPublisher) Close() error {
+	return errors.WithStack(p.w.Close())
+}
@@ -0,0 +1,51 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// NATSPublisher publishes every event as a JSON-encoded message to a NATS subject.
+type NATSPublisher struct {
+	nc      *nats.Conn
+	subject string
+}
+
+var _ Publisher = (*NATSPublisher)(nil)
+
+// NewNATSPublisher connects to the NATS server at url. If the server is unavailable, the connection is retried in
+// the background and events are buffered by the NATS client in the meantime.
+func NewNATSPublisher(url, subject string) (*NATSPublisher, error) {
+	nc, err := nats.Connect(url,
+		nats.Name("Ory Hydra"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &NATSPublisher{nc: nc, subject: subject}, nil
+}
+
+func (p *NATSPublisher) Publish(_ context.Context, events []Event) error {
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := p.nc.Publish(p.subject, data); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (p *NATSPublisher) Close() error {
+	return errors.WithStack(p.nc.Drain())
+}
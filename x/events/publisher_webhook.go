@@ -0,0 +1,61 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+)
+
+// WebhookPublisher sends every batch of events as a JSON-encoded array to a webhook.
+type WebhookPublisher struct {
+	c    *retryablehttp.Client
+	url  string
+	auth func(*http.Request) error
+}
+
+var _ Publisher = (*WebhookPublisher)(nil)
+
+// NewWebhookPublisher returns a publisher for the webhook at url. If not nil, auth is applied to every request.
+func NewWebhookPublisher(c *retryablehttp.Client, url string, auth func(*http.Request) error) *WebhookPublisher {
+	return &WebhookPublisher{c: c, url: url, auth: auth}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if p.auth != nil {
+		if err := p.auth(req.Request); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	res, err := p.c.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("the event webhook responded with status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (p *WebhookPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/x/logrusx"
+)
+
+// Publisher publishes batches of events to a message broker or webhook.
+type Publisher interface {
+	Publish(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// Stream is an Emitter which buffers events and publishes them in batches, so that emitting an event never blocks
+// the request which caused it.
+type Stream struct {
+	p             Publisher
+	l             *logrusx.Logger
+	events        chan Event
+	batchSize     int
+	flushInterval time.Duration
+}
+
+var _ Emitter = (*Stream)(nil)
+
+func NewStream(p Publisher, l *logrusx.Logger, bufferSize, batchSize int, flushInterval time.Duration) *Stream {
+	return &Stream{
+		p:             p,
+		l:             l,
+		events:        make(chan Event, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// Emit enqueues the event. The event is dropped if the buffer is full.
+func (s *Stream) Emit(_ context.Context, e Event) {
+	select {
+	case s.events <- e:
+	default:
+		s.l.WithField("event_id", e.ID).WithField("event_type", e.Type).
+			Warn("The event stream buffer is full, dropping the event.")
+	}
+}
+
+// Run publishes events until the context is canceled. Buffered events are published and the publisher is closed
+// before Run returns.
+func (s *Stream) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	publishCtx := context.WithoutCancel(ctx)
+	batch := make([]Event, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.p.Publish(publishCtx, batch); err != nil {
+			s.l.WithError(err).WithField("events", len(batch)).Error("Unable to publish events to the event stream.")
+		}
+		batch = batch[:0]
+	}
+	add := func(e Event) {
+		batch = append(batch, e)
+		if len(batch) >= s.batchSize {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case e := <-s.events:
+			add(e)
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case e := <-s.events:
+					add(e)
+				default:
+					flush()
+					if err := s.p.Close(); err != nil {
+						s.l.WithError(err).Error("Unable to close the event stream.")
+					}
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/x/events"
+	"github.com/ory/x/logrusx"
+)
+
+type recordingEmitter struct {
+	sync.Mutex
+	events []events.Event
+}
+
+func (r *recordingEmitter) Emit(_ context.Context, e events.Event) {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestTrace(t *testing.T) {
+	t.Run("case=does nothing without an emitter", func(t *testing.T) {
+		events.Trace(context.Background(), events.LoginAccepted, events.WithSubject("alice"))
+	})
+
+	t.Run("case=emits the event with its attributes", func(t *testing.T) {
+		e := new(recordingEmitter)
+		events.Trace(events.WithEmitter(context.Background(), e), events.LogoutAccepted, events.WithClientID("my-client"), events.WithSubject("alice"))
+
+		require.Len(t, e.events, 1)
+		assert.Equal(t, "OIDCLogoutAccepted", e.events[0].Type)
+		assert.NotEmpty(t, e.events[0].ID)
+		assert.WithinDuration(t, time.Now(), e.events[0].Time, time.Minute)
+		assert.Equal(t, "my-client", e.events[0].Attributes["OAuth2ClientID"])
+		assert.Equal(t, "alice", e.events[0].Attributes["OAuth2Subject"])
+	})
+}
+
+func TestStream(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		batches [][]events.Event
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+
+		var batch []events.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+
+		lock.Lock()
+		defer lock.Unlock()
+		batches = append(batches, batch)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+
+	publisher := events.NewWebhookPublisher(retryablehttp.NewClient(), ts.URL, func(r *http.Request) error {
+		r.Header.Set("Authorization", "secret")
+		return nil
+	})
+	stream := events.NewStream(publisher, logrusx.New("", ""), 10, 2, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		stream.Run(ctx)
+		close(done)
+	}()
+
+	emitCtx := events.WithEmitter(context.Background(), stream)
+	for i := 0; i < 3; i++ {
+		events.Trace(emitCtx, events.AccessTokenIssued, events.WithClientID("my-client"))
+	}
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(batches) == 1
+	}, 5*time.Second, 10*time.Millisecond, "a full batch is published immediately")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream did not stop")
+	}
+
+	lock.Lock()
+	require.Len(t, batches, 2, "the remaining events are published on shutdown")
+	assert.Len(t, batches[0], 2)
+	assert.Len(t, batches[1], 1)
+	assert.Equal(t, "OAuth2AccessTokenIssued", batches[1][0].Type)
+	assert.Equal(t, "my-client", batches[1][0].Attributes["OAuth2ClientID"])
+	lock.Unlock()
+
+	t.Run("case=drops events if the buffer is full", func(t *testing.T) {
+		stream := events.NewStream(publisher, logrusx.New("", ""), 1, 1, time.Hour)
+		stream.Emit(context.Background(), events.Event{Type: "first"})
+		stream.Emit(context.Background(), events.Event{Type: "second"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		stream.Run(ctx)
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.Len(t, batches, 3)
+		require.Len(t, batches[2], 1)
+		assert.Equal(t, "first", batches[2][0].Type)
+	})
+}
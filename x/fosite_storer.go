@@ -41,6 +41,14 @@ type FositeStorer interface {
 
 	FlushInactiveRefreshTokens(ctx context.Context, notAfter time.Time, limit int, batchSize int) error
 
+	// UseOpenIDConnectNonce marks the nonce of an OpenID Connect authentication request of the client as used until
+	// exp. It returns fosite.ErrInvalidRequest if the client already used the nonce.
+	UseOpenIDConnectNonce(ctx context.Context, clientID, nonce string, exp time.Time) error
+
+	// IncrementTokenQuotaUsage counts a token request of the client in the quota window which starts at start and
+	// ends at end, and returns the number of token requests in the window including this one.
+	IncrementTokenQuotaUsage(ctx context.Context, clientID, window string, start, end time.Time) (int, error)
+
 	// DeleteOpenIDConnectSession deletes an OpenID Connect session.
 	// This is duplicated from Ory Fosite to help against deprecation linting errors.
 	DeleteOpenIDConnectSession(ctx context.Context, authorizeCode string) error
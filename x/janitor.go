@@ -0,0 +1,200 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type janitorRoutineContextKey struct{}
+
+// JanitorSummary is the machine-readable summary of a janitor run.
+type JanitorSummary struct {
+	StartedAt time.Time `json:"started_at"`
+	// Duration is the duration of the run in seconds.
+	Duration float64                  `json:"duration_seconds"`
+	Routines []*JanitorRoutineSummary `json:"routines"`
+	Errors   int                      `json:"errors"`
+}
+
+// JanitorRoutineSummary is the summary of a single cleanup routine of a janitor run.
+type JanitorRoutineSummary struct {
+	Name string `json:"name"`
+	// DeletedRows are the rows deleted by the routine per table. Rows deleted in cascade are not counted.
+	DeletedRows map[string]int64 `json:"deleted_rows"`
+	// LimitReached is true if the routine deleted as many rows as the limit allows, in which case more stale rows are
+	// likely left behind. If this happens on every run, the janitor falls behind.
+	LimitReached bool `json:"limit_reached"`
+	// Duration is the duration of the routine in seconds.
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+
+	mu sync.Mutex
+}
+
+func NewJanitorSummary() *JanitorSummary {
+	return &JanitorSummary{StartedAt: time.Now().UTC(), Routines: []*JanitorRoutineSummary{}}
+}
+
+// Run runs the cleanup routine and adds its summary. The rows deleted by the routine are counted with
+// RecordJanitorDeletedRows.
+func (s *JanitorSummary) Run(ctx context.Context, name string, limit int, run func(ctx context.Context) error) error {
+	r := &JanitorRoutineSummary{Name: name, DeletedRows: map[string]int64{}}
+	start := time.Now()
+	err := run(context.WithValue(ctx, janitorRoutineContextKey{}, r))
+	r.Duration = time.Since(start).Seconds()
+	if err != nil {
+		r.Error = err.Error()
+		s.Errors++
+	}
+
+	var deleted int64
+	for _, n := range r.DeletedRows {
+		deleted += n
+	}
+	r.LimitReached = limit > 0 && deleted >= int64(limit)
+
+	s.Routines = append(s.Routines, r)
+	return err
+}
+
+// Finish sets the duration of the run.
+func (s *JanitorSummary) Finish() {
+	s.Duration = time.Since(s.StartedAt).Seconds()
+}
+
+func (s *JanitorSummary) String() string {
+	var b strings.Builder
+	for _, r := range s.Routines {
+		if r.Error != "" {
+			fmt.Fprintf(&b, "Janitor run on %s failed after %.3fs: %s\n", r.Name, r.Duration, r.Error)
+			continue
+		}
+		tables := make([]string, 0, len(r.DeletedRows))
+		for t := range r.DeletedRows {
+			tables = append(tables, t)
+		}
+		sort.Strings(tables)
+		for _, t := range tables {
+			fmt.Fprintf(&b, "Deleted %d rows from %s\n", r.DeletedRows[t], t)
+		}
+		if r.LimitReached {
+			fmt.Fprintf(&b, "Janitor run on %s reached the limit, stale rows are left behind\n", r.Name)
+		}
+		fmt.Fprintf(&b, "Successfully completed Janitor run on %s in %.3fs\n", r.Name, r.Duration)
+	}
+	return b.String()
+}
+
+// RecordJanitorDeletedRows adds n to the deleted rows of the table in the janitor routine of the context. It does
+// nothing if the context does not belong to a janitor routine.
+func RecordJanitorDeletedRows(ctx context.Context, table string, n int64) {
+	r, ok := ctx.Value(janitorRoutineContextKey{}).(*JanitorRoutineSummary)
+	if !ok || n <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.DeletedRows[table] += n
+}
+
+// JanitorMetrics records the janitor runs, so that it is possible to alert when a routine fails or reaches its limit on
+// every run, and thus the cleanup falls behind.
+type JanitorMetrics struct {
+	deleted      *prometheus.CounterVec
+	runs         *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	limitReached *prometheus.GaugeVec
+	lastSuccess  *prometheus.GaugeVec
+}
+
+// NewJanitorMetrics registers the metrics with the registerer. Metrics which are already registered, for example by
+// another registry in the same process, are reused.
+func NewJanitorMetrics(reg prometheus.Registerer) *JanitorMetrics {
+	m := &JanitorMetrics{
+		deleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "hydra",
+			Name:      "janitor_deleted_rows_total",
+			Help:      "The number of rows deleted by the janitor.",
+		}, []string{"routine", "table"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "hydra",
+			Name:      "janitor_routine_runs_total",
+			Help:      "The number of janitor routine runs.",
+		}, []string{"routine", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "hydra",
+			Name:      "janitor_routine_duration_seconds",
+			Help:      "The duration of janitor routine runs.",
+			Buckets:   []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900},
+		}, []string{"routine"}),
+		limitReached: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "hydra",
+			Name:      "janitor_routine_limit_reached",
+			Help:      "Whether the last run of the janitor routine deleted as many rows as the limit allows.",
+		}, []string{"routine"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "hydra",
+			Name:      "janitor_routine_last_success_timestamp_seconds",
+			Help:      "The time of the last successful run of the janitor routine.",
+		}, []string{"routine"}),
+	}
+
+	m.deleted = RegisterOrExisting(reg, m.deleted)
+	m.runs = RegisterOrExisting(reg, m.runs)
+	m.duration = RegisterOrExisting(reg, m.duration)
+	m.limitReached = RegisterOrExisting(reg, m.limitReached)
+	m.lastSuccess = RegisterOrExisting(reg, m.lastSuccess)
+	return m
+}
+
+// RegisterOrExisting registers the collector with the registerer, or returns the collector which is already registered
+// under the same name.
+func RegisterOrExisting[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+	}
+	return c
+}
+
+// Record records the routines of the janitor run. A routine which runs more than once, for example once per tenant,
+// reached its limit if any of its runs reached the limit.
+func (m *JanitorMetrics) Record(s *JanitorSummary) {
+	limitReached := map[string]bool{}
+	for _, r := range s.Routines {
+		for table, n := range r.DeletedRows {
+			m.deleted.WithLabelValues(r.Name, table).Add(float64(n))
+		}
+		m.duration.WithLabelValues(r.Name).Observe(r.Duration)
+
+		if r.Error != "" {
+			m.runs.WithLabelValues(r.Name, "error").Inc()
+			continue
+		}
+		m.runs.WithLabelValues(r.Name, "success").Inc()
+		m.lastSuccess.WithLabelValues(r.Name).Set(float64(time.Now().Unix()))
+		limitReached[r.Name] = limitReached[r.Name] || r.LimitReached
+	}
+
+	for name, reached := range limitReached {
+		if reached {
+			m.limitReached.WithLabelValues(name).Set(1)
+		} else {
+			m.limitReached.WithLabelValues(name).Set(0)
+		}
+	}
+}
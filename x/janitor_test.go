@@ -0,0 +1,54 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJanitorSummary(t *testing.T) {
+	ctx := context.Background()
+
+	// Deleted rows outside of a janitor routine are not recorded.
+	RecordJanitorDeletedRows(ctx, "hydra_oauth2_access", 1)
+
+	s := NewJanitorSummary()
+	require.NoError(t, s.Run(ctx, "tokens", 3, func(ctx context.Context) error {
+		RecordJanitorDeletedRows(ctx, "hydra_oauth2_access", 2)
+		RecordJanitorDeletedRows(ctx, "hydra_oauth2_refresh", 1)
+		return nil
+	}))
+	require.NoError(t, s.Run(ctx, "tokens", 3, func(ctx context.Context) error {
+		RecordJanitorDeletedRows(ctx, "hydra_oauth2_access", 1)
+		return nil
+	}))
+	require.Error(t, s.Run(ctx, "grants", 3, func(ctx context.Context) error {
+		return errors.New("database is gone")
+	}))
+	s.Finish()
+
+	assert.Equal(t, map[string]int64{"hydra_oauth2_access": 2, "hydra_oauth2_refresh": 1}, s.Routines[0].DeletedRows)
+	assert.True(t, s.Routines[0].LimitReached)
+	assert.False(t, s.Routines[1].LimitReached)
+	assert.Equal(t, "database is gone", s.Routines[2].Error)
+	assert.Equal(t, 1, s.Errors)
+
+	reg := prometheus.NewRegistry()
+	m := NewJanitorMetrics(reg)
+	m.Record(s)
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.deleted.WithLabelValues("tokens", "hydra_oauth2_access")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.limitReached.WithLabelValues("tokens")), "a routine reached its limit if any of its runs did")
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.runs.WithLabelValues("tokens", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.runs.WithLabelValues("grants", "error")))
+
+	assert.Same(t, m.deleted, NewJanitorMetrics(reg).deleted, "reuses registered metrics")
+}
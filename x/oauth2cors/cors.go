@@ -30,12 +30,16 @@ func Middleware(
 
 			opts, enabled := reg.Config().CORS(ctx, config.PublicInterface)
 			if !enabled {
-				reg.Logger().Debug("not enhancing CORS per client, as CORS is disabled")
-				h.ServeHTTP(w, r)
-				return
+				if !reg.Config().OAuth2ClientCORSEnabled(ctx) {
+					reg.Logger().Debug("not enhancing CORS per client, as CORS is disabled")
+					h.ServeHTTP(w, r)
+					return
+				}
+				// Only the allowed origins of the clients are allowed.
+				opts.AllowedOrigins = []string{}
 			}
 
-			alwaysAllow := len(opts.AllowedOrigins) == 0
+			alwaysAllow := enabled && len(opts.AllowedOrigins) == 0
 			patterns := make([]glob.Glob, 0, len(opts.AllowedOrigins))
 			for _, o := range opts.AllowedOrigins {
 				if o == "*" {
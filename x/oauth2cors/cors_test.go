@@ -273,6 +273,44 @@ func TestOAuth2AwareCORSMiddleware(t *testing.T) {
 			header:       http.Header{"Origin": {"http://client-app.example.com"}, "Authorization": {fmt.Sprintf("Basic %s", x.BasicAuth("foo-13", "bar"))}},
 			expectHeader: http.Header{"Access-Control-Allow-Credentials": []string{"true"}, "Access-Control-Allow-Origin": []string{"http://client-app.example.com"}, "Access-Control-Expose-Headers": []string{"Cache-Control, Expires, Last-Modified, Pragma, Content-Length, Content-Language, Content-Type"}, "Vary": []string{"Origin"}},
 		},
+		{
+			d: "should accept when client cors is enabled, cors is disabled, and origin allowed by the client",
+			prep: func(t *testing.T, r driver.Registry) {
+				r.Config().MustSet(ctx, "oauth2.client_cors.enabled", true)
+
+				// Ignore unique violations
+				_ = r.ClientManager().CreateClient(ctx, &client.Client{ID: "foo-14", Secret: "bar", AllowedCORSOrigins: []string{"http://foobar.com"}})
+			},
+			code:         http.StatusNotImplemented,
+			header:       http.Header{"Origin": {"http://foobar.com"}, "Authorization": {fmt.Sprintf("Basic %s", x.BasicAuth("foo-14", "bar"))}},
+			expectHeader: http.Header{"Access-Control-Allow-Credentials": []string{"true"}, "Access-Control-Allow-Origin": []string{"http://foobar.com"}, "Access-Control-Expose-Headers": []string{"Cache-Control, Expires, Last-Modified, Pragma, Content-Length, Content-Language, Content-Type"}, "Vary": []string{"Origin"}},
+		},
+		{
+			d: "should reject when client cors is enabled, cors is disabled, and origin not allowed by the client",
+			prep: func(t *testing.T, r driver.Registry) {
+				r.Config().MustSet(ctx, "oauth2.client_cors.enabled", true)
+
+				// Ignore unique violations
+				_ = r.ClientManager().CreateClient(ctx, &client.Client{ID: "foo-15", Secret: "bar", AllowedCORSOrigins: []string{"http://not-foobar.com"}})
+			},
+			code:         http.StatusNotImplemented,
+			header:       http.Header{"Origin": {"http://foobar.com"}, "Authorization": {fmt.Sprintf("Basic %s", x.BasicAuth("foo-15", "bar"))}},
+			expectHeader: http.Header{"Vary": {"Origin"}},
+		},
+		{
+			d: "should reject public client requests when client cors is enabled, cors is disabled, and origin not allowed by the client",
+			prep: func(t *testing.T, r driver.Registry) {
+				r.Config().MustSet(ctx, "oauth2.client_cors.enabled", true)
+
+				// Ignore unique violations
+				_ = r.ClientManager().CreateClient(ctx, &client.Client{ID: "foo-16", TokenEndpointAuthMethod: "none", AllowedCORSOrigins: []string{"http://not-foobar.com"}})
+			},
+			code:         http.StatusNotImplemented,
+			header:       http.Header{"Origin": {"http://foobar.com"}, "Content-Type": {"application/x-www-form-urlencoded"}},
+			expectHeader: http.Header{"Vary": {"Origin"}},
+			method:       http.MethodPost,
+			body:         bytes.NewBufferString(url.Values{"client_id": {"foo-16"}}.Encode()),
+		},
 	} {
 		t.Run(fmt.Sprintf("case=%d/description=%s", k, tc.d), func(t *testing.T) {
 			r.WithConfig(internal.NewConfigurationWithDefaults())
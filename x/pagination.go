@@ -6,8 +6,11 @@ package x
 import (
 	"net/http"
 	"net/url"
+	"strconv"
 
-	"github.com/ory/x/pagination/tokenpagination"
+	"github.com/ory/fosite"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/pagination/keysetpagination"
 )
 
 // swagger:model paginationHeaders
@@ -46,24 +49,39 @@ type PaginationParams struct {
 	//
 	// required: false
 	// in: query
-	// default: 1
-	// min: 1
 	PageToken string `json:"page_token"`
 }
 
 const paginationMaxItems = 1000
 const paginationDefaultItems = 250
 
-var paginator = &tokenpagination.TokenPaginator{
-	MaxItems:     paginationMaxItems,
-	DefaultItems: paginationDefaultItems,
-}
+// ParsePagination parses the page token and page size from *http.Request. The page token is opaque to API consumers
+// and encodes the position of the last item on the previous page, so that pages remain stable when items are added
+// or removed in between requests.
+func ParsePagination(r *http.Request) ([]keysetpagination.Option, error) {
+	q := r.URL.Query()
+	if q.Get("page_token") == "" {
+		q.Del("page_token")
+	}
+
+	opts, err := keysetpagination.Parse(q, keysetpagination.NewMapPageToken)
+	if err != nil {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The page_token or page_size query parameter is invalid.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	opts = append(opts,
+		keysetpagination.WithDefaultSize(paginationDefaultItems),
+		keysetpagination.WithMaxSize(paginationMaxItems),
+	)
+	if keysetpagination.GetPaginator(opts...).Size() < 1 {
+		return nil, errorsx.WithStack(fosite.ErrInvalidRequest.WithHint("The page_size query parameter must be a positive number."))
+	}
 
-// ParsePagination parses limit and page from *http.Request with given limits and defaults.
-func ParsePagination(r *http.Request) (page, itemsPerPage int) {
-	return paginator.ParsePagination(r)
+	return opts, nil
 }
 
-func PaginationHeader(w http.ResponseWriter, u *url.URL, total int64, page, itemsPerPage int) {
-	tokenpagination.PaginationHeader(w, u, total, page, itemsPerPage)
+// PaginationHeader sets the Link header pointing to the first and next page as well as the X-Total-Count header.
+func PaginationHeader(w http.ResponseWriter, u *url.URL, total int64, p *keysetpagination.Paginator) {
+	keysetpagination.Header(w, u, p)
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
 }
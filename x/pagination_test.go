@@ -0,0 +1,96 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/peterhellberg/link"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/fosite"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+func TestParsePagination(t *testing.T) {
+	parse := func(t *testing.T, query string) (*keysetpagination.Paginator, error) {
+		opts, err := ParsePagination(httptest.NewRequest("GET", "/clients?"+query, nil))
+		if err != nil {
+			return nil, err
+		}
+		return keysetpagination.GetPaginator(append([]keysetpagination.Option{
+			keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{"id": "default"}),
+		}, opts...)...), nil
+	}
+
+	t.Run("case=defaults", func(t *testing.T) {
+		p, err := parse(t, "")
+		require.NoError(t, err)
+		assert.Equal(t, paginationDefaultItems, p.Size())
+		assert.Equal(t, "default", p.Token().Parse("id")["id"])
+	})
+
+	t.Run("case=empty page token", func(t *testing.T) {
+		p, err := parse(t, "page_token=")
+		require.NoError(t, err)
+		assert.Equal(t, "default", p.Token().Parse("id")["id"])
+	})
+
+	t.Run("case=page token and size", func(t *testing.T) {
+		token := keysetpagination.MapPageToken{"id": "foo"}.Encode()
+		p, err := parse(t, "page_size=10&page_token="+url.QueryEscape(token))
+		require.NoError(t, err)
+		assert.Equal(t, 10, p.Size())
+		assert.Equal(t, "foo", p.Token().Parse("id")["id"])
+	})
+
+	t.Run("case=page size is capped", func(t *testing.T) {
+		p, err := parse(t, "page_size=100000")
+		require.NoError(t, err)
+		assert.Equal(t, paginationMaxItems, p.Size())
+	})
+
+	for _, query := range []string{"page_size=-1", "page_size=foo", "page_token=not-base64!"} {
+		t.Run("case=invalid "+query, func(t *testing.T) {
+			_, err := parse(t, query)
+			assert.ErrorIs(t, err, fosite.ErrInvalidRequest)
+		})
+	}
+}
+
+func TestPaginationHeader(t *testing.T) {
+	u, err := url.Parse("https://example.com/admin/clients?client_name=foo")
+	require.NoError(t, err)
+
+	opts, err := ParsePagination(httptest.NewRequest("GET", "/admin/clients?page_size=1", nil))
+	require.NoError(t, err)
+	p := keysetpagination.GetPaginator(append([]keysetpagination.Option{
+		keysetpagination.WithDefaultToken(keysetpagination.MapPageToken{"id": ""}),
+	}, opts...)...)
+	_, next := keysetpagination.Result([]item{{"a"}, {"b"}}, p)
+
+	rec := httptest.NewRecorder()
+	PaginationHeader(rec, u, 2, next)
+
+	assert.Equal(t, "2", rec.Header().Get("X-Total-Count"))
+	links := link.ParseResponse(rec.Result())
+	require.Contains(t, links, "first")
+	require.Contains(t, links, "next")
+	assert.Contains(t, links["next"].URI, "client_name=foo")
+
+	nextURL, err := url.Parse(links["next"].URI)
+	require.NoError(t, err)
+	token, err := keysetpagination.NewMapPageToken(nextURL.Query().Get("page_token"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", token.Parse("id")["id"])
+}
+
+type item struct{ ID string }
+
+func (i item) PageToken() keysetpagination.PageToken {
+	return keysetpagination.MapPageToken{"id": i.ID}
+}
@@ -0,0 +1,120 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// PrometheusProducer exports the metrics of a Prometheus registry through an OpenTelemetry metric reader, so that
+// they can be pushed with OTLP. Counters are exported as monotonic sums, gauges and untyped metrics as gauges, and
+// histograms as histograms. Summaries are not supported by OpenTelemetry and are skipped.
+type PrometheusProducer struct {
+	gatherer prometheus.Gatherer
+	start    time.Time
+}
+
+var _ metric.Producer = (*PrometheusProducer)(nil)
+
+func NewPrometheusProducer(gatherer prometheus.Gatherer) *PrometheusProducer {
+	return &PrometheusProducer{gatherer: gatherer, start: time.Now()}
+}
+
+func (p *PrometheusProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, errors.WithStack(err)
+	}
+
+	now := time.Now()
+	metrics := make([]metricdata.Metrics, 0, len(families))
+	for _, f := range families {
+		m := metricdata.Metrics{Name: f.GetName(), Description: f.GetHelp()}
+		switch f.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+			for _, pm := range f.GetMetric() {
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: prometheusAttributes(pm),
+					StartTime:  p.start,
+					Time:       now,
+					Value:      pm.GetCounter().GetValue(),
+				})
+			}
+			m.Data = sum
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			var gauge metricdata.Gauge[float64]
+			for _, pm := range f.GetMetric() {
+				value := pm.GetGauge().GetValue()
+				if f.GetType() == dto.MetricType_UNTYPED {
+					value = pm.GetUntyped().GetValue()
+				}
+				gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: prometheusAttributes(pm),
+					Time:       now,
+					Value:      value,
+				})
+			}
+			m.Data = gauge
+		case dto.MetricType_HISTOGRAM:
+			histogram := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+			for _, pm := range f.GetMetric() {
+				histogram.DataPoints = append(histogram.DataPoints, prometheusHistogram(pm, p.start, now))
+			}
+			m.Data = histogram
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: "github.com/ory/hydra/v2"},
+		Metrics: metrics,
+	}}, nil
+}
+
+func prometheusAttributes(m *dto.Metric) attribute.Set {
+	kvs := make([]attribute.KeyValue, len(m.GetLabel()))
+	for i, l := range m.GetLabel() {
+		kvs[i] = attribute.String(l.GetName(), l.GetValue())
+	}
+	return attribute.NewSet(kvs...)
+}
+
+// prometheusHistogram converts the cumulative buckets of a Prometheus histogram to the bucket counts of an
+// OpenTelemetry histogram.
+func prometheusHistogram(m *dto.Metric, start, now time.Time) metricdata.HistogramDataPoint[float64] {
+	h := m.GetHistogram()
+	dp := metricdata.HistogramDataPoint[float64]{
+		Attributes: prometheusAttributes(m),
+		StartTime:  start,
+		Time:       now,
+		Count:      h.GetSampleCount(),
+		Sum:        h.GetSampleSum(),
+	}
+
+	var previous uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		dp.Bounds = append(dp.Bounds, b.GetUpperBound())
+		dp.BucketCounts = append(dp.BucketCounts, b.GetCumulativeCount()-previous)
+		previous = b.GetCumulativeCount()
+	}
+	// The last bucket counts the observations above the highest bound.
+	dp.BucketCounts = append(dp.BucketCounts, h.GetSampleCount()-previous)
+	return dp
+}
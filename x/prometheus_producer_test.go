@@ -0,0 +1,59 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestPrometheusProducer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "Requests."}, []string{"status"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "connections", Help: "Connections."})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "duration_seconds", Help: "Durations.", Buckets: []float64{1, 2}})
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "size_bytes", Help: "Sizes."})
+	reg.MustRegister(counter, gauge, histogram, summary)
+
+	counter.WithLabelValues("200").Add(3)
+	gauge.Set(7)
+	for _, v := range []float64{0.5, 1.5, 1.5, 5} {
+		histogram.Observe(v)
+	}
+	summary.Observe(1)
+
+	scopes, err := NewPrometheusProducer(reg).Produce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range scopes[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	require.Len(t, metrics, 3, "summaries are skipped")
+
+	sum := metrics["requests_total"].(metricdata.Sum[float64])
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, metricdata.CumulativeTemporality, sum.Temporality)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, 3.0, sum.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(attribute.String("status", "200")), sum.DataPoints[0].Attributes)
+
+	g := metrics["connections"].(metricdata.Gauge[float64])
+	require.Len(t, g.DataPoints, 1)
+	assert.Equal(t, 7.0, g.DataPoints[0].Value)
+
+	h := metrics["duration_seconds"].(metricdata.Histogram[float64])
+	require.Len(t, h.DataPoints, 1)
+	assert.EqualValues(t, 4, h.DataPoints[0].Count)
+	assert.Equal(t, 8.5, h.DataPoints[0].Sum)
+	assert.Equal(t, []float64{1, 2}, h.DataPoints[0].Bounds)
+	assert.Equal(t, []uint64{1, 2, 1}, h.DataPoints[0].BucketCounts)
+}
@@ -0,0 +1,152 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// NewProxyProtocolListener returns a listener which reads the PROXY protocol (version 1 or 2) header sent by trusted
+// proxies and reports the client address it carries as the remote address of the connection. Connections from other
+// peers are served as they are, so a header sent by them is not interpreted and makes the request fail.
+//
+// The header is read on the first call to Read or RemoteAddr, which happens in the goroutine serving the connection,
+// so that slow peers do not block the accept loop. Reading the header times out after the given duration.
+func NewProxyProtocolListener(l net.Listener, c trustedProxiesConfig, timeout time.Duration) net.Listener {
+	return &proxyProtocolListener{Listener: l, c: c, timeout: timeout}
+}
+
+type proxyProtocolListener struct {
+	net.Listener
+	c       trustedProxiesConfig
+	timeout time.Duration
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	peer, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok || !l.c.IsTrusted(peer.IP) {
+		return conn, nil
+	}
+	return &proxyProtocolConn{Conn: conn, r: bufio.NewReader(conn), timeout: l.timeout}, nil
+}
+
+type proxyProtocolConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtocolConn) readHeader() {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		c.err = errors.WithStack(err)
+		return
+	}
+	defer func() {
+		if err := c.Conn.SetReadDeadline(time.Time{}); err != nil && c.err == nil {
+			c.err = errors.WithStack(err)
+		}
+	}()
+
+	if prefix, err := c.r.Peek(len(proxyProtocolV2Signature)); err == nil && bytes.Equal(prefix, proxyProtocolV2Signature) {
+		c.remote, c.err = readProxyProtocolV2(c.r)
+	} else if prefix, err := c.r.Peek(6); err == nil && string(prefix) == "PROXY " {
+		c.remote, c.err = readProxyProtocolV1(c.r)
+	}
+}
+
+// readProxyProtocolV1 reads a header such as "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n".
+func readProxyProtocolV1(r *bufio.Reader) (net.Addr, error) {
+	// The header is at most 107 bytes long, which fits into the buffer of the reader.
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read the PROXY protocol header")
+	}
+
+	fields := strings.Fields(strings.TrimSuffix(string(line), "\r\n"))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.Errorf("malformed PROXY protocol header: %q", line)
+	}
+
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, errors.Errorf("malformed PROXY protocol header: %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyProtocolV2 reads a binary header. Only the source address of TCP over IPv4 and IPv6 is used, all other
+// address families and LOCAL commands keep the address of the peer.
+func readProxyProtocolV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(err, "unable to read the PROXY protocol header")
+	}
+	if header[12]>>4 != 2 {
+		return nil, errors.Errorf("unsupported PROXY protocol version %d", header[12]>>4)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errors.Wrap(err, "unable to read the PROXY protocol header")
+	}
+
+	if header[12]&0x0f != 1 { // LOCAL, e.g. health checks of the proxy
+		return nil, nil
+	}
+
+	switch header[13] {
+	case 0x11: // TCP over IPv4
+		if len(payload) < 12 {
+			return nil, errors.New("malformed PROXY protocol header")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(payload) < 36 {
+			return nil, errors.New("malformed PROXY protocol header")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x_test
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/hydra/v2/driver/config"
+	"github.com/ory/hydra/v2/internal"
+	. "github.com/ory/hydra/v2/x"
+)
+
+func TestProxyProtocolListener(t *testing.T) {
+	ctx := context.Background()
+
+	listen := func(t *testing.T, cidrs []string) string {
+		c := internal.NewConfigurationWithDefaults()
+		c.MustSet(ctx, config.PublicInterface.Key(config.KeySuffixTrustedProxiesCIDRs), cidrs)
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.RemoteAddr))
+		}), ReadHeaderTimeout: time.Second}
+		go func() {
+			_ = srv.Serve(NewProxyProtocolListener(l, c.TrustedProxies(config.PublicInterface), time.Second))
+		}()
+		t.Cleanup(func() { _ = srv.Close() })
+		return l.Addr().String()
+	}
+
+	send := func(t *testing.T, addr string, header []byte) string {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write(append(header, []byte("GET / HTTP/1.0\r\n\r\n")...))
+		require.NoError(t, err)
+		res, err := io.ReadAll(conn)
+		require.NoError(t, err)
+		return string(res)
+	}
+
+	v2, err := hex.DecodeString("0d0a0d0a000d0a515549540a" + "21" + "11" + "000c" + "cb007107" + "7f000001" + "1267" + "01bb")
+	require.NoError(t, err)
+
+	t.Run("case=version 1", func(t *testing.T) {
+		res := send(t, listen(t, []string{"127.0.0.0/8"}), []byte("PROXY TCP4 203.0.113.7 127.0.0.1 4711 443\r\n"))
+		assert.Contains(t, res, "\r\n\r\n203.0.113.7:4711")
+	})
+
+	t.Run("case=version 2", func(t *testing.T) {
+		res := send(t, listen(t, []string{"127.0.0.0/8"}), v2)
+		assert.Contains(t, res, "\r\n\r\n203.0.113.7:4711")
+	})
+
+	t.Run("case=without header", func(t *testing.T) {
+		res := send(t, listen(t, []string{"127.0.0.0/8"}), nil)
+		assert.Contains(t, res, "\r\n\r\n127.0.0.1:")
+	})
+
+	t.Run("case=untrusted peer", func(t *testing.T) {
+		res := send(t, listen(t, []string{"10.0.0.0/8"}), []byte("PROXY TCP4 203.0.113.7 127.0.0.1 4711 443\r\n"))
+		assert.Contains(t, res, "400 Bad Request")
+	})
+}
@@ -6,6 +6,7 @@ package x
 import (
 	"context"
 	"net/url"
+	"strings"
 
 	"github.com/ory/fosite"
 )
@@ -27,3 +28,14 @@ func IsRedirectURISecure(rc redirectConfiguration) func(context.Context, *url.UR
 		return false
 	}
 }
+
+// MatchSubdomainWildcard reports whether the host matches the pattern. A pattern which starts with a "*." label
+// matches all subdomains of the rest of the pattern, but not the rest of the pattern itself.
+func MatchSubdomainWildcard(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	base, ok := strings.CutPrefix(pattern, "*.")
+	if !ok {
+		return pattern == host
+	}
+	return strings.HasSuffix(host, "."+base) && !strings.HasPrefix(host, ".")
+}
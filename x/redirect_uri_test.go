@@ -37,3 +37,24 @@ func TestIsRedirectURISecure(t *testing.T) {
 		assert.Equal(t, !c.err, IsRedirectURISecure(&mockrc{dm: c.dev})(context.Background(), uu), "case %d", d)
 	}
 }
+
+func TestMatchSubdomainWildcard(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, host string
+		match         bool
+	}{
+		{pattern: "example.org", host: "example.org", match: true},
+		{pattern: "example.org", host: "EXAMPLE.org", match: true},
+		{pattern: "example.org", host: "www.example.org"},
+		{pattern: "*.example.org", host: "www.example.org", match: true},
+		{pattern: "*.example.org", host: "a.b.example.org", match: true},
+		{pattern: "*.example.org", host: "example.org"},
+		{pattern: "*.example.org", host: ".example.org"},
+		{pattern: "*.example.org", host: "wwwexample.org"},
+		{pattern: "*.example.org", host: "example.org.evil.com"},
+	} {
+		t.Run("case="+tc.pattern+"/"+tc.host, func(t *testing.T) {
+			assert.Equal(t, tc.match, MatchSubdomainWildcard(tc.pattern, tc.host))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/stringslice"
+)
+
+// SDJWTHashAlgorithm is the hash algorithm of the digests of selectively disclosable claims.
+const SDJWTHashAlgorithm = "sha-256"
+
+// ConcealClaims replaces the claims, except those in plain, by the digests of their disclosures as defined by
+// Selective Disclosure for JWTs (SD-JWT). It returns the concealed claims and the disclosures, which the holder may
+// present to reveal the claims.
+func ConcealClaims(claims map[string]interface{}, plain ...string) (concealed map[string]interface{}, disclosures []string, err error) {
+	names := make([]string, 0, len(claims))
+	concealed = make(map[string]interface{}, len(claims)+2)
+	for name, value := range claims {
+		if name == "_sd" || name == "_sd_alg" {
+			return nil, nil, errors.Errorf("the claim %q is reserved", name)
+		}
+		if stringslice.Has(plain, name) {
+			concealed[name] = value
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return concealed, nil, nil
+	}
+
+	// The claims are concealed in order, and the digests sorted, so that neither reveals the claim names.
+	sort.Strings(names)
+	digests := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		disclosure, err := newDisclosure(name, claims[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		digests = append(digests, disclosureDigest(disclosure))
+		disclosures = append(disclosures, disclosure)
+	}
+	sort.Slice(digests, func(i, j int) bool { return digests[i].(string) < digests[j].(string) })
+
+	concealed["_sd"] = digests
+	concealed["_sd_alg"] = SDJWTHashAlgorithm
+	return concealed, disclosures, nil
+}
+
+// SDJWT combines the issuer-signed JWT with the disclosures into an SD-JWT without key binding JWT.
+func SDJWT(jwt string, disclosures []string) string {
+	var b strings.Builder
+	b.WriteString(jwt)
+	b.WriteString("~")
+	for _, d := range disclosures {
+		b.WriteString(d)
+		b.WriteString("~")
+	}
+	return b.String()
+}
+
+// DisclosedClaims returns the claims revealed by the disclosures of the SD-JWT, if their digests are in the
+// claims of the issuer-signed JWT.
+func DisclosedClaims(claims map[string]interface{}, sdJWT string) (map[string]interface{}, error) {
+	parts := strings.Split(sdJWT, "~")
+	digests := map[string]bool{}
+	if sd, ok := claims["_sd"].([]interface{}); ok {
+		for _, d := range sd {
+			if s, ok := d.(string); ok {
+				digests[s] = true
+			}
+		}
+	}
+
+	disclosed := map[string]interface{}{}
+	for _, disclosure := range parts[1:] {
+		if disclosure == "" {
+			continue
+		}
+		if !digests[disclosureDigest(disclosure)] {
+			return nil, errors.New("the disclosure is not referenced by the JWT")
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(disclosure)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var d []interface{}
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(d) != 3 {
+			return nil, errors.New("the disclosure is malformed")
+		}
+		name, ok := d[1].(string)
+		if !ok {
+			return nil, errors.New("the disclosure is malformed")
+		}
+		disclosed[name] = d[2]
+	}
+	return disclosed, nil
+}
+
+func newDisclosure(name string, value interface{}) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.WithStack(err)
+	}
+	raw, err := json.Marshal([]interface{}{base64.RawURLEncoding.EncodeToString(salt), name, value})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func disclosureDigest(disclosure string) string {
+	digest := sha256.Sum256([]byte(disclosure))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
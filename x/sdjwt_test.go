@@ -0,0 +1,55 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSDJWT(t *testing.T) {
+	claims := map[string]interface{}{"email": "foo@bar.com", "name": "Foo", "sid": "session"}
+
+	concealed, disclosures, err := ConcealClaims(claims, "sid")
+	require.NoError(t, err)
+	require.Len(t, disclosures, 2)
+	assert.Equal(t, "session", concealed["sid"])
+	assert.NotContains(t, concealed, "email")
+	assert.NotContains(t, concealed, "name")
+	assert.Len(t, concealed["_sd"], 2)
+	assert.Equal(t, SDJWTHashAlgorithm, concealed["_sd_alg"])
+
+	sdJWT := SDJWT("header.payload.signature", disclosures)
+	assert.Equal(t, "header.payload.signature~"+disclosures[0]+"~"+disclosures[1]+"~", sdJWT)
+
+	disclosed, err := DisclosedClaims(concealed, sdJWT)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"email": "foo@bar.com", "name": "Foo"}, disclosed)
+
+	t.Run("case=only reveals presented disclosures", func(t *testing.T) {
+		disclosed, err := DisclosedClaims(concealed, SDJWT("header.payload.signature", disclosures[:1]))
+		require.NoError(t, err)
+		assert.Len(t, disclosed, 1)
+	})
+
+	t.Run("case=rejects disclosures which are not referenced", func(t *testing.T) {
+		_, other, err := ConcealClaims(map[string]interface{}{"email": "foo@bar.com"})
+		require.NoError(t, err)
+		_, err = DisclosedClaims(concealed, SDJWT("header.payload.signature", other))
+		require.Error(t, err)
+	})
+
+	t.Run("case=disclosures are salted", func(t *testing.T) {
+		_, again, err := ConcealClaims(claims, "sid")
+		require.NoError(t, err)
+		assert.NotEqual(t, disclosures, again)
+	})
+
+	t.Run("case=rejects reserved claims", func(t *testing.T) {
+		_, _, err := ConcealClaims(map[string]interface{}{"_sd": []string{}})
+		require.Error(t, err)
+	})
+}